@@ -0,0 +1,18 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/handlers"
+)
+
+func calendarImporter() {
+	// anonymous, asynchronous function for continuous import of other sites' calendars in background
+	go func() {
+		for {
+			handlers.Repo.ImportExternalCalendars(context.Background())
+			time.Sleep(time.Duration(app.ICalImportMinutes) * time.Minute)
+		}
+	}()
+}
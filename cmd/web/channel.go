@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/handlers"
+)
+
+func channelSyncer() {
+	// anonymous, asynchronous function for continuous push of availability & rates to the channel manager in background, syncing
+	// straight away whenever a change is made
+	go func() {
+		for {
+			handlers.Repo.SyncChannel(context.Background())
+			select {
+			case <-handlers.ChannelSyncRequests():
+			case <-time.After(time.Duration(app.ChannelSyncMinutes) * time.Minute):
+			}
+		}
+	}()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/handlers"
+)
+
+// reapInterval is how often expired room holds are released
+const reapInterval = time.Minute
+
+func holdReaper() {
+	// anonymous, asynchronous function for continuous release of expired room holds in background
+	go func() {
+		for {
+			releaseExpiredHolds()
+			time.Sleep(reapInterval)
+		}
+	}()
+}
+
+// releaseExpiredHolds frees rooms held by guests who did not complete their reservation in time
+func releaseExpiredHolds() {
+	released, err := handlers.Repo.DB.ReleaseExpiredHolds(context.Background(), time.Now())
+	if err != nil {
+		errorLog.Println(err)
+		return
+	}
+	if released > 0 {
+		infoLog.Printf("Released %d expired room hold(s)\n", released)
+	}
+}
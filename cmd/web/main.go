@@ -6,15 +6,19 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/StratoNET/bnb-bookings/internal/channel"
 	"github.com/StratoNET/bnb-bookings/internal/config"
 	"github.com/StratoNET/bnb-bookings/internal/database"
 	"github.com/StratoNET/bnb-bookings/internal/handlers"
 	"github.com/StratoNET/bnb-bookings/internal/helpers"
 	"github.com/StratoNET/bnb-bookings/internal/models"
 	"github.com/StratoNET/bnb-bookings/internal/render"
+	"github.com/StratoNET/bnb-bookings/internal/repository"
 	"github.com/alexedwards/scs/v2"
 	"github.com/joho/godotenv"
 )
@@ -26,11 +30,18 @@ var session *scs.SessionManager
 var infoLog *log.Logger
 var errorLog *log.Logger
 
-// var dbSsl bool
-
 // main is the main application function
 func main() {
 
+	// "migrate up|down|status" migrates the database's schema, or reports which migrations it has, instead of starting the application
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := runMigrate(os.Args[2:], os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	db, err := run_main()
 	if err != nil {
 		log.Fatal(err)
@@ -44,6 +55,28 @@ func main() {
 	infoLog.Println("Starting continuous email listening function...")
 	mailListener()
 
+	// start continuous purging of the trash in purge.go
+	infoLog.Printf("Starting trash purge, retaining deleted items for %d days...\n", app.TrashRetentionDays)
+	purgeListener()
+
+	// start continuous release of expired room holds in holds.go
+	infoLog.Printf("Starting hold reaper, holding chosen rooms for %d minutes...\n", app.HoldMinutes)
+	holdReaper()
+
+	// start continuous import of other sites' calendars in calendars.go
+	infoLog.Printf("Starting calendar importer, importing every %d minutes...\n", app.ICalImportMinutes)
+	calendarImporter()
+
+	// start continuous delivery of events to webhooks in webhooks.go
+	infoLog.Printf("Starting webhook dispatcher, delivering every %s...\n", webhookInterval)
+	webhookDispatcher()
+
+	// start continuous push of availability & rates to the channel manager in channel.go
+	if app.Channel.URL != "" {
+		infoLog.Printf("Starting channel sync, pushing %d days ahead to %s...\n", app.ChannelDays, app.Channel.URL)
+		channelSyncer()
+	}
+
 	infoLog.Printf("Starting application on port %s\n", portNumber)
 
 	srv := &http.Server{
@@ -64,6 +97,9 @@ func run_main() (*database.DB, error) {
 	gob.Register(models.Room{})
 	gob.Register(models.RoomRestriction{})
 	gob.Register(models.RestrictionCategory{})
+	gob.Register(models.Quote{})
+	gob.Register(models.WaitlistEntry{})
+	gob.Register(models.BookingGroup{})
 	gob.Register(map[string]int{})
 
 	// load environment
@@ -71,14 +107,6 @@ func run_main() (*database.DB, error) {
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
-	// get environment settings
-	dbUser := os.Getenv("DB_USER")
-	dbPass := os.Getenv("DB_PASS")
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbName := os.Getenv("DB_NAME")
-	// dbSsl, _ = strconv.ParseBool(os.Getenv("DB_SSL"))
-
 	// create application email channel
 	mailChannel := make(chan models.MailData)
 	app.MailChannel = mailChannel
@@ -86,6 +114,58 @@ func run_main() (*database.DB, error) {
 	// set development / production mode
 	app.ProductionMode, _ = strconv.ParseBool(os.Getenv("PRODUCTION_MODE"))
 
+	// guests manage their own bookings via signed links, which need the site's address & a secret signing key
+	app.SiteURL = strings.TrimSuffix(os.Getenv("SITE_URL"), "/")
+	if app.SiteURL == "" {
+		app.SiteURL = "http://localhost" + portNumber
+	}
+	app.BookingKey = []byte(os.Getenv("BOOKING_KEY"))
+	if len(app.BookingKey) == 0 {
+		log.Fatal("BOOKING_KEY must be set in .env file")
+	}
+	app.CancellationDays, _ = strconv.Atoi(os.Getenv("CANCELLATION_DAYS"))
+
+	// deleted reservations & owner blocks can be restored from the trash until purged, 30 days unless set otherwise
+	app.TrashRetentionDays, _ = strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if app.TrashRetentionDays <= 0 {
+		app.TrashRetentionDays = 30
+	}
+
+	// a chosen room is held for a guest while they complete their reservation, 15 minutes unless set otherwise
+	app.HoldMinutes, _ = strconv.Atoi(os.Getenv("HOLD_MINUTES"))
+	if app.HoldMinutes <= 0 {
+		app.HoldMinutes = 15
+	}
+
+	// bookings taken on other sites are copied from their calendars, every 30 minutes unless set otherwise
+	app.ICalImportMinutes, _ = strconv.Atoi(os.Getenv("ICAL_IMPORT_MINUTES"))
+	if app.ICalImportMinutes <= 0 {
+		app.ICalImportMinutes = 30
+	}
+
+	// availability & rates are pushed to a channel manager only if its endpoint is set, a year ahead every 15 minutes unless set otherwise
+	app.Channel = channel.Config{
+		URL:       os.Getenv("CHANNEL_URL"),
+		HotelCode: os.Getenv("CHANNEL_HOTEL_CODE"),
+		Username:  os.Getenv("CHANNEL_USERNAME"),
+		Password:  os.Getenv("CHANNEL_PASSWORD"),
+	}
+	app.ChannelSyncMinutes, _ = strconv.Atoi(os.Getenv("CHANNEL_SYNC_MINUTES"))
+	if app.ChannelSyncMinutes <= 0 {
+		app.ChannelSyncMinutes = 15
+	}
+	app.ChannelDays, _ = strconv.Atoi(os.Getenv("CHANNEL_DAYS"))
+	if app.ChannelDays <= 0 {
+		app.ChannelDays = 365
+	}
+
+	// each database operation is given 3 seconds, though some handling many rows are allowed longer, unless set otherwise e.g.
+	// DB_TIMEOUT=5s & DB_TIMEOUTS=ImportReservations=2m,StreamReservations=5m
+	app.DBTimeouts, err = dbTimeouts(os.Getenv("DB_TIMEOUT"), os.Getenv("DB_TIMEOUTS"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// create InfoLog & ErrorLog, making them available throughout application via config
 	infoLog = log.New(os.Stdout, "\033[36;1mINFO\033[0;0m\t", log.Ldate|log.Ltime)
 	app.InfoLog = infoLog
@@ -102,15 +182,24 @@ func run_main() (*database.DB, error) {
 	app.Session = session
 
 	infoLog.Println("Connecting to database...")
-	// original connection string = "root:@tcp(localhost:3306)/bnb-bookings?parseTime=true"
-	// connect to database (parseTime parameter allows for parsing MySQL []uint8 timestamps as Go *time.Time type)
-	connectionString := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", dbUser, dbPass, dbHost, dbPort, dbName)
-	db, err := database.ConnectSQL(connectionString)
+	db, err := connectDatabase()
 	if err != nil {
 		log.Fatal("Cannot connect to database ! ... terminating...")
 	}
 	infoLog.Println("Connected to database OK")
 
+	// a SQLite database serves this host alone, so is migrated as the application starts, whereas any other must be migrated beforehand
+	if db.Driver == database.SQLite {
+		_, err = database.MigrateUp(db)
+		if err != nil {
+			log.Fatal("Cannot migrate database ! ... terminating...", err)
+		}
+	}
+	err = database.CheckSchema(db)
+	if err != nil {
+		log.Fatalf("%s, run 'migrate up' first ! ... terminating...", err)
+	}
+
 	tc, err := render.CreateTemplateCache()
 	if err != nil {
 		log.Fatal("cannot create application template cache")
@@ -129,3 +218,83 @@ func run_main() (*database.DB, error) {
 
 	return db, nil
 }
+
+// connectDatabase connects to the database given by the environment, by its driver
+func connectDatabase() (*database.DB, error) {
+	// get environment settings
+	dbUser := os.Getenv("DB_USER")
+	dbPass := os.Getenv("DB_PASS")
+	dbHost := os.Getenv("DB_HOST")
+	dbPort := os.Getenv("DB_PORT")
+	dbName := os.Getenv("DB_NAME")
+	dbSsl, _ := strconv.ParseBool(os.Getenv("DB_SSL"))
+
+	// MariaDB is employed unless another database's driver is set
+	dbDriver := os.Getenv("DB_DRIVER")
+	if dbDriver == "" {
+		dbDriver = database.MariaDB
+	}
+
+	var connectionString string
+	switch dbDriver {
+	case database.MariaDB:
+		// original connection string = "root:@tcp(localhost:3306)/bnb-bookings?parseTime=true"
+		// connect to database (parseTime parameter allows for parsing MySQL []uint8 timestamps as Go *time.Time type)
+		connectionString = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", dbUser, dbPass, dbHost, dbPort, dbName)
+	case database.Postgres:
+		sslMode := "disable"
+		if dbSsl {
+			sslMode = "require"
+		}
+		// sessions are kept to UTC, as MariaDB's are by default, so that dates are neither stored nor read a day out
+		connectionString = fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s sslmode=%s timezone=UTC", dbHost, dbPort, dbName, dbUser, dbPass, sslMode)
+	case database.SQLite:
+		// DB_NAME is the database's file, created if not found; each transaction takes the write lock as it begins, so
+		// that bookings are made one at a time, & waits up to 5 seconds for it rather than failing at once
+		connectionString = fmt.Sprintf("file:%s?_foreign_keys=1&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate", dbName)
+	default:
+		log.Fatalf("DB_DRIVER %q is not supported, use %s, %s or %s", dbDriver, database.MariaDB, database.Postgres, database.SQLite)
+	}
+
+	return database.ConnectSQL(dbDriver, connectionString)
+}
+
+// dbTimeouts reads the timeout of every database operation, & those of particular operations given as a comma separated list of
+// DatabaseRepository method names each with its timeout, e.g. "ImportReservations=2m,StreamReservations=5m"
+func dbTimeouts(timeout, timeouts string) (config.DBTimeouts, error) {
+	var t config.DBTimeouts
+
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return t, fmt.Errorf("DB_TIMEOUT %q is not a positive duration e.g. 5s", timeout)
+		}
+		t.Default = d
+	}
+
+	repo := reflect.TypeOf((*repository.DatabaseRepository)(nil)).Elem()
+	for _, entry := range strings.Split(timeouts, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return t, fmt.Errorf("DB_TIMEOUTS entry %q is not of the form Operation=duration", entry)
+		}
+		op := strings.TrimSpace(parts[0])
+		if _, ok := repo.MethodByName(op); !ok {
+			return t, fmt.Errorf("DB_TIMEOUTS operation %q is not a database operation", op)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil || d <= 0 {
+			return t, fmt.Errorf("DB_TIMEOUTS timeout of %s %q is not a positive duration e.g. 2m", op, parts[1])
+		}
+		if t.Operations == nil {
+			t.Operations = make(map[string]time.Duration)
+		}
+		t.Operations[op] = d
+	}
+
+	return t, nil
+}
@@ -1,6 +1,9 @@
 package main
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestRun_main(t *testing.T) {
 	_, err := run_main()
@@ -8,3 +11,44 @@ func TestRun_main(t *testing.T) {
 		t.Error("failed run_main()")
 	}
 }
+
+func TestDBTimeouts(t *testing.T) {
+	timeouts, err := dbTimeouts("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := timeouts.For("GetRoomByID"); got != 3*time.Second {
+		t.Errorf("expected default timeout of 3s, got %s", got)
+	}
+	if got := timeouts.For("StreamReservations"); got != time.Minute {
+		t.Errorf("expected StreamReservations to be allowed 1m, got %s", got)
+	}
+
+	timeouts, err = dbTimeouts("5s", "ImportReservations=2m, GetRoomByID=500ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for op, expected := range map[string]time.Duration{
+		"GetAllRooms":        5 * time.Second,
+		"GetRoomByID":        500 * time.Millisecond,
+		"ImportReservations": 2 * time.Minute,
+		"StreamReservations": time.Minute,
+	} {
+		if got := timeouts.For(op); got != expected {
+			t.Errorf("expected %s timeout of %s, got %s", op, expected, got)
+		}
+	}
+
+	for _, v := range []struct{ timeout, timeouts string }{
+		{"soon", ""},
+		{"-1s", ""},
+		{"", "ImportReservations"},
+		{"", "ImportReservatons=2m"},
+		{"", "ImportReservations=0s"},
+	} {
+		_, err = dbTimeouts(v.timeout, v.timeouts)
+		if err == nil {
+			t.Errorf("expected DB_TIMEOUT %q & DB_TIMEOUTS %q to be rejected", v.timeout, v.timeouts)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/StratoNET/bnb-bookings/internal/database"
+	"github.com/joho/godotenv"
+)
+
+const migrateUsage = "usage: migrate up|down|status"
+
+// runMigrate connects to the database given by the .env file & migrates its schema up or down, or reports its migrations, as args say
+func runMigrate(args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	err := godotenv.Load()
+	if err != nil {
+		return errors.New("error loading .env file")
+	}
+
+	db, err := connectDatabase()
+	if err != nil {
+		return fmt.Errorf("cannot connect to database: %w", err)
+	}
+	defer db.SQL.Close()
+
+	return migrateDatabase(db, args[0], w)
+}
+
+// migrateDatabase applies every pending migration (up), undoes the latest one (down) or lists every migration & whether applied (status)
+func migrateDatabase(db *database.DB, command string, w io.Writer) error {
+	switch command {
+	case "up":
+		done, err := database.MigrateUp(db)
+		for _, m := range done {
+			fmt.Fprintf(w, "applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Fprintln(w, "schema is up to date")
+		}
+	case "down":
+		m, err := database.MigrateDown(db)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			fmt.Fprintln(w, "no migration has been applied")
+			return nil
+		}
+		fmt.Fprintf(w, "undid %04d_%s\n", m.Version, m.Name)
+	case "status":
+		all, err := database.MigrationStatus(db)
+		if err != nil {
+			return err
+		}
+		for _, m := range all {
+			applied := "pending"
+			if !m.AppliedAt.IsZero() {
+				applied = "applied " + m.AppliedAt.Format("02/01/2006 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d_%s\t%s\n", m.Version, m.Name, applied)
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
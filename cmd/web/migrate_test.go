@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/StratoNET/bnb-bookings/internal/database"
+)
+
+func TestMigrateDatabase(t *testing.T) {
+	db, err := database.ConnectSQL(database.SQLite, "file:"+filepath.Join(t.TempDir(), "bnb-bookings.db")+"?_foreign_keys=1")
+	if err != nil {
+		t.Fatal("cannot connect", err)
+	}
+	defer db.SQL.Close()
+
+	var tests = []struct {
+		command  string
+		expected []string
+	}{
+		{"status", []string{"0001_create_tables\tpending", "0002_upgrade_legacy_tables\tpending", "0003_seed_rooms\tpending"}},
+		{"up", []string{"applied 0001_create_tables", "applied 0002_upgrade_legacy_tables", "applied 0003_seed_rooms"}},
+		{"up", []string{"schema is up to date"}},
+		{"down", []string{"undid 0003_seed_rooms"}},
+		{"status", []string{"0001_create_tables\tapplied ", "0002_upgrade_legacy_tables\tapplied ", "0003_seed_rooms\tpending"}},
+		{"down", []string{"undid 0002_upgrade_legacy_tables"}},
+		{"down", []string{"undid 0001_create_tables"}},
+		{"down", []string{"no migration has been applied"}},
+	}
+
+	for _, e := range tests {
+		var out bytes.Buffer
+		err = migrateDatabase(db, e.command, &out)
+		if err != nil {
+			t.Fatalf("migrate %s: %s", e.command, err)
+		}
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != len(e.expected) {
+			t.Fatalf("migrate %s: expected %d lines, got %q", e.command, len(e.expected), out.String())
+		}
+		for i, line := range lines {
+			if !strings.HasPrefix(line, e.expected[i]) {
+				t.Errorf("migrate %s: expected %q, got %q", e.command, e.expected[i], line)
+			}
+		}
+	}
+
+	err = migrateDatabase(db, "sideways", &bytes.Buffer{})
+	if err == nil || err.Error() != migrateUsage {
+		t.Errorf("expected usage for an unknown command, got %v", err)
+	}
+	err = runMigrate(nil, &bytes.Buffer{})
+	if err == nil || err.Error() != migrateUsage {
+		t.Errorf("expected usage without a command, got %v", err)
+	}
+}
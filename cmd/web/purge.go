@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/handlers"
+)
+
+// purgeInterval is how often the trash is checked for items past their retention period
+const purgeInterval = 6 * time.Hour
+
+func purgeListener() {
+	// anonymous, asynchronous function for continuous purging of the trash in background
+	go func() {
+		for {
+			purgeTrash()
+			time.Sleep(purgeInterval)
+		}
+	}()
+}
+
+// purgeTrash permanently removes deleted reservations & owner blocks which have been in the trash longer than the retention period
+func purgeTrash() {
+	before := time.Now().AddDate(0, 0, -app.TrashRetentionDays)
+	purged, err := handlers.Repo.DB.PurgeDeleted(context.Background(), before)
+	if err != nil {
+		errorLog.Println(err)
+		return
+	}
+	if purged > 0 {
+		infoLog.Printf("Purged %d item(s) deleted before %s from the trash\n", purged, before.Format("02/01/2006"))
+		handlers.RequestChannelSync()
+	}
+}
@@ -26,6 +26,7 @@ func routes(app *config.AppConfig) http.Handler {
 	mux.Get("/ms", handlers.Repo.MS)
 	mux.Get("/choose-room/{id}", handlers.Repo.ChooseRoom)
 	mux.Get("/reserve-room", handlers.Repo.ReserveRoom)
+	mux.Post("/choose-rooms", handlers.Repo.PostChooseRooms)
 
 	mux.Get("/search-availability", handlers.Repo.Availability)
 	mux.Post("/search-availability", handlers.Repo.PostAvailability)
@@ -34,9 +35,42 @@ func routes(app *config.AppConfig) http.Handler {
 	mux.Get("/make-reservation", handlers.Repo.Reservation)
 	mux.Post("/make-reservation", handlers.Repo.PostReservation)
 	mux.Get("/reservation-summary", handlers.Repo.ReservationSummary)
+	mux.Get("/make-group-reservation", handlers.Repo.GroupReservation)
+	mux.Post("/make-group-reservation", handlers.Repo.PostGroupReservation)
+	mux.Get("/group-reservation-summary", handlers.Repo.GroupReservationSummary)
+	mux.Get("/waitlist", handlers.Repo.Waitlist)
+	mux.Post("/waitlist", handlers.Repo.PostWaitlist)
+
+	mux.Get("/my-booking/{ref}", handlers.Repo.GuestBooking)
+	mux.Post("/my-booking/{ref}", handlers.Repo.PostGuestBooking)
+	mux.Post("/my-booking/{ref}/cancel", handlers.Repo.GuestBookingCancel)
 
 	mux.Get("/contact", handlers.Repo.Contact)
 
+	// rooms' iCal feeds for other booking sites, the secret token in the URL standing in for a login
+	mux.Get("/ical/{token}.ics", handlers.Repo.ICalFeed)
+
+	// read-only JSON API, versioned so that later changes need not break the website front end or partner sites
+	mux.Get("/api/openapi.json", handlers.Repo.APISpec)
+	mux.Get("/api/docs", handlers.Repo.APIDocs)
+	mux.Route("/api/v1", func(mux chi.Router) {
+		mux.Get("/availability", handlers.Repo.APIAvailability)
+		mux.Get("/rooms/{id}/calendar", handlers.Repo.APIRoomCalendar)
+
+		// the admin API is for administrators' scripts & integrations, so uses API tokens rather than a login session
+		mux.Route("/admin", func(mux chi.Router) {
+			mux.Use(handlers.Repo.APIAuth)
+
+			mux.Get("/reservations", handlers.Repo.APIAdminReservations)
+			mux.Get("/reservations/{id}", handlers.Repo.APIAdminReservation)
+			mux.Put("/reservations/{id}", handlers.Repo.APIAdminUpdateReservation)
+			mux.Delete("/reservations/{id}", handlers.Repo.APIAdminDeleteReservation)
+			mux.Post("/reservations/{id}/status", handlers.Repo.APIAdminReservationStatus)
+			mux.Post("/blocks", handlers.Repo.APIAdminCreateBlock)
+			mux.Delete("/blocks/{id}", handlers.Repo.APIAdminDeleteBlock)
+		})
+	})
+
 	mux.Get("/login", handlers.Repo.Login)
 	mux.Get("/logout", handlers.Repo.Logout)
 	mux.Post("/login", handlers.Repo.PostLogin)
@@ -49,13 +83,52 @@ func routes(app *config.AppConfig) http.Handler {
 		mux.Get("/dashboard", handlers.Repo.AdminDashboard)
 		mux.Get("/reservations-new", handlers.Repo.AdminReservationsNew)
 		mux.Get("/reservations-all", handlers.Repo.AdminReservationsAll)
+		mux.Get("/reservations-export", handlers.Repo.AdminReservationsExport)
+		mux.Get("/reservations-import", handlers.Repo.AdminReservationsImport)
+		mux.Post("/reservations-import", handlers.Repo.AdminPostReservationsImport)
+		mux.Post("/reservations-import/preview", handlers.Repo.AdminReservationsImportPreview)
+		mux.Post("/reservations-import/commit", handlers.Repo.AdminReservationsImportCommit)
 		mux.Get("/reservations-cal", handlers.Repo.AdminReservationsCalendar)
 		mux.Post("/reservations-cal", handlers.Repo.AdminPostReservationsCalendar)
 		// these routes can be reached via either 'all' or 'new' reservations administration pages
-		mux.Get("/reservation-processed/{src}/{id}/page", handlers.Repo.AdminReservationProcess)
+		mux.Get("/reservation-status/{src}/{id}/{status}/page", handlers.Repo.AdminReservationStatus)
 		mux.Get("/reservation-deleted/{src}/{id}/page", handlers.Repo.AdminReservationDelete)
 		mux.Get("/reservations/{src}/{id}/page", handlers.Repo.AdminReservation)
+		mux.Get("/groups", handlers.Repo.AdminBookingGroups)
+		mux.Get("/groups/{id}", handlers.Repo.AdminBookingGroup)
+		mux.Get("/group-status/{id}/{status}", handlers.Repo.AdminBookingGroupStatus)
+		mux.Get("/group-deleted/{id}", handlers.Repo.AdminBookingGroupDelete)
+		mux.Get("/trash", handlers.Repo.AdminTrash)
+		mux.Get("/reservation-restored/{id}", handlers.Repo.AdminReservationRestore)
+		mux.Get("/block-restored/{id}", handlers.Repo.AdminRoomBlockRestore)
 		mux.Post("/reservations/{src}/{id}", handlers.Repo.AdminPostReservation)
+		mux.Get("/rates", handlers.Repo.AdminRates)
+		mux.Post("/rates", handlers.Repo.AdminPostRoomRates)
+		mux.Post("/seasonal-rates", handlers.Repo.AdminPostSeasonalRate)
+		mux.Get("/seasonal-rate-deleted/{id}", handlers.Repo.AdminSeasonalRateDelete)
+		mux.Get("/stay-rules", handlers.Repo.AdminStayRules)
+		mux.Post("/stay-rules", handlers.Repo.AdminPostStayRule)
+		mux.Get("/stay-rule-deleted/{id}", handlers.Repo.AdminStayRuleDelete)
+		mux.Get("/waitlist", handlers.Repo.AdminWaitlist)
+		mux.Get("/waitlist-deleted/{id}", handlers.Repo.AdminWaitlistDelete)
+		mux.Get("/ical-feeds", handlers.Repo.AdminICalFeeds)
+		mux.Post("/ical-feeds", handlers.Repo.AdminPostICalFeed)
+		mux.Get("/ical-feed-deleted/{id}", handlers.Repo.AdminICalFeedDelete)
+		mux.Get("/ical-imports", handlers.Repo.AdminICalImports)
+		mux.Post("/ical-imports", handlers.Repo.AdminPostICalImport)
+		mux.Post("/ical-imports/sync", handlers.Repo.AdminICalImportsSync)
+		mux.Get("/ical-import-deleted/{id}", handlers.Repo.AdminICalImportDelete)
+		mux.Get("/api-tokens", handlers.Repo.AdminAPITokens)
+		mux.Post("/api-tokens", handlers.Repo.AdminPostAPIToken)
+		mux.Get("/api-token-deleted/{id}", handlers.Repo.AdminAPITokenDelete)
+		mux.Get("/webhooks", handlers.Repo.AdminWebhooks)
+		mux.Post("/webhooks", handlers.Repo.AdminPostWebhook)
+		mux.Get("/webhook-deleted/{id}", handlers.Repo.AdminWebhookDelete)
+		mux.Get("/webhook-delivery-retry/{id}", handlers.Repo.AdminWebhookDeliveryRetry)
+		mux.Get("/channel", handlers.Repo.AdminChannel)
+		mux.Post("/channel", handlers.Repo.AdminPostChannelRooms)
+		mux.Post("/channel/sync", handlers.Repo.AdminChannelSync)
+		mux.Post("/channel/resync", handlers.Repo.AdminChannelResync)
 	})
 
 	// creat fileserver for static content
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/handlers"
+)
+
+// webhookInterval is how often due webhook deliveries are attempted
+const webhookInterval = 15 * time.Second
+
+func webhookDispatcher() {
+	// anonymous, asynchronous function for continuous delivery of events to webhooks in background
+	go func() {
+		for {
+			handlers.Repo.DeliverWebhooks(context.Background())
+			time.Sleep(webhookInterval)
+		}
+	}()
+}
@@ -17,3 +17,7 @@ require github.com/xhit/go-simple-mail/v2 v2.10.0
 require golang.org/x/crypto v0.0.0-20211215153901-e495a2d5b3d3
 
 require github.com/joho/godotenv v1.4.0
+
+require github.com/lib/pq v1.10.4
+
+require github.com/mattn/go-sqlite3 v1.14.16
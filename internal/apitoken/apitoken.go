@@ -0,0 +1,46 @@
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// prefix marks a string as one of our tokens, so one committed to source control or pasted into a log is easily recognised
+const prefix = "bnb_"
+
+// tokenBytes gives 256 bits of randomness, so tokens cannot realistically be guessed
+const tokenBytes = 32
+
+// New creates a random API token e.g. bnb_3f9c..., shown to the administrator once & never stored
+func New() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
+
+// Hash is the form in which a token is stored & looked up, so tokens cannot be recovered from the database
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// FromHeader extracts the token from an Authorization header value e.g. "Bearer bnb_3f9c...", returning an empty string if there is none
+func FromHeader(header string) string {
+	scheme, token, found := cut(strings.TrimSpace(header), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// cut slices s around the first instance of sep
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,56 @@
+package apitoken
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		token, err := New()
+		if err != nil {
+			t.Fatal("New failed", err)
+		}
+		if !strings.HasPrefix(token, prefix) || len(token) != len(prefix)+2*tokenBytes {
+			t.Errorf("unexpected token %q", token)
+		}
+		if seen[token] {
+			t.Errorf("token %q was repeated", token)
+		}
+		seen[token] = true
+	}
+}
+
+func TestHash(t *testing.T) {
+	if Hash("bnb_a") != Hash("bnb_a") {
+		t.Error("expected the same token to give the same hash")
+	}
+	if Hash("bnb_a") == Hash("bnb_b") {
+		t.Error("expected different tokens to give different hashes")
+	}
+	if len(Hash("bnb_a")) != 64 {
+		t.Errorf("expected a hex encoded SHA-256 hash, got %q", Hash("bnb_a"))
+	}
+}
+
+var fromHeaderTests = []struct {
+	name     string
+	header   string
+	expected string
+}{
+	{"bearer", "Bearer bnb_abc", "bnb_abc"},
+	{"lower-case-scheme", "bearer bnb_abc", "bnb_abc"},
+	{"extra-space", "  Bearer   bnb_abc ", "bnb_abc"},
+	{"empty", "", ""},
+	{"no-token", "Bearer", ""},
+	{"basic", "Basic dXNlcjpwYXNz", ""},
+}
+
+func TestFromHeader(t *testing.T) {
+	for _, v := range fromHeaderTests {
+		if got := FromHeader(v.header); got != v.expected {
+			t.Errorf("%s: expected %q, got %q", v.name, v.expected, got)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package bookingref
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+)
+
+// refAlphabet leaves out characters easily confused when read aloud or copied by hand (0/O, 1/I/L)
+const refAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// refLength gives just under 50 bits of randomness, so references cannot realistically be guessed
+const refLength = 10
+
+// NewRef creates a random confirmation reference e.g. K7QX2MZP4D, for quoting to guests
+func NewRef() (string, error) {
+	ref := make([]byte, refLength)
+	max := big.NewInt(int64(len(refAlphabet)))
+	for i := range ref {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		ref[i] = refAlphabet[n.Int64()]
+	}
+	return string(ref), nil
+}
+
+// Sign creates an access token for a confirmation reference, only someone holding the key can create a valid token
+func Sign(key []byte, ref string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("my-booking:" + ref))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks an access token was created for a confirmation reference with the given key
+func Verify(key []byte, ref, token string) bool {
+	if ref == "" || token == "" {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(key, ref)), []byte(token))
+}
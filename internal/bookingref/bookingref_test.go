@@ -0,0 +1,51 @@
+package bookingref
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewRef(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		ref, err := NewRef()
+		if err != nil {
+			t.Fatal("NewRef failed", err)
+		}
+		if len(ref) != refLength {
+			t.Errorf("expected reference of %d characters, got %q", refLength, ref)
+		}
+		for _, c := range ref {
+			if !strings.ContainsRune(refAlphabet, c) {
+				t.Errorf("reference %q contains unexpected character %q", ref, c)
+			}
+		}
+		if seen[ref] {
+			t.Errorf("reference %q was repeated", ref)
+		}
+		seen[ref] = true
+	}
+}
+
+var verifyTests = []struct {
+	name     string
+	key      string
+	ref      string
+	token    string
+	expected bool
+}{
+	{"valid", "secret", "K7QX2MZP4D", Sign([]byte("secret"), "K7QX2MZP4D"), true},
+	{"different-key", "other", "K7QX2MZP4D", Sign([]byte("secret"), "K7QX2MZP4D"), false},
+	{"different-ref", "secret", "K7QX2MZP4E", Sign([]byte("secret"), "K7QX2MZP4D"), false},
+	{"tampered-token", "secret", "K7QX2MZP4D", Sign([]byte("secret"), "K7QX2MZP4D") + "x", false},
+	{"missing-token", "secret", "K7QX2MZP4D", "", false},
+	{"missing-ref", "secret", "", Sign([]byte("secret"), ""), false},
+}
+
+func TestVerify(t *testing.T) {
+	for _, v := range verifyTests {
+		if Verify([]byte(v.key), v.ref, v.token) != v.expected {
+			t.Errorf("%s: expected Verify to return %t", v.name, v.expected)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package channel
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Namespace is the OpenTravel namespace of every message
+const Namespace = "http://www.opentravel.org/OTA/2003/05"
+
+// messages pushed to the channel manager, as named in the sync log
+const (
+	AvailNotif      = "OTA_HotelAvailNotifRQ"
+	RateAmountNotif = "OTA_HotelRateAmountNotifRQ"
+)
+
+// otaDate is the date format of the Start & End of a message's StatusApplicationControl e.g. 2026-11-01
+const otaDate = "2006-01-02"
+
+// MaxAttempts is how many times a message is posted before it is given up as failed, until the next sync
+const MaxAttempts = 3
+
+// firstRetry is the delay before posting a message again, which doubles with each attempt
+const firstRetry = 2 * time.Second
+
+// maxResponseBytes is the most of a response which is read, a channel manager's response being short
+const maxResponseBytes = 1 << 20
+
+// Config is the channel manager's endpoint, together with the codes & credentials identifying the B&B to it
+type Config struct {
+	URL       string
+	HotelCode string
+	Username  string
+	Password  string
+}
+
+// Availability opens or closes a room for sale for the nights from Start to End inclusive
+type Availability struct {
+	RoomCode string
+	Start    time.Time
+	End      time.Time
+	Open     bool
+}
+
+// Rate sets a room's nightly rate, in pence, for the nights from Start to End inclusive
+type Rate struct {
+	RoomCode     string
+	RatePlanCode string
+	Start        time.Time
+	End          time.Time
+	Amount       int
+	Currency     string
+	Guests       int // the number of guests the rate is for, zero if not known
+}
+
+// Error is a problem reported by the channel manager within its response. It means the message was received but rejected, so
+// posting the same message again would not put it right
+type Error struct {
+	Type      string `xml:"Type,attr"`
+	Code      string `xml:"Code,attr"`
+	ShortText string `xml:"ShortText,attr"`
+	Text      string `xml:",chardata"`
+}
+
+func (e *Error) Error() string {
+	msg := strings.TrimSpace(e.Text)
+	if msg == "" {
+		msg = e.ShortText
+	}
+	if e.Code != "" {
+		return fmt.Sprintf("channel manager error %s: %s", e.Code, msg)
+	}
+	return "channel manager error: " + msg
+}
+
+// message elements shared by both requests
+
+type pos struct {
+	RequestorID requestorID `xml:"Source>RequestorID"`
+}
+
+type requestorID struct {
+	ID              string `xml:"ID,attr"`
+	MessagePassword string `xml:"MessagePassword,attr,omitempty"`
+}
+
+type statusApplicationControl struct {
+	Start        string `xml:"Start,attr"`
+	End          string `xml:"End,attr"`
+	InvTypeCode  string `xml:"InvTypeCode,attr"`
+	RatePlanCode string `xml:"RatePlanCode,attr,omitempty"`
+}
+
+type availNotifRQ struct {
+	XMLName   xml.Name            `xml:"http://www.opentravel.org/OTA/2003/05 OTA_HotelAvailNotifRQ"`
+	EchoToken string              `xml:"EchoToken,attr"`
+	TimeStamp string              `xml:"TimeStamp,attr"`
+	Version   string              `xml:"Version,attr"`
+	POS       *pos                `xml:"POS,omitempty"`
+	Messages  availStatusMessages `xml:"AvailStatusMessages"`
+}
+
+type availStatusMessages struct {
+	HotelCode string               `xml:"HotelCode,attr"`
+	Messages  []availStatusMessage `xml:"AvailStatusMessage"`
+}
+
+type availStatusMessage struct {
+	BookingLimit int                      `xml:"BookingLimit,attr"`
+	Control      statusApplicationControl `xml:"StatusApplicationControl"`
+	Restriction  restrictionStatus        `xml:"RestrictionStatus"`
+}
+
+type restrictionStatus struct {
+	Status string `xml:"Status,attr"`
+}
+
+type rateAmountNotifRQ struct {
+	XMLName   xml.Name           `xml:"http://www.opentravel.org/OTA/2003/05 OTA_HotelRateAmountNotifRQ"`
+	EchoToken string             `xml:"EchoToken,attr"`
+	TimeStamp string             `xml:"TimeStamp,attr"`
+	Version   string             `xml:"Version,attr"`
+	POS       *pos               `xml:"POS,omitempty"`
+	Messages  rateAmountMessages `xml:"RateAmountMessages"`
+}
+
+type rateAmountMessages struct {
+	HotelCode string              `xml:"HotelCode,attr"`
+	Messages  []rateAmountMessage `xml:"RateAmountMessage"`
+}
+
+type rateAmountMessage struct {
+	Control statusApplicationControl `xml:"StatusApplicationControl"`
+	Amounts []baseByGuestAmt         `xml:"Rates>Rate>BaseByGuestAmts>BaseByGuestAmt"`
+}
+
+type baseByGuestAmt struct {
+	AmountAfterTax string `xml:"AmountAfterTax,attr"`
+	CurrencyCode   string `xml:"CurrencyCode,attr"`
+	NumberOfGuests int    `xml:"NumberOfGuests,attr,omitempty"`
+}
+
+// response is either message's response e.g. OTA_HotelAvailNotifRS, holding Success or Errors
+type response struct {
+	XMLName xml.Name
+	Success *struct{} `xml:"Success"`
+	Errors  []Error   `xml:"Errors>Error"`
+}
+
+// header fills in the attributes & POS common to every request
+func (c Config) header(now time.Time) (echoToken, timeStamp string, p *pos, err error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", nil, err
+	}
+	if c.Username != "" {
+		p = &pos{RequestorID: requestorID{ID: c.Username, MessagePassword: c.Password}}
+	}
+	return hex.EncodeToString(b), now.UTC().Format(time.RFC3339), p, nil
+}
+
+// NewAvailNotif builds an OTA_HotelAvailNotifRQ opening or closing each room for its nights. Every room is a single unit, so an
+// open room has a booking limit of 1 & a closed room 0
+func NewAvailNotif(c Config, now time.Time, avail []Availability) ([]byte, error) {
+	token, ts, p, err := c.header(now)
+	if err != nil {
+		return nil, err
+	}
+	rq := availNotifRQ{EchoToken: token, TimeStamp: ts, Version: "1.0", POS: p, Messages: availStatusMessages{HotelCode: c.HotelCode}}
+	for _, a := range avail {
+		msg := availStatusMessage{
+			Control:     statusApplicationControl{Start: a.Start.Format(otaDate), End: a.End.Format(otaDate), InvTypeCode: a.RoomCode},
+			Restriction: restrictionStatus{Status: "Close"},
+		}
+		if a.Open {
+			msg.BookingLimit = 1
+			msg.Restriction.Status = "Open"
+		}
+		rq.Messages.Messages = append(rq.Messages.Messages, msg)
+	}
+	return marshal(rq)
+}
+
+// NewRateAmountNotif builds an OTA_HotelRateAmountNotifRQ setting each room's nightly rate for its nights
+func NewRateAmountNotif(c Config, now time.Time, rates []Rate) ([]byte, error) {
+	token, ts, p, err := c.header(now)
+	if err != nil {
+		return nil, err
+	}
+	rq := rateAmountNotifRQ{EchoToken: token, TimeStamp: ts, Version: "1.0", POS: p, Messages: rateAmountMessages{HotelCode: c.HotelCode}}
+	for _, r := range rates {
+		rq.Messages.Messages = append(rq.Messages.Messages, rateAmountMessage{
+			Control: statusApplicationControl{Start: r.Start.Format(otaDate), End: r.End.Format(otaDate), InvTypeCode: r.RoomCode, RatePlanCode: r.RatePlanCode},
+			Amounts: []baseByGuestAmt{{AmountAfterTax: formatAmount(r.Amount), CurrencyCode: r.Currency, NumberOfGuests: r.Guests}},
+		})
+	}
+	return marshal(rq)
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	out, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// formatAmount gives an amount in pence as a decimal e.g. 8050 as 80.50
+func formatAmount(pence int) string {
+	return fmt.Sprintf("%d.%02d", pence/100, pence%100)
+}
+
+// Backoff is how long to wait before posting a message again, after it has failed a given number of times
+func Backoff(attempts int) time.Duration {
+	d := firstRetry
+	for i := 1; i < attempts; i++ {
+		d *= 2
+	}
+	return d
+}
+
+// Result is the outcome of pushing a message
+type Result struct {
+	Attempts     int
+	ResponseCode int // of the last attempt, zero if there was no response
+}
+
+// Client pushes messages to a channel manager's endpoint
+type Client struct {
+	HTTP *http.Client
+	URL  string
+	// Wait is called between attempts, given how many have failed so far, which tests may replace so as not to wait
+	Wait func(attempts int)
+}
+
+// Push posts a message, retrying up to MaxAttempts times should the channel manager be unreachable or fail with a 5xx (or 429)
+// status. A message the channel manager rejects, with a 4xx status or errors within its response, is not retried
+func (c *Client) Push(body []byte) (Result, error) {
+	var res Result
+	for {
+		res.Attempts++
+		code, err := c.post(body)
+		res.ResponseCode = code
+		if err == nil || !retryable(code, err) || res.Attempts >= MaxAttempts {
+			return res, err
+		}
+		if c.Wait != nil {
+			c.Wait(res.Attempts)
+		} else {
+			time.Sleep(Backoff(res.Attempts))
+		}
+	}
+}
+
+func retryable(code int, err error) bool {
+	var rejected *Error
+	if errors.As(err, &rejected) {
+		return false
+	}
+	return code == 0 || code == http.StatusTooManyRequests || code >= 500
+}
+
+// post makes a single attempt at posting a message, returning the response's status code. Any response other than 2xx, or one
+// which does not report success, is an error
+func (c *Client) post(body []byte) (int, error) {
+	req, err := http.NewRequest("POST", c.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("channel manager returned %s", resp.Status)
+	}
+
+	var rs response
+	err = xml.Unmarshal(b, &rs)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("channel manager response cannot be read: %w", err)
+	}
+	if len(rs.Errors) > 0 {
+		return resp.StatusCode, &rs.Errors[0]
+	}
+	if rs.Success == nil {
+		return resp.StatusCode, fmt.Errorf("channel manager response %s does not report success", rs.XMLName.Local)
+	}
+	return resp.StatusCode, nil
+}
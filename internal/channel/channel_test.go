@@ -0,0 +1,176 @@
+package channel
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testConfig = Config{HotelCode: "EDEN", Username: "bnb", Password: "secret"}
+
+func TestNewAvailNotif(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+	body, err := NewAvailNotif(testConfig, now, []Availability{
+		{RoomCode: "GQ", Start: time.Date(2098, 3, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2098, 3, 9, 0, 0, 0, 0, time.UTC), Open: true},
+		{RoomCode: "GQ", Start: time.Date(2098, 3, 10, 0, 0, 0, 0, time.UTC), End: time.Date(2098, 3, 13, 0, 0, 0, 0, time.UTC)},
+	})
+	if err != nil {
+		t.Fatal("NewAvailNotif failed", err)
+	}
+
+	for _, s := range []string{
+		`<OTA_HotelAvailNotifRQ xmlns="` + Namespace + `"`,
+		`TimeStamp="2026-10-18T09:30:00Z" Version="1.0"`,
+		`<RequestorID ID="bnb" MessagePassword="secret">`,
+		`<AvailStatusMessages HotelCode="EDEN">`,
+		`<AvailStatusMessage BookingLimit="1">`,
+		`<StatusApplicationControl Start="2098-03-01" End="2098-03-09" InvTypeCode="GQ">`,
+		`<RestrictionStatus Status="Open">`,
+		`<AvailStatusMessage BookingLimit="0">`,
+		`<StatusApplicationControl Start="2098-03-10" End="2098-03-13" InvTypeCode="GQ">`,
+		`<RestrictionStatus Status="Close">`,
+	} {
+		if !strings.Contains(string(body), s) {
+			t.Errorf("expected message to contain %s, got\n%s", s, body)
+		}
+	}
+
+	// the message must be well formed
+	var rq availNotifRQ
+	if err := xml.Unmarshal(body, &rq); err != nil || len(rq.Messages.Messages) != 2 || rq.EchoToken == "" {
+		t.Errorf("cannot read back message: %v %+v", err, rq)
+	}
+}
+
+func TestNewRateAmountNotif(t *testing.T) {
+	body, err := NewRateAmountNotif(Config{HotelCode: "EDEN"}, time.Now(), []Rate{
+		{RoomCode: "GQ", RatePlanCode: "BAR", Start: time.Date(2098, 3, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2098, 3, 5, 0, 0, 0, 0, time.UTC), Amount: 8050, Currency: "GBP", Guests: 2},
+	})
+	if err != nil {
+		t.Fatal("NewRateAmountNotif failed", err)
+	}
+
+	for _, s := range []string{
+		`<OTA_HotelRateAmountNotifRQ xmlns="` + Namespace + `"`,
+		`<RateAmountMessages HotelCode="EDEN">`,
+		`<StatusApplicationControl Start="2098-03-01" End="2098-03-05" InvTypeCode="GQ" RatePlanCode="BAR">`,
+		`<BaseByGuestAmt AmountAfterTax="80.50" CurrencyCode="GBP" NumberOfGuests="2">`,
+	} {
+		if !strings.Contains(string(body), s) {
+			t.Errorf("expected message to contain %s, got\n%s", s, body)
+		}
+	}
+	// without a username there are no credentials to give
+	if strings.Contains(string(body), "<POS>") {
+		t.Errorf("expected no POS without a username, got\n%s", body)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	expected := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second}
+	for i, d := range expected {
+		if Backoff(i+1) != d {
+			t.Errorf("attempt %d: expected %s, got %s", i+1, d, Backoff(i+1))
+		}
+	}
+}
+
+const (
+	successRS = `<?xml version="1.0" encoding="UTF-8"?><OTA_HotelAvailNotifRS xmlns="http://www.opentravel.org/OTA/2003/05" Version="1.0"><Success/></OTA_HotelAvailNotifRS>`
+	errorsRS  = `<OTA_HotelAvailNotifRS xmlns="http://www.opentravel.org/OTA/2003/05"><Errors><Error Type="3" Code="392">Invalid hotel code</Error></Errors></OTA_HotelAvailNotifRS>`
+)
+
+// stubChannel is a local stand in for a channel manager, failing the first failures requests to each path with a 503
+func stubChannel(failures int) (*httptest.Server, map[string]int, *string) {
+	attempts := make(map[string]int)
+	var received string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received = string(b)
+		attempts[r.URL.Path]++
+		switch {
+		case attempts[r.URL.Path] <= failures:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/rejects":
+			w.Write([]byte(errorsRS))
+		case r.URL.Path == "/unauthorised":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/html":
+			w.Write([]byte("<html><body>Maintenance</body></html>"))
+		default:
+			w.Write([]byte(successRS))
+		}
+	}))
+	return srv, attempts, &received
+}
+
+func TestClient_Push(t *testing.T) {
+	srv, attempts, received := stubChannel(0)
+	defer srv.Close()
+
+	body, _ := NewAvailNotif(testConfig, time.Now(), []Availability{{RoomCode: "GQ", Start: time.Now(), End: time.Now(), Open: true}})
+	c := &Client{HTTP: srv.Client(), URL: srv.URL + "/ota", Wait: func(int) {}}
+	res, err := c.Push(body)
+	if err != nil || res.Attempts != 1 || res.ResponseCode != http.StatusOK {
+		t.Errorf("expected push to succeed at once, got %+v %v", res, err)
+	}
+	if *received != string(body) {
+		t.Errorf("channel manager received %q, expected the message", *received)
+	}
+
+	var tests = []struct {
+		path             string
+		expectedAttempts int
+		expectedCode     int
+		expectedError    string
+	}{
+		{"/rejects", 1, http.StatusOK, "channel manager error 392: Invalid hotel code"},
+		{"/unauthorised", 1, http.StatusUnauthorized, "401"},
+		{"/html", 1, http.StatusOK, "does not report success"},
+	}
+	for _, v := range tests {
+		c.URL = srv.URL + v.path
+		res, err := c.Push(body)
+		if err == nil || !strings.Contains(err.Error(), v.expectedError) {
+			t.Errorf("%s: expected error containing %q, got %v", v.path, v.expectedError, err)
+		}
+		if res.Attempts != v.expectedAttempts || res.ResponseCode != v.expectedCode || attempts[v.path] != v.expectedAttempts {
+			t.Errorf("%s: expected %d attempts ending %d, got %+v", v.path, v.expectedAttempts, v.expectedCode, res)
+		}
+	}
+}
+
+func TestClient_Push_Retries(t *testing.T) {
+	srv, attempts, _ := stubChannel(MaxAttempts - 1)
+	defer srv.Close()
+
+	var waits []int
+	c := &Client{HTTP: srv.Client(), URL: srv.URL + "/ota", Wait: func(n int) { waits = append(waits, n) }}
+	res, err := c.Push([]byte("<OTA_HotelAvailNotifRQ/>"))
+	if err != nil || res.Attempts != MaxAttempts || attempts["/ota"] != MaxAttempts {
+		t.Errorf("expected push to succeed on attempt %d, got %+v %v", MaxAttempts, res, err)
+	}
+	if len(waits) != MaxAttempts-1 || waits[0] != 1 {
+		t.Errorf("expected a wait before each retry, got %v", waits)
+	}
+
+	// a channel manager which keeps failing is given up on
+	srv, attempts, _ = stubChannel(MaxAttempts)
+	defer srv.Close()
+	c.URL = srv.URL + "/ota"
+	res, err = c.Push([]byte("<OTA_HotelAvailNotifRQ/>"))
+	if err == nil || res.Attempts != MaxAttempts || res.ResponseCode != http.StatusServiceUnavailable || attempts["/ota"] != MaxAttempts {
+		t.Errorf("expected push to fail after %d attempts, got %+v %v", MaxAttempts, res, err)
+	}
+
+	// as is one which cannot be reached
+	c.URL = "http://127.0.0.1:0/ota"
+	res, err = c.Push([]byte("<OTA_HotelAvailNotifRQ/>"))
+	if err == nil || res.Attempts != MaxAttempts || res.ResponseCode != 0 {
+		t.Errorf("expected an unreachable channel manager to fail after %d attempts, got %+v %v", MaxAttempts, res, err)
+	}
+}
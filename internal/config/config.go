@@ -3,7 +3,9 @@ package config
 import (
 	"html/template"
 	"log"
+	"time"
 
+	"github.com/StratoNET/bnb-bookings/internal/channel"
 	"github.com/StratoNET/bnb-bookings/internal/models"
 	"github.com/alexedwards/scs/v2"
 )
@@ -17,4 +19,62 @@ type AppConfig struct {
 	ErrorLog       *log.Logger
 	ProductionMode bool
 	MailChannel    chan models.MailData
+	// SiteURL is the public address of the site e.g. https://www.example.com, used to build links in emails
+	SiteURL string
+	// BookingKey signs the access tokens which let guests manage their own bookings
+	BookingKey []byte
+	// CancellationDays is the minimum notice, in days before arrival, a guest must give to cancel online
+	CancellationDays int
+	// TrashRetentionDays is how long deleted reservations & owner blocks stay in the trash before being purged
+	TrashRetentionDays int
+	// HoldMinutes is how long a room chosen by a guest is held for them while they complete their reservation
+	HoldMinutes int
+	// ICalImportMinutes is how often calendars of bookings taken on other sites are imported
+	ICalImportMinutes int
+	// Channel is the channel manager to which availability & rates are pushed, nothing is pushed when its URL is empty
+	Channel channel.Config
+	// ChannelSyncMinutes is how often availability & rates are checked for changes to push, besides straight after a change is made
+	ChannelSyncMinutes int
+	// ChannelDays is how many days ahead availability & rates are pushed to the channel manager
+	ChannelDays int
+	// DBTimeouts limits how long each database operation may take
+	DBTimeouts DBTimeouts
+}
+
+// DefaultDBTimeout limits any database operation given no timeout of its own
+const DefaultDBTimeout = 3 * time.Second
+
+// defaultDBTimeouts allow longer for operations which may handle many rows
+var defaultDBTimeouts = map[string]time.Duration{
+	// an import may hold years of reservations
+	"ImportReservations": 30 * time.Second,
+	// rows are read only as quickly as the caller, often writing to a client, takes them
+	"StreamReservations": 60 * time.Second,
+	// a full year of nights may be saved
+	"SaveChannelDays": 10 * time.Second,
+}
+
+// DBTimeouts limits how long database operations may take, so that a slow database cannot hold up requests indefinitely
+type DBTimeouts struct {
+	// Default limits any operation not given a timeout of its own, DefaultDBTimeout unless set
+	Default time.Duration
+	// Operations gives operations, by DatabaseRepository method name, timeouts of their own
+	Operations map[string]time.Duration
+}
+
+// For returns the timeout of a database operation, given by DatabaseRepository method name
+func (t DBTimeouts) For(op string) time.Duration {
+	if d := t.Operations[op]; d > 0 {
+		return d
+	}
+
+	d := t.Default
+	if d <= 0 {
+		d = DefaultDBTimeout
+	}
+	// operations which may handle many rows are allowed longer, though never less than the default
+	if long := defaultDBTimeouts[op]; long > d {
+		return long
+	}
+	return d
 }
@@ -5,11 +5,21 @@ import (
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 )
 
-// DB holds whichever database is employed (e.g. MariaDB, Postgres etc) connection pool
+// drivers of the databases which may be employed, as named by DB_DRIVER
+const (
+	MariaDB  = "mysql"
+	Postgres = "postgres"
+	SQLite   = "sqlite3"
+)
+
+// DB holds whichever database is employed (e.g. MariaDB, Postgres, SQLite etc) connection pool, with the driver it is connected by
 type DB struct {
-	SQL *sql.DB
+	SQL    *sql.DB
+	Driver string
 }
 
 var dbConn = &DB{}
@@ -18,9 +28,9 @@ const maxOpenDBConn = 10
 const maxIdleDBConn = 10
 const maxDBLifetime = 3 * time.Minute
 
-// ConnectSQL creates pool for currently used database (MariaDB/MySQL)
-func ConnectSQL(dsn string) (*DB, error) {
-	dp, err := NewDatabase(dsn)
+// ConnectSQL creates pool for currently used database (MariaDB/MySQL, Postgres or SQLite) by its driver
+func ConnectSQL(driver, dsn string) (*DB, error) {
+	dp, err := NewDatabase(driver, dsn)
 	if err != nil {
 		panic(err)
 	}
@@ -30,6 +40,7 @@ func ConnectSQL(dsn string) (*DB, error) {
 	dp.SetConnMaxLifetime(maxDBLifetime)
 
 	dbConn.SQL = dp
+	dbConn.Driver = driver
 
 	err = testDB(dp)
 	if err != nil {
@@ -40,8 +51,8 @@ func ConnectSQL(dsn string) (*DB, error) {
 }
 
 // NewDatabase creates a new database for the application
-func NewDatabase(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("mysql", dsn)
+func NewDatabase(driver, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	for _, driver := range []string{MariaDB, Postgres, SQLite} {
+		all, err := Migrations(driver)
+		if err != nil {
+			t.Fatal(driver, err)
+		}
+		for i, m := range all {
+			if m.Version != i+1 {
+				t.Errorf("%s: expected migration %d, got %04d_%s", driver, i+1, m.Version, m.Name)
+			}
+			if len(sqlStatements(m.Up)) == 0 || len(sqlStatements(m.Down)) == 0 {
+				t.Errorf("%s: migration %04d_%s has no statements", driver, m.Version, m.Name)
+			}
+		}
+		if len(all) == 0 {
+			t.Errorf("%s: expected migrations", driver)
+		}
+	}
+
+	if _, err := Migrations("oracle"); err == nil {
+		t.Error("expected no migrations for an unsupported driver")
+	}
+}
+
+func TestMigrate_SQLite(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "bnb-bookings.db") + "?_foreign_keys=1&_busy_timeout=5000&_txlock=immediate"
+	db, err := ConnectSQL(SQLite, dsn)
+	if err != nil {
+		t.Fatal("cannot connect", err)
+	}
+	defer db.SQL.Close()
+
+	all, err := Migrations(SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a new database is behind by every migration
+	err = CheckSchema(db)
+	if !errors.Is(err, ErrSchemaBehind) {
+		t.Errorf("expected ErrSchemaBehind for a new database, got %v", err)
+	}
+
+	done, err := MigrateUp(db)
+	if err != nil {
+		t.Fatal("cannot migrate up", err)
+	}
+	if len(done) != len(all) {
+		t.Errorf("expected %d migrations to be applied, got %d", len(all), len(done))
+	}
+	if err = CheckSchema(db); err != nil {
+		t.Error("expected schema to be up to date", err)
+	}
+
+	// migrating again applies nothing
+	done, err = MigrateUp(db)
+	if err != nil || len(done) != 0 {
+		t.Errorf("expected nothing to be applied again, got %d, %v", len(done), err)
+	}
+
+	var rooms []string
+	rows, err := db.SQL.Query("SELECT room_name FROM rooms ORDER BY id;")
+	if err != nil {
+		t.Fatal("cannot query rooms", err)
+	}
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		rooms = append(rooms, name)
+	}
+	rows.Close()
+	if len(rooms) != 2 || rooms[0] != "General's Quarters" || rooms[1] != "Major's Suite" {
+		t.Errorf("expected the two rooms, got %v", rooms)
+	}
+
+	var restrictions int
+	err = db.SQL.QueryRow("SELECT COUNT(id) FROM restrictions;").Scan(&restrictions)
+	if err != nil {
+		t.Fatal("cannot count restrictions", err)
+	}
+	if restrictions != 4 {
+		t.Errorf("expected 4 restrictions, got %d", restrictions)
+	}
+
+	// the latest migration is undone, leaving the schema behind by it alone
+	m, err := MigrateDown(db)
+	if err != nil {
+		t.Fatal("cannot migrate down", err)
+	}
+	if m == nil || m.Version != all[len(all)-1].Version {
+		t.Fatalf("expected migration %d to be undone, got %v", all[len(all)-1].Version, m)
+	}
+	status, err := MigrationStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if s.AppliedAt.IsZero() != (s.Version == m.Version) {
+			t.Errorf("migration %04d_%s: unexpected applied at %v", s.Version, s.Name, s.AppliedAt)
+		}
+	}
+	if err = CheckSchema(db); !errors.Is(err, ErrSchemaBehind) {
+		t.Errorf("expected ErrSchemaBehind, got %v", err)
+	}
+
+	// every migration is undone, leaving no tables but schema_migrations
+	for {
+		m, err = MigrateDown(db)
+		if err != nil {
+			t.Fatal("cannot migrate down", err)
+		}
+		if m == nil {
+			break
+		}
+	}
+	var tables int
+	err = db.SQL.QueryRow("SELECT COUNT(name) FROM sqlite_master WHERE type = 'table' AND name NOT IN ('schema_migrations', 'sqlite_sequence');").Scan(&tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Errorf("expected no tables once every migration is undone, got %d", tables)
+	}
+}
+
+func TestBind(t *testing.T) {
+	stmt := "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?);"
+	if got := Bind(MariaDB, stmt); got != stmt {
+		t.Errorf("expected MariaDB statement unchanged, got %q", got)
+	}
+	if got := Bind(Postgres, stmt); got != "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3);" {
+		t.Errorf("unexpected Postgres statement %q", got)
+	}
+}
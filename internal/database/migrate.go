@@ -0,0 +1,268 @@
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrations hold the versioned changes to the schema of each database, in a directory named by its driver, each as a pair of files
+// e.g. 0001_create_tables.up.sql & 0001_create_tables.down.sql, one making the change & the other undoing it
+//go:embed migrations
+var migrations embed.FS
+
+// ErrSchemaBehind is returned when a database has not had every migration applied to it
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// schemaMigrations records which migrations have been applied to a database, created with whichever timestamp type the database has
+var schemaMigrations = map[string]string{
+	MariaDB:  `CREATE TABLE IF NOT EXISTS schema_migrations (version INT PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at DATETIME NOT NULL) ENGINE=InnoDB;`,
+	Postgres: `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMPTZ NOT NULL);`,
+	SQLite:   `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at DATETIME NOT NULL);`,
+}
+
+// Migration is a versioned change to the schema, which has been applied to a database if AppliedAt is set
+type Migration struct {
+	Version   int
+	Name      string
+	Up        string
+	Down      string
+	AppliedAt time.Time
+}
+
+// Migrations returns every migration of a driver's databases, in order of version
+func Migrations(driver string) ([]Migration, error) {
+	dir := path.Join("migrations", driver)
+	entries, err := fs.ReadDir(migrations, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for driver %q", driver)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var up bool
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			up = true
+			name = strings.TrimSuffix(name, ".up.sql")
+		case strings.HasSuffix(name, ".down.sql"):
+			name = strings.TrimSuffix(name, ".down.sql")
+		default:
+			continue
+		}
+
+		i := strings.Index(name, "_")
+		if i < 0 {
+			return nil, fmt.Errorf("migration %s is not named as version_name", entry.Name())
+		}
+		version, err := strconv.Atoi(name[:i])
+		if err != nil {
+			return nil, fmt.Errorf("migration %s is not numbered: %w", entry.Name(), err)
+		}
+
+		b, err := migrations.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name[i+1:]}
+			byVersion[version] = m
+		}
+		if up {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	var all []Migration
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have both an up & a down file", m.Version, m.Name)
+		}
+		all = append(all, *m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+
+	return all, nil
+}
+
+// MigrationStatus returns every migration of a database's driver, with when each was applied, if it has been
+func MigrationStatus(db *DB) ([]Migration, error) {
+	all, err := Migrations(db.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.SQL.Exec(schemaMigrations[db.Driver])
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.SQL.Query("SELECT version, applied_at FROM schema_migrations;")
+	if err != nil {
+		return nil, err
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		err = rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range all {
+		all[i].AppliedAt = applied[all[i].Version]
+	}
+
+	return all, nil
+}
+
+// MigrateUp applies, in order, every migration not yet applied to a database, returning those it applied
+func MigrateUp(db *DB) ([]Migration, error) {
+	all, err := MigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range all {
+		if !m.AppliedAt.IsZero() {
+			continue
+		}
+		m.AppliedAt = time.Now().UTC()
+		err = migrate(db, m.Up, Bind(db.Driver, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?);"), m.Version, m.Name, m.AppliedAt)
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+
+	return done, nil
+}
+
+// MigrateDown undoes the latest migration applied to a database, returning it, or nil if none has been applied
+func MigrateDown(db *DB) (*Migration, error) {
+	all, err := MigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(all) - 1; i >= 0; i-- {
+		m := all[i]
+		if m.AppliedAt.IsZero() {
+			continue
+		}
+		err = migrate(db, m.Down, Bind(db.Driver, "DELETE FROM schema_migrations WHERE version = ?;"), m.Version)
+		if err != nil {
+			return nil, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		m.AppliedAt = time.Time{}
+		return &m, nil
+	}
+
+	return nil, nil
+}
+
+// CheckSchema returns ErrSchemaBehind if any migration has not been applied to a database
+func CheckSchema(db *DB) error {
+	all, err := MigrationStatus(db)
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, m := range all {
+		if m.AppliedAt.IsZero() {
+			pending = append(pending, fmt.Sprintf("%04d_%s", m.Version, m.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w, %s not applied", ErrSchemaBehind, strings.Join(pending, ", "))
+	}
+
+	return nil
+}
+
+// migrate executes the statements of one direction of a migration, & records it in schema_migrations, in one transaction, though MariaDB
+// commits each statement which changes a table as it executes it, so these statements must be repeatable should a later one fail
+func migrate(db *DB, statements, record string, args ...interface{}) error {
+	tx, err := db.SQL.Begin()
+	if err != nil {
+		return err
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	for _, stmt := range sqlStatements(statements) {
+		_, err = tx.Exec(stmt)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(record, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Bind gives a statement written with ? placeholders those of a database's driver, being $1, $2 etc for Postgres, so the statement
+// must have no ? other than its placeholders
+func Bind(driver, stmt string) string {
+	if driver != Postgres {
+		return stmt
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range stmt {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+// sqlStatements splits a migration into its statements, each ending with a semicolon at the end of a line, leaving out comments, as
+// MariaDB will execute only one statement at a time
+func sqlStatements(s string) []string {
+	var stmts []string
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		lines = append(lines, line)
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			stmts = append(stmts, strings.TrimSpace(strings.Join(lines, "\n")))
+			lines = nil
+		}
+	}
+
+	return stmts
+}
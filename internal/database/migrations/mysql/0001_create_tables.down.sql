@@ -0,0 +1,16 @@
+-- drops the tables of the schema added since migrations were versioned, in the reverse of the order they were created, keeping
+-- administrators, rooms, restrictions, reservations & room_restrictions, which a database set up before then already had
+
+DROP TABLE IF EXISTS channel_syncs;
+DROP TABLE IF EXISTS channel_days;
+DROP TABLE IF EXISTS channel_rooms;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS ical_feeds;
+DROP TABLE IF EXISTS stay_rules;
+DROP TABLE IF EXISTS seasonal_rates;
+DROP TABLE IF EXISTS waitlist;
+DROP TABLE IF EXISTS ical_imports;
+DROP TABLE IF EXISTS reservation_status_changes;
+DROP TABLE IF EXISTS booking_groups;
+DROP TABLE IF EXISTS api_tokens;
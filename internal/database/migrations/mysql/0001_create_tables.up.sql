@@ -0,0 +1,204 @@
+-- tables of the schema, & the categories of restriction, created only where missing so that a database set up before migrations were
+-- versioned keeps its tables; rooms, reservations & room_restrictions are created as they were then, for 0002 to bring up to date
+
+CREATE TABLE IF NOT EXISTS administrators (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	first_name VARCHAR(255) NOT NULL DEFAULT '',
+	last_name VARCHAR(255) NOT NULL DEFAULT '',
+	email VARCHAR(255) NOT NULL UNIQUE,
+	password VARCHAR(60) NOT NULL,
+	access_level INT NOT NULL DEFAULT 1,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	administrator_id INT NOT NULL REFERENCES administrators (id),
+	name VARCHAR(255) NOT NULL,
+	token_hash VARCHAR(64) NOT NULL UNIQUE,
+	last_used_at DATETIME NULL,
+	created_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS rooms (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	room_name VARCHAR(255) NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS restrictions (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	restriction_name VARCHAR(255) NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+INSERT IGNORE INTO restrictions (id, restriction_name, created_at, updated_at) VALUES
+	(1, 'Reservation', NOW(), NOW()),
+	(2, 'Owner Block', NOW(), NOW()),
+	(3, 'Hold', NOW(), NOW()),
+	(4, 'External Booking', NOW(), NOW());
+
+CREATE TABLE IF NOT EXISTS booking_groups (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	confirmation_ref VARCHAR(20) NOT NULL,
+	first_name VARCHAR(255) NOT NULL,
+	last_name VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL,
+	phone VARCHAR(255) NOT NULL DEFAULT '',
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS reservations (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	room_id INT NOT NULL REFERENCES rooms (id),
+	first_name VARCHAR(255) NOT NULL,
+	last_name VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL,
+	phone VARCHAR(255) NOT NULL DEFAULT '',
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	processed TINYINT NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS reservation_status_changes (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	reservation_id INT NOT NULL REFERENCES reservations (id),
+	from_status VARCHAR(20) NOT NULL,
+	to_status VARCHAR(20) NOT NULL,
+	created_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS ical_imports (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	room_id INT NOT NULL REFERENCES rooms (id),
+	name VARCHAR(255) NOT NULL,
+	url TEXT NOT NULL,
+	last_synced_at DATETIME NULL,
+	last_error TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS room_restrictions (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	room_id INT NOT NULL REFERENCES rooms (id),
+	reservation_id INT NULL REFERENCES reservations (id),
+	restriction_id INT NOT NULL REFERENCES restrictions (id),
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS waitlist (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	first_name VARCHAR(255) NOT NULL,
+	last_name VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL,
+	phone VARCHAR(255) NOT NULL DEFAULT '',
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	adults INT NOT NULL DEFAULT 1,
+	children INT NOT NULL DEFAULT 0,
+	infants INT NOT NULL DEFAULT 0,
+	notified_at DATETIME NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS seasonal_rates (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	room_id INT NOT NULL REFERENCES rooms (id),
+	season_name VARCHAR(255) NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	nightly_rate INT NOT NULL,
+	weekend_rate INT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS stay_rules (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	room_id INT NOT NULL REFERENCES rooms (id),
+	rule_name VARCHAR(255) NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	min_nights INT NOT NULL DEFAULT 0,
+	max_nights INT NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS ical_feeds (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	room_id INT NOT NULL REFERENCES rooms (id),
+	token VARCHAR(64) NOT NULL UNIQUE,
+	private TINYINT(1) NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS webhooks (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	url TEXT NOT NULL,
+	secret VARCHAR(255) NOT NULL,
+	events TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	webhook_id INT NOT NULL REFERENCES webhooks (id),
+	event VARCHAR(64) NOT NULL,
+	payload TEXT NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	attempts INT NOT NULL DEFAULT 0,
+	response_code INT NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL,
+	next_attempt_at DATETIME NOT NULL,
+	delivered_at DATETIME NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	INDEX webhook_deliveries_due_idx (status, next_attempt_at)
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS channel_rooms (
+	room_id INT PRIMARY KEY REFERENCES rooms (id),
+	room_code VARCHAR(32) NOT NULL,
+	rate_plan_code VARCHAR(32) NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS channel_days (
+	room_id INT NOT NULL REFERENCES rooms (id),
+	night DATE NOT NULL,
+	open TINYINT(1) NOT NULL,
+	rate INT NOT NULL DEFAULT 0,
+	updated_at DATETIME NOT NULL,
+	PRIMARY KEY (room_id, night)
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS channel_syncs (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	room_id INT NOT NULL REFERENCES rooms (id),
+	message VARCHAR(64) NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	changes INT NOT NULL DEFAULT 0,
+	status VARCHAR(20) NOT NULL,
+	attempts INT NOT NULL DEFAULT 0,
+	response_code INT NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL,
+	request TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+) ENGINE=InnoDB;
@@ -0,0 +1,40 @@
+-- returns rooms, reservations & room_restrictions to how a database set up before migrations were versioned has them, a reservation
+-- being processed once it is no longer pending. Indexes of the columns kept are left, as they may be all that enforces the foreign
+-- keys of those columns, & 0002 adds them only if missing. As for 0002 itself, every statement is repeatable
+
+ALTER TABLE room_restrictions
+	DROP FOREIGN KEY IF EXISTS room_restrictions_import_id_fk,
+	DROP INDEX IF EXISTS room_restrictions_hold_token_idx,
+	DROP COLUMN IF EXISTS deleted_at,
+	DROP COLUMN IF EXISTS external_uid,
+	DROP COLUMN IF EXISTS import_id,
+	DROP COLUMN IF EXISTS expires_at,
+	DROP COLUMN IF EXISTS hold_token;
+
+-- status is added back, as pending, should an earlier attempt have dropped it, by which time every processed flag had been set from it
+ALTER TABLE reservations
+	ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	ADD COLUMN IF NOT EXISTS processed TINYINT NOT NULL DEFAULT 0;
+
+UPDATE reservations SET processed = 1 WHERE status <> 'pending';
+
+ALTER TABLE reservations
+	DROP FOREIGN KEY IF EXISTS reservations_group_id_fk,
+	DROP INDEX IF EXISTS reservations_confirmation_ref_idx,
+	DROP COLUMN IF EXISTS deleted_at,
+	DROP COLUMN IF EXISTS group_id,
+	DROP COLUMN IF EXISTS confirmation_ref,
+	DROP COLUMN IF EXISTS infants,
+	DROP COLUMN IF EXISTS children,
+	DROP COLUMN IF EXISTS adults,
+	DROP COLUMN IF EXISTS total_price,
+	DROP COLUMN IF EXISTS status;
+
+ALTER TABLE rooms
+	DROP COLUMN IF EXISTS max_infants,
+	DROP COLUMN IF EXISTS max_children,
+	DROP COLUMN IF EXISTS max_adults,
+	DROP COLUMN IF EXISTS extra_guest_rate,
+	DROP COLUMN IF EXISTS included_guests,
+	DROP COLUMN IF EXISTS weekend_rate,
+	DROP COLUMN IF EXISTS nightly_rate;
@@ -0,0 +1,43 @@
+-- brings rooms, reservations & room_restrictions, as a database set up before migrations were versioned has them, up to date; a
+-- reservation the owner had marked as processed is one they had confirmed, every other is still pending. MariaDB commits each change
+-- to a table as it is made, so every statement is repeatable, should a later one fail & the migration be run again
+
+ALTER TABLE rooms
+	ADD COLUMN IF NOT EXISTS nightly_rate INT NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS weekend_rate INT NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS included_guests INT NOT NULL DEFAULT 2,
+	ADD COLUMN IF NOT EXISTS extra_guest_rate INT NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS max_adults INT NOT NULL DEFAULT 2,
+	ADD COLUMN IF NOT EXISTS max_children INT NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS max_infants INT NOT NULL DEFAULT 0;
+
+ALTER TABLE reservations
+	ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	ADD COLUMN IF NOT EXISTS total_price INT NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS adults INT NOT NULL DEFAULT 1,
+	ADD COLUMN IF NOT EXISTS children INT NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS infants INT NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS confirmation_ref VARCHAR(20) NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS group_id INT NULL,
+	ADD COLUMN IF NOT EXISTS deleted_at DATETIME NULL,
+	ADD CONSTRAINT reservations_group_id_fk FOREIGN KEY IF NOT EXISTS reservations_group_id_fk (group_id) REFERENCES booking_groups (id),
+	ADD INDEX IF NOT EXISTS reservations_confirmation_ref_idx (confirmation_ref),
+	ADD INDEX IF NOT EXISTS reservations_start_date_idx (start_date);
+
+-- processed is added back, unset, should an earlier attempt have dropped it, by which time every status had been converted from it
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS processed TINYINT NOT NULL DEFAULT 0;
+
+UPDATE reservations SET status = 'confirmed' WHERE processed = 1;
+
+ALTER TABLE reservations DROP COLUMN IF EXISTS processed;
+
+ALTER TABLE room_restrictions
+	ADD COLUMN IF NOT EXISTS hold_token VARCHAR(255) NULL,
+	ADD COLUMN IF NOT EXISTS expires_at DATETIME NULL,
+	ADD COLUMN IF NOT EXISTS import_id INT NULL,
+	ADD COLUMN IF NOT EXISTS external_uid VARCHAR(255) NULL,
+	ADD COLUMN IF NOT EXISTS deleted_at DATETIME NULL,
+	ADD CONSTRAINT room_restrictions_import_id_fk FOREIGN KEY IF NOT EXISTS room_restrictions_import_id_fk (import_id) REFERENCES ical_imports (id),
+	ADD INDEX IF NOT EXISTS room_restrictions_room_dates_idx (room_id, start_date, end_date),
+	ADD INDEX IF NOT EXISTS room_restrictions_reservation_idx (reservation_id),
+	ADD INDEX IF NOT EXISTS room_restrictions_hold_token_idx (hold_token);
@@ -0,0 +1,4 @@
+-- removes the rooms the room pages book, leaving any still referred to, as those of a database set up before migrations were versioned
+-- are by its reservations
+
+DELETE IGNORE FROM rooms WHERE id IN (1, 2);
@@ -0,0 +1,20 @@
+-- drops every table of the schema, in the reverse of the order they were created
+
+DROP TABLE IF EXISTS channel_syncs;
+DROP TABLE IF EXISTS channel_days;
+DROP TABLE IF EXISTS channel_rooms;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS ical_feeds;
+DROP TABLE IF EXISTS stay_rules;
+DROP TABLE IF EXISTS seasonal_rates;
+DROP TABLE IF EXISTS waitlist;
+DROP TABLE IF EXISTS room_restrictions;
+DROP TABLE IF EXISTS ical_imports;
+DROP TABLE IF EXISTS reservation_status_changes;
+DROP TABLE IF EXISTS reservations;
+DROP TABLE IF EXISTS booking_groups;
+DROP TABLE IF EXISTS restrictions;
+DROP TABLE IF EXISTS rooms;
+DROP TABLE IF EXISTS api_tokens;
+DROP TABLE IF EXISTS administrators;
@@ -0,0 +1,41 @@
+-- brings rooms, reservations & room_restrictions up to date as for MariaDB, should they be as a database set up before migrations were
+-- versioned has them; 0001 creates them as they are now, so this changes nothing of a database it created. Every statement is
+-- repeatable, as for MariaDB
+
+ALTER TABLE rooms
+	ADD COLUMN IF NOT EXISTS nightly_rate INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS weekend_rate INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS included_guests INTEGER NOT NULL DEFAULT 2,
+	ADD COLUMN IF NOT EXISTS extra_guest_rate INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS max_adults INTEGER NOT NULL DEFAULT 2,
+	ADD COLUMN IF NOT EXISTS max_children INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS max_infants INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE reservations
+	ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	ADD COLUMN IF NOT EXISTS total_price INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS adults INTEGER NOT NULL DEFAULT 1,
+	ADD COLUMN IF NOT EXISTS children INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS infants INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS confirmation_ref VARCHAR(20) NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS group_id INTEGER NULL REFERENCES booking_groups (id),
+	ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL,
+	ADD COLUMN IF NOT EXISTS processed SMALLINT NOT NULL DEFAULT 0;
+
+UPDATE reservations SET status = 'confirmed' WHERE processed = 1;
+
+ALTER TABLE reservations DROP COLUMN IF EXISTS processed;
+
+CREATE INDEX IF NOT EXISTS reservations_confirmation_ref_idx ON reservations (confirmation_ref);
+CREATE INDEX IF NOT EXISTS reservations_start_date_idx ON reservations (start_date);
+
+ALTER TABLE room_restrictions
+	ADD COLUMN IF NOT EXISTS hold_token VARCHAR(255) NULL,
+	ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ NULL,
+	ADD COLUMN IF NOT EXISTS import_id INTEGER NULL REFERENCES ical_imports (id),
+	ADD COLUMN IF NOT EXISTS external_uid VARCHAR(255) NULL,
+	ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS room_restrictions_room_dates_idx ON room_restrictions (room_id, start_date, end_date);
+CREATE INDEX IF NOT EXISTS room_restrictions_reservation_idx ON room_restrictions (reservation_id);
+CREATE INDEX IF NOT EXISTS room_restrictions_hold_token_idx ON room_restrictions (hold_token);
@@ -0,0 +1,3 @@
+-- removes the rooms the room pages book, which cannot be done while anything refers to them
+
+DELETE FROM rooms WHERE id IN (1, 2);
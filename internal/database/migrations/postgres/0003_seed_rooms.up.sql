@@ -0,0 +1,8 @@
+-- the rooms the room pages book, by their ids, unless a database already has them; their rates are left for the owner to set
+
+INSERT INTO rooms (id, room_name, max_adults, max_children, max_infants, created_at, updated_at) VALUES
+	(1, 'General''s Quarters', 2, 1, 1, now(), now()),
+	(2, 'Major''s Suite', 4, 2, 1, now(), now())
+ON CONFLICT (id) DO NOTHING;
+
+SELECT setval('rooms_id_seq', (SELECT MAX(id) FROM rooms));
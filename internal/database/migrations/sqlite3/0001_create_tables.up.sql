@@ -0,0 +1,231 @@
+-- tables of the schema, & the categories of restriction, as they are now, since only a MariaDB database can have been set up before
+-- migrations were versioned
+
+CREATE TABLE IF NOT EXISTS administrators (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	first_name VARCHAR(255) NOT NULL DEFAULT '',
+	last_name VARCHAR(255) NOT NULL DEFAULT '',
+	email VARCHAR(255) NOT NULL UNIQUE,
+	password VARCHAR(60) NOT NULL,
+	access_level INTEGER NOT NULL DEFAULT 1,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	administrator_id INTEGER NOT NULL REFERENCES administrators (id),
+	name VARCHAR(255) NOT NULL,
+	token_hash VARCHAR(64) NOT NULL UNIQUE,
+	last_used_at DATETIME NULL,
+	created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS rooms (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	room_name VARCHAR(255) NOT NULL,
+	nightly_rate INTEGER NOT NULL DEFAULT 0,
+	weekend_rate INTEGER NOT NULL DEFAULT 0,
+	included_guests INTEGER NOT NULL DEFAULT 2,
+	extra_guest_rate INTEGER NOT NULL DEFAULT 0,
+	max_adults INTEGER NOT NULL DEFAULT 2,
+	max_children INTEGER NOT NULL DEFAULT 0,
+	max_infants INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS restrictions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	restriction_name VARCHAR(255) NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+INSERT OR IGNORE INTO restrictions (id, restriction_name, created_at, updated_at) VALUES
+	(1, 'Reservation', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+	(2, 'Owner Block', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+	(3, 'Hold', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+	(4, 'External Booking', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+
+CREATE TABLE IF NOT EXISTS booking_groups (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	confirmation_ref VARCHAR(20) NOT NULL,
+	first_name VARCHAR(255) NOT NULL,
+	last_name VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL,
+	phone VARCHAR(255) NOT NULL DEFAULT '',
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS reservations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	room_id INTEGER NOT NULL REFERENCES rooms (id),
+	first_name VARCHAR(255) NOT NULL,
+	last_name VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL,
+	phone VARCHAR(255) NOT NULL DEFAULT '',
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	total_price INTEGER NOT NULL DEFAULT 0,
+	adults INTEGER NOT NULL DEFAULT 1,
+	children INTEGER NOT NULL DEFAULT 0,
+	infants INTEGER NOT NULL DEFAULT 0,
+	confirmation_ref VARCHAR(20) NOT NULL DEFAULT '',
+	group_id INTEGER NULL REFERENCES booking_groups (id),
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	deleted_at DATETIME NULL
+);
+
+CREATE INDEX IF NOT EXISTS reservations_confirmation_ref_idx ON reservations (confirmation_ref);
+CREATE INDEX IF NOT EXISTS reservations_start_date_idx ON reservations (start_date);
+
+CREATE TABLE IF NOT EXISTS reservation_status_changes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	reservation_id INTEGER NOT NULL REFERENCES reservations (id),
+	from_status VARCHAR(20) NOT NULL,
+	to_status VARCHAR(20) NOT NULL,
+	created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ical_imports (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	room_id INTEGER NOT NULL REFERENCES rooms (id),
+	name VARCHAR(255) NOT NULL,
+	url TEXT NOT NULL,
+	last_synced_at DATETIME NULL,
+	last_error TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS room_restrictions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	room_id INTEGER NOT NULL REFERENCES rooms (id),
+	reservation_id INTEGER NULL REFERENCES reservations (id),
+	restriction_id INTEGER NOT NULL REFERENCES restrictions (id),
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	hold_token VARCHAR(255) NULL,
+	expires_at DATETIME NULL,
+	import_id INTEGER NULL REFERENCES ical_imports (id),
+	external_uid VARCHAR(255) NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	deleted_at DATETIME NULL
+);
+
+CREATE INDEX IF NOT EXISTS room_restrictions_room_dates_idx ON room_restrictions (room_id, start_date, end_date);
+CREATE INDEX IF NOT EXISTS room_restrictions_reservation_idx ON room_restrictions (reservation_id);
+CREATE INDEX IF NOT EXISTS room_restrictions_hold_token_idx ON room_restrictions (hold_token);
+
+CREATE TABLE IF NOT EXISTS waitlist (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	first_name VARCHAR(255) NOT NULL,
+	last_name VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL,
+	phone VARCHAR(255) NOT NULL DEFAULT '',
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	adults INTEGER NOT NULL DEFAULT 1,
+	children INTEGER NOT NULL DEFAULT 0,
+	infants INTEGER NOT NULL DEFAULT 0,
+	notified_at DATETIME NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS seasonal_rates (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	room_id INTEGER NOT NULL REFERENCES rooms (id),
+	season_name VARCHAR(255) NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	nightly_rate INTEGER NOT NULL,
+	weekend_rate INTEGER NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS stay_rules (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	room_id INTEGER NOT NULL REFERENCES rooms (id),
+	rule_name VARCHAR(255) NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	min_nights INTEGER NOT NULL DEFAULT 0,
+	max_nights INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ical_feeds (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	room_id INTEGER NOT NULL REFERENCES rooms (id),
+	token VARCHAR(64) NOT NULL UNIQUE,
+	private BOOLEAN NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	url TEXT NOT NULL,
+	secret VARCHAR(255) NOT NULL,
+	events TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	webhook_id INTEGER NOT NULL REFERENCES webhooks (id),
+	event VARCHAR(64) NOT NULL,
+	payload TEXT NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	response_code INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL,
+	next_attempt_at DATETIME NOT NULL,
+	delivered_at DATETIME NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS channel_rooms (
+	room_id INTEGER PRIMARY KEY REFERENCES rooms (id),
+	room_code VARCHAR(32) NOT NULL,
+	rate_plan_code VARCHAR(32) NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS channel_days (
+	room_id INTEGER NOT NULL REFERENCES rooms (id),
+	night DATE NOT NULL,
+	open BOOLEAN NOT NULL,
+	rate INTEGER NOT NULL DEFAULT 0,
+	updated_at DATETIME NOT NULL,
+	PRIMARY KEY (room_id, night)
+);
+
+CREATE TABLE IF NOT EXISTS channel_syncs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	room_id INTEGER NOT NULL REFERENCES rooms (id),
+	message VARCHAR(64) NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	changes INTEGER NOT NULL DEFAULT 0,
+	status VARCHAR(20) NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	response_code INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL,
+	request TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
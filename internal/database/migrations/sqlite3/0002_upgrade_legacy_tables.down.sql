@@ -0,0 +1,7 @@
+-- 0002 changed nothing here, so rooms, reservations & room_restrictions are left as 0001 created them, which this checks
+
+SELECT nightly_rate, weekend_rate, included_guests, extra_guest_rate, max_adults, max_children, max_infants FROM rooms WHERE 1 = 0;
+
+SELECT status, total_price, adults, children, infants, confirmation_ref, group_id, deleted_at FROM reservations WHERE 1 = 0;
+
+SELECT hold_token, expires_at, import_id, external_uid, deleted_at FROM room_restrictions WHERE 1 = 0;
@@ -0,0 +1,8 @@
+-- only a MariaDB database can have been set up before migrations were versioned, so there are no tables here to bring up to date;
+-- this checks 0001 created rooms, reservations & room_restrictions as they are now, keeping versions in step with MariaDB
+
+SELECT nightly_rate, weekend_rate, included_guests, extra_guest_rate, max_adults, max_children, max_infants FROM rooms WHERE 1 = 0;
+
+SELECT status, total_price, adults, children, infants, confirmation_ref, group_id, deleted_at FROM reservations WHERE 1 = 0;
+
+SELECT hold_token, expires_at, import_id, external_uid, deleted_at FROM room_restrictions WHERE 1 = 0;
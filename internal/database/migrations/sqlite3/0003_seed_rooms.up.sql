@@ -0,0 +1,5 @@
+-- the rooms the room pages book, by their ids, unless a database already has them; their rates are left for the owner to set
+
+INSERT OR IGNORE INTO rooms (id, room_name, max_adults, max_children, max_infants, created_at, updated_at) VALUES
+	(1, 'General''s Quarters', 2, 1, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+	(2, 'Major''s Suite', 4, 2, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
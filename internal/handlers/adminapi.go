@@ -0,0 +1,496 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/apitoken"
+	"github.com/StratoNET/bnb-bookings/internal/lifecycle"
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/repository"
+	forms "github.com/StratoNET/bnb-bookings/internal/validation"
+	"github.com/StratoNET/bnb-bookings/internal/webhook"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxAPIBody is the largest request body the admin API will read
+const maxAPIBody = 1 << 20
+
+// contextKey is the type of keys for values placed into a request's context by this package
+type contextKey string
+
+// apiAdminKey holds the administrator authenticated by APIAuth
+const apiAdminKey contextKey = "api_admin"
+
+// apiReservation is a reservation as given by the admin API, amounts are in pence
+type apiReservation struct {
+	ID              int    `json:"id"`
+	RoomID          int    `json:"room_id"`
+	RoomName        string `json:"room_name"`
+	FirstName       string `json:"first_name"`
+	LastName        string `json:"last_name"`
+	Email           string `json:"email"`
+	Phone           string `json:"phone"`
+	Start           string `json:"start" format:"date"`
+	End             string `json:"end" format:"date"`
+	Nights          int    `json:"nights"`
+	Status          string `json:"status"`
+	TotalPrice      int    `json:"total_price"`
+	Adults          int    `json:"adults"`
+	Children        int    `json:"children"`
+	Infants         int    `json:"infants"`
+	ConfirmationRef string `json:"confirmation_ref"`
+	GroupID         int    `json:"group_id,omitempty"`
+	CreatedAt       string `json:"created_at" format:"date-time"`
+	UpdatedAt       string `json:"updated_at" format:"date-time"`
+}
+
+type apiReservationList struct {
+	Reservations []apiReservation `json:"reservations"`
+}
+
+// apiGuestUpdate is the body of a PUT /api/v1/admin/reservations/{id} request, the same 4 fields as the admin reservation page
+type apiGuestUpdate struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+}
+
+// apiStatusChange is the body of a POST /api/v1/admin/reservations/{id}/status request
+type apiStatusChange struct {
+	Status string `json:"status"`
+}
+
+// apiBlock is an owner block as given to, & returned by, the admin API
+type apiBlock struct {
+	ID     int64  `json:"id,omitempty"`
+	RoomID int    `json:"room_id"`
+	Start  string `json:"start" format:"date"`
+	End    string `json:"end" format:"date"`
+}
+
+// apiReservationFromModel converts a reservation for the admin API
+func apiReservationFromModel(r models.Reservation) apiReservation {
+	return apiReservation{
+		ID:              r.ID,
+		RoomID:          r.RoomID,
+		RoomName:        r.Room.RoomName,
+		FirstName:       r.FirstName,
+		LastName:        r.LastName,
+		Email:           r.Email,
+		Phone:           r.Phone,
+		Start:           r.StartDate.Format(isoDate),
+		End:             r.EndDate.Format(isoDate),
+		Nights:          int(r.EndDate.Sub(r.StartDate).Hours() / 24),
+		Status:          string(r.Status),
+		TotalPrice:      r.TotalPrice,
+		Adults:          r.Adults,
+		Children:        r.Children,
+		Infants:         r.Infants,
+		ConfirmationRef: r.ConfirmationRef,
+		GroupID:         r.GroupID,
+		CreatedAt:       r.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       r.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// APIAuth is to protect routes only accessible by administrators' scripts & integrations, by requiring an API token given as
+// "Authorization: Bearer <token>". The administrator owning the token is placed into the request's context
+func (m *Repository) APIAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := apitoken.FromHeader(r.Header.Get("Authorization"))
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeJSONError(w, http.StatusUnauthorized, apiErrUnauthorized, "", "an API token is required, given as Authorization: Bearer <token>")
+			return
+		}
+
+		admin, err := m.DB.GetAdministratorByAPIToken(r.Context(), apitoken.Hash(token))
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin", error="invalid_token"`)
+			writeJSONError(w, http.StatusUnauthorized, apiErrUnauthorized, "", "the API token is not valid or has been revoked")
+			return
+		} else if err != nil {
+			m.App.ErrorLog.Println(err)
+			writeJSONError(w, http.StatusInternalServerError, apiErrInternal, "", "cannot check API token")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiAdminKey, admin)))
+	})
+}
+
+// apiAdmin returns the administrator authenticated by APIAuth
+func apiAdmin(r *http.Request) models.Administrator {
+	admin, _ := r.Context().Value(apiAdminKey).(models.Administrator)
+	return admin
+}
+
+// logAPIChange records a change made through the admin API & by whom, as there is no session to show it was made by an administrator
+func (m *Repository) logAPIChange(r *http.Request, format string, v ...interface{}) {
+	m.App.InfoLog.Printf("admin API (%s): %s", apiAdmin(r).Email, fmt.Sprintf(format, v...))
+}
+
+// decodeJSONBody reads a request body into v, writing an error response & returning false if it is not valid JSON of the expected shape
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBody))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, apiErrInvalidBody, "", fmt.Sprintf("request body is not valid: %s", err))
+		return false
+	}
+	return true
+}
+
+// writeJSONValidationError writes the problem with each invalid field of a request body, as found by a validation form
+func writeJSONValidationError(w http.ResponseWriter, form *forms.Form) {
+	fields := make(map[string]string)
+	for field := range form.Errors {
+		fields[field] = form.Errors.GetErrMsg(field)
+	}
+	writeJSON(w, http.StatusBadRequest, apiError{Error: apiErrorDetail{
+		Status:  http.StatusBadRequest,
+		Code:    apiErrValidation,
+		Message: "some fields are not valid",
+		Fields:  fields,
+	}})
+}
+
+// apiReservationByID gets the reservation named by the {id} URL parameter, writing an error response & returning false if it cannot
+func (m *Repository) apiReservationByID(w http.ResponseWriter, r *http.Request) (models.Reservation, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id < 1 {
+		writeJSONError(w, http.StatusNotFound, apiErrNotFound, "", "reservation not found")
+		return models.Reservation{}, false
+	}
+
+	reservation, err := m.DB.GetReservationByID(r.Context(), id)
+	if m.dbInterrupted(w, r, err) {
+		return reservation, false
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, http.StatusNotFound, apiErrNotFound, "", "reservation not found")
+		return reservation, false
+	} else if err != nil {
+		m.App.ErrorLog.Println(err)
+		writeJSONError(w, http.StatusInternalServerError, apiErrInternal, "", "cannot get reservation")
+		return reservation, false
+	}
+	return reservation, true
+}
+
+// APIAdminReservations is the handler for GET /api/v1/admin/reservations, returning reservations optionally filtered by status,
+// room_id & stays overlapping start/end dates
+func (m *Repository) APIAdminReservations(w http.ResponseWriter, r *http.Request) {
+	var filter models.ReservationFilter
+
+	if s := r.URL.Query().Get("status"); s != "" {
+		status, err := lifecycle.Parse(s)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, apiErrInvalidParameter, "status", err.Error())
+			return
+		}
+		filter.Status = status
+	}
+
+	if s := r.URL.Query().Get("room_id"); s != "" {
+		roomID, err := strconv.Atoi(s)
+		if err != nil || roomID < 1 {
+			writeJSONError(w, http.StatusBadRequest, apiErrInvalidParameter, "room_id", "room_id must be a room's id")
+			return
+		}
+		filter.RoomID = roomID
+	}
+
+	for _, name := range []string{"start", "end"} {
+		s := r.URL.Query().Get(name)
+		if s == "" {
+			continue
+		}
+		d, err := time.Parse(isoDate, s)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, apiErrInvalidDate, name, fmt.Sprintf("%s must be a date in the form YYYY-MM-DD", name))
+			return
+		}
+		if name == "start" {
+			filter.StartDate = d
+		} else {
+			filter.EndDate = d
+		}
+	}
+	if !filter.StartDate.IsZero() && !filter.EndDate.IsZero() && filter.EndDate.Before(filter.StartDate) {
+		writeJSONError(w, http.StatusBadRequest, apiErrInvalidDateRange, "end", "end cannot be before start")
+		return
+	}
+
+	reservations, err := m.DB.FilterReservations(r.Context(), filter)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		writeJSONError(w, http.StatusInternalServerError, apiErrInternal, "", "cannot get reservations")
+		return
+	}
+
+	resp := apiReservationList{Reservations: []apiReservation{}}
+	for _, rsvn := range reservations {
+		resp.Reservations = append(resp.Reservations, apiReservationFromModel(rsvn))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// APIAdminReservation is the handler for GET /api/v1/admin/reservations/{id}
+func (m *Repository) APIAdminReservation(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := m.apiReservationByID(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiReservationFromModel(reservation))
+}
+
+// APIAdminUpdateReservation is the handler for PUT /api/v1/admin/reservations/{id}, updating the guest's details with the same
+// validation as the admin reservation page
+func (m *Repository) APIAdminUpdateReservation(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := m.apiReservationByID(w, r)
+	if !ok {
+		return
+	}
+
+	var body apiGuestUpdate
+	if !decodeJSONBody(w, r, &body) {
+		return
+	}
+
+	form := forms.NewForm(url.Values{
+		"first_name": {body.FirstName},
+		"last_name":  {body.LastName},
+		"email":      {body.Email},
+		"phone":      {body.Phone},
+	})
+	form.RequiredFields("first_name", "last_name", "email", "phone")
+	form.MinLength("first_name", 2)
+	form.MinLength("last_name", 2)
+	form.MinLength("phone", 6)
+	form.IsEmail("email")
+
+	if !form.ValidForm() {
+		writeJSONValidationError(w, form)
+		return
+	}
+
+	reservation.FirstName = body.FirstName
+	reservation.LastName = body.LastName
+	reservation.Email = body.Email
+	reservation.Phone = body.Phone
+
+	err := m.DB.UpdateReservation(r.Context(), reservation)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		writeJSONError(w, http.StatusInternalServerError, apiErrInternal, "", "cannot update reservation")
+		return
+	}
+	m.logAPIChange(r, "reservation (id=%d) guest details updated", reservation.ID)
+	m.publishReservation(webhook.ReservationUpdated, reservation, reservation.Status)
+
+	writeJSON(w, http.StatusOK, apiReservationFromModel(reservation))
+}
+
+// APIAdminReservationStatus is the handler for POST /api/v1/admin/reservations/{id}/status, moving a reservation on to a new status
+// allowed by its lifecycle
+func (m *Repository) APIAdminReservationStatus(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := m.apiReservationByID(w, r)
+	if !ok {
+		return
+	}
+
+	var body apiStatusChange
+	if !decodeJSONBody(w, r, &body) {
+		return
+	}
+
+	status, err := lifecycle.Parse(body.Status)
+	if err != nil {
+		form := forms.NewForm(url.Values{})
+		form.Errors.AddErrMsg("status", err.Error())
+		writeJSONValidationError(w, form)
+		return
+	}
+
+	err = m.DB.UpdateReservationStatus(r.Context(), reservation.ID, reservation.Status, status)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		var transitionErr *lifecycle.TransitionError
+		var changedErr *repository.StatusChangedError
+		switch {
+		case errors.As(err, &transitionErr):
+			writeJSONError(w, http.StatusConflict, apiErrConflict, "", transitionErr.Error())
+		case errors.As(err, &changedErr):
+			writeJSONError(w, http.StatusConflict, apiErrConflict, "", changedErr.Error())
+		default:
+			m.App.ErrorLog.Println(err)
+			writeJSONError(w, http.StatusInternalServerError, apiErrInternal, "", "cannot update reservation status")
+		}
+		return
+	}
+	m.logAPIChange(r, "reservation (id=%d) marked as %s", reservation.ID, status.Label())
+
+	if status == models.StatusCancelled {
+		m.notifyWaitlist(reservation.RoomID, reservation.StartDate, reservation.EndDate)
+	}
+
+	previous := reservation.Status
+	RequestChannelSync()
+	reservation.Status = status
+	m.publishReservation(webhook.ReservationStatusChanged, reservation, previous)
+
+	writeJSON(w, http.StatusOK, apiReservationFromModel(reservation))
+}
+
+// APIAdminDeleteReservation is the handler for DELETE /api/v1/admin/reservations/{id}, moving a reservation to the trash & freeing its room
+func (m *Repository) APIAdminDeleteReservation(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := m.apiReservationByID(w, r)
+	if !ok {
+		return
+	}
+
+	err := m.DB.DeleteReservation(r.Context(), reservation.ID)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		writeJSONError(w, http.StatusInternalServerError, apiErrInternal, "", "cannot delete reservation")
+		return
+	}
+	m.logAPIChange(r, "reservation (id=%d) moved to trash", reservation.ID)
+
+	RequestChannelSync()
+	m.notifyWaitlist(reservation.RoomID, reservation.StartDate, reservation.EndDate)
+	m.publishReservation(webhook.ReservationDeleted, reservation, reservation.Status)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APIAdminCreateBlock is the handler for POST /api/v1/admin/blocks, blocking a room from its start to its end date inclusive, only if
+// the room is free for those dates
+func (m *Repository) APIAdminCreateBlock(w http.ResponseWriter, r *http.Request) {
+	var body apiBlock
+	if !decodeJSONBody(w, r, &body) {
+		return
+	}
+
+	form := forms.NewForm(url.Values{
+		"start": {body.Start},
+		"end":   {body.End},
+	})
+	form.RequiredFields("start", "end")
+	if body.RoomID < 1 {
+		form.Errors.AddErrMsg("room_id", "room_id must be a room's id")
+	}
+	startDate, errS := time.Parse(isoDate, body.Start)
+	if body.Start != "" && errS != nil {
+		form.Errors.AddErrMsg("start", "start must be a date in the form YYYY-MM-DD")
+	}
+	endDate, errE := time.Parse(isoDate, body.End)
+	if body.End != "" && errE != nil {
+		form.Errors.AddErrMsg("end", "end must be a date in the form YYYY-MM-DD")
+	}
+	if errS == nil && errE == nil && endDate.Before(startDate) {
+		form.Errors.AddErrMsg("end", "end cannot be before start")
+	}
+
+	if !form.ValidForm() {
+		writeJSONValidationError(w, form)
+		return
+	}
+
+	id, err := m.DB.CreateRoomBlock(r.Context(), body.RoomID, startDate, endDate)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		var unavailableErr *repository.RoomUnavailableError
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			writeJSONError(w, http.StatusNotFound, apiErrNotFound, "", "room not found")
+		case errors.As(err, &unavailableErr):
+			writeJSONError(w, http.StatusConflict, apiErrConflict, "", unavailableErr.Error())
+		default:
+			m.App.ErrorLog.Println(err)
+			writeJSONError(w, http.StatusInternalServerError, apiErrInternal, "", "cannot create block")
+		}
+		return
+	}
+	m.logAPIChange(r, "block (id=%d) added for room %d", id, body.RoomID)
+
+	RequestChannelSync()
+	body.ID = id
+	m.publish(webhook.BlockAdded, newWebhookBlock(int(id), body.RoomID, startDate, endDate))
+
+	writeJSON(w, http.StatusCreated, body)
+}
+
+// APIAdminDeleteBlock is the handler for DELETE /api/v1/admin/blocks/{id}, moving an owner block to the trash
+func (m *Repository) APIAdminDeleteBlock(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id < 1 {
+		writeJSONError(w, http.StatusNotFound, apiErrNotFound, "", "block not found")
+		return
+	}
+
+	// the block's room & dates are read first, to be posted to webhooks once it is in the trash
+	block, err := m.DB.GetRoomBlockByID(r.Context(), id)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, http.StatusNotFound, apiErrNotFound, "", "block not found")
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		writeJSONError(w, http.StatusInternalServerError, apiErrInternal, "", "cannot delete block")
+		return
+	}
+
+	err = m.DB.DeleteRoomBlock(r.Context(), id)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		// the block was moved to the trash meanwhile
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, http.StatusNotFound, apiErrNotFound, "", "block not found")
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		writeJSONError(w, http.StatusInternalServerError, apiErrInternal, "", "cannot delete block")
+		return
+	}
+	m.logAPIChange(r, "block (id=%d) moved to trash", id)
+	RequestChannelSync()
+	m.notifyWaitlist(block.RoomID, block.StartDate, block.EndDate)
+	m.publish(webhook.BlockRemoved, newWebhookBlock(id, block.RoomID, block.StartDate, block.EndDate))
+
+	w.WriteHeader(http.StatusNoContent)
+}
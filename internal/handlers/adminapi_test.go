@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/repository"
+)
+
+// apiRequest makes a request of the admin API through the routes, with an optional API token & JSON body
+func apiRequest(method, url, token, body string) *httptest.ResponseRecorder {
+	return routedAPIRequest(getRoutes(), method, url, token, body)
+}
+
+// routedAPIRequest makes a request of the admin API through the given routes, with an optional API token & JSON body
+func routedAPIRequest(routes http.Handler, method, url, token, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+	return rr
+}
+
+var apiAuthTests = []struct {
+	name               string
+	header             string
+	expectedStatusCode int
+}{
+	{"no-token", "", http.StatusUnauthorized},
+	{"basic-auth", "Basic dXNlcjpwYXNz", http.StatusUnauthorized},
+	{"revoked-token", "Bearer bnb_revoked", http.StatusUnauthorized},
+	{"valid-token", "Bearer bnb_test", http.StatusOK},
+}
+
+func TestRepository_APIAuth(t *testing.T) {
+	routes := getRoutes()
+
+	for _, v := range apiAuthTests {
+		req, _ := http.NewRequest("GET", "/api/v1/admin/reservations", nil)
+		if v.header != "" {
+			req.Header.Set("Authorization", v.header)
+		}
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("APIAuth middleware (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+		}
+		if rr.Code == http.StatusUnauthorized {
+			if !strings.HasPrefix(rr.Header().Get("WWW-Authenticate"), "Bearer") {
+				t.Errorf("APIAuth middleware (%s) did not ask for a bearer token", v.name)
+			}
+			var resp apiError
+			err := json.Unmarshal(rr.Body.Bytes(), &resp)
+			if err != nil || resp.Error.Code != apiErrUnauthorized {
+				t.Errorf("APIAuth middleware (%s) returned unexpected error body: %s", v.name, rr.Body.String())
+			}
+		}
+	}
+}
+
+var apiAdminReservationsTests = []struct {
+	name               string
+	query              string
+	expectedStatusCode int
+	expectedIDs        []int
+}{
+	{"all", "", http.StatusOK, []int{1, 2}},
+	{"by-status", "status=confirmed", http.StatusOK, []int{2}},
+	{"by-room", "room_id=1", http.StatusOK, []int{1}},
+	{"overlapping-dates", "start=2098-01-04&end=2098-01-20", http.StatusOK, []int{1}},
+	{"from-date", "start=2098-01-06", http.StatusOK, []int{2}},
+	{"none", "status=cancelled", http.StatusOK, []int{}},
+	{"unknown-status", "status=lost", http.StatusBadRequest, nil},
+	{"invalid-room", "room_id=one", http.StatusBadRequest, nil},
+	{"invalid-date", "start=04/01/2098", http.StatusBadRequest, nil},
+	{"end-before-start", "start=2098-01-04&end=2098-01-01", http.StatusBadRequest, nil},
+}
+
+func TestRepository_APIAdminReservations(t *testing.T) {
+	for _, v := range apiAdminReservationsTests {
+		rr := apiRequest("GET", "/api/v1/admin/reservations?"+v.query, "bnb_test", "")
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("APIAdminReservations handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+			continue
+		}
+		if v.expectedIDs == nil {
+			continue
+		}
+
+		var resp apiReservationList
+		err := json.Unmarshal(rr.Body.Bytes(), &resp)
+		if err != nil {
+			t.Errorf("APIAdminReservations handler (%s) returned invalid JSON: %v", v.name, err)
+			continue
+		}
+		var ids []int
+		for _, r := range resp.Reservations {
+			ids = append(ids, r.ID)
+		}
+		if len(ids) != len(v.expectedIDs) {
+			t.Errorf("APIAdminReservations handler (%s) returned reservations %v, expected %v", v.name, ids, v.expectedIDs)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != v.expectedIDs[i] {
+				t.Errorf("APIAdminReservations handler (%s) returned reservations %v, expected %v", v.name, ids, v.expectedIDs)
+				break
+			}
+		}
+	}
+}
+
+var apiAdminReservationTests = []struct {
+	name               string
+	method             string
+	url                string
+	body               string
+	expectedStatusCode int
+	expectedCode       string
+}{
+	{"get", "GET", "/api/v1/admin/reservations/1", "", http.StatusOK, ""},
+	{"get-invalid-id", "GET", "/api/v1/admin/reservations/one", "", http.StatusNotFound, apiErrNotFound},
+	{"get-zero-id", "GET", "/api/v1/admin/reservations/0", "", http.StatusNotFound, apiErrNotFound},
+	{"update", "PUT", "/api/v1/admin/reservations/1", `{"first_name":"Joe","last_name":"Soap","email":"joe@soap.bar","phone":"01234 567890"}`, http.StatusOK, ""},
+	{"update-invalid", "PUT", "/api/v1/admin/reservations/1", `{"first_name":"J","last_name":"Soap","email":"joe","phone":"01234 567890"}`, http.StatusBadRequest, apiErrValidation},
+	{"update-not-json", "PUT", "/api/v1/admin/reservations/1", `first_name=Joe`, http.StatusBadRequest, apiErrInvalidBody},
+	{"update-unknown-field", "PUT", "/api/v1/admin/reservations/1", `{"first_name":"Joe","room_id":2}`, http.StatusBadRequest, apiErrInvalidBody},
+	{"status", "POST", "/api/v1/admin/reservations/1/status", `{"status":"confirmed"}`, http.StatusOK, ""},
+	{"status-cancelled", "POST", "/api/v1/admin/reservations/1/status", `{"status":"cancelled"}`, http.StatusOK, ""},
+	{"status-unknown", "POST", "/api/v1/admin/reservations/1/status", `{"status":"lost"}`, http.StatusBadRequest, apiErrValidation},
+	{"status-not-allowed", "POST", "/api/v1/admin/reservations/1/status", `{"status":"checked-out"}`, http.StatusConflict, apiErrConflict},
+	{"delete", "DELETE", "/api/v1/admin/reservations/1", "", http.StatusNoContent, ""},
+	{"block", "POST", "/api/v1/admin/blocks", `{"room_id":1,"start":"2098-01-01","end":"2098-01-01"}`, http.StatusCreated, ""},
+	{"block-missing-dates", "POST", "/api/v1/admin/blocks", `{"room_id":1}`, http.StatusBadRequest, apiErrValidation},
+	{"block-invalid-date", "POST", "/api/v1/admin/blocks", `{"room_id":1,"start":"01/01/2098","end":"2098-01-01"}`, http.StatusBadRequest, apiErrValidation},
+	{"block-end-before-start", "POST", "/api/v1/admin/blocks", `{"room_id":1,"start":"2098-01-05","end":"2098-01-01"}`, http.StatusBadRequest, apiErrValidation},
+	{"block-no-room", "POST", "/api/v1/admin/blocks", `{"start":"2098-01-01","end":"2098-01-01"}`, http.StatusBadRequest, apiErrValidation},
+	{"unblock", "DELETE", "/api/v1/admin/blocks/1", "", http.StatusNoContent, ""},
+	{"unblock-invalid-id", "DELETE", "/api/v1/admin/blocks/one", "", http.StatusNotFound, apiErrNotFound},
+}
+
+func TestRepository_APIAdminReservation(t *testing.T) {
+	for _, v := range apiAdminReservationTests {
+		rr := apiRequest(v.method, v.url, "bnb_test", v.body)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("admin API (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+			continue
+		}
+		if rr.Code == http.StatusNoContent {
+			continue
+		}
+		if rr.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("admin API (%s) returned content type: %s, expected application/json", v.name, rr.Header().Get("Content-Type"))
+		}
+
+		var resp apiError
+		err := json.Unmarshal(rr.Body.Bytes(), &resp)
+		if err != nil {
+			t.Errorf("admin API (%s) returned invalid JSON: %v", v.name, err)
+			continue
+		}
+		if resp.Error.Code != v.expectedCode {
+			t.Errorf("admin API (%s) returned error code: %q, expected %q", v.name, resp.Error.Code, v.expectedCode)
+		}
+		if v.expectedCode == apiErrValidation && len(resp.Error.Fields) == 0 {
+			t.Errorf("admin API (%s) did not say which fields are invalid", v.name)
+		}
+	}
+}
+
+// apiAdminMemoryTests are requests of the admin API of a repository held in memory, holding Peter Barrett's bnb_test token & a pending
+// reservation, id 1, of the General's Quarters for 1-5/01/2098. Any method named by failOn fails with err
+var apiAdminMemoryTests = []struct {
+	name               string
+	method             string
+	url                string
+	body               string
+	failOn             string
+	err                error
+	expectedStatusCode int
+	expectedCode       string
+}{
+	{"auth-database-error", "GET", "/api/v1/admin/reservations", "", "GetAdministratorByAPIToken", errMemoryDB, http.StatusInternalServerError, apiErrInternal},
+	{"reservations-database-error", "GET", "/api/v1/admin/reservations", "", "FilterReservations", errMemoryDB, http.StatusInternalServerError, apiErrInternal},
+	{"get-not-found", "GET", "/api/v1/admin/reservations/8888", "", "", nil, http.StatusNotFound, apiErrNotFound},
+	{"update-not-found", "PUT", "/api/v1/admin/reservations/8888", `{}`, "", nil, http.StatusNotFound, apiErrNotFound},
+	{"update-database-error", "PUT", "/api/v1/admin/reservations/1", `{"first_name":"Joe","last_name":"Soap","email":"joe@soap.bar","phone":"01234 567890"}`, "UpdateReservation", errMemoryDB, http.StatusInternalServerError, apiErrInternal},
+	{"status-changed-elsewhere", "POST", "/api/v1/admin/reservations/1/status", `{"status":"confirmed"}`, "UpdateReservationStatus", &repository.StatusChangedError{ReservationID: 1, Expected: models.StatusPending}, http.StatusConflict, apiErrConflict},
+	{"status-not-found", "POST", "/api/v1/admin/reservations/8888/status", `{"status":"confirmed"}`, "", nil, http.StatusNotFound, apiErrNotFound},
+	{"status-database-error", "POST", "/api/v1/admin/reservations/1/status", `{"status":"confirmed"}`, "UpdateReservationStatus", errMemoryDB, http.StatusInternalServerError, apiErrInternal},
+	{"delete-not-found", "DELETE", "/api/v1/admin/reservations/8888", "", "", nil, http.StatusNotFound, apiErrNotFound},
+	{"delete-database-error", "DELETE", "/api/v1/admin/reservations/1", "", "DeleteReservation", errMemoryDB, http.StatusInternalServerError, apiErrInternal},
+	{"block-unknown-room", "POST", "/api/v1/admin/blocks", `{"room_id":3,"start":"2098-01-01","end":"2098-01-01"}`, "", nil, http.StatusNotFound, apiErrNotFound},
+	{"block-room-taken", "POST", "/api/v1/admin/blocks", `{"room_id":1,"start":"2098-01-03","end":"2098-01-03"}`, "", nil, http.StatusConflict, apiErrConflict},
+	{"unblock-not-found", "DELETE", "/api/v1/admin/blocks/8888", "", "", nil, http.StatusNotFound, apiErrNotFound},
+}
+
+// apiAdminMemoryRoutes returns the routes of a repository held in memory as apiAdminMemoryTests describe, with the method named by
+// failOn, if any, failing with err
+func apiAdminMemoryRoutes(t *testing.T, failOn string, err error) http.Handler {
+	db, repo := newMemoryRepository()
+	addMemoryAdministrator(t, db)
+	addMemoryReservation(t, db, models.Reservation{
+		RoomID:    1,
+		StartDate: time.Date(2098, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2098, 1, 5, 0, 0, 0, 0, time.UTC),
+	})
+	if failOn != "" {
+		db.FailOn(failOn, err)
+	}
+	return routesFor(repo)
+}
+
+func TestMemoryRepository_APIAdmin(t *testing.T) {
+	for _, v := range apiAdminMemoryTests {
+		rr := routedAPIRequest(apiAdminMemoryRoutes(t, v.failOn, v.err), v.method, v.url, "bnb_test", v.body)
+
+		var resp apiError
+		_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+		if rr.Code != v.expectedStatusCode || resp.Error.Code != v.expectedCode {
+			t.Errorf("admin API (%s) returned code: %d (%q), expected code: %d (%q)", v.name, rr.Code, resp.Error.Code, v.expectedStatusCode, v.expectedCode)
+		}
+	}
+}
+
+func TestRepository_APIAdminUpdateReservation_Fields(t *testing.T) {
+	rr := apiRequest("PUT", "/api/v1/admin/reservations/1", "bnb_test", `{"first_name":"J","last_name":"Soap","email":"joe","phone":"01234 567890"}`)
+
+	var resp apiError
+	err := json.Unmarshal(rr.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal("admin API returned invalid JSON", err)
+	}
+	for _, field := range []string{"first_name", "email"} {
+		if resp.Error.Fields[field] == "" {
+			t.Errorf("admin API did not report invalid field %s", field)
+		}
+	}
+	if _, ok := resp.Error.Fields["last_name"]; ok {
+		t.Error("admin API reported valid field last_name as invalid")
+	}
+}
@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/pricing"
+	"github.com/StratoNET/bnb-bookings/internal/stayrules"
+	"github.com/go-chi/chi/v5"
+)
+
+// isoDate is the date format used throughout the JSON API e.g. 2026-11-01
+const isoDate = "2006-01-02"
+
+// maxAPINights is the longest stay the availability API will search for
+const maxAPINights = 365
+
+// isoMonth is the month format used by the calendar API e.g. 2026-11
+const isoMonth = "2006-01"
+
+// maxCalendarMonths is the most months the calendar API will give at once
+const maxCalendarMonths = 12
+
+// API error codes, identifying the kind of problem so that callers need not rely on the wording of messages
+const (
+	apiErrMissingParameter = "missing_parameter"
+	apiErrInvalidParameter = "invalid_parameter"
+	apiErrInvalidDate      = "invalid_date"
+	apiErrInvalidDateRange = "invalid_date_range"
+	apiErrInternal         = "internal_error"
+	apiErrUnauthorized     = "unauthorized"
+	apiErrNotFound         = "not_found"
+	apiErrInvalidBody      = "invalid_body"
+	apiErrValidation       = "validation_failed"
+	apiErrConflict         = "conflict"
+	apiErrTimeout          = "timeout"
+)
+
+// apiError is the body of every JSON API error response
+type apiError struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+type apiErrorDetail struct {
+	Status    int               `json:"status"`
+	Code      string            `json:"code"`
+	Parameter string            `json:"parameter,omitempty"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"` // the problem with each invalid field of a request body
+}
+
+// apiAvailability is the body of a successful availability API response
+type apiAvailability struct {
+	Start    string                `json:"start" format:"date"`
+	End      string                `json:"end" format:"date"`
+	Nights   int                   `json:"nights"`
+	Guests   int                   `json:"guests"`
+	Children int                   `json:"children"`
+	Infants  int                   `json:"infants"`
+	Rooms    []apiRoomAvailability `json:"rooms"`
+}
+
+// reasons a room is unavailable, as given by the availability API
+const (
+	apiReasonBooked   = "booked"
+	apiReasonCapacity = "capacity"
+	apiReasonStayRule = "stay_rule"
+)
+
+// statuses of a day, as given by the calendar API. A day is arrival-only when the next day is taken & departure-only when the day
+// before is taken, as a stay can neither start nor end on a day the room is taken
+const (
+	apiDayAvailable     = "available"
+	apiDayBooked        = "booked"
+	apiDayBlocked       = "blocked"
+	apiDayArrivalOnly   = "arrival-only"
+	apiDayDepartureOnly = "departure-only"
+)
+
+// apiCalendar is the body of a successful calendar API response, giving every day of whole months
+type apiCalendar struct {
+	RoomID   int              `json:"room_id"`
+	RoomName string           `json:"room_name"`
+	Start    string           `json:"start" format:"date"`
+	End      string           `json:"end" format:"date"`
+	Days     []apiCalendarDay `json:"days"`
+}
+
+type apiCalendarDay struct {
+	Date   string `json:"date" format:"date"`
+	Status string `json:"status"`
+}
+
+type apiRoomAvailability struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Available bool      `json:"available"`
+	Reason    string    `json:"reason,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Price     *apiPrice `json:"price"`
+}
+
+// apiPrice is a quote as given by the JSON API, amounts are in pence
+type apiPrice struct {
+	Currency  string          `json:"currency"`
+	Total     int             `json:"total"`
+	Formatted string          `json:"formatted"`
+	Nights    []apiNightPrice `json:"nights"`
+}
+
+type apiNightPrice struct {
+	Date    string `json:"date" format:"date"`
+	Rate    string `json:"rate"`
+	Weekend bool   `json:"weekend"`
+	Amount  int    `json:"amount"`
+}
+
+// writeJSON writes a value as an indented JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	out, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(out)
+}
+
+// writeJSONError writes a typed JSON API error, naming the offending query parameter if there is one
+func writeJSONError(w http.ResponseWriter, status int, code, parameter, message string) {
+	writeJSON(w, status, apiError{Error: apiErrorDetail{
+		Status:    status,
+		Code:      code,
+		Parameter: parameter,
+		Message:   message,
+	}})
+}
+
+// apiPriceFromQuote converts a quote for the JSON API, a quote with no total means the price is not known
+func apiPriceFromQuote(quote models.Quote) *apiPrice {
+	if quote.Total == 0 {
+		return nil
+	}
+	price := &apiPrice{
+		Currency:  "GBP",
+		Total:     quote.Total,
+		Formatted: pricing.FormatAmount(quote.Total),
+	}
+	for _, n := range quote.Nights {
+		rate := "Standard"
+		if n.SeasonName != "" {
+			rate = n.SeasonName
+		}
+		price.Nights = append(price.Nights, apiNightPrice{
+			Date:    n.Date.Format(isoDate),
+			Rate:    rate,
+			Weekend: n.Weekend,
+			Amount:  n.Amount,
+		})
+	}
+	return price
+}
+
+// queryInt reads an optional whole number query parameter, within a given range, using a default when it is absent
+func queryInt(r *http.Request, name string, def, min, max int) (int, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s must be a whole number from %d to %d", name, min, max)
+	}
+	return n, nil
+}
+
+// APIAvailability is the handler for GET /api/v1/availability?start=2026-11-01&end=2026-11-05&guests=2, returning the availability
+// & price of every room for a stay. Guests counts adults & children, of whom an optional number of children may be given, with
+// infants optional & counted separately
+func (m *Repository) APIAvailability(w http.ResponseWriter, r *http.Request) {
+	// the API is read-only & public, so partner sites may call it directly from the browser
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	dates := make(map[string]time.Time)
+	for _, name := range []string{"start", "end"} {
+		s := r.URL.Query().Get(name)
+		if s == "" {
+			writeJSONError(w, http.StatusBadRequest, apiErrMissingParameter, name, fmt.Sprintf("%s is required, as a date in the form YYYY-MM-DD", name))
+			return
+		}
+		d, err := time.Parse(isoDate, s)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, apiErrInvalidDate, name, fmt.Sprintf("%s must be a date in the form YYYY-MM-DD", name))
+			return
+		}
+		dates[name] = d
+	}
+	startDate, endDate := dates["start"], dates["end"]
+
+	// dates are parsed as UTC, so today is too
+	y, mth, d := time.Now().Date()
+	today := time.Date(y, mth, d, 0, 0, 0, 0, time.UTC)
+	nights := int(endDate.Sub(startDate).Hours() / 24)
+	switch {
+	case startDate.Before(today):
+		writeJSONError(w, http.StatusBadRequest, apiErrInvalidDateRange, "start", "start cannot be in the past")
+		return
+	case nights < 1:
+		writeJSONError(w, http.StatusBadRequest, apiErrInvalidDateRange, "end", "end must be after start")
+		return
+	case nights > maxAPINights:
+		writeJSONError(w, http.StatusBadRequest, apiErrInvalidDateRange, "end", fmt.Sprintf("stays can be no more than %d nights", maxAPINights))
+		return
+	}
+
+	if r.URL.Query().Get("guests") == "" {
+		writeJSONError(w, http.StatusBadRequest, apiErrMissingParameter, "guests", fmt.Sprintf("guests is required, as a whole number from 1 to %d", maxPartySize))
+		return
+	}
+	guests, err := queryInt(r, "guests", 0, 1, maxPartySize)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, apiErrInvalidParameter, "guests", err.Error())
+		return
+	}
+	children, err := queryInt(r, "children", 0, 0, guests-1)
+	if err != nil {
+		// at least one guest must be an adult
+		writeJSONError(w, http.StatusBadRequest, apiErrInvalidParameter, "children", err.Error())
+		return
+	}
+	infants, err := queryInt(r, "infants", 0, 0, maxPartySize)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, apiErrInvalidParameter, "infants", err.Error())
+		return
+	}
+
+	rooms, err := m.DB.GetAllRooms(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		writeJSONError(w, http.StatusInternalServerError, apiErrInternal, "", "cannot get rooms")
+		return
+	}
+
+	// a room the guest is already holding, having chosen it in the same session, is available to them
+	holdToken := m.App.Session.GetString(r.Context(), "hold_token")
+
+	resp := apiAvailability{
+		Start:    startDate.Format(isoDate),
+		End:      endDate.Format(isoDate),
+		Nights:   nights,
+		Guests:   guests,
+		Children: children,
+		Infants:  infants,
+		Rooms:    []apiRoomAvailability{},
+	}
+
+	for _, room := range rooms {
+		ra := apiRoomAvailability{
+			ID:   room.ID,
+			Name: room.RoomName,
+		}
+
+		available, err := m.DB.SearchAvailabilityByDatesAndRoomID(r.Context(), startDate, endDate, room.ID, holdToken)
+		if err != nil {
+			if m.dbInterrupted(w, r, err) {
+				return
+			}
+			m.App.ErrorLog.Println(err)
+			writeJSONError(w, http.StatusInternalServerError, apiErrInternal, "", "cannot search availability")
+			return
+		}
+
+		var violation *stayrules.Violation
+		switch {
+		case !available:
+			ra.Reason = apiReasonBooked
+			ra.Message = "the room is already booked for some or all of these dates"
+		case !room.Accommodates(guests-children, children, infants):
+			ra.Reason = apiReasonCapacity
+			ra.Message = fmt.Sprintf("the room can accommodate at most %s", models.PartyDescription(room.MaxAdults, room.MaxChildren, room.MaxInfants))
+		default:
+			err = m.checkStayRules(r.Context(), room.ID, startDate, endDate)
+			if m.dbInterrupted(w, r, err) {
+				return
+			}
+			if errors.As(err, &violation) {
+				ra.Reason = apiReasonStayRule
+				ra.Message = violation.Error()
+			} else if err != nil {
+				m.App.ErrorLog.Println(err)
+				writeJSONError(w, http.StatusInternalServerError, apiErrInternal, "", "cannot get stay rules")
+				return
+			} else {
+				ra.Available = true
+			}
+		}
+
+		quote, err := m.quoteRoom(r.Context(), room, startDate, endDate, guests)
+		if err != nil {
+			if m.dbInterrupted(w, r, err) {
+				return
+			}
+			m.App.ErrorLog.Println(err)
+		} else {
+			ra.Price = apiPriceFromQuote(quote)
+		}
+
+		resp.Rooms = append(resp.Rooms, ra)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// calendarDays gives the status of each day from first to last. As when searching availability, a restriction takes every day from its
+// start to its end date inclusive, so restrictions for the days either side are needed to tell whether a stay can start or end on the
+// first & last days
+func calendarDays(restrictions []models.RoomRestriction, first, last time.Time) []apiCalendarDay {
+	from, to := first.AddDate(0, 0, -1), last.AddDate(0, 0, 1)
+
+	taken := make(map[string]string)
+	for _, rr := range restrictions {
+		status := apiDayBooked
+		if rr.RestrictionID == models.RestrictionOwnerBlock {
+			status = apiDayBlocked
+		}
+		d := rr.StartDate
+		if d.Before(from) {
+			d = from
+		}
+		for ; !d.After(rr.EndDate) && !d.After(to); d = d.AddDate(0, 0, 1) {
+			// a day both booked & blocked is shown as booked, as a guest is staying
+			if taken[d.Format(isoDate)] != apiDayBooked {
+				taken[d.Format(isoDate)] = status
+			}
+		}
+	}
+	takenBy := func(d time.Time) string {
+		return taken[d.Format(isoDate)]
+	}
+
+	days := []apiCalendarDay{}
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		status := takenBy(d)
+		if status == "" {
+			arrive := takenBy(d.AddDate(0, 0, 1)) == ""
+			depart := takenBy(d.AddDate(0, 0, -1)) == ""
+			switch {
+			case arrive && depart:
+				status = apiDayAvailable
+			case arrive:
+				status = apiDayArrivalOnly
+			case depart:
+				status = apiDayDepartureOnly
+			default:
+				// a single day between two restrictions, on which a stay can neither start nor end
+				status = takenBy(d.AddDate(0, 0, 1))
+			}
+		}
+		days = append(days, apiCalendarDay{Date: d.Format(isoDate), Status: status})
+	}
+	return days
+}
+
+// APIRoomCalendar is the handler for GET /api/v1/rooms/1/calendar?start=2026-11&end=2027-01, returning the status of each day of a
+// room's calendar for whole months, so that date pickers can disable the days on which a stay cannot start or end. End is optional,
+// giving start's month alone
+func (m *Repository) APIRoomCalendar(w http.ResponseWriter, r *http.Request) {
+	// the API is read-only & public, so partner sites may call it directly from the browser
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id < 1 {
+		writeJSONError(w, http.StatusNotFound, apiErrNotFound, "", "room not found")
+		return
+	}
+
+	months := make(map[string]time.Time)
+	for _, name := range []string{"start", "end"} {
+		s := r.URL.Query().Get(name)
+		if s == "" && name == "end" {
+			months[name] = months["start"]
+			continue
+		}
+		if s == "" {
+			writeJSONError(w, http.StatusBadRequest, apiErrMissingParameter, name, fmt.Sprintf("%s is required, as a month in the form YYYY-MM", name))
+			return
+		}
+		mth, err := time.Parse(isoMonth, s)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, apiErrInvalidDate, name, fmt.Sprintf("%s must be a month in the form YYYY-MM", name))
+			return
+		}
+		months[name] = mth
+	}
+	startMonth, endMonth := months["start"], months["end"]
+
+	n := (endMonth.Year()-startMonth.Year())*12 + int(endMonth.Month()-startMonth.Month()) + 1
+	switch {
+	case n < 1:
+		writeJSONError(w, http.StatusBadRequest, apiErrInvalidDateRange, "end", "end cannot be before start")
+		return
+	case n > maxCalendarMonths:
+		writeJSONError(w, http.StatusBadRequest, apiErrInvalidDateRange, "end", fmt.Sprintf("the calendar can give no more than %d months at once", maxCalendarMonths))
+		return
+	}
+
+	room, err := m.DB.GetRoomByID(r.Context(), id)
+	if m.dbInterrupted(w, r, err) {
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, http.StatusNotFound, apiErrNotFound, "", "room not found")
+		return
+	} else if err != nil {
+		m.App.ErrorLog.Println(err)
+		writeJSONError(w, http.StatusInternalServerError, apiErrInternal, "", "cannot get room")
+		return
+	}
+
+	first, last := startMonth, endMonth.AddDate(0, 1, -1)
+	restrictions, err := m.DB.GetRoomRestrictionsForCalendar(r.Context(), room.ID, first.AddDate(0, 0, -1), last.AddDate(0, 0, 1))
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		writeJSONError(w, http.StatusInternalServerError, apiErrInternal, "", "cannot get room calendar")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiCalendar{
+		RoomID:   room.ID,
+		RoomName: room.RoomName,
+		Start:    first.Format(isoDate),
+		End:      last.Format(isoDate),
+		Days:     calendarDays(restrictions, first, last),
+	})
+}
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/models"
+)
+
+var apiAvailabilityErrorTests = []struct {
+	name               string
+	query              string
+	expectedStatusCode int
+	expectedCode       string
+	expectedParameter  string
+}{
+	{"missing-start", "end=2098-01-05&guests=2", http.StatusBadRequest, apiErrMissingParameter, "start"},
+	{"missing-end", "start=2098-01-01&guests=2", http.StatusBadRequest, apiErrMissingParameter, "end"},
+	{"uk-date", "start=01/01/2098&end=2098-01-05&guests=2", http.StatusBadRequest, apiErrInvalidDate, "start"},
+	{"impossible-date", "start=2098-01-01&end=2098-02-30&guests=2", http.StatusBadRequest, apiErrInvalidDate, "end"},
+	{"start-in-past", "start=2001-01-01&end=2001-01-05&guests=2", http.StatusBadRequest, apiErrInvalidDateRange, "start"},
+	{"end-before-start", "start=2098-01-05&end=2098-01-01&guests=2", http.StatusBadRequest, apiErrInvalidDateRange, "end"},
+	{"same-day", "start=2098-01-05&end=2098-01-05&guests=2", http.StatusBadRequest, apiErrInvalidDateRange, "end"},
+	{"too-long", "start=2098-01-01&end=2099-06-01&guests=2", http.StatusBadRequest, apiErrInvalidDateRange, "end"},
+	{"missing-guests", "start=2098-01-01&end=2098-01-05", http.StatusBadRequest, apiErrMissingParameter, "guests"},
+	{"no-guests", "start=2098-01-01&end=2098-01-05&guests=0", http.StatusBadRequest, apiErrInvalidParameter, "guests"},
+	{"guests-not-number", "start=2098-01-01&end=2098-01-05&guests=two", http.StatusBadRequest, apiErrInvalidParameter, "guests"},
+	{"only-children", "start=2098-01-01&end=2098-01-05&guests=2&children=2", http.StatusBadRequest, apiErrInvalidParameter, "children"},
+	{"negative-infants", "start=2098-01-01&end=2098-01-05&guests=2&infants=-1", http.StatusBadRequest, apiErrInvalidParameter, "infants"},
+	{"database-error", "start=2099-01-01&end=2099-01-05&guests=2", http.StatusInternalServerError, apiErrInternal, ""},
+}
+
+func TestRepository_APIAvailability_Errors(t *testing.T) {
+	routes := getRoutes()
+
+	for _, v := range apiAvailabilityErrorTests {
+		req, _ := http.NewRequest("GET", "/api/v1/availability?"+v.query, nil)
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("APIAvailability handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+		}
+		if rr.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("APIAvailability handler (%s) returned content type: %s, expected application/json", v.name, rr.Header().Get("Content-Type"))
+		}
+
+		var resp apiError
+		err := json.Unmarshal(rr.Body.Bytes(), &resp)
+		if err != nil {
+			t.Errorf("APIAvailability handler (%s) returned invalid JSON: %v", v.name, err)
+			continue
+		}
+		if resp.Error.Status != v.expectedStatusCode || resp.Error.Code != v.expectedCode || resp.Error.Parameter != v.expectedParameter {
+			t.Errorf("APIAvailability handler (%s) returned error %+v, expected code %s for parameter %q", v.name, resp.Error, v.expectedCode, v.expectedParameter)
+		}
+		if resp.Error.Message == "" {
+			t.Errorf("APIAvailability handler (%s) returned an error without a message", v.name)
+		}
+	}
+}
+
+var apiAvailabilityTests = []struct {
+	name              string
+	query             string
+	expectedNights    int
+	expectedAvailable map[int]bool
+	expectedReasons   map[int]string
+}{
+	{"both-rooms", "start=2098-01-01&end=2098-01-05&guests=2", 4, map[int]bool{1: true, 2: true}, map[int]string{}},
+	{"too-many-adults", "start=2098-01-01&end=2098-01-05&guests=3", 4, map[int]bool{1: false, 2: true}, map[int]string{1: apiReasonCapacity}},
+	{"family", "start=2098-01-01&end=2098-01-05&guests=3&children=1&infants=1", 4, map[int]bool{1: true, 2: true}, map[int]string{}},
+	{"party-too-large", "start=2098-01-01&end=2098-01-05&guests=5&children=1", 4, map[int]bool{1: false, 2: false}, map[int]string{1: apiReasonCapacity, 2: apiReasonCapacity}},
+}
+
+func TestRepository_APIAvailability(t *testing.T) {
+	routes := getRoutes()
+
+	for _, v := range apiAvailabilityTests {
+		req, _ := http.NewRequest("GET", "/api/v1/availability?"+v.query, nil)
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("APIAvailability handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, http.StatusOK)
+			continue
+		}
+		if rr.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("APIAvailability handler (%s) cannot be called from partner sites", v.name)
+		}
+
+		var resp apiAvailability
+		err := json.Unmarshal(rr.Body.Bytes(), &resp)
+		if err != nil {
+			t.Errorf("APIAvailability handler (%s) returned invalid JSON: %v", v.name, err)
+			continue
+		}
+		if resp.Nights != v.expectedNights {
+			t.Errorf("APIAvailability handler (%s) returned %d nights, expected %d", v.name, resp.Nights, v.expectedNights)
+		}
+		if len(resp.Rooms) != len(v.expectedAvailable) {
+			t.Errorf("APIAvailability handler (%s) returned %d rooms, expected %d", v.name, len(resp.Rooms), len(v.expectedAvailable))
+		}
+
+		for _, room := range resp.Rooms {
+			if room.Available != v.expectedAvailable[room.ID] {
+				t.Errorf("APIAvailability handler (%s) returned room %d available: %t, expected %t", v.name, room.ID, room.Available, v.expectedAvailable[room.ID])
+			}
+			if room.Reason != v.expectedReasons[room.ID] {
+				t.Errorf("APIAvailability handler (%s) returned room %d reason: %q, expected %q", v.name, room.ID, room.Reason, v.expectedReasons[room.ID])
+			}
+			if room.Price == nil || room.Price.Currency != "GBP" || len(room.Price.Nights) != v.expectedNights {
+				t.Errorf("APIAvailability handler (%s) returned room %d without a price for every night", v.name, room.ID)
+				continue
+			}
+			total := 0
+			for _, n := range room.Price.Nights {
+				total += n.Amount
+			}
+			if total != room.Price.Total {
+				t.Errorf("APIAvailability handler (%s) returned room %d nights totalling %d, expected %d", v.name, room.ID, total, room.Price.Total)
+			}
+		}
+	}
+}
+
+func TestMemoryRepository_APIAvailabilityStayRule(t *testing.T) {
+	db, repo := newMemoryRepository()
+	addMemoryStayRule(t, db)
+
+	req, _ := http.NewRequest("GET", "/api/v1/availability?start=2097-05-01&end=2097-05-02&guests=2", nil)
+	rr := httptest.NewRecorder()
+	routesFor(repo).ServeHTTP(rr, req)
+
+	var resp apiAvailability
+	err := json.Unmarshal(rr.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatalf("APIAvailability handler returned invalid JSON: %v", err)
+	}
+	if len(resp.Rooms) != 2 {
+		t.Fatalf("APIAvailability handler returned %d rooms, expected 2", len(resp.Rooms))
+	}
+	// a single night over the Bank Holiday Weekend is too short a stay in either room
+	for _, room := range resp.Rooms {
+		if room.Available || room.Reason != apiReasonStayRule {
+			t.Errorf("APIAvailability handler returned room %d available: %t, reason: %q, expected unavailable for %q", room.ID, room.Available, room.Reason, apiReasonStayRule)
+		}
+	}
+}
+
+func TestAPIPriceFromQuote(t *testing.T) {
+	// a room with no rates has no known price
+	if price := apiPriceFromQuote(models.Quote{}); price != nil {
+		t.Errorf("expected no price for an empty quote, got %+v", price)
+	}
+}
+
+var apiRoomCalendarErrorTests = []struct {
+	name               string
+	url                string
+	expectedStatusCode int
+	expectedCode       string
+	expectedParameter  string
+}{
+	{"missing-start", "/api/v1/rooms/1/calendar", http.StatusBadRequest, apiErrMissingParameter, "start"},
+	{"date-not-month", "/api/v1/rooms/1/calendar?start=2098-03-01", http.StatusBadRequest, apiErrInvalidDate, "start"},
+	{"impossible-month", "/api/v1/rooms/1/calendar?start=2098-03&end=2098-13", http.StatusBadRequest, apiErrInvalidDate, "end"},
+	{"end-before-start", "/api/v1/rooms/1/calendar?start=2098-03&end=2098-02", http.StatusBadRequest, apiErrInvalidDateRange, "end"},
+	{"too-many-months", "/api/v1/rooms/1/calendar?start=2098-03&end=2099-03", http.StatusBadRequest, apiErrInvalidDateRange, "end"},
+	{"room-not-number", "/api/v1/rooms/gq/calendar?start=2098-03", http.StatusNotFound, apiErrNotFound, ""},
+	{"room-error", "/api/v1/rooms/3/calendar?start=2098-03", http.StatusInternalServerError, apiErrInternal, ""},
+}
+
+func TestRepository_APIRoomCalendar_Errors(t *testing.T) {
+	routes := getRoutes()
+
+	for _, v := range apiRoomCalendarErrorTests {
+		req, _ := http.NewRequest("GET", v.url, nil)
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("APIRoomCalendar handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+		}
+
+		var resp apiError
+		err := json.Unmarshal(rr.Body.Bytes(), &resp)
+		if err != nil {
+			t.Errorf("APIRoomCalendar handler (%s) returned invalid JSON: %v", v.name, err)
+			continue
+		}
+		if resp.Error.Code != v.expectedCode || resp.Error.Parameter != v.expectedParameter {
+			t.Errorf("APIRoomCalendar handler (%s) returned error %+v, expected code %s for parameter %q", v.name, resp.Error, v.expectedCode, v.expectedParameter)
+		}
+	}
+}
+
+func TestMemoryRepository_APIRoomCalendar_Errors(t *testing.T) {
+	db, repo := newMemoryRepository()
+
+	// a room never added is not found
+	req, _ := http.NewRequest("GET", "/api/v1/rooms/8888/calendar?start=2098-03", nil)
+	rr := httptest.NewRecorder()
+	routesFor(repo).ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("APIRoomCalendar handler (room-not-found) returned code: %d, expected code: %d", rr.Code, http.StatusNotFound)
+	}
+
+	// an existing room whose restrictions cannot be read
+	db.FailOn("GetRoomRestrictionsForCalendar", errMemoryDB)
+	req, _ = http.NewRequest("GET", "/api/v1/rooms/2/calendar?start=2098-03", nil)
+	rr = httptest.NewRecorder()
+	routesFor(repo).ServeHTTP(rr, req)
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("APIRoomCalendar handler (database-error) returned code: %d, expected code: %d", rr.Code, http.StatusInternalServerError)
+	}
+}
+
+var apiRoomCalendarTests = []struct {
+	name          string
+	url           string
+	expectedStart string
+	expectedEnd   string
+	expectedDays  int
+}{
+	{"one-month", "/api/v1/rooms/1/calendar?start=2098-03", "2098-03-01", "2098-03-31", 31},
+	{"leap-february", "/api/v1/rooms/1/calendar?start=2096-02&end=2096-02", "2096-02-01", "2096-02-29", 29},
+	{"over-new-year", "/api/v1/rooms/1/calendar?start=2097-12&end=2098-03", "2097-12-01", "2098-03-31", 121},
+}
+
+func TestRepository_APIRoomCalendar(t *testing.T) {
+	routes := getRoutes()
+
+	for _, v := range apiRoomCalendarTests {
+		req, _ := http.NewRequest("GET", v.url, nil)
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("APIRoomCalendar handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, http.StatusOK)
+			continue
+		}
+		if rr.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("APIRoomCalendar handler (%s) cannot be called from partner sites", v.name)
+		}
+
+		var resp apiCalendar
+		err := json.Unmarshal(rr.Body.Bytes(), &resp)
+		if err != nil {
+			t.Errorf("APIRoomCalendar handler (%s) returned invalid JSON: %v", v.name, err)
+			continue
+		}
+		if resp.RoomID != 1 || resp.Start != v.expectedStart || resp.End != v.expectedEnd || len(resp.Days) != v.expectedDays {
+			t.Errorf("APIRoomCalendar handler (%s) returned room %d from %s to %s with %d days, expected room 1 from %s to %s with %d days",
+				v.name, resp.RoomID, resp.Start, resp.End, len(resp.Days), v.expectedStart, v.expectedEnd, v.expectedDays)
+			continue
+		}
+		if resp.Days[0].Date != v.expectedStart || resp.Days[len(resp.Days)-1].Date != v.expectedEnd {
+			t.Errorf("APIRoomCalendar handler (%s) returned days from %s to %s", v.name, resp.Days[0].Date, resp.Days[len(resp.Days)-1].Date)
+		}
+	}
+}
+
+func TestCalendarDays(t *testing.T) {
+	date := func(day int) time.Time {
+		return time.Date(2098, 3, day, 0, 0, 0, 0, time.UTC)
+	}
+	restrictions := []models.RoomRestriction{
+		{RestrictionID: models.RestrictionReservation, StartDate: date(10), EndDate: date(13)},
+		{RestrictionID: models.RestrictionHold, StartDate: date(15), EndDate: date(16)},
+		{RestrictionID: models.RestrictionOwnerBlock, StartDate: date(20), EndDate: date(21)},
+		{RestrictionID: models.RestrictionExternalBooking, StartDate: date(26), EndDate: date(27)},
+		{RestrictionID: models.RestrictionOwnerBlock, StartDate: date(27), EndDate: date(28)},
+		// a block running on past the last day
+		{RestrictionID: models.RestrictionOwnerBlock, StartDate: date(31), EndDate: date(31).AddDate(1, 0, 0)},
+	}
+
+	expected := map[int]string{
+		8: apiDayAvailable, 9: apiDayDepartureOnly,
+		10: apiDayBooked, 13: apiDayBooked,
+		// between a reservation & a hold, so neither arrival nor departure is possible
+		14: apiDayBooked,
+		15: apiDayBooked, 16: apiDayBooked, 17: apiDayArrivalOnly, 18: apiDayAvailable, 19: apiDayDepartureOnly,
+		20: apiDayBlocked, 21: apiDayBlocked, 22: apiDayArrivalOnly, 23: apiDayAvailable,
+		// an external booking & a block overlapping on the 27th
+		27: apiDayBooked, 28: apiDayBlocked, 29: apiDayArrivalOnly, 30: apiDayDepartureOnly, 31: apiDayBlocked,
+	}
+
+	days := calendarDays(restrictions, date(1), date(31))
+	if len(days) != 31 {
+		t.Fatalf("expected 31 days, got %d", len(days))
+	}
+	for day, status := range expected {
+		if days[day-1].Date != date(day).Format(isoDate) || days[day-1].Status != status {
+			t.Errorf("expected %s to be %s, got %+v", date(day).Format(isoDate), status, days[day-1])
+		}
+	}
+
+	// restrictions either side of the days asked for are still taken into account
+	days = calendarDays(restrictions, date(14), date(14))
+	if len(days) != 1 || days[0].Status != apiDayBooked {
+		t.Errorf("expected a single booked day, got %+v", days)
+	}
+	days = calendarDays(restrictions, date(22), date(22))
+	if len(days) != 1 || days[0].Status != apiDayArrivalOnly {
+		t.Errorf("expected a single arrival-only day, got %+v", days)
+	}
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/channel"
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/pricing"
+)
+
+// channelTimeout is how long the channel manager is given to respond to a message
+const channelTimeout = 30 * time.Second
+
+// channelLogSize is how many of the most recent messages are shown in the sync log
+const channelLogSize = 100
+
+// channelCurrency is the currency of every rate pushed to the channel manager
+const channelCurrency = "GBP"
+
+// channelCode is the form of a room type or rate plan code at the channel manager e.g. GQ or BAR
+var channelCode = regexp.MustCompile(`^[A-Za-z0-9._-]{1,32}$`)
+
+var channelHTTP = &http.Client{Timeout: channelTimeout}
+
+// channelWait is called between attempts at pushing a message, waiting channel.Backoff when nil
+var channelWait func(attempts int)
+
+// syncMu stops the channel manager being synced twice at once, by the syncer & an administrator, which could push a change twice
+var syncMu sync.Mutex
+
+// channelSyncs holds a request for the channel syncer to sync straight away. One request is enough, as a sync pushes every change
+var channelSyncs = make(chan struct{}, 1)
+
+// ChannelSyncRequests is notified whenever a change is made which the channel manager should be told of
+func ChannelSyncRequests() <-chan struct{} {
+	return channelSyncs
+}
+
+// RequestChannelSync asks the channel syncer to push changes straight away, without waiting for it to do so, whenever a room's restrictions
+// are written. A sync finding no change pushes nothing
+func RequestChannelSync() {
+	select {
+	case channelSyncs <- struct{}{}:
+	default:
+	}
+}
+
+// channelDelta is the changes to a room's availability & rates to be pushed to the channel manager, in runs of nights, together with
+// each changed night as it will be once pushed
+type channelDelta struct {
+	Avail     []channel.Availability
+	Rates     []channel.Rate
+	AvailDays []models.ChannelDay
+	RateDays  []models.ChannelDay
+}
+
+// channelDays gives a room's availability & nightly rate for n nights from first. As when searching availability, a night is open
+// only if a stay can both arrive that day & depart the next. Guests' holds are left out, as they are soon either released or become
+// reservations. Rates are for the room's included guests
+func channelDays(room models.Room, restrictions []models.RoomRestriction, seasons []models.SeasonalRate, first time.Time, n int) ([]models.ChannelDay, error) {
+	last := first.AddDate(0, 0, n-1)
+
+	var taken []models.RoomRestriction
+	for _, rr := range restrictions {
+		if rr.RestrictionID != models.RestrictionHold {
+			taken = append(taken, rr)
+		}
+	}
+
+	guests := room.IncludedGuests
+	if guests < 1 {
+		guests = 1
+	}
+	quote, err := pricing.QuoteStay(room, seasons, first, last.AddDate(0, 0, 1), guests)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []models.ChannelDay
+	for i, d := range calendarDays(taken, first, last) {
+		days = append(days, models.ChannelDay{
+			Date: first.AddDate(0, 0, i),
+			Open: d.Status == apiDayAvailable || d.Status == apiDayArrivalOnly,
+			Rate: quote.Nights[i].Amount,
+		})
+	}
+	return days, nil
+}
+
+// channelChanges compares a room's nights as they are with what was last pushed for them, giving the changes to push. A night never
+// pushed is a change, whereas a night without a rate has no rate to push, nor does a room without a rate plan
+func channelChanges(cr models.ChannelRoom, pushed, wanted []models.ChannelDay) channelDelta {
+	var delta channelDelta
+
+	last := make(map[string]models.ChannelDay)
+	for _, d := range pushed {
+		last[d.Date.Format(isoDate)] = d
+	}
+
+	for _, d := range wanted {
+		p, ok := last[d.Date.Format(isoDate)]
+
+		if !ok || p.Open != d.Open {
+			// a change continuing the last run, with the same availability from the night before, extends it
+			if n := len(delta.Avail); n > 0 && delta.Avail[n-1].Open == d.Open && delta.Avail[n-1].End.AddDate(0, 0, 1).Equal(d.Date) {
+				delta.Avail[n-1].End = d.Date
+			} else {
+				delta.Avail = append(delta.Avail, channel.Availability{RoomCode: cr.RoomCode, Start: d.Date, End: d.Date, Open: d.Open})
+			}
+			delta.AvailDays = append(delta.AvailDays, models.ChannelDay{Date: d.Date, Open: d.Open, Rate: p.Rate})
+		}
+
+		if cr.RatePlanCode != "" && d.Rate > 0 && (!ok || p.Rate != d.Rate) {
+			if n := len(delta.Rates); n > 0 && delta.Rates[n-1].Amount == d.Rate && delta.Rates[n-1].End.AddDate(0, 0, 1).Equal(d.Date) {
+				delta.Rates[n-1].End = d.Date
+			} else {
+				delta.Rates = append(delta.Rates, channel.Rate{
+					RoomCode:     cr.RoomCode,
+					RatePlanCode: cr.RatePlanCode,
+					Start:        d.Date,
+					End:          d.Date,
+					Amount:       d.Rate,
+					Currency:     channelCurrency,
+					Guests:       cr.Room.IncludedGuests,
+				})
+			}
+			delta.RateDays = append(delta.RateDays, d)
+		}
+	}
+	return delta
+}
+
+// pushChannel pushes a message to the channel manager, recording the outcome in the sync log
+func (m *Repository) pushChannel(ctx context.Context, cr models.ChannelRoom, message string, body []byte, start, end time.Time, changes int) error {
+	client := &channel.Client{HTTP: channelHTTP, URL: m.App.Channel.URL, Wait: channelWait}
+	res, err := client.Push(body)
+
+	s := models.ChannelSync{
+		RoomID:       cr.RoomID,
+		Message:      message,
+		StartDate:    start,
+		EndDate:      end,
+		Changes:      changes,
+		Status:       models.DeliveryDelivered,
+		Attempts:     res.Attempts,
+		ResponseCode: res.ResponseCode,
+		Request:      string(body),
+	}
+	if err != nil {
+		s.Status = models.DeliveryFailed
+		s.LastError = err.Error()
+	}
+
+	logErr := m.DB.InsertChannelSync(ctx, s)
+	if logErr != nil {
+		m.App.ErrorLog.Println(logErr)
+	}
+	return err
+}
+
+// syncChannelRoom pushes the changes to a room's availability & rates, for the configured number of nights from first. Availability
+// is pushed before rates, so that a room is never sold at a new rate on nights it should be closed. Nights are recorded as pushed
+// only once the channel manager has accepted them, so a failed push is tried again by the next sync
+func (m *Repository) syncChannelRoom(ctx context.Context, cr models.ChannelRoom, room models.Room, first time.Time) error {
+	n := m.App.ChannelDays
+	last := first.AddDate(0, 0, n-1)
+
+	// the days either side are needed to tell whether the first & last nights are open
+	restrictions, err := m.DB.GetRoomRestrictionsForCalendar(ctx, room.ID, first.AddDate(0, 0, -1), last.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+	seasons, err := m.DB.GetSeasonalRatesByRoomID(ctx, room.ID, first, last.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+	wanted, err := channelDays(room, restrictions, seasons, first, n)
+	if err != nil {
+		return err
+	}
+	pushed, err := m.DB.GetChannelDays(ctx, room.ID, first, last)
+	if err != nil {
+		return err
+	}
+
+	cr.Room = room
+	delta := channelChanges(cr, pushed, wanted)
+
+	if len(delta.Avail) > 0 {
+		body, err := channel.NewAvailNotif(m.App.Channel, time.Now(), delta.Avail)
+		if err != nil {
+			return err
+		}
+		err = m.pushChannel(ctx, cr, channel.AvailNotif, body, delta.Avail[0].Start, delta.Avail[len(delta.Avail)-1].End, len(delta.Avail))
+		if err != nil {
+			return err
+		}
+		err = m.DB.SaveChannelDays(ctx, room.ID, delta.AvailDays)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(delta.Rates) > 0 {
+		body, err := channel.NewRateAmountNotif(m.App.Channel, time.Now(), delta.Rates)
+		if err != nil {
+			return err
+		}
+		err = m.pushChannel(ctx, cr, channel.RateAmountNotif, body, delta.Rates[0].Start, delta.Rates[len(delta.Rates)-1].End, len(delta.Rates))
+		if err != nil {
+			return err
+		}
+		err = m.DB.SaveChannelDays(ctx, room.ID, delta.RateDays)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SyncChannel pushes the changes to every mapped room's availability & rates to the channel manager, from today for the configured
+// number of days. Nothing is pushed unless a channel manager is configured
+func (m *Repository) SyncChannel(ctx context.Context) {
+	if m.App.Channel.URL == "" {
+		return
+	}
+
+	syncMu.Lock()
+	defer syncMu.Unlock()
+
+	mapped, err := m.DB.GetChannelRooms(ctx)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		return
+	}
+	if len(mapped) == 0 {
+		return
+	}
+
+	rooms, err := m.DB.GetAllRooms(ctx)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		return
+	}
+	byID := make(map[int]models.Room)
+	for _, room := range rooms {
+		byID[room.ID] = room
+	}
+
+	first := calendarDay(time.Now())
+	for _, cr := range mapped {
+		room, ok := byID[cr.RoomID]
+		if !ok {
+			continue
+		}
+		err = m.syncChannelRoom(ctx, cr, room, first)
+		if err != nil {
+			m.App.ErrorLog.Printf("Cannot sync %s with the channel manager: %s\n", room.RoomName, err)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/channel"
+	"github.com/StratoNET/bnb-bookings/internal/models"
+)
+
+func march(day int) time.Time {
+	return time.Date(2098, 3, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestChannelDays(t *testing.T) {
+	room := models.Room{ID: 1, NightlyRate: 8000, WeekendRate: 9500}
+	restrictions := []models.RoomRestriction{
+		{RestrictionID: models.RestrictionReservation, StartDate: march(3), EndDate: march(5)},
+		// a guest's hold leaves the room open to the channel
+		{RestrictionID: models.RestrictionHold, StartDate: march(8), EndDate: march(9)},
+	}
+
+	days, err := channelDays(room, restrictions, nil, march(1), 10)
+	if err != nil {
+		t.Fatal("channelDays failed", err)
+	}
+	if len(days) != 10 {
+		t.Fatalf("expected 10 nights, got %d", len(days))
+	}
+
+	closed := map[int]bool{2: true, 3: true, 4: true, 5: true}
+	for i, d := range days {
+		if !d.Date.Equal(march(i+1)) || d.Open == closed[i+1] {
+			t.Errorf("expected %s open: %t, got %+v", march(i+1).Format(isoDate), !closed[i+1], d)
+		}
+		rate := 8000
+		if d.Date.Weekday() == time.Friday || d.Date.Weekday() == time.Saturday {
+			rate = 9500
+		}
+		if d.Rate != rate {
+			t.Errorf("expected %s at %d, got %d", d.Date.Format(isoDate), rate, d.Rate)
+		}
+	}
+}
+
+func TestChannelChanges(t *testing.T) {
+	cr := models.ChannelRoom{RoomID: 1, RoomCode: "GQ", RatePlanCode: "BAR", Room: models.Room{IncludedGuests: 2}}
+	wanted := []models.ChannelDay{
+		{Date: march(1), Open: true, Rate: 8000},
+		{Date: march(2), Open: false, Rate: 8000},
+		{Date: march(3), Open: false, Rate: 9500},
+		{Date: march(4), Open: true, Rate: 9500},
+		{Date: march(5), Open: true, Rate: 8000},
+	}
+	pushed := []models.ChannelDay{
+		{Date: march(1), Open: true, Rate: 8000},
+		{Date: march(2), Open: true, Rate: 8000},
+		{Date: march(3), Open: true, Rate: 9500},
+		{Date: march(4), Open: true},
+	}
+
+	delta := channelChanges(cr, pushed, wanted)
+
+	expectedAvail := []channel.Availability{
+		{RoomCode: "GQ", Start: march(2), End: march(3), Open: false},
+		{RoomCode: "GQ", Start: march(5), End: march(5), Open: true},
+	}
+	if len(delta.Avail) != len(expectedAvail) {
+		t.Fatalf("expected availability changes %+v, got %+v", expectedAvail, delta.Avail)
+	}
+	for i, a := range expectedAvail {
+		if delta.Avail[i] != a {
+			t.Errorf("expected availability change %+v, got %+v", a, delta.Avail[i])
+		}
+	}
+
+	expectedRates := []channel.Rate{
+		{RoomCode: "GQ", RatePlanCode: "BAR", Start: march(4), End: march(4), Amount: 9500, Currency: "GBP", Guests: 2},
+		{RoomCode: "GQ", RatePlanCode: "BAR", Start: march(5), End: march(5), Amount: 8000, Currency: "GBP", Guests: 2},
+	}
+	if len(delta.Rates) != len(expectedRates) {
+		t.Fatalf("expected rate changes %+v, got %+v", expectedRates, delta.Rates)
+	}
+	for i, r := range expectedRates {
+		if delta.Rates[i] != r {
+			t.Errorf("expected rate change %+v, got %+v", r, delta.Rates[i])
+		}
+	}
+
+	// nights are recorded as they will be once pushed, keeping the rate last pushed until rates are pushed too
+	if len(delta.AvailDays) != 3 || delta.AvailDays[2] != (models.ChannelDay{Date: march(5), Open: true}) {
+		t.Errorf("unexpected nights to record once availability is pushed %+v", delta.AvailDays)
+	}
+	if len(delta.RateDays) != 2 || delta.RateDays[1] != wanted[4] {
+		t.Errorf("unexpected nights to record once rates are pushed %+v", delta.RateDays)
+	}
+
+	// nothing has changed once everything is pushed, & without a rate plan no rates are pushed
+	if delta := channelChanges(cr, wanted, wanted); len(delta.Avail) != 0 || len(delta.Rates) != 0 {
+		t.Errorf("expected no changes, got %+v", delta)
+	}
+	cr.RatePlanCode = ""
+	if delta := channelChanges(cr, nil, wanted); len(delta.Avail) != 3 || len(delta.Rates) != 0 {
+		t.Errorf("expected availability only, got %+v", delta)
+	}
+}
+
+// stubChannelManager is a local stand in for the channel manager, recording each message posted to it & answering with a 503 to
+// any posted to /down
+func stubChannelManager(t *testing.T) (*httptest.Server, *[]string) {
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received = append(received, string(b))
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`<OTA_HotelAvailNotifRS xmlns="http://www.opentravel.org/OTA/2003/05" Version="1.0"><Success/></OTA_HotelAvailNotifRS>`))
+	}))
+
+	previous, previousWait := app.Channel, channelWait
+	app.Channel.URL = srv.URL + "/ota"
+	channelWait = func(int) {}
+	t.Cleanup(func() {
+		srv.Close()
+		app.Channel, channelWait = previous, previousWait
+	})
+	return srv, &received
+}
+
+func TestRepository_SyncChannelRoom(t *testing.T) {
+	srv, received := stubChannelManager(t)
+	previousDays := app.ChannelDays
+	app.ChannelDays = 31
+	defer func() { app.ChannelDays = previousDays }()
+
+	rooms, _ := Repo.DB.GetAllRooms(context.Background())
+	cr := models.ChannelRoom{RoomID: 1, RoomCode: "GQ", RatePlanCode: "BAR"}
+
+	err := Repo.syncChannelRoom(context.Background(), cr, rooms[0], march(1))
+	if err != nil {
+		t.Fatal("syncChannelRoom failed", err)
+	}
+	if len(*received) != 2 {
+		t.Fatalf("expected availability & rates to be pushed, got %d messages", len(*received))
+	}
+
+	// testdb has pushed the 1st to 8th as open, so only the rest of the month has changed
+	avail := (*received)[0]
+	for _, s := range []string{
+		"<OTA_HotelAvailNotifRQ",
+		`<StatusApplicationControl Start="2098-03-09" End="2098-03-13" InvTypeCode="GQ">`,
+		`<StatusApplicationControl Start="2098-03-14" End="2098-03-18" InvTypeCode="GQ">`,
+		`<StatusApplicationControl Start="2098-03-19" End="2098-03-21" InvTypeCode="GQ">`,
+		`<StatusApplicationControl Start="2098-03-22" End="2098-03-31" InvTypeCode="GQ">`,
+	} {
+		if !strings.Contains(avail, s) {
+			t.Errorf("expected availability message to contain %s, got\n%s", s, avail)
+		}
+	}
+	if strings.Contains(avail, `Start="2098-03-01"`) || strings.Count(avail, "<AvailStatusMessage ") != 4 {
+		t.Errorf("expected only the changes to be pushed, got\n%s", avail)
+	}
+
+	// testdb's seasonal rate covers the whole month, no rates having been pushed
+	rates := (*received)[1]
+	for _, s := range []string{"<OTA_HotelRateAmountNotifRQ", `Start="2098-03-01"`, `RatePlanCode="BAR"`, `AmountAfterTax="100.00"`, `AmountAfterTax="120.00"`} {
+		if !strings.Contains(rates, s) {
+			t.Errorf("expected rates message to contain %s, got\n%s", s, rates)
+		}
+	}
+
+	// rates are not pushed while availability cannot be
+	*received = nil
+	app.Channel.URL = srv.URL + "/down"
+	err = Repo.syncChannelRoom(context.Background(), cr, rooms[0], march(1))
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("expected push to fail with a 503, got %v", err)
+	}
+	if len(*received) != channel.MaxAttempts || !strings.Contains((*received)[0], "<OTA_HotelAvailNotifRQ") {
+		t.Errorf("expected availability alone to be attempted %d times, got %d messages", channel.MaxAttempts, len(*received))
+	}
+
+	// testdb cannot get room 2's restrictions
+	err = Repo.syncChannelRoom(context.Background(), models.ChannelRoom{RoomID: 2, RoomCode: "MS"}, rooms[1], march(1))
+	if err == nil {
+		t.Error("expected syncChannelRoom to fail for room 2")
+	}
+}
+
+func TestRepository_SyncChannel(t *testing.T) {
+	// nothing is pushed without a channel manager
+	Repo.SyncChannel(context.Background())
+
+	_, received := stubChannelManager(t)
+	Repo.SyncChannel(context.Background())
+	if len(*received) == 0 || !strings.Contains((*received)[0], "<OTA_HotelAvailNotifRQ") {
+		t.Errorf("expected room 1's availability to be pushed, got %d messages", len(*received))
+	}
+}
+
+func TestRequestChannelSync(t *testing.T) {
+	// requests made before the syncer gets to them make a single sync
+	RequestChannelSync()
+	RequestChannelSync()
+	select {
+	case <-ChannelSyncRequests():
+	default:
+		t.Fatal("expected a sync to be requested")
+	}
+	select {
+	case <-ChannelSyncRequests():
+		t.Error("expected requests to be combined")
+	default:
+	}
+}
+
+func TestRepository_AdminChannel(t *testing.T) {
+	routes := getRoutes()
+
+	req, _ := http.NewRequest("GET", "/admin/channel", nil)
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("AdminChannel handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+	for _, s := range []string{"No channel manager is configured", `name="room_code_1" value="GQ"`, `name="rate_plan_code_1" value="BAR"`, `name="room_code_2" value="MS"`,
+		"OTA_HotelRateAmountNotifRQ", "channel manager returned 503 Service Unavailable"} {
+		if !strings.Contains(rr.Body.String(), s) {
+			t.Errorf("AdminChannel handler did not show %q", s)
+		}
+	}
+}
+
+var adminPostChannelRoomsTests = []struct {
+	name               string
+	postedData         url.Values
+	expectedStatusCode int
+	expectedContent    string
+}{
+	{"valid", url.Values{"room_code_1": {"GQ"}, "rate_plan_code_1": {"BAR"}, "room_code_2": {"MS"}}, http.StatusSeeOther, ""},
+	{"none-mapped", url.Values{}, http.StatusSeeOther, ""},
+	{"rate-plan-alone", url.Values{"rate_plan_code_1": {"BAR"}}, http.StatusOK, "A room code is needed for the rate plan"},
+	{"invalid-code", url.Values{"room_code_1": {"G Q"}}, http.StatusOK, "Codes may only contain letters"},
+	{"invalid-rate-plan", url.Values{"room_code_1": {"GQ"}, "rate_plan_code_1": {"BAR&BB"}}, http.StatusOK, "Codes may only contain letters"},
+	{"duplicate-code", url.Values{"room_code_1": {"GQ"}, "room_code_2": {"gq"}}, http.StatusOK, "Another room has this room code"},
+}
+
+func TestRepository_AdminPostChannelRooms(t *testing.T) {
+	routes := getRoutes()
+
+	for _, v := range adminPostChannelRoomsTests {
+		req, _ := http.NewRequest("POST", "/admin/channel", strings.NewReader(v.postedData.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("AdminPostChannelRooms handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+		}
+		if v.expectedContent != "" && !strings.Contains(rr.Body.String(), v.expectedContent) {
+			t.Errorf("AdminPostChannelRooms handler (%s) did not show %q", v.name, v.expectedContent)
+		}
+	}
+}
+
+func TestRepository_AdminChannelSync(t *testing.T) {
+	routes := getRoutes()
+
+	for _, path := range []string{"/admin/channel/sync", "/admin/channel/resync"} {
+		req, _ := http.NewRequest("POST", path, nil)
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		actualLoc, _ := rr.Result().Location()
+		if rr.Code != http.StatusSeeOther || actualLoc.String() != "/admin/channel" {
+			t.Errorf("handler for %s returned code: %d & location: %s, expected redirect to /admin/channel", path, rr.Code, actualLoc.String())
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/lifecycle"
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/stayrules"
+	"github.com/StratoNET/bnb-bookings/internal/xlsx"
+)
+
+// exportColumn is a column which may be chosen for an export of reservations, value giving its cell for a reservation as a string,
+// int, time.Time or xlsx.Amount
+type exportColumn struct {
+	Name    string
+	Heading string
+	Default bool
+	value   func(rsvn models.Reservation) interface{}
+}
+
+// exportColumns are the columns offered for an export, in the order they are written
+var exportColumns = []exportColumn{
+	{"id", "ID", false, func(rsvn models.Reservation) interface{} { return rsvn.ID }},
+	{"ref", "Confirmation Ref", true, func(rsvn models.Reservation) interface{} { return rsvn.ConfirmationRef }},
+	{"last_name", "Last Name", true, func(rsvn models.Reservation) interface{} { return rsvn.LastName }},
+	{"first_name", "First Name", true, func(rsvn models.Reservation) interface{} { return rsvn.FirstName }},
+	{"email", "Email", false, func(rsvn models.Reservation) interface{} { return rsvn.Email }},
+	{"phone", "Phone", false, func(rsvn models.Reservation) interface{} { return rsvn.Phone }},
+	{"room", "Room", true, func(rsvn models.Reservation) interface{} { return rsvn.Room.RoomName }},
+	{"arrival", "Arrival Date", true, func(rsvn models.Reservation) interface{} { return rsvn.StartDate }},
+	{"departure", "Departure Date", true, func(rsvn models.Reservation) interface{} { return rsvn.EndDate }},
+	{"nights", "Nights", true, func(rsvn models.Reservation) interface{} { return stayrules.Nights(rsvn.StartDate, rsvn.EndDate) }},
+	{"adults", "Adults", false, func(rsvn models.Reservation) interface{} { return rsvn.Adults }},
+	{"children", "Children", false, func(rsvn models.Reservation) interface{} { return rsvn.Children }},
+	{"infants", "Infants", false, func(rsvn models.Reservation) interface{} { return rsvn.Infants }},
+	{"status", "Status", true, func(rsvn models.Reservation) interface{} { return rsvn.Status.Label() }},
+	{"total", "Total (GBP)", true, func(rsvn models.Reservation) interface{} { return xlsx.Amount(float64(rsvn.TotalPrice) / 100) }},
+	{"booked", "Booked On", false, func(rsvn models.Reservation) interface{} { return rsvn.CreatedAt }},
+}
+
+// exportFlushRows is how many rows are written between flushes, so that a long export reaches the client as it is written
+const exportFlushRows = 100
+
+// rowWriter writes an export's rows in CSV or XLSX
+type rowWriter interface {
+	WriteHeading(headings []string) error
+	WriteRow(values []interface{}) error
+	Flush() error
+	Close() error
+}
+
+// csvRows writes an export's rows as CSV, with dates as YYYY-MM-DD & amounts in pounds
+type csvRows struct {
+	w *csv.Writer
+}
+
+func (c csvRows) WriteHeading(headings []string) error {
+	return c.w.Write(headings)
+}
+
+func (c csvRows) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case string:
+			record[i] = csvSafe(v)
+		case int:
+			record[i] = strconv.Itoa(v)
+		case xlsx.Amount:
+			record[i] = strconv.FormatFloat(float64(v), 'f', 2, 64)
+		case time.Time:
+			if !v.IsZero() {
+				record[i] = v.Format(isoDate)
+			}
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return c.w.Write(record)
+}
+
+func (c csvRows) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c csvRows) Close() error {
+	return c.Flush()
+}
+
+// csvSafe stops text entered by guests, such as a name beginning "=", being run as a formula when a CSV file is opened in a
+// spreadsheet, by beginning it with an apostrophe. Phone numbers e.g. "+44 1234 567890" are left as they are
+func csvSafe(s string) string {
+	if s == "" || !strings.ContainsAny(s[:1], "=+-@\t\r") {
+		return s
+	}
+	if strings.Trim(s, "+-0123456789 ()") == "" {
+		return s
+	}
+	return "'" + s
+}
+
+// exportFilter reads an export's filter & chosen columns from its query, in the form given on the all reservations page
+func exportFilter(r *http.Request) (models.ReservationFilter, []exportColumn, error) {
+	var filter models.ReservationFilter
+	q := r.URL.Query()
+
+	if s := q.Get("status"); s != "" {
+		status, err := lifecycle.Parse(s)
+		if err != nil {
+			return filter, nil, err
+		}
+		filter.Status = status
+	}
+
+	if s := q.Get("room_id"); s != "" {
+		roomID, err := strconv.Atoi(s)
+		if err != nil || roomID < 0 {
+			return filter, nil, fmt.Errorf("unknown room %q", s)
+		}
+		filter.RoomID = roomID
+	}
+
+	layout := "02/01/2006"
+	for _, name := range []string{"start_date", "end_date"} {
+		s := strings.TrimSpace(q.Get(name))
+		if s == "" {
+			continue
+		}
+		d, err := time.Parse(layout, s)
+		if err != nil {
+			return filter, nil, fmt.Errorf("%q is not a date as dd/mm/yyyy", s)
+		}
+		if name == "start_date" {
+			filter.StartDate = d
+		} else {
+			filter.EndDate = d
+		}
+	}
+	if !filter.StartDate.IsZero() && !filter.EndDate.IsZero() && filter.EndDate.Before(filter.StartDate) {
+		return filter, nil, fmt.Errorf("the date range cannot end before it starts")
+	}
+
+	chosen := make(map[string]bool)
+	for _, name := range q["columns"] {
+		chosen[name] = true
+	}
+
+	// with no columns chosen, the default columns are written
+	defaults := len(chosen) == 0
+	var columns []exportColumn
+	for _, c := range exportColumns {
+		if chosen[c.Name] || (defaults && c.Default) {
+			columns = append(columns, c)
+			delete(chosen, c.Name)
+		}
+	}
+	for name := range chosen {
+		return filter, nil, fmt.Errorf("unknown column %q", name)
+	}
+
+	return filter, columns, nil
+}
+
+// AdminReservationsExport downloads reservations as CSV or XLSX, selected by status, room & dates overlapping a range, with the
+// chosen columns. Rows are written as they are read from the database, so that a long history is never held in memory at once
+func (m *Repository) AdminReservationsExport(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "csv" && format != "xlsx" {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("#0089: invalid export, unknown format %q", format))
+		http.Redirect(w, r, "/admin/reservations-all", http.StatusSeeOther)
+		return
+	}
+
+	filter, columns, err := exportFilter(r)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("#0089: invalid export, %s", err))
+		http.Redirect(w, r, "/admin/reservations-all", http.StatusSeeOther)
+		return
+	}
+
+	headings := make([]string, len(columns))
+	for i, c := range columns {
+		headings[i] = c.Heading
+	}
+
+	// the response is begun with the first reservation read, so that a failed query can still be reported as usual
+	var out rowWriter
+	begin := func() error {
+		filename := fmt.Sprintf("reservations-%s.%s", time.Now().Format("20060102"), format)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		if format == "xlsx" {
+			w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+			xw, err := xlsx.NewWriter(w, "Reservations")
+			if err != nil {
+				return err
+			}
+			out = xw
+		} else {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			out = csvRows{w: csv.NewWriter(w)}
+		}
+		return out.WriteHeading(headings)
+	}
+
+	rows := 0
+	err = m.DB.StreamReservations(r.Context(), filter, func(rsvn models.Reservation) error {
+		if out == nil {
+			if err := begin(); err != nil {
+				return err
+			}
+		}
+
+		values := make([]interface{}, len(columns))
+		for i, c := range columns {
+			values[i] = c.value(rsvn)
+		}
+		if err := out.WriteRow(values); err != nil {
+			return err
+		}
+
+		rows++
+		if rows%exportFlushRows == 0 {
+			if err := out.Flush(); err != nil {
+				return err
+			}
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+		return nil
+	})
+	if err != nil && out == nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0090: cannot get reservations for export from database")
+		http.Redirect(w, r, "/admin/reservations-all", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		// part of the export has already been sent, so it can only be cut short
+		m.App.ErrorLog.Println(err)
+		return
+	}
+
+	if out == nil {
+		if err = begin(); err != nil {
+			m.App.ErrorLog.Println(err)
+			return
+		}
+	}
+	if err = out.Close(); err != nil {
+		m.App.ErrorLog.Println(err)
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var adminReservationsExportTests = []struct {
+	name            string
+	query           string
+	expectedRecords [][]string
+}{
+	{
+		"default-columns",
+		"format=csv",
+		[][]string{
+			{"Confirmation Ref", "Last Name", "First Name", "Room", "Arrival Date", "Departure Date", "Nights", "Status", "Total (GBP)"},
+			{"K7QX2MZP4D", "Soap", "Joe", "General's Quarters", "2098-01-01", "2098-01-05", "4", "Pending", "0.00"},
+			{"M2PD7QXK4Z", "Doe", "Jane", "Major's Suite", "2098-02-01", "2098-02-03", "2", "Confirmed", "0.00"},
+		},
+	},
+	{
+		"chosen-columns",
+		"format=csv&columns=nights&columns=id&columns=room",
+		[][]string{
+			{"ID", "Room", "Nights"},
+			{"1", "General's Quarters", "4"},
+			{"2", "Major's Suite", "2"},
+		},
+	},
+	{
+		"filtered",
+		"format=csv&columns=ref&status=confirmed&room_id=2&start_date=15/01/2098&end_date=01/02/2098",
+		[][]string{
+			{"Confirmation Ref"},
+			{"M2PD7QXK4Z"},
+		},
+	},
+	{
+		"none-found",
+		"format=csv&columns=ref&start_date=01/01/2099",
+		[][]string{
+			{"Confirmation Ref"},
+		},
+	},
+}
+
+func TestRepository_AdminReservationsExport(t *testing.T) {
+	routes := getRoutes()
+
+	for _, v := range adminReservationsExportTests {
+		req, _ := http.NewRequest("GET", "/admin/reservations-export?"+v.query, nil)
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("AdminReservationsExport handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, http.StatusOK)
+			continue
+		}
+		if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/csv") || !strings.Contains(rr.Header().Get("Content-Disposition"), ".csv") {
+			t.Errorf("AdminReservationsExport handler (%s) returned unexpected headers %v", v.name, rr.Header())
+		}
+
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		if err != nil {
+			t.Errorf("AdminReservationsExport handler (%s) returned invalid CSV: %v", v.name, err)
+			continue
+		}
+		if len(records) != len(v.expectedRecords) {
+			t.Errorf("AdminReservationsExport handler (%s) returned %d records, expected %d", v.name, len(records), len(v.expectedRecords))
+			continue
+		}
+		for i := range records {
+			if strings.Join(records[i], "|") != strings.Join(v.expectedRecords[i], "|") {
+				t.Errorf("AdminReservationsExport handler (%s) record %d: got %q, expected %q", v.name, i, records[i], v.expectedRecords[i])
+			}
+		}
+	}
+}
+
+func TestRepository_AdminReservationsExport_XLSX(t *testing.T) {
+	routes := getRoutes()
+
+	req, _ := http.NewRequest("GET", "/admin/reservations-export?format=xlsx", nil)
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet" {
+		t.Fatalf("AdminReservationsExport handler returned code: %d & type %q, expected an xlsx workbook", rr.Code, rr.Header().Get("Content-Type"))
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	if err != nil {
+		t.Fatal("AdminReservationsExport handler did not return a zip", err)
+	}
+	var sheet bytes.Buffer
+	for _, f := range zr.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			rc, _ := f.Open()
+			sheet.ReadFrom(rc)
+			rc.Close()
+		}
+	}
+	for _, s := range []string{"Confirmation Ref", "K7QX2MZP4D", "Major&#39;s Suite", `<row r="3">`} {
+		if !strings.Contains(sheet.String(), s) {
+			t.Errorf("AdminReservationsExport handler's sheet did not contain %q", s)
+		}
+	}
+}
+
+func TestRepository_AdminReservationsExport_Invalid(t *testing.T) {
+	routes := getRoutes()
+
+	for _, query := range []string{
+		"format=pdf",
+		"format=csv&status=lost",
+		"format=csv&room_id=two",
+		"format=csv&start_date=2098-01-01",
+		"format=csv&start_date=05/01/2098&end_date=01/01/2098",
+		"format=csv&columns=password",
+	} {
+		req, _ := http.NewRequest("GET", "/admin/reservations-export?"+query, nil)
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		actualLoc, _ := rr.Result().Location()
+		if rr.Code != http.StatusSeeOther || actualLoc.String() != "/admin/reservations-all" {
+			t.Errorf("AdminReservationsExport handler (%s) returned code: %d, expected redirect to /admin/reservations-all", query, rr.Code)
+		}
+	}
+}
+
+func TestMemoryRepository_AdminReservationsExport_DatabaseError(t *testing.T) {
+	db, repo := newMemoryRepository()
+	db.FailOn("StreamReservations", errMemoryDB)
+
+	req, _ := http.NewRequest("GET", "/admin/reservations-export?format=csv", nil)
+	rr := httptest.NewRecorder()
+	routesFor(repo).ServeHTTP(rr, req)
+
+	actualLoc, _ := rr.Result().Location()
+	if rr.Code != http.StatusSeeOther || actualLoc.String() != "/admin/reservations-all" {
+		t.Errorf("AdminReservationsExport handler returned code: %d, expected redirect to /admin/reservations-all", rr.Code)
+	}
+}
+
+func TestCSVSafe(t *testing.T) {
+	expected := map[string]string{
+		"Soap":              "Soap",
+		"":                  "",
+		"=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")",
+		"@SUM(A1)":          "'@SUM(A1)",
+		"-2+3":              "-2+3",
+		"+44 1234 567890":   "+44 1234 567890",
+		"-cmd":              "'-cmd",
+	}
+	for s, safe := range expected {
+		if csvSafe(s) != safe {
+			t.Errorf("csvSafe(%q): expected %q, got %q", s, safe, csvSafe(s))
+		}
+	}
+}
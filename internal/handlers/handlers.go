@@ -1,22 +1,35 @@
 package handlers
 
 import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html/template"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/StratoNET/bnb-bookings/internal/apitoken"
+	"github.com/StratoNET/bnb-bookings/internal/bookingref"
 	"github.com/StratoNET/bnb-bookings/internal/config"
 	"github.com/StratoNET/bnb-bookings/internal/database"
 	"github.com/StratoNET/bnb-bookings/internal/helpers"
+	"github.com/StratoNET/bnb-bookings/internal/lifecycle"
 	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/pricing"
 	"github.com/StratoNET/bnb-bookings/internal/render"
 	"github.com/StratoNET/bnb-bookings/internal/repository"
 	"github.com/StratoNET/bnb-bookings/internal/repository/dbrepository"
+	"github.com/StratoNET/bnb-bookings/internal/stayrules"
 	forms "github.com/StratoNET/bnb-bookings/internal/validation"
+	"github.com/StratoNET/bnb-bookings/internal/webhook"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -31,6 +44,19 @@ type Repository struct {
 
 // NewRepository creates a new repository, which incorporates a database repository
 func NewRepository(a *config.AppConfig, db *database.DB) *Repository {
+	switch db.Driver {
+	case database.Postgres:
+		return &Repository{
+			App: a,
+			DB:  dbrepository.NewPostgresDBRepository(db.SQL, a),
+		}
+	case database.SQLite:
+		return &Repository{
+			App: a,
+			DB:  dbrepository.NewSQLiteDBRepository(db.SQL, a),
+		}
+	}
+
 	return &Repository{
 		App: a,
 		DB:  dbrepository.NewMariaDBRepository(db.SQL, a),
@@ -50,6 +76,233 @@ func NewHandlers(r *Repository) {
 	Repo = r
 }
 
+// maxPartySize limits any single guest count entered on forms
+const maxPartySize = 20
+
+// dbRetryAfter is how many seconds a client whose database operation timed out is told to wait before trying again
+const dbRetryAfter = "5"
+
+// partyFromValues reads numbers of adults, children & infants from form or query values, where counts are missing or invalid the
+// party defaults to a single adult
+func partyFromValues(v url.Values) (adults, children, infants int) {
+	adults, err := strconv.Atoi(v.Get("adults"))
+	if err != nil || adults < 1 || adults > maxPartySize {
+		adults = 1
+	}
+	children, err = strconv.Atoi(v.Get("children"))
+	if err != nil || children < 0 || children > maxPartySize {
+		children = 0
+	}
+	infants, err = strconv.Atoi(v.Get("infants"))
+	if err != nil || infants < 0 || infants > maxPartySize {
+		infants = 0
+	}
+	return adults, children, infants
+}
+
+// manageBookingURL builds the link, signed so that no login is needed, by which a guest can view, update or cancel their booking
+func (m *Repository) manageBookingURL(ref string) string {
+	return fmt.Sprintf("%s/my-booking/%s?token=%s", m.App.SiteURL, ref, bookingref.Sign(m.App.BookingKey, ref))
+}
+
+// cancellationDeadline is the last day on which a guest may cancel their own booking online
+func (m *Repository) cancellationDeadline(rsvn models.Reservation) time.Time {
+	return rsvn.StartDate.AddDate(0, 0, -m.App.CancellationDays)
+}
+
+// newHoldToken returns a random token identifying a guest's hold on a room, kept in their session
+func newHoldToken() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// holdRooms holds the room, or rooms, chosen by a guest for their dates, for the configured number of minutes, so that no one else can
+// take them while they complete their reservation. Any hold the guest already has is replaced
+func (m *Repository) holdRooms(r *http.Request, rsvns ...models.Reservation) error {
+	token := m.App.Session.GetString(r.Context(), "hold_token")
+	if token == "" {
+		var err error
+		token, err = newHoldToken()
+		if err != nil {
+			return err
+		}
+		m.App.Session.Put(r.Context(), "hold_token", token)
+	}
+
+	expires := time.Now().Add(time.Duration(m.App.HoldMinutes) * time.Minute)
+	holds := make([]models.RoomRestriction, 0, len(rsvns))
+	for _, rsvn := range rsvns {
+		holds = append(holds, models.RoomRestriction{
+			RoomID:    rsvn.RoomID,
+			StartDate: rsvn.StartDate,
+			EndDate:   rsvn.EndDate,
+			HoldToken: token,
+			ExpiresAt: expires,
+		})
+	}
+
+	return m.DB.PlaceHold(r.Context(), holds...)
+}
+
+// releaseHold frees any room held by a guest, e.g. when they begin a new search
+func (m *Repository) releaseHold(r *http.Request) {
+	token := m.App.Session.PopString(r.Context(), "hold_token")
+	if token == "" {
+		return
+	}
+	err := m.DB.ReleaseHold(r.Context(), token)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+	}
+}
+
+// dbInterrupted reports a database operation abandoned before completing, returning true should err be one, in which case the handler
+// has nothing more to do. A client which has disconnected is gone, so is only logged, whereas one whose operation timed out is told the
+// service is unavailable & to try again shortly, unlike any other failure
+func (m *Repository) dbInterrupted(w http.ResponseWriter, r *http.Request, err error) bool {
+	return m.reportInterrupted(w, r, err, func() {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			writeJSONError(w, http.StatusServiceUnavailable, apiErrTimeout, "", "the database took too long to respond, please try again shortly")
+			return
+		}
+		http.Error(w, "Sorry, the database took too long to respond, please try again shortly", http.StatusServiceUnavailable)
+	})
+}
+
+// modalInterrupted is dbInterrupted for the availability modal, whose script reads every response as a jsonResponse, so is told of a
+// timeout in one
+func (m *Repository) modalInterrupted(w http.ResponseWriter, r *http.Request, err error) bool {
+	return m.reportInterrupted(w, r, err, func() {
+		resp := jsonResponse{
+			Ok:      false,
+			Message: "Sorry, the database took too long to respond, please try again shortly",
+		}
+		out, _ := json.MarshalIndent(resp, "", "    ")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write(out)
+	})
+}
+
+// reportInterrupted logs a database operation abandoned before completing, returning true should err be one, calling timedOut to tell
+// a client whose operation timed out, after setting Retry-After
+func (m *Repository) reportInterrupted(w http.ResponseWriter, r *http.Request, err error, timedOut func()) bool {
+	var interrupted *repository.InterruptedError
+	if !errors.As(err, &interrupted) {
+		return false
+	}
+
+	if !interrupted.Timeout() {
+		m.App.InfoLog.Printf("%s %s abandoned by client, %s", r.Method, r.URL.Path, err)
+		return true
+	}
+
+	m.App.ErrorLog.Printf("%s %s: %s", r.Method, r.URL.Path, err)
+	w.Header().Set("Retry-After", dbRetryAfter)
+	timedOut()
+	return true
+}
+
+// checkStayRules tests a stay in a given room against the room's stay rules, returning a *stayrules.Violation for any rule broken
+func (m *Repository) checkStayRules(ctx context.Context, roomID int, start, end time.Time) error {
+	rules, err := m.DB.GetStayRulesByRoomID(ctx, roomID, start, end)
+	if err != nil {
+		return err
+	}
+	return stayrules.Check(rules, start, end)
+}
+
+// quoteRoom prices a stay in a given room, taking account of any seasonal rates covering the stay
+func (m *Repository) quoteRoom(ctx context.Context, room models.Room, start, end time.Time, guests int) (models.Quote, error) {
+	seasons, err := m.DB.GetSeasonalRatesByRoomID(ctx, room.ID, start, end)
+	if err != nil {
+		return models.Quote{}, err
+	}
+	return pricing.QuoteStay(room, seasons, start, end, guests)
+}
+
+// notifyWaitlist emails, in the order they joined, each waitlisted guest whose dates overlap a room's newly freed dates, provided the
+// room is now free for all of their dates & can accommodate their party. Each guest is told only once, failures are logged & skipped.
+// Guests are told even should the client freeing the room have since disconnected, hence the request's context is not used
+func (m *Repository) notifyWaitlist(roomID int, start, end time.Time) {
+	ctx := context.Background()
+
+	entries, err := m.DB.GetWaitlistByDates(ctx, start, end)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		return
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	room, err := m.DB.GetRoomByID(ctx, roomID)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		return
+	}
+
+	for _, e := range entries {
+		if !room.Accommodates(e.Adults, e.Children, e.Infants) {
+			continue
+		}
+		available, err := m.DB.SearchAvailabilityByDatesAndRoomID(ctx, e.StartDate, e.EndDate, roomID, "")
+		if err != nil {
+			m.App.ErrorLog.Println(err)
+			continue
+		}
+		if !available {
+			continue
+		}
+
+		htmlMsg := fmt.Sprintf(`
+			<h3 class="text-center">Eden House: A Room Is Now Available</h3>
+			<p>&nbsp;</p>
+			<p>Dear %s&nbsp;%s</p>
+			<p>Good news, the %s has become available from %s to %s for %s.</p>
+			<p>Rooms are offered to everyone on our waitlist, so please <a href="%s/search-availability">book now</a> to avoid disappointment.</p>
+		`, e.FirstName, e.LastName, room.RoomName, e.StartDate.Format("Monday 02 January 2006"), e.EndDate.Format("Monday 02 January 2006"),
+			e.Party(), m.App.SiteURL)
+		msg := models.MailData{
+			To:       e.Email,
+			From:     os.Getenv("SMTP_FROM"),
+			Subject:  "Eden House: A Room Is Now Available",
+			Content:  htmlMsg,
+			Template: "confirmation.html",
+		}
+		m.App.MailChannel <- msg
+
+		err = m.DB.MarkWaitlistNotified(ctx, e.ID)
+		if err != nil {
+			m.App.ErrorLog.Println(err)
+		}
+	}
+}
+
+// quoteHTML returns the nightly breakdown & total of a quote as an HTML table, for use within emails
+func quoteHTML(quote models.Quote) string {
+	var b strings.Builder
+	b.WriteString(`<table><tr><th align="left">Night</th><th align="left">Rate</th><th align="right">Price</th></tr>`)
+	for _, n := range quote.Nights {
+		rate := "Standard"
+		if n.SeasonName != "" {
+			rate = n.SeasonName
+		}
+		if n.Weekend {
+			rate += " (weekend)"
+		}
+		b.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%s</td><td align="right">%s</td></tr>`,
+			n.Date.Format("Mon 02 Jan 2006"), template.HTMLEscapeString(rate), pricing.FormatAmount(n.Amount)))
+	}
+	b.WriteString(fmt.Sprintf(`<tr><td colspan="2"><strong>Total</strong></td><td align="right"><strong>%s</strong></td></tr></table>`,
+		pricing.FormatAmount(quote.Total)))
+	return b.String()
+}
+
 // Index is the handler for the home page
 func (m *Repository) Index(w http.ResponseWriter, r *http.Request) {
 	render.Template(w, r, "index.page.tmpl", &models.TemplateData{})
@@ -102,30 +355,96 @@ func (m *Repository) PostAvailability(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
+	if endDate.Before(startDate) {
+		m.App.Session.Put(r.Context(), "error", "#0034: departure date cannot be before arrival date")
+		http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+		return
+	}
 
-	rooms, err := m.DB.SearchAvailabilityForAllRooms(startDate, endDate)
+	adults, children, infants := partyFromValues(r.Form)
+
+	// a new search starts over, so any room the guest was holding is let go
+	m.releaseHold(r)
+
+	rooms, err := m.DB.SearchAvailabilityForAllRooms(r.Context(), startDate, endDate, adults, children, infants, "")
 	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
 		m.App.Session.Put(r.Context(), "error", "unable to get AVAILABILITY FOR ALL ROOMS")
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
 
-	if len(rooms) == 0 {
-		// no availability
-		m.App.Session.Put(r.Context(), "error", "Sorry, no availability for the specific requested period")
+	// leave out any room whose stay rules do not allow these dates, keeping the rule broken so the guest can be told why
+	var violation *stayrules.Violation
+	var allowed []models.Room
+	for _, rm := range rooms {
+		err := m.checkStayRules(r.Context(), rm.ID, startDate, endDate)
+		if err != nil {
+			if m.dbInterrupted(w, r, err) {
+				return
+			}
+			if !errors.As(err, &violation) {
+				m.App.ErrorLog.Println(err)
+				m.App.Session.Put(r.Context(), "error", "#0035: cannot get stay rules from database")
+				http.Redirect(w, r, "/", http.StatusSeeOther)
+				return
+			}
+			continue
+		}
+		allowed = append(allowed, rm)
+	}
+	rooms = allowed
+
+	if len(rooms) == 0 && violation != nil {
+		m.App.Session.Put(r.Context(), "error", "Sorry, "+violation.Error())
 		http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
 		return
 	}
 
+	if len(rooms) == 0 {
+		// no availability, so offer the guest a place on the waitlist for these dates & party
+		m.App.Session.Put(r.Context(), "waitlist", models.WaitlistEntry{
+			StartDate: startDate,
+			EndDate:   endDate,
+			Adults:    adults,
+			Children:  children,
+			Infants:   infants,
+		})
+		m.App.Session.Put(r.Context(), "warning", "Sorry, no availability for the specific requested period, but you can join our waitlist")
+		http.Redirect(w, r, "/waitlist", http.StatusSeeOther)
+		return
+	}
+
 	// by this point there must be availability, utilise data object available throughout from templatedata
 	data := make(map[string]interface{})
 	data["rooms"] = rooms
 
-	// instantiate a reservation with only the information known so far from search availability (i.e. the dates, room is still unknown)
+	// price the stay in each available room
+	quotes := make(map[int]models.Quote)
+	for _, rm := range rooms {
+		quote, err := m.quoteRoom(r.Context(), rm, startDate, endDate, adults+children)
+		if err != nil {
+			if m.dbInterrupted(w, r, err) {
+				return
+			}
+			m.App.ErrorLog.Println(err)
+			continue
+		}
+		quotes[rm.ID] = quote
+	}
+	data["quotes"] = quotes
+
+	// instantiate a reservation with only the information known so far from search availability (i.e. the dates & party, room is still unknown)
 	reservation := models.Reservation{
 		StartDate: startDate,
 		EndDate:   endDate,
+		Adults:    adults,
+		Children:  children,
+		Infants:   infants,
 	}
+	data["reservation"] = reservation
 	// store this within session
 	m.App.Session.Put(r.Context(), "reservation", reservation)
 
@@ -139,9 +458,13 @@ func (m *Repository) PostAvailability(w http.ResponseWriter, r *http.Request) {
 type jsonResponse struct {
 	Ok        bool   `json:"ok"`
 	Message   string `json:"message"`
+	Rule      string `json:"rule"`
 	RoomID    string `json:"room_id"`
 	StartDate string `json:"start_date"`
 	EndDate   string `json:"end_date"`
+	Adults    int    `json:"adults"`
+	Children  int    `json:"children"`
+	Infants   int    `json:"infants"`
 }
 
 // PostAvailabilityModal handles request for modal availability search & returns JSON response
@@ -168,9 +491,25 @@ func (m *Repository) PostAvailabilityModal(w http.ResponseWriter, r *http.Reques
 	startDate, _ := time.Parse(layout, start)
 	endDate, _ := time.Parse(layout, end)
 	roomID, _ := strconv.Atoi(r.Form.Get("room_id"))
+	adults, children, infants := partyFromValues(r.Form)
+
+	if endDate.Before(startDate) {
+		resp := jsonResponse{
+			Ok:      false,
+			Message: "Sorry, your departure date cannot be before your arrival date",
+		}
+		out, _ := json.MarshalIndent(resp, "", "    ")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(out)
+		return
+	}
 
-	available, err := m.DB.SearchAvailabilityByDatesAndRoomID(startDate, endDate, roomID)
+	// the room the guest is already holding is available to them
+	available, err := m.DB.SearchAvailabilityByDatesAndRoomID(r.Context(), startDate, endDate, roomID, m.App.Session.GetString(r.Context(), "hold_token"))
 	if err != nil {
+		if m.modalInterrupted(w, r, err) {
+			return
+		}
 		// return an appropriate JSON response
 		resp := jsonResponse{
 			Ok:      false,
@@ -182,12 +521,45 @@ func (m *Repository) PostAvailabilityModal(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	message, rule := "", ""
+	if available {
+		// the room may be free but still too small for the party
+		room, err := m.DB.GetRoomByID(r.Context(), roomID)
+		if err != nil {
+			available = false
+			message = "#0032: cannot get room from database"
+		} else if !room.Accommodates(adults, children, infants) {
+			available = false
+			message = fmt.Sprintf("Sorry, this room can accommodate at most %s", models.PartyDescription(room.MaxAdults, room.MaxChildren, room.MaxInfants))
+		}
+	}
+	if available {
+		// the room must also allow a stay of this length over these dates
+		err := m.checkStayRules(r.Context(), roomID, startDate, endDate)
+		var violation *stayrules.Violation
+		if m.modalInterrupted(w, r, err) {
+			return
+		}
+		if errors.As(err, &violation) {
+			available = false
+			message = "Sorry, " + violation.Error()
+			rule = violation.Rule.RuleName
+		} else if err != nil {
+			available = false
+			message = "#0035: cannot get stay rules from database"
+		}
+	}
+
 	resp := jsonResponse{
 		Ok:        available,
-		Message:   "",
+		Message:   message,
+		Rule:      rule,
 		RoomID:    strconv.Itoa(roomID),
 		StartDate: start,
 		EndDate:   end,
+		Adults:    adults,
+		Children:  children,
+		Infants:   infants,
 	}
 
 	// ignore error check at this point because all aspects of JSON response have already been handled
@@ -206,14 +578,27 @@ func (m *Repository) Reservation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// get room name & populate Room, which is a member of Reservation model (only RoomName is required)
-	room, err := m.DB.GetRoomByID(reservation.RoomID)
+	// get room name & rates & populate Room, which is a member of Reservation model
+	room, err := m.DB.GetRoomByID(r.Context(), reservation.RoomID)
 	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
 		m.App.Session.Put(r.Context(), "error", "#0002: cannot get room number from database")
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
-	reservation.Room.RoomName = room.RoomName
+	reservation.Room = room
+
+	quote, err := m.quoteRoom(r.Context(), room, reservation.StartDate, reservation.EndDate, reservation.Adults+reservation.Children)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0022: cannot price reservation")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
 
 	// format start/end dates as strings (instead of time.Time) & place into a StringMap (templatedata) for display in make-reservation page
 	sd := reservation.StartDate.Format("02/01/2006")
@@ -221,9 +606,11 @@ func (m *Repository) Reservation(w http.ResponseWriter, r *http.Request) {
 	stringMap := make(map[string]string)
 	stringMap["start_date"] = sd
 	stringMap["end_date"] = ed
+	stringMap["hold_minutes"] = strconv.Itoa(m.App.HoldMinutes)
 
 	data := make(map[string]interface{})
 	data["reservation"] = reservation
+	data["quote"] = quote
 
 	// put updated (with Room.RoomName) reservation back into session
 	m.App.Session.Put(r.Context(), "reservation", reservation)
@@ -254,20 +641,66 @@ func (m *Repository) PostReservation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the stay must still satisfy the room's stay rules, which may have changed since the room was chosen
+	err = m.checkStayRules(r.Context(), reservation.RoomID, reservation.StartDate, reservation.EndDate)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		var violation *stayrules.Violation
+		if errors.As(err, &violation) || errors.Is(err, stayrules.ErrEndBeforeStart) {
+			m.App.Session.Put(r.Context(), "error", "Sorry, "+err.Error())
+			http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0035: cannot get stay rules from database")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
 	reservation.FirstName = r.Form.Get("first_name")
 	reservation.LastName = r.Form.Get("last_name")
 	reservation.Email = r.Form.Get("email")
 	reservation.Phone = r.Form.Get("phone")
+	// guest counts are trimmed as the form's validation trims them, so that " 2" is not validated as 2 & then stored as 0
+	reservation.Adults, _ = strconv.Atoi(strings.TrimSpace(r.Form.Get("adults")))
+	reservation.Children, _ = strconv.Atoi(strings.TrimSpace(r.Form.Get("children")))
+	reservation.Infants, _ = strconv.Atoi(strings.TrimSpace(r.Form.Get("infants")))
+
+	// price the stay at current rates, this quoted total is saved with the reservation
+	quote, err := m.quoteRoom(r.Context(), reservation.Room, reservation.StartDate, reservation.EndDate, reservation.Adults+reservation.Children)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0022: cannot price reservation")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	reservation.TotalPrice = quote.Total
 
 	form := forms.NewForm(r.PostForm)
 
 	// perform all necessary validations
 
-	form.RequiredFields("first_name", "last_name", "email", "phone")
+	form.RequiredFields("first_name", "last_name", "email", "phone", "adults")
 	form.MinLength("first_name", 2)
 	form.MinLength("last_name", 2)
 	form.MinLength("phone", 6)
 	form.IsEmail("email")
+	form.IsNumberInRange("adults", 1, maxPartySize)
+	// children & infants are optional, no entry means none
+	for _, field := range []string{"children", "infants"} {
+		if form.HasField(field) {
+			form.IsNumberInRange(field, 0, maxPartySize)
+		}
+	}
+
+	if form.ValidForm() && !reservation.Room.Accommodates(reservation.Adults, reservation.Children, reservation.Infants) {
+		form.Errors.AddErrMsg("adults", fmt.Sprintf("the %s can accommodate at most %s", reservation.Room.RoomName,
+			models.PartyDescription(reservation.Room.MaxAdults, reservation.Room.MaxChildren, reservation.Room.MaxInfants)))
+	}
 
 	if !form.ValidForm() {
 		// format start/end dates as strings (instead of time.Time) & place into a StringMap (templatedata) for re-display in make-reservation page
@@ -279,6 +712,7 @@ func (m *Repository) PostReservation(w http.ResponseWriter, r *http.Request) {
 
 		data := make(map[string]interface{})
 		data["reservation"] = reservation
+		data["quote"] = quote
 
 		m.App.Session.Put(r.Context(), "error", "#0006: invalid form details submitted")
 
@@ -290,38 +724,61 @@ func (m *Repository) PostReservation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// after all validation procedures are complete, insert reservation record into database
-	lastReservationID, err := m.DB.InsertReservation(reservation)
+	// give the reservation a confirmation reference, by which the guest can later manage their booking
+	reservation.ConfirmationRef, err = bookingref.NewRef()
 	if err != nil {
-		m.App.Session.Put(r.Context(), "error", "#0007: cannot insert reservation into database")
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0046: cannot create confirmation reference")
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
 
-	// populate and instance of the RoomRestriction object, get ReservationID from LastInsertId() after executing InsertReservation()
-	room_restriction := models.RoomRestriction{
-		RoomID:        reservation.RoomID,
-		ReservationID: lastReservationID,
-		RestrictionID: 1,
-		StartDate:     reservation.StartDate,
-		EndDate:       reservation.EndDate,
-	}
-
-	err = m.DB.InsertRoomRestriction(room_restriction)
+	// after all validation procedures are complete, re-check availability & insert reservation with its room restriction as one operation
+	reservationID, err := m.DB.CreateReservation(r.Context(), reservation, m.App.Session.GetString(r.Context(), "hold_token"))
 	if err != nil {
-		m.App.Session.Put(r.Context(), "error", "#0008: cannot insert 'room restriction', corresponding to current reservation, into database")
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		var unavailable *repository.RoomUnavailableError
+		if errors.As(err, &unavailable) {
+			// another guest has taken the room since it was chosen, so offer a fresh search for the same dates
+			m.App.Session.Put(r.Context(), "warning", fmt.Sprintf("Sorry, the %s has just been reserved by another guest", reservation.Room.RoomName))
+			m.App.Session.Remove(r.Context(), "reservation")
+
+			stringMap := make(map[string]string)
+			stringMap["start_date"] = reservation.StartDate.Format("02/01/2006")
+			stringMap["end_date"] = reservation.EndDate.Format("02/01/2006")
+			stringMap["room_name"] = reservation.Room.RoomName
+			stringMap["adults"] = strconv.Itoa(reservation.Adults)
+			stringMap["children"] = strconv.Itoa(reservation.Children)
+			stringMap["infants"] = strconv.Itoa(reservation.Infants)
+
+			render.Template(w, r, "search-availability.page.tmpl", &models.TemplateData{
+				StringMap: stringMap,
+			})
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0007: cannot insert reservation into database")
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
 
+	RequestChannelSync()
+	m.publishCreatedReservation(int(reservationID))
+
 	// send email notification to guest
 	htmlMsg := fmt.Sprintf(`
 		<h3 class="text-center">Eden House: Reservation Confirmation</h3>
 		<p>&nbsp;</p>
 		<p>Dear %s&nbsp;%s</p>
-		<p>This is to confirm your reservation from %s to %s in the %s, we look forward to seeing you then.</p>
+		<p>This is to confirm your reservation from %s to %s in the %s for %s, we look forward to seeing you then.</p>
+		<p>Your confirmation reference is <strong>%s</strong></p>
+		%s
+		<p>You can view, update or cancel your booking at any time by following this link... <a href="%s">manage your booking</a></p>
 	`, reservation.FirstName, reservation.LastName, reservation.StartDate.Format("Monday 02 January 2006"),
-		reservation.EndDate.Format("Monday 02 January 2006"), reservation.Room.RoomName)
+		reservation.EndDate.Format("Monday 02 January 2006"), reservation.Room.RoomName, reservation.Party(), reservation.ConfirmationRef,
+		quoteHTML(quote), m.manageBookingURL(reservation.ConfirmationRef))
 	msg := models.MailData{
 		To:       reservation.Email,
 		From:     os.Getenv("SMTP_FROM"),
@@ -335,9 +792,9 @@ func (m *Repository) PostReservation(w http.ResponseWriter, r *http.Request) {
 	htmlMsg = fmt.Sprintf(`
 		<h3>Eden House: Reservation Notification</h3>
 		<p>&nbsp;</p>
-		<p>A reservation has been made by %s %s covering %s to %s for the %s.</p>
-	`, reservation.FirstName, reservation.LastName, reservation.StartDate.Format("02/01/2006"), reservation.EndDate.Format("02/01/2006"),
-		reservation.Room.RoomName)
+		<p>A reservation (ref. %s) has been made by %s %s covering %s to %s for the %s (%s), quoted at %s.</p>
+	`, reservation.ConfirmationRef, reservation.FirstName, reservation.LastName, reservation.StartDate.Format("02/01/2006"),
+		reservation.EndDate.Format("02/01/2006"), reservation.Room.RoomName, reservation.Party(), pricing.FormatAmount(reservation.TotalPrice))
 	msg = models.MailData{
 		To:       os.Getenv("SMTP_TO"),
 		From:     os.Getenv("SMTP_FROM"),
@@ -347,8 +804,12 @@ func (m *Repository) PostReservation(w http.ResponseWriter, r *http.Request) {
 	}
 	m.App.MailChannel <- msg
 
-	//for a valid reservation form, put into session & redirect to summary page
+	// the hold has become the reservation's own room restriction, so the guest no longer holds anything
+	m.App.Session.Remove(r.Context(), "hold_token")
+
+	//for a valid reservation form, put into session (with its quote) & redirect to summary page
 	m.App.Session.Put(r.Context(), "reservation", reservation)
+	m.App.Session.Put(r.Context(), "quote", quote)
 	http.Redirect(w, r, "/reservation-summary", http.StatusSeeOther)
 }
 
@@ -365,16 +826,23 @@ func (m *Repository) ReservationSummary(w http.ResponseWriter, r *http.Request)
 	//reaching this point implies 'reservation' was successfully retrieved, therefore can now be removed from session
 	m.App.Session.Remove(r.Context(), "reservation")
 
+	// the quote breakdown is optional, the saved total is always available from the reservation itself
+	quote, _ := m.App.Session.Pop(r.Context(), "quote").(models.Quote)
+
 	// format start/end dates as strings (instead of time.Time) & place into a StringMap (templatedata) for display in reservation-summary page
 	sd := reservation.StartDate.Format("Monday 02 January 2006")
 	ed := reservation.EndDate.Format("Monday 02 January 2006")
 	stringMap := make(map[string]string)
 	stringMap["start_date"] = sd
 	stringMap["end_date"] = ed
+	if reservation.ConfirmationRef != "" {
+		stringMap["manage_url"] = m.manageBookingURL(reservation.ConfirmationRef)
+	}
 
 	// create data object and populate with reservation data
 	data := make(map[string]interface{})
 	data["reservation"] = reservation
+	data["quote"] = quote
 
 	render.Template(w, r, "reservation-summary.page.tmpl", &models.TemplateData{
 		Data:      data,
@@ -399,8 +867,26 @@ func (m *Repository) ChooseRoom(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
-	// add roomID to reservation model, put it back into the session & redirect to make-reservation page
+	// add roomID to reservation model, hold the room, put reservation back into the session & redirect to make-reservation page
 	reservation.RoomID = roomID
+
+	err = m.holdRooms(r, reservation)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		var unavailable *repository.RoomUnavailableError
+		if errors.As(err, &unavailable) {
+			m.App.Session.Put(r.Context(), "warning", "Sorry, that room has just been taken by another guest, please search again")
+			http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0058: cannot hold room for reservation")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
 	m.App.Session.Put(r.Context(), "reservation", reservation)
 	http.Redirect(w, r, "/make-reservation", http.StatusSeeOther)
 
@@ -427,9 +913,12 @@ func (m *Repository) ReserveRoom(w http.ResponseWriter, r *http.Request) {
 	// instantiate a reservation
 	var reservation models.Reservation
 
-	// get room name & populate Room, which is a member of Reservation model (only RoomName is required)
-	room, err := m.DB.GetRoomByID(roomID)
+	// get room name & rates & populate Room, which is a member of Reservation model
+	room, err := m.DB.GetRoomByID(r.Context(), roomID)
 	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
 		m.App.Session.Put(r.Context(), "error", "#0012: cannot get room name from database")
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
@@ -437,9 +926,27 @@ func (m *Repository) ReserveRoom(w http.ResponseWriter, r *http.Request) {
 
 	// populate reservation with currently known details
 	reservation.RoomID = roomID
-	reservation.Room.RoomName = room.RoomName
+	reservation.Room = room
 	reservation.StartDate = startDate
 	reservation.EndDate = endDate
+	reservation.Adults, reservation.Children, reservation.Infants = partyFromValues(r.URL.Query())
+
+	err = m.holdRooms(r, reservation)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		var unavailable *repository.RoomUnavailableError
+		if errors.As(err, &unavailable) {
+			m.App.Session.Put(r.Context(), "warning", fmt.Sprintf("Sorry, the %s has just been taken by another guest, please search again", room.RoomName))
+			http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0058: cannot hold room for reservation")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
 
 	// put all details back into the session & redirect to make-reservation page
 	m.App.Session.Put(r.Context(), "reservation", reservation)
@@ -447,423 +954,2518 @@ func (m *Repository) ReserveRoom(w http.ResponseWriter, r *http.Request) {
 
 }
 
-// Login displays login page & gets the administrator's login form
-func (m *Repository) Login(w http.ResponseWriter, r *http.Request) {
-	render.Template(w, r, "login.page.tmpl", &models.TemplateData{
-		Form: forms.NewForm(nil),
-	})
-}
-
-//PostLogin handles administrator login
-func (m *Repository) PostLogin(w http.ResponseWriter, r *http.Request) {
-	// prevent session fixation attack
-	_ = m.App.Session.RenewToken(r.Context())
-	// parse form
+// PostChooseRooms takes several rooms chosen together from the choose-room page & holds them all for a group booking
+func (m *Repository) PostChooseRooms(w http.ResponseWriter, r *http.Request) {
+	// initially ensure form data is parsed correctly
 	err := r.ParseForm()
 	if err != nil {
-		m.App.ErrorLog.Println(err)
-	}
-	email := r.Form.Get("email")
-	password := r.Form.Get("password")
-	// validation...
-	form := forms.NewForm(r.PostForm)
-	form.RequiredFields("email", "password")
-	form.IsEmail("email")
-	if !form.ValidForm() {
-		stringMap := make(map[string]string)
-		stringMap["email"] = email
-		stringMap["password"] = password
-		render.Template(w, r, "login.page.tmpl", &models.TemplateData{
-			Form:      form,
-			StringMap: stringMap,
-		})
+		m.App.Session.Put(r.Context(), "error", "#0059: cannot parse choose rooms form")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
-	// attempt administrator authentication
-	id, _, err := m.DB.AuthenticateAdministrator(email, password)
-	if err != nil {
-		m.App.ErrorLog.Println(err)
-		m.App.Session.Put(r.Context(), "error", "Please check - invalid login credentials")
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+
+	// the search dates & party are still in the session, the rooms are still unknown
+	reservation, ok := m.App.Session.Get(r.Context(), "reservation").(models.Reservation)
+	if !ok {
+		m.App.Session.Put(r.Context(), "error", "#0010: unable to retrieve reservation from session")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
-	// store the returned administrator's id in session
-	m.App.Session.Put(r.Context(), "admin_id", id)
-	m.App.Session.Put(r.Context(), "flash", "Logged in successfully")
-	http.Redirect(w, r, "/", http.StatusSeeOther)
-}
 
-// Logout logs an administrator out
-func (m *Repository) Logout(w http.ResponseWriter, r *http.Request) {
+	var roomIDs []int
+	chosen := make(map[int]bool)
+	for _, v := range r.Form["room_id"] {
+		id, err := strconv.Atoi(v)
+		if err != nil || chosen[id] {
+			continue
+		}
+		chosen[id] = true
+		roomIDs = append(roomIDs, id)
+	}
+
+	switch len(roomIDs) {
+	case 0:
+		m.App.Session.Put(r.Context(), "warning", "Please select the rooms you would like to book together")
+		http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+		return
+	case 1:
+		// a single room is an ordinary booking
+		http.Redirect(w, r, fmt.Sprintf("/choose-room/%d", roomIDs[0]), http.StatusSeeOther)
+		return
+	}
+
+	// each room starts with the party searched for, which the guest can then change room by room
+	group := models.BookingGroup{
+		StartDate: reservation.StartDate,
+		EndDate:   reservation.EndDate,
+	}
+	for _, id := range roomIDs {
+		room, err := m.DB.GetRoomByID(r.Context(), id)
+		if err != nil {
+			if m.dbInterrupted(w, r, err) {
+				return
+			}
+			m.App.Session.Put(r.Context(), "error", "#0002: cannot get room number from database")
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		group.Reservations = append(group.Reservations, models.Reservation{
+			RoomID:    id,
+			StartDate: reservation.StartDate,
+			EndDate:   reservation.EndDate,
+			Adults:    reservation.Adults,
+			Children:  reservation.Children,
+			Infants:   reservation.Infants,
+			Room:      room,
+		})
+	}
+
+	// all of the rooms are held, or none of them
+	err = m.holdRooms(r, group.Reservations...)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		var unavailable *repository.RoomUnavailableError
+		if errors.As(err, &unavailable) {
+			roomName := "room"
+			for _, rsvn := range group.Reservations {
+				if rsvn.RoomID == unavailable.RoomID {
+					roomName = rsvn.Room.RoomName
+				}
+			}
+			m.App.Session.Put(r.Context(), "warning", fmt.Sprintf("Sorry, the %s has just been taken by another guest, please search again", roomName))
+			http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0058: cannot hold room for reservation")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Remove(r.Context(), "reservation")
+	m.App.Session.Put(r.Context(), "group", group)
+	http.Redirect(w, r, "/make-group-reservation", http.StatusSeeOther)
+}
+
+// quoteGroup prices the stay in each room of a group for its own party, keyed by room id, saving each quoted total with its reservation
+func (m *Repository) quoteGroup(ctx context.Context, group *models.BookingGroup) (map[int]models.Quote, error) {
+	quotes := make(map[int]models.Quote)
+	for i := range group.Reservations {
+		rsvn := &group.Reservations[i]
+		quote, err := m.quoteRoom(ctx, rsvn.Room, rsvn.StartDate, rsvn.EndDate, rsvn.Adults+rsvn.Children)
+		if err != nil {
+			return quotes, err
+		}
+		rsvn.TotalPrice = quote.Total
+		quotes[rsvn.RoomID] = quote
+	}
+	return quotes, nil
+}
+
+// GroupReservation displays the make-group-reservation page, taking the lead guest's details & the party staying in each room
+func (m *Repository) GroupReservation(w http.ResponseWriter, r *http.Request) {
+	group, ok := m.App.Session.Get(r.Context(), "group").(models.BookingGroup)
+	if !ok {
+		m.App.Session.Put(r.Context(), "error", "#0060: cannot get group booking rooms from session")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	quotes, err := m.quoteGroup(r.Context(), &group)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0022: cannot price reservation")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	stringMap := make(map[string]string)
+	stringMap["start_date"] = group.StartDate.Format("02/01/2006")
+	stringMap["end_date"] = group.EndDate.Format("02/01/2006")
+	stringMap["hold_minutes"] = strconv.Itoa(m.App.HoldMinutes)
+
+	data := make(map[string]interface{})
+	data["group"] = group
+	data["quotes"] = quotes
+
+	render.Template(w, r, "make-group-reservation.page.tmpl", &models.TemplateData{
+		Form:      forms.NewForm(nil),
+		Data:      data,
+		StringMap: stringMap,
+	})
+}
+
+// PostGroupReservation is the handler for posting the make-group-reservation form, booking every room of the group as one operation
+func (m *Repository) PostGroupReservation(w http.ResponseWriter, r *http.Request) {
+	group, ok := m.App.Session.Get(r.Context(), "group").(models.BookingGroup)
+	if !ok {
+		m.App.Session.Put(r.Context(), "error", "#0060: cannot get group booking rooms from session")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	// initially ensure form data is parsed correctly
+	err := r.ParseForm()
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0061: cannot parse group reservation form")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	// the stay must still satisfy every room's stay rules, which may have changed since the rooms were chosen
+	for _, rsvn := range group.Reservations {
+		err = m.checkStayRules(r.Context(), rsvn.RoomID, group.StartDate, group.EndDate)
+		if err != nil {
+			if m.dbInterrupted(w, r, err) {
+				return
+			}
+			var violation *stayrules.Violation
+			if errors.As(err, &violation) || errors.Is(err, stayrules.ErrEndBeforeStart) {
+				m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Sorry, the %s cannot be booked for these dates (%s)", rsvn.Room.RoomName, err))
+				http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+				return
+			}
+			m.App.ErrorLog.Println(err)
+			m.App.Session.Put(r.Context(), "error", "#0035: cannot get stay rules from database")
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+	}
+
+	group.FirstName = r.Form.Get("first_name")
+	group.LastName = r.Form.Get("last_name")
+	group.Email = r.Form.Get("email")
+	group.Phone = r.Form.Get("phone")
+
+	form := forms.NewForm(r.PostForm)
+
+	// perform all necessary validations, the lead guest's details are given once for the whole group
+
+	form.RequiredFields("first_name", "last_name", "email", "phone")
+	form.MinLength("first_name", 2)
+	form.MinLength("last_name", 2)
+	form.MinLength("phone", 6)
+	form.IsEmail("email")
+
+	// each room has its own party, named by room id e.g. adults_1, children_1 & infants_1
+	for i := range group.Reservations {
+		rsvn := &group.Reservations[i]
+		adults := fmt.Sprintf("adults_%d", rsvn.RoomID)
+		form.RequiredFields(adults)
+		form.IsNumberInRange(adults, 1, maxPartySize)
+		for _, field := range []string{fmt.Sprintf("children_%d", rsvn.RoomID), fmt.Sprintf("infants_%d", rsvn.RoomID)} {
+			if form.HasField(field) {
+				form.IsNumberInRange(field, 0, maxPartySize)
+			}
+		}
+
+		rsvn.FirstName = group.FirstName
+		rsvn.LastName = group.LastName
+		rsvn.Email = group.Email
+		rsvn.Phone = group.Phone
+		rsvn.Adults, _ = strconv.Atoi(strings.TrimSpace(r.Form.Get(adults)))
+		rsvn.Children, _ = strconv.Atoi(strings.TrimSpace(r.Form.Get(fmt.Sprintf("children_%d", rsvn.RoomID))))
+		rsvn.Infants, _ = strconv.Atoi(strings.TrimSpace(r.Form.Get(fmt.Sprintf("infants_%d", rsvn.RoomID))))
+
+		if form.ValidForm() && !rsvn.Room.Accommodates(rsvn.Adults, rsvn.Children, rsvn.Infants) {
+			form.Errors.AddErrMsg(adults, fmt.Sprintf("the %s can accommodate at most %s", rsvn.Room.RoomName,
+				models.PartyDescription(rsvn.Room.MaxAdults, rsvn.Room.MaxChildren, rsvn.Room.MaxInfants)))
+		}
+	}
+
+	// price each room at current rates for its party, these quoted totals are saved with the reservations
+	quotes, err := m.quoteGroup(r.Context(), &group)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0022: cannot price reservation")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	if !form.ValidForm() {
+		stringMap := make(map[string]string)
+		stringMap["start_date"] = group.StartDate.Format("02/01/2006")
+		stringMap["end_date"] = group.EndDate.Format("02/01/2006")
+
+		data := make(map[string]interface{})
+		data["group"] = group
+		data["quotes"] = quotes
+
+		m.App.Session.Put(r.Context(), "error", "#0006: invalid form details submitted")
+
+		render.Template(w, r, "make-group-reservation.page.tmpl", &models.TemplateData{
+			Form:      form,
+			Data:      data,
+			StringMap: stringMap,
+		})
+		return
+	}
+
+	// the group has one confirmation reference for the lead guest, while each room keeps its own so it can still be managed on its own
+	group.ConfirmationRef, err = bookingref.NewRef()
+	for i := 0; err == nil && i < len(group.Reservations); i++ {
+		group.Reservations[i].ConfirmationRef, err = bookingref.NewRef()
+	}
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0046: cannot create confirmation reference")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	// after all validation procedures are complete, re-check availability & insert every room's reservation as one operation
+	groupID, err := m.DB.CreateBookingGroup(r.Context(), group, m.App.Session.GetString(r.Context(), "hold_token"))
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		var unavailable *repository.RoomUnavailableError
+		if errors.As(err, &unavailable) {
+			roomName := "room"
+			for _, rsvn := range group.Reservations {
+				if rsvn.RoomID == unavailable.RoomID {
+					roomName = rsvn.Room.RoomName
+				}
+			}
+			m.App.Session.Put(r.Context(), "warning", fmt.Sprintf("Sorry, the %s has just been reserved by another guest, please search again", roomName))
+			m.App.Session.Remove(r.Context(), "group")
+			http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0062: cannot insert group reservation into database")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	// each room's reservation is published on its own, read back with the id it was given
+	created, err := m.DB.GetBookingGroupByID(r.Context(), int(groupID))
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+	}
+	RequestChannelSync()
+	for _, rsvn := range created.Reservations {
+		m.publishReservation(webhook.ReservationCreated, rsvn, rsvn.Status)
+	}
+
+	// the rooms of the group, as listed in both emails
+	var rooms strings.Builder
+	for _, rsvn := range group.Reservations {
+		rooms.WriteString(fmt.Sprintf(`<li>the %s for %s, quoted at %s (ref. %s, <a href="%s">manage this room</a>)</li>`,
+			rsvn.Room.RoomName, rsvn.Party(), pricing.FormatAmount(rsvn.TotalPrice), rsvn.ConfirmationRef, m.manageBookingURL(rsvn.ConfirmationRef)))
+	}
+
+	// send one email notification to the lead guest covering every room
+	htmlMsg := fmt.Sprintf(`
+		<h3 class="text-center">Eden House: Group Reservation Confirmation</h3>
+		<p>&nbsp;</p>
+		<p>Dear %s&nbsp;%s</p>
+		<p>This is to confirm your group reservation from %s to %s, we look forward to seeing you all then.</p>
+		<p>Your group confirmation reference is <strong>%s</strong>, covering...</p>
+		<ul>%s</ul>
+		<p>The total price of your stay is <strong>%s</strong>.</p>
+	`, group.FirstName, group.LastName, group.StartDate.Format("Monday 02 January 2006"), group.EndDate.Format("Monday 02 January 2006"),
+		group.ConfirmationRef, rooms.String(), pricing.FormatAmount(group.TotalPrice()))
+	msg := models.MailData{
+		To:       group.Email,
+		From:     os.Getenv("SMTP_FROM"),
+		Subject:  "Eden House: Group Reservation Confirmation",
+		Content:  htmlMsg,
+		Template: "confirmation.html",
+	}
+	m.App.MailChannel <- msg
+
+	// send email notification to owner / admin
+	htmlMsg = fmt.Sprintf(`
+		<h3>Eden House: Group Reservation Notification</h3>
+		<p>&nbsp;</p>
+		<p>A group reservation (ref. %s) has been made by %s %s covering %s to %s for %s, quoted at %s in total...</p>
+		<ul>%s</ul>
+	`, group.ConfirmationRef, group.FirstName, group.LastName, group.StartDate.Format("02/01/2006"), group.EndDate.Format("02/01/2006"),
+		group.Party(), pricing.FormatAmount(group.TotalPrice()), rooms.String())
+	msg = models.MailData{
+		To:       os.Getenv("SMTP_TO"),
+		From:     os.Getenv("SMTP_FROM"),
+		Subject:  "Eden House: Group Reservation Notification",
+		Content:  htmlMsg,
+		Template: "notification.html",
+	}
+	m.App.MailChannel <- msg
+
+	// the holds have become the reservations' own room restrictions, so the guest no longer holds anything
+	m.App.Session.Remove(r.Context(), "hold_token")
+
+	m.App.Session.Put(r.Context(), "group", group)
+	http.Redirect(w, r, "/group-reservation-summary", http.StatusSeeOther)
+}
+
+// GroupReservationSummary is the handler for displaying group reservation details
+func (m *Repository) GroupReservationSummary(w http.ResponseWriter, r *http.Request) {
+	group, ok := m.App.Session.Get(r.Context(), "group").(models.BookingGroup)
+	if !ok || group.ConfirmationRef == "" {
+		m.App.Session.Put(r.Context(), "error", "There are no reservation details available to display")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	//reaching this point implies 'group' was successfully retrieved, therefore can now be removed from session
+	m.App.Session.Remove(r.Context(), "group")
+
+	stringMap := make(map[string]string)
+	stringMap["start_date"] = group.StartDate.Format("Monday 02 January 2006")
+	stringMap["end_date"] = group.EndDate.Format("Monday 02 January 2006")
+
+	data := make(map[string]interface{})
+	data["group"] = group
+
+	render.Template(w, r, "group-reservation-summary.page.tmpl", &models.TemplateData{
+		Data:      data,
+		StringMap: stringMap,
+	})
+}
+
+// Waitlist displays the form by which a guest joins the waitlist for dates & a party found to have no availability
+func (m *Repository) Waitlist(w http.ResponseWriter, r *http.Request) {
+	entry, ok := m.App.Session.Get(r.Context(), "waitlist").(models.WaitlistEntry)
+	if !ok {
+		m.App.Session.Put(r.Context(), "error", "#0053: cannot get waitlist dates from session")
+		http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["waitlist"] = entry
+
+	render.Template(w, r, "waitlist.page.tmpl", &models.TemplateData{
+		Form: forms.NewForm(nil),
+		Data: data,
+	})
+}
+
+// PostWaitlist adds a guest to the waitlist, so they can be emailed should a room become free for their dates & party
+func (m *Repository) PostWaitlist(w http.ResponseWriter, r *http.Request) {
+	entry, ok := m.App.Session.Get(r.Context(), "waitlist").(models.WaitlistEntry)
+	if !ok {
+		m.App.Session.Put(r.Context(), "error", "#0053: cannot get waitlist dates from session")
+		http.Redirect(w, r, "/search-availability", http.StatusSeeOther)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0054: cannot parse waitlist form")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	entry.FirstName = r.Form.Get("first_name")
+	entry.LastName = r.Form.Get("last_name")
+	entry.Email = r.Form.Get("email")
+	entry.Phone = r.Form.Get("phone")
+
+	form := forms.NewForm(r.PostForm)
+
+	form.RequiredFields("first_name", "last_name", "email", "phone")
+	form.MinLength("first_name", 2)
+	form.MinLength("last_name", 2)
+	form.MinLength("phone", 6)
+	form.IsEmail("email")
+
+	if !form.ValidForm() {
+		data := make(map[string]interface{})
+		data["waitlist"] = entry
+
+		render.Template(w, r, "waitlist.page.tmpl", &models.TemplateData{
+			Form: form,
+			Data: data,
+		})
+		return
+	}
+
+	err = m.DB.InsertWaitlistEntry(r.Context(), entry)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0055: cannot add you to the waitlist, please try again later")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Remove(r.Context(), "waitlist")
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("You are on the waitlist, we will email %s should a room become available", entry.Email))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// guestBooking finds the reservation named in a /my-booking/{ref} URL, provided the access token given with it is valid
+func (m *Repository) guestBooking(r *http.Request, token string) (models.Reservation, bool) {
+	ref := chi.URLParam(r, "ref")
+	if !bookingref.Verify(m.App.BookingKey, ref, token) {
+		return models.Reservation{}, false
+	}
+
+	reservation, err := m.DB.GetReservationByRef(r.Context(), ref)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		return models.Reservation{}, false
+	}
+
+	return reservation, true
+}
+
+// renderGuestBooking displays the my-booking page for a guest's reservation
+func (m *Repository) renderGuestBooking(w http.ResponseWriter, r *http.Request, reservation models.Reservation, token string, form *forms.Form) {
+	deadline := m.cancellationDeadline(reservation)
+
+	stringMap := make(map[string]string)
+	stringMap["start_date"] = reservation.StartDate.Format("Monday 02 January 2006")
+	stringMap["end_date"] = reservation.EndDate.Format("Monday 02 January 2006")
+	stringMap["cancel_by"] = deadline.Format("Monday 02 January 2006")
+	stringMap["token"] = token
+
+	data := make(map[string]interface{})
+	data["reservation"] = reservation
+	data["cancellable"] = !time.Now().After(deadline) && lifecycle.Check(reservation.Status, models.StatusCancelled) == nil
+
+	render.Template(w, r, "my-booking.page.tmpl", &models.TemplateData{
+		Form:      form,
+		Data:      data,
+		StringMap: stringMap,
+	})
+}
+
+// GuestBooking displays a guest's own booking, reached via the signed link sent in their confirmation email
+func (m *Repository) GuestBooking(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	reservation, ok := m.guestBooking(r, token)
+	if !ok {
+		m.App.Session.Put(r.Context(), "error", "#0041: this booking link is not valid, please check it against your confirmation email")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	m.renderGuestBooking(w, r, reservation, token, forms.NewForm(nil))
+}
+
+// PostGuestBooking updates the contact details of a guest's own booking
+func (m *Repository) PostGuestBooking(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0042: cannot parse booking form")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	token := r.Form.Get("token")
+
+	reservation, ok := m.guestBooking(r, token)
+	if !ok {
+		m.App.Session.Put(r.Context(), "error", "#0041: this booking link is not valid, please check it against your confirmation email")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	reservation.FirstName = r.Form.Get("first_name")
+	reservation.LastName = r.Form.Get("last_name")
+	reservation.Email = r.Form.Get("email")
+	reservation.Phone = r.Form.Get("phone")
+
+	form := forms.NewForm(r.PostForm)
+
+	form.RequiredFields("first_name", "last_name", "email", "phone")
+	form.MinLength("first_name", 2)
+	form.MinLength("last_name", 2)
+	form.MinLength("phone", 6)
+	form.IsEmail("email")
+
+	if !form.ValidForm() {
+		m.App.Session.Put(r.Context(), "error", "#0043: invalid contact details submitted")
+		m.renderGuestBooking(w, r, reservation, token, form)
+		return
+	}
+
+	err = m.DB.UpdateReservation(r.Context(), reservation)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0044: cannot update your booking, please try again later")
+		http.Redirect(w, r, m.manageBookingURL(reservation.ConfirmationRef), http.StatusSeeOther)
+		return
+	}
+
+	m.publishReservation(webhook.ReservationUpdated, reservation, reservation.Status)
+
+	m.App.Session.Put(r.Context(), "flash", "Your contact details have been updated")
+	http.Redirect(w, r, m.manageBookingURL(reservation.ConfirmationRef), http.StatusSeeOther)
+}
+
+// GuestBookingCancel cancels a guest's own booking, provided enough notice is given under the cancellation policy
+func (m *Repository) GuestBookingCancel(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0042: cannot parse booking form")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	reservation, ok := m.guestBooking(r, r.Form.Get("token"))
+	if !ok {
+		m.App.Session.Put(r.Context(), "error", "#0041: this booking link is not valid, please check it against your confirmation email")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	if time.Now().After(m.cancellationDeadline(reservation)) {
+		m.App.Session.Put(r.Context(), "warning", fmt.Sprintf("Sorry, bookings can only be cancelled online up to %d days before arrival, please contact us", m.App.CancellationDays))
+		http.Redirect(w, r, m.manageBookingURL(reservation.ConfirmationRef), http.StatusSeeOther)
+		return
+	}
+
+	if lifecycle.Check(reservation.Status, models.StatusCancelled) != nil {
+		m.App.Session.Put(r.Context(), "warning", fmt.Sprintf("Sorry, this booking is %s and can no longer be cancelled online, please contact us", strings.ToLower(reservation.Status.Label())))
+		http.Redirect(w, r, m.manageBookingURL(reservation.ConfirmationRef), http.StatusSeeOther)
+		return
+	}
+
+	// cancelling keeps the reservation but frees its room
+	err = m.DB.UpdateReservationStatus(r.Context(), reservation.ID, reservation.Status, models.StatusCancelled)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0045: cannot cancel your booking, please try again later")
+		http.Redirect(w, r, m.manageBookingURL(reservation.ConfirmationRef), http.StatusSeeOther)
+		return
+	}
+
+	previous := reservation.Status
+	RequestChannelSync()
+	reservation.Status = models.StatusCancelled
+	m.publishReservation(webhook.ReservationStatusChanged, reservation, previous)
+
+	// send email notification to guest
+	htmlMsg := fmt.Sprintf(`
+		<h3 class="text-center">Eden House: Cancellation Confirmation</h3>
+		<p>&nbsp;</p>
+		<p>Dear %s&nbsp;%s</p>
+		<p>This is to confirm that your reservation (ref. %s) from %s to %s in the %s has been cancelled.</p>
+	`, reservation.FirstName, reservation.LastName, reservation.ConfirmationRef, reservation.StartDate.Format("Monday 02 January 2006"),
+		reservation.EndDate.Format("Monday 02 January 2006"), reservation.Room.RoomName)
+	msg := models.MailData{
+		To:       reservation.Email,
+		From:     os.Getenv("SMTP_FROM"),
+		Subject:  "Eden House: Cancellation Confirmation",
+		Content:  htmlMsg,
+		Template: "confirmation.html",
+	}
+	m.App.MailChannel <- msg
+
+	// send email notification to owner / admin
+	htmlMsg = fmt.Sprintf(`
+		<h3>Eden House: Cancellation Notification</h3>
+		<p>&nbsp;</p>
+		<p>The reservation (ref. %s) made by %s %s covering %s to %s for the %s has been cancelled by the guest.</p>
+	`, reservation.ConfirmationRef, reservation.FirstName, reservation.LastName, reservation.StartDate.Format("02/01/2006"),
+		reservation.EndDate.Format("02/01/2006"), reservation.Room.RoomName)
+	msg = models.MailData{
+		To:       os.Getenv("SMTP_TO"),
+		From:     os.Getenv("SMTP_FROM"),
+		Subject:  "Eden House: Cancellation Notification",
+		Content:  htmlMsg,
+		Template: "notification.html",
+	}
+	m.App.MailChannel <- msg
+
+	m.notifyWaitlist(reservation.RoomID, reservation.StartDate, reservation.EndDate)
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Your booking (ref. %s) has been cancelled", reservation.ConfirmationRef))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// Login displays login page & gets the administrator's login form
+func (m *Repository) Login(w http.ResponseWriter, r *http.Request) {
+	render.Template(w, r, "login.page.tmpl", &models.TemplateData{
+		Form: forms.NewForm(nil),
+	})
+}
+
+//PostLogin handles administrator login
+func (m *Repository) PostLogin(w http.ResponseWriter, r *http.Request) {
+	// prevent session fixation attack
+	_ = m.App.Session.RenewToken(r.Context())
+	// parse form
+	err := r.ParseForm()
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+	}
+	email := r.Form.Get("email")
+	password := r.Form.Get("password")
+	// validation...
+	form := forms.NewForm(r.PostForm)
+	form.RequiredFields("email", "password")
+	form.IsEmail("email")
+	if !form.ValidForm() {
+		stringMap := make(map[string]string)
+		stringMap["email"] = email
+		stringMap["password"] = password
+		render.Template(w, r, "login.page.tmpl", &models.TemplateData{
+			Form:      form,
+			StringMap: stringMap,
+		})
+		return
+	}
+	// attempt administrator authentication
+	id, _, err := m.DB.AuthenticateAdministrator(r.Context(), email, password)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "Please check - invalid login credentials")
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	// store the returned administrator's id in session
+	m.App.Session.Put(r.Context(), "admin_id", id)
+	m.App.Session.Put(r.Context(), "flash", "Logged in successfully")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// Logout logs an administrator out
+func (m *Repository) Logout(w http.ResponseWriter, r *http.Request) {
 	_ = m.App.Session.Destroy(r.Context())
 	_ = m.App.Session.RenewToken(r.Context())
 	http.Redirect(w, r, "/login", http.StatusSeeOther)
 }
 
-// AdminDashboard gets the reservations management dashboard
-func (m *Repository) AdminDashboard(w http.ResponseWriter, r *http.Request) {
-	render.Template(w, r, "admin-dashboard.page.tmpl", &models.TemplateData{})
+// AdminDashboard gets the reservations management dashboard
+func (m *Repository) AdminDashboard(w http.ResponseWriter, r *http.Request) {
+	render.Template(w, r, "admin-dashboard.page.tmpl", &models.TemplateData{})
+}
+
+// AdminReservationsNew gets any new (pending) reservations
+func (m *Repository) AdminReservationsNew(w http.ResponseWriter, r *http.Request) {
+	reservations, err := m.DB.GetNewReservations(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0013: cannot get new reservations from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["reservations"] = reservations
+
+	render.Template(w, r, "admin-reservations-new.page.tmpl", &models.TemplateData{
+		Data: data,
+	})
+}
+
+// AdminReservationsAll gets all reservations, or only those of a given status when filtered
+func (m *Repository) AdminReservationsAll(w http.ResponseWriter, r *http.Request) {
+	var reservations []models.Reservation
+	var err error
+
+	filter := r.URL.Query().Get("status")
+	if filter == "" {
+		reservations, err = m.DB.GetAllReservations(r.Context())
+	} else {
+		status, errP := lifecycle.Parse(filter)
+		if errP != nil {
+			m.App.Session.Put(r.Context(), "error", "#0047: unknown reservation status")
+			http.Redirect(w, r, "/admin/reservations-all", http.StatusSeeOther)
+			return
+		}
+		reservations, err = m.DB.GetReservationsByStatus(r.Context(), status)
+	}
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0014: cannot get all reservations from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	// rooms are offered as a filter on exports
+	rooms, err := m.DB.GetAllRooms(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0023: cannot get rooms from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	stringMap := make(map[string]string)
+	stringMap["status"] = filter
+
+	data := make(map[string]interface{})
+	data["reservations"] = reservations
+	data["statuses"] = lifecycle.All()
+	data["rooms"] = rooms
+	data["columns"] = exportColumns
+
+	render.Template(w, r, "admin-reservations-all.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+	})
+}
+
+// AdminReservationsCalendar gets the reservations displayed in calendar block format
+func (m *Repository) AdminReservationsCalendar(w http.ResponseWriter, r *http.Request) {
+	// initially assume that request URL has no added parameters i.e. no year/month specified, which is equal to now...
+	now := time.Now()
+	// ... if URL parameter are included
+	if r.URL.Query().Get("y") != "" {
+		year, _ := strconv.Atoi(r.URL.Query().Get("y"))
+		month, _ := strconv.Atoi(r.URL.Query().Get("m"))
+		// allows updating of 'now' to incorporate parameter values for next/prev(ious) dates
+		now = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+	}
+	// allows next/prev to be created based on +/- one month from 'now'
+	next := now.AddDate(0, 1, 0)
+	prev := now.AddDate(0, -1, 0)
+	// format for display in reservations calendar page, remembering that a month can roll from 12 to 1 (or vice-versa) if year changes
+	nextMonth := next.Format("01")
+	nextMonthYear := next.Format("2006")
+	prevMonth := prev.Format("01")
+	prevMonthYear := prev.Format("2006")
+
+	stringMap := make(map[string]string)
+	stringMap["next_month"] = nextMonth
+	stringMap["next_month_year"] = nextMonthYear
+	stringMap["prev_month"] = prevMonth
+	stringMap["prev_month_year"] = prevMonthYear
+	stringMap["this_month"] = now.Format("01")
+	stringMap["this_month_year"] = now.Format("2006")
+	stringMap["show_month"] = now.Format("January")
+
+	// reservations may be filtered by status, those not matching are still shown, but faded
+	filter := r.URL.Query().Get("status")
+	if filter != "" {
+		if _, err := lifecycle.Parse(filter); err != nil {
+			m.App.Session.Put(r.Context(), "error", "#0047: unknown reservation status")
+			http.Redirect(w, r, "/admin/reservations-cal", http.StatusSeeOther)
+			return
+		}
+	}
+	stringMap["status"] = filter
+
+	// need to get first & last days of each month
+	currentYear, currentMonth, _ := now.Date()
+	currentLocation := now.Location()
+	firstDayOfMonth := time.Date(currentYear, currentMonth, 1, 0, 0, 0, 0, currentLocation)
+	lastDayOfMonth := firstDayOfMonth.AddDate(0, 1, -1)
+	// store number of days in month in intMap which will vary based on current result from preceding code block
+	dimIntMap := make(map[string]int)
+	dimIntMap["days_in_month"] = lastDayOfMonth.Day()
+
+	// get information for all rooms
+	rooms, err := m.DB.GetAllRooms(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		helpers.ServerError(w, err)
+		return
+	}
+	// pass all rooms data to calendar template
+	data := make(map[string]interface{})
+	data["rooms"] = rooms
+	data["statuses"] = lifecycle.All()
+
+	// create reservation, reservation status & owner blocked maps for each day of current month, for each room
+	for _, rm := range rooms {
+		reservationsMap := make(map[string]int)
+		statusMap := make(map[string]models.ReservationStatus)
+		blockedMap := make(map[string]int)
+		externalMap := make(map[string]int)
+
+		for d := firstDayOfMonth; !d.After(lastDayOfMonth); d = d.AddDate(0, 0, 1) {
+			// initialise each day in each map as 0
+			reservationsMap[d.Format("2-01-2006")] = 0
+			blockedMap[d.Format("2-01-2006")] = 0
+			externalMap[d.Format("2-01-2006")] = 0
+		}
+		// get all room restrictions for each room
+		restrictions, err := m.DB.GetRoomRestrictionsByDate(r.Context(), rm.ID, firstDayOfMonth, lastDayOfMonth)
+		if err != nil {
+			if m.dbInterrupted(w, r, err) {
+				return
+			}
+			helpers.ServerError(w, err)
+			return
+		}
+
+		for _, rs := range restrictions {
+			if rs.RestrictionID == models.RestrictionExternalBooking {
+				// bookings taken on other sites, which can only be changed there
+				for d := rs.StartDate; !d.After(rs.EndDate); d = d.AddDate(0, 0, 1) {
+					externalMap[d.Format("2-01-2006")] = rs.ID
+				}
+			} else if rs.ReservationID == 0 {
+				// owner blocked entries where query has substituted null with 0 for reservation_id
+				for d := rs.StartDate; !d.After(rs.EndDate); d = d.AddDate(0, 0, 1) {
+					blockedMap[d.Format("2-01-2006")] = rs.ID
+				}
+			} else {
+				// reservations
+				for d := rs.StartDate; !d.After(rs.EndDate); d = d.AddDate(0, 0, 1) {
+					reservationsMap[d.Format("2-01-2006")] = int(rs.ReservationID)
+					statusMap[d.Format("2-01-2006")] = rs.Reservation.Status
+				}
+			}
+		}
+		data[fmt.Sprintf("reservations_map_%d", rm.ID)] = reservationsMap
+		data[fmt.Sprintf("reservation_status_map_%d", rm.ID)] = statusMap
+		data[fmt.Sprintf("blocked_map_%d", rm.ID)] = blockedMap
+		data[fmt.Sprintf("external_map_%d", rm.ID)] = externalMap
+
+		m.App.Session.Put(r.Context(), fmt.Sprintf("blocked_map_%d", rm.ID), blockedMap)
+
+	}
+
+	render.Template(w, r, "admin-reservations-cal.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		IntMap:    dimIntMap,
+		Data:      data,
+	})
+}
+
+// AdminPostReservationsCalendar handles posting of edited data submitted from the reservations calendar
+func (m *Repository) AdminPostReservationsCalendar(w http.ResponseWriter, r *http.Request) {
+	// parse form
+	err := r.ParseForm()
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+	}
+
+	month, _ := strconv.Atoi(r.Form.Get("month"))
+	year, _ := strconv.Atoi(r.Form.Get("year"))
+	blocks, _ := strconv.Atoi(r.Form.Get("blocks"))
+
+	// process owner room blocks
+
+	// get information for all rooms
+	rooms, err := m.DB.GetAllRooms(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		helpers.ServerError(w, err)
+		return
+	}
+
+	// get access to forms.go HasField()
+	form := forms.NewForm(r.PostForm)
+
+	// dates freed this month by each removed block, for each room, so that any waitlisted guests can be told once all changes are saved
+	freed := make(map[int]map[int][]time.Time)
+
+	// 1. handle owner blocks to be REMOVED for current month, for each room
+	for _, rm := range rooms {
+		// get blocked maps from session for given rooms / month, which contain current blocked information at point calendar was displayed.
+		// loop through entire map for each room. If there is an entry in a map which does NOT exist in posted data AND if its restriction_id
+		// is > 0, then it is a room block which needs to be removed. This is because unchecking a checkbox means that it will never be submitted
+		// within post data, which is normal behaviour. Consequently if it was previously checked in the map, it will have a value > 0 and need
+		// to be removed.
+		currentBlockedMap := m.App.Session.Get(r.Context(), fmt.Sprintf("blocked_map_%d", rm.ID)).(map[string]int)
+		freed[rm.ID] = make(map[int][]time.Time)
+
+		for dated, rsID := range currentBlockedMap {
+			// ok will be false if value is NOT in the map
+			if value, ok := currentBlockedMap[dated]; ok {
+				// only concerned with values > 0 AND which are NOT in form post data (i.e. unchecked), the remainder amount to placeholders for days without /// blocks
+				if value > 0 {
+					if !form.HasField(fmt.Sprintf("remove_blocked_%d_%s", rm.ID, dated)) {
+						// delete the blocked restriction by its id, only once for a block of several days
+						if _, deleted := freed[rm.ID][rsID]; !deleted {
+							err := m.DB.DeleteRoomBlock(r.Context(), rsID)
+							if err != nil {
+								m.App.ErrorLog.Println(err)
+								continue
+							}
+						}
+						day, _ := time.Parse("2-01-2006", dated)
+						freed[rm.ID][rsID] = append(freed[rm.ID][rsID], day)
+					}
+				}
+			}
+		}
+	}
+
+	// 2. handle owner blocks to be ADDED for current month, for each room
+	for blockedName := range r.PostForm {
+		if strings.HasPrefix(blockedName, "add_blocked") {
+			elements := strings.Split(blockedName, "_")
+			roomID, _ := strconv.Atoi(elements[2])
+			startDate, _ := time.Parse("2-01-2006", elements[3])
+			endDate := startDate.AddDate(0, 0, (blocks - 1))
+			// insert a new owner block, its id given to webhooks so that the block's later removal can be matched to it
+			id, err := m.DB.CreateRoomBlock(r.Context(), roomID, startDate, endDate)
+			if err != nil {
+				m.App.ErrorLog.Println(err)
+				continue
+			}
+			RequestChannelSync()
+			m.publish(webhook.BlockAdded, newWebhookBlock(int(id), roomID, startDate, endDate))
+		}
+	}
+
+	// 3. tell waitlisted guests of any dates freed by removed blocks
+	for roomID, blocks := range freed {
+		for rsID, days := range blocks {
+			start, end := days[0], days[0]
+			for _, d := range days {
+				if d.Before(start) {
+					start = d
+				}
+				if d.After(end) {
+					end = d
+				}
+			}
+			RequestChannelSync()
+			m.notifyWaitlist(roomID, start, end)
+			m.publish(webhook.BlockRemoved, newWebhookBlock(rsID, roomID, start, end))
+		}
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Room(s) blocked... changes saved")
+	http.Redirect(w, r, fmt.Sprintf("/admin/reservations-cal?y=%d&m=%d", year, month), http.StatusSeeOther)
+}
+
+// AdminReservationStatus moves the associated reservation on to a new status, where its lifecycle allows
+func (m *Repository) AdminReservationStatus(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	src := chi.URLParam(r, "src")
+
+	year := r.URL.Query().Get("y")
+	month := r.URL.Query().Get("m")
+
+	redirect := fmt.Sprintf("/admin/reservations-%s", src)
+	if (year != "") || (month != "") {
+		redirect = fmt.Sprintf("/admin/reservations-cal?y=%s&m=%s", year, month)
+	}
+
+	status, err := lifecycle.Parse(chi.URLParam(r, "status"))
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0047: unknown reservation status")
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+		return
+	}
+
+	reservation, err := m.DB.GetReservationByID(r.Context(), id)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0016: cannot get requested reservation from database")
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+		return
+	}
+
+	err = m.DB.UpdateReservationStatus(r.Context(), id, reservation.Status, status)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		var transitionErr *lifecycle.TransitionError
+		var changedErr *repository.StatusChangedError
+		switch {
+		case errors.As(err, &transitionErr):
+			m.App.Session.Put(r.Context(), "warning", fmt.Sprintf("Sorry, %s", transitionErr))
+		case errors.As(err, &changedErr):
+			m.App.Session.Put(r.Context(), "warning", fmt.Sprintf("Reservation (id=%d) has been changed elsewhere, please check it and try again", id))
+		default:
+			m.App.ErrorLog.Println(err)
+			m.App.Session.Put(r.Context(), "error", "#0019: cannot update requested reservation")
+		}
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+		return
+	}
+
+	if status == models.StatusCancelled {
+		m.notifyWaitlist(reservation.RoomID, reservation.StartDate, reservation.EndDate)
+	}
+
+	previous := reservation.Status
+	RequestChannelSync()
+	reservation.Status = status
+	m.publishReservation(webhook.ReservationStatusChanged, reservation, previous)
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Reservation (id=%d) has been marked as %s", id, status.Label()))
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// AdminReservationDelete moves the associated reservation to the trash, freeing its room
+func (m *Repository) AdminReservationDelete(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	src := chi.URLParam(r, "src")
+
+	// the reservation's room & dates are needed afterwards, to tell any waitlisted guests the room is free
+	reservation, errR := m.DB.GetReservationByID(r.Context(), id)
+	if errR != nil {
+		m.App.ErrorLog.Println(errR)
+	}
+
+	err := m.DB.DeleteReservation(r.Context(), id)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0020: cannot delete requested reservation")
+		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
+		return
+	}
+
+	if errR == nil && reservation.Status != models.StatusCancelled {
+		m.notifyWaitlist(reservation.RoomID, reservation.StartDate, reservation.EndDate)
+	}
+
+	RequestChannelSync()
+	// should the reservation not have been read, it is published by id alone
+	reservation.ID = id
+	m.publishReservation(webhook.ReservationDeleted, reservation, reservation.Status)
+
+	year := r.URL.Query().Get("y")
+	month := r.URL.Query().Get("m")
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Reservation (id=%d) has been moved to the trash", id))
+
+	if (year == "") && (month == "") {
+		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
+	} else {
+		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-cal?y=%s&m=%s", year, month), http.StatusSeeOther)
+	}
+}
+
+// AdminTrash displays deleted reservations & owner blocks, which may be restored until they are purged
+func (m *Repository) AdminTrash(w http.ResponseWriter, r *http.Request) {
+	reservations, err := m.DB.GetDeletedReservations(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0049: cannot get deleted reservations from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	blocks, err := m.DB.GetDeletedRoomBlocks(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0050: cannot get deleted owner blocks from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	intMap := make(map[string]int)
+	intMap["retention_days"] = m.App.TrashRetentionDays
+
+	data := make(map[string]interface{})
+	data["reservations"] = reservations
+	data["blocks"] = blocks
+
+	render.Template(w, r, "admin-trash.page.tmpl", &models.TemplateData{
+		IntMap: intMap,
+		Data:   data,
+	})
+}
+
+// AdminReservationRestore takes the associated reservation back out of the trash, provided its room is still free, posting it to
+// webhooks as created once more, since they were told of its deletion
+func (m *Repository) AdminReservationRestore(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := m.DB.RestoreReservation(r.Context(), id)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		var unavailable *repository.RoomUnavailableError
+		if errors.As(err, &unavailable) {
+			m.App.Session.Put(r.Context(), "warning", fmt.Sprintf("Reservation (id=%d) cannot be restored, its room has since been taken", id))
+		} else {
+			m.App.ErrorLog.Println(err)
+			m.App.Session.Put(r.Context(), "error", "#0051: cannot restore requested reservation")
+		}
+		http.Redirect(w, r, "/admin/trash", http.StatusSeeOther)
+		return
+	}
+
+	RequestChannelSync()
+	m.publishCreatedReservation(id)
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Reservation (id=%d) has been restored", id))
+	http.Redirect(w, r, "/admin/trash", http.StatusSeeOther)
+}
+
+// AdminRoomBlockRestore takes the associated owner block back out of the trash, provided its room is still free, posting it to
+// webhooks as added once more, since they were told of its removal
+func (m *Repository) AdminRoomBlockRestore(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := m.DB.RestoreRoomBlock(r.Context(), id)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		var unavailable *repository.RoomUnavailableError
+		if errors.As(err, &unavailable) {
+			m.App.Session.Put(r.Context(), "warning", fmt.Sprintf("Owner block (id=%d) cannot be restored, its room has since been taken", id))
+		} else {
+			m.App.ErrorLog.Println(err)
+			m.App.Session.Put(r.Context(), "error", "#0052: cannot restore requested owner block")
+		}
+		http.Redirect(w, r, "/admin/trash", http.StatusSeeOther)
+		return
+	}
+
+	RequestChannelSync()
+	block, err := m.DB.GetRoomBlockByID(r.Context(), id)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+	} else {
+		m.publish(webhook.BlockAdded, newWebhookBlock(id, block.RoomID, block.StartDate, block.EndDate))
+	}
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Owner block (id=%d) has been restored", id))
+	http.Redirect(w, r, "/admin/trash", http.StatusSeeOther)
+}
+
+// AdminReservation gets a single reservation by id & displays it in form layout
+func (m *Repository) AdminReservation(w http.ResponseWriter, r *http.Request) {
+	// get id from reservation link clicked, get elements exploded on '/' & convert 4th element into string
+	elements := strings.Split(r.RequestURI, "/")
+	id, err := strconv.Atoi(elements[4])
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0015: missing URL parameter (id)")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+	stringMap := make(map[string]string)
+	src := elements[3]
+	stringMap["src"] = src
+	stringMap["year"] = r.URL.Query().Get("y")
+	stringMap["month"] = r.URL.Query().Get("m")
+
+	reservation, err := m.DB.GetReservationByID(r.Context(), id)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0016: cannot get requested reservation from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	changes, err := m.DB.GetReservationStatusChanges(r.Context(), id)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0048: cannot get reservation status history from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["reservation"] = reservation
+	data["status_changes"] = changes
+	data["next_statuses"] = lifecycle.Next(reservation.Status)
+
+	render.Template(w, r, "admin-reservation.page.tmpl", &models.TemplateData{
+		Data:      data,
+		StringMap: stringMap,
+		Form:      forms.NewForm(nil),
+	})
+}
+
+// AdminPostReservation updates a single reservation by id
+func (m *Repository) AdminPostReservation(w http.ResponseWriter, r *http.Request) {
+	// initially ensure form data is parsed correctly
+	err := r.ParseForm()
+	if err != nil {
+		m.App.ErrorLog.Println("unable to parse update reservation form")
+		return
+	}
+	// get id from reservation link clicked, get elements exploded on '/' & convert 4th element into string
+	elements := strings.Split(r.RequestURI, "/")
+	id, err := strconv.Atoi(elements[4])
+	if err != nil {
+		m.App.ErrorLog.Println(" missing URL parameter (id)")
+		return
+	}
+	stringMap := make(map[string]string)
+	src := elements[3]
+	stringMap["src"] = src
+
+	reservation, err := m.DB.GetReservationByID(r.Context(), id)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0017: cannot get requested reservation from database")
+		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
+		return
+	}
+	// overwrite any/all of 4 possible updatable fields
+	reservation.FirstName = r.Form.Get("first_name")
+	reservation.LastName = r.Form.Get("last_name")
+	reservation.Email = r.Form.Get("email")
+	reservation.Phone = r.Form.Get("phone")
+
+	form := forms.NewForm(r.PostForm)
+
+	// perform all necessary validations
+
+	// form.HasField("first_name", r)
+	form.RequiredFields("first_name", "last_name", "email", "phone")
+	form.MinLength("first_name", 2)
+	form.MinLength("last_name", 2)
+	form.MinLength("phone", 6)
+	form.IsEmail("email")
+
+	if !form.ValidForm() {
+		// format start/end dates as strings (instead of time.Time) & place into a StringMap (templatedata) for re-display in make-reservation page
+		sd := reservation.StartDate.Format("02/01/2006")
+		ed := reservation.EndDate.Format("02/01/2006")
+		stringMap := make(map[string]string)
+		stringMap["start_date"] = sd
+		stringMap["end_date"] = ed
+		// also must persist 'src' to maintain URL integrity if validation fails and function is subsequently recalled
+		stringMap["src"] = src
+
+		changes, err := m.DB.GetReservationStatusChanges(r.Context(), id)
+		if err != nil {
+			m.App.ErrorLog.Println(err)
+		}
+
+		data := make(map[string]interface{})
+		data["reservation"] = reservation
+		data["status_changes"] = changes
+		data["next_statuses"] = lifecycle.Next(reservation.Status)
+
+		m.App.Session.Put(r.Context(), "error", "#0021: invalid form details submitted")
+
+		render.Template(w, r, "admin-reservation.page.tmpl", &models.TemplateData{
+			Form:      form,
+			Data:      data,
+			StringMap: stringMap,
+		})
+		return
+	}
+
+	// after all validation procedures are complete, update reservation record in database
+	err = m.DB.UpdateReservation(r.Context(), reservation)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0018: cannot update reservation")
+		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
+		return
+	}
+
+	m.publishReservation(webhook.ReservationUpdated, reservation, reservation.Status)
+
+	year := r.Form.Get("y")
+	month := r.Form.Get("m")
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Reservation (id=%d) has been updated", reservation.ID))
+
+	if (year == "") && (month == "") {
+		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
+	} else {
+		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-cal?y=%s&m=%s", year, month), http.StatusSeeOther)
+	}
+}
+
+// AdminBookingGroups displays all booking groups, each with its rooms
+func (m *Repository) AdminBookingGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := m.DB.GetAllBookingGroups(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0063: cannot get booking groups from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["groups"] = groups
+
+	render.Template(w, r, "admin-groups.page.tmpl", &models.TemplateData{
+		Data: data,
+	})
+}
+
+// AdminBookingGroup gets a single booking group by id & displays it, with its rooms, for management as one unit
+func (m *Repository) AdminBookingGroup(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	group, err := m.DB.GetBookingGroupByID(r.Context(), id)
+	if err != nil || len(group.Reservations) == 0 {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0064: cannot get requested booking group from database")
+		http.Redirect(w, r, "/admin/groups", http.StatusSeeOther)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["group"] = group
+	data["next_statuses"] = lifecycle.NextForAll(group.Statuses())
+
+	render.Template(w, r, "admin-group.page.tmpl", &models.TemplateData{
+		Data: data,
+	})
+}
+
+// AdminBookingGroupStatus moves every room of the associated booking group on to the status given, or none of them should any not be allowed to
+func (m *Repository) AdminBookingGroupStatus(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	redirect := fmt.Sprintf("/admin/groups/%d", id)
+
+	status, err := lifecycle.Parse(chi.URLParam(r, "status"))
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0047: unknown reservation status")
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+		return
+	}
+
+	group, err := m.DB.GetBookingGroupByID(r.Context(), id)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0064: cannot get requested booking group from database")
+		http.Redirect(w, r, "/admin/groups", http.StatusSeeOther)
+		return
+	}
+
+	err = m.DB.UpdateBookingGroupStatus(r.Context(), id, status)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		var transitionErr *lifecycle.TransitionError
+		if errors.As(err, &transitionErr) {
+			m.App.Session.Put(r.Context(), "warning", fmt.Sprintf("Sorry, %s, so no rooms of the group have been changed", transitionErr))
+		} else {
+			m.App.ErrorLog.Println(err)
+			m.App.Session.Put(r.Context(), "error", "#0065: cannot update requested booking group")
+		}
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+		return
+	}
+
+	if status == models.StatusCancelled {
+		for _, rsvn := range group.Reservations {
+			m.notifyWaitlist(rsvn.RoomID, rsvn.StartDate, rsvn.EndDate)
+		}
+	}
+
+	for _, rsvn := range group.Reservations {
+		if rsvn.Status == status {
+			continue
+		}
+		previous := rsvn.Status
+		RequestChannelSync()
+		rsvn.Status = status
+		m.publishReservation(webhook.ReservationStatusChanged, rsvn, previous)
+	}
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Every room of group %s has been marked as %s", group.ConfirmationRef, status.Label()))
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// AdminBookingGroupDelete moves every reservation of the associated booking group to the trash, freeing their rooms
+func (m *Repository) AdminBookingGroupDelete(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	// the group's rooms & dates are needed afterwards, to tell any waitlisted guests the rooms are free
+	group, errG := m.DB.GetBookingGroupByID(r.Context(), id)
+	if errG != nil {
+		m.App.ErrorLog.Println(errG)
+	}
+
+	err := m.DB.DeleteBookingGroup(r.Context(), id)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0066: cannot delete requested booking group")
+		http.Redirect(w, r, "/admin/groups", http.StatusSeeOther)
+		return
+	}
+
+	if errG == nil {
+		for _, rsvn := range group.Reservations {
+			RequestChannelSync()
+			if rsvn.Status != models.StatusCancelled {
+				m.notifyWaitlist(rsvn.RoomID, rsvn.StartDate, rsvn.EndDate)
+			}
+			m.publishReservation(webhook.ReservationDeleted, rsvn, rsvn.Status)
+		}
+	}
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Every room of group (id=%d) has been moved to the trash", id))
+	http.Redirect(w, r, "/admin/groups", http.StatusSeeOther)
+}
+
+// AdminRates displays the nightly rates of all rooms together with all seasonal rates
+func (m *Repository) AdminRates(w http.ResponseWriter, r *http.Request) {
+	rooms, err := m.DB.GetAllRooms(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0023: cannot get rooms from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	seasons, err := m.DB.GetAllSeasonalRates(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0024: cannot get seasonal rates from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["rooms"] = rooms
+	data["seasons"] = seasons
+
+	render.Template(w, r, "admin-rates.page.tmpl", &models.TemplateData{
+		Data: data,
+		Form: forms.NewForm(nil),
+	})
+}
+
+// AdminPostRoomRates updates the nightly, weekend & extra guest rates, together with maximum occupancy, of every room
+func (m *Repository) AdminPostRoomRates(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0025: cannot parse room rates form")
+		http.Redirect(w, r, "/admin/rates", http.StatusSeeOther)
+		return
+	}
+
+	rooms, err := m.DB.GetAllRooms(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		helpers.ServerError(w, err)
+		return
+	}
+
+	for _, rm := range rooms {
+		// rooms without posted rates are left unchanged
+		if r.Form.Get(fmt.Sprintf("nightly_rate_%d", rm.ID)) == "" {
+			continue
+		}
+
+		nightly, errN := pricing.ParseAmount(r.Form.Get(fmt.Sprintf("nightly_rate_%d", rm.ID)))
+		weekend, errW := pricing.ParseAmount(r.Form.Get(fmt.Sprintf("weekend_rate_%d", rm.ID)))
+		extra, errE := pricing.ParseAmount(r.Form.Get(fmt.Sprintf("extra_guest_rate_%d", rm.ID)))
+		included, errI := strconv.Atoi(r.Form.Get(fmt.Sprintf("included_guests_%d", rm.ID)))
+		maxAdults, errA := strconv.Atoi(r.Form.Get(fmt.Sprintf("max_adults_%d", rm.ID)))
+		maxChildren, errC := strconv.Atoi(r.Form.Get(fmt.Sprintf("max_children_%d", rm.ID)))
+		maxInfants, errF := strconv.Atoi(r.Form.Get(fmt.Sprintf("max_infants_%d", rm.ID)))
+		if errN != nil || errW != nil || errE != nil || errI != nil || included < 0 ||
+			errA != nil || errC != nil || errF != nil || maxAdults < 1 || maxChildren < 0 || maxInfants < 0 {
+			m.App.Session.Put(r.Context(), "error", fmt.Sprintf("#0026: invalid rates given for the %s", rm.RoomName))
+			http.Redirect(w, r, "/admin/rates", http.StatusSeeOther)
+			return
+		}
+
+		rm.NightlyRate = nightly
+		rm.WeekendRate = weekend
+		rm.ExtraGuestRate = extra
+		rm.IncludedGuests = included
+		rm.MaxAdults = maxAdults
+		rm.MaxChildren = maxChildren
+		rm.MaxInfants = maxInfants
+
+		err = m.DB.UpdateRoomRates(r.Context(), rm)
+		if err != nil {
+			if m.dbInterrupted(w, r, err) {
+				return
+			}
+			m.App.Session.Put(r.Context(), "error", "#0027: cannot update room rates")
+			http.Redirect(w, r, "/admin/rates", http.StatusSeeOther)
+			return
+		}
+
+		err = m.DB.UpdateRoomOccupancy(r.Context(), rm)
+		if err != nil {
+			if m.dbInterrupted(w, r, err) {
+				return
+			}
+			m.App.Session.Put(r.Context(), "error", "#0033: cannot update room occupancy")
+			http.Redirect(w, r, "/admin/rates", http.StatusSeeOther)
+			return
+		}
+	}
+
+	RequestChannelSync()
+	m.App.Session.Put(r.Context(), "flash", "Room rates & occupancy have been updated")
+	http.Redirect(w, r, "/admin/rates", http.StatusSeeOther)
+}
+
+// AdminPostSeasonalRate adds a seasonal rate for a room
+func (m *Repository) AdminPostSeasonalRate(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0028: cannot parse seasonal rate form")
+		http.Redirect(w, r, "/admin/rates", http.StatusSeeOther)
+		return
+	}
+
+	form := forms.NewForm(r.PostForm)
+	form.RequiredFields("room_id", "season_name", "start_date", "end_date", "nightly_rate")
+	form.MinLength("season_name", 2)
+
+	layout := "02/01/2006"
+	startDate, errS := time.Parse(layout, r.Form.Get("start_date"))
+	endDate, errE := time.Parse(layout, r.Form.Get("end_date"))
+	if errS != nil || errE != nil || endDate.Before(startDate) {
+		form.Errors.AddErrMsg("end_date", "please give a valid date range, as dd/mm/yyyy, ending on or after its start")
+	}
+
+	nightly, err := pricing.ParseAmount(r.Form.Get("nightly_rate"))
+	if err != nil {
+		form.Errors.AddErrMsg("nightly_rate", "please give a valid amount e.g. 95.00")
+	}
+
+	// a weekend rate is optional, without one the seasonal nightly rate applies all week
+	weekend := 0
+	if r.Form.Get("weekend_rate") != "" {
+		weekend, err = pricing.ParseAmount(r.Form.Get("weekend_rate"))
+		if err != nil {
+			form.Errors.AddErrMsg("weekend_rate", "please give a valid amount e.g. 110.00")
+		}
+	}
+
+	roomID, _ := strconv.Atoi(r.Form.Get("room_id"))
+
+	if !form.ValidForm() {
+		rooms, err := m.DB.GetAllRooms(r.Context())
+		if err != nil {
+			if m.dbInterrupted(w, r, err) {
+				return
+			}
+			helpers.ServerError(w, err)
+			return
+		}
+		seasons, err := m.DB.GetAllSeasonalRates(r.Context())
+		if err != nil {
+			if m.dbInterrupted(w, r, err) {
+				return
+			}
+			helpers.ServerError(w, err)
+			return
+		}
+
+		data := make(map[string]interface{})
+		data["rooms"] = rooms
+		data["seasons"] = seasons
+
+		m.App.Session.Put(r.Context(), "error", "#0029: invalid seasonal rate details submitted")
+
+		render.Template(w, r, "admin-rates.page.tmpl", &models.TemplateData{
+			Form: form,
+			Data: data,
+		})
+		return
+	}
+
+	season := models.SeasonalRate{
+		RoomID:      roomID,
+		SeasonName:  r.Form.Get("season_name"),
+		StartDate:   startDate,
+		EndDate:     endDate,
+		NightlyRate: nightly,
+		WeekendRate: weekend,
+	}
+
+	err = m.DB.InsertSeasonalRate(r.Context(), season)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0030: cannot insert seasonal rate into database")
+		http.Redirect(w, r, "/admin/rates", http.StatusSeeOther)
+		return
+	}
+
+	RequestChannelSync()
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Seasonal rate (%s) has been added", season.SeasonName))
+	http.Redirect(w, r, "/admin/rates", http.StatusSeeOther)
+}
+
+// AdminSeasonalRateDelete deletes a seasonal rate by id
+func (m *Repository) AdminSeasonalRateDelete(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := m.DB.DeleteSeasonalRate(r.Context(), id)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0031: cannot delete requested seasonal rate")
+		http.Redirect(w, r, "/admin/rates", http.StatusSeeOther)
+		return
+	}
+
+	RequestChannelSync()
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Seasonal rate (id=%d) has been deleted", id))
+	http.Redirect(w, r, "/admin/rates", http.StatusSeeOther)
+}
+
+// AdminStayRules displays all stay rules, for all rooms
+func (m *Repository) AdminStayRules(w http.ResponseWriter, r *http.Request) {
+	rooms, err := m.DB.GetAllRooms(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0023: cannot get rooms from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	rules, err := m.DB.GetAllStayRules(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0036: cannot get stay rules from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["rooms"] = rooms
+	data["rules"] = rules
+
+	render.Template(w, r, "admin-stay-rules.page.tmpl", &models.TemplateData{
+		Data: data,
+		Form: forms.NewForm(nil),
+	})
+}
+
+// AdminPostStayRule adds a minimum &/or maximum stay rule for a room
+func (m *Repository) AdminPostStayRule(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0037: cannot parse stay rule form")
+		http.Redirect(w, r, "/admin/stay-rules", http.StatusSeeOther)
+		return
+	}
+
+	form := forms.NewForm(r.PostForm)
+	form.RequiredFields("room_id", "rule_name", "start_date", "end_date", "min_nights")
+	form.MinLength("rule_name", 2)
+	form.IsNumberInRange("min_nights", 1, 365)
+
+	layout := "02/01/2006"
+	startDate, errS := time.Parse(layout, r.Form.Get("start_date"))
+	endDate, errE := time.Parse(layout, r.Form.Get("end_date"))
+	if errS != nil || errE != nil || endDate.Before(startDate) {
+		form.Errors.AddErrMsg("end_date", "please give a valid date range, as dd/mm/yyyy, ending on or after its start")
+	}
+
+	minNights, _ := strconv.Atoi(r.Form.Get("min_nights"))
+
+	// a maximum is optional, without one stays of any length are allowed
+	maxNights := 0
+	if form.HasField("max_nights") {
+		if form.IsNumberInRange("max_nights", 0, 365) {
+			maxNights, _ = strconv.Atoi(r.Form.Get("max_nights"))
+			if maxNights > 0 && maxNights < minNights {
+				form.Errors.AddErrMsg("max_nights", "maximum nights cannot be fewer than minimum nights")
+			}
+		}
+	}
+
+	roomID, _ := strconv.Atoi(r.Form.Get("room_id"))
+
+	if !form.ValidForm() {
+		rooms, err := m.DB.GetAllRooms(r.Context())
+		if err != nil {
+			if m.dbInterrupted(w, r, err) {
+				return
+			}
+			helpers.ServerError(w, err)
+			return
+		}
+		rules, err := m.DB.GetAllStayRules(r.Context())
+		if err != nil {
+			if m.dbInterrupted(w, r, err) {
+				return
+			}
+			helpers.ServerError(w, err)
+			return
+		}
+
+		data := make(map[string]interface{})
+		data["rooms"] = rooms
+		data["rules"] = rules
+
+		m.App.Session.Put(r.Context(), "error", "#0038: invalid stay rule details submitted")
+
+		render.Template(w, r, "admin-stay-rules.page.tmpl", &models.TemplateData{
+			Form: form,
+			Data: data,
+		})
+		return
+	}
+
+	rule := models.StayRule{
+		RoomID:    roomID,
+		RuleName:  r.Form.Get("rule_name"),
+		StartDate: startDate,
+		EndDate:   endDate,
+		MinNights: minNights,
+		MaxNights: maxNights,
+	}
+
+	err = m.DB.InsertStayRule(r.Context(), rule)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0039: cannot insert stay rule into database")
+		http.Redirect(w, r, "/admin/stay-rules", http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Stay rule (%s) has been added", rule.RuleName))
+	http.Redirect(w, r, "/admin/stay-rules", http.StatusSeeOther)
+}
+
+// AdminStayRuleDelete deletes a stay rule by id
+func (m *Repository) AdminStayRuleDelete(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := m.DB.DeleteStayRule(r.Context(), id)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0040: cannot delete requested stay rule")
+		http.Redirect(w, r, "/admin/stay-rules", http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Stay rule (id=%d) has been deleted", id))
+	http.Redirect(w, r, "/admin/stay-rules", http.StatusSeeOther)
 }
 
-// AdminReservationsNew gets any new (unprocessed) reservations
-func (m *Repository) AdminReservationsNew(w http.ResponseWriter, r *http.Request) {
-	reservations, err := m.DB.GetNewReservations()
+// AdminWaitlist displays all guests on the waitlist, in the order they joined it
+func (m *Repository) AdminWaitlist(w http.ResponseWriter, r *http.Request) {
+	entries, err := m.DB.GetWaitlist(r.Context())
 	if err != nil {
-		m.App.Session.Put(r.Context(), "error", "#0013: cannot get new reservations from database")
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0056: cannot get waitlist from database")
 		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
 		return
 	}
 
 	data := make(map[string]interface{})
-	data["reservations"] = reservations
+	data["entries"] = entries
 
-	render.Template(w, r, "admin-reservations-new.page.tmpl", &models.TemplateData{
+	render.Template(w, r, "admin-waitlist.page.tmpl", &models.TemplateData{
 		Data: data,
 	})
 }
 
-// AdminReservationsAll gets all reservations
-func (m *Repository) AdminReservationsAll(w http.ResponseWriter, r *http.Request) {
-	reservations, err := m.DB.GetAllReservations()
+// AdminWaitlistDelete removes the associated guest from the waitlist
+func (m *Repository) AdminWaitlistDelete(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := m.DB.DeleteWaitlistEntry(r.Context(), id)
 	if err != nil {
-		m.App.Session.Put(r.Context(), "error", "#0014: cannot get all reservations from database")
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0057: cannot delete requested waitlist entry")
+		http.Redirect(w, r, "/admin/waitlist", http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Waitlist entry (id=%d) has been deleted", id))
+	http.Redirect(w, r, "/admin/waitlist", http.StatusSeeOther)
+}
+
+// AdminAPITokens displays the logged in administrator's API tokens, for use with the admin JSON API, together with any token just
+// created (which is shown only once)
+func (m *Repository) AdminAPITokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := m.DB.GetAPITokensByAdministratorID(r.Context(), m.App.Session.GetInt(r.Context(), "admin_id"))
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0067: cannot get API tokens from database")
 		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
 		return
 	}
 
+	stringMap := make(map[string]string)
+	stringMap["new_token"] = m.App.Session.PopString(r.Context(), "api_token")
+
 	data := make(map[string]interface{})
-	data["reservations"] = reservations
+	data["tokens"] = tokens
 
-	render.Template(w, r, "admin-reservations-all.page.tmpl", &models.TemplateData{
-		Data: data,
+	render.Template(w, r, "admin-api-tokens.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+		Form:      forms.NewForm(nil),
 	})
 }
 
-// AdminReservationsCalendar gets the reservations displayed in calendar block format
-func (m *Repository) AdminReservationsCalendar(w http.ResponseWriter, r *http.Request) {
-	// initially assume that request URL has no added parameters i.e. no year/month specified, which is equal to now...
-	now := time.Now()
-	// ... if URL parameter are included
-	if r.URL.Query().Get("y") != "" {
-		year, _ := strconv.Atoi(r.URL.Query().Get("y"))
-		month, _ := strconv.Atoi(r.URL.Query().Get("m"))
-		// allows updating of 'now' to incorporate parameter values for next/prev(ious) dates
-		now = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+// AdminPostAPIToken creates a new API token for the logged in administrator
+func (m *Repository) AdminPostAPIToken(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0068: cannot parse API token form")
+		http.Redirect(w, r, "/admin/api-tokens", http.StatusSeeOther)
+		return
+	}
+
+	adminID := m.App.Session.GetInt(r.Context(), "admin_id")
+	if adminID == 0 {
+		m.App.Session.Put(r.Context(), "error", "Please login !")
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	form := forms.NewForm(r.PostForm)
+	form.RequiredFields("name")
+	form.MinLength("name", 2)
+
+	if !form.ValidForm() {
+		tokens, err := m.DB.GetAPITokensByAdministratorID(r.Context(), adminID)
+		if err != nil {
+			if m.dbInterrupted(w, r, err) {
+				return
+			}
+			helpers.ServerError(w, err)
+			return
+		}
+
+		stringMap := make(map[string]string)
+		stringMap["new_token"] = ""
+
+		data := make(map[string]interface{})
+		data["tokens"] = tokens
+
+		m.App.Session.Put(r.Context(), "error", "#0069: invalid API token details submitted")
+
+		render.Template(w, r, "admin-api-tokens.page.tmpl", &models.TemplateData{
+			StringMap: stringMap,
+			Data:      data,
+			Form:      form,
+		})
+		return
+	}
+
+	token, err := apitoken.New()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	err = m.DB.InsertAPIToken(r.Context(), models.APIToken{
+		AdministratorID: adminID,
+		Name:            r.Form.Get("name"),
+		TokenHash:       apitoken.Hash(token),
+	})
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0070: cannot insert API token into database")
+		http.Redirect(w, r, "/admin/api-tokens", http.StatusSeeOther)
+		return
+	}
+
+	// only the token's hash is kept, so the token itself is shown this once
+	m.App.Session.Put(r.Context(), "api_token", token)
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("API token (%s) has been created", r.Form.Get("name")))
+	http.Redirect(w, r, "/admin/api-tokens", http.StatusSeeOther)
+}
+
+// AdminAPITokenDelete revokes one of the logged in administrator's API tokens by id
+func (m *Repository) AdminAPITokenDelete(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := m.DB.DeleteAPIToken(r.Context(), id, m.App.Session.GetInt(r.Context(), "admin_id"))
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0071: cannot revoke requested API token")
+		http.Redirect(w, r, "/admin/api-tokens", http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("API token (id=%d) has been revoked", id))
+	http.Redirect(w, r, "/admin/api-tokens", http.StatusSeeOther)
+}
+
+// AdminICalFeeds displays every room's iCal feeds, with their secret URLs for adding to booking sites & calendar apps
+func (m *Repository) AdminICalFeeds(w http.ResponseWriter, r *http.Request) {
+	rooms, err := m.DB.GetAllRooms(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0023: cannot get rooms from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	feeds, err := m.DB.GetAllICalFeeds(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0072: cannot get iCal feeds from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
 	}
-	// allows next/prev to be created based on +/- one month from 'now'
-	next := now.AddDate(0, 1, 0)
-	prev := now.AddDate(0, -1, 0)
-	// format for display in reservations calendar page, remembering that a month can roll from 12 to 1 (or vice-versa) if year changes
-	nextMonth := next.Format("01")
-	nextMonthYear := next.Format("2006")
-	prevMonth := prev.Format("01")
-	prevMonthYear := prev.Format("2006")
 
 	stringMap := make(map[string]string)
-	stringMap["next_month"] = nextMonth
-	stringMap["next_month_year"] = nextMonthYear
-	stringMap["prev_month"] = prevMonth
-	stringMap["prev_month_year"] = prevMonthYear
-	stringMap["this_month"] = now.Format("01")
-	stringMap["this_month_year"] = now.Format("2006")
-	stringMap["show_month"] = now.Format("January")
+	stringMap["base_url"] = baseURL(r)
 
-	// need to get first & last days of each month
-	currentYear, currentMonth, _ := now.Date()
-	currentLocation := now.Location()
-	firstDayOfMonth := time.Date(currentYear, currentMonth, 1, 0, 0, 0, 0, currentLocation)
-	lastDayOfMonth := firstDayOfMonth.AddDate(0, 1, -1)
-	// store number of days in month in intMap which will vary based on current result from preceding code block
-	dimIntMap := make(map[string]int)
-	dimIntMap["days_in_month"] = lastDayOfMonth.Day()
+	data := make(map[string]interface{})
+	data["rooms"] = rooms
+	data["feeds"] = feeds
+
+	render.Template(w, r, "admin-ical-feeds.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		Data:      data,
+	})
+}
+
+// AdminPostICalFeed creates a new public or private iCal feed for a room
+func (m *Repository) AdminPostICalFeed(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0073: cannot parse iCal feed form")
+		http.Redirect(w, r, "/admin/ical-feeds", http.StatusSeeOther)
+		return
+	}
+
+	roomID, _ := strconv.Atoi(r.Form.Get("room_id"))
+
+	token, err := newFeedToken()
+	if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	feed := models.ICalFeed{
+		RoomID:  roomID,
+		Token:   token,
+		Private: r.Form.Get("private") == "1",
+	}
+
+	err = m.DB.InsertICalFeed(r.Context(), feed)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0074: cannot insert iCal feed into database")
+		http.Redirect(w, r, "/admin/ical-feeds", http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "iCal feed has been created")
+	http.Redirect(w, r, "/admin/ical-feeds", http.StatusSeeOther)
+}
+
+// AdminICalFeedDelete revokes an iCal feed by id, so that its URL no longer works
+func (m *Repository) AdminICalFeedDelete(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := m.DB.DeleteICalFeed(r.Context(), id)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0075: cannot revoke requested iCal feed")
+		http.Redirect(w, r, "/admin/ical-feeds", http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("iCal feed (id=%d) has been revoked", id))
+	http.Redirect(w, r, "/admin/ical-feeds", http.StatusSeeOther)
+}
+
+// renderICalImports displays every room's iCal imports, with the outcome of each one's last import & any double bookings found
+func (m *Repository) renderICalImports(w http.ResponseWriter, r *http.Request, form *forms.Form) {
+	rooms, err := m.DB.GetAllRooms(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0023: cannot get rooms from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	imports, err := m.DB.GetAllICalImports(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0076: cannot get iCal imports from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	conflicts, err := m.DB.GetBookingConflicts(r.Context(), calendarDay(time.Now()))
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0077: cannot get double bookings from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["rooms"] = rooms
+	data["imports"] = imports
+	data["conflicts"] = conflicts
+
+	render.Template(w, r, "admin-ical-imports.page.tmpl", &models.TemplateData{
+		Data: data,
+		Form: form,
+	})
+}
+
+// AdminICalImports displays every room's iCal imports of calendars from other sites
+func (m *Repository) AdminICalImports(w http.ResponseWriter, r *http.Request) {
+	m.renderICalImports(w, r, forms.NewForm(nil))
+}
+
+// AdminPostICalImport adds a calendar from another site to be imported for a room
+func (m *Repository) AdminPostICalImport(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0078: cannot parse iCal import form")
+		http.Redirect(w, r, "/admin/ical-imports", http.StatusSeeOther)
+		return
+	}
+
+	form := forms.NewForm(r.PostForm)
+	form.RequiredFields("name", "url")
+	form.MinLength("name", 2)
+	form.IsWebURL("url")
+
+	if !form.ValidForm() {
+		m.App.Session.Put(r.Context(), "error", "#0079: invalid iCal import details submitted")
+		m.renderICalImports(w, r, form)
+		return
+	}
+
+	roomID, _ := strconv.Atoi(r.Form.Get("room_id"))
+
+	imp := models.ICalImport{
+		RoomID: roomID,
+		Name:   strings.TrimSpace(r.Form.Get("name")),
+		URL:    strings.TrimSpace(r.Form.Get("url")),
+	}
+
+	err = m.DB.InsertICalImport(r.Context(), imp)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0080: cannot insert iCal import into database")
+		http.Redirect(w, r, "/admin/ical-imports", http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("iCal import (%s) has been added, its calendar will be imported within %d minutes", imp.Name, m.App.ICalImportMinutes))
+	http.Redirect(w, r, "/admin/ical-imports", http.StatusSeeOther)
+}
 
-	// get information for all rooms
-	rooms, err := m.DB.GetAllRooms()
+// AdminICalImportsSync imports every room's calendars from other sites now, rather than waiting for the importer
+func (m *Repository) AdminICalImportsSync(w http.ResponseWriter, r *http.Request) {
+	m.ImportExternalCalendars(r.Context())
+
+	m.App.Session.Put(r.Context(), "flash", "Calendars have been imported")
+	http.Redirect(w, r, "/admin/ical-imports", http.StatusSeeOther)
+}
+
+// AdminICalImportDelete removes an iCal import by id, together with the external bookings copied from its calendar
+func (m *Repository) AdminICalImportDelete(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := m.DB.DeleteICalImport(r.Context(), id)
 	if err != nil {
-		helpers.ServerError(w, err)
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0081: cannot delete requested iCal import")
+		http.Redirect(w, r, "/admin/ical-imports", http.StatusSeeOther)
 		return
 	}
-	// pass all rooms data to calendar template
-	data := make(map[string]interface{})
-	data["rooms"] = rooms
 
-	// create reservation & owner blocked maps for each day of current month, for each room
-	for _, rm := range rooms {
-		reservationsMap := make(map[string]int)
-		blockedMap := make(map[string]int)
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("iCal import (id=%d) & its external bookings have been deleted", id))
+	http.Redirect(w, r, "/admin/ical-imports", http.StatusSeeOther)
+}
 
-		for d := firstDayOfMonth; !d.After(lastDayOfMonth); d = d.AddDate(0, 0, 1) {
-			// initialise each day in each map as 0
-			reservationsMap[d.Format("2-01-2006")] = 0
-			blockedMap[d.Format("2-01-2006")] = 0
-		}
-		// get all room restrictions for each room
-		restrictions, err := m.DB.GetRoomRestrictionsByDate(rm.ID, firstDayOfMonth, lastDayOfMonth)
-		if err != nil {
-			helpers.ServerError(w, err)
+// renderWebhooks displays every webhook, with the log of recent deliveries & any webhook secret just created (which is shown only
+// once)
+func (m *Repository) renderWebhooks(w http.ResponseWriter, r *http.Request, form *forms.Form, secret string) {
+	hooks, err := m.DB.GetAllWebhooks(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
 			return
 		}
+		m.App.Session.Put(r.Context(), "error", "#0082: cannot get webhooks from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
 
-		for _, rs := range restrictions {
-			if rs.ReservationID == 0 {
-				// owner blocked entries where query has substituted null with 0 for reservation_id
-				for d := rs.StartDate; !d.After(rs.EndDate); d = d.AddDate(0, 0, 1) {
-					blockedMap[d.Format("2-01-2006")] = rs.ID
-				}
-			} else {
-				// reservations
-				for d := rs.StartDate; !d.After(rs.EndDate); d = d.AddDate(0, 0, 1) {
-					reservationsMap[d.Format("2-01-2006")] = int(rs.ReservationID)
-				}
-			}
+	deliveries, err := m.DB.GetWebhookDeliveries(r.Context(), webhookLogSize)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
 		}
-		data[fmt.Sprintf("reservations_map_%d", rm.ID)] = reservationsMap
-		data[fmt.Sprintf("blocked_map_%d", rm.ID)] = blockedMap
-
-		m.App.Session.Put(r.Context(), fmt.Sprintf("blocked_map_%d", rm.ID), blockedMap)
+		m.App.Session.Put(r.Context(), "error", "#0083: cannot get webhook deliveries from database")
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
 
+	// events ticked on the form, kept when it is redisplayed with errors
+	selected := make(map[string]bool)
+	for _, e := range form.Values["events"] {
+		selected[e] = true
 	}
 
-	render.Template(w, r, "admin-reservations-cal.page.tmpl", &models.TemplateData{
+	stringMap := make(map[string]string)
+	stringMap["new_secret"] = secret
+
+	data := make(map[string]interface{})
+	data["webhooks"] = hooks
+	data["deliveries"] = deliveries
+	data["events"] = webhook.Events()
+	data["selected"] = selected
+
+	render.Template(w, r, "admin-webhooks.page.tmpl", &models.TemplateData{
 		StringMap: stringMap,
-		IntMap:    dimIntMap,
 		Data:      data,
+		Form:      form,
 	})
 }
 
-// AdminPostReservationsCalendar handles posting of edited data submitted from the reservations calendar
-func (m *Repository) AdminPostReservationsCalendar(w http.ResponseWriter, r *http.Request) {
-	// parse form
+// AdminWebhooks displays every webhook & the log of recent deliveries to them
+func (m *Repository) AdminWebhooks(w http.ResponseWriter, r *http.Request) {
+	m.renderWebhooks(w, r, forms.NewForm(nil), m.App.Session.PopString(r.Context(), "webhook_secret"))
+}
+
+// AdminPostWebhook adds a webhook, to which the chosen events (or, if none are chosen, every event) are posted
+func (m *Repository) AdminPostWebhook(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
-		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0084: cannot parse webhook form")
+		http.Redirect(w, r, "/admin/webhooks", http.StatusSeeOther)
+		return
 	}
 
-	month, _ := strconv.Atoi(r.Form.Get("month"))
-	year, _ := strconv.Atoi(r.Form.Get("year"))
-	blocks, _ := strconv.Atoi(r.Form.Get("blocks"))
+	form := forms.NewForm(r.PostForm)
+	form.RequiredFields("url")
+	form.IsWebURL("url")
 
-	// process owner room blocks
+	known := make(map[string]bool)
+	for _, e := range webhook.Events() {
+		known[e] = true
+	}
+	for _, e := range r.PostForm["events"] {
+		if !known[e] {
+			form.Errors.AddErrMsg("events", fmt.Sprintf("Unknown event %q", e))
+			break
+		}
+	}
 
-	// get information for all rooms
-	rooms, err := m.DB.GetAllRooms()
+	if !form.ValidForm() {
+		m.App.Session.Put(r.Context(), "error", "#0085: invalid webhook details submitted")
+		m.renderWebhooks(w, r, form, "")
+		return
+	}
+
+	secret, err := webhook.NewSecret()
 	if err != nil {
 		helpers.ServerError(w, err)
 		return
 	}
 
-	// get access to forms.go HasField()
-	form := forms.NewForm(r.PostForm)
-
-	// 1. handle owner blocks to be REMOVED for current month, for each room
-	for _, rm := range rooms {
-		// get blocked maps from session for given rooms / month, which contain current blocked information at point calendar was displayed.
-		// loop through entire map for each room. If there is an entry in a map which does NOT exist in posted data AND if its restriction_id
-		// is > 0, then it is a room block which needs to be removed. This is because unchecking a checkbox means that it will never be submitted
-		// within post data, which is normal behaviour. Consequently if it was previously checked in the map, it will have a value > 0 and need
-		// to be removed.
-		currentBlockedMap := m.App.Session.Get(r.Context(), fmt.Sprintf("blocked_map_%d", rm.ID)).(map[string]int)
-
-		for dated, rsID := range currentBlockedMap {
-			// ok will be false if value is NOT in the map
-			if value, ok := currentBlockedMap[dated]; ok {
-				// only concerned with values > 0 AND which are NOT in form post data (i.e. unchecked), the remainder amount to placeholders for days without /// blocks
-				if value > 0 {
-					if !form.HasField(fmt.Sprintf("remove_blocked_%d_%s", rm.ID, dated)) {
-						// delete the blocked restriction by its id
-						err := m.DB.DeleteRoomBlock(rsID)
-						if err != nil {
-							m.App.ErrorLog.Println(err)
-						}
-					}
-				}
-			}
-		}
+	hook := models.Webhook{
+		URL:    strings.TrimSpace(r.Form.Get("url")),
+		Secret: secret,
+		Events: r.PostForm["events"],
 	}
 
-	// 2. handle owner blocks to be ADDED for current month, for each room
-	for blockedName := range r.PostForm {
-		if strings.HasPrefix(blockedName, "add_blocked") {
-			elements := strings.Split(blockedName, "_")
-			roomID, _ := strconv.Atoi(elements[2])
-			startDate, _ := time.Parse("2-01-2006", elements[3])
-			endDate := startDate.AddDate(0, 0, (blocks - 1))
-			// insert a new owner block
-			err := m.DB.InsertRoomBlock(roomID, startDate, endDate)
-			if err != nil {
-				m.App.ErrorLog.Println(err)
-			}
+	err = m.DB.InsertWebhook(r.Context(), hook)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
 		}
+		m.App.Session.Put(r.Context(), "error", "#0086: cannot insert webhook into database")
+		http.Redirect(w, r, "/admin/webhooks", http.StatusSeeOther)
+		return
 	}
 
-	m.App.Session.Put(r.Context(), "flash", "Room(s) blocked... changes saved")
-	http.Redirect(w, r, fmt.Sprintf("/admin/reservations-cal?y=%d&m=%d", year, month), http.StatusSeeOther)
+	// the receiver needs the secret to check deliveries' signatures, so it is shown straight away
+	m.App.Session.Put(r.Context(), "webhook_secret", secret)
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Webhook (%s) has been added", hook.URL))
+	http.Redirect(w, r, "/admin/webhooks", http.StatusSeeOther)
 }
 
-// AdminReservationProcess marks the associated reservation as processed
-func (m *Repository) AdminReservationProcess(w http.ResponseWriter, r *http.Request) {
+// AdminWebhookDelete removes a webhook by id, together with its deliveries
+func (m *Repository) AdminWebhookDelete(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
 
-	src := chi.URLParam(r, "src")
-
-	err := m.DB.UpdateReservationProcessed(id, 1)
+	err := m.DB.DeleteWebhook(r.Context(), id)
 	if err != nil {
-		m.App.Session.Put(r.Context(), "error", "#0019: cannot update requested reservation")
-		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0087: cannot delete requested webhook")
+		http.Redirect(w, r, "/admin/webhooks", http.StatusSeeOther)
 		return
 	}
 
-	year := r.URL.Query().Get("y")
-	month := r.URL.Query().Get("m")
-
-	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Reservation (id=%d) has been marked as processed", id))
-
-	if (year == "") && (month == "") {
-		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
-	} else {
-		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-cal?y=%s&m=%s", year, month), http.StatusSeeOther)
-	}
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Webhook (id=%d) has been deleted", id))
+	http.Redirect(w, r, "/admin/webhooks", http.StatusSeeOther)
 }
 
-// AdminReservationDelete deletes the associated reservation from the database
-func (m *Repository) AdminReservationDelete(w http.ResponseWriter, r *http.Request) {
+// AdminWebhookDeliveryRetry queues a failed delivery to be attempted again, as if it were new
+func (m *Repository) AdminWebhookDeliveryRetry(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
 
-	src := chi.URLParam(r, "src")
-
-	err := m.DB.DeleteReservation(id)
+	err := m.DB.RetryWebhookDelivery(r.Context(), id)
+	if m.dbInterrupted(w, r, err) {
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		m.App.Session.Put(r.Context(), "warning", fmt.Sprintf("Webhook delivery (id=%d) has not failed, so cannot be retried", id))
+		http.Redirect(w, r, "/admin/webhooks", http.StatusSeeOther)
+		return
+	}
 	if err != nil {
-		m.App.Session.Put(r.Context(), "error", "#0020: cannot delete requested reservation")
-		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
+		m.App.Session.Put(r.Context(), "error", "#0088: cannot retry requested webhook delivery")
+		http.Redirect(w, r, "/admin/webhooks", http.StatusSeeOther)
 		return
 	}
 
-	year := r.URL.Query().Get("y")
-	month := r.URL.Query().Get("m")
-
-	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Reservation (id=%d) has been deleted", id))
-
-	if (year == "") && (month == "") {
-		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
-	} else {
-		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-cal?y=%s&m=%s", year, month), http.StatusSeeOther)
-	}
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Webhook delivery (id=%d) will be attempted again shortly", id))
+	http.Redirect(w, r, "/admin/webhooks", http.StatusSeeOther)
 }
 
-// AdminReservation gets a single reservation by id & displays it in form layout
-func (m *Repository) AdminReservation(w http.ResponseWriter, r *http.Request) {
-	// get id from reservation link clicked, get elements exploded on '/' & convert 4th element into string
-	elements := strings.Split(r.RequestURI, "/")
-	id, err := strconv.Atoi(elements[4])
+// renderChannel displays the channel manager page, with the mapping form filled in from the saved mapping unless it is being
+// redisplayed with errors
+func (m *Repository) renderChannel(w http.ResponseWriter, r *http.Request, form *forms.Form) {
+	rooms, err := m.DB.GetAllRooms(r.Context())
 	if err != nil {
-		m.App.Session.Put(r.Context(), "error", "#0015: missing URL parameter (id)")
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0097: cannot get rooms from database")
 		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
 		return
 	}
-	stringMap := make(map[string]string)
-	src := elements[3]
-	stringMap["src"] = src
-	stringMap["year"] = r.URL.Query().Get("y")
-	stringMap["month"] = r.URL.Query().Get("m")
 
-	reservation, err := m.DB.GetReservationByID(id)
+	if form == nil {
+		mapped, err := m.DB.GetChannelRooms(r.Context())
+		if err != nil {
+			if m.dbInterrupted(w, r, err) {
+				return
+			}
+			m.App.Session.Put(r.Context(), "error", "#0097: cannot get channel rooms from database")
+			http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+			return
+		}
+		values := url.Values{}
+		for _, cr := range mapped {
+			values.Set(fmt.Sprintf("room_code_%d", cr.RoomID), cr.RoomCode)
+			values.Set(fmt.Sprintf("rate_plan_code_%d", cr.RoomID), cr.RatePlanCode)
+		}
+		form = forms.NewForm(values)
+	}
+
+	syncs, err := m.DB.GetChannelSyncs(r.Context(), channelLogSize)
 	if err != nil {
-		m.App.Session.Put(r.Context(), "error", "#0016: cannot get requested reservation from database")
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0098: cannot get channel sync log from database")
 		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
 		return
 	}
 
+	stringMap := make(map[string]string)
+	stringMap["channel_url"] = m.App.Channel.URL
+	stringMap["hotel_code"] = m.App.Channel.HotelCode
+
+	intMap := make(map[string]int)
+	intMap["channel_days"] = m.App.ChannelDays
+	intMap["sync_minutes"] = m.App.ChannelSyncMinutes
+
 	data := make(map[string]interface{})
-	data["reservation"] = reservation
+	data["rooms"] = rooms
+	data["syncs"] = syncs
 
-	render.Template(w, r, "admin-reservation.page.tmpl", &models.TemplateData{
-		Data:      data,
+	render.Template(w, r, "admin-channel.page.tmpl", &models.TemplateData{
 		StringMap: stringMap,
-		Form:      forms.NewForm(nil),
+		IntMap:    intMap,
+		Data:      data,
+		Form:      form,
 	})
 }
 
-// AdminPostReservation updates a single reservation by id
-func (m *Repository) AdminPostReservation(w http.ResponseWriter, r *http.Request) {
-	// initially ensure form data is parsed correctly
+// AdminChannel displays the mapping of rooms to the channel manager's room types & rate plans, together with the sync log
+func (m *Repository) AdminChannel(w http.ResponseWriter, r *http.Request) {
+	m.renderChannel(w, r, nil)
+}
+
+// AdminPostChannelRooms saves the mapping of rooms to the channel manager's room types & rate plans. A room without a room code is not
+// pushed to the channel manager, & one without a rate plan has its availability pushed but not its rates
+func (m *Repository) AdminPostChannelRooms(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
-		m.App.ErrorLog.Println("unable to parse update reservation form")
-		return
-	}
-	// get id from reservation link clicked, get elements exploded on '/' & convert 4th element into string
-	elements := strings.Split(r.RequestURI, "/")
-	id, err := strconv.Atoi(elements[4])
-	if err != nil {
-		m.App.ErrorLog.Println(" missing URL parameter (id)")
+		m.App.Session.Put(r.Context(), "error", "#0099: cannot parse channel manager form")
+		http.Redirect(w, r, "/admin/channel", http.StatusSeeOther)
 		return
 	}
-	stringMap := make(map[string]string)
-	src := elements[3]
-	stringMap["src"] = src
 
-	reservation, err := m.DB.GetReservationByID(id)
+	rooms, err := m.DB.GetAllRooms(r.Context())
 	if err != nil {
-		m.App.Session.Put(r.Context(), "error", "#0017: cannot get requested reservation from database")
-		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0097: cannot get rooms from database")
+		http.Redirect(w, r, "/admin/channel", http.StatusSeeOther)
 		return
 	}
-	// overwrite any/all of 4 possible updatable fields
-	reservation.FirstName = r.Form.Get("first_name")
-	reservation.LastName = r.Form.Get("last_name")
-	reservation.Email = r.Form.Get("email")
-	reservation.Phone = r.Form.Get("phone")
 
 	form := forms.NewForm(r.PostForm)
+	var mapped []models.ChannelRoom
+	codes := make(map[string]bool)
+	for _, room := range rooms {
+		roomField, planField := fmt.Sprintf("room_code_%d", room.ID), fmt.Sprintf("rate_plan_code_%d", room.ID)
+		roomCode, planCode := strings.TrimSpace(r.Form.Get(roomField)), strings.TrimSpace(r.Form.Get(planField))
+
+		switch {
+		case roomCode == "" && planCode != "":
+			form.Errors.AddErrMsg(roomField, "A room code is needed for the rate plan")
+		case roomCode == "":
+			continue
+		case !channelCode.MatchString(roomCode):
+			form.Errors.AddErrMsg(roomField, "Codes may only contain letters, digits, dots, dashes & underscores")
+		case codes[strings.ToUpper(roomCode)]:
+			form.Errors.AddErrMsg(roomField, "Another room has this room code")
+		}
+		if planCode != "" && !channelCode.MatchString(planCode) {
+			form.Errors.AddErrMsg(planField, "Codes may only contain letters, digits, dots, dashes & underscores")
+		}
 
-	// perform all necessary validations
-
-	// form.HasField("first_name", r)
-	form.RequiredFields("first_name", "last_name", "email", "phone")
-	form.MinLength("first_name", 2)
-	form.MinLength("last_name", 2)
-	form.MinLength("phone", 6)
-	form.IsEmail("email")
+		codes[strings.ToUpper(roomCode)] = true
+		mapped = append(mapped, models.ChannelRoom{RoomID: room.ID, RoomCode: roomCode, RatePlanCode: planCode})
+	}
 
 	if !form.ValidForm() {
-		// format start/end dates as strings (instead of time.Time) & place into a StringMap (templatedata) for re-display in make-reservation page
-		sd := reservation.StartDate.Format("02/01/2006")
-		ed := reservation.EndDate.Format("02/01/2006")
-		stringMap := make(map[string]string)
-		stringMap["start_date"] = sd
-		stringMap["end_date"] = ed
-		// also must persist 'src' to maintain URL integrity if validation fails and function is subsequently recalled
-		stringMap["src"] = src
+		m.App.Session.Put(r.Context(), "error", "#0100: invalid channel manager codes submitted")
+		m.renderChannel(w, r, form)
+		return
+	}
 
-		data := make(map[string]interface{})
-		data["reservation"] = reservation
+	err = m.DB.SaveChannelRooms(r.Context(), mapped)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0101: cannot save channel rooms to database")
+		http.Redirect(w, r, "/admin/channel", http.StatusSeeOther)
+		return
+	}
 
-		m.App.Session.Put(r.Context(), "error", "#0021: invalid form details submitted")
+	RequestChannelSync()
+	m.App.Session.Put(r.Context(), "flash", "Channel manager room codes have been saved")
+	http.Redirect(w, r, "/admin/channel", http.StatusSeeOther)
+}
 
-		render.Template(w, r, "admin-reservation.page.tmpl", &models.TemplateData{
-			Form:      form,
-			Data:      data,
-			StringMap: stringMap,
-		})
+// AdminChannelSync pushes any changes to the channel manager straight away
+func (m *Repository) AdminChannelSync(w http.ResponseWriter, r *http.Request) {
+	if m.App.Channel.URL == "" {
+		m.App.Session.Put(r.Context(), "warning", "No channel manager is configured, so there is nothing to push to")
+		http.Redirect(w, r, "/admin/channel", http.StatusSeeOther)
 		return
 	}
 
-	// after all validation procedures are complete, update reservation record in database
-	err = m.DB.UpdateReservation(reservation)
-	if err != nil {
-		m.App.Session.Put(r.Context(), "error", "#0018: cannot update reservation")
-		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
+	m.SyncChannel(r.Context())
+
+	m.App.Session.Put(r.Context(), "flash", "Changes have been pushed to the channel manager, see the sync log for the outcome")
+	http.Redirect(w, r, "/admin/channel", http.StatusSeeOther)
+}
+
+// AdminChannelResync forgets what has been pushed to the channel manager, then pushes every room's availability & rates in full, as
+// is needed should the channel manager have lost or been given other figures
+func (m *Repository) AdminChannelResync(w http.ResponseWriter, r *http.Request) {
+	if m.App.Channel.URL == "" {
+		m.App.Session.Put(r.Context(), "warning", "No channel manager is configured, so there is nothing to push to")
+		http.Redirect(w, r, "/admin/channel", http.StatusSeeOther)
 		return
 	}
 
-	year := r.Form.Get("y")
-	month := r.Form.Get("m")
+	err := m.DB.ResetChannelDays(r.Context())
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.Session.Put(r.Context(), "error", "#0102: cannot reset what has been pushed to the channel manager")
+		http.Redirect(w, r, "/admin/channel", http.StatusSeeOther)
+		return
+	}
 
-	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Reservation (id=%d) has been updated", reservation.ID))
+	m.SyncChannel(r.Context())
 
-	if (year == "") && (month == "") {
-		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-%s", src), http.StatusSeeOther)
-	} else {
-		http.Redirect(w, r, fmt.Sprintf("/admin/reservations-cal?y=%s&m=%s", year, month), http.StatusSeeOther)
-	}
+	m.App.Session.Put(r.Context(), "flash", "Everything has been pushed to the channel manager, see the sync log for the outcome")
+	http.Redirect(w, r, "/admin/channel", http.StatusSeeOther)
 }
@@ -13,6 +13,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/StratoNET/bnb-bookings/internal/bookingref"
 	"github.com/StratoNET/bnb-bookings/internal/database"
 	"github.com/StratoNET/bnb-bookings/internal/models"
 )
@@ -49,6 +50,8 @@ var theTests = []struct {
 	{"show reservation page", "/admin/reservations/new/1/page", "GET", http.StatusOK},
 	{"reservation id missing", "/admin/reservations/all/one/page", "GET", http.StatusOK},
 	{"non-existent reservation", "/admin/reservations/all/0/page", "GET", http.StatusOK},
+	{"room rates", "/admin/rates", "GET", http.StatusOK},
+	{"stay rules", "/admin/stay-rules", "GET", http.StatusOK},
 
 	// // all POST tests & use session
 	// {"POST-search-availability", "/search-availability", "POST", []postData{
@@ -157,8 +160,11 @@ func TestRepository_PostReservation(t *testing.T) {
 	reservation := models.Reservation{
 		RoomID: 2,
 		Room: models.Room{
-			ID:       2,
-			RoomName: "Major's Suite",
+			ID:          2,
+			RoomName:    "Major's Suite",
+			MaxAdults:   2,
+			MaxChildren: 2,
+			MaxInfants:  1,
 		},
 	}
 	// create a request body for reservation data to be posted
@@ -170,6 +176,7 @@ func TestRepository_PostReservation(t *testing.T) {
 	postedData.Add("last_name", "Soap")
 	postedData.Add("email", "joe@soap.bar")
 	postedData.Add("phone", "01234 567890")
+	postedData.Add("adults", "2")
 
 	// initialise request & context, incorporate & encode data in post body
 	req, _ := http.NewRequest("POST", "/make-reservation", strings.NewReader(postedData.Encode()))
@@ -233,6 +240,7 @@ func TestRepository_PostReservation(t *testing.T) {
 	postedData.Add("last_name", "Soap")
 	postedData.Add("email", "joe@soap.bar")
 	postedData.Add("phone", "01234 567890")
+	postedData.Add("adults", "2")
 
 	// initialise request & context, incorporate & encode data in post body
 	req, _ = http.NewRequest("POST", "/make-reservation", strings.NewReader(postedData.Encode()))
@@ -252,16 +260,16 @@ func TestRepository_PostReservation(t *testing.T) {
 		t.Errorf("PostReservation handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
 	}
 
-	// test for failure to INSERT ROOM RESERVATION
+	// test for PARTY TOO LARGE for the chosen room
 	// create a request body for reservation data to be posted
 	postedData = url.Values{}
 	postedData.Add("start_date", "01/01/2099")
 	postedData.Add("end_date", "02/01/2099")
-	// postedData.Add("room_id", "1") ... already known
 	postedData.Add("first_name", "Joe")
 	postedData.Add("last_name", "Soap")
 	postedData.Add("email", "joe@soap.bar")
 	postedData.Add("phone", "01234 567890")
+	postedData.Add("adults", "4") // room sleeps at most 2 adults
 
 	// initialise request & context, incorporate & encode data in post body
 	req, _ = http.NewRequest("POST", "/make-reservation", strings.NewReader(postedData.Encode()))
@@ -271,47 +279,18 @@ func TestRepository_PostReservation(t *testing.T) {
 	// session has now been incorporated, initialise 'fake' response writer
 	rr = httptest.NewRecorder()
 
-	reservation.RoomID = 99 //will fail to insert reservation, room number does not exist
-
 	session.Put(ctx, "reservation", reservation)
 
 	handler = http.HandlerFunc(Repo.PostReservation)
 
 	handler.ServeHTTP(rr, req)
 
-	if rr.Code != http.StatusSeeOther {
-		t.Errorf("PostReservation handler returned code: %d, expected code: %d", rr.Code, http.StatusSeeOther)
+	if rr.Code != http.StatusOK {
+		t.Errorf("PostReservation handler (PARTY TOO LARGE) returned code: %d, expected code: %d", rr.Code, http.StatusOK)
 	}
 
-	// test for failure to INSERT ROOM RESTRICTION
-	// create a request body for reservation data to be posted
-	postedData = url.Values{}
-	postedData.Add("start_date", "01/01/2099")
-	postedData.Add("end_date", "02/01/2099")
-	// postedData.Add("room_id", "1") ... already known
-	postedData.Add("first_name", "Joe")
-	postedData.Add("last_name", "Soap")
-	postedData.Add("email", "joe@soap.bar")
-	postedData.Add("phone", "01234 567890")
-
-	// initialise request & context, incorporate & encode data in post body
-	req, _ = http.NewRequest("POST", "/make-reservation", strings.NewReader(postedData.Encode()))
-	ctx = getCtx(req)
-	req = req.WithContext(ctx)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	// session has now been incorporated, initialise 'fake' response writer
-	rr = httptest.NewRecorder()
-
-	reservation.RoomID = 9999 //will fail to insert reservation, room number does not exist
-
-	session.Put(ctx, "reservation", reservation)
-
-	handler = http.HandlerFunc(Repo.PostReservation)
-
-	handler.ServeHTTP(rr, req)
-
-	if rr.Code != http.StatusSeeOther {
-		t.Errorf("PostReservation handler returned code: %d, expected code: %d", rr.Code, http.StatusSeeOther)
+	if !strings.Contains(rr.Body.String(), "can accommodate at most 2 adults") {
+		t.Error("PostReservation handler (PARTY TOO LARGE) did not report the room's occupancy limit")
 	}
 }
 
@@ -421,6 +400,64 @@ func TestRepository_PostAvailabilityModal(t *testing.T) {
 	if err != nil && !jr.Ok {
 		t.Error("PostAvailabilityModal did NOT find availability when there is !", err)
 	}
+
+	// 5. ROOM AVAILABLE BUT PARTY TOO LARGE
+	// create a request body for reservation data to be posted
+	postedData = url.Values{}
+	postedData.Add("start_date", "02/01/2099")
+	postedData.Add("end_date", "03/01/2099")
+	postedData.Add("room_id", "1")
+	postedData.Add("adults", "3")
+	postedData.Add("children", "2")
+	// initialise request & context, incorporate & encode data in post body
+	req, _ = http.NewRequest("POST", "/search-availability-modal", strings.NewReader(postedData.Encode()))
+	ctx = getCtx(req)
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// session has now been incorporated, initialise 'fake' response writer
+	rr = httptest.NewRecorder()
+
+	handler = http.HandlerFunc(Repo.PostAvailabilityModal)
+
+	handler.ServeHTTP(rr, req)
+
+	jr = jsonResponse{}
+	err = json.Unmarshal([]byte(rr.Body.Bytes()), &jr)
+	if err != nil {
+		t.Error("PostAvailabilityModal handler failed to parse JSON", err)
+	}
+
+	if jr.Ok || jr.Message == "" || jr.Adults != 3 || jr.Children != 2 {
+		t.Errorf("PostAvailabilityModal (PARTY TOO LARGE) gave ok %t, message %q, adults %d, children %d", jr.Ok, jr.Message, jr.Adults, jr.Children)
+	}
+
+	// 6. END DATE BEFORE START DATE
+	// create a request body for reservation data to be posted
+	postedData = url.Values{}
+	postedData.Add("start_date", "05/01/2099")
+	postedData.Add("end_date", "03/01/2099")
+	postedData.Add("room_id", "1")
+	// initialise request & context, incorporate & encode data in post body
+	req, _ = http.NewRequest("POST", "/search-availability-modal", strings.NewReader(postedData.Encode()))
+	ctx = getCtx(req)
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// session has now been incorporated, initialise 'fake' response writer
+	rr = httptest.NewRecorder()
+
+	handler = http.HandlerFunc(Repo.PostAvailabilityModal)
+
+	handler.ServeHTTP(rr, req)
+
+	jr = jsonResponse{}
+	err = json.Unmarshal([]byte(rr.Body.Bytes()), &jr)
+	if err != nil {
+		t.Error("PostAvailabilityModal handler failed to parse JSON", err)
+	}
+
+	if jr.Ok || jr.Message == "" {
+		t.Errorf("PostAvailabilityModal (END BEFORE START) gave ok %t & message %q", jr.Ok, jr.Message)
+	}
 }
 
 func TestNewRepository(t *testing.T) {
@@ -584,6 +621,51 @@ func TestRepository_PostAvailability(t *testing.T) {
 	if rr.Code != http.StatusSeeOther {
 		t.Errorf("PostAvailability handler (DATABASE QUERY FAILURE) returned code: %d, expected code: %d", rr.Code, http.StatusSeeOther)
 	}
+
+	// 7. test for NO ROOM LARGE ENOUGH for the party
+	// create a request body for availability data to be posted
+	postedData = url.Values{}
+	postedData.Add("start_date", "01/01/2089")
+	postedData.Add("end_date", "02/01/2089")
+	postedData.Add("adults", "5")
+
+	// initialise request & context, incorporate & encode data in post body
+	req, _ = http.NewRequest("POST", "/search-availability", strings.NewReader(postedData.Encode()))
+	ctx = getCtx(req)
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// session has now been incorporated, initialise 'fake' response writer
+	rr = httptest.NewRecorder()
+
+	handler = http.HandlerFunc(Repo.PostAvailability)
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusSeeOther {
+		t.Errorf("PostAvailability handler (NO ROOM LARGE ENOUGH) returned code: %d, expected code: %d", rr.Code, http.StatusSeeOther)
+	}
+
+	// 8. test for END DATE BEFORE START DATE
+	// create a request body for availability data to be posted
+	postedData = url.Values{}
+	postedData.Add("start_date", "05/01/2089")
+	postedData.Add("end_date", "02/01/2089")
+
+	// initialise request & context, incorporate & encode data in post body
+	req, _ = http.NewRequest("POST", "/search-availability", strings.NewReader(postedData.Encode()))
+	ctx = getCtx(req)
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// session has now been incorporated, initialise 'fake' response writer
+	rr = httptest.NewRecorder()
+
+	handler = http.HandlerFunc(Repo.PostAvailability)
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusSeeOther {
+		t.Errorf("PostAvailability handler (END BEFORE START) returned code: %d, expected code: %d", rr.Code, http.StatusSeeOther)
+	}
 }
 
 func TestRepository_ReservationSummary(t *testing.T) {
@@ -619,6 +701,46 @@ func TestRepository_ReservationSummary(t *testing.T) {
 		t.Errorf("ReservationSummary handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
 	}
 
+	// reservation summary including a quote, which should show the nightly breakdown
+	req, _ = http.NewRequest("GET", "/reservation-summary", nil)
+	ctx = getCtx(req)
+	req = req.WithContext(ctx)
+	rr = httptest.NewRecorder()
+
+	reservation.TotalPrice = 24000
+	session.Put(ctx, "reservation", reservation)
+	session.Put(ctx, "quote", models.Quote{
+		RoomID: 2,
+		Nights: []models.NightlyPrice{
+			{Date: sd, RoomRate: 12000, Amount: 12000},
+			{Date: sd.AddDate(0, 0, 1), SeasonName: "High Season", RoomRate: 12000, Amount: 12000},
+		},
+		Total: 24000,
+	})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("ReservationSummary handler (WITH QUOTE) returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+	if !strings.Contains(rr.Body.String(), "£240.00") || !strings.Contains(rr.Body.String(), "High Season") {
+		t.Error("ReservationSummary handler (WITH QUOTE) did not display the quote")
+	}
+
+	// reservation summary with a confirmation reference, which should link to the guest's manage booking page
+	req, _ = http.NewRequest("GET", "/reservation-summary", nil)
+	ctx = getCtx(req)
+	req = req.WithContext(ctx)
+	rr = httptest.NewRecorder()
+
+	reservation.ConfirmationRef = "K7QX2MZP4D"
+	session.Put(ctx, "reservation", reservation)
+
+	handler.ServeHTTP(rr, req)
+
+	if !strings.Contains(rr.Body.String(), "/my-booking/K7QX2MZP4D?token="+bookingref.Sign([]byte("test-booking-key"), "K7QX2MZP4D")) {
+		t.Error("ReservationSummary handler (WITH CONFIRMATION REF) did not link to manage booking page")
+	}
 }
 
 func TestRepository_ChooseRoom(t *testing.T) {
@@ -1109,8 +1231,11 @@ func TestRepository_AdminPostReservationsCalendar(t *testing.T) {
 			rm[lastOfMonth.Format("2-01-2006")] = v.reservations
 		}
 
-		session.Put(ctx, "block_map_1", bm)
-		session.Put(ctx, "reservation_map_1", rm)
+		// the calendar was displayed with these maps for each room
+		for _, id := range []int{1, 2} {
+			session.Put(ctx, fmt.Sprintf("blocked_map_%d", id), bm)
+			session.Put(ctx, fmt.Sprintf("reservations_map_%d", id), rm)
+		}
 
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 		rr := httptest.NewRecorder()
@@ -1126,34 +1251,86 @@ func TestRepository_AdminPostReservationsCalendar(t *testing.T) {
 
 // ========================================================================================================================================
 
-var adminReservationProcessTests = []struct {
-	name, queryParams, expectedLocation string
-	expectedStatusCode                  int
+var adminReservationStatusTests = []struct {
+	name, url, expectedLocation string
+	expectedStatusCode          int
 }{
 	{
-		name:               "reservation-processed",
-		queryParams:        "",
-		expectedLocation:   "",
+		name:               "confirmed",
+		url:                "/admin/reservation-status/new/1/confirmed/page",
+		expectedLocation:   "/admin/reservations-new",
 		expectedStatusCode: http.StatusSeeOther,
 	},
 	{
-		name:               "reservation-processed-cal",
-		queryParams:        "?y=2022&m=10",
-		expectedLocation:   "",
+		name:               "confirmed-cal",
+		url:                "/admin/reservation-status/cal/1/confirmed/page?y=2022&m=10",
+		expectedLocation:   "/admin/reservations-cal?y=2022&m=10",
+		expectedStatusCode: http.StatusSeeOther,
+	},
+	{
+		name:               "transition-not-allowed",
+		url:                "/admin/reservation-status/all/1/checked-out/page",
+		expectedLocation:   "/admin/reservations-all",
+		expectedStatusCode: http.StatusSeeOther,
+	},
+	{
+		name:               "unknown-status",
+		url:                "/admin/reservation-status/all/1/processed/page",
+		expectedLocation:   "/admin/reservations-all",
+		expectedStatusCode: http.StatusSeeOther,
+	},
+	{
+		name:               "non-existent-reservation",
+		url:                "/admin/reservation-status/all/0/confirmed/page",
+		expectedLocation:   "/admin/reservations-all",
 		expectedStatusCode: http.StatusSeeOther,
 	},
 }
 
-func TestRepository_AdminReservationProcess(t *testing.T) {
-	for _, v := range adminReservationProcessTests {
-		req, _ := http.NewRequest("GET", fmt.Sprintf("/admin/reservation-processed/cal/1/page%s", v.queryParams), nil)
-		ctx := getCtx(req)
-		req = req.WithContext(ctx)
+func TestRepository_AdminReservationStatus(t *testing.T) {
+	routes := getRoutes()
+
+	for _, v := range adminReservationStatusTests {
+		req, _ := http.NewRequest("GET", v.url, nil)
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(Repo.AdminReservationProcess)
-		handler.ServeHTTP(rr, req)
-		if rr.Code != http.StatusSeeOther {
-			t.Errorf("AdminReservationProcess handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+
+		// served through the router so that the {id} & {status} URL parameters are available to the handler
+		routes.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("AdminReservationStatus handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+		}
+
+		actualLoc, _ := rr.Result().Location()
+		if actualLoc.String() != v.expectedLocation {
+			t.Errorf("AdminReservationStatus handler (%s) returned location: %s, expected location: %s", v.name, actualLoc.String(), v.expectedLocation)
+		}
+	}
+}
+
+var adminReservationsAllTests = []struct {
+	name               string
+	url                string
+	expectedStatusCode int
+}{
+	{"all", "/admin/reservations-all", http.StatusOK},
+	{"filtered", "/admin/reservations-all?status=checked-in", http.StatusOK},
+	{"unknown-status", "/admin/reservations-all?status=processed", http.StatusSeeOther},
+	{"calendar-filtered", "/admin/reservations-cal?status=confirmed", http.StatusOK},
+	{"calendar-unknown-status", "/admin/reservations-cal?status=processed", http.StatusSeeOther},
+}
+
+func TestRepository_AdminReservationsAll(t *testing.T) {
+	routes := getRoutes()
+
+	for _, v := range adminReservationsAllTests {
+		req, _ := http.NewRequest("GET", v.url, nil)
+		rr := httptest.NewRecorder()
+
+		routes.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("%s returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
 		}
 	}
 }
@@ -1194,11 +1371,1062 @@ func TestRepository_AdminReservationDelete(t *testing.T) {
 
 // ========================================================================================================================================
 
-// getCtx creates a context for use in TestRepository_Reservation() request
-func getCtx(r *http.Request) context.Context {
-	ctx, err := session.Load(r.Context(), r.Header.Get("X-Session"))
-	if err != nil {
-		log.Println(err)
+func TestRepository_AdminTrash(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/admin/trash", nil)
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(Repo.AdminTrash)
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("AdminTrash handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+
+	for _, expected := range []string{`href="/admin/reservation-restored/1"`, `href="/admin/block-restored/3"`, "removed after 30 days"} {
+		if !strings.Contains(rr.Body.String(), expected) {
+			t.Errorf("AdminTrash handler did not return expected HTML: %s", expected)
+		}
+	}
+}
+
+var adminRestoreTests = []struct {
+	name               string
+	url                string
+	expectedStatusCode int
+	expectedLocation   string
+}{
+	{"reservation-restored", "/admin/reservation-restored/1", http.StatusSeeOther, "/admin/trash"},
+	{"block-restored", "/admin/block-restored/3", http.StatusSeeOther, "/admin/trash"},
+}
+
+func TestRepository_AdminRestore(t *testing.T) {
+	routes := getRoutes()
+
+	for _, v := range adminRestoreTests {
+		req, _ := http.NewRequest("GET", v.url, nil)
+		rr := httptest.NewRecorder()
+
+		// served through the router so that the {id} URL parameter is available to the handler
+		routes.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("%s returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+		}
+
+		actualLoc, _ := rr.Result().Location()
+		if actualLoc.String() != v.expectedLocation {
+			t.Errorf("%s returned location: %s, expected location: %s", v.name, actualLoc.String(), v.expectedLocation)
+		}
+	}
+}
+
+// ========================================================================================================================================
+
+var adminPostSeasonalRateTests = []struct {
+	name, expectedLocation, expectedHTML string
+	expectedStatusCode                   int
+	postedData                           url.Values
+}{
+	{
+		name:               "valid-season",
+		expectedLocation:   "/admin/rates",
+		expectedStatusCode: http.StatusSeeOther,
+		postedData: url.Values{
+			"room_id":      {"1"},
+			"season_name":  {"Christmas"},
+			"start_date":   {"20/12/2026"},
+			"end_date":     {"02/01/2027"},
+			"nightly_rate": {"120.00"},
+			"weekend_rate": {"135"},
+		},
+	},
+	{
+		name:               "end-before-start",
+		expectedHTML:       `action="/admin/seasonal-rates"`,
+		expectedStatusCode: http.StatusOK,
+		postedData: url.Values{
+			"room_id":      {"1"},
+			"season_name":  {"Christmas"},
+			"start_date":   {"02/01/2027"},
+			"end_date":     {"20/12/2026"},
+			"nightly_rate": {"120.00"},
+		},
+	},
+	{
+		name:               "invalid-rate",
+		expectedHTML:       `action="/admin/seasonal-rates"`,
+		expectedStatusCode: http.StatusOK,
+		postedData: url.Values{
+			"room_id":      {"1"},
+			"season_name":  {"Christmas"},
+			"start_date":   {"20/12/2026"},
+			"end_date":     {"02/01/2027"},
+			"nightly_rate": {"lots"},
+		},
+	},
+}
+
+func TestRepository_AdminPostSeasonalRate(t *testing.T) {
+	for _, v := range adminPostSeasonalRateTests {
+		req, _ := http.NewRequest("POST", "/admin/seasonal-rates", strings.NewReader(v.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostSeasonalRate)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("AdminPostSeasonalRate handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+		}
+
+		if v.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != v.expectedLocation {
+				t.Errorf("AdminPostSeasonalRate handler (%s) returned location: %s, expected location: %s", v.name, actualLoc.String(), v.expectedLocation)
+			}
+		}
+
+		if v.expectedHTML != "" && !strings.Contains(rr.Body.String(), v.expectedHTML) {
+			t.Errorf("AdminPostSeasonalRate handler (%s) did not return expected HTML: %s", v.name, v.expectedHTML)
+		}
+	}
+}
+
+func TestRepository_AdminPostRoomRates(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/admin/rates", strings.NewReader(url.Values{}.Encode()))
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	handler := http.HandlerFunc(Repo.AdminPostRoomRates)
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusSeeOther {
+		t.Errorf("AdminPostRoomRates handler returned code: %d, expected code: %d", rr.Code, http.StatusSeeOther)
+	}
+}
+
+func TestRepository_AdminSeasonalRateDelete(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/admin/seasonal-rate-deleted/1", nil)
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+	rr := httptest.NewRecorder()
+
+	handler := http.HandlerFunc(Repo.AdminSeasonalRateDelete)
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusSeeOther {
+		t.Errorf("AdminSeasonalRateDelete handler returned code: %d, expected code: %d", rr.Code, http.StatusSeeOther)
+	}
+}
+
+// ========================================================================================================================================
+
+var adminPostStayRuleTests = []struct {
+	name, expectedLocation, expectedHTML string
+	expectedStatusCode                   int
+	postedData                           url.Values
+}{
+	{
+		name:               "valid-rule",
+		expectedLocation:   "/admin/stay-rules",
+		expectedStatusCode: http.StatusSeeOther,
+		postedData: url.Values{
+			"room_id":    {"1"},
+			"rule_name":  {"Bank Holiday Weekend"},
+			"start_date": {"28/08/2026"},
+			"end_date":   {"31/08/2026"},
+			"min_nights": {"3"},
+			"max_nights": {"14"},
+		},
+	},
+	{
+		name:               "no-maximum",
+		expectedLocation:   "/admin/stay-rules",
+		expectedStatusCode: http.StatusSeeOther,
+		postedData: url.Values{
+			"room_id":    {"1"},
+			"rule_name":  {"Christmas"},
+			"start_date": {"20/12/2026"},
+			"end_date":   {"02/01/2027"},
+			"min_nights": {"4"},
+		},
+	},
+	{
+		name:               "end-before-start",
+		expectedHTML:       `action="/admin/stay-rules"`,
+		expectedStatusCode: http.StatusOK,
+		postedData: url.Values{
+			"room_id":    {"1"},
+			"rule_name":  {"Christmas"},
+			"start_date": {"02/01/2027"},
+			"end_date":   {"20/12/2026"},
+			"min_nights": {"4"},
+		},
+	},
+	{
+		name:               "maximum-below-minimum",
+		expectedHTML:       "maximum nights cannot be fewer than minimum nights",
+		expectedStatusCode: http.StatusOK,
+		postedData: url.Values{
+			"room_id":    {"1"},
+			"rule_name":  {"Christmas"},
+			"start_date": {"20/12/2026"},
+			"end_date":   {"02/01/2027"},
+			"min_nights": {"4"},
+			"max_nights": {"2"},
+		},
+	},
+	{
+		name:               "invalid-minimum",
+		expectedHTML:       `action="/admin/stay-rules"`,
+		expectedStatusCode: http.StatusOK,
+		postedData: url.Values{
+			"room_id":    {"1"},
+			"rule_name":  {"Christmas"},
+			"start_date": {"20/12/2026"},
+			"end_date":   {"02/01/2027"},
+			"min_nights": {"0"},
+		},
+	},
+}
+
+func TestRepository_AdminPostStayRule(t *testing.T) {
+	for _, v := range adminPostStayRuleTests {
+		req, _ := http.NewRequest("POST", "/admin/stay-rules", strings.NewReader(v.postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostStayRule)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("AdminPostStayRule handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+		}
+
+		if v.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != v.expectedLocation {
+				t.Errorf("AdminPostStayRule handler (%s) returned location: %s, expected location: %s", v.name, actualLoc.String(), v.expectedLocation)
+			}
+		}
+
+		if v.expectedHTML != "" && !strings.Contains(rr.Body.String(), v.expectedHTML) {
+			t.Errorf("AdminPostStayRule handler (%s) did not return expected HTML: %s", v.name, v.expectedHTML)
+		}
+	}
+}
+
+func TestRepository_AdminStayRuleDelete(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/admin/stay-rule-deleted/1", nil)
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+	rr := httptest.NewRecorder()
+
+	handler := http.HandlerFunc(Repo.AdminStayRuleDelete)
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusSeeOther {
+		t.Errorf("AdminStayRuleDelete handler returned code: %d, expected code: %d", rr.Code, http.StatusSeeOther)
+	}
+}
+
+// ========================================================================================================================================
+
+var guestBookingTests = []struct {
+	name               string
+	url                string
+	expectedStatusCode int
+	expectedLocation   string
+	expectedHTML       string
+}{
+	{"valid-link", "/my-booking/K7QX2MZP4D?token=" + bookingref.Sign([]byte("test-booking-key"), "K7QX2MZP4D"), http.StatusOK, "", "K7QX2MZP4D"},
+	{"cancellable", "/my-booking/K7QX2MZP4D?token=" + bookingref.Sign([]byte("test-booking-key"), "K7QX2MZP4D"), http.StatusOK, "", `action="/my-booking/K7QX2MZP4D/cancel"`},
+	{"invalid-token", "/my-booking/K7QX2MZP4D?token=forged", http.StatusSeeOther, "/", ""},
+	{"missing-token", "/my-booking/K7QX2MZP4D", http.StatusSeeOther, "/", ""},
+}
+
+func TestRepository_GuestBooking(t *testing.T) {
+	routes := getRoutes()
+
+	for _, v := range guestBookingTests {
+		req, _ := http.NewRequest("GET", v.url, nil)
+		rr := httptest.NewRecorder()
+
+		// served through the router so that the {ref} URL parameter is available to the handler
+		routes.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("GuestBooking handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+		}
+
+		if v.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != v.expectedLocation {
+				t.Errorf("GuestBooking handler (%s) returned location: %s, expected location: %s", v.name, actualLoc.String(), v.expectedLocation)
+			}
+		}
+
+		if v.expectedHTML != "" && !strings.Contains(rr.Body.String(), v.expectedHTML) {
+			t.Errorf("GuestBooking handler (%s) did not return expected HTML: %s", v.name, v.expectedHTML)
+		}
+	}
+}
+
+var postGuestBookingTests = []struct {
+	name               string
+	url                string
+	postedData         url.Values
+	expectedStatusCode int
+	expectedLocation   string
+}{
+	{
+		name: "valid-update",
+		url:  "/my-booking/K7QX2MZP4D",
+		postedData: url.Values{
+			"token":      {bookingref.Sign([]byte("test-booking-key"), "K7QX2MZP4D")},
+			"first_name": {"Joe"},
+			"last_name":  {"Soap"},
+			"email":      {"joe@soap.bar"},
+			"phone":      {"01234 567890"},
+		},
+		expectedStatusCode: http.StatusSeeOther,
+		expectedLocation:   "http://localhost:8080/my-booking/K7QX2MZP4D?token=" + bookingref.Sign([]byte("test-booking-key"), "K7QX2MZP4D"),
+	},
+	{
+		name: "invalid-form",
+		url:  "/my-booking/K7QX2MZP4D",
+		postedData: url.Values{
+			"token":      {bookingref.Sign([]byte("test-booking-key"), "K7QX2MZP4D")},
+			"first_name": {"J"},
+			"last_name":  {"Soap"},
+			"email":      {"joe"},
+			"phone":      {"01234 567890"},
+		},
+		expectedStatusCode: http.StatusOK,
+	},
+	{
+		name: "cannot-update",
+		url:  "/my-booking/K7QX2MZP4D",
+		postedData: url.Values{
+			"token":      {bookingref.Sign([]byte("test-booking-key"), "K7QX2MZP4D")},
+			"first_name": {"Bonzo"},
+			"last_name":  {"Soap"},
+			"email":      {"joe@soap.bar"},
+			"phone":      {"01234 567890"},
+		},
+		expectedStatusCode: http.StatusSeeOther,
+		expectedLocation:   "http://localhost:8080/my-booking/K7QX2MZP4D?token=" + bookingref.Sign([]byte("test-booking-key"), "K7QX2MZP4D"),
+	},
+	{
+		name: "invalid-token",
+		url:  "/my-booking/K7QX2MZP4D",
+		postedData: url.Values{
+			"token":      {"forged"},
+			"first_name": {"Joe"},
+			"last_name":  {"Soap"},
+			"email":      {"joe@soap.bar"},
+			"phone":      {"01234 567890"},
+		},
+		expectedStatusCode: http.StatusSeeOther,
+		expectedLocation:   "/",
+	},
+	{
+		name:               "cancel",
+		url:                "/my-booking/K7QX2MZP4D/cancel",
+		postedData:         url.Values{"token": {bookingref.Sign([]byte("test-booking-key"), "K7QX2MZP4D")}},
+		expectedStatusCode: http.StatusSeeOther,
+		expectedLocation:   "/",
+	},
+	{
+		name:               "cancel-invalid-token",
+		url:                "/my-booking/K7QX2MZP4D/cancel",
+		postedData:         url.Values{"token": {"forged"}},
+		expectedStatusCode: http.StatusSeeOther,
+		expectedLocation:   "/",
+	},
+}
+
+func TestRepository_PostGuestBooking(t *testing.T) {
+	routes := getRoutes()
+
+	for _, v := range postGuestBookingTests {
+		req, _ := http.NewRequest("POST", v.url, strings.NewReader(v.postedData.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		// served through the router so that the {ref} URL parameter is available to the handler
+		routes.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("%s returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+		}
+
+		if v.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != v.expectedLocation {
+				t.Errorf("%s returned location: %s, expected location: %s", v.name, actualLoc.String(), v.expectedLocation)
+			}
+		}
+	}
+}
+
+// TestMemoryRepository_GuestBooking checks what a guest may do with their own booking depends on the reservation stored: one arriving
+// too soon or already checked in cannot be cancelled online, & one never made is not found
+func TestMemoryRepository_GuestBooking(t *testing.T) {
+	db, repo := newMemoryRepository()
+	routes := routesFor(repo)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	addMemoryReservation(t, db, models.Reservation{ConfirmationRef: "ARRIVE7QX2", RoomID: 1, StartDate: today.AddDate(0, 0, 1), EndDate: today.AddDate(0, 0, 3)})
+	checkedIn := addMemoryReservation(t, db, models.Reservation{ConfirmationRef: "CHECK7QX2M", RoomID: 2, StartDate: today.AddDate(0, 1, 0), EndDate: today.AddDate(0, 1, 2)})
+	for _, to := range []models.ReservationStatus{models.StatusConfirmed, models.StatusCheckedIn} {
+		err := db.UpdateReservationStatus(context.Background(), checkedIn.ID, checkedIn.Status, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkedIn.Status = to
+	}
+	cancellable := addMemoryReservation(t, db, models.Reservation{ConfirmationRef: "K7QX2MZP4D", RoomID: 1, StartDate: today.AddDate(0, 1, 0), EndDate: today.AddDate(0, 1, 2)})
+
+	bookingURL := func(ref string) string {
+		return "http://localhost:8080/my-booking/" + ref + "?token=" + bookingref.Sign([]byte("test-booking-key"), ref)
+	}
+	get := func(ref string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/my-booking/"+ref+"?token="+bookingref.Sign([]byte("test-booking-key"), ref), nil)
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+		return rr
+	}
+	cancel := func(ref string) *httptest.ResponseRecorder {
+		postedData := url.Values{"token": {bookingref.Sign([]byte("test-booking-key"), ref)}}
+		req, _ := http.NewRequest("POST", "/my-booking/"+ref+"/cancel", strings.NewReader(postedData.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+		return rr
+	}
+	status := func(id int) models.ReservationStatus {
+		stored, err := db.GetReservationByID(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stored.Status
+	}
+
+	for _, ref := range []string{"ARRIVE7QX2", "CHECK7QX2M"} {
+		rr := get(ref)
+		if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "can no longer be cancelled online") {
+			t.Errorf("GuestBooking handler (%s) returned code: %d, expected code: %d saying it can no longer be cancelled", ref, rr.Code, http.StatusOK)
+		}
+
+		rr = cancel(ref)
+		if loc := rr.Header().Get("Location"); rr.Code != http.StatusSeeOther || loc != bookingURL(ref) {
+			t.Errorf("GuestBookingCancel handler (%s) returned code: %d (%s), expected code: %d (%s)", ref, rr.Code, loc, http.StatusSeeOther, bookingURL(ref))
+		}
+	}
+	if s := status(checkedIn.ID); s != models.StatusCheckedIn {
+		t.Errorf("expected the checked in reservation to be left %s, got %s", models.StatusCheckedIn, s)
+	}
+
+	rr := get("UNKNOWN7QX")
+	if loc := rr.Header().Get("Location"); rr.Code != http.StatusSeeOther || loc != "/" {
+		t.Errorf("GuestBooking handler (unknown-ref) returned code: %d (%s), expected code: %d (/)", rr.Code, loc, http.StatusSeeOther)
+	}
+
+	// a cancellation the database cannot make leaves the guest on their booking, still pending
+	db.FailOn("UpdateReservationStatus", errMemoryDB)
+	rr = cancel("K7QX2MZP4D")
+	if loc := rr.Header().Get("Location"); rr.Code != http.StatusSeeOther || loc != bookingURL("K7QX2MZP4D") {
+		t.Errorf("GuestBookingCancel handler (cannot-cancel) returned code: %d (%s), expected code: %d (%s)", rr.Code, loc, http.StatusSeeOther, bookingURL("K7QX2MZP4D"))
+	}
+	if s := status(cancellable.ID); s != models.StatusPending {
+		t.Errorf("expected the reservation to be left %s, got %s", models.StatusPending, s)
+	}
+
+	db.FailOn("UpdateReservationStatus", nil)
+	rr = cancel("K7QX2MZP4D")
+	if loc := rr.Header().Get("Location"); rr.Code != http.StatusSeeOther || loc != "/" {
+		t.Errorf("GuestBookingCancel handler returned code: %d (%s), expected code: %d (/)", rr.Code, loc, http.StatusSeeOther)
+	}
+	if s := status(cancellable.ID); s != models.StatusCancelled {
+		t.Errorf("expected the reservation to be %s, got %s", models.StatusCancelled, s)
+	}
+}
+
+// ========================================================================================================================================
+
+func TestRepository_Waitlist(t *testing.T) {
+	entry := models.WaitlistEntry{
+		StartDate: time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2099, 1, 2, 0, 0, 0, 0, time.UTC),
+		Adults:    2,
+	}
+
+	req, _ := http.NewRequest("GET", "/waitlist", nil)
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+	session.Put(ctx, "waitlist", entry)
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(Repo.Waitlist)
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Waitlist handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+	if !strings.Contains(rr.Body.String(), "Thursday 01 January 2099") {
+		t.Error("Waitlist handler did not show the requested dates")
+	}
+
+	// no dates in session
+	req, _ = http.NewRequest("GET", "/waitlist", nil)
+	ctx = getCtx(req)
+	req = req.WithContext(ctx)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusSeeOther {
+		t.Errorf("Waitlist handler (no session) returned code: %d, expected code: %d", rr.Code, http.StatusSeeOther)
+	}
+}
+
+var postWaitlistTests = []struct {
+	name               string
+	firstName          string
+	email              string
+	inSession          bool
+	expectedStatusCode int
+	expectedLocation   string
+}{
+	{"valid", "Joe", "joe@soap.bar", true, http.StatusSeeOther, "/"},
+	{"invalid-form", "J", "joe", true, http.StatusOK, ""},
+	{"no-session", "Joe", "joe@soap.bar", false, http.StatusSeeOther, "/search-availability"},
+}
+
+func TestRepository_PostWaitlist(t *testing.T) {
+	for _, v := range postWaitlistTests {
+		postedData := url.Values{
+			"first_name": {v.firstName},
+			"last_name":  {"Soap"},
+			"email":      {v.email},
+			"phone":      {"01234 567890"},
+		}
+		req, _ := http.NewRequest("POST", "/waitlist", strings.NewReader(postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if v.inSession {
+			session.Put(ctx, "waitlist", models.WaitlistEntry{
+				StartDate: time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC),
+				EndDate:   time.Date(2099, 1, 2, 0, 0, 0, 0, time.UTC),
+				Adults:    2,
+			})
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(Repo.PostWaitlist)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("%s returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+		}
+
+		if v.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != v.expectedLocation {
+				t.Errorf("%s returned location: %s, expected location: %s", v.name, actualLoc.String(), v.expectedLocation)
+			}
+		}
+	}
+}
+
+// TestMemoryRepository_PostWaitlist checks a guest the waitlist cannot take is told so, rather than that they are on it
+func TestMemoryRepository_PostWaitlist(t *testing.T) {
+	db, repo := newMemoryRepository()
+	db.FailOn("InsertWaitlistEntry", errMemoryDB)
+
+	postedData := url.Values{
+		"first_name": {"Joe"},
+		"last_name":  {"Soap"},
+		"email":      {"joe@soap.bar"},
+		"phone":      {"01234 567890"},
+	}
+	req, _ := http.NewRequest("POST", "/waitlist", strings.NewReader(postedData.Encode()))
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	session.Put(ctx, "waitlist", models.WaitlistEntry{
+		StartDate: time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2099, 1, 2, 0, 0, 0, 0, time.UTC),
+		Adults:    2,
+	})
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(repo.PostWaitlist).ServeHTTP(rr, req)
+
+	if loc := rr.Header().Get("Location"); rr.Code != http.StatusSeeOther || loc != "/" {
+		t.Errorf("PostWaitlist handler (cannot-insert) returned code: %d (%s), expected code: %d (/)", rr.Code, loc, http.StatusSeeOther)
+	}
+	if session.Exists(ctx, "flash") || !session.Exists(ctx, "error") {
+		t.Errorf("PostWaitlist handler (cannot-insert) told the guest %q, expected an error", session.GetString(ctx, "flash"))
+	}
+}
+
+func TestRepository_AdminWaitlist(t *testing.T) {
+	routes := getRoutes()
+
+	req, _ := http.NewRequest("GET", "/admin/waitlist", nil)
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("AdminWaitlist handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+	if !strings.Contains(rr.Body.String(), "joe@soap.bar") {
+		t.Error("AdminWaitlist handler did not list the waiting guest")
+	}
+
+	req, _ = http.NewRequest("GET", "/admin/waitlist-deleted/1", nil)
+	rr = httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	actualLoc, _ := rr.Result().Location()
+	if rr.Code != http.StatusSeeOther || actualLoc.String() != "/admin/waitlist" {
+		t.Errorf("AdminWaitlistDelete handler returned code: %d & location: %s, expected redirect to /admin/waitlist", rr.Code, actualLoc.String())
+	}
+}
+
+var postChooseRoomsTests = []struct {
+	name             string
+	roomIDs          []string
+	inSession        bool
+	expectedLocation string
+}{
+	{"two-rooms", []string{"1", "2"}, true, "/make-group-reservation"},
+	{"room-chosen-twice", []string{"1", "1"}, true, "/choose-room/1"},
+	{"no-rooms", nil, true, "/search-availability"},
+	{"missing-reservation", []string{"1", "2"}, false, "/"},
+	{"non-existent-room", []string{"1", "3"}, true, "/"},
+}
+
+func TestRepository_PostChooseRooms(t *testing.T) {
+	for _, v := range postChooseRoomsTests {
+		postedData := url.Values{"room_id": v.roomIDs}
+		req, _ := http.NewRequest("POST", "/choose-rooms", strings.NewReader(postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if v.inSession {
+			session.Put(ctx, "reservation", models.Reservation{
+				StartDate: time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC),
+				EndDate:   time.Date(2099, 1, 3, 0, 0, 0, 0, time.UTC),
+				Adults:    2,
+			})
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(Repo.PostChooseRooms)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusSeeOther {
+			t.Errorf("PostChooseRooms handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, http.StatusSeeOther)
+		}
+
+		actualLoc, _ := rr.Result().Location()
+		if actualLoc.String() != v.expectedLocation {
+			t.Errorf("PostChooseRooms handler (%s) returned location: %s, expected location: %s", v.name, actualLoc.String(), v.expectedLocation)
+		}
+
+		if v.expectedLocation == "/make-group-reservation" {
+			group, ok := session.Get(ctx, "group").(models.BookingGroup)
+			if !ok || len(group.Reservations) != 2 {
+				t.Errorf("PostChooseRooms handler (%s) did not put both rooms of the group into the session", v.name)
+			}
+		}
+	}
+}
+
+// testGroup returns a booking group of the given rooms, as it would be in the session once the rooms have been chosen
+func testGroup(roomIDs ...int) models.BookingGroup {
+	group := models.BookingGroup{
+		StartDate: time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2099, 1, 3, 0, 0, 0, 0, time.UTC),
+	}
+	for _, id := range roomIDs {
+		group.Reservations = append(group.Reservations, models.Reservation{
+			RoomID:    id,
+			StartDate: group.StartDate,
+			EndDate:   group.EndDate,
+			Adults:    2,
+			Room: models.Room{
+				ID:          id,
+				RoomName:    fmt.Sprintf("Room %d", id),
+				MaxAdults:   2,
+				MaxChildren: 2,
+				MaxInfants:  1,
+			},
+		})
+	}
+	return group
+}
+
+func TestRepository_GroupReservation(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/make-group-reservation", nil)
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+	session.Put(ctx, "group", testGroup(1, 2))
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(Repo.GroupReservation)
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("GroupReservation handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+	if !strings.Contains(rr.Body.String(), `name='adults_2'`) {
+		t.Error("GroupReservation handler did not ask for the party staying in each room")
+	}
+
+	// group missing from session
+	req, _ = http.NewRequest("GET", "/make-group-reservation", nil)
+	ctx = getCtx(req)
+	req = req.WithContext(ctx)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusSeeOther {
+		t.Errorf("GroupReservation handler (MISSING GROUP) returned code: %d, expected code: %d", rr.Code, http.StatusSeeOther)
+	}
+}
+
+var postGroupReservationTests = []struct {
+	name               string
+	roomIDs            []int
+	firstName          string
+	adults             string
+	expectedStatusCode int
+	expectedLocation   string
+}{
+	{"valid", []int{1, 2}, "Joe", "2", http.StatusSeeOther, "/group-reservation-summary"},
+	{"missing-group", nil, "Joe", "2", http.StatusSeeOther, "/"},
+	{"invalid-lead-guest", []int{1, 2}, "J", "2", http.StatusOK, ""},
+	{"room-over-capacity", []int{1, 2}, "Joe", "5", http.StatusOK, ""},
+}
+
+func TestRepository_PostGroupReservation(t *testing.T) {
+	for _, v := range postGroupReservationTests {
+		postedData := url.Values{
+			"first_name": {v.firstName},
+			"last_name":  {"Soap"},
+			"email":      {"joe@soap.bar"},
+			"phone":      {"01234 567890"},
+		}
+		for _, id := range v.roomIDs {
+			postedData.Set(fmt.Sprintf("adults_%d", id), v.adults)
+		}
+		req, _ := http.NewRequest("POST", "/make-group-reservation", strings.NewReader(postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if v.roomIDs != nil {
+			session.Put(ctx, "group", testGroup(v.roomIDs...))
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(Repo.PostGroupReservation)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("PostGroupReservation handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+		}
+
+		if v.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != v.expectedLocation {
+				t.Errorf("PostGroupReservation handler (%s) returned location: %s, expected location: %s", v.name, actualLoc.String(), v.expectedLocation)
+			}
+		}
+
+		if v.name == "valid" {
+			group, _ := session.Get(ctx, "group").(models.BookingGroup)
+			if group.ConfirmationRef == "" || group.TotalPrice() == 0 {
+				t.Errorf("PostGroupReservation handler (%s) did not confirm & price the group", v.name)
+			}
+			for _, rsvn := range group.Reservations {
+				if rsvn.FirstName != "Joe" || rsvn.ConfirmationRef == "" {
+					t.Errorf("PostGroupReservation handler (%s) did not book room %d under the lead guest", v.name, rsvn.RoomID)
+				}
+			}
+		}
+	}
+}
+
+// TestMemoryRepository_PostGroupReservation checks a group is booked wholly or not at all, being turned away should one of its rooms be
+// taken meanwhile, or the database fail
+func TestMemoryRepository_PostGroupReservation(t *testing.T) {
+	post := func(repo *Repository) *httptest.ResponseRecorder {
+		postedData := url.Values{
+			"first_name": {"Joe"},
+			"last_name":  {"Soap"},
+			"email":      {"joe@soap.bar"},
+			"phone":      {"01234 567890"},
+			"adults_1":   {"2"},
+			"adults_2":   {"2"},
+		}
+		req, _ := http.NewRequest("POST", "/make-group-reservation", strings.NewReader(postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		session.Put(ctx, "group", testGroup(1, 2))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(repo.PostGroupReservation).ServeHTTP(rr, req)
+		return rr
+	}
+
+	// the Major's Suite is reserved by another guest before the group is booked
+	db, repo := newMemoryRepository()
+	addMemoryReservation(t, db, models.Reservation{RoomID: 2, StartDate: time.Date(2099, 1, 2, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2099, 1, 4, 0, 0, 0, 0, time.UTC)})
+
+	rr := post(repo)
+	if loc := rr.Header().Get("Location"); rr.Code != http.StatusSeeOther || loc != "/search-availability" {
+		t.Errorf("PostGroupReservation handler (room-taken) returned code: %d (%s), expected code: %d (/search-availability)", rr.Code, loc, http.StatusSeeOther)
+	}
+	groups, err := db.GetAllBookingGroups(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reservations, err := db.GetAllReservations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 0 || len(reservations) != 1 {
+		t.Errorf("expected no group to be stored, got %d groups & %d reservations", len(groups), len(reservations))
+	}
+
+	db, repo = newMemoryRepository()
+	db.FailOn("CreateBookingGroup", errMemoryDB)
+
+	rr = post(repo)
+	if loc := rr.Header().Get("Location"); rr.Code != http.StatusSeeOther || loc != "/" {
+		t.Errorf("PostGroupReservation handler (insert-fails) returned code: %d (%s), expected code: %d (/)", rr.Code, loc, http.StatusSeeOther)
+	}
+}
+
+func TestRepository_GroupReservationSummary(t *testing.T) {
+	group := testGroup(1, 2)
+	group.ConfirmationRef = "GROUP1"
+
+	req, _ := http.NewRequest("GET", "/group-reservation-summary", nil)
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+	session.Put(ctx, "group", group)
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(Repo.GroupReservationSummary)
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("GroupReservationSummary handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+
+	// a group still being booked has no confirmation to summarise
+	req, _ = http.NewRequest("GET", "/group-reservation-summary", nil)
+	ctx = getCtx(req)
+	req = req.WithContext(ctx)
+	session.Put(ctx, "group", testGroup(1, 2))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusSeeOther {
+		t.Errorf("GroupReservationSummary handler (UNCONFIRMED) returned code: %d, expected code: %d", rr.Code, http.StatusSeeOther)
+	}
+}
+
+var adminBookingGroupTests = []struct {
+	name               string
+	url                string
+	expectedStatusCode int
+	expectedLocation   string
+}{
+	{"list", "/admin/groups", http.StatusOK, ""},
+	{"show", "/admin/groups/1", http.StatusOK, ""},
+	{"confirm-all", "/admin/group-status/1/confirmed", http.StatusSeeOther, "/admin/groups/1"},
+	{"cancel-all", "/admin/group-status/1/cancelled", http.StatusSeeOther, "/admin/groups/1"},
+	{"not-allowed", "/admin/group-status/1/checked-out", http.StatusSeeOther, "/admin/groups/1"},
+	{"unknown-status", "/admin/group-status/1/processed", http.StatusSeeOther, "/admin/groups/1"},
+	{"delete", "/admin/group-deleted/1", http.StatusSeeOther, "/admin/groups"},
+}
+
+func TestRepository_AdminBookingGroups(t *testing.T) {
+	routes := getRoutes()
+
+	for _, v := range adminBookingGroupTests {
+		req, _ := http.NewRequest("GET", v.url, nil)
+		rr := httptest.NewRecorder()
+
+		// served through the router so that the {id} & {status} URL parameters are available to the handler
+		routes.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("AdminBookingGroup handlers (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+		}
+
+		if v.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != v.expectedLocation {
+				t.Errorf("AdminBookingGroup handlers (%s) returned location: %s, expected location: %s", v.name, actualLoc.String(), v.expectedLocation)
+			}
+		}
+	}
+}
+
+func TestMemoryRepository_AdminBookingGroups_NotFound(t *testing.T) {
+	_, repo := newMemoryRepository()
+	routes := routesFor(repo)
+
+	for _, url := range []string{"/admin/groups/9999", "/admin/group-status/9999/confirmed", "/admin/group-deleted/9999"} {
+		req, _ := http.NewRequest("GET", url, nil)
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		if loc := rr.Header().Get("Location"); rr.Code != http.StatusSeeOther || loc != "/admin/groups" {
+			t.Errorf("AdminBookingGroup handlers (%s) returned code: %d (%s), expected code: %d (/admin/groups)", url, rr.Code, loc, http.StatusSeeOther)
+		}
+	}
+}
+
+// ========================================================================================================================================
+
+// getCtx creates a context for use in TestRepository_Reservation() request
+func getCtx(r *http.Request) context.Context {
+	ctx, err := session.Load(r.Context(), r.Header.Get("X-Session"))
+	if err != nil {
+		log.Println(err)
+	}
+	return ctx
+}
+
+func TestRepository_AdminAPITokens(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/admin/api-tokens", nil)
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+	session.Put(ctx, "admin_id", 1)
+	session.Put(ctx, "api_token", "bnb_new")
+	rr := httptest.NewRecorder()
+
+	handler := http.HandlerFunc(Repo.AdminAPITokens)
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("AdminAPITokens handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+	if !strings.Contains(rr.Body.String(), "Channel manager") {
+		t.Error("AdminAPITokens handler did not list the administrator's token")
+	}
+	if !strings.Contains(rr.Body.String(), "bnb_new") {
+		t.Error("AdminAPITokens handler did not show the new token")
+	}
+	if session.Exists(ctx, "api_token") {
+		t.Error("AdminAPITokens handler left the new token in the session, to be shown again")
+	}
+}
+
+var adminPostAPITokenTests = []struct {
+	name               string
+	adminID            int
+	tokenName          string
+	expectedStatusCode int
+	expectedLocation   string
+	expectedHTML       string
+}{
+	{"valid", 1, "Channel manager", http.StatusSeeOther, "/admin/api-tokens", ""},
+	{"not-logged-in", 0, "Channel manager", http.StatusSeeOther, "/login", ""},
+	{"missing-name", 1, "", http.StatusOK, "", `action="/admin/api-tokens"`},
+	{"short-name", 1, "C", http.StatusOK, "", "this field must be at least 2 characters in length"},
+}
+
+func TestRepository_AdminPostAPIToken(t *testing.T) {
+	for _, v := range adminPostAPITokenTests {
+		postedData := url.Values{"name": {v.tokenName}}
+		req, _ := http.NewRequest("POST", "/admin/api-tokens", strings.NewReader(postedData.Encode()))
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		if v.adminID != 0 {
+			session.Put(ctx, "admin_id", v.adminID)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.AdminPostAPIToken)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("AdminPostAPIToken handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+		}
+
+		if v.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != v.expectedLocation {
+				t.Errorf("AdminPostAPIToken handler (%s) returned location: %s, expected location: %s", v.name, actualLoc.String(), v.expectedLocation)
+			}
+		}
+
+		if v.expectedHTML != "" && !strings.Contains(rr.Body.String(), v.expectedHTML) {
+			t.Errorf("AdminPostAPIToken handler (%s) did not return expected HTML: %s", v.name, v.expectedHTML)
+		}
+
+		// the new token is kept in the session only until it has been shown
+		if v.name == "valid" && !strings.HasPrefix(session.GetString(ctx, "api_token"), "bnb_") {
+			t.Errorf("AdminPostAPIToken handler (%s) did not keep the new token to be shown", v.name)
+		}
+	}
+}
+
+func TestRepository_AdminAPITokenDelete(t *testing.T) {
+	routes := getRoutes()
+
+	req, _ := http.NewRequest("GET", "/admin/api-token-deleted/1", nil)
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	actualLoc, _ := rr.Result().Location()
+	if rr.Code != http.StatusSeeOther || actualLoc.String() != "/admin/api-tokens" {
+		t.Errorf("AdminAPITokenDelete handler returned code: %d & location: %s, expected redirect to /admin/api-tokens", rr.Code, actualLoc.String())
+	}
+}
+
+func TestRepository_AdminICalFeeds(t *testing.T) {
+	routes := getRoutes()
+
+	req, _ := http.NewRequest("GET", "/admin/ical-feeds", nil)
+	req.Host = "edenhouse.example"
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("AdminICalFeeds handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+	if !strings.Contains(rr.Body.String(), "http://edenhouse.example/ical/private-feed-token.ics") {
+		t.Error("AdminICalFeeds handler did not give the feed's URL")
+	}
+
+	postedData := url.Values{"room_id": {"1"}, "private": {"1"}}
+	req, _ = http.NewRequest("POST", "/admin/ical-feeds", strings.NewReader(postedData.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	actualLoc, _ := rr.Result().Location()
+	if rr.Code != http.StatusSeeOther || actualLoc.String() != "/admin/ical-feeds" {
+		t.Errorf("AdminPostICalFeed handler returned code: %d & location: %s, expected redirect to /admin/ical-feeds", rr.Code, actualLoc.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/admin/ical-feed-deleted/1", nil)
+	rr = httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	actualLoc, _ = rr.Result().Location()
+	if rr.Code != http.StatusSeeOther || actualLoc.String() != "/admin/ical-feeds" {
+		t.Errorf("AdminICalFeedDelete handler returned code: %d & location: %s, expected redirect to /admin/ical-feeds", rr.Code, actualLoc.String())
 	}
-	return ctx
 }
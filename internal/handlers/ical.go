@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/helpers"
+	"github.com/StratoNET/bnb-bookings/internal/ical"
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// icalProdID identifies this application as the creator of its iCal feeds
+const icalProdID = "-//StratoNET//bnb-bookings//EN"
+
+// icalFeedHistoryDays is how many days into the past a feed goes, so that calendars still show guests who have recently left
+const icalFeedHistoryDays = 30
+
+// newFeedToken returns a random token for an iCal feed's secret URL, long enough that feeds cannot be found by guessing
+func newFeedToken() (string, error) {
+	b := make([]byte, 24)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// baseURL is the scheme & host a request was made to, for giving absolute URLs to be used from elsewhere
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// feedEvent converts a room restriction into an iCal event. Private feeds name the guest, public feeds say only that the room is taken
+func feedEvent(rest models.RoomRestriction, private bool) ical.Event {
+	e := ical.Event{
+		UID:   fmt.Sprintf("restriction-%d@bnb-bookings", rest.ID),
+		Start: rest.StartDate,
+		End:   rest.EndDate,
+		Stamp: rest.UpdatedAt,
+	}
+	if e.Stamp.IsZero() {
+		e.Stamp = time.Now()
+	}
+
+	if rest.RestrictionID != models.RestrictionReservation {
+		// an owner block or external booking includes its end date, whereas a reservation ends on the day of departure
+		e.End = rest.EndDate.AddDate(0, 0, 1)
+		e.Summary = "Not available"
+		if private {
+			e.Summary = "Owner block"
+			if rest.RestrictionID == models.RestrictionExternalBooking {
+				e.Summary = "External booking"
+			}
+		}
+		return e
+	}
+
+	e.Summary = "Reserved"
+	if private {
+		rsvn := rest.Reservation
+		e.Summary = fmt.Sprintf("%s %s (%s)", rsvn.FirstName, rsvn.LastName, rsvn.ConfirmationRef)
+		e.Description = fmt.Sprintf("%s, %s", rsvn.Party(), rsvn.Status.Label())
+	}
+	return e
+}
+
+// ICalFeed is the handler for GET /ical/{token}.ics, returning a room's reservations & owner blocks as an iCal feed for booking
+// sites & calendar apps. The secret token is the only protection, so revoking a feed's token is how access is withdrawn
+func (m *Repository) ICalFeed(w http.ResponseWriter, r *http.Request) {
+	feed, err := m.DB.GetICalFeedByToken(r.Context(), chi.URLParam(r, "token"))
+	if m.dbInterrupted(w, r, err) {
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		helpers.ServerError(w, err)
+		return
+	}
+
+	from := time.Now().AddDate(0, 0, -icalFeedHistoryDays)
+	restrictions, err := m.DB.GetRoomRestrictionsForFeed(r.Context(), feed.RoomID, from)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		helpers.ServerError(w, err)
+		return
+	}
+
+	cal := ical.Calendar{
+		ProdID: icalProdID,
+		Name:   "Eden House - " + feed.Room.RoomName,
+	}
+	if feed.Private {
+		cal.Name += " (private)"
+	}
+	for _, rest := range restrictions {
+		cal.Events = append(cal.Events, feedEvent(rest, feed.Private))
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	// private feeds name guests, so must not be kept by any shared cache
+	w.Header().Set("Cache-Control", "private, no-cache")
+	err = cal.Encode(w)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+	}
+}
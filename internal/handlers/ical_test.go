@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/models"
+)
+
+var icalFeedTests = []struct {
+	name               string
+	url                string
+	expectedStatusCode int
+	expectedContent    []string
+	unexpectedContent  []string
+}{
+	{"public", "/ical/public-feed-token.ics", http.StatusOK, []string{"BEGIN:VCALENDAR", "SUMMARY:Reserved", "SUMMARY:Not available", "X-WR-CALNAME:Eden House - General's Quarters\r\n"}, []string{"Joe", "Soap", "K7QX2MZP4D", "DESCRIPTION"}},
+	{"private", "/ical/private-feed-token.ics", http.StatusOK, []string{"SUMMARY:Joe Soap (K7QX2MZP4D)", "DESCRIPTION:2 adults\\, Confirmed", "SUMMARY:Owner block", "(private)"}, []string{"SUMMARY:Reserved"}},
+	{"revoked", "/ical/revoked-feed-token.ics", http.StatusNotFound, nil, nil},
+	{"no-extension", "/ical/public-feed-token", http.StatusNotFound, nil, nil},
+}
+
+func TestRepository_ICalFeed(t *testing.T) {
+	routes := getRoutes()
+
+	for _, v := range icalFeedTests {
+		req, _ := http.NewRequest("GET", v.url, nil)
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("ICalFeed handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+			continue
+		}
+		if rr.Code != http.StatusOK {
+			continue
+		}
+		if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/calendar") {
+			t.Errorf("ICalFeed handler (%s) returned content type: %s, expected text/calendar", v.name, rr.Header().Get("Content-Type"))
+		}
+		for _, s := range v.expectedContent {
+			if !strings.Contains(rr.Body.String(), s) {
+				t.Errorf("ICalFeed handler (%s) did not return %q", v.name, s)
+			}
+		}
+		for _, s := range v.unexpectedContent {
+			if strings.Contains(rr.Body.String(), s) {
+				t.Errorf("ICalFeed handler (%s) returned %q", v.name, s)
+			}
+		}
+	}
+}
+
+func TestMemoryRepository_ICalFeed_DatabaseError(t *testing.T) {
+	db, repo := newMemoryRepository()
+	err := db.InsertICalFeed(context.Background(), models.ICalFeed{RoomID: 1, Token: "public-feed-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.FailOn("GetRoomRestrictionsForFeed", errMemoryDB)
+
+	req, _ := http.NewRequest("GET", "/ical/public-feed-token.ics", nil)
+	rr := httptest.NewRecorder()
+	routesFor(repo).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("ICalFeed handler (database-error) returned code: %d, expected code: %d", rr.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestFeedEvent(t *testing.T) {
+	start := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	// a reservation ends on the day of departure
+	e := feedEvent(models.RoomRestriction{ID: 1, RestrictionID: models.RestrictionReservation, StartDate: start, EndDate: start.AddDate(0, 0, 2)}, false)
+	if !e.End.Equal(start.AddDate(0, 0, 2)) {
+		t.Errorf("expected a reservation to end on the day of departure, got %s", e.End)
+	}
+
+	// an owner block includes its last day, so ends the day after
+	e = feedEvent(models.RoomRestriction{ID: 2, RestrictionID: models.RestrictionOwnerBlock, StartDate: start, EndDate: start}, false)
+	if !e.End.Equal(start.AddDate(0, 0, 1)) {
+		t.Errorf("expected a single day owner block to end the day after, got %s", e.End)
+	}
+
+	// an external booking is only named in private feeds, as other sites need only know the room is taken
+	external := models.RoomRestriction{ID: 4, RestrictionID: models.RestrictionExternalBooking, StartDate: start, EndDate: start}
+	if feedEvent(external, false).Summary != "Not available" || feedEvent(external, true).Summary != "External booking" {
+		t.Error("expected an external booking to be shown as taken publicly & as an external booking privately")
+	}
+
+	// events keep the same UID, so that calendars update rather than duplicate them
+	if feedEvent(models.RoomRestriction{ID: 2}, true).UID != e.UID {
+		t.Error("expected an event's UID to depend only on its restriction")
+	}
+}
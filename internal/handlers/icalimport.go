@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/ical"
+	"github.com/StratoNET/bnb-bookings/internal/models"
+)
+
+// icalImportTimeout is how long another site is given to return its calendar
+const icalImportTimeout = 30 * time.Second
+
+// icalImportMaxBytes is the largest calendar which will be read, anything longer is cut short & so fails to parse
+const icalImportMaxBytes = 5 << 20
+
+// icalOwnUID is the suffix of the UIDs of this application's own feed events, which other sites may publish back to us
+const icalOwnUID = "@bnb-bookings"
+
+var icalClient = &http.Client{Timeout: icalImportTimeout}
+
+// importMu stops calendars being imported twice at once, by the importer & an administrator, which could copy a booking twice
+var importMu sync.Mutex
+
+// importResult counts the changes made to an import's external bookings by importing its calendar
+type importResult struct {
+	Added   int
+	Moved   int
+	Removed int
+}
+
+// fetchCalendar gets & parses the calendar at a given URL, abandoning the fetch once ctx is done
+func fetchCalendar(ctx context.Context, url string) (*ical.Calendar, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/calendar")
+
+	resp, err := icalClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("calendar returned %s", resp.Status)
+	}
+
+	return ical.Parse(io.LimitReader(resp.Body, icalImportMaxBytes))
+}
+
+// calendarDay is the calendar day of t, in its own time zone, as midnight UTC, the form in which restrictions' dates are kept
+func calendarDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// externalBookings converts a calendar's events into external bookings for an import's room. A booking, like an owner block, ends on
+// its last night, whereas an event ends on the day of departure. Events this application published itself are left out
+func externalBookings(imp models.ICalImport, cal *ical.Calendar) []models.RoomRestriction {
+	var bookings []models.RoomRestriction
+	seen := make(map[string]bool)
+
+	for _, e := range cal.Events {
+		if strings.HasSuffix(e.UID, icalOwnUID) {
+			continue
+		}
+
+		start := calendarDay(e.Start)
+		end := calendarDay(e.End).AddDate(0, 0, -1)
+		if end.Before(start) {
+			end = start
+		}
+
+		// bookings are matched to their events by UID, so an event with no UID, or sharing one, is told apart by its start
+		uid := e.UID
+		if uid == "" || seen[uid] {
+			uid = fmt.Sprintf("%s/%s", e.UID, start.Format("20060102"))
+		}
+		seen[uid] = true
+
+		bookings = append(bookings, models.RoomRestriction{
+			RoomID:        imp.RoomID,
+			RestrictionID: models.RestrictionExternalBooking,
+			StartDate:     start,
+			EndDate:       end,
+			ImportID:      imp.ID,
+			ExternalUID:   uid,
+		})
+	}
+	return bookings
+}
+
+// diffExternalBookings compares the external bookings already copied from a calendar with those now in it, giving the bookings
+// to add, those to move to new dates & the ids of those no longer in the calendar, to be removed
+func diffExternalBookings(existing, current []models.RoomRestriction) (add, move []models.RoomRestriction, remove []int) {
+	byUID := make(map[string]models.RoomRestriction)
+	for _, b := range existing {
+		byUID[b.ExternalUID] = b
+	}
+
+	for _, b := range current {
+		old, ok := byUID[b.ExternalUID]
+		if !ok {
+			add = append(add, b)
+			continue
+		}
+		delete(byUID, b.ExternalUID)
+		if !old.StartDate.Equal(b.StartDate) || !old.EndDate.Equal(b.EndDate) {
+			b.ID = old.ID
+			move = append(move, b)
+		}
+	}
+
+	// in the existing order, so that bookings are always removed in the same order
+	for _, b := range existing {
+		if _, ok := byUID[b.ExternalUID]; ok {
+			remove = append(remove, b.ID)
+		}
+	}
+	return add, move, remove
+}
+
+// syncICalImport imports an import's calendar, bringing its external bookings up to date
+func (m *Repository) syncICalImport(ctx context.Context, imp models.ICalImport) (importResult, error) {
+	var result importResult
+
+	cal, err := fetchCalendar(ctx, imp.URL)
+	if err != nil {
+		return result, err
+	}
+
+	existing, err := m.DB.GetExternalBookings(ctx, imp.ID)
+	if err != nil {
+		return result, err
+	}
+
+	add, move, remove := diffExternalBookings(existing, externalBookings(imp, cal))
+	if len(add) == 0 && len(move) == 0 && len(remove) == 0 {
+		return result, nil
+	}
+
+	err = m.DB.SyncExternalBookings(ctx, imp.ID, add, move, remove)
+	if err != nil {
+		return result, err
+	}
+
+	result = importResult{Added: len(add), Moved: len(move), Removed: len(remove)}
+	return result, nil
+}
+
+// ImportExternalCalendars imports every room's calendars from other sites, recording the outcome of each, then reports any
+// external booking which conflicts with one of our own reservations
+func (m *Repository) ImportExternalCalendars(ctx context.Context) {
+	importMu.Lock()
+	defer importMu.Unlock()
+
+	imports, err := m.DB.GetAllICalImports(ctx)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		return
+	}
+
+	for _, imp := range imports {
+		result, err := m.syncICalImport(ctx, imp)
+		syncErr := ""
+		if err != nil {
+			syncErr = err.Error()
+			m.App.ErrorLog.Printf("Cannot import %s calendar for %s: %s\n", imp.Name, imp.Room.RoomName, syncErr)
+		} else if result != (importResult{}) {
+			m.App.InfoLog.Printf("Imported %s calendar for %s: %d added, %d moved, %d removed\n", imp.Name, imp.Room.RoomName, result.Added, result.Moved, result.Removed)
+			RequestChannelSync()
+		}
+
+		err = m.DB.UpdateICalImportSync(ctx, imp.ID, time.Now(), syncErr)
+		if err != nil {
+			m.App.ErrorLog.Println(err)
+		}
+	}
+
+	m.reportBookingConflicts(ctx)
+}
+
+// reportBookingConflicts logs every current or future external booking which overlaps one of our own reservations, so that it
+// can be resolved before either guest arrives. Conflicts are also listed on the iCal imports page
+func (m *Repository) reportBookingConflicts(ctx context.Context) {
+	conflicts, err := m.DB.GetBookingConflicts(ctx, calendarDay(time.Now()))
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		return
+	}
+
+	for _, c := range conflicts {
+		m.App.ErrorLog.Printf("Double booking of %s: %s booking %s to %s overlaps reservation %s (%s %s) %s to %s\n",
+			c.Booking.Room.RoomName, c.Import.Name, c.Booking.StartDate.Format("02/01/2006"), c.Booking.EndDate.Format("02/01/2006"),
+			c.Reservation.ConfirmationRef, c.Reservation.FirstName, c.Reservation.LastName,
+			c.Reservation.StartDate.Format("02/01/2006"), c.Reservation.EndDate.Format("02/01/2006"))
+	}
+}
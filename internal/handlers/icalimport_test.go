@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/database"
+	"github.com/StratoNET/bnb-bookings/internal/ical"
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/repository/dbrepository"
+)
+
+// externalCalendar is another site's calendar as testdb's GetExternalBookings last saw it, with "kept@airbnb.com" since moved
+// by 2 days, "removed@airbnb.com" gone, a new booking & one of our own events published back to us
+const externalCalendar = "BEGIN:VCALENDAR\r\n" +
+	"PRODID:-//Airbnb Inc//Hosting Calendar 0.8.8//EN\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20980303\r\n" +
+	"DTEND;VALUE=DATE:20980305\r\n" +
+	"UID:kept@airbnb.com\r\n" +
+	"SUMMARY:Reserved\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20980510\r\n" +
+	"DTEND;VALUE=DATE:20980514\r\n" +
+	"UID:new@airbnb.com\r\n" +
+	"SUMMARY:Reserved\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20980601\r\n" +
+	"DTEND;VALUE=DATE:20980603\r\n" +
+	"UID:restriction-2@bnb-bookings\r\n" +
+	"SUMMARY:Not available\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+// unchangedCalendar is another site's calendar exactly as testdb's GetExternalBookings last saw it
+const unchangedCalendar = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20980301\r\n" +
+	"DTEND;VALUE=DATE:20980303\r\n" +
+	"UID:kept@airbnb.com\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20980401\r\n" +
+	"DTEND;VALUE=DATE:20980403\r\n" +
+	"UID:removed@airbnb.com\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+// newExternalSite stands in for another site, publishing calendars at /external.ics, /unchanged.ics & /page.html
+func newExternalSite() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/external.ics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/calendar")
+		fmt.Fprint(w, externalCalendar)
+	})
+	mux.HandleFunc("/unchanged.ics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/calendar")
+		fmt.Fprint(w, unchangedCalendar)
+	})
+	mux.HandleFunc("/page.html", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<!DOCTYPE html><html><body>Please log in</body></html>")
+	})
+	return httptest.NewServer(mux)
+}
+
+var syncICalImportTests = []struct {
+	name           string
+	importID       int
+	path           string
+	expectedResult importResult
+	expectedError  string
+}{
+	{"changed", 1, "/external.ics", importResult{Added: 1, Moved: 1, Removed: 1}, ""},
+	{"unchanged", 1, "/unchanged.ics", importResult{}, ""},
+	{"not-found", 1, "/missing.ics", importResult{}, "404"},
+	{"not-a-calendar", 1, "/page.html", importResult{}, "not an iCalendar"},
+}
+
+func TestRepository_syncICalImport(t *testing.T) {
+	site := newExternalSite()
+	defer site.Close()
+
+	for _, v := range syncICalImportTests {
+		imp := models.ICalImport{ID: v.importID, RoomID: 1, Name: "Airbnb", URL: site.URL + v.path}
+
+		result, err := Repo.syncICalImport(context.Background(), imp)
+		if v.expectedError == "" && err != nil {
+			t.Errorf("%s: expected no error, got %v", v.name, err)
+		}
+		if v.expectedError != "" && (err == nil || !strings.Contains(err.Error(), v.expectedError)) {
+			t.Errorf("%s: expected error containing %q, got %v", v.name, v.expectedError, err)
+		}
+		if result != v.expectedResult {
+			t.Errorf("%s: expected %+v, got %+v", v.name, v.expectedResult, result)
+		}
+	}
+
+	// an import whose request has been cancelled does not fetch the calendar
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	imp := models.ICalImport{ID: 1, RoomID: 1, Name: "Airbnb", URL: site.URL + "/external.ics"}
+	result, err := Repo.syncICalImport(ctx, imp)
+	if !errors.Is(err, context.Canceled) || result != (importResult{}) {
+		t.Errorf("cancelled: expected %v & no change, got %+v %v", context.Canceled, result, err)
+	}
+}
+
+// TestMemoryRepository_syncICalImport checks an import's external bookings are left as they were should the database fail, & are not
+// saved again when nothing has changed
+func TestMemoryRepository_syncICalImport(t *testing.T) {
+	site := newExternalSite()
+	defer site.Close()
+
+	db, repo := newMemoryRepository()
+	imp := models.ICalImport{ID: 1, RoomID: 1, Name: "Airbnb", URL: site.URL + "/external.ics"}
+	err := db.InsertICalImport(context.Background(), imp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bookings := func() int {
+		existing, err := db.GetExternalBookings(context.Background(), imp.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(existing)
+	}
+
+	for _, method := range []string{"GetExternalBookings", "SyncExternalBookings"} {
+		db.FailOn(method, errMemoryDB)
+		result, err := repo.syncICalImport(context.Background(), imp)
+		db.FailOn(method, nil)
+
+		if !errors.Is(err, errMemoryDB) || result != (importResult{}) {
+			t.Errorf("%s failing: expected %v & no result, got %v & %+v", method, errMemoryDB, err, result)
+		}
+		if n := bookings(); n != 0 {
+			t.Errorf("%s failing: expected no external bookings to be saved, got %d", method, n)
+		}
+	}
+
+	// both of the other site's bookings are new, its event published from our own calendar is not
+	result, err := repo.syncICalImport(context.Background(), imp)
+	if err != nil || result != (importResult{Added: 2}) {
+		t.Fatalf("expected 2 external bookings to be added, got %+v (%v)", result, err)
+	}
+
+	// nothing to change, so no attempt is made to save the changes
+	db.FailOn("SyncExternalBookings", errMemoryDB)
+	result, err = repo.syncICalImport(context.Background(), imp)
+	if err != nil || result != (importResult{}) {
+		t.Errorf("unchanged: expected nothing to be saved, got %+v (%v)", result, err)
+	}
+	if n := bookings(); n != 2 {
+		t.Errorf("unchanged: expected 2 external bookings, got %d", n)
+	}
+}
+
+func TestDiffExternalBookings(t *testing.T) {
+	cal, err := ical.Parse(strings.NewReader(externalCalendar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	imp := models.ICalImport{ID: 1, RoomID: 1}
+	existing, _ := Repo.DB.GetExternalBookings(context.Background(), imp.ID)
+
+	add, move, remove := diffExternalBookings(existing, externalBookings(imp, cal))
+
+	if len(add) != 1 || add[0].ExternalUID != "new@airbnb.com" || add[0].RoomID != 1 || add[0].ImportID != 1 {
+		t.Errorf("expected new@airbnb.com to be added to room 1, got %+v", add)
+	}
+	// 4 nights from 10/05, so the last night is 13/05
+	if len(add) == 1 && (add[0].StartDate.Format("2006-01-02") != "2098-05-10" || add[0].EndDate.Format("2006-01-02") != "2098-05-13") {
+		t.Errorf("expected new booking from 10/05 to its last night on 13/05, got %s to %s", add[0].StartDate, add[0].EndDate)
+	}
+	if len(move) != 1 || move[0].ID != 11 || move[0].StartDate.Format("2006-01-02") != "2098-03-03" {
+		t.Errorf("expected booking 11 to be moved to 03/03, got %+v", move)
+	}
+	if len(remove) != 1 || remove[0] != 12 {
+		t.Errorf("expected booking 12 to be removed, got %v", remove)
+	}
+}
+
+func TestExternalBookings(t *testing.T) {
+	london, _ := time.LoadLocation("Europe/London")
+	cal := &ical.Calendar{Events: []ical.Event{
+		// a single night
+		{UID: "a", Start: time.Date(2098, 1, 1, 0, 0, 0, 0, time.Local), End: time.Date(2098, 1, 2, 0, 0, 0, 0, time.Local)},
+		// a stay of 2 nights given by check in & check out times
+		{UID: "b", Start: time.Date(2098, 1, 10, 15, 0, 0, 0, london), End: time.Date(2098, 1, 12, 11, 0, 0, 0, london)},
+		// same UID as an earlier event, as a recurring event's instances share one
+		{UID: "b", Start: time.Date(2098, 2, 10, 0, 0, 0, 0, time.Local), End: time.Date(2098, 2, 11, 0, 0, 0, 0, time.Local)},
+		// no UID at all
+		{Start: time.Date(2098, 3, 1, 0, 0, 0, 0, time.Local), End: time.Date(2098, 3, 1, 0, 0, 0, 0, time.Local)},
+	}}
+
+	bookings := externalBookings(models.ICalImport{ID: 1, RoomID: 2}, cal)
+
+	expected := []struct {
+		uid, start, end string
+	}{
+		{"a", "2098-01-01", "2098-01-01"},
+		{"b", "2098-01-10", "2098-01-11"},
+		{"b/20980210", "2098-02-10", "2098-02-10"},
+		{"/20980301", "2098-03-01", "2098-03-01"},
+	}
+	if len(bookings) != len(expected) {
+		t.Fatalf("expected %d bookings, got %d", len(expected), len(bookings))
+	}
+	for i, e := range expected {
+		b := bookings[i]
+		if b.ExternalUID != e.uid || b.StartDate.Format("2006-01-02") != e.start || b.EndDate.Format("2006-01-02") != e.end {
+			t.Errorf("booking %d: expected %s %s to %s, got %s %s to %s", i, e.uid, e.start, e.end, b.ExternalUID, b.StartDate.Format("2006-01-02"), b.EndDate.Format("2006-01-02"))
+		}
+		if b.RoomID != 2 || b.RestrictionID != models.RestrictionExternalBooking {
+			t.Errorf("booking %d: expected an external booking for room 2, got %+v", i, b)
+		}
+	}
+}
+
+func TestRepository_AdminICalImports(t *testing.T) {
+	routes := getRoutes()
+
+	req, _ := http.NewRequest("GET", "/admin/ical-imports", nil)
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("AdminICalImports handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+	for _, s := range []string{"Double bookings", "Joe Soap (K7QX2MZP4D)", "calendar returned 404 Not Found"} {
+		if !strings.Contains(rr.Body.String(), s) {
+			t.Errorf("AdminICalImports handler did not show %q", s)
+		}
+	}
+}
+
+var adminPostICalImportTests = []struct {
+	name               string
+	postedData         url.Values
+	expectedStatusCode int
+	expectedContent    string
+}{
+	{"valid", url.Values{"room_id": {"1"}, "name": {"Airbnb"}, "url": {"https://www.airbnb.co.uk/calendar/ical/123.ics?s=abc"}}, http.StatusSeeOther, ""},
+	{"missing-name", url.Values{"room_id": {"1"}, "url": {"https://www.airbnb.co.uk/calendar/ical/123.ics"}}, http.StatusOK, "this field is required"},
+	{"invalid-url", url.Values{"room_id": {"1"}, "name": {"Airbnb"}, "url": {"webcal://www.airbnb.co.uk/calendar/ical/123.ics"}}, http.StatusOK, "please input a valid web address"},
+}
+
+func TestRepository_AdminPostICalImport(t *testing.T) {
+	routes := getRoutes()
+
+	for _, v := range adminPostICalImportTests {
+		req, _ := http.NewRequest("POST", "/admin/ical-imports", strings.NewReader(v.postedData.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("AdminPostICalImport handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+		}
+		if v.expectedContent != "" && !strings.Contains(rr.Body.String(), v.expectedContent) {
+			t.Errorf("AdminPostICalImport handler (%s) did not show %q", v.name, v.expectedContent)
+		}
+	}
+}
+
+func TestRepository_AdminICalImportsSync(t *testing.T) {
+	routes := getRoutes()
+
+	req, _ := http.NewRequest("POST", "/admin/ical-imports/sync", nil)
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	actualLoc, _ := rr.Result().Location()
+	if rr.Code != http.StatusSeeOther || actualLoc.String() != "/admin/ical-imports" {
+		t.Errorf("AdminICalImportsSync handler returned code: %d & location: %s, expected redirect to /admin/ical-imports", rr.Code, actualLoc.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/admin/ical-import-deleted/1", nil)
+	rr = httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	actualLoc, _ = rr.Result().Location()
+	if rr.Code != http.StatusSeeOther || actualLoc.String() != "/admin/ical-imports" {
+		t.Errorf("AdminICalImportDelete handler returned code: %d & location: %s, expected redirect to /admin/ical-imports", rr.Code, actualLoc.String())
+	}
+}
+
+// TestRepository_syncICalImport_TimeZone imports a calendar into a SQLite database on a server away from UTC, whose all-day events
+// must still block the very nights they cover, as dates everywhere else in the application are midnight UTC
+func TestRepository_syncICalImport_TimeZone(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("no time zone database", err)
+	}
+	local := time.Local
+	time.Local = london
+	defer func() { time.Local = local }()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "bnb-bookings.db") + "?_foreign_keys=1&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+	db, err := sql.Open(database.SQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = database.MigrateUp(&database.DB{SQL: db, Driver: database.SQLite})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2 nights, 10/07 & 11/07, leaving on 12/07
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/calendar")
+		fmt.Fprint(w, "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20980710\r\nDTEND;VALUE=DATE:20980712\r\n"+
+			"UID:summer@airbnb.com\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n")
+	}))
+	defer site.Close()
+
+	ctx := context.Background()
+	repo := &Repository{App: &app, DB: dbrepository.NewSQLiteDBRepository(db, &app)}
+	err = repo.DB.InsertICalImport(ctx, models.ICalImport{RoomID: 1, Name: "Airbnb", URL: site.URL + "/summer.ics"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	imports, err := repo.DB.GetAllICalImports(ctx)
+	if err != nil || len(imports) != 1 {
+		t.Fatalf("expected 1 import, got %d (%v)", len(imports), err)
+	}
+
+	_, err = repo.syncICalImport(ctx, imports[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bookings, err := repo.DB.GetExternalBookings(ctx, imports[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, last := time.Date(2098, 7, 10, 0, 0, 0, 0, time.UTC), time.Date(2098, 7, 11, 0, 0, 0, 0, time.UTC)
+	if len(bookings) != 1 || !bookings[0].StartDate.Equal(first) || !bookings[0].EndDate.Equal(last) {
+		t.Fatalf("expected an external booking from %s to its last night on %s, got %+v", first, last, bookings)
+	}
+
+	for _, v := range []struct {
+		night     time.Time
+		available bool
+	}{
+		{time.Date(2098, 7, 8, 0, 0, 0, 0, time.UTC), true},
+		{first, false},
+		{last, false},
+		{time.Date(2098, 7, 12, 0, 0, 0, 0, time.UTC), true},
+	} {
+		available, err := repo.DB.SearchAvailabilityByDatesAndRoomID(ctx, v.night, v.night.AddDate(0, 0, 1), 1, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if available != v.available {
+			t.Errorf("night of %s: expected available %t, got %t", v.night.Format("2006-01-02"), v.available, available)
+		}
+	}
+}
@@ -0,0 +1,1042 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/apitoken"
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/repository"
+	"github.com/StratoNET/bnb-bookings/internal/repository/dbrepository"
+	"github.com/StratoNET/bnb-bookings/internal/webhook"
+	"github.com/go-chi/chi/v5"
+)
+
+// postMemoryReservation posts a guest's details for a stay in the General's Quarters, as chosen earlier in their session, the request
+// ending when parent does
+func postMemoryReservation(parent context.Context, repo *Repository, firstName string) *httptest.ResponseRecorder {
+	postedData := url.Values{}
+	postedData.Add("first_name", firstName)
+	postedData.Add("last_name", "Soap")
+	postedData.Add("email", "joe@soap.bar")
+	postedData.Add("phone", "01234 567890")
+	postedData.Add("adults", "2")
+
+	return postMemoryReservationForm(parent, repo, postedData)
+}
+
+// postMemoryReservationForm posts the given make-reservation form for a stay in the General's Quarters, as the repository holds it
+func postMemoryReservationForm(parent context.Context, repo *Repository, postedData url.Values) *httptest.ResponseRecorder {
+	// the room is got before the request, whose context may already have ended
+	room, _ := repo.DB.GetRoomByID(context.Background(), 1)
+	reservation := models.Reservation{
+		RoomID:    1,
+		StartDate: time.Date(2099, 6, 10, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2099, 6, 12, 0, 0, 0, 0, time.UTC),
+		Room:      room,
+	}
+
+	req, _ := http.NewRequestWithContext(parent, "POST", "/make-reservation", strings.NewReader(postedData.Encode()))
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	session.Put(ctx, "reservation", reservation)
+
+	http.HandlerFunc(repo.PostReservation).ServeHTTP(rr, req)
+
+	return rr
+}
+
+// TestMemoryRepository_PostReservation makes reservations against the in-memory repository, so that the room really is taken by the
+// first guest & the second guest is turned away, rather than the outcome depending on magic room numbers or dates
+func TestMemoryRepository_PostReservation(t *testing.T) {
+	db := dbrepository.NewMemoryDBRepository(&app)
+	repo := &Repository{App: &app, DB: db}
+
+	rr := postMemoryReservation(context.Background(), repo, "Joe")
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/reservation-summary" {
+		t.Fatalf("PostReservation handler returned code: %d (%s), expected code: %d (/reservation-summary)", rr.Code, rr.Header().Get("Location"), http.StatusSeeOther)
+	}
+
+	reservations, err := db.GetAllReservations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reservations) != 1 || reservations[0].FirstName != "Joe" || reservations[0].RoomID != 1 {
+		t.Fatalf("expected Joe's reservation of room 1 to be stored, got %+v", reservations)
+	}
+
+	// the room is now taken, so the next guest is offered a fresh search
+	rr = postMemoryReservation(context.Background(), repo, "Jane")
+	if rr.Code != http.StatusOK {
+		t.Errorf("PostReservation handler (ROOM TAKEN) returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+	if !strings.Contains(rr.Body.String(), "search-availability") {
+		t.Error("PostReservation handler (ROOM TAKEN) did not offer a fresh search")
+	}
+
+	// any other failure of the database returns the guest to the home page
+	db.FailOn("CreateReservation", errors.New("connection lost"))
+	rr = postMemoryReservation(context.Background(), repo, "Jack")
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/" {
+		t.Errorf("PostReservation handler (DATABASE FAILURE) returned code: %d (%s), expected code: %d (/)", rr.Code, rr.Header().Get("Location"), http.StatusSeeOther)
+	}
+
+	reservations, err = db.GetAllReservations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reservations) != 1 {
+		t.Errorf("expected only Joe's reservation to be stored, got %d reservations", len(reservations))
+	}
+}
+
+// TestMemoryRepository_Interrupted checks a request whose database operation is cancelled or times out is reported as such, rather
+// than as the database failing
+func TestMemoryRepository_Interrupted(t *testing.T) {
+	db := dbrepository.NewMemoryDBRepository(&app)
+	repo := &Repository{App: &app, DB: db}
+
+	// a client which has disconnected is sent nothing
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	rr := postMemoryReservation(cancelled, repo, "Joe")
+	if rr.Body.Len() != 0 || rr.Header().Get("Location") != "" {
+		t.Errorf("PostReservation handler (CANCELLED) responded with code: %d (%s), expected no response", rr.Code, rr.Header().Get("Location"))
+	}
+
+	// a client whose request timed out is told to try again
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	rr = postMemoryReservation(expired, repo, "Joe")
+	if rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("PostReservation handler (TIMED OUT) returned code: %d, expected code: %d with Retry-After", rr.Code, http.StatusServiceUnavailable)
+	}
+
+	reservations, err := db.GetAllReservations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reservations) != 0 {
+		t.Errorf("expected no reservation to be stored, got %d reservations", len(reservations))
+	}
+
+	// the JSON API reports a timeout with its own error code
+	req, _ := http.NewRequestWithContext(expired, "GET", "/api/v1/availability?start=2099-06-10&end=2099-06-12&guests=2", nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(repo.APIAvailability).ServeHTTP(rr, req)
+
+	var body apiError
+	_ = json.Unmarshal(rr.Body.Bytes(), &body)
+	if rr.Code != http.StatusServiceUnavailable || body.Error.Code != apiErrTimeout {
+		t.Errorf("APIAvailability handler (TIMED OUT) returned code: %d (%s), expected code: %d (%s)", rr.Code, body.Error.Code, http.StatusServiceUnavailable, apiErrTimeout)
+	}
+
+	// as does the availability modal, in the JSON its script reads
+	postedData := url.Values{}
+	postedData.Add("start_date", "10/06/2099")
+	postedData.Add("end_date", "12/06/2099")
+	postedData.Add("room_id", "1")
+	req, _ = http.NewRequestWithContext(expired, "POST", "/search-availability-modal", strings.NewReader(postedData.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = req.WithContext(getCtx(req))
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(repo.PostAvailabilityModal).ServeHTTP(rr, req)
+
+	var modal jsonResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &modal)
+	if err != nil || rr.Code != http.StatusServiceUnavailable || modal.Ok || modal.Message == "" {
+		t.Errorf("PostAvailabilityModal handler (TIMED OUT) returned code: %d (%q), expected code: %d with JSON reporting the timeout", rr.Code, rr.Body.String(), http.StatusServiceUnavailable)
+	}
+}
+
+// TestMemoryRepository_PostReservationPaddedCounts checks guest counts which pass validation with surrounding spaces are stored as entered
+func TestMemoryRepository_PostReservationPaddedCounts(t *testing.T) {
+	db := dbrepository.NewMemoryDBRepository(&app)
+	repo := &Repository{App: &app, DB: db}
+
+	postedData := url.Values{}
+	postedData.Add("first_name", "Joe")
+	postedData.Add("last_name", "Soap")
+	postedData.Add("email", "joe@soap.bar")
+	postedData.Add("phone", "01234 567890")
+	postedData.Add("adults", " 2")
+	postedData.Add("children", "1 ")
+
+	rr := postMemoryReservationForm(context.Background(), repo, postedData)
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/reservation-summary" {
+		t.Fatalf("PostReservation handler returned code: %d (%s), expected code: %d (/reservation-summary)", rr.Code, rr.Header().Get("Location"), http.StatusSeeOther)
+	}
+
+	reservations, err := db.GetAllReservations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reservations) != 1 || reservations[0].Adults != 2 || reservations[0].Children != 1 {
+		t.Errorf("expected a reservation for 2 adults & 1 child to be stored, got %+v", reservations)
+	}
+}
+
+// deleteMemoryBlock moves an owner block to the trash through the admin API
+func deleteMemoryBlock(repo *Repository, id string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("DELETE", "/api/v1/admin/blocks/"+id, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr := httptest.NewRecorder()
+
+	http.HandlerFunc(repo.APIAdminDeleteBlock).ServeHTTP(rr, req)
+
+	return rr
+}
+
+// memoryBlockEvents returns the owner blocks posted to webhooks, oldest first, keyed by event
+func memoryBlockEvents(t *testing.T, db *dbrepository.MemoryDBRepository) map[string][]webhookBlock {
+	deliveries, err := db.GetWebhookDeliveries(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(map[string][]webhookBlock)
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		var payload struct {
+			Event string       `json:"event"`
+			Data  webhookBlock `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(deliveries[i].Payload), &payload); err != nil {
+			t.Fatal(err)
+		}
+		events[payload.Event] = append(events[payload.Event], payload.Data)
+	}
+	return events
+}
+
+// TestMemoryRepository_BlockWebhooks checks an owner block added from the reservations calendar is posted to webhooks with the id by
+// which its removal through the admin API is later posted, together with its room & dates
+func TestMemoryRepository_BlockWebhooks(t *testing.T) {
+	db := dbrepository.NewMemoryDBRepository(&app)
+	repo := &Repository{App: &app, DB: db}
+	if err := db.InsertWebhook(context.Background(), models.Webhook{URL: "https://example.com/hook", Secret: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	postedData := url.Values{}
+	postedData.Add("month", "7")
+	postedData.Add("year", "2098")
+	postedData.Add("blocks", "2")
+	postedData.Add("add_blocked_1_10-07-2098", "1")
+
+	req, _ := http.NewRequest("POST", "/admin/reservations-cal", strings.NewReader(postedData.Encode()))
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, id := range []int{1, 2} {
+		session.Put(ctx, fmt.Sprintf("blocked_map_%d", id), map[string]int{})
+		session.Put(ctx, fmt.Sprintf("reservations_map_%d", id), map[string]int{})
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(repo.AdminPostReservationsCalendar).ServeHTTP(rr, req)
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("AdminPostReservationsCalendar handler returned code: %d, expected code: %d", rr.Code, http.StatusSeeOther)
+	}
+
+	want := webhookBlock{RoomID: 1, Start: "2098-07-10", End: "2098-07-11"}
+	added := memoryBlockEvents(t, db)[webhook.BlockAdded]
+	if len(added) != 1 || added[0].ID == 0 {
+		t.Fatalf("expected one block.added with the block's id, got %+v", added)
+	}
+	want.ID = added[0].ID
+	if added[0] != want {
+		t.Errorf("block.added posted %+v, expected %+v", added[0], want)
+	}
+
+	rr = deleteMemoryBlock(repo, strconv.Itoa(want.ID))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("APIAdminDeleteBlock handler returned code: %d, expected code: %d", rr.Code, http.StatusNoContent)
+	}
+
+	removed := memoryBlockEvents(t, db)[webhook.BlockRemoved]
+	if len(removed) != 1 || removed[0] != want {
+		t.Errorf("block.removed posted %+v, expected %+v", removed, want)
+	}
+}
+
+// TestMemoryRepository_APIDeleteBlock checks removing an owner block through the admin API tells waitlisted guests the room is free, &
+// that a block not found, or already in the trash, is reported as such
+func TestMemoryRepository_APIDeleteBlock(t *testing.T) {
+	db := dbrepository.NewMemoryDBRepository(&app)
+	repo := &Repository{App: &app, DB: db}
+	ctx := context.Background()
+
+	start, end := time.Date(2098, 7, 10, 0, 0, 0, 0, time.UTC), time.Date(2098, 7, 11, 0, 0, 0, 0, time.UTC)
+	block, err := db.CreateRoomBlock(ctx, 1, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.InsertWaitlistEntry(ctx, models.WaitlistEntry{FirstName: "Joe", LastName: "Soap", Email: "joe@soap.bar", StartDate: start, EndDate: end, Adults: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := deleteMemoryBlock(repo, strconv.Itoa(int(block)))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("APIAdminDeleteBlock handler returned code: %d, expected code: %d", rr.Code, http.StatusNoContent)
+	}
+	waitlist, err := db.GetWaitlist(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(waitlist) != 1 || waitlist[0].NotifiedAt.IsZero() {
+		t.Errorf("expected the waitlisted guest to be told the room is free, got %+v", waitlist)
+	}
+
+	for _, id := range []string{strconv.Itoa(int(block)), "999"} {
+		rr = deleteMemoryBlock(repo, id)
+		var body apiError
+		_ = json.Unmarshal(rr.Body.Bytes(), &body)
+		if rr.Code != http.StatusNotFound || body.Error.Code != apiErrNotFound {
+			t.Errorf("APIAdminDeleteBlock handler (block %s) returned code: %d (%s), expected code: %d (%s)", id, rr.Code, body.Error.Code, http.StatusNotFound, apiErrNotFound)
+		}
+	}
+}
+
+// chooseMemoryRoom chooses the General's Quarters from a search for 10-12/06/2099, so holding the room, the request ending when parent
+// does. The session's context is returned for the guest's later requests
+func chooseMemoryRoom(parent context.Context, repo *Repository) (*httptest.ResponseRecorder, context.Context) {
+	reservation := models.Reservation{
+		StartDate: time.Date(2099, 6, 10, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2099, 6, 12, 0, 0, 0, 0, time.UTC),
+		Adults:    2,
+	}
+
+	req, _ := http.NewRequestWithContext(parent, "GET", "/choose-room/1", nil)
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+	req.RequestURI = "/choose-room/1"
+	rr := httptest.NewRecorder()
+
+	session.Put(ctx, "reservation", reservation)
+
+	http.HandlerFunc(repo.ChooseRoom).ServeHTTP(rr, req)
+
+	return rr, ctx
+}
+
+// TestMemoryRepository_InterruptedHold checks a hold, or a quote, whose database operation is cancelled or times out is reported as
+// such, rather than as the database failing
+func TestMemoryRepository_InterruptedHold(t *testing.T) {
+	db := dbrepository.NewMemoryDBRepository(&app)
+	repo := &Repository{App: &app, DB: db}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	rr, _ := chooseMemoryRoom(cancelled, repo)
+	if rr.Body.Len() != 0 || rr.Header().Get("Location") != "" {
+		t.Errorf("ChooseRoom handler (CANCELLED) responded with code: %d (%s), expected no response", rr.Code, rr.Header().Get("Location"))
+	}
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	rr, _ = chooseMemoryRoom(expired, repo)
+	if rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("ChooseRoom handler (TIMED OUT) returned code: %d, expected code: %d with Retry-After", rr.Code, http.StatusServiceUnavailable)
+	}
+
+	available, err := db.SearchAvailabilityByDatesAndRoomID(context.Background(), time.Date(2099, 6, 10, 0, 0, 0, 0, time.UTC), time.Date(2099, 6, 12, 0, 0, 0, 0, time.UTC), 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !available {
+		t.Error("expected no hold to be placed on the room")
+	}
+
+	// a quote timing out once the rooms have been found
+	db.FailOn("GetSeasonalRatesByRoomID", &repository.InterruptedError{Err: context.DeadlineExceeded, Cause: context.DeadlineExceeded})
+	defer db.FailOn("GetSeasonalRatesByRoomID", nil)
+
+	req, _ := http.NewRequest("GET", "/api/v1/availability?start=2099-06-10&end=2099-06-12&guests=2", nil)
+	req = req.WithContext(getCtx(req))
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(repo.APIAvailability).ServeHTTP(rr, req)
+
+	var body apiError
+	_ = json.Unmarshal(rr.Body.Bytes(), &body)
+	if rr.Code != http.StatusServiceUnavailable || body.Error.Code != apiErrTimeout {
+		t.Errorf("APIAvailability handler (QUOTE TIMED OUT) returned code: %d (%s), expected code: %d (%s)", rr.Code, body.Error.Code, http.StatusServiceUnavailable, apiErrTimeout)
+	}
+}
+
+// TestMemoryRepository_ChooseRoom checks a room chosen by one guest is held from the next, & a hold which cannot be placed for any other
+// reason returns the guest to the home page
+func TestMemoryRepository_ChooseRoom(t *testing.T) {
+	db := dbrepository.NewMemoryDBRepository(&app)
+	repo := &Repository{App: &app, DB: db}
+
+	rr, _ := chooseMemoryRoom(context.Background(), repo)
+	if loc := rr.Header().Get("Location"); rr.Code != http.StatusSeeOther || loc != "/make-reservation" {
+		t.Fatalf("ChooseRoom handler returned code: %d (%s), expected code: %d (/make-reservation)", rr.Code, loc, http.StatusSeeOther)
+	}
+
+	// a second guest, in a session of their own, finds the room held
+	rr, _ = chooseMemoryRoom(context.Background(), repo)
+	if loc := rr.Header().Get("Location"); rr.Code != http.StatusSeeOther || loc != "/search-availability" {
+		t.Errorf("ChooseRoom handler (ROOM HELD) returned code: %d (%s), expected code: %d (/search-availability)", rr.Code, loc, http.StatusSeeOther)
+	}
+
+	db.FailOn("PlaceHold", errMemoryDB)
+	rr, _ = chooseMemoryRoom(context.Background(), repo)
+	if loc := rr.Header().Get("Location"); rr.Code != http.StatusSeeOther || loc != "/" {
+		t.Errorf("ChooseRoom handler (HOLD ERROR) returned code: %d (%s), expected code: %d (/)", rr.Code, loc, http.StatusSeeOther)
+	}
+}
+
+// TestMemoryRepository_StayRules checks a single night over the Bank Holiday Weekend, which requires at least 3, is refused when
+// searching, checking a room & reserving it
+func TestMemoryRepository_StayRules(t *testing.T) {
+	db := dbrepository.NewMemoryDBRepository(&app)
+	repo := &Repository{App: &app, DB: db}
+	addMemoryStayRule(t, db)
+
+	// searching every room
+	postedData := url.Values{}
+	postedData.Add("start_date", "02/05/2097")
+	postedData.Add("end_date", "03/05/2097")
+
+	req, _ := http.NewRequest("POST", "/search-availability", strings.NewReader(postedData.Encode()))
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	http.HandlerFunc(repo.PostAvailability).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusSeeOther {
+		t.Errorf("PostAvailability handler (STAY RULE BROKEN) returned code: %d, expected code: %d", rr.Code, http.StatusSeeOther)
+	}
+	if !strings.Contains(session.GetString(ctx, "error"), "Bank Holiday Weekend") {
+		t.Error("PostAvailability handler (STAY RULE BROKEN) did not name the rule broken")
+	}
+
+	// checking a single room
+	postedData.Add("room_id", "1")
+
+	req, _ = http.NewRequest("POST", "/search-availability-modal", strings.NewReader(postedData.Encode()))
+	ctx = getCtx(req)
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+
+	http.HandlerFunc(repo.PostAvailabilityModal).ServeHTTP(rr, req)
+
+	var jr jsonResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &jr)
+	if err != nil {
+		t.Error("PostAvailabilityModal handler failed to parse JSON", err)
+	}
+	if jr.Ok || jr.Rule != "Bank Holiday Weekend" {
+		t.Errorf("PostAvailabilityModal (STAY RULE BROKEN) gave ok %t & rule %q, expected rule Bank Holiday Weekend", jr.Ok, jr.Rule)
+	}
+
+	// reserving a room chosen before the rule was added
+	postedData = url.Values{}
+	postedData.Add("first_name", "Joe")
+	postedData.Add("last_name", "Soap")
+	postedData.Add("email", "joe@soap.bar")
+	postedData.Add("phone", "01234 567890")
+	postedData.Add("adults", "2")
+
+	req, _ = http.NewRequest("POST", "/make-reservation", strings.NewReader(postedData.Encode()))
+	ctx = getCtx(req)
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+
+	session.Put(ctx, "reservation", models.Reservation{
+		RoomID:    2,
+		StartDate: time.Date(2097, 5, 2, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2097, 5, 3, 0, 0, 0, 0, time.UTC),
+		Room:      models.Room{ID: 2, RoomName: "Major's Suite", MaxAdults: 4, MaxChildren: 2},
+	})
+
+	http.HandlerFunc(repo.PostReservation).ServeHTTP(rr, req)
+
+	if loc := rr.Header().Get("Location"); rr.Code != http.StatusSeeOther || loc != "/search-availability" {
+		t.Errorf("PostReservation handler (STAY RULE BROKEN) returned code: %d (%s), expected code: %d (/search-availability)", rr.Code, loc, http.StatusSeeOther)
+	}
+
+	reservations, err := db.GetAllReservations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reservations) != 0 {
+		t.Errorf("expected no reservation to be stored, got %d reservations", len(reservations))
+	}
+}
+
+// withURLParams gives a context carrying the route's URL parameters, given as name & value pairs, as routing would
+func withURLParams(ctx context.Context, params ...string) context.Context {
+	rctx := chi.NewRouteContext()
+	for i := 0; i+1 < len(params); i += 2 {
+		rctx.URLParams.Add(params[i], params[i+1])
+	}
+	return context.WithValue(ctx, chi.RouteCtxKey, rctx)
+}
+
+// TestMemoryRepository_BookingFlow follows a guest from searching availability, through choosing a room & making their reservation, to
+// an administrator updating & confirming it, checking what is stored at each step
+func TestMemoryRepository_BookingFlow(t *testing.T) {
+	db := dbrepository.NewMemoryDBRepository(&app)
+	repo := &Repository{App: &app, DB: db}
+	start, end := time.Date(2099, 6, 10, 0, 0, 0, 0, time.UTC), time.Date(2099, 6, 12, 0, 0, 0, 0, time.UTC)
+
+	// 1. the guest searches, & is offered both rooms
+	postedData := url.Values{}
+	postedData.Add("start_date", "10/06/2099")
+	postedData.Add("end_date", "12/06/2099")
+	postedData.Add("adults", "2")
+
+	req, _ := http.NewRequest("POST", "/search-availability", strings.NewReader(postedData.Encode()))
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(repo.PostAvailability).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("PostAvailability handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+	reservation, ok := session.Get(ctx, "reservation").(models.Reservation)
+	if !ok || !reservation.StartDate.Equal(start) || !reservation.EndDate.Equal(end) || reservation.Adults != 2 {
+		t.Fatalf("expected the search to be kept in the session, got %+v", reservation)
+	}
+
+	// 2. choosing the General's Quarters holds it for the guest
+	req, _ = http.NewRequest("GET", "/choose-room/1", nil)
+	req = req.WithContext(ctx)
+	req.RequestURI = "/choose-room/1"
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(repo.ChooseRoom).ServeHTTP(rr, req)
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/make-reservation" {
+		t.Fatalf("ChooseRoom handler returned code: %d (%s), expected code: %d (/make-reservation)", rr.Code, rr.Header().Get("Location"), http.StatusSeeOther)
+	}
+	token := session.GetString(ctx, "hold_token")
+	restrictions, err := db.GetRoomRestrictionsForCalendar(context.Background(), 1, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == "" || len(restrictions) != 1 || restrictions[0].RestrictionID != models.RestrictionHold || restrictions[0].HoldToken != token {
+		t.Fatalf("expected the room to be held for the guest, got %+v", restrictions)
+	}
+	if available, _ := db.SearchAvailabilityByDatesAndRoomID(context.Background(), start, end, 1, ""); available {
+		t.Error("expected the held room to be unavailable to other guests")
+	}
+
+	// the guest checking the room they are holding again, in the modal or through the API, still finds it available
+	modalData := url.Values{"start_date": {"10/06/2099"}, "end_date": {"12/06/2099"}, "room_id": {"1"}, "adults": {"2"}}
+	req, _ = http.NewRequest("POST", "/search-availability-modal", strings.NewReader(modalData.Encode()))
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(repo.PostAvailabilityModal).ServeHTTP(rr, req)
+	var modal jsonResponse
+	if err = json.Unmarshal(rr.Body.Bytes(), &modal); err != nil || !modal.Ok {
+		t.Errorf("PostAvailabilityModal handler reported the guest's held room as unavailable: %s %v", rr.Body.String(), err)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/v1/availability?start=2099-06-10&end=2099-06-12&guests=2", nil)
+	req = req.WithContext(ctx)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(repo.APIAvailability).ServeHTTP(rr, req)
+	var availability apiAvailability
+	if err = json.Unmarshal(rr.Body.Bytes(), &availability); err != nil || len(availability.Rooms) == 0 || !availability.Rooms[0].Available {
+		t.Errorf("APIAvailability handler reported the guest's held room as unavailable: %s %v", rr.Body.String(), err)
+	}
+
+	// 3. the guest is shown the room they chose, & their reservation takes the room they were holding
+	req, _ = http.NewRequest("GET", "/make-reservation", nil)
+	req = req.WithContext(ctx)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(repo.Reservation).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Reservation handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+
+	postedData = url.Values{}
+	postedData.Add("first_name", "Joe")
+	postedData.Add("last_name", "Soap")
+	postedData.Add("email", "joe@soap.bar")
+	postedData.Add("phone", "01234 567890")
+	postedData.Add("adults", "2")
+
+	req, _ = http.NewRequest("POST", "/make-reservation", strings.NewReader(postedData.Encode()))
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(repo.PostReservation).ServeHTTP(rr, req)
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/reservation-summary" {
+		t.Fatalf("PostReservation handler returned code: %d (%s), expected code: %d (/reservation-summary)", rr.Code, rr.Header().Get("Location"), http.StatusSeeOther)
+	}
+	reservations, err := db.GetAllReservations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reservations) != 1 || reservations[0].RoomID != 1 || reservations[0].Status != models.StatusPending {
+		t.Fatalf("expected one pending reservation of room 1, got %+v", reservations)
+	}
+	id := reservations[0].ID
+	restrictions, err = db.GetRoomRestrictionsForCalendar(context.Background(), 1, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restrictions) != 1 || restrictions[0].RestrictionID != models.RestrictionReservation || restrictions[0].ReservationID != int64(id) {
+		t.Fatalf("expected the hold to become the reservation's restriction, got %+v", restrictions)
+	}
+
+	// 4. an administrator corrects the guest's phone number
+	postedData.Set("phone", "09876 543210")
+	uri := fmt.Sprintf("/admin/reservations/all/%d/page", id)
+	req, _ = http.NewRequest("POST", uri, strings.NewReader(postedData.Encode()))
+	req = req.WithContext(getCtx(req))
+	req.RequestURI = uri
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(repo.AdminPostReservation).ServeHTTP(rr, req)
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/admin/reservations-all" {
+		t.Fatalf("AdminPostReservation handler returned code: %d (%s), expected code: %d (/admin/reservations-all)", rr.Code, rr.Header().Get("Location"), http.StatusSeeOther)
+	}
+	stored, err := db.GetReservationByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Phone != "09876 543210" || stored.FirstName != "Joe" || stored.Status != models.StatusPending {
+		t.Errorf("expected only the phone number to be updated, got %+v", stored)
+	}
+
+	// 5. & confirms the reservation, the change of status being recorded
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/admin/reservation-status/all/%d/confirmed/page", id), nil)
+	req = req.WithContext(withURLParams(getCtx(req), "src", "all", "id", strconv.Itoa(id), "status", "confirmed"))
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(repo.AdminReservationStatus).ServeHTTP(rr, req)
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/admin/reservations-all" {
+		t.Fatalf("AdminReservationStatus handler returned code: %d (%s), expected code: %d (/admin/reservations-all)", rr.Code, rr.Header().Get("Location"), http.StatusSeeOther)
+	}
+	stored, err = db.GetReservationByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := db.GetReservationStatusChanges(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.StatusConfirmed || len(changes) != 1 || changes[0].FromStatus != models.StatusPending || changes[0].ToStatus != models.StatusConfirmed {
+		t.Errorf("expected the reservation to be confirmed, got status %s & changes %+v", stored.Status, changes)
+	}
+}
+
+// errMemoryDB is the failure of a method of the in-memory repository made to fail by FailOn
+var errMemoryDB = errors.New("connection lost")
+
+// newMemoryRepository returns a repository whose handlers use a database held in memory, holding only the two rooms until a test adds
+// to it, together with that database
+func newMemoryRepository() (*dbrepository.MemoryDBRepository, *Repository) {
+	db := dbrepository.NewMemoryDBRepository(&app)
+	return db, &Repository{App: &app, DB: db}
+}
+
+// addMemoryAdministrator adds Peter Barrett, whose API token is bnb_test, returning their id
+func addMemoryAdministrator(t *testing.T, db *dbrepository.MemoryDBRepository) int {
+	id, err := db.AddAdministrator(models.Administrator{FirstName: "Peter", LastName: "Barrett", Email: "peter@barrett.com", AccessLevel: 3}, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.InsertAPIToken(context.Background(), models.APIToken{AdministratorID: id, Name: "Channel manager", TokenHash: apitoken.Hash("bnb_test")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// addMemoryReservation reserves a room for Joe Soap, as a guest would, returning the reservation as stored
+func addMemoryReservation(t *testing.T, db *dbrepository.MemoryDBRepository, rsvn models.Reservation) models.Reservation {
+	rsvn.FirstName, rsvn.LastName, rsvn.Email, rsvn.Phone = "Joe", "Soap", "joe@soap.bar", "01234 567890"
+	if rsvn.Adults == 0 {
+		rsvn.Adults = 2
+	}
+	id, err := db.CreateReservation(context.Background(), rsvn, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := db.GetReservationByID(context.Background(), int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+// addMemoryStayRule adds a Bank Holiday Weekend of 2-5/05/2097 to both rooms, during which a stay must be of 3 to 14 nights
+func addMemoryStayRule(t *testing.T, db *dbrepository.MemoryDBRepository) {
+	for _, roomID := range []int{1, 2} {
+		err := db.InsertStayRule(context.Background(), models.StayRule{
+			RoomID:    roomID,
+			RuleName:  "Bank Holiday Weekend",
+			StartDate: time.Date(2097, 5, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:   time.Date(2097, 5, 5, 0, 0, 0, 0, time.UTC),
+			MinNights: 3,
+			MaxNights: 14,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// reserveMemoryRoomAgain moves reservation 1 to the trash & reserves its room again for the same dates, as another guest would
+func reserveMemoryRoomAgain(t *testing.T, db *dbrepository.MemoryDBRepository) {
+	err := db.DeleteReservation(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addMemoryReservation(t, db, models.Reservation{RoomID: 1, StartDate: time.Date(2098, 1, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2098, 1, 5, 0, 0, 0, 0, time.UTC)})
+}
+
+// bookMemoryBlockedRoom moves an owner block of the Major's Suite, id 2, to the trash & reserves the room over its dates
+func bookMemoryBlockedRoom(t *testing.T, db *dbrepository.MemoryDBRepository) {
+	start, end := time.Date(2098, 1, 10, 0, 0, 0, 0, time.UTC), time.Date(2098, 1, 11, 0, 0, 0, 0, time.UTC)
+	id, err := db.CreateRoomBlock(context.Background(), 2, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.DeleteRoomBlock(context.Background(), int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addMemoryReservation(t, db, models.Reservation{RoomID: 2, StartDate: start, EndDate: end.AddDate(0, 0, 1)})
+}
+
+// adminMemoryActionTests are administrators' actions against a repository held in memory, holding Peter Barrett, who is logged in, & a
+// pending reservation, id 1, of the General's Quarters for 1-5/01/2098. Before each action setup, if any, adds to the repository &
+// the method named by failOn fails with err. Whether the action went wrong is told by the kind of message it leaves in the session
+var adminMemoryActionTests = []struct {
+	name             string
+	handler          func(*Repository, http.ResponseWriter, *http.Request)
+	params           []string
+	postedData       url.Values
+	setup            func(*testing.T, *dbrepository.MemoryDBRepository)
+	failOn           string
+	err              error
+	expectedLocation string
+	expectedMessage  string
+}{
+	{
+		name:             "status-changed-elsewhere",
+		handler:          (*Repository).AdminReservationStatus,
+		params:           []string{"src", "all", "id", "1", "status", "cancelled"},
+		failOn:           "UpdateReservationStatus",
+		err:              &repository.StatusChangedError{ReservationID: 1, Expected: models.StatusPending},
+		expectedLocation: "/admin/reservations-all",
+		expectedMessage:  "warning",
+	},
+	{
+		name:             "status-cannot-update",
+		handler:          (*Repository).AdminReservationStatus,
+		params:           []string{"src", "all", "id", "1", "status", "cancelled"},
+		failOn:           "UpdateReservationStatus",
+		err:              errMemoryDB,
+		expectedLocation: "/admin/reservations-all",
+		expectedMessage:  "error",
+	},
+	{
+		name:             "reservation-room-taken",
+		handler:          (*Repository).AdminReservationRestore,
+		params:           []string{"id", "1"},
+		setup:            reserveMemoryRoomAgain,
+		expectedLocation: "/admin/trash",
+		expectedMessage:  "warning",
+	},
+	{
+		name:             "reservation-cannot-restore",
+		handler:          (*Repository).AdminReservationRestore,
+		params:           []string{"id", "1"},
+		failOn:           "RestoreReservation",
+		err:              errMemoryDB,
+		expectedLocation: "/admin/trash",
+		expectedMessage:  "error",
+	},
+	{
+		name:             "block-room-taken",
+		handler:          (*Repository).AdminRoomBlockRestore,
+		params:           []string{"id", "2"},
+		setup:            bookMemoryBlockedRoom,
+		expectedLocation: "/admin/trash",
+		expectedMessage:  "warning",
+	},
+	{
+		name:             "block-not-in-trash",
+		handler:          (*Repository).AdminRoomBlockRestore,
+		params:           []string{"id", "9999"},
+		expectedLocation: "/admin/trash",
+		expectedMessage:  "error",
+	},
+	{
+		name:             "season-unknown-room",
+		handler:          (*Repository).AdminPostSeasonalRate,
+		postedData:       url.Values{"room_id": {"3"}, "season_name": {"Christmas"}, "start_date": {"20/12/2026"}, "end_date": {"02/01/2027"}, "nightly_rate": {"120.00"}},
+		expectedLocation: "/admin/rates",
+		expectedMessage:  "error",
+	},
+	{
+		name:             "stay-rule-unknown-room",
+		handler:          (*Repository).AdminPostStayRule,
+		postedData:       url.Values{"room_id": {"3"}, "rule_name": {"Christmas"}, "start_date": {"20/12/2026"}, "end_date": {"02/01/2027"}, "min_nights": {"4"}},
+		expectedLocation: "/admin/stay-rules",
+		expectedMessage:  "error",
+	},
+	{
+		name:             "waitlist-cannot-delete",
+		handler:          (*Repository).AdminWaitlistDelete,
+		params:           []string{"id", "1"},
+		failOn:           "DeleteWaitlistEntry",
+		err:              errMemoryDB,
+		expectedLocation: "/admin/waitlist",
+		expectedMessage:  "error",
+	},
+	{
+		name:             "ical-feed-unknown-room",
+		handler:          (*Repository).AdminPostICalFeed,
+		postedData:       url.Values{"room_id": {"3"}, "private": {"1"}},
+		expectedLocation: "/admin/ical-feeds",
+		expectedMessage:  "error",
+	},
+	{
+		name:             "ical-feed-cannot-revoke",
+		handler:          (*Repository).AdminICalFeedDelete,
+		params:           []string{"id", "1"},
+		failOn:           "DeleteICalFeed",
+		err:              errMemoryDB,
+		expectedLocation: "/admin/ical-feeds",
+		expectedMessage:  "error",
+	},
+	{
+		name:             "ical-import-unknown-room",
+		handler:          (*Repository).AdminPostICalImport,
+		postedData:       url.Values{"room_id": {"3"}, "name": {"Airbnb"}, "url": {"https://www.airbnb.co.uk/calendar/ical/123.ics"}},
+		expectedLocation: "/admin/ical-imports",
+		expectedMessage:  "error",
+	},
+	{
+		name:             "ical-import-cannot-delete",
+		handler:          (*Repository).AdminICalImportDelete,
+		params:           []string{"id", "1"},
+		failOn:           "DeleteICalImport",
+		err:              errMemoryDB,
+		expectedLocation: "/admin/ical-imports",
+		expectedMessage:  "error",
+	},
+	{
+		name:             "api-tokens-cannot-get",
+		handler:          (*Repository).AdminAPITokens,
+		failOn:           "GetAPITokensByAdministratorID",
+		err:              errMemoryDB,
+		expectedLocation: "/admin/dashboard",
+		expectedMessage:  "error",
+	},
+	{
+		name:             "api-token-cannot-insert",
+		handler:          (*Repository).AdminPostAPIToken,
+		postedData:       url.Values{"name": {"Channel manager"}},
+		failOn:           "InsertAPIToken",
+		err:              errMemoryDB,
+		expectedLocation: "/admin/api-tokens",
+		expectedMessage:  "error",
+	},
+	{
+		name:             "api-token-cannot-revoke",
+		handler:          (*Repository).AdminAPITokenDelete,
+		params:           []string{"id", "1"},
+		failOn:           "DeleteAPIToken",
+		err:              errMemoryDB,
+		expectedLocation: "/admin/api-tokens",
+		expectedMessage:  "error",
+	},
+	{
+		name:             "webhook-cannot-insert",
+		handler:          (*Repository).AdminPostWebhook,
+		postedData:       url.Values{"url": {"https://example.com/hooks"}},
+		failOn:           "InsertWebhook",
+		err:              errMemoryDB,
+		expectedLocation: "/admin/webhooks",
+		expectedMessage:  "error",
+	},
+	{
+		name:             "webhook-cannot-delete",
+		handler:          (*Repository).AdminWebhookDelete,
+		params:           []string{"id", "1"},
+		failOn:           "DeleteWebhook",
+		err:              errMemoryDB,
+		expectedLocation: "/admin/webhooks",
+		expectedMessage:  "error",
+	},
+	{
+		name:             "delivery-not-failed",
+		handler:          (*Repository).AdminWebhookDeliveryRetry,
+		params:           []string{"id", "1"},
+		expectedLocation: "/admin/webhooks",
+		expectedMessage:  "warning",
+	},
+	{
+		name:             "delivery-cannot-retry",
+		handler:          (*Repository).AdminWebhookDeliveryRetry,
+		params:           []string{"id", "1"},
+		failOn:           "RetryWebhookDelivery",
+		err:              errMemoryDB,
+		expectedLocation: "/admin/webhooks",
+		expectedMessage:  "error",
+	},
+	{
+		name:             "channel-cannot-save",
+		handler:          (*Repository).AdminPostChannelRooms,
+		postedData:       url.Values{"room_code_1": {"GQ"}},
+		failOn:           "SaveChannelRooms",
+		err:              errMemoryDB,
+		expectedLocation: "/admin/channel",
+		expectedMessage:  "error",
+	},
+}
+
+func TestMemoryRepository_AdminActions(t *testing.T) {
+	for _, v := range adminMemoryActionTests {
+		db, repo := newMemoryRepository()
+		adminID := addMemoryAdministrator(t, db)
+		addMemoryReservation(t, db, models.Reservation{RoomID: 1, StartDate: time.Date(2098, 1, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2098, 1, 5, 0, 0, 0, 0, time.UTC)})
+		if v.setup != nil {
+			v.setup(t, db)
+		}
+		if v.failOn != "" {
+			db.FailOn(v.failOn, v.err)
+		}
+
+		req, _ := http.NewRequest("GET", "/admin", nil)
+		if v.postedData != nil {
+			req, _ = http.NewRequest("POST", "/admin", strings.NewReader(v.postedData.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		ctx := getCtx(req)
+		session.Put(ctx, "admin_id", adminID)
+		req = req.WithContext(withURLParams(ctx, v.params...))
+		rr := httptest.NewRecorder()
+
+		v.handler(repo, rr, req)
+
+		if loc := rr.Header().Get("Location"); rr.Code != http.StatusSeeOther || loc != v.expectedLocation {
+			t.Errorf("%s: returned code: %d (%s), expected code: %d (%s)", v.name, rr.Code, loc, http.StatusSeeOther, v.expectedLocation)
+		}
+		for _, kind := range []string{"flash", "warning", "error"} {
+			if session.Exists(ctx, kind) != (kind == v.expectedMessage) {
+				t.Errorf("%s: left the session's %s as %q, expected only a %s", v.name, kind, session.GetString(ctx, kind), v.expectedMessage)
+			}
+		}
+	}
+}
+
+// restoreMemoryTrash takes the reservation & owner block given back out of the trash through the admin handlers, calling check after
+// each restore with the handler's name
+func restoreMemoryTrash(t *testing.T, repo *Repository, rsvnID, blockID int, check func(name string)) {
+	for _, v := range []struct {
+		name    string
+		handler func(*Repository, http.ResponseWriter, *http.Request)
+		id      int
+	}{
+		{"AdminReservationRestore", (*Repository).AdminReservationRestore, rsvnID},
+		{"AdminRoomBlockRestore", (*Repository).AdminRoomBlockRestore, blockID},
+	} {
+		req, _ := http.NewRequest("GET", "/admin", nil)
+		ctx := getCtx(req)
+		req = req.WithContext(withURLParams(ctx, "id", strconv.Itoa(v.id)))
+		rr := httptest.NewRecorder()
+
+		v.handler(repo, rr, req)
+
+		if !session.Exists(ctx, "flash") {
+			t.Fatalf("%s: did not restore id %d, left the session's error as %q", v.name, v.id, session.GetString(ctx, "error"))
+		}
+		check(v.name)
+	}
+}
+
+// trashMemoryBooking reserves room 1 & blocks room 2 for 1-5/01/2098, moving both to the trash, returning their ids
+func trashMemoryBooking(t *testing.T, db *dbrepository.MemoryDBRepository) (int, int) {
+	start, end := time.Date(2098, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2098, 1, 5, 0, 0, 0, 0, time.UTC)
+	rsvn := addMemoryReservation(t, db, models.Reservation{RoomID: 1, StartDate: start, EndDate: end})
+	blockID, err := db.CreateRoomBlock(context.Background(), 2, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteReservation(context.Background(), rsvn.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteRoomBlock(context.Background(), int(blockID)); err != nil {
+		t.Fatal(err)
+	}
+	return rsvn.ID, int(blockID)
+}
+
+// TestMemoryRepository_RestoreChannelSync checks restoring a reservation or owner block from the trash asks for the channel to be
+// synced straight away, whereas posting a webhook does not
+func TestMemoryRepository_RestoreChannelSync(t *testing.T) {
+	db, repo := newMemoryRepository()
+	rsvnID, blockID := trashMemoryBooking(t, db)
+
+	drain := func() {
+		select {
+		case <-ChannelSyncRequests():
+		default:
+		}
+	}
+	drain()
+	restoreMemoryTrash(t, repo, rsvnID, blockID, func(name string) {
+		select {
+		case <-ChannelSyncRequests():
+		default:
+			t.Errorf("%s: did not request a channel sync", name)
+		}
+	})
+
+	drain()
+	repo.publish(webhook.BlockAdded, newWebhookBlock(blockID, 2, time.Date(2098, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2098, 1, 5, 0, 0, 0, 0, time.UTC)))
+	select {
+	case <-ChannelSyncRequests():
+		t.Error("publishing a webhook requested a channel sync")
+	default:
+	}
+}
+
+// TestMemoryRepository_RestoreWebhooks checks restoring a reservation or owner block from the trash is posted to webhooks, as
+// created or added once more, so that receivers told of its removal no longer treat its room as free
+func TestMemoryRepository_RestoreWebhooks(t *testing.T) {
+	db, repo := newMemoryRepository()
+	if err := db.InsertWebhook(context.Background(), models.Webhook{URL: "https://example.com/hook", Secret: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	rsvnID, blockID := trashMemoryBooking(t, db)
+
+	restoreMemoryTrash(t, repo, rsvnID, blockID, func(string) {})
+
+	events := memoryBlockEvents(t, db)
+	if created := events[webhook.ReservationCreated]; len(created) != 1 || created[0].ID != rsvnID || created[0].RoomID != 1 {
+		t.Errorf("reservation.created posted %+v, expected reservation %d of room 1", created, rsvnID)
+	}
+	want := webhookBlock{ID: blockID, RoomID: 2, Start: "2098-01-01", End: "2098-01-05"}
+	if added := events[webhook.BlockAdded]; len(added) != 1 || added[0] != want {
+		t.Errorf("block.added posted %+v, expected %+v", added, want)
+	}
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/StratoNET/bnb-bookings/internal/lifecycle"
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/openapi"
+	"github.com/StratoNET/bnb-bookings/internal/render"
+)
+
+// apiSpec is the OpenAPI document describing the JSON API, its schemas generated from the same types the API handlers encode & decode
+var apiSpec = newAPISpec()
+
+// apiBearerAuth is the security requirement of every admin API operation
+var apiBearerAuth = []map[string][]string{{"bearerAuth": {}}}
+
+func newAPISpec() *openapi.Document {
+	spec := openapi.New(openapi.Info{
+		Title:       "Eden House Bed & Breakfast API",
+		Description: "Room availability & prices for partner sites, together with reservation & owner block administration for administrators' scripts & integrations. Dates are given as YYYY-MM-DD & amounts in pence.",
+		Version:     "1.0",
+	}, openapi.Server{URL: "/api/v1"})
+
+	spec.Components.SecuritySchemes["bearerAuth"] = openapi.SecurityScheme{
+		Type:        "http",
+		Scheme:      "bearer",
+		Description: "An API token, created by an administrator on the admin API tokens page",
+	}
+
+	errorSchema := spec.AddSchema("Error", "Every error response, the code identifying the kind of problem", apiError{})
+	availability := spec.AddSchema("Availability", "The availability & price of every room for a stay", apiAvailability{})
+	calendar := spec.AddSchema("RoomCalendar", "The status of each day of a room's calendar, from the first day of the start month to the last day of the end month", apiCalendar{})
+	reservation := spec.AddSchema("Reservation", "A reservation, with its guest, room, dates & status", apiReservation{})
+	reservationList := spec.AddSchema("ReservationList", "Reservations, ordered by arrival", apiReservationList{})
+	guestUpdate := spec.AddSchema("GuestUpdate", "A reservation's guest details", apiGuestUpdate{})
+	statusChange := spec.AddSchema("StatusChange", "A new status for a reservation", apiStatusChange{})
+	block := spec.AddSchema("Block", "An owner block, closing a room from its start to its end date inclusive", apiBlock{})
+
+	// statuses & reasons are the only values the handlers give these fields
+	var statuses []string
+	for _, s := range lifecycle.All() {
+		statuses = append(statuses, string(s))
+	}
+	spec.Components.Schemas["Reservation"].Properties["status"].Enum = statuses
+	spec.Components.Schemas["StatusChange"].Properties["status"].Enum = statuses
+	rooms := spec.Components.Schemas["Availability"].Properties["rooms"]
+	rooms.Items.Properties["reason"].Enum = []string{apiReasonBooked, apiReasonCapacity, apiReasonStayRule}
+	days := spec.Components.Schemas["RoomCalendar"].Properties["days"]
+	days.Items.Properties["status"].Enum = []string{apiDayAvailable, apiDayBooked, apiDayBlocked, apiDayArrivalOnly, apiDayDepartureOnly}
+
+	errorResponse := func(description string) openapi.Response {
+		return openapi.Response{Description: description, Content: openapi.JSON(errorSchema)}
+	}
+	date := &openapi.Schema{Type: "string", Format: "date"}
+	month := &openapi.Schema{Type: "string", Description: "A month in the form YYYY-MM"}
+	integer := &openapi.Schema{Type: "integer"}
+	idParameter := openapi.Parameter{Name: "id", In: "path", Required: true, Schema: integer}
+
+	spec.AddOperation("/availability", http.MethodGet, &openapi.Operation{
+		OperationID: "getAvailability",
+		Summary:     "Search availability",
+		Description: "Returns every room, whether it is available for the stay & if not why, together with its price. Partner sites may call this from the browser.",
+		Tags:        []string{"availability"},
+		Parameters: []openapi.Parameter{
+			{Name: "start", In: "query", Description: "Arrival date, today or later", Required: true, Schema: date},
+			{Name: "end", In: "query", Description: fmt.Sprintf("Departure date, after arrival & no more than %d nights later", maxAPINights), Required: true, Schema: date},
+			{Name: "guests", In: "query", Description: fmt.Sprintf("Number of adults & children, from 1 to %d", maxPartySize), Required: true, Schema: integer},
+			{Name: "children", In: "query", Description: "How many of the guests are children, at least one guest must be an adult", Schema: integer},
+			{Name: "infants", In: "query", Description: "Number of infants, counted separately from guests", Schema: integer},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Availability of every room", Content: openapi.JSON(availability)},
+			"400": errorResponse("A parameter is missing or not valid"),
+			"500": errorResponse("Availability could not be searched"),
+			"503": errorResponse("The database took too long to respond, try again after the Retry-After header's seconds"),
+		},
+	})
+
+	spec.AddOperation("/rooms/{id}/calendar", http.MethodGet, &openapi.Operation{
+		OperationID: "getRoomCalendar",
+		Summary:     "Get a room's calendar",
+		Description: "Returns the status of each day of whole months, so that date pickers can disable the days on which a stay cannot start or end. A stay can arrive on an available or arrival-only day & depart on an available or departure-only day. Partner sites may call this from the browser.",
+		Tags:        []string{"availability"},
+		Parameters: []openapi.Parameter{
+			idParameter,
+			{Name: "start", In: "query", Description: "First month", Required: true, Schema: month},
+			{Name: "end", In: "query", Description: fmt.Sprintf("Last month, defaulting to the start month, giving no more than %d months in all", maxCalendarMonths), Schema: month},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The room's calendar", Content: openapi.JSON(calendar)},
+			"400": errorResponse("A parameter is missing or not valid"),
+			"404": errorResponse("The room does not exist"),
+			"500": errorResponse("The calendar could not be got"),
+			"503": errorResponse("The database took too long to respond, try again after the Retry-After header's seconds"),
+		},
+	})
+
+	spec.AddOperation("/admin/reservations", http.MethodGet, &openapi.Operation{
+		OperationID: "listReservations",
+		Summary:     "List reservations",
+		Description: "Returns reservations, other than those in the trash, optionally filtered. Start & end select stays overlapping those dates, either may be given alone.",
+		Tags:        []string{"admin"},
+		Security:    apiBearerAuth,
+		Parameters: []openapi.Parameter{
+			{Name: "status", In: "query", Description: "Only reservations with this status", Schema: &openapi.Schema{Type: "string", Enum: statuses}},
+			{Name: "room_id", In: "query", Description: "Only reservations of this room", Schema: integer},
+			{Name: "start", In: "query", Description: "Only stays ending on or after this date", Schema: date},
+			{Name: "end", In: "query", Description: "Only stays starting on or before this date", Schema: date},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The reservations", Content: openapi.JSON(reservationList)},
+			"400": errorResponse("A parameter is not valid"),
+			"401": errorResponse("No valid API token was given"),
+			"500": errorResponse("Reservations could not be got"),
+			"503": errorResponse("The database took too long to respond, try again after the Retry-After header's seconds"),
+		},
+	})
+
+	spec.AddOperation("/admin/reservations/{id}", http.MethodGet, &openapi.Operation{
+		OperationID: "getReservation",
+		Summary:     "Get a reservation",
+		Tags:        []string{"admin"},
+		Security:    apiBearerAuth,
+		Parameters:  []openapi.Parameter{idParameter},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The reservation", Content: openapi.JSON(reservation)},
+			"401": errorResponse("No valid API token was given"),
+			"404": errorResponse("There is no such reservation"),
+			"500": errorResponse("The reservation could not be got"),
+			"503": errorResponse("The database took too long to respond, try again after the Retry-After header's seconds"),
+		},
+	})
+
+	spec.AddOperation("/admin/reservations/{id}", http.MethodPut, &openapi.Operation{
+		OperationID: "updateReservation",
+		Summary:     "Update a reservation's guest",
+		Description: "Replaces the guest's name, email & phone, validated as on the admin reservation page.",
+		Tags:        []string{"admin"},
+		Security:    apiBearerAuth,
+		Parameters:  []openapi.Parameter{idParameter},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(guestUpdate)},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The updated reservation", Content: openapi.JSON(reservation)},
+			"400": errorResponse("The body is not valid, any invalid fields are listed"),
+			"401": errorResponse("No valid API token was given"),
+			"404": errorResponse("There is no such reservation"),
+			"500": errorResponse("The reservation could not be updated"),
+			"503": errorResponse("The database took too long to respond, try again after the Retry-After header's seconds"),
+		},
+	})
+
+	spec.AddOperation("/admin/reservations/{id}", http.MethodDelete, &openapi.Operation{
+		OperationID: "deleteReservation",
+		Summary:     "Delete a reservation",
+		Description: "Moves the reservation to the trash, freeing its room. Any waitlisted guests the room is now free for are told.",
+		Tags:        []string{"admin"},
+		Security:    apiBearerAuth,
+		Parameters:  []openapi.Parameter{idParameter},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The reservation is in the trash"},
+			"401": errorResponse("No valid API token was given"),
+			"404": errorResponse("There is no such reservation"),
+			"500": errorResponse("The reservation could not be deleted"),
+			"503": errorResponse("The database took too long to respond, try again after the Retry-After header's seconds"),
+		},
+	})
+
+	spec.AddOperation("/admin/reservations/{id}/status", http.MethodPost, &openapi.Operation{
+		OperationID: "changeReservationStatus",
+		Summary:     "Change a reservation's status",
+		Description: "Moves the reservation on to a status allowed from its current status. Cancelling frees its room, telling any waitlisted guests.",
+		Tags:        []string{"admin"},
+		Security:    apiBearerAuth,
+		Parameters:  []openapi.Parameter{idParameter},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(statusChange)},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The reservation with its new status", Content: openapi.JSON(reservation)},
+			"400": errorResponse("The body is not valid or the status is unknown"),
+			"401": errorResponse("No valid API token was given"),
+			"404": errorResponse("There is no such reservation"),
+			"409": errorResponse("The status cannot be changed to that given, or was changed elsewhere meanwhile"),
+			"500": errorResponse("The status could not be changed"),
+			"503": errorResponse("The database took too long to respond, try again after the Retry-After header's seconds"),
+		},
+	})
+
+	spec.AddOperation("/admin/blocks", http.MethodPost, &openapi.Operation{
+		OperationID: "createBlock",
+		Summary:     "Block a room",
+		Description: "Closes a room for the given dates, only if it is free for all of them.",
+		Tags:        []string{"admin"},
+		Security:    apiBearerAuth,
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(block)},
+		Responses: map[string]openapi.Response{
+			"201": {Description: "The new block", Content: openapi.JSON(block)},
+			"400": errorResponse("The body is not valid, any invalid fields are listed"),
+			"401": errorResponse("No valid API token was given"),
+			"404": errorResponse("There is no such room"),
+			"409": errorResponse("The room is already taken for some or all of the dates"),
+			"500": errorResponse("The block could not be created"),
+			"503": errorResponse("The database took too long to respond, try again after the Retry-After header's seconds"),
+		},
+	})
+
+	spec.AddOperation("/admin/blocks/{id}", http.MethodDelete, &openapi.Operation{
+		OperationID: "deleteBlock",
+		Summary:     "Remove a block",
+		Description: "Moves the owner block to the trash, freeing its room.",
+		Tags:        []string{"admin"},
+		Security:    apiBearerAuth,
+		Parameters:  []openapi.Parameter{idParameter},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The block is in the trash"},
+			"401": errorResponse("No valid API token was given"),
+			"404": errorResponse("The id is not valid"),
+			"500": errorResponse("The block could not be removed"),
+			"503": errorResponse("The database took too long to respond, try again after the Retry-After header's seconds"),
+		},
+	})
+
+	return spec
+}
+
+// APISpec is the handler for GET /api/openapi.json, returning the OpenAPI document describing the JSON API
+func (m *Repository) APISpec(w http.ResponseWriter, r *http.Request) {
+	// partner sites' tools may fetch the document directly from the browser
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	writeJSON(w, http.StatusOK, apiSpec)
+}
+
+// APIDocs is the handler for GET /api/docs, displaying the OpenAPI document for partner integrators to read
+func (m *Repository) APIDocs(w http.ResponseWriter, r *http.Request) {
+	data := make(map[string]interface{})
+	data["spec"] = apiSpec
+	data["operations"] = apiSpec.Operations()
+
+	render.Template(w, r, "api-docs.page.tmpl", &models.TemplateData{
+		Data: data,
+	})
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/StratoNET/bnb-bookings/internal/openapi"
+	"github.com/go-chi/chi/v5"
+)
+
+// apiSpecRequest is a request of the JSON API, made to check its response is as documented
+type apiSpecRequest struct {
+	name   string
+	method string
+	url    string
+	token  string
+	body   string
+	// routes serve the request, or those of the test repository if nil
+	routes http.Handler
+}
+
+// apiSpecRequests gathers the requests made by the JSON API tests, together with a request without an API token of every
+// operation needing one, so that every response they get can be checked against the OpenAPI document
+func apiSpecRequests(t *testing.T) []apiSpecRequest {
+	var reqs []apiSpecRequest
+	for _, v := range apiAvailabilityErrorTests {
+		reqs = append(reqs, apiSpecRequest{v.name, "GET", "/api/v1/availability?" + v.query, "", "", nil})
+	}
+	for _, v := range apiAvailabilityTests {
+		reqs = append(reqs, apiSpecRequest{v.name, "GET", "/api/v1/availability?" + v.query, "", "", nil})
+	}
+	for _, v := range apiRoomCalendarErrorTests {
+		reqs = append(reqs, apiSpecRequest{v.name, "GET", v.url, "", "", nil})
+	}
+	for _, v := range apiRoomCalendarTests {
+		reqs = append(reqs, apiSpecRequest{v.name, "GET", v.url, "", "", nil})
+	}
+	for _, v := range apiAdminReservationsTests {
+		reqs = append(reqs, apiSpecRequest{v.name, "GET", "/api/v1/admin/reservations?" + v.query, "bnb_test", "", nil})
+	}
+	for _, v := range apiAdminReservationTests {
+		reqs = append(reqs, apiSpecRequest{v.name, v.method, v.url, "bnb_test", v.body, nil})
+	}
+	for _, v := range apiAdminMemoryTests {
+		reqs = append(reqs, apiSpecRequest{v.name, v.method, v.url, "bnb_test", v.body, apiAdminMemoryRoutes(t, v.failOn, v.err)})
+	}
+	for _, op := range apiSpec.Operations() {
+		if op.Security != nil {
+			path := strings.Replace(op.Path, "{id}", "1", 1)
+			reqs = append(reqs, apiSpecRequest{op.OperationID + "-no-token", op.Method, "/api/v1" + path, "", "", nil})
+		}
+	}
+	return reqs
+}
+
+func TestRepository_APISpec(t *testing.T) {
+	routes := getRoutes()
+
+	req, _ := http.NewRequest("GET", "/api/openapi.json", nil)
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("APISpec handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+	if rr.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Error("APISpec handler cannot be called from partner sites")
+	}
+
+	var spec openapi.Document
+	err := json.Unmarshal(rr.Body.Bytes(), &spec)
+	if err != nil {
+		t.Fatal("APISpec handler returned invalid JSON", err)
+	}
+	if spec.OpenAPI != openapi.Version || len(spec.Paths) == 0 || len(spec.Components.Schemas) == 0 {
+		t.Errorf("APISpec handler returned an incomplete document: openapi %q with %d paths & %d schemas", spec.OpenAPI, len(spec.Paths), len(spec.Components.Schemas))
+	}
+}
+
+// TestAPISpec_Routes checks every JSON API route is documented & every documented operation has a route
+func TestAPISpec_Routes(t *testing.T) {
+	routes := make(map[string]bool)
+	err := chi.Walk(getRoutes().(chi.Routes), func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if strings.HasPrefix(route, "/api/v1/") {
+			routes[method+" "+strings.TrimPrefix(route, "/api/v1")] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("cannot walk routes", err)
+	}
+
+	documented := make(map[string]bool)
+	for _, op := range apiSpec.Operations() {
+		documented[op.Method+" "+op.Path] = true
+		if !routes[op.Method+" "+op.Path] {
+			t.Errorf("operation %s (%s %s) is documented but has no route", op.OperationID, op.Method, op.Path)
+		}
+	}
+	for route := range routes {
+		if !documented[route] {
+			t.Errorf("route %s is not documented", route)
+		}
+	}
+}
+
+// TestAPISpec_Responses checks every response the JSON API gives is documented for its operation, with a body matching its schema
+func TestAPISpec_Responses(t *testing.T) {
+	routes := getRoutes()
+	seen := make(map[string]bool)
+
+	for _, v := range apiSpecRequests(t) {
+		req, _ := http.NewRequest(v.method, v.url, strings.NewReader(v.body))
+		if v.token != "" {
+			req.Header.Set("Authorization", "Bearer "+v.token)
+		}
+		rr := httptest.NewRecorder()
+		if v.routes != nil {
+			v.routes.ServeHTTP(rr, req)
+		} else {
+			routes.ServeHTTP(rr, req)
+		}
+
+		// find the documented operation from the pattern of the route the request matched
+		rctx := chi.NewRouteContext()
+		if !routes.(chi.Routes).Match(rctx, v.method, req.URL.Path) {
+			t.Errorf("%s: %s %s matches no route", v.name, v.method, req.URL.Path)
+			continue
+		}
+		path := strings.TrimPrefix(rctx.RoutePattern(), "/api/v1")
+		op := apiSpec.Paths[path][strings.ToLower(v.method)]
+		if op == nil {
+			t.Errorf("%s: %s %s is not documented", v.name, v.method, path)
+			continue
+		}
+
+		status := fmt.Sprint(rr.Code)
+		seen[op.OperationID+" "+status] = true
+		response, ok := op.Responses[status]
+		if !ok {
+			t.Errorf("%s: %s returned status %s, which is not documented", v.name, op.OperationID, status)
+			continue
+		}
+
+		content, hasBody := response.Content["application/json"]
+		if !hasBody {
+			if rr.Body.Len() != 0 {
+				t.Errorf("%s: %s returned a body for status %s, which is documented as having none", v.name, op.OperationID, status)
+			}
+			continue
+		}
+		if rr.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%s: %s returned content type %s, expected application/json", v.name, op.OperationID, rr.Header().Get("Content-Type"))
+		}
+		if err := apiSpec.ValidateJSON(content.Schema, rr.Body.Bytes()); err != nil {
+			t.Errorf("%s: %s returned a %s body not matching its schema: %v", v.name, op.OperationID, status, err)
+		}
+	}
+
+	// responses only given when the database fails or times out cannot all be brought about, every other documented response must be seen
+	for _, op := range apiSpec.Operations() {
+		for status := range op.Responses {
+			if status != "500" && status != "503" && !seen[op.OperationID+" "+status] {
+				t.Errorf("%s response %s is documented but was never returned", op.OperationID, status)
+			}
+		}
+	}
+}
+
+// TestAPISpec_RequiredParameters checks leaving out each documented required query parameter is rejected, naming that parameter
+func TestAPISpec_RequiredParameters(t *testing.T) {
+	routes := getRoutes()
+
+	valid := url.Values{"start": {"2098-01-01"}, "end": {"2098-01-05"}, "guests": {"2"}}
+	op := apiSpec.Paths["/availability"]["get"]
+
+	for _, p := range op.Parameters {
+		if !p.Required {
+			continue
+		}
+		if _, ok := valid[p.Name]; !ok {
+			t.Errorf("required parameter %s is missing from the valid request", p.Name)
+			continue
+		}
+		query := url.Values{}
+		for k, v := range valid {
+			if k != p.Name {
+				query[k] = v
+			}
+		}
+
+		req, _ := http.NewRequest("GET", "/api/v1/availability?"+query.Encode(), nil)
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		var resp apiError
+		_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+		if rr.Code != http.StatusBadRequest || resp.Error.Parameter != p.Name {
+			t.Errorf("leaving out required parameter %s returned code %d naming parameter %q", p.Name, rr.Code, resp.Error.Parameter)
+		}
+	}
+}
+
+func TestRepository_APIDocs(t *testing.T) {
+	routes := getRoutes()
+
+	req, _ := http.NewRequest("GET", "/api/docs", nil)
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("APIDocs handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+	for _, op := range apiSpec.Operations() {
+		if !strings.Contains(rr.Body.String(), `id="`+op.OperationID+`"`) {
+			t.Errorf("APIDocs handler did not document operation %s", op.OperationID)
+		}
+	}
+}
@@ -0,0 +1,509 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/bookingref"
+	"github.com/StratoNET/bnb-bookings/internal/helpers"
+	"github.com/StratoNET/bnb-bookings/internal/lifecycle"
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/pricing"
+	"github.com/StratoNET/bnb-bookings/internal/render"
+	"github.com/StratoNET/bnb-bookings/internal/repository"
+	"github.com/StratoNET/bnb-bookings/internal/stayrules"
+	forms "github.com/StratoNET/bnb-bookings/internal/validation"
+)
+
+// importMaxBytes is the largest CSV file accepted for import, kept in the session between the steps of an import
+const importMaxBytes = 2 << 20
+
+// importField is a reservation field which a CSV file's columns are mapped to. A column is guessed for each field from its heading,
+// matched against the field's name, label & aliases
+type importField struct {
+	Name     string
+	Label    string
+	Required bool
+	Hint     string
+	aliases  []string
+}
+
+// importFields are the fields offered for mapping, in the order they are shown
+var importFields = []importField{
+	{"first_name", "First Name", true, "", []string{"firstname", "forename", "given name"}},
+	{"last_name", "Last Name", true, "", []string{"lastname", "surname", "family name"}},
+	{"email", "Email", true, "", []string{"email address", "e-mail"}},
+	{"phone", "Phone", true, "", []string{"telephone", "tel", "mobile", "phone number"}},
+	{"room", "Room", true, "room name or id", []string{"room name", "room id"}},
+	{"arrival", "Arrival Date", true, "dd/mm/yyyy or yyyy-mm-dd", []string{"arrival", "start", "start date", "check in", "check-in"}},
+	{"departure", "Departure Date", true, "dd/mm/yyyy or yyyy-mm-dd", []string{"departure", "end", "end date", "check out", "check-out"}},
+	{"adults", "Adults", false, "1 if not given", nil},
+	{"children", "Children", false, "none if not given", nil},
+	{"infants", "Infants", false, "none if not given", nil},
+	{"status", "Status", false, "checked out if departed, otherwise confirmed, if not given", nil},
+	{"total", "Total", false, "quoted at current rates if not given", []string{"total price", "price", "amount"}},
+}
+
+// importRow is a row of a CSV file read as a reservation, together with every problem found with it. Line is the row's line in the
+// file, counting the headings as line 1
+type importRow struct {
+	Line        int
+	Reservation models.Reservation
+	Errors      []string
+}
+
+// readImportCSV reads a CSV file's headings & rows, ignoring a byte order mark as written by Excel & any blank rows
+func readImportCSV(data string) ([]string, [][]string, error) {
+	cr := csv.NewReader(strings.NewReader(strings.TrimPrefix(data, "\ufeff")))
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) < 2 {
+		return nil, nil, errors.New("the file has no reservations, expected a row of headings followed by a row for each reservation")
+	}
+
+	var rows [][]string
+	for _, rec := range records[1:] {
+		if strings.TrimSpace(strings.Join(rec, "")) != "" {
+			rows = append(rows, rec)
+		}
+	}
+	return records[0], rows, nil
+}
+
+// guessImportMapping maps each field to the first column whose heading matches it, ignoring case, spaces & underscores
+func guessImportMapping(headings []string) map[string]int {
+	normalise := func(s string) string {
+		return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(s)))
+	}
+
+	mapping := make(map[string]int)
+	for _, f := range importFields {
+		names := append([]string{f.Name, f.Label}, f.aliases...)
+		for i, h := range headings {
+			for _, name := range names {
+				if normalise(h) == normalise(name) {
+					mapping[f.Name] = i
+					break
+				}
+			}
+			if _, ok := mapping[f.Name]; ok {
+				break
+			}
+		}
+	}
+	return mapping
+}
+
+// importMapping reads the column chosen for each field from the mapping form, where "" leaves a field unmapped
+func importMapping(form url.Values, columns int) (map[string]int, error) {
+	mapping := make(map[string]int)
+	for _, f := range importFields {
+		s := form.Get("map_" + f.Name)
+		if s == "" {
+			if f.Required {
+				return nil, fmt.Errorf("please choose the column holding %s", f.Label)
+			}
+			continue
+		}
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 0 || i >= columns {
+			return nil, fmt.Errorf("unknown column chosen for %s", f.Label)
+		}
+		mapping[f.Name] = i
+	}
+	return mapping, nil
+}
+
+// parseImportDate reads a date as the admin pages give them, dd/mm/yyyy, or as spreadsheets often export them, yyyy-mm-dd
+func parseImportDate(s string) (time.Time, error) {
+	for _, layout := range []string{"02/01/2006", "2/1/2006", isoDate} {
+		if d, err := time.Parse(layout, s); err == nil {
+			return d, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date as dd/mm/yyyy", s)
+}
+
+// parseImportStatus reads a status by name e.g. "checked-out" or as shown on the admin pages e.g. "Checked Out"
+func parseImportStatus(s string) (models.ReservationStatus, error) {
+	for _, status := range lifecycle.All() {
+		if strings.EqualFold(s, string(status)) || strings.EqualFold(s, status.Label()) {
+			return status, nil
+		}
+	}
+	return lifecycle.Parse(s)
+}
+
+// importOverlaps reports whether two stays in the same room clash, by the same overlap rules as SearchAvailabilityByDatesAndRoomID
+func importOverlaps(a, b models.Reservation) bool {
+	return a.RoomID == b.RoomID && !a.StartDate.After(b.EndDate) && !b.StartDate.After(a.EndDate)
+}
+
+// checkImportRows reads every row as a reservation by the mapping, validating it with the same rules as a reservation made by a
+// guest, & checks its room is free, both in the database & of the rows before it. Stay rules are not applied, as an import records
+// bookings already taken. An error is returned only should the database fail
+func (m *Repository) checkImportRows(ctx context.Context, rows [][]string, mapping map[string]int) ([]importRow, error) {
+	rooms, err := m.DB.GetAllRooms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roomsByName := make(map[string]models.Room)
+	roomsByID := make(map[string]models.Room)
+	for _, rm := range rooms {
+		roomsByName[strings.ToLower(rm.RoomName)] = rm
+		roomsByID[strconv.Itoa(rm.ID)] = rm
+	}
+
+	today := calendarDay(time.Now())
+	checked := make([]importRow, len(rows))
+
+	for i, rec := range rows {
+		row := importRow{Line: i + 2}
+
+		values := url.Values{}
+		for field, col := range mapping {
+			if col < len(rec) {
+				values.Set(field, strings.TrimSpace(rec[col]))
+			}
+		}
+		if _, ok := mapping["adults"]; !ok {
+			values.Set("adults", "1")
+		}
+
+		form := forms.NewForm(values)
+		form.RequiredFields("first_name", "last_name", "email", "phone", "room", "arrival", "departure", "adults")
+		form.MinLength("first_name", 2)
+		form.MinLength("last_name", 2)
+		form.MinLength("phone", 6)
+		form.IsEmail("email")
+		form.IsNumberInRange("adults", 1, maxPartySize)
+		for _, field := range []string{"children", "infants"} {
+			if form.HasField(field) {
+				form.IsNumberInRange(field, 0, maxPartySize)
+			}
+		}
+
+		rsvn := models.Reservation{
+			FirstName: values.Get("first_name"),
+			LastName:  values.Get("last_name"),
+			Email:     values.Get("email"),
+			Phone:     values.Get("phone"),
+		}
+		rsvn.Adults, _ = strconv.Atoi(values.Get("adults"))
+		rsvn.Children, _ = strconv.Atoi(values.Get("children"))
+		rsvn.Infants, _ = strconv.Atoi(values.Get("infants"))
+
+		if s := values.Get("room"); s != "" {
+			rm, ok := roomsByName[strings.ToLower(s)]
+			if !ok {
+				rm, ok = roomsByID[s]
+			}
+			if ok {
+				rsvn.RoomID = rm.ID
+				rsvn.Room = rm
+			} else {
+				form.Errors.AddErrMsg("room", fmt.Sprintf("there is no room %q", s))
+			}
+		}
+
+		for _, field := range []string{"arrival", "departure"} {
+			s := values.Get(field)
+			if s == "" {
+				continue
+			}
+			d, err := parseImportDate(s)
+			if err != nil {
+				form.Errors.AddErrMsg(field, err.Error())
+			} else if field == "arrival" {
+				rsvn.StartDate = d
+			} else {
+				rsvn.EndDate = d
+			}
+		}
+		if !rsvn.StartDate.IsZero() && !rsvn.EndDate.IsZero() && !rsvn.EndDate.After(rsvn.StartDate) {
+			form.Errors.AddErrMsg("departure", "the departure date must be after the arrival date")
+		}
+
+		if rsvn.RoomID != 0 && form.ValidForm() && !rsvn.Room.Accommodates(rsvn.Adults, rsvn.Children, rsvn.Infants) {
+			form.Errors.AddErrMsg("room", fmt.Sprintf("%s cannot accommodate %s", rsvn.Room.RoomName, rsvn.Party()))
+		}
+
+		if s := values.Get("status"); s != "" {
+			status, err := parseImportStatus(s)
+			if err != nil {
+				form.Errors.AddErrMsg("status", err.Error())
+			}
+			rsvn.Status = status
+		} else if !rsvn.EndDate.IsZero() && rsvn.EndDate.Before(today) {
+			rsvn.Status = models.StatusCheckedOut
+		} else {
+			rsvn.Status = models.StatusConfirmed
+		}
+
+		if s := values.Get("total"); s != "" {
+			total, err := pricing.ParseAmount(s)
+			if err != nil {
+				form.Errors.AddErrMsg("total", "please give a valid amount e.g. 240.00")
+			}
+			rsvn.TotalPrice = total
+		}
+
+		// fields' errors are listed in the order the fields are offered
+		for _, f := range importFields {
+			for _, msg := range form.Errors[f.Name] {
+				row.Errors = append(row.Errors, fmt.Sprintf("%s: %s", f.Label, msg))
+			}
+		}
+
+		if len(row.Errors) == 0 && values.Get("total") == "" {
+			quote, err := m.quoteRoom(ctx, rsvn.Room, rsvn.StartDate, rsvn.EndDate, rsvn.Adults+rsvn.Children)
+			if err != nil {
+				return nil, err
+			}
+			rsvn.TotalPrice = quote.Total
+		}
+
+		// a cancelled reservation takes no room, so cannot clash
+		if len(row.Errors) == 0 && rsvn.Status != models.StatusCancelled {
+			for _, earlier := range checked[:i] {
+				if len(earlier.Errors) == 0 && earlier.Reservation.Status != models.StatusCancelled && importOverlaps(rsvn, earlier.Reservation) {
+					row.Errors = append(row.Errors, fmt.Sprintf("Room: %s is already taken by line %d", rsvn.Room.RoomName, earlier.Line))
+					break
+				}
+			}
+		}
+		if len(row.Errors) == 0 && rsvn.Status != models.StatusCancelled {
+			available, err := m.DB.SearchAvailabilityByDatesAndRoomID(ctx, rsvn.StartDate, rsvn.EndDate, rsvn.RoomID, "")
+			if err != nil {
+				return nil, err
+			}
+			if !available {
+				row.Errors = append(row.Errors, fmt.Sprintf("Room: %s is not available from %s to %s", rsvn.Room.RoomName,
+					rsvn.StartDate.Format("02/01/2006"), rsvn.EndDate.Format("02/01/2006")))
+			}
+		}
+
+		row.Reservation = rsvn
+		checked[i] = row
+	}
+
+	return checked, nil
+}
+
+// renderImport displays a step of an import: "upload" to choose a file, "map" to choose the column holding each field & "preview"
+// to check every row before it is imported
+func (m *Repository) renderImport(w http.ResponseWriter, r *http.Request, step string, headings []string, mapping map[string]int, rows []importRow) {
+	// mapping is given as strings, so that the template can compare it with each column's index
+	chosen := make(map[string]string)
+	for field, col := range mapping {
+		chosen[field] = strconv.Itoa(col)
+	}
+
+	invalid, nights := 0, 0
+	for _, row := range rows {
+		if len(row.Errors) > 0 {
+			invalid++
+		} else {
+			nights += stayrules.Nights(row.Reservation.StartDate, row.Reservation.EndDate)
+		}
+	}
+
+	stringMap := make(map[string]string)
+	stringMap["step"] = step
+	stringMap["filename"] = m.App.Session.GetString(r.Context(), "import_filename")
+
+	intMap := make(map[string]int)
+	intMap["rows"] = len(rows)
+	intMap["invalid"] = invalid
+	intMap["nights"] = nights
+
+	data := make(map[string]interface{})
+	data["fields"] = importFields
+	data["headings"] = headings
+	data["mapping"] = chosen
+	data["rows"] = rows
+
+	render.Template(w, r, "admin-reservations-import.page.tmpl", &models.TemplateData{
+		StringMap: stringMap,
+		IntMap:    intMap,
+		Data:      data,
+		Form:      forms.NewForm(nil),
+	})
+}
+
+// AdminReservationsImport displays the first step of a CSV import of reservations, choosing the file
+func (m *Repository) AdminReservationsImport(w http.ResponseWriter, r *http.Request) {
+	m.renderImport(w, r, "upload", nil, nil, nil)
+}
+
+// AdminPostReservationsImport reads an uploaded CSV file, keeping it in the session for the steps that follow, & displays its
+// headings to be mapped to reservation fields
+func (m *Repository) AdminPostReservationsImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, importMaxBytes+1<<20)
+	err := r.ParseMultipartForm(importMaxBytes)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0091: cannot read import file, please choose a CSV file of at most 2MB")
+		http.Redirect(w, r, "/admin/reservations-import", http.StatusSeeOther)
+		return
+	}
+
+	file, header, err := r.FormFile("csv")
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0091: cannot read import file, please choose a CSV file of at most 2MB")
+		http.Redirect(w, r, "/admin/reservations-import", http.StatusSeeOther)
+		return
+	}
+	defer file.Close()
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(file, importMaxBytes+1))
+	if err != nil || n > importMaxBytes {
+		m.App.Session.Put(r.Context(), "error", "#0091: cannot read import file, please choose a CSV file of at most 2MB")
+		http.Redirect(w, r, "/admin/reservations-import", http.StatusSeeOther)
+		return
+	}
+
+	headings, _, err := readImportCSV(buf.String())
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("#0092: invalid import file, %s", err))
+		http.Redirect(w, r, "/admin/reservations-import", http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "import_csv", buf.String())
+	m.App.Session.Put(r.Context(), "import_filename", header.Filename)
+
+	m.renderImport(w, r, "map", headings, guessImportMapping(headings), nil)
+}
+
+// importFromSession reads the file kept in the session & the mapping posted with a step of an import, redirecting (& returning false)
+// should either be missing or invalid
+func (m *Repository) importFromSession(w http.ResponseWriter, r *http.Request) ([]string, [][]string, map[string]int, bool) {
+	err := r.ParseForm()
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0093: cannot parse import form")
+		http.Redirect(w, r, "/admin/reservations-import", http.StatusSeeOther)
+		return nil, nil, nil, false
+	}
+
+	data := m.App.Session.GetString(r.Context(), "import_csv")
+	if data == "" {
+		m.App.Session.Put(r.Context(), "warning", "Your import has expired, please choose the file again")
+		http.Redirect(w, r, "/admin/reservations-import", http.StatusSeeOther)
+		return nil, nil, nil, false
+	}
+	headings, rows, err := readImportCSV(data)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("#0092: invalid import file, %s", err))
+		http.Redirect(w, r, "/admin/reservations-import", http.StatusSeeOther)
+		return nil, nil, nil, false
+	}
+
+	mapping, err := importMapping(r.PostForm, len(headings))
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "#0093: "+err.Error())
+		m.renderImport(w, r, "map", headings, guessImportMapping(headings), nil)
+		return nil, nil, nil, false
+	}
+	return headings, rows, mapping, true
+}
+
+// AdminReservationsImportPreview checks every row of an import by the chosen mapping, without importing anything, displaying each
+// row's reservation or the problems found with it
+func (m *Repository) AdminReservationsImportPreview(w http.ResponseWriter, r *http.Request) {
+	headings, rows, mapping, ok := m.importFromSession(w, r)
+	if !ok {
+		return
+	}
+
+	checked, err := m.checkImportRows(r.Context(), rows, mapping)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0094: cannot check import against database")
+		http.Redirect(w, r, "/admin/reservations-import", http.StatusSeeOther)
+		return
+	}
+
+	m.renderImport(w, r, "preview", headings, mapping, checked)
+}
+
+// AdminReservationsImportCommit checks every row of an import again, as rooms may have been booked since the preview, then imports
+// every reservation, with its room restriction, in a single transaction. Nothing is imported should any row have a problem
+func (m *Repository) AdminReservationsImportCommit(w http.ResponseWriter, r *http.Request) {
+	headings, rows, mapping, ok := m.importFromSession(w, r)
+	if !ok {
+		return
+	}
+
+	checked, err := m.checkImportRows(r.Context(), rows, mapping)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		m.App.ErrorLog.Println(err)
+		m.App.Session.Put(r.Context(), "error", "#0094: cannot check import against database")
+		http.Redirect(w, r, "/admin/reservations-import", http.StatusSeeOther)
+		return
+	}
+
+	reservations := make([]models.Reservation, 0, len(checked))
+	for _, row := range checked {
+		if len(row.Errors) > 0 {
+			m.App.Session.Put(r.Context(), "error", "#0095: nothing has been imported, as some rows have problems")
+			m.renderImport(w, r, "preview", headings, mapping, checked)
+			return
+		}
+
+		rsvn := row.Reservation
+		rsvn.ConfirmationRef, err = bookingref.NewRef()
+		if err != nil {
+			helpers.ServerError(w, err)
+			return
+		}
+		reservations = append(reservations, rsvn)
+	}
+
+	ids, err := m.DB.ImportReservations(r.Context(), reservations)
+	if err != nil {
+		if m.dbInterrupted(w, r, err) {
+			return
+		}
+		var unavailable *repository.RoomUnavailableError
+		if errors.As(err, &unavailable) {
+			m.App.Session.Put(r.Context(), "error", fmt.Sprintf("#0096: nothing has been imported, as %s", err))
+		} else {
+			m.App.ErrorLog.Println(err)
+			m.App.Session.Put(r.Context(), "error", "#0096: cannot import reservations into database, nothing has been imported")
+		}
+		m.renderImport(w, r, "preview", headings, mapping, checked)
+		return
+	}
+
+	RequestChannelSync()
+	for _, id := range ids {
+		m.publishCreatedReservation(int(id))
+	}
+
+	m.App.Session.Remove(r.Context(), "import_csv")
+	m.App.Session.Remove(r.Context(), "import_filename")
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("%d reservations have been imported", len(ids)))
+	http.Redirect(w, r, "/admin/reservations-all", http.StatusSeeOther)
+}
@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/repository"
+	"github.com/StratoNET/bnb-bookings/internal/repository/dbrepository"
+)
+
+// importCSV is a spreadsheet of bookings, as exported with Excel's byte order mark, with a blank row
+const importCSV = "\ufeffSurname,First name,E-mail,Tel,Room,Check-in,Check-out,Adults,Status,Price\r\n" +
+	"Soap,Joe,joe@soap.bar,01234 567890,General's Quarters,01/03/2098,05/03/2098,2,,\r\n" +
+	",,,,,,,,,\r\n" +
+	"Doe,Jane,jane@doe.bar,01234 567891,2,2098-03-01,2098-03-03,3,Cancelled,150.00\r\n"
+
+// importMap maps importCSV's columns, as guessed from its headings
+var importMap = url.Values{
+	"map_first_name": {"1"},
+	"map_last_name":  {"0"},
+	"map_email":      {"2"},
+	"map_phone":      {"3"},
+	"map_room":       {"4"},
+	"map_arrival":    {"5"},
+	"map_departure":  {"6"},
+	"map_adults":     {"7"},
+	"map_status":     {"8"},
+	"map_total":      {"9"},
+}
+
+func TestReadImportCSV(t *testing.T) {
+	headings, rows, err := readImportCSV(importCSV)
+	if err != nil {
+		t.Fatal("readImportCSV failed", err)
+	}
+	if len(headings) != 10 || headings[0] != "Surname" {
+		t.Errorf("unexpected headings %q", headings)
+	}
+	if len(rows) != 2 || rows[1][0] != "Doe" {
+		t.Errorf("expected blank rows to be skipped, got %q", rows)
+	}
+
+	for _, data := range []string{"", "Surname,First name\r\n", "Surname,\"First name\r\nSoap,Joe\r\n"} {
+		if _, _, err := readImportCSV(data); err == nil {
+			t.Errorf("expected %q not to be read", data)
+		}
+	}
+}
+
+func TestGuessImportMapping(t *testing.T) {
+	headings, _, _ := readImportCSV(importCSV)
+	mapping := guessImportMapping(headings)
+
+	for field, col := range importMap {
+		name := strings.TrimPrefix(field, "map_")
+		if got, ok := mapping[name]; !ok || strconv.Itoa(got) != col[0] {
+			t.Errorf("%s: expected column %s, got %d (%v)", name, col[0], got, ok)
+		}
+	}
+	if _, ok := guessImportMapping([]string{"Notes"})["first_name"]; ok {
+		t.Error("expected an unknown heading not to be mapped")
+	}
+}
+
+var checkImportRowsTests = []struct {
+	name           string
+	row            []string
+	expectedErrors []string
+	expectedStatus models.ReservationStatus
+}{
+	{"valid", []string{"Soap", "Joe", "joe@soap.bar", "01234 567890", "general's quarters", "01/06/2098", "05/06/2098", "2", "", ""}, nil, models.StatusConfirmed},
+	{"departed", []string{"Soap", "Joe", "joe@soap.bar", "01234 567890", "1", "2020-06-01", "2020-06-05", "2", "", "320"}, nil, models.StatusCheckedOut},
+	{"status-label", []string{"Soap", "Joe", "joe@soap.bar", "01234 567890", "1", "01/07/2098", "05/07/2098", "1", "No Show", ""}, nil, models.StatusNoShow},
+	{"invalid-fields", []string{"S", "Joe", "joe-at-soap", "0123", "Attic", "01/06/2098", "05/06/2098", "0", "lost", "lots"}, []string{
+		"Last Name: this field must be at least 2 characters",
+		"Email: please input a valid email address",
+		"Phone: this field must be at least 6 characters",
+		`Room: there is no room "Attic"`,
+		"Adults: this field must be a whole number from 1",
+		`Status: unknown reservation status "lost"`,
+		"Total: please give a valid amount",
+	}, ""},
+	{"missing-fields", []string{"Soap", "Joe", "", "01234 567890", "1"}, []string{
+		"Email: this field is required",
+		"Email: please input a valid email address",
+		"Arrival Date: this field is required",
+		"Departure Date: this field is required",
+		"Adults: this field is required",
+		"Adults: this field must be a whole number from 1",
+	}, ""},
+	{"bad-dates", []string{"Soap", "Joe", "joe@soap.bar", "01234 567890", "1", "31/02/2098", "05/06/2098", "2", "", ""}, []string{`Arrival Date: "31/02/2098" is not a date`}, ""},
+	{"departs-first", []string{"Soap", "Joe", "joe@soap.bar", "01234 567890", "1", "05/06/2098", "05/06/2098", "2", "", ""}, []string{"Departure Date: the departure date must be after the arrival date"}, ""},
+	{"too-many-guests", []string{"Soap", "Joe", "joe@soap.bar", "01234 567890", "1", "01/08/2098", "05/08/2098", "3", "", ""}, []string{"Room: General's Quarters cannot accommodate 3 adults"}, ""},
+}
+
+func TestRepository_checkImportRows(t *testing.T) {
+	headings, _, _ := readImportCSV(importCSV)
+	mapping := guessImportMapping(headings)
+
+	for _, v := range checkImportRowsTests {
+		checked, err := Repo.checkImportRows(context.Background(), [][]string{v.row}, mapping)
+		if err != nil {
+			t.Fatalf("%s: checkImportRows failed: %v", v.name, err)
+		}
+		row := checked[0]
+		if row.Line != 2 {
+			t.Errorf("%s: expected line 2, got %d", v.name, row.Line)
+		}
+
+		if len(row.Errors) != len(v.expectedErrors) {
+			t.Errorf("%s: expected %d errors, got %q", v.name, len(v.expectedErrors), row.Errors)
+			continue
+		}
+		for i, e := range v.expectedErrors {
+			if !strings.HasPrefix(row.Errors[i], e) {
+				t.Errorf("%s: expected error %q, got %q", v.name, e, row.Errors[i])
+			}
+		}
+		if v.expectedStatus != "" && row.Reservation.Status != v.expectedStatus {
+			t.Errorf("%s: expected status %s, got %s", v.name, v.expectedStatus, row.Reservation.Status)
+		}
+	}
+}
+
+func TestRepository_checkImportRows_Clash(t *testing.T) {
+	headings, _, _ := readImportCSV(importCSV)
+	mapping := guessImportMapping(headings)
+
+	checked, err := Repo.checkImportRows(context.Background(), [][]string{
+		{"Soap", "Joe", "joe@soap.bar", "01234 567890", "1", "01/06/2098", "05/06/2098", "2", "", "320.00"},
+		{"Doe", "Jane", "jane@doe.bar", "01234 567891", "1", "05/06/2098", "07/06/2098", "2", "", ""},
+		{"Roe", "Jim", "jim@roe.bar", "01234 567892", "2", "05/06/2098", "07/06/2098", "2", "", ""},
+	}, mapping)
+	if err != nil {
+		t.Fatal("checkImportRows failed", err)
+	}
+
+	if len(checked[0].Errors) != 0 || checked[0].Reservation.TotalPrice != 32000 || checked[0].Reservation.RoomID != 1 {
+		t.Errorf("unexpected first row %+v", checked[0])
+	}
+	if len(checked[1].Errors) != 1 || checked[1].Errors[0] != "Room: General's Quarters is already taken by line 2" {
+		t.Errorf("expected second row to clash with the first, got %q", checked[1].Errors)
+	}
+	// a total not given is quoted
+	if len(checked[2].Errors) != 0 || checked[2].Reservation.TotalPrice == 0 {
+		t.Errorf("expected third row to be quoted in another room, got %+v", checked[2])
+	}
+
+	// database error
+	_, err = Repo.checkImportRows(context.Background(), [][]string{{"Soap", "Joe", "joe@soap.bar", "01234 567890", "1", "01/01/2099", "05/01/2099", "2", "", ""}}, mapping)
+	if err == nil {
+		t.Error("expected checkImportRows to fail should the database fail")
+	}
+}
+
+func TestRepository_AdminPostReservationsImport(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("csv", "bookings.csv")
+	fw.Write([]byte(importCSV))
+	mw.Close()
+
+	req, _ := http.NewRequest("POST", "/admin/reservations-import", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	ctx := getCtx(req)
+	req = req.WithContext(ctx)
+	rr := httptest.NewRecorder()
+
+	http.HandlerFunc(Repo.AdminPostReservationsImport).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("AdminPostReservationsImport handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+	for _, s := range []string{"bookings.csv", "Check-in", `<option value="5" selected>Check-in</option>`} {
+		if !strings.Contains(rr.Body.String(), s) {
+			t.Errorf("AdminPostReservationsImport handler did not show %q", s)
+		}
+	}
+	if session.GetString(ctx, "import_csv") != importCSV {
+		t.Error("AdminPostReservationsImport handler did not keep the file in the session")
+	}
+
+	// no file, or a file with no reservations
+	for _, data := range []string{"", "Surname,First name\r\n"} {
+		body.Reset()
+		mw = multipart.NewWriter(&body)
+		if data != "" {
+			fw, _ = mw.CreateFormFile("csv", "empty.csv")
+			fw.Write([]byte(data))
+		}
+		mw.Close()
+
+		req, _ = http.NewRequest("POST", "/admin/reservations-import", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req = req.WithContext(getCtx(req))
+		rr = httptest.NewRecorder()
+
+		http.HandlerFunc(Repo.AdminPostReservationsImport).ServeHTTP(rr, req)
+
+		actualLoc, _ := rr.Result().Location()
+		if rr.Code != http.StatusSeeOther || actualLoc.String() != "/admin/reservations-import" {
+			t.Errorf("AdminPostReservationsImport handler (%q) returned code: %d, expected redirect to /admin/reservations-import", data, rr.Code)
+		}
+	}
+}
+
+var adminReservationsImportStepTests = []struct {
+	name               string
+	handler            func(*Repository, http.ResponseWriter, *http.Request)
+	csv                string
+	mapping            url.Values
+	expectedStatusCode int
+	expectedLocation   string
+	expectedContent    string
+}{
+	{"preview", (*Repository).AdminReservationsImportPreview, importCSV, importMap, http.StatusOK, "", "Import 2 Reservations"},
+	{"preview-problems", (*Repository).AdminReservationsImportPreview, importCSV + "Roe,Jim,jim-at-roe,01234 567892,1,01/03/2098,02/03/2098,1,,\r\n", importMap, http.StatusOK, "", "1 of 3 rows have problems"},
+	{"preview-unmapped", (*Repository).AdminReservationsImportPreview, importCSV, url.Values{"map_first_name": {"1"}}, http.StatusOK, "", "choose..."},
+	{"preview-expired", (*Repository).AdminReservationsImportPreview, "", importMap, http.StatusSeeOther, "/admin/reservations-import", ""},
+	{"preview-database-error", (*Repository).AdminReservationsImportPreview, importCSV + "Roe,Jim,jim@roe.bar,01234 567892,1,01/01/2099,02/01/2099,1,,\r\n", importMap, http.StatusSeeOther, "/admin/reservations-import", ""},
+	{"commit", (*Repository).AdminReservationsImportCommit, importCSV, importMap, http.StatusSeeOther, "/admin/reservations-all", ""},
+	{"commit-problems", (*Repository).AdminReservationsImportCommit, importCSV + "Roe,Jim,jim-at-roe,01234 567892,1,01/03/2098,02/03/2098,1,,\r\n", importMap, http.StatusOK, "", "1 of 3 rows have problems"},
+}
+
+func TestRepository_AdminReservationsImportSteps(t *testing.T) {
+	for _, v := range adminReservationsImportStepTests {
+		req, _ := http.NewRequest("POST", "/admin/reservations-import/step", strings.NewReader(v.mapping.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		if v.csv != "" {
+			session.Put(ctx, "import_csv", v.csv)
+		}
+		rr := httptest.NewRecorder()
+
+		v.handler(Repo, rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("%s: returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+			continue
+		}
+		if v.expectedLocation != "" {
+			actualLoc, _ := rr.Result().Location()
+			if actualLoc.String() != v.expectedLocation {
+				t.Errorf("%s: redirected to %s, expected %s", v.name, actualLoc.String(), v.expectedLocation)
+			}
+		}
+		if v.expectedContent != "" && !strings.Contains(rr.Body.String(), v.expectedContent) {
+			t.Errorf("%s: did not show %q", v.name, v.expectedContent)
+		}
+		if v.name == "commit" && session.Exists(ctx, "import_csv") {
+			t.Error("commit: left the imported file in the session")
+		}
+	}
+}
+
+// TestMemoryRepository_checkImportRows checks a row is refused a room already reserved for its dates, unless it is itself cancelled
+func TestMemoryRepository_checkImportRows(t *testing.T) {
+	db, repo := newMemoryRepository()
+	addMemoryReservation(t, db, models.Reservation{RoomID: 1, StartDate: time.Date(2098, 12, 25, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2098, 12, 27, 0, 0, 0, 0, time.UTC)})
+
+	headings, _, _ := readImportCSV(importCSV)
+	mapping := guessImportMapping(headings)
+
+	checked, err := repo.checkImportRows(context.Background(), [][]string{
+		{"Roe", "Jim", "jim@roe.bar", "01234 567892", "1", "25/12/2098", "27/12/2098", "2", "", ""},
+		{"Roe", "Jim", "jim@roe.bar", "01234 567892", "1", "25/12/2098", "27/12/2098", "2", "cancelled", ""},
+	}, mapping)
+	if err != nil {
+		t.Fatal("checkImportRows failed", err)
+	}
+
+	expected := "Room: General's Quarters is not available from 25/12/2098 to 27/12/2098"
+	if len(checked[0].Errors) != 1 || !strings.HasPrefix(checked[0].Errors[0], expected) {
+		t.Errorf("unavailable: expected error %q, got %q", expected, checked[0].Errors)
+	}
+	if len(checked[1].Errors) != 0 || checked[1].Reservation.Status != models.StatusCancelled {
+		t.Errorf("cancelled-unavailable: expected a cancelled reservation without errors, got %s & %q", checked[1].Reservation.Status, checked[1].Errors)
+	}
+}
+
+// TestMemoryRepository_AdminReservationsImportCommit checks an import is stored wholly, or not at all should a room be taken after the
+// preview but before the commit
+func TestMemoryRepository_AdminReservationsImportCommit(t *testing.T) {
+	commit := func(repo *Repository) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/admin/reservations-import/commit", strings.NewReader(importMap.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		ctx := getCtx(req)
+		req = req.WithContext(ctx)
+		session.Put(ctx, "import_csv", importCSV)
+		rr := httptest.NewRecorder()
+		repo.AdminReservationsImportCommit(rr, req)
+		return rr
+	}
+	stored := func(db *dbrepository.MemoryDBRepository) int {
+		reservations, err := db.GetAllReservations(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(reservations)
+	}
+
+	db, repo := newMemoryRepository()
+	db.FailOn("ImportReservations", &repository.RoomUnavailableError{
+		RoomID:    1,
+		StartDate: time.Date(2098, 3, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2098, 3, 5, 0, 0, 0, 0, time.UTC),
+	})
+
+	rr := commit(repo)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "#0096: nothing has been imported, as room 1 is no longer available") {
+		t.Errorf("commit-taken-since: returned code: %d, expected code: %d saying nothing has been imported", rr.Code, http.StatusOK)
+	}
+	if n := stored(db); n != 0 {
+		t.Errorf("commit-taken-since: expected no reservations to be stored, got %d", n)
+	}
+
+	db.FailOn("ImportReservations", nil)
+	rr = commit(repo)
+	if loc := rr.Header().Get("Location"); rr.Code != http.StatusSeeOther || loc != "/admin/reservations-all" {
+		t.Errorf("commit: returned code: %d (%s), expected code: %d (/admin/reservations-all)", rr.Code, loc, http.StatusSeeOther)
+	}
+	if n := stored(db); n != 2 {
+		t.Errorf("commit: expected 2 reservations to be stored, got %d", n)
+	}
+}
+
+func TestRepository_AdminReservationsImport(t *testing.T) {
+	routes := getRoutes()
+
+	req, _ := http.NewRequest("GET", "/admin/reservations-import", nil)
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `enctype="multipart/form-data"`) {
+		t.Errorf("AdminReservationsImport handler returned code: %d, expected the upload form", rr.Code)
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/StratoNET/bnb-bookings/internal/config"
 	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/pricing"
 	"github.com/StratoNET/bnb-bookings/internal/render"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-chi/chi/v5"
@@ -26,6 +27,8 @@ var pathToTemplates = "./../../templates"
 var functions = template.FuncMap{
 	"dateUK":      render.DateUK,
 	"iterateDays": render.IterateDays,
+	"currency":    pricing.FormatAmount,
+	"pounds":      pricing.Pounds,
 }
 
 func TestMain(m *testing.M) {
@@ -35,11 +38,27 @@ func TestMain(m *testing.M) {
 	gob.Register(models.Room{})
 	gob.Register(models.RoomRestriction{})
 	gob.Register(models.RestrictionCategory{})
+	gob.Register(models.Quote{})
+	gob.Register(models.WaitlistEntry{})
+	gob.Register(models.BookingGroup{})
 	gob.Register(map[string]int{})
 
 	// set development / production mode
 	app.ProductionMode = false
 
+	// guests manage their own bookings via signed links
+	app.SiteURL = "http://localhost:8080"
+	app.BookingKey = []byte("test-booking-key")
+	app.CancellationDays = 7
+
+	// deleted reservations & owner blocks stay in the trash for a month
+	app.TrashRetentionDays = 30
+	app.HoldMinutes = 15
+
+	// no channel manager is configured, tests point the channel at a stub of their own
+	app.ChannelSyncMinutes = 15
+	app.ChannelDays = 365
+
 	// create InfoLog & ErrorLog, making them available throughout application via config
 	infoLog := log.New(os.Stdout, "\033[36;1mINFO\033[0;0m\t", log.Ldate|log.Ltime)
 	app.InfoLog = infoLog
@@ -86,7 +105,13 @@ func mailListener() {
 	}()
 }
 
+// getRoutes routes requests to the handlers of the test repository
 func getRoutes() http.Handler {
+	return routesFor(Repo)
+}
+
+// routesFor routes requests to the handlers of the given repository, e.g. one holding its data in memory
+func routesFor(repo *Repository) http.Handler {
 
 	mux := chi.NewRouter()
 
@@ -97,35 +122,101 @@ func getRoutes() http.Handler {
 	mux.Use(SessionLoad)
 	//===========================
 
-	mux.Get("/", Repo.Index)
-	mux.Get("/about", Repo.About)
-	mux.Get("/gq", Repo.GQ)
-	mux.Get("/ms", Repo.MS)
-
-	mux.Get("/search-availability", Repo.Availability)
-	mux.Post("/search-availability", Repo.PostAvailability)
-	mux.Post("/search-availability-modal", Repo.PostAvailabilityModal)
-
-	mux.Get("/make-reservation", Repo.Reservation)
-	mux.Post("/make-reservation", Repo.PostReservation)
-	mux.Get("/reservation-summary", Repo.ReservationSummary)
-
-	mux.Get("/contact", Repo.Contact)
-
-	mux.Get("/login", Repo.Login)
-	mux.Get("/logout", Repo.Logout)
-	mux.Post("/login", Repo.PostLogin)
+	mux.Get("/", repo.Index)
+	mux.Get("/about", repo.About)
+	mux.Get("/gq", repo.GQ)
+	mux.Get("/ms", repo.MS)
+
+	mux.Get("/search-availability", repo.Availability)
+	mux.Post("/search-availability", repo.PostAvailability)
+	mux.Post("/search-availability-modal", repo.PostAvailabilityModal)
+
+	mux.Get("/make-reservation", repo.Reservation)
+	mux.Post("/make-reservation", repo.PostReservation)
+	mux.Get("/reservation-summary", repo.ReservationSummary)
+	mux.Get("/make-group-reservation", repo.GroupReservation)
+	mux.Post("/make-group-reservation", repo.PostGroupReservation)
+	mux.Get("/group-reservation-summary", repo.GroupReservationSummary)
+	mux.Get("/waitlist", repo.Waitlist)
+	mux.Post("/waitlist", repo.PostWaitlist)
+
+	mux.Get("/my-booking/{ref}", repo.GuestBooking)
+	mux.Post("/my-booking/{ref}", repo.PostGuestBooking)
+	mux.Post("/my-booking/{ref}/cancel", repo.GuestBookingCancel)
+
+	mux.Get("/contact", repo.Contact)
+
+	mux.Get("/ical/{token}.ics", repo.ICalFeed)
+
+	mux.Get("/api/openapi.json", repo.APISpec)
+	mux.Get("/api/docs", repo.APIDocs)
+	mux.Get("/api/v1/availability", repo.APIAvailability)
+	mux.Get("/api/v1/rooms/{id}/calendar", repo.APIRoomCalendar)
+	mux.Route("/api/v1/admin", func(mux chi.Router) {
+		mux.Use(repo.APIAuth)
+
+		mux.Get("/reservations", repo.APIAdminReservations)
+		mux.Get("/reservations/{id}", repo.APIAdminReservation)
+		mux.Put("/reservations/{id}", repo.APIAdminUpdateReservation)
+		mux.Delete("/reservations/{id}", repo.APIAdminDeleteReservation)
+		mux.Post("/reservations/{id}/status", repo.APIAdminReservationStatus)
+		mux.Post("/blocks", repo.APIAdminCreateBlock)
+		mux.Delete("/blocks/{id}", repo.APIAdminDeleteBlock)
+	})
 
-	mux.Get("/admin/dashboard", Repo.AdminDashboard)
-	mux.Get("/admin/reservations-new", Repo.AdminReservationsNew)
-	mux.Get("/admin/reservations-all", Repo.AdminReservationsAll)
-	mux.Get("/admin/reservations-cal", Repo.AdminReservationsCalendar)
-	mux.Post("/admin/reservations-cal", Repo.AdminPostReservationsCalendar)
+	mux.Get("/login", repo.Login)
+	mux.Get("/logout", repo.Logout)
+	mux.Post("/login", repo.PostLogin)
+
+	mux.Get("/admin/dashboard", repo.AdminDashboard)
+	mux.Get("/admin/reservations-new", repo.AdminReservationsNew)
+	mux.Get("/admin/reservations-all", repo.AdminReservationsAll)
+	mux.Get("/admin/reservations-export", repo.AdminReservationsExport)
+	mux.Get("/admin/reservations-import", repo.AdminReservationsImport)
+	mux.Post("/admin/reservations-import", repo.AdminPostReservationsImport)
+	mux.Post("/admin/reservations-import/preview", repo.AdminReservationsImportPreview)
+	mux.Post("/admin/reservations-import/commit", repo.AdminReservationsImportCommit)
+	mux.Get("/admin/reservations-cal", repo.AdminReservationsCalendar)
+	mux.Post("/admin/reservations-cal", repo.AdminPostReservationsCalendar)
 	// these routes can be reached via either 'all' or 'new' reservations administration pages
-	mux.Get("/admin/reservation-processed/{src}/{id}/page", Repo.AdminReservationProcess)
-	mux.Get("/admin/reservation-deleted/{src}/{id}/page", Repo.AdminReservationDelete)
-	mux.Get("/admin/reservations/{src}/{id}/page", Repo.AdminReservation)
-	mux.Post("/admin/reservations/{src}/{id}", Repo.AdminPostReservation)
+	mux.Get("/admin/reservation-status/{src}/{id}/{status}/page", repo.AdminReservationStatus)
+	mux.Get("/admin/reservation-deleted/{src}/{id}/page", repo.AdminReservationDelete)
+	mux.Get("/admin/reservations/{src}/{id}/page", repo.AdminReservation)
+	mux.Get("/admin/groups", repo.AdminBookingGroups)
+	mux.Get("/admin/groups/{id}", repo.AdminBookingGroup)
+	mux.Get("/admin/group-status/{id}/{status}", repo.AdminBookingGroupStatus)
+	mux.Get("/admin/group-deleted/{id}", repo.AdminBookingGroupDelete)
+	mux.Get("/admin/trash", repo.AdminTrash)
+	mux.Get("/admin/reservation-restored/{id}", repo.AdminReservationRestore)
+	mux.Get("/admin/block-restored/{id}", repo.AdminRoomBlockRestore)
+	mux.Post("/admin/reservations/{src}/{id}", repo.AdminPostReservation)
+	mux.Get("/admin/rates", repo.AdminRates)
+	mux.Post("/admin/rates", repo.AdminPostRoomRates)
+	mux.Post("/admin/seasonal-rates", repo.AdminPostSeasonalRate)
+	mux.Get("/admin/seasonal-rate-deleted/{id}", repo.AdminSeasonalRateDelete)
+	mux.Get("/admin/stay-rules", repo.AdminStayRules)
+	mux.Post("/admin/stay-rules", repo.AdminPostStayRule)
+	mux.Get("/admin/stay-rule-deleted/{id}", repo.AdminStayRuleDelete)
+	mux.Get("/admin/waitlist", repo.AdminWaitlist)
+	mux.Get("/admin/waitlist-deleted/{id}", repo.AdminWaitlistDelete)
+	mux.Get("/admin/ical-feeds", repo.AdminICalFeeds)
+	mux.Post("/admin/ical-feeds", repo.AdminPostICalFeed)
+	mux.Get("/admin/ical-feed-deleted/{id}", repo.AdminICalFeedDelete)
+	mux.Get("/admin/ical-imports", repo.AdminICalImports)
+	mux.Post("/admin/ical-imports", repo.AdminPostICalImport)
+	mux.Post("/admin/ical-imports/sync", repo.AdminICalImportsSync)
+	mux.Get("/admin/ical-import-deleted/{id}", repo.AdminICalImportDelete)
+	mux.Get("/admin/api-tokens", repo.AdminAPITokens)
+	mux.Post("/admin/api-tokens", repo.AdminPostAPIToken)
+	mux.Get("/admin/api-token-deleted/{id}", repo.AdminAPITokenDelete)
+	mux.Get("/admin/webhooks", repo.AdminWebhooks)
+	mux.Post("/admin/webhooks", repo.AdminPostWebhook)
+	mux.Get("/admin/webhook-deleted/{id}", repo.AdminWebhookDelete)
+	mux.Get("/admin/webhook-delivery-retry/{id}", repo.AdminWebhookDeliveryRetry)
+	mux.Get("/admin/channel", repo.AdminChannel)
+	mux.Post("/admin/channel", repo.AdminPostChannelRooms)
+	mux.Post("/admin/channel/sync", repo.AdminChannelSync)
+	mux.Post("/admin/channel/resync", repo.AdminChannelResync)
 
 	// creat fileserver for static content
 	staticFileServer := http.FileServer(http.Dir("./static/"))
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/webhook"
+)
+
+// webhookTimeout is how long a webhook is given to respond to a delivery
+const webhookTimeout = 10 * time.Second
+
+// webhookBatch is the most deliveries attempted each time deliveries are made, so that a backlog is worked through in turn
+const webhookBatch = 50
+
+// webhookLogSize is how many of the most recent deliveries are shown in the delivery log
+const webhookLogSize = 100
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// deliverMu stops deliveries being attempted twice at once, which would post the same delivery twice
+var deliverMu sync.Mutex
+
+// webhookReservation is a reservation as posted to webhooks, the same as given by the admin API. A status change also gives the
+// reservation's previous status
+type webhookReservation struct {
+	apiReservation
+	PreviousStatus string `json:"previous_status,omitempty"`
+}
+
+// webhookBlock is an owner block as posted to webhooks, with the same id whether added or removed, so that a receiver can match the two
+type webhookBlock struct {
+	ID     int    `json:"id"`
+	RoomID int    `json:"room_id"`
+	Start  string `json:"start" format:"date"`
+	End    string `json:"end" format:"date"`
+}
+
+// newWebhookBlock converts an owner block's room & dates to be posted to webhooks
+func newWebhookBlock(id, roomID int, start, end time.Time) webhookBlock {
+	return webhookBlock{ID: id, RoomID: roomID, Start: start.Format(isoDate), End: end.Format(isoDate)}
+}
+
+// webhookDeliveries gives a delivery of an event's payload to each webhook receiving that event
+func webhookDeliveries(hooks []models.Webhook, event string, payload []byte) []models.WebhookDelivery {
+	var deliveries []models.WebhookDelivery
+	for _, h := range hooks {
+		if h.Receives(event) {
+			deliveries = append(deliveries, models.WebhookDelivery{WebhookID: h.ID, Event: event, Payload: string(payload)})
+		}
+	}
+	return deliveries
+}
+
+// publish queues an event to be delivered to every webhook receiving it. A change has already been made by the time its event is
+// published, so failing to publish it is logged rather than failing the request, & it is published even should the client have since
+// disconnected, hence the request's context is not used
+func (m *Repository) publish(event string, data interface{}) {
+	ctx := context.Background()
+
+	hooks, err := m.DB.GetAllWebhooks(ctx)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	id, err := webhook.NewEventID()
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		return
+	}
+	payload, err := json.Marshal(webhook.Payload{ID: id, Event: event, CreatedAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		return
+	}
+
+	deliveries := webhookDeliveries(hooks, event, payload)
+	if len(deliveries) == 0 {
+		return
+	}
+	err = m.DB.InsertWebhookDeliveries(ctx, deliveries)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+	}
+}
+
+// publishReservation queues a reservation event, giving the reservation's previous status for a status change
+func (m *Repository) publishReservation(event string, rsvn models.Reservation, previous models.ReservationStatus) {
+	data := webhookReservation{apiReservation: apiReservationFromModel(rsvn)}
+	if previous != rsvn.Status {
+		data.PreviousStatus = string(previous)
+	}
+	m.publish(event, data)
+}
+
+// publishCreatedReservation queues the event for a newly created reservation, read back so that it is published as stored
+func (m *Repository) publishCreatedReservation(id int) {
+	rsvn, err := m.DB.GetReservationByID(context.Background(), id)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		return
+	}
+	m.publishReservation(webhook.ReservationCreated, rsvn, rsvn.Status)
+}
+
+// attemptDelivery posts a delivery to its webhook, returning the delivery updated with the outcome. A failed delivery is retried
+// after a backoff, until it has been attempted webhook.MaxAttempts times
+func attemptDelivery(ctx context.Context, d models.WebhookDelivery, now time.Time) models.WebhookDelivery {
+	code, err := webhook.Post(ctx, webhookClient, d.Webhook.URL, d.Webhook.Secret, d.Event, d.ID, []byte(d.Payload))
+	d.Attempts++
+	d.ResponseCode = code
+
+	switch {
+	case err == nil:
+		d.Status = models.DeliveryDelivered
+		d.LastError = ""
+		d.DeliveredAt = now
+	case d.Attempts >= webhook.MaxAttempts:
+		d.Status = models.DeliveryFailed
+		d.LastError = err.Error()
+	default:
+		d.LastError = err.Error()
+		d.NextAttemptAt = now.Add(webhook.Backoff(d.Attempts))
+	}
+	return d
+}
+
+// DeliverWebhooks attempts every delivery which is due, recording the outcome of each
+func (m *Repository) DeliverWebhooks(ctx context.Context) {
+	deliverMu.Lock()
+	defer deliverMu.Unlock()
+
+	deliveries, err := m.DB.GetDueWebhookDeliveries(ctx, time.Now(), webhookBatch)
+	if err != nil {
+		m.App.ErrorLog.Println(err)
+		return
+	}
+
+	for _, d := range deliveries {
+		d = attemptDelivery(ctx, d, time.Now())
+		// a delivery abandoned part way is left due, rather than counted as an attempt
+		if ctx.Err() != nil {
+			return
+		}
+		if d.Status == models.DeliveryFailed {
+			m.App.ErrorLog.Printf("Webhook delivery (id=%d) of %s to %s has failed after %d attempts: %s\n", d.ID, d.Event, d.Webhook.URL, d.Attempts, d.LastError)
+		}
+
+		err = m.DB.UpdateWebhookDelivery(ctx, d)
+		if err != nil {
+			m.App.ErrorLog.Println(err)
+		}
+	}
+}
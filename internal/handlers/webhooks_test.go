@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/webhook"
+)
+
+func TestWebhookDeliveries(t *testing.T) {
+	hooks, _ := Repo.DB.GetAllWebhooks(context.Background())
+	payload := []byte(`{"id":"evt_1"}`)
+
+	// webhook 1 receives only its chosen events, webhook 2 receives every event
+	deliveries := webhookDeliveries(hooks, webhook.ReservationCreated, payload)
+	if len(deliveries) != 2 || deliveries[0].WebhookID != 1 || deliveries[1].WebhookID != 2 {
+		t.Errorf("expected reservation.created to be delivered to webhooks 1 & 2, got %+v", deliveries)
+	}
+	if deliveries[0].Event != webhook.ReservationCreated || deliveries[0].Payload != string(payload) {
+		t.Errorf("unexpected delivery %+v", deliveries[0])
+	}
+
+	deliveries = webhookDeliveries(hooks, webhook.BlockAdded, payload)
+	if len(deliveries) != 1 || deliveries[0].WebhookID != 2 {
+		t.Errorf("expected block.added to be delivered to webhook 2 alone, got %+v", deliveries)
+	}
+}
+
+func TestWebhookReservation(t *testing.T) {
+	rsvn := models.Reservation{ID: 7, RoomID: 1, FirstName: "Joe", LastName: "Soap", Status: models.StatusCancelled}
+
+	b, _ := json.Marshal(webhookReservation{apiReservation: apiReservationFromModel(rsvn), PreviousStatus: string(models.StatusConfirmed)})
+	var got map[string]interface{}
+	json.Unmarshal(b, &got)
+	if got["id"] != float64(7) || got["status"] != string(models.StatusCancelled) || got["previous_status"] != string(models.StatusConfirmed) {
+		t.Errorf("unexpected status change payload %s", b)
+	}
+
+	b, _ = json.Marshal(webhookReservation{apiReservation: apiReservationFromModel(rsvn)})
+	if strings.Contains(string(b), "previous_status") {
+		t.Errorf("expected no previous_status without a status change, got %s", b)
+	}
+
+	b, _ = json.Marshal(newWebhookBlock(3, 1, time.Date(2098, 7, 10, 0, 0, 0, 0, time.UTC), time.Date(2098, 7, 11, 0, 0, 0, 0, time.UTC)))
+	if string(b) != `{"id":3,"room_id":1,"start":"2098-07-10","end":"2098-07-11"}` {
+		t.Errorf("unexpected block payload %s", b)
+	}
+}
+
+func TestAttemptDelivery(t *testing.T) {
+	var signature, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		signature = r.Header.Get(webhook.SignatureHeader)
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	d := models.WebhookDelivery{
+		ID:      5,
+		Event:   webhook.BlockAdded,
+		Payload: `{"id":"evt_1","event":"block.added"}`,
+		Status:  models.DeliveryPending,
+		Webhook: models.Webhook{URL: srv.URL + "/hook", Secret: "whsec_test"},
+	}
+
+	got := attemptDelivery(context.Background(), d, now)
+	if got.Status != models.DeliveryDelivered || got.Attempts != 1 || got.ResponseCode != http.StatusOK || !got.DeliveredAt.Equal(now) {
+		t.Errorf("expected delivery to succeed, got %+v", got)
+	}
+	if err := webhook.Verify("whsec_test", signature, []byte(body), time.Now(), time.Minute); err != nil || body != d.Payload {
+		t.Errorf("expected a signed payload to be delivered, got %q (%v)", body, err)
+	}
+
+	// a failed attempt is retried after a backoff...
+	d.Webhook.URL = srv.URL + "/down"
+	d.Attempts = 2
+	got = attemptDelivery(context.Background(), d, now)
+	if got.Status != models.DeliveryPending || got.Attempts != 3 || got.ResponseCode != http.StatusServiceUnavailable || !got.NextAttemptAt.Equal(now.Add(webhook.Backoff(3))) {
+		t.Errorf("expected delivery to be retried, got %+v", got)
+	}
+	if !strings.Contains(got.LastError, "503") {
+		t.Errorf("expected the last error to give the response, got %q", got.LastError)
+	}
+
+	// ...until it has been attempted webhook.MaxAttempts times
+	d.Attempts = webhook.MaxAttempts - 1
+	got = attemptDelivery(context.Background(), d, now)
+	if got.Status != models.DeliveryFailed || got.Attempts != webhook.MaxAttempts {
+		t.Errorf("expected delivery to fail, got %+v", got)
+	}
+}
+
+func TestRepository_DeliverWebhooks(t *testing.T) {
+	// testdb's due delivery cannot be reached, so this only checks deliveries are worked through without blocking
+	done := make(chan struct{})
+	go func() {
+		Repo.DeliverWebhooks(context.Background())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(webhookTimeout + 5*time.Second):
+		t.Error("DeliverWebhooks did not finish")
+	}
+}
+
+func TestRepository_AdminWebhooks(t *testing.T) {
+	routes := getRoutes()
+
+	req, _ := http.NewRequest("GET", "/admin/webhooks", nil)
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("AdminWebhooks handler returned code: %d, expected code: %d", rr.Code, http.StatusOK)
+	}
+	for _, s := range []string{"https://cleaning.example.com/hooks/bnb", "all events", "webhook returned 503 Service Unavailable", "/admin/webhook-delivery-retry/1"} {
+		if !strings.Contains(rr.Body.String(), s) {
+			t.Errorf("AdminWebhooks handler did not show %q", s)
+		}
+	}
+}
+
+var adminPostWebhookTests = []struct {
+	name               string
+	postedData         url.Values
+	expectedStatusCode int
+	expectedContent    string
+}{
+	{"valid", url.Values{"url": {"https://example.com/hooks"}, "events": {"reservation.created", "block.added"}}, http.StatusSeeOther, ""},
+	{"all-events", url.Values{"url": {"https://example.com/hooks"}}, http.StatusSeeOther, ""},
+	{"invalid-url", url.Values{"url": {"ftp://example.com/hooks"}}, http.StatusOK, "please input a valid web address"},
+	{"unknown-event", url.Values{"url": {"https://example.com/hooks"}, "events": {"room.painted"}}, http.StatusOK, "Unknown event"},
+}
+
+func TestRepository_AdminPostWebhook(t *testing.T) {
+	routes := getRoutes()
+
+	for _, v := range adminPostWebhookTests {
+		req, _ := http.NewRequest("POST", "/admin/webhooks", strings.NewReader(v.postedData.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		if rr.Code != v.expectedStatusCode {
+			t.Errorf("AdminPostWebhook handler (%s) returned code: %d, expected code: %d", v.name, rr.Code, v.expectedStatusCode)
+		}
+		if v.expectedContent != "" && !strings.Contains(rr.Body.String(), v.expectedContent) {
+			t.Errorf("AdminPostWebhook handler (%s) did not show %q", v.name, v.expectedContent)
+		}
+	}
+}
+
+func TestRepository_AdminWebhookDelete(t *testing.T) {
+	routes := getRoutes()
+
+	for _, path := range []string{"/admin/webhook-deleted/1", "/admin/webhook-delivery-retry/1"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		actualLoc, _ := rr.Result().Location()
+		if rr.Code != http.StatusSeeOther || actualLoc.String() != "/admin/webhooks" {
+			t.Errorf("%s returned code: %d & location: %s, expected redirect to /admin/webhooks", path, rr.Code, actualLoc.String())
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// dateFormat is the form of an all-day DATE value e.g. 20261101
+const dateFormat = "20060102"
+
+// dateTimeFormat is the form of a UTC DATE-TIME value e.g. 20261101T093000Z
+const dateTimeFormat = "20060102T150405Z"
+
+// maxLineOctets is the longest a content line may be before it must be folded onto the next
+const maxLineOctets = 75
+
+// Calendar is an iCalendar (RFC 5545) calendar of all-day events, as published by booking sites & calendar apps for each room
+type Calendar struct {
+	ProdID string // identifies the product that created the calendar e.g. "-//StratoNET//bnb-bookings//EN"
+	Name   string // shown by calendar apps as the calendar's name
+	Events []Event
+}
+
+// Event is an all-day event. End is the day after the last day, as in iCalendar, so a 2 night stay ends on the day of departure
+type Event struct {
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Stamp       time.Time // when the event was created or last changed
+}
+
+// Encode writes the calendar in iCalendar format
+func (c *Calendar) Encode(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeLine(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", c.ProdID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escapeText(c.Name))
+	}
+
+	for _, e := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", e.UID)
+		line("DTSTAMP", e.Stamp.UTC().Format(dateTimeFormat))
+		line("DTSTART;VALUE=DATE", e.Start.Format(dateFormat))
+		line("DTEND;VALUE=DATE", e.End.Format(dateFormat))
+		line("SUMMARY", escapeText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escapeText(e.Description))
+		}
+		line("TRANSP", "OPAQUE")
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// writeLine writes a content line ending in CRLF, folding it so that no line is longer than 75 octets & no UTF-8 character is split
+func writeLine(w *bufio.Writer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// continuation lines begin with a space, which counts towards their length
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+// textEscaper escapes the characters with special meaning in TEXT values
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
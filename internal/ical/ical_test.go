@@ -0,0 +1,201 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendar_Encode(t *testing.T) {
+	c := Calendar{
+		ProdID: "-//Test//EN",
+		Name:   "General's Quarters",
+		Events: []Event{
+			{
+				UID:         "1@test",
+				Start:       time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+				End:         time.Date(2026, 11, 3, 0, 0, 0, 0, time.UTC),
+				Summary:     "Soap, Joe; K7QX2MZP4D",
+				Description: "2 adults\nconfirmed",
+				Stamp:       time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := c.Encode(&buf)
+	if err != nil {
+		t.Fatal("Encode failed", err)
+	}
+	out := buf.String()
+
+	expected := []string{
+		"BEGIN:VCALENDAR\r\n",
+		"VERSION:2.0\r\n",
+		"PRODID:-//Test//EN\r\n",
+		"X-WR-CALNAME:General's Quarters\r\n",
+		"BEGIN:VEVENT\r\n",
+		"UID:1@test\r\n",
+		"DTSTAMP:20261018T093000Z\r\n",
+		"DTSTART;VALUE=DATE:20261101\r\n",
+		"DTEND;VALUE=DATE:20261103\r\n",
+		`SUMMARY:Soap\, Joe\; K7QX2MZP4D` + "\r\n",
+		`DESCRIPTION:2 adults\nconfirmed` + "\r\n",
+		"END:VEVENT\r\n",
+	}
+	for _, s := range expected {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected calendar to contain %q, got:\n%s", s, out)
+		}
+	}
+	if !strings.HasSuffix(out, "END:VCALENDAR\r\n") {
+		t.Error("expected calendar to end with END:VCALENDAR")
+	}
+	if strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Error("expected every line to end with CRLF")
+	}
+}
+
+func TestCalendar_Encode_Folding(t *testing.T) {
+	// a long summary of 2 octet characters, so that folding must take care not to split one
+	c := Calendar{ProdID: "-//Test//EN", Events: []Event{{UID: "1@test", Summary: strings.Repeat("é", 100)}}}
+
+	var buf bytes.Buffer
+	err := c.Encode(&buf)
+	if err != nil {
+		t.Fatal("Encode failed", err)
+	}
+
+	var summary string
+	for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line %d is %d octets long: %q", i, len(line), line)
+		}
+		switch {
+		case strings.HasPrefix(line, "SUMMARY:"):
+			summary = strings.TrimPrefix(line, "SUMMARY:")
+		case strings.HasPrefix(line, " ") && summary != "":
+			summary += strings.TrimPrefix(line, " ")
+		case summary != "" && !strings.HasPrefix(line, " "):
+			if summary != strings.Repeat("é", 100) {
+				t.Errorf("summary was not folded whole, unfolded to %q", summary)
+			}
+			summary = ""
+		}
+	}
+}
+
+const airbnbCalendar = "BEGIN:VCALENDAR\r\n" +
+	"PRODID;X-RICAL-TZSOURCE=TZINFO:-//Airbnb Inc//Hosting Calendar 0.8.8//EN\r\n" +
+	"CALSCALE:GREGORIAN\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Europe/London\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:19701025T020000\r\n" +
+	"END:STANDARD\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTEND;VALUE=DATE:20261104\r\n" +
+	"DTSTART;VALUE=DATE:20261101\r\n" +
+	"UID:1418fb94e984-a1b2@airbnb.com\r\n" +
+	"DESCRIPTION:Reservation URL: https://www.airbnb.com/hosting/reservations/details/HM\r\n" +
+	" ABC123\\nPhone Number (Last 4 Digits): 1234\r\n" +
+	"SUMMARY:Reserved\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"TRIGGER:-PT15M\r\n" +
+	"SUMMARY:Alarm\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;TZID=\"Europe/London\":20261210T150000\r\n" +
+	"DTEND;TZID=\"Europe/London\":20261212T110000\r\n" +
+	"UID:timed@example.com\r\n" +
+	"SUMMARY:Smith\\, J\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20261220\r\n" +
+	"UID:single-day@example.com\r\n" +
+	"SUMMARY:Not available\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20261224\r\n" +
+	"DTEND;VALUE=DATE:20261226\r\n" +
+	"UID:cancelled@example.com\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParse(t *testing.T) {
+	c, err := Parse(strings.NewReader(airbnbCalendar))
+	if err != nil {
+		t.Fatal("Parse failed", err)
+	}
+
+	if c.ProdID != "-//Airbnb Inc//Hosting Calendar 0.8.8//EN" {
+		t.Errorf("unexpected PRODID %q", c.ProdID)
+	}
+	if len(c.Events) != 3 {
+		t.Fatalf("expected 3 events, cancelled event & alarm left out, got %d", len(c.Events))
+	}
+
+	e := c.Events[0]
+	if e.UID != "1418fb94e984-a1b2@airbnb.com" || e.Summary != "Reserved" {
+		t.Errorf("unexpected event %q %q, an alarm's properties must not be the event's", e.UID, e.Summary)
+	}
+	if !e.Start.Equal(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)) || !e.End.Equal(time.Date(2026, 11, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected dates %s to %s", e.Start, e.End)
+	}
+	if !strings.Contains(e.Description, "HMABC123\nPhone") {
+		t.Errorf("folded description not unfolded & unescaped: %q", e.Description)
+	}
+
+	e = c.Events[1]
+	if e.Summary != "Smith, J" {
+		t.Errorf("summary not unescaped: %q", e.Summary)
+	}
+	london, _ := time.LoadLocation("Europe/London")
+	if !e.Start.Equal(time.Date(2026, 12, 10, 15, 0, 0, 0, london)) {
+		t.Errorf("expected start in its own time zone, got %s", e.Start)
+	}
+
+	e = c.Events[2]
+	if e.End.Format(dateFormat) != "20261221" {
+		t.Errorf("expected an event with no end to end the next day, got %s", e.End)
+	}
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+	c := Calendar{ProdID: "-//Test//EN", Name: "Rooms; all", Events: []Event{
+		{UID: "1@test", Start: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 11, 3, 0, 0, 0, 0, time.UTC), Summary: strings.Repeat("Soap, Joe; ", 10)},
+	}}
+	var buf bytes.Buffer
+	if err := c.Encode(&buf); err != nil {
+		t.Fatal("Encode failed", err)
+	}
+
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatal("Parse failed", err)
+	}
+	if parsed.Name != c.Name || len(parsed.Events) != 1 || parsed.Events[0].Summary != c.Events[0].Summary || !parsed.Events[0].End.Equal(c.Events[0].End) {
+		t.Errorf("calendar changed by encoding then parsing, got %+v", parsed)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for name, s := range map[string]string{
+		"empty":        "",
+		"html":         "<!DOCTYPE html>\n<html></html>\n",
+		"unterminated": "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20261101\r\n",
+		"no-start":     "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:1\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+		"bad-date":     "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:2026-11-01\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+		"bad-line":     "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nnonsense\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+	} {
+		_, err := Parse(strings.NewReader(s))
+		if _, ok := err.(*ParseError); !ok {
+			t.Errorf("%s: expected a *ParseError, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// floatingFormat is the form of a DATE-TIME value with no time zone e.g. 20261101T093000
+const floatingFormat = "20060102T150405"
+
+// ParseError is returned when a calendar cannot be read, giving the (unfolded) line at which the problem was found
+type ParseError struct {
+	Line    int
+	Problem string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("ical: line %d: %s", e.Line, e.Problem)
+}
+
+// property is a single content line e.g. DTSTART;VALUE=DATE:20261101 has name DTSTART, params VALUE=DATE & value 20261101
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads a calendar in iCalendar format, as published by booking sites & calendar apps. Only its events are read, other
+// components such as to-dos & alarms are skipped. Cancelled events are left out, as they no longer take the room. An event with
+// no end ends the day after it starts, as the start of an all-day event is its only day
+func Parse(r io.Reader) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Calendar
+	var e *Event
+	var cancelled bool
+	inCalendar, done := false, false
+	// depth of components nested within an event e.g. VALARM, whose properties are not the event's own
+	nested := 0
+
+	for i, l := range lines {
+		n := i + 1
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		p, err := parseProperty(l)
+		if err != nil && !inCalendar {
+			// commonly an HTML page, such as a login page, given in place of the calendar
+			return nil, &ParseError{Line: n, Problem: "not an iCalendar, expected BEGIN:VCALENDAR"}
+		} else if err != nil {
+			return nil, &ParseError{Line: n, Problem: err.Error()}
+		}
+
+		switch {
+		case done:
+			// anything following the calendar is ignored
+		case !inCalendar:
+			if p.name != "BEGIN" || !strings.EqualFold(p.value, "VCALENDAR") {
+				return nil, &ParseError{Line: n, Problem: "not an iCalendar, expected BEGIN:VCALENDAR"}
+			}
+			inCalendar = true
+		case p.name == "BEGIN":
+			switch {
+			case e != nil:
+				nested++
+			case strings.EqualFold(p.value, "VEVENT"):
+				e = &Event{}
+				cancelled = false
+			default:
+				nested++
+			}
+		case p.name == "END":
+			switch {
+			case nested > 0:
+				nested--
+			case e != nil:
+				if e.Start.IsZero() {
+					return nil, &ParseError{Line: n, Problem: fmt.Sprintf("event %q has no DTSTART", e.UID)}
+				}
+				if e.End.IsZero() {
+					e.End = e.Start.AddDate(0, 0, 1)
+				}
+				if !cancelled {
+					c.Events = append(c.Events, *e)
+				}
+				e = nil
+			case strings.EqualFold(p.value, "VCALENDAR"):
+				done = true
+			}
+		case nested > 0:
+			// a property of a nested component
+		case e == nil:
+			switch p.name {
+			case "PRODID":
+				c.ProdID = p.value
+			case "X-WR-CALNAME":
+				c.Name = unescapeText(p.value)
+			}
+		default:
+			switch p.name {
+			case "UID":
+				e.UID = p.value
+			case "SUMMARY":
+				e.Summary = unescapeText(p.value)
+			case "DESCRIPTION":
+				e.Description = unescapeText(p.value)
+			case "STATUS":
+				cancelled = strings.EqualFold(p.value, "CANCELLED")
+			case "DTSTART", "DTEND", "DTSTAMP":
+				t, err := parseTime(p)
+				if err != nil {
+					return nil, &ParseError{Line: n, Problem: fmt.Sprintf("%s: %s", p.name, err)}
+				}
+				switch p.name {
+				case "DTSTART":
+					e.Start = t
+				case "DTEND":
+					e.End = t
+				default:
+					e.Stamp = t
+				}
+			}
+		}
+	}
+
+	if !done {
+		return nil, &ParseError{Line: len(lines), Problem: "calendar has no END:VCALENDAR"}
+	}
+	return &c, nil
+}
+
+// unfold reads content lines, joining any line folded onto the next. Lines may end in CRLF or, as some publishers use, LF alone
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	// a line may be long once unfolded e.g. an event's description
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		l := strings.TrimSuffix(sc.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return lines, sc.Err()
+}
+
+// parseProperty splits a content line into its name, parameters & value. Parameter values may be quoted, so may contain ":" or ";"
+func parseProperty(l string) (property, error) {
+	p := property{params: make(map[string]string)}
+
+	// the value begins at the first colon not within a quoted parameter value
+	quoted := false
+	colon := -1
+	for i := 0; i < len(l) && colon < 0; i++ {
+		switch l[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				colon = i
+			}
+		}
+	}
+	if colon < 0 {
+		return p, fmt.Errorf("%q is not a property", l)
+	}
+	p.value = l[colon+1:]
+
+	parts := strings.Split(l[:colon], ";")
+	p.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		eq := strings.Index(param, "=")
+		if eq < 0 {
+			continue
+		}
+		p.params[strings.ToUpper(param[:eq])] = strings.Trim(param[eq+1:], `"`)
+	}
+	return p, nil
+}
+
+// parseTime reads a DATE or DATE-TIME value. A DATE is midnight UTC, the form in which the application keeps dates, whatever the
+// server's time zone. A DATE-TIME is in UTC, in its TZID time zone or, failing both, in local time
+func parseTime(p property) (time.Time, error) {
+	if strings.EqualFold(p.params["VALUE"], "DATE") || len(p.value) == len(dateFormat) {
+		return time.ParseInLocation(dateFormat, p.value, time.UTC)
+	}
+	if strings.HasSuffix(p.value, "Z") {
+		return time.Parse(dateTimeFormat, p.value)
+	}
+	loc := time.Local
+	if tzid := p.params["TZID"]; tzid != "" {
+		// an unknown time zone is read as local time, rather than losing the event
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	return time.ParseInLocation(floatingFormat, p.value, loc)
+}
+
+// textUnescaper reverses textEscaper, also accepting "\N" for a new line
+var textUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+func unescapeText(s string) string {
+	return textUnescaper.Replace(s)
+}
@@ -0,0 +1,84 @@
+package lifecycle
+
+import (
+	"fmt"
+
+	"github.com/StratoNET/bnb-bookings/internal/models"
+)
+
+// transitions lists, for each status, the statuses a reservation may move on to, cancelled, checked out & no show are final
+var transitions = map[models.ReservationStatus][]models.ReservationStatus{
+	models.StatusPending:    {models.StatusConfirmed, models.StatusCheckedIn, models.StatusCancelled, models.StatusNoShow},
+	models.StatusConfirmed:  {models.StatusCheckedIn, models.StatusCancelled, models.StatusNoShow},
+	models.StatusCheckedIn:  {models.StatusCheckedOut},
+	models.StatusCheckedOut: {},
+	models.StatusCancelled:  {},
+	models.StatusNoShow:     {},
+}
+
+// All returns every status, in lifecycle order
+func All() []models.ReservationStatus {
+	return []models.ReservationStatus{
+		models.StatusPending,
+		models.StatusConfirmed,
+		models.StatusCheckedIn,
+		models.StatusCheckedOut,
+		models.StatusCancelled,
+		models.StatusNoShow,
+	}
+}
+
+// Parse converts a status name, as used in URLs & the database, into a status
+func Parse(s string) (models.ReservationStatus, error) {
+	status := models.ReservationStatus(s)
+	if _, ok := transitions[status]; !ok {
+		return "", fmt.Errorf("unknown reservation status %q", s)
+	}
+	return status, nil
+}
+
+// Next returns the statuses a reservation may move on to from its current status
+func Next(from models.ReservationStatus) []models.ReservationStatus {
+	return transitions[from]
+}
+
+// NextForAll returns the statuses every one of a number of reservations, e.g. the rooms of a booking group, may move on to together
+func NextForAll(from []models.ReservationStatus) []models.ReservationStatus {
+	if len(from) == 0 {
+		return nil
+	}
+	var next []models.ReservationStatus
+	for _, to := range All() {
+		allowed := true
+		for _, f := range from {
+			if Check(f, to) != nil {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			next = append(next, to)
+		}
+	}
+	return next
+}
+
+// TransitionError is returned when a reservation cannot move from one status to another
+type TransitionError struct {
+	From models.ReservationStatus
+	To   models.ReservationStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("a %s reservation cannot be marked as %s", e.From.Label(), e.To.Label())
+}
+
+// Check tests whether a reservation may move from one status to another, returning a *TransitionError if not
+func Check(from, to models.ReservationStatus) error {
+	for _, next := range transitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return &TransitionError{From: from, To: to}
+}
@@ -0,0 +1,102 @@
+package lifecycle
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/StratoNET/bnb-bookings/internal/models"
+)
+
+var checkTests = []struct {
+	name     string
+	from, to models.ReservationStatus
+	allowed  bool
+}{
+	{"confirm", models.StatusPending, models.StatusConfirmed, true},
+	{"check-in-unconfirmed", models.StatusPending, models.StatusCheckedIn, true},
+	{"cancel-pending", models.StatusPending, models.StatusCancelled, true},
+	{"check-in", models.StatusConfirmed, models.StatusCheckedIn, true},
+	{"no-show", models.StatusConfirmed, models.StatusNoShow, true},
+	{"check-out", models.StatusCheckedIn, models.StatusCheckedOut, true},
+	{"check-out-before-check-in", models.StatusConfirmed, models.StatusCheckedOut, false},
+	{"cancel-checked-in", models.StatusCheckedIn, models.StatusCancelled, false},
+	{"reopen-cancelled", models.StatusCancelled, models.StatusPending, false},
+	{"reopen-no-show", models.StatusNoShow, models.StatusConfirmed, false},
+	{"same-status", models.StatusConfirmed, models.StatusConfirmed, false},
+	{"unknown-status", models.ReservationStatus("processed"), models.StatusConfirmed, false},
+}
+
+func TestCheck(t *testing.T) {
+	for _, v := range checkTests {
+		err := Check(v.from, v.to)
+		if v.allowed && err != nil {
+			t.Errorf("%s: expected change to be allowed, got %v", v.name, err)
+		}
+		if !v.allowed {
+			var te *TransitionError
+			if !errors.As(err, &te) {
+				t.Errorf("%s: expected a *TransitionError, got %v", v.name, err)
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, s := range All() {
+		status, err := Parse(string(s))
+		if err != nil || status != s {
+			t.Errorf("cannot parse status %q", s)
+		}
+	}
+
+	if _, err := Parse("processed"); err == nil {
+		t.Error("expected error parsing unknown status")
+	}
+}
+
+func TestNext(t *testing.T) {
+	if len(Next(models.StatusCheckedIn)) != 1 || Next(models.StatusCheckedIn)[0] != models.StatusCheckedOut {
+		t.Errorf("expected checked in reservations to move on only to checked out, got %v", Next(models.StatusCheckedIn))
+	}
+
+	for _, final := range []models.ReservationStatus{models.StatusCheckedOut, models.StatusCancelled, models.StatusNoShow} {
+		if len(Next(final)) != 0 {
+			t.Errorf("expected %s to be final, got %v", final, Next(final))
+		}
+	}
+}
+
+var nextForAllTests = []struct {
+	name     string
+	from     []models.ReservationStatus
+	expected []models.ReservationStatus
+}{
+	{"no-reservations", nil, nil},
+	{"all-pending", []models.ReservationStatus{models.StatusPending, models.StatusPending}, Next(models.StatusPending)},
+	{"pending-and-confirmed", []models.ReservationStatus{models.StatusPending, models.StatusConfirmed}, Next(models.StatusConfirmed)},
+	{"confirmed-and-checked-in", []models.ReservationStatus{models.StatusConfirmed, models.StatusCheckedIn}, nil},
+	{"one-cancelled", []models.ReservationStatus{models.StatusConfirmed, models.StatusCancelled}, nil},
+}
+
+func TestNextForAll(t *testing.T) {
+	for _, v := range nextForAllTests {
+		next := NextForAll(v.from)
+		if len(next) != len(v.expected) {
+			t.Errorf("%s: expected %v, got %v", v.name, v.expected, next)
+			continue
+		}
+		for i := range next {
+			if next[i] != v.expected[i] {
+				t.Errorf("%s: expected %v, got %v", v.name, v.expected, next)
+				break
+			}
+		}
+	}
+}
+
+func TestTransitionError_Error(t *testing.T) {
+	err := TransitionError{From: models.StatusCancelled, To: models.StatusCheckedIn}
+	if err.Error() != "a Cancelled reservation cannot be marked as Checked In" {
+		t.Errorf("unexpected error message: %s", err.Error())
+	}
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 //Administrator is the administrator model
 type Administrator struct {
@@ -14,12 +18,75 @@ type Administrator struct {
 	UpdatedAt   time.Time
 }
 
-// Room is the room model
+// APIToken is the API token model, allowing an administrator's scripts & integrations to use the admin JSON API. Only a hash of
+// the token is held, the token itself being shown once when created
+type APIToken struct {
+	ID              int
+	AdministratorID int
+	Name            string
+	TokenHash       string
+	LastUsedAt      time.Time // zero until the token has been used
+	CreatedAt       time.Time
+}
+
+// ICalFeed is the iCal feed model, a secret URL from which a room's reservations & owner blocks can be read by booking sites & calendar
+// apps. Private feeds, for administrators' own calendars, also give guests' names. A feed is revoked by deleting it
+type ICalFeed struct {
+	ID        int
+	RoomID    int
+	Token     string
+	Private   bool
+	CreatedAt time.Time
+	Room      Room
+}
+
+// Room is the room model (all rates are held in pence)
 type Room struct {
+	ID             int
+	RoomName       string
+	NightlyRate    int
+	WeekendRate    int
+	IncludedGuests int
+	ExtraGuestRate int
+	MaxAdults      int
+	MaxChildren    int
+	MaxInfants     int
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Accommodates reports whether a room can hold a party of guests, children may also take any places not needed by adults
+func (rm Room) Accommodates(adults, children, infants int) bool {
+	return adults <= rm.MaxAdults && adults+children <= rm.MaxAdults+rm.MaxChildren && infants <= rm.MaxInfants
+}
+
+// SeasonalRate is the seasonal rate model, overriding a room's nightly & weekend rates within a date range (rates held in pence)
+type SeasonalRate struct {
+	ID          int
+	RoomID      int
+	SeasonName  string
+	StartDate   time.Time
+	EndDate     time.Time
+	NightlyRate int
+	WeekendRate int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Room        Room
+}
+
+// StayRule is the stay rule model, limiting the number of nights that may be booked in a room for stays covering a date range
+// (a MaxNights of 0 means no maximum)
+type StayRule struct {
 	ID        int
-	RoomName  string
+	RoomID    int
+	RuleName  string
+	StartDate time.Time
+	EndDate   time.Time
+	MinNights int
+	MaxNights int
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Room      Room
 }
 
 // RestrictionCategory is the restriction category model
@@ -30,20 +97,318 @@ type RestrictionCategory struct {
 	UpdatedAt       time.Time
 }
 
+// restriction categories, as held in the restrictions table
+const (
+	RestrictionReservation = 1
+	RestrictionOwnerBlock  = 2
+	// RestrictionHold keeps a room for a guest between choosing it & completing their reservation, until it expires
+	RestrictionHold = 3
+	// RestrictionExternalBooking is a booking taken on another site, copied from its calendar by the iCal importer
+	RestrictionExternalBooking = 4
+)
+
+// ReservationStatus is a stage in the lifecycle of a reservation, see the lifecycle package for the changes allowed between them
+type ReservationStatus string
+
+const (
+	StatusPending    ReservationStatus = "pending"
+	StatusConfirmed  ReservationStatus = "confirmed"
+	StatusCheckedIn  ReservationStatus = "checked-in"
+	StatusCheckedOut ReservationStatus = "checked-out"
+	StatusCancelled  ReservationStatus = "cancelled"
+	StatusNoShow     ReservationStatus = "no-show"
+)
+
+// Label gives the status in words for display e.g. "Checked In"
+func (s ReservationStatus) Label() string {
+	switch s {
+	case StatusPending:
+		return "Pending"
+	case StatusConfirmed:
+		return "Confirmed"
+	case StatusCheckedIn:
+		return "Checked In"
+	case StatusCheckedOut:
+		return "Checked Out"
+	case StatusCancelled:
+		return "Cancelled"
+	case StatusNoShow:
+		return "No Show"
+	}
+	return string(s)
+}
+
+// Colour gives the bootstrap colour name used to show the status throughout the admin pages
+func (s ReservationStatus) Colour() string {
+	switch s {
+	case StatusPending:
+		return "warning"
+	case StatusConfirmed:
+		return "primary"
+	case StatusCheckedIn:
+		return "success"
+	case StatusCheckedOut:
+		return "secondary"
+	case StatusCancelled:
+		return "danger"
+	case StatusNoShow:
+		return "dark"
+	}
+	return "light"
+}
+
+// StatusChange is the status change model, recording when a reservation moved from one status to another
+type StatusChange struct {
+	ID            int
+	ReservationID int
+	FromStatus    ReservationStatus
+	ToStatus      ReservationStatus
+	CreatedAt     time.Time
+}
+
 // Reservation is the reservation model
 type Reservation struct {
-	ID        int
+	ID              int
+	RoomID          int
+	FirstName       string
+	LastName        string
+	Email           string
+	Phone           string
+	StartDate       time.Time
+	EndDate         time.Time
+	Status          ReservationStatus
+	TotalPrice      int
+	Adults          int
+	Children        int
+	Infants         int
+	ConfirmationRef string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       time.Time // zero unless the reservation is in the trash
+	GroupID         int       // zero unless the reservation is one room of a booking group
+	Room            Room
+}
+
+// Party describes the guests staying under a reservation e.g. "2 adults & 1 child"
+func (rsvn Reservation) Party() string {
+	return PartyDescription(rsvn.Adults, rsvn.Children, rsvn.Infants)
+}
+
+// ReservationFilter selects reservations by any combination of status, room & dates, leaving a field at its zero value to
+// select all. StartDate & EndDate select stays overlapping that range, either may be given alone
+type ReservationFilter struct {
+	Status    ReservationStatus
 	RoomID    int
-	FirstName string
-	LastName  string
-	Email     string
-	Phone     string
 	StartDate time.Time
 	EndDate   time.Time
-	Processed uint8
+}
+
+// BookingGroup links the reservations of several rooms, booked together for the same dates, under one lead guest & one confirmation
+type BookingGroup struct {
+	ID              int
+	ConfirmationRef string
+	FirstName       string
+	LastName        string
+	Email           string
+	Phone           string
+	StartDate       time.Time
+	EndDate         time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Reservations    []Reservation
+}
+
+// TotalPrice is the sum of the quoted totals of every room in the group
+func (g BookingGroup) TotalPrice() int {
+	total := 0
+	for _, rsvn := range g.Reservations {
+		total += rsvn.TotalPrice
+	}
+	return total
+}
+
+// Party describes all the guests staying across the group's rooms e.g. "4 adults & 2 children"
+func (g BookingGroup) Party() string {
+	var adults, children, infants int
+	for _, rsvn := range g.Reservations {
+		adults += rsvn.Adults
+		children += rsvn.Children
+		infants += rsvn.Infants
+	}
+	return PartyDescription(adults, children, infants)
+}
+
+// Statuses returns the status of each reservation in the group, in room order
+func (g BookingGroup) Statuses() []ReservationStatus {
+	statuses := make([]ReservationStatus, 0, len(g.Reservations))
+	for _, rsvn := range g.Reservations {
+		statuses = append(statuses, rsvn.Status)
+	}
+	return statuses
+}
+
+// PartyDescription describes numbers of adults, children & infants in words, leaving out any category with none
+func PartyDescription(adults, children, infants int) string {
+	var parts []string
+	for _, p := range []struct {
+		count            int
+		single, multiple string
+	}{
+		{adults, "adult", "adults"},
+		{children, "child", "children"},
+		{infants, "infant", "infants"},
+	} {
+		switch {
+		case p.count == 1:
+			parts = append(parts, "1 "+p.single)
+		case p.count > 1:
+			parts = append(parts, fmt.Sprintf("%d %s", p.count, p.multiple))
+		}
+	}
+
+	switch len(parts) {
+	case 0:
+		return "no guests"
+	case 1:
+		return parts[0]
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " & " + parts[len(parts)-1]
+}
+
+// DateOnly strips any time of day, giving the calendar day of t as midnight UTC, the form in which dates are stored, so that nights
+// are always counted as whole days
+func DateOnly(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// WaitlistEntry is the waitlist model, a guest waiting for a room to become free for their dates & party
+type WaitlistEntry struct {
+	ID         int
+	FirstName  string
+	LastName   string
+	Email      string
+	Phone      string
+	StartDate  time.Time
+	EndDate    time.Time
+	Adults     int
+	Children   int
+	Infants    int
+	NotifiedAt time.Time // zero until the guest has been told a room is free
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Party describes the guests waiting under a waitlist entry e.g. "2 adults & 1 child"
+func (e WaitlistEntry) Party() string {
+	return PartyDescription(e.Adults, e.Children, e.Infants)
+}
+
+// ICalImport is the iCal import model, the .ics URL of a calendar on another site (e.g. Airbnb, Booking.com) listing a room's bookings
+// taken there, which the importer copies into the room's restrictions as external bookings
+type ICalImport struct {
+	ID           int
+	RoomID       int
+	Name         string
+	URL          string
+	LastSyncedAt time.Time // zero until the calendar has first been imported
+	LastError    string    // why the last import failed, empty if it succeeded
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Room         Room
+}
+
+// BookingConflict is an external booking overlapping one of our own reservations, so that the room has been sold twice
+type BookingConflict struct {
+	Import      ICalImport
+	Booking     RoomRestriction
+	Reservation Reservation
+}
+
+// Webhook is the webhook model, an endpoint to which reservation & owner block events are posted as JSON, signed with its secret.
+// Events lists the events it receives, all of them if empty
+type Webhook struct {
+	ID        int
+	URL       string
+	Secret    string
+	Events    []string
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Room      Room
+}
+
+// Receives reports whether a webhook is to be sent a given event
+func (h Webhook) Receives(event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// statuses of a webhook delivery, also used for a channel sync
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is the webhook delivery model, one event's payload to be posted to one webhook, retried until it is delivered or
+// has failed too many times
+type WebhookDelivery struct {
+	ID            int
+	WebhookID     int
+	Event         string
+	Payload       string
+	Status        string
+	Attempts      int
+	ResponseCode  int    // of the last attempt, zero if there was no response
+	LastError     string // why the last attempt failed, empty if it succeeded
+	NextAttemptAt time.Time
+	DeliveredAt   time.Time // zero until delivered
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Webhook       Webhook
+}
+
+// ChannelRoom maps a room to its room type (InvTypeCode) at the channel manager & the rate plan its rates are pushed to. Rates are
+// not pushed for a room without a rate plan
+type ChannelRoom struct {
+	RoomID       int
+	RoomCode     string
+	RatePlanCode string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Room         Room
+}
+
+// ChannelDay is a room's availability & nightly rate (in pence) for one night, as last pushed to the channel manager. A rate of zero
+// has not been pushed
+type ChannelDay struct {
+	Date time.Time
+	Open bool
+	Rate int
+}
+
+// ChannelSync is the channel sync model, one message pushed to the channel manager with the changes to a room's availability or
+// rates from StartDate to EndDate, as recorded in the sync log
+type ChannelSync struct {
+	ID           int
+	RoomID       int
+	Message      string // OTA_HotelAvailNotifRQ or OTA_HotelRateAmountNotifRQ
+	StartDate    time.Time
+	EndDate      time.Time
+	Changes      int // the number of runs of nights changed
+	Status       string
+	Attempts     int
+	ResponseCode int    // of the last attempt, zero if there was no response
+	LastError    string // why the push failed, empty if it succeeded
+	Request      string // the message as pushed
+	CreatedAt    time.Time
+	Room         Room
 }
 
 // RoomRestriction is the room restriction model (NB: LastInsertId() requires ReservationID as type int64)
@@ -56,11 +421,34 @@ type RoomRestriction struct {
 	EndDate             time.Time
 	CreatedAt           time.Time
 	UpdatedAt           time.Time
+	DeletedAt           time.Time // zero unless the restriction is in the trash
+	HoldToken           string    // identifies the guest session holding the room, for holds only
+	ExpiresAt           time.Time // when a hold lapses, zero for every other restriction
+	ImportID            int       // the iCal import an external booking was copied from, zero for every other restriction
+	ExternalUID         string    // the UID of an external booking's event within its calendar
 	Room                Room
 	Reservation         Reservation
 	RestrictionCategory RestrictionCategory
 }
 
+// NightlyPrice is the price of a single night within a quote (amounts held in pence)
+type NightlyPrice struct {
+	Date       time.Time
+	SeasonName string
+	Weekend    bool
+	RoomRate   int
+	ExtraGuest int
+	Amount     int
+}
+
+// Quote is the priced breakdown of a stay, night by night, with its total (amounts held in pence)
+type Quote struct {
+	RoomID int
+	Guests int
+	Nights []NightlyPrice
+	Total  int
+}
+
 // MailData holds an email message
 type MailData struct {
 	To       string
@@ -0,0 +1,309 @@
+package openapi
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Version is the version of the OpenAPI specification documents are written to
+const Version = "3.0.3"
+
+// Document is an OpenAPI document, holding only those parts of the specification used to describe this application's API
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path, keyed by lower case HTTP method e.g. "get"
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Schema describes a JSON value. Only the keywords needed to describe values produced by encoding/json from Go types are held
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+// refPrefix begins the reference to every schema held in a document's components
+const refPrefix = "#/components/schemas/"
+
+// New creates an empty document
+func New(info Info, servers ...Server) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Servers: servers,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: make(map[string]SecurityScheme),
+		},
+	}
+}
+
+// AddSchema generates a schema from the Go type of v, holds it in the document's components under the given name & returns a
+// reference to it
+func (d *Document) AddSchema(name, description string, v interface{}) *Schema {
+	s := SchemaOf(v)
+	s.Description = description
+	d.Components.Schemas[name] = s
+	return Ref(name)
+}
+
+// AddOperation adds an operation to a path, for a given HTTP method e.g. "GET"
+func (d *Document) AddOperation(path, method string, op *Operation) {
+	if d.Paths[path] == nil {
+		d.Paths[path] = make(PathItem)
+	}
+	d.Paths[path][strings.ToLower(method)] = op
+}
+
+// Ref is a reference to a schema held in a document's components
+func Ref(name string) *Schema {
+	return &Schema{Ref: refPrefix + name}
+}
+
+// JSON is the content of a request or response body given as JSON
+func JSON(s *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
+
+// Resolve follows a reference to a schema held in the document's components, returning any other schema as it is
+func (d *Document) Resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, refPrefix)]
+	}
+	return s
+}
+
+// methodOrder lists HTTP methods in the order their operations are listed by Operations
+var methodOrder = []string{"get", "post", "put", "patch", "delete"}
+
+// OperationRef is an operation together with its path & HTTP method
+type OperationRef struct {
+	Path   string
+	Method string
+	*Operation
+}
+
+// Operations lists every operation in the document, ordered by path & then method
+func (d *Document) Operations() []OperationRef {
+	var paths []string
+	for p := range d.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var ops []OperationRef
+	for _, p := range paths {
+		for _, m := range methodOrder {
+			if op, ok := d.Paths[p][m]; ok {
+				ops = append(ops, OperationRef{Path: p, Method: strings.ToUpper(m), Operation: op})
+			}
+		}
+	}
+	return ops
+}
+
+// Field is a single value within a schema, as listed by Fields e.g. "rooms[].price.total"
+type Field struct {
+	Name     string
+	Type     string
+	Required bool
+	Nullable bool
+	Enum     []string
+}
+
+// Fields lists every value within a schema, depth first & in property name order, for documenting it in a table
+func (d *Document) Fields(s *Schema) []Field {
+	var fields []Field
+	d.fields(d.Resolve(s), "", &fields)
+	return fields
+}
+
+func (d *Document) fields(s *Schema, prefix string, fields *[]Field) {
+	var names []string
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop := d.Resolve(s.Properties[name])
+		f := Field{
+			Name:     prefix + name,
+			Type:     prop.typeName(),
+			Required: contains(s.Required, name),
+			Nullable: prop.Nullable,
+			Enum:     prop.Enum,
+		}
+		*fields = append(*fields, f)
+
+		switch {
+		case prop.Type == "object" && prop.Properties != nil:
+			d.fields(prop, f.Name+".", fields)
+		case prop.Type == "array" && d.Resolve(prop.Items).Type == "object":
+			d.fields(d.Resolve(prop.Items), f.Name+"[].", fields)
+		}
+	}
+}
+
+// typeName describes a schema's type in words e.g. "array of integer", "string (date)"
+func (s *Schema) typeName() string {
+	switch {
+	case s.Type == "array" && s.Items != nil && s.Items.Type != "":
+		return "array of " + s.Items.typeName()
+	case s.Type == "object" && s.AdditionalProperties != nil:
+		return "map of " + s.AdditionalProperties.typeName()
+	case s.Format != "":
+		return s.Type + " (" + s.Format + ")"
+	}
+	return s.Type
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaOf generates a schema describing the JSON encoding/json produces for the Go type of v. Struct fields follow their json tags,
+// those marked omitempty being optional & pointers being nullable. A format tag e.g. `format:"date"` gives the format of a string
+func SchemaOf(v interface{}) *Schema {
+	return schemaOf(reflect.TypeOf(v))
+}
+
+func schemaOf(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		s := schemaOf(t.Elem())
+		s.Nullable = true
+		return s
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem())}
+	case reflect.Struct:
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				// unexported fields are not encoded
+				continue
+			}
+			name, opts := f.Name, ""
+			if tag, ok := f.Tag.Lookup("json"); ok {
+				if tag == "-" {
+					continue
+				}
+				if i := strings.Index(tag, ","); i >= 0 {
+					name, opts = tag[:i], tag[i:]
+				} else {
+					name = tag
+				}
+				if name == "" {
+					name = f.Name
+				}
+			}
+			prop := schemaOf(f.Type)
+			prop.Format = f.Tag.Get("format")
+			if prop.Format == "" && f.Type == timeType {
+				prop.Format = "date-time"
+			}
+			s.Properties[name] = prop
+			if !strings.Contains(opts, ",omitempty") {
+				s.Required = append(s.Required, name)
+			}
+		}
+		return s
+	}
+	// any other kind of value cannot be encoded as JSON, so is described as any value
+	return &Schema{}
+}
@@ -0,0 +1,138 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testPrice struct {
+	Total int `json:"total"`
+}
+
+type testRoom struct {
+	ID        int               `json:"id"`
+	Name      string            `json:"name"`
+	Available bool              `json:"available"`
+	Reason    string            `json:"reason,omitempty"`
+	Price     *testPrice        `json:"price"`
+	Arrival   string            `json:"arrival" format:"date"`
+	Nights    []int             `json:"nights"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	Updated   time.Time         `json:"updated"`
+	Ignored   string            `json:"-"`
+	internal  int
+}
+
+func TestSchemaOf(t *testing.T) {
+	s := SchemaOf(testRoom{})
+
+	if s.Type != "object" {
+		t.Fatalf("expected an object, got %q", s.Type)
+	}
+	expected := map[string]string{
+		"id":        "integer",
+		"name":      "string",
+		"available": "boolean",
+		"reason":    "string",
+		"price":     "object",
+		"arrival":   "string (date)",
+		"nights":    "array of integer",
+		"fields":    "map of string",
+		"updated":   "string (date-time)",
+	}
+	if len(s.Properties) != len(expected) {
+		t.Errorf("expected %d properties, got %d", len(expected), len(s.Properties))
+	}
+	for name, typ := range expected {
+		prop, ok := s.Properties[name]
+		if !ok {
+			t.Errorf("property %s is missing", name)
+			continue
+		}
+		if prop.typeName() != typ {
+			t.Errorf("property %s is %q, expected %q", name, prop.typeName(), typ)
+		}
+	}
+
+	if !s.Properties["price"].Nullable {
+		t.Error("expected a pointer to be nullable")
+	}
+	required := []string{"id", "name", "available", "price", "arrival", "nights", "updated"}
+	if !reflect.DeepEqual(s.Required, required) {
+		t.Errorf("expected required properties %v, got %v", required, s.Required)
+	}
+}
+
+var validateTests = []struct {
+	name    string
+	json    string
+	isValid bool
+}{
+	{"valid", `{"id":1,"name":"GQ","available":true,"price":{"total":100},"arrival":"2026-11-01","nights":[1,2],"updated":"2026-11-01T10:00:00Z"}`, true},
+	{"optional-given", `{"id":1,"name":"GQ","available":false,"reason":"booked","price":null,"arrival":"2026-11-01","nights":[],"fields":{"a":"b"},"updated":"2026-11-01T10:00:00Z"}`, true},
+	{"not-json", `{"id":`, false},
+	{"missing-required", `{"name":"GQ","available":true,"price":null,"arrival":"2026-11-01","nights":[],"updated":"2026-11-01T10:00:00Z"}`, false},
+	{"unknown-property", `{"id":1,"name":"GQ","available":true,"price":null,"arrival":"2026-11-01","nights":[],"updated":"2026-11-01T10:00:00Z","colour":"red"}`, false},
+	{"wrong-type", `{"id":"1","name":"GQ","available":true,"price":null,"arrival":"2026-11-01","nights":[],"updated":"2026-11-01T10:00:00Z"}`, false},
+	{"fractional-integer", `{"id":1.5,"name":"GQ","available":true,"price":null,"arrival":"2026-11-01","nights":[],"updated":"2026-11-01T10:00:00Z"}`, false},
+	{"invalid-date", `{"id":1,"name":"GQ","available":true,"price":null,"arrival":"01/11/2026","nights":[],"updated":"2026-11-01T10:00:00Z"}`, false},
+	{"invalid-nested", `{"id":1,"name":"GQ","available":true,"price":{"total":"100"},"arrival":"2026-11-01","nights":[],"updated":"2026-11-01T10:00:00Z"}`, false},
+	{"invalid-item", `{"id":1,"name":"GQ","available":true,"price":null,"arrival":"2026-11-01","nights":["one"],"updated":"2026-11-01T10:00:00Z"}`, false},
+	{"invalid-map-value", `{"id":1,"name":"GQ","available":true,"price":null,"arrival":"2026-11-01","nights":[],"fields":{"a":1},"updated":"2026-11-01T10:00:00Z"}`, false},
+	{"null-not-nullable", `{"id":1,"name":null,"available":true,"price":null,"arrival":"2026-11-01","nights":[],"updated":"2026-11-01T10:00:00Z"}`, false},
+}
+
+func TestDocument_ValidateJSON(t *testing.T) {
+	d := New(Info{Title: "Test", Version: "1"})
+	ref := d.AddSchema("Room", "a room", testRoom{})
+
+	for _, v := range validateTests {
+		err := d.ValidateJSON(ref, []byte(v.json))
+		if v.isValid && err != nil {
+			t.Errorf("%s: expected valid, got %v", v.name, err)
+		}
+		if !v.isValid && err == nil {
+			t.Errorf("%s: expected invalid", v.name)
+		}
+	}
+
+	// a reference to a schema not in the document
+	if err := d.ValidateJSON(Ref("Missing"), []byte(`{}`)); err == nil {
+		t.Error("expected a missing schema to be reported")
+	}
+}
+
+func TestDocument_Operations(t *testing.T) {
+	d := New(Info{Title: "Test", Version: "1"})
+	d.AddOperation("/rooms/{id}", "DELETE", &Operation{OperationID: "deleteRoom"})
+	d.AddOperation("/rooms", "POST", &Operation{OperationID: "addRoom"})
+	d.AddOperation("/rooms/{id}", "GET", &Operation{OperationID: "getRoom"})
+	d.AddOperation("/rooms", "GET", &Operation{OperationID: "listRooms"})
+
+	var ids []string
+	for _, op := range d.Operations() {
+		ids = append(ids, op.OperationID)
+	}
+	expected := []string{"listRooms", "addRoom", "getRoom", "deleteRoom"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected operations %v, got %v", expected, ids)
+	}
+}
+
+func TestDocument_Fields(t *testing.T) {
+	d := New(Info{Title: "Test", Version: "1"})
+	ref := d.AddSchema("List", "", struct {
+		Rooms []testPrice `json:"rooms"`
+		Count int         `json:"count,omitempty"`
+	}{})
+
+	var names []string
+	for _, f := range d.Fields(ref) {
+		names = append(names, f.Name)
+	}
+	expected := []string{"count", "rooms", "rooms[].total"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected fields %v, got %v", expected, names)
+	}
+}
@@ -0,0 +1,154 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ValidationError is returned when a JSON value does not match its schema, naming where within the value the problem was found
+type ValidationError struct {
+	Path    string
+	Problem string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Problem)
+}
+
+// ValidateJSON checks a JSON encoded value matches a schema. As schemas are generated from Go types, any object property not in the
+// schema is also an error, so that fields added to a type but not to a document are found
+func (d *Document) ValidateJSON(s *Schema, data []byte) error {
+	var v interface{}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return &ValidationError{Path: "$", Problem: fmt.Sprintf("not valid JSON: %s", err)}
+	}
+	return d.validate(s, v, "$")
+}
+
+func (d *Document) validate(s *Schema, v interface{}, path string) error {
+	if s.Ref != "" {
+		resolved := d.Resolve(s)
+		if resolved == nil {
+			return &ValidationError{Path: path, Problem: fmt.Sprintf("schema %s is not in the document", s.Ref)}
+		}
+		s = resolved
+	}
+
+	if v == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return &ValidationError{Path: path, Problem: fmt.Sprintf("expected %s, got null", s.Type)}
+	}
+
+	switch s.Type {
+	case "":
+		// any value
+		return nil
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return typeError(path, s.Type, v)
+		}
+		return validateString(s, str, path)
+	case "integer":
+		n, ok := v.(float64)
+		if !ok || n != math.Trunc(n) {
+			return typeError(path, s.Type, v)
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return typeError(path, s.Type, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return typeError(path, s.Type, v)
+		}
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			return typeError(path, s.Type, v)
+		}
+		for i, item := range items {
+			if err := d.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return typeError(path, s.Type, v)
+		}
+		return d.validateObject(s, obj, path)
+	default:
+		return &ValidationError{Path: path, Problem: fmt.Sprintf("unknown schema type %q", s.Type)}
+	}
+	return nil
+}
+
+func (d *Document) validateObject(s *Schema, obj map[string]interface{}, path string) error {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			return &ValidationError{Path: path, Problem: fmt.Sprintf("required property %q is missing", name)}
+		}
+	}
+
+	// check properties in name order, so the same problem is always reported first
+	var names []string
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, ok := s.Properties[name]
+		if !ok {
+			prop = s.AdditionalProperties
+		}
+		if prop == nil {
+			return &ValidationError{Path: path, Problem: fmt.Sprintf("property %q is not in the schema", name)}
+		}
+		if err := d.validate(prop, obj[name], path+"."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateString(s *Schema, str, path string) error {
+	if len(s.Enum) > 0 && !contains(s.Enum, str) {
+		return &ValidationError{Path: path, Problem: fmt.Sprintf("%q is not one of %s", str, strings.Join(s.Enum, ", "))}
+	}
+
+	var err error
+	switch s.Format {
+	case "date":
+		_, err = time.Parse("2006-01-02", str)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, str)
+	}
+	if err != nil {
+		return &ValidationError{Path: path, Problem: fmt.Sprintf("%q is not a valid %s", str, s.Format)}
+	}
+	return nil
+}
+
+func typeError(path, expected string, v interface{}) error {
+	got := "object"
+	switch v.(type) {
+	case string:
+		got = "string"
+	case float64:
+		got = "number"
+	case bool:
+		got = "boolean"
+	case []interface{}:
+		got = "array"
+	}
+	return &ValidationError{Path: path, Problem: fmt.Sprintf("expected %s, got %s", expected, got)}
+}
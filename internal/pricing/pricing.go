@@ -0,0 +1,136 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/models"
+)
+
+// QuoteStay prices a stay night by night, from arrival (start) up to, but not including, departure (end). A same day arrival &
+// departure is priced as a single night. Seasonal rates override the room's own rates for any night they cover, Friday & Saturday
+// nights use weekend rates (falling back to nightly rates if none are set) & guests beyond the room's included number are charged
+// the room's extra guest rate per night.
+func QuoteStay(room models.Room, seasons []models.SeasonalRate, start, end time.Time, guests int) (models.Quote, error) {
+	quote := models.Quote{
+		RoomID: room.ID,
+		Guests: guests,
+	}
+
+	start = models.DateOnly(start)
+	end = models.DateOnly(end)
+
+	if end.Before(start) {
+		return quote, errors.New("departure date cannot be before arrival date")
+	}
+
+	if end.Equal(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	extraGuests := 0
+	if room.IncludedGuests > 0 && guests > room.IncludedGuests {
+		extraGuests = guests - room.IncludedGuests
+	}
+
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		night := models.NightlyPrice{
+			Date:    d,
+			Weekend: IsWeekendNight(d),
+		}
+
+		nightly, weekend := room.NightlyRate, room.WeekendRate
+		if season, ok := seasonFor(seasons, d); ok {
+			night.SeasonName = season.SeasonName
+			nightly, weekend = season.NightlyRate, season.WeekendRate
+		}
+
+		night.RoomRate = nightly
+		if night.Weekend && weekend > 0 {
+			night.RoomRate = weekend
+		}
+
+		night.ExtraGuest = extraGuests * room.ExtraGuestRate
+		night.Amount = night.RoomRate + night.ExtraGuest
+
+		quote.Nights = append(quote.Nights, night)
+		quote.Total += night.Amount
+	}
+
+	return quote, nil
+}
+
+// IsWeekendNight returns true for the nights of Friday & Saturday
+func IsWeekendNight(d time.Time) bool {
+	return d.Weekday() == time.Friday || d.Weekday() == time.Saturday
+}
+
+// seasonFor returns the seasonal rate covering a given night, where seasons overlap the one starting latest takes precedence
+func seasonFor(seasons []models.SeasonalRate, d time.Time) (models.SeasonalRate, bool) {
+	var found models.SeasonalRate
+	ok := false
+	for _, s := range seasons {
+		if d.Before(models.DateOnly(s.StartDate)) || d.After(models.DateOnly(s.EndDate)) {
+			continue
+		}
+		if !ok || s.StartDate.After(found.StartDate) {
+			found = s
+			ok = true
+		}
+	}
+	return found, ok
+}
+
+// FormatAmount formats an amount held in pence as pounds sterling e.g. 8050 as £80.50
+func FormatAmount(pence int) string {
+	if pence < 0 {
+		return "-£" + Pounds(-pence)
+	}
+	return "£" + Pounds(pence)
+}
+
+// Pounds formats an amount held in pence as pounds, without a currency sign, suitable for editing e.g. 8050 as 80.50
+func Pounds(pence int) string {
+	sign := ""
+	if pence < 0 {
+		sign = "-"
+		pence = -pence
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, pence/100, pence%100)
+}
+
+// ParseAmount parses an amount entered in pounds, with or without pence & an optional £ sign e.g. "£80.5", into pence
+func ParseAmount(s string) (int, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "£")
+	if s == "" {
+		return 0, errors.New("no amount given")
+	}
+
+	pounds, pence := s, "00"
+	if i := strings.Index(s, "."); i >= 0 {
+		pounds, pence = s[:i], s[i+1:]
+		if len(pence) == 1 {
+			pence += "0"
+		}
+		if len(pence) != 2 {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+	}
+	if pounds == "" {
+		pounds = "0"
+	}
+
+	p, err := strconv.Atoi(pounds)
+	if err != nil || p < 0 {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	q, err := strconv.Atoi(pence)
+	if err != nil || q < 0 {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	return p*100 + q, nil
+}
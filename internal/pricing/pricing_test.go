@@ -0,0 +1,154 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/models"
+)
+
+var testRoom = models.Room{
+	ID:             1,
+	RoomName:       "General's Quarters",
+	NightlyRate:    8000,
+	WeekendRate:    9500,
+	IncludedGuests: 2,
+	ExtraGuestRate: 1500,
+}
+
+var testSeasons = []models.SeasonalRate{
+	{
+		ID:          1,
+		RoomID:      1,
+		SeasonName:  "Christmas",
+		StartDate:   date("24/12/2026"),
+		EndDate:     date("26/12/2026"),
+		NightlyRate: 12000,
+		WeekendRate: 0,
+	},
+}
+
+func date(s string) time.Time {
+	d, _ := time.Parse("02/01/2006", s)
+	return d
+}
+
+var quoteTests = []struct {
+	name                string
+	start, end          string
+	guests              int
+	expectedNights      int
+	expectedTotal       int
+	expectedError       bool
+	expectedFirstSeason string
+}{
+	// 02/11/2026 is a Monday
+	{"weekdays", "02/11/2026", "05/11/2026", 2, 3, 3 * 8000, false, ""},
+	// Thursday, Friday & Saturday nights
+	{"weekend", "05/11/2026", "08/11/2026", 2, 3, 8000 + 2*9500, false, ""},
+	{"extra-guests", "02/11/2026", "04/11/2026", 4, 2, 2 * (8000 + 2*1500), false, ""},
+	{"same-day", "02/11/2026", "02/11/2026", 1, 1, 8000, false, ""},
+	// 23/12/2026 is a Wednesday, 24/12 to 26/12 are seasonal (26/12 is a Saturday, but the season has no weekend rate)
+	{"seasonal", "24/12/2026", "27/12/2026", 2, 3, 3 * 12000, false, "Christmas"},
+	{"into-season", "23/12/2026", "25/12/2026", 2, 2, 8000 + 12000, false, ""},
+	{"end-before-start", "05/11/2026", "02/11/2026", 2, 0, 0, true, ""},
+}
+
+func TestQuoteStay(t *testing.T) {
+	for _, v := range quoteTests {
+		quote, err := QuoteStay(testRoom, testSeasons, date(v.start), date(v.end), v.guests)
+
+		if v.expectedError {
+			if err == nil {
+				t.Errorf("QuoteStay (%s) returned no error, expected an error", v.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("QuoteStay (%s) returned error: %s", v.name, err)
+			continue
+		}
+
+		if len(quote.Nights) != v.expectedNights {
+			t.Errorf("QuoteStay (%s) returned %d nights, expected %d", v.name, len(quote.Nights), v.expectedNights)
+		}
+		if quote.Total != v.expectedTotal {
+			t.Errorf("QuoteStay (%s) returned total %d, expected %d", v.name, quote.Total, v.expectedTotal)
+		}
+		if len(quote.Nights) > 0 && quote.Nights[0].SeasonName != v.expectedFirstSeason {
+			t.Errorf("QuoteStay (%s) returned first night season %q, expected %q", v.name, quote.Nights[0].SeasonName, v.expectedFirstSeason)
+		}
+
+		sum := 0
+		for _, n := range quote.Nights {
+			sum += n.Amount
+		}
+		if sum != quote.Total {
+			t.Errorf("QuoteStay (%s) nightly breakdown sums to %d but total is %d", v.name, sum, quote.Total)
+		}
+	}
+}
+
+// TestQuoteStay_TimeZone checks dates given away from UTC are priced as the same calendar days as seasons, stored in UTC, so that a
+// stay is counted the same by the quote as by stay rules
+func TestQuoteStay_TimeZone(t *testing.T) {
+	zone := time.FixedZone("NZDT", 13*60*60)
+	start := time.Date(2026, 12, 24, 0, 0, 0, 0, zone)
+	end := time.Date(2026, 12, 27, 0, 0, 0, 0, zone)
+
+	quote, err := QuoteStay(testRoom, testSeasons, start, end, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quote.Nights) != 3 || quote.Total != 3*12000 || !quote.Nights[0].Date.Equal(date("24/12/2026")) {
+		t.Errorf("QuoteStay returned %+v, expected 3 Christmas nights from 24/12/2026", quote)
+	}
+}
+
+func TestIsWeekendNight(t *testing.T) {
+	if !IsWeekendNight(date("06/11/2026")) {
+		t.Error("Friday night not treated as a weekend night")
+	}
+	if IsWeekendNight(date("08/11/2026")) {
+		t.Error("Sunday night treated as a weekend night")
+	}
+}
+
+var amountTests = []struct {
+	input         string
+	expected      int
+	expectedError bool
+}{
+	{"80", 8000, false},
+	{"80.5", 8050, false},
+	{"£80.50", 8050, false},
+	{".99", 99, false},
+	{"", 0, true},
+	{"eighty", 0, true},
+	{"80.505", 0, true},
+	{"-5", 0, true},
+}
+
+func TestParseAmount(t *testing.T) {
+	for _, v := range amountTests {
+		amount, err := ParseAmount(v.input)
+		if v.expectedError {
+			if err == nil {
+				t.Errorf("ParseAmount(%q) returned no error, expected an error", v.input)
+			}
+			continue
+		}
+		if err != nil || amount != v.expected {
+			t.Errorf("ParseAmount(%q) returned %d (%v), expected %d", v.input, amount, err, v.expected)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	if FormatAmount(8050) != "£80.50" {
+		t.Errorf("FormatAmount(8050) returned %s, expected £80.50", FormatAmount(8050))
+	}
+	if FormatAmount(5) != "£0.05" {
+		t.Errorf("FormatAmount(5) returned %s, expected £0.05", FormatAmount(5))
+	}
+}
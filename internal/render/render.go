@@ -12,6 +12,7 @@ import (
 
 	"github.com/StratoNET/bnb-bookings/internal/config"
 	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/pricing"
 	"github.com/justinas/nosurf"
 )
 
@@ -20,6 +21,8 @@ var functions = template.FuncMap{
 	// "add":         Add,
 	"dateUK":      DateUK,
 	"iterateDays": IterateDays,
+	"currency":    pricing.FormatAmount,
+	"pounds":      pricing.Pounds,
 }
 
 var app *config.AppConfig
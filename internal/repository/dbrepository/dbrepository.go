@@ -1,15 +1,19 @@
 package dbrepository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/StratoNET/bnb-bookings/internal/config"
+	"github.com/StratoNET/bnb-bookings/internal/database"
 	"github.com/StratoNET/bnb-bookings/internal/repository"
 )
 
-type mariaDBRepository struct {
+// sqlDBRepository is the repository of every SQL database, its queries written once for MariaDB & executed in the database's own dialect
+type sqlDBRepository struct {
 	App *config.AppConfig
-	DB  *sql.DB
+	DB  *dialectDB
 }
 
 type testDBRepository struct {
@@ -18,9 +22,21 @@ type testDBRepository struct {
 }
 
 func NewMariaDBRepository(conn *sql.DB, app *config.AppConfig) repository.DatabaseRepository {
-	return &mariaDBRepository{
+	return newSQLDBRepository(conn, database.MariaDB, app)
+}
+
+func NewPostgresDBRepository(conn *sql.DB, app *config.AppConfig) repository.DatabaseRepository {
+	return newSQLDBRepository(conn, database.Postgres, app)
+}
+
+func NewSQLiteDBRepository(conn *sql.DB, app *config.AppConfig) repository.DatabaseRepository {
+	return newSQLDBRepository(conn, database.SQLite, app)
+}
+
+func newSQLDBRepository(conn *sql.DB, driver string, app *config.AppConfig) *sqlDBRepository {
+	return &sqlDBRepository{
 		App: app,
-		DB:  conn,
+		DB:  &dialectDB{DB: conn, dialect: dialects[driver]},
 	}
 }
 
@@ -29,3 +45,27 @@ func NewTestingDBRepository(app *config.AppConfig) repository.DatabaseRepository
 		App: app,
 	}
 }
+
+// operationContext derives the context of a database operation from its caller's, ending it once the operation's configured timeout has
+// passed, should the caller's context not end first e.g. on the client disconnecting
+func operationContext(ctx context.Context, app *config.AppConfig, op string) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, app.DBTimeouts.For(op))
+}
+
+// interrupted returns err as a *repository.InterruptedError should the operation's context have ended, since drivers report being
+// interrupted in their own ways e.g. 'pq: canceling statement due to user request', otherwise err unchanged. A room being unavailable or
+// a status having changed is reported as such, being known whether or not the operation was then interrupted
+func interrupted(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+
+	var interrupted *repository.InterruptedError
+	var unavailable *repository.RoomUnavailableError
+	var changed *repository.StatusChangedError
+	if errors.As(err, &interrupted) || errors.As(err, &unavailable) || errors.As(err, &changed) {
+		return err
+	}
+
+	return &repository.InterruptedError{Err: ctx.Err(), Cause: err}
+}
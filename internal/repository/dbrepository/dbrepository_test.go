@@ -0,0 +1,1122 @@
+package dbrepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/apitoken"
+	"github.com/StratoNET/bnb-bookings/internal/database"
+	"github.com/StratoNET/bnb-bookings/internal/lifecycle"
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/repository"
+)
+
+// schemaTables are the tables of the schema in the order they are created, so they may be dropped in reverse
+var schemaTables = []string{
+	"schema_migrations", "administrators", "api_tokens", "rooms", "restrictions", "booking_groups", "reservations", "reservation_status_changes",
+	"ical_imports", "room_restrictions", "waitlist", "seasonal_rates", "stay_rules", "ical_feeds", "webhooks", "webhook_deliveries",
+	"channel_rooms", "channel_days", "channel_syncs",
+}
+
+// resetSchema drops every table of the schema, then migrates a database of the driver's afresh, with its two rooms, & seeds it with an
+// administrator & room rates
+func resetSchema(t *testing.T, db *sql.DB, driver string) {
+	t.Helper()
+
+	dropSchema(t, db)
+	_, err := database.MigrateUp(&database.DB{SQL: db, Driver: driver})
+	if err != nil {
+		t.Fatal("cannot migrate", err)
+	}
+	execSQLFile(t, db, "testdata/seed.sql")
+}
+
+// dropSchema drops every table of the schema
+func dropSchema(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	for i := len(schemaTables) - 1; i >= 0; i-- {
+		_, err := db.Exec("DROP TABLE IF EXISTS " + schemaTables[i] + ";")
+		if err != nil {
+			t.Fatal("cannot drop table", schemaTables[i], err)
+		}
+	}
+}
+
+// execSQLFile executes every statement of a file of SQL
+func execSQLFile(t *testing.T, db *sql.DB, name string) {
+	t.Helper()
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range sqlStatements(string(b)) {
+		_, err = db.Exec(stmt)
+		if err != nil {
+			t.Fatalf("%s: cannot execute %q: %s", name, stmt, err)
+		}
+	}
+}
+
+// sqlStatements splits a file of SQL into its statements, each ending with a semicolon at the end of a line, leaving out comments
+func sqlStatements(s string) []string {
+	var stmts []string
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		lines = append(lines, line)
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			stmts = append(stmts, strings.TrimSpace(strings.Join(lines, "\n")))
+			lines = nil
+		}
+	}
+	return stmts
+}
+
+// repositoryTests are the behaviours every DatabaseRepository must share, whichever database it is backed by
+var repositoryTests = []struct {
+	name string
+	test func(t *testing.T, repo repository.DatabaseRepository)
+}{
+	{"rooms", testRooms},
+	{"administrators", testAdministrators},
+	{"availability", testAvailability},
+	{"room-restrictions", testRoomRestrictions},
+	{"reservations", testReservations},
+	{"reservation-status", testReservationStatus},
+	{"holds", testHolds},
+	{"trash", testTrash},
+	{"booking-groups", testBookingGroups},
+	{"import-reservations", testImportReservations},
+	{"waitlist", testWaitlist},
+	{"seasonal-rates-and-stay-rules", testSeasonalRatesAndStayRules},
+	{"ical-feeds", testICalFeeds},
+	{"ical-imports", testICalImports},
+	{"webhooks", testWebhooks},
+	{"channel", testChannel},
+	{"interrupted", testInterrupted},
+}
+
+// testDatabaseRepository runs every behavioural test against a repository, newRepo giving each test a freshly seeded database
+func testDatabaseRepository(t *testing.T, newRepo func(t *testing.T) repository.DatabaseRepository) {
+	for _, v := range repositoryTests {
+		t.Run(v.name, func(t *testing.T) {
+			v.test(t, newRepo(t))
+		})
+	}
+}
+
+// day gives a date in March 2098, well clear of today, as parsed from a form
+func day(d int) time.Time {
+	return time.Date(2098, 3, d, 0, 0, 0, 0, time.UTC)
+}
+
+func testReservation(roomID, start, end int) models.Reservation {
+	return models.Reservation{
+		RoomID:          roomID,
+		FirstName:       "John",
+		LastName:        "Smith",
+		Email:           "john@example.com",
+		Phone:           "07700 900123",
+		StartDate:       day(start),
+		EndDate:         day(end),
+		TotalPrice:      32000,
+		Adults:          2,
+		ConfirmationRef: "BNB-TEST",
+	}
+}
+
+func mustReserve(t *testing.T, repo repository.DatabaseRepository, rsvn models.Reservation) int {
+	t.Helper()
+	ctx := context.Background()
+	id, err := repo.CreateReservation(ctx, rsvn, "")
+	if err != nil {
+		t.Fatal("CreateReservation failed", err)
+	}
+	return int(id)
+}
+
+func expectAvailable(t *testing.T, repo repository.DatabaseRepository, roomID, start, end int, expected bool) {
+	t.Helper()
+	ctx := context.Background()
+	available, err := repo.SearchAvailabilityByDatesAndRoomID(ctx, day(start), day(end), roomID, "")
+	if err != nil {
+		t.Fatal("SearchAvailabilityByDatesAndRoomID failed", err)
+	}
+	if available != expected {
+		t.Errorf("room %d from %d to %d March: expected available %t, got %t", roomID, start, end, expected, available)
+	}
+}
+
+func expectUnavailable(t *testing.T, err error, what string) {
+	t.Helper()
+	var unavailable *repository.RoomUnavailableError
+	if !errors.As(err, &unavailable) {
+		t.Errorf("%s: expected *repository.RoomUnavailableError, got %v", what, err)
+	}
+}
+
+func testRooms(t *testing.T, repo repository.DatabaseRepository) {
+	ctx := context.Background()
+	rooms, err := repo.GetAllRooms(ctx)
+	if err != nil || len(rooms) != 2 {
+		t.Fatalf("expected 2 rooms, got %d %v", len(rooms), err)
+	}
+
+	room, err := repo.GetRoomByID(ctx, 1)
+	if err != nil || room.RoomName != "General's Quarters" || room.NightlyRate != 8000 || room.MaxAdults != 2 {
+		t.Errorf("unexpected room 1: %+v %v", room, err)
+	}
+	if _, err = repo.GetRoomByID(ctx, 99); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for a missing room, got %v", err)
+	}
+
+	err = repo.UpdateRoomRates(ctx, models.Room{ID: 1, NightlyRate: 8500, WeekendRate: 9900, ExtraGuestRate: 1000})
+	if err != nil {
+		t.Fatal("UpdateRoomRates failed", err)
+	}
+	err = repo.UpdateRoomOccupancy(ctx, models.Room{ID: 1, MaxAdults: 3, MaxChildren: 2, MaxInfants: 0})
+	if err != nil {
+		t.Fatal("UpdateRoomOccupancy failed", err)
+	}
+	room, _ = repo.GetRoomByID(ctx, 1)
+	if room.NightlyRate != 8500 || room.WeekendRate != 9900 || room.ExtraGuestRate != 1000 || room.MaxAdults != 3 || room.MaxChildren != 2 || room.MaxInfants != 0 {
+		t.Errorf("expected rates & occupancy to be updated, got %+v", room)
+	}
+}
+
+func testAdministrators(t *testing.T, repo repository.DatabaseRepository) {
+	ctx := context.Background()
+	if !repo.AllAdministrators() {
+		t.Error("expected AllAdministrators to be true")
+	}
+
+	id, _, err := repo.AuthenticateAdministrator(ctx, "admin@example.com", "password")
+	if err != nil || id != 1 {
+		t.Errorf("expected administrator 1 to authenticate, got %d %v", id, err)
+	}
+	if _, _, err = repo.AuthenticateAdministrator(ctx, "admin@example.com", "wrong"); err == nil {
+		t.Error("expected an incorrect password to fail")
+	}
+	if _, _, err = repo.AuthenticateAdministrator(ctx, "nobody@example.com", "password"); err == nil {
+		t.Error("expected an unknown email to fail")
+	}
+
+	admin, err := repo.GetAdministratorByID(ctx, 1)
+	if err != nil || admin.Email != "admin@example.com" || admin.AccessLevel != 3 {
+		t.Errorf("unexpected administrator: %+v %v", admin, err)
+	}
+
+	hash := apitoken.Hash("bnb_secret")
+	err = repo.InsertAPIToken(ctx, models.APIToken{AdministratorID: 1, Name: "Zapier", TokenHash: hash})
+	if err != nil {
+		t.Fatal("InsertAPIToken failed", err)
+	}
+	tokens, err := repo.GetAPITokensByAdministratorID(ctx, 1)
+	if err != nil || len(tokens) != 1 || tokens[0].Name != "Zapier" || !tokens[0].LastUsedAt.IsZero() {
+		t.Fatalf("expected one unused token, got %+v %v", tokens, err)
+	}
+
+	admin, err = repo.GetAdministratorByAPIToken(ctx, hash)
+	if err != nil || admin.ID != 1 {
+		t.Errorf("expected token to belong to administrator 1, got %+v %v", admin, err)
+	}
+	if _, err = repo.GetAdministratorByAPIToken(ctx, apitoken.Hash("unknown")); err == nil {
+		t.Error("expected an unknown token to fail")
+	}
+	tokens, _ = repo.GetAPITokensByAdministratorID(ctx, 1)
+	if len(tokens) != 1 || tokens[0].LastUsedAt.IsZero() {
+		t.Errorf("expected token to be recorded as used, got %+v", tokens)
+	}
+
+	// a token can only be revoked by its own administrator
+	if err = repo.DeleteAPIToken(ctx, tokens[0].ID, 2); err != nil {
+		t.Fatal("DeleteAPIToken failed", err)
+	}
+	if tokens, _ = repo.GetAPITokensByAdministratorID(ctx, 1); len(tokens) != 1 {
+		t.Errorf("expected token to be kept, got %d tokens", len(tokens))
+	}
+	if err = repo.DeleteAPIToken(ctx, tokens[0].ID, 1); err != nil {
+		t.Fatal("DeleteAPIToken failed", err)
+	}
+	if tokens, _ = repo.GetAPITokensByAdministratorID(ctx, 1); len(tokens) != 0 {
+		t.Errorf("expected token to be revoked, got %d tokens", len(tokens))
+	}
+}
+
+func testAvailability(t *testing.T, repo repository.DatabaseRepository) {
+	ctx := context.Background()
+	expectAvailable(t, repo, 1, 1, 5, true)
+	mustReserve(t, repo, testReservation(1, 1, 5))
+
+	expectAvailable(t, repo, 1, 1, 5, false)
+	expectAvailable(t, repo, 1, 3, 4, false)
+	// arriving on the day of departure counts as taken
+	expectAvailable(t, repo, 1, 5, 7, false)
+	expectAvailable(t, repo, 1, 6, 8, true)
+	expectAvailable(t, repo, 2, 1, 5, true)
+
+	var tests = []struct {
+		name                     string
+		start, end               int
+		adults, children, infant int
+		expected                 []string
+	}{
+		{"both-free", 10, 12, 2, 0, 0, []string{"General's Quarters", "Major's Suite"}},
+		{"too-many-adults", 10, 12, 3, 0, 0, []string{"Major's Suite"}},
+		{"too-many-infants", 10, 12, 2, 0, 2, nil},
+		{"one-taken", 3, 4, 2, 0, 0, []string{"Major's Suite"}},
+	}
+	for _, v := range tests {
+		rooms, err := repo.SearchAvailabilityForAllRooms(ctx, day(v.start), day(v.end), v.adults, v.children, v.infant, "")
+		if err != nil {
+			t.Fatal("SearchAvailabilityForAllRooms failed", err)
+		}
+		var names []string
+		for _, room := range rooms {
+			names = append(names, room.RoomName)
+		}
+		// rooms are found in no particular order
+		sort.Strings(names)
+		if strings.Join(names, ",") != strings.Join(v.expected, ",") {
+			t.Errorf("%s: expected %v, got %v", v.name, v.expected, names)
+		}
+	}
+}
+
+func testRoomRestrictions(t *testing.T, repo repository.DatabaseRepository) {
+	ctx := context.Background()
+	rsvnID := mustReserve(t, repo, testReservation(1, 1, 5))
+
+	_, err := repo.CreateReservation(ctx, testReservation(1, 4, 8), "")
+	expectUnavailable(t, err, "overlapping reservation")
+	_, err = repo.CreateRoomBlock(ctx, 1, day(5), day(6))
+	expectUnavailable(t, err, "overlapping block")
+
+	blockID, err := repo.CreateRoomBlock(ctx, 1, day(10), day(12))
+	if err != nil || blockID == 0 {
+		t.Fatalf("expected block to be created, got %d %v", blockID, err)
+	}
+	expectAvailable(t, repo, 1, 11, 11, false)
+
+	// an owner block inserted directly is not checked against what is already there
+	if err = repo.InsertRoomBlock(ctx, 1, day(11), day(12)); err != nil {
+		t.Fatal("InsertRoomBlock failed", err)
+	}
+	if err = repo.InsertRoomBlock(ctx, 2, day(1), day(2)); err != nil {
+		t.Fatal("InsertRoomBlock failed", err)
+	}
+
+	restrictions, err := repo.GetRoomRestrictionsByDate(ctx, 1, day(1), day(31))
+	if err != nil || len(restrictions) != 3 {
+		t.Fatalf("expected 3 restrictions for room 1, got %d %v", len(restrictions), err)
+	}
+	for _, rr := range restrictions {
+		switch {
+		case rr.ReservationID == int64(rsvnID):
+			if rr.RestrictionID != models.RestrictionReservation || rr.Reservation.Status != models.StatusPending || !rr.StartDate.Equal(day(1)) || !rr.EndDate.Equal(day(5)) {
+				t.Errorf("unexpected reservation restriction %+v", rr)
+			}
+		case rr.RestrictionID != models.RestrictionOwnerBlock || rr.Reservation.Status != "":
+			t.Errorf("unexpected restriction %+v", rr)
+		}
+	}
+	if restrictions, _ = repo.GetRoomRestrictionsByDate(ctx, 1, day(6), day(9)); len(restrictions) != 0 {
+		t.Errorf("expected no restrictions between stays, got %+v", restrictions)
+	}
+
+	calendar, err := repo.GetRoomRestrictionsForCalendar(ctx, 1, day(1), day(10))
+	if err != nil || len(calendar) != 2 || !calendar[0].StartDate.Equal(day(1)) || !calendar[1].StartDate.Equal(day(10)) {
+		t.Errorf("expected the reservation & first block in date order, got %+v %v", calendar, err)
+	}
+
+	feed, err := repo.GetRoomRestrictionsForFeed(ctx, 1, day(5))
+	if err != nil || len(feed) != 3 {
+		t.Fatalf("expected 3 restrictions ending on or after 5 March, got %d %v", len(feed), err)
+	}
+	if feed[0].Reservation.FirstName != "John" || feed[0].Reservation.ConfirmationRef != "BNB-TEST" || feed[0].Reservation.Adults != 2 {
+		t.Errorf("expected the reservation with its guest first, got %+v", feed[0])
+	}
+	if feed, _ = repo.GetRoomRestrictionsForFeed(ctx, 1, day(6)); len(feed) != 2 {
+		t.Errorf("expected only the blocks to end on or after 6 March, got %d", len(feed))
+	}
+}
+
+func testReservations(t *testing.T, repo repository.DatabaseRepository) {
+	ctx := context.Background()
+	first := testReservation(1, 10, 12)
+	first.ConfirmationRef = "BNB-FIRST"
+	firstID := mustReserve(t, repo, first)
+	secondID := mustReserve(t, repo, testReservation(2, 1, 3))
+
+	rsvn, err := repo.GetReservationByID(ctx, firstID)
+	if err != nil {
+		t.Fatal("GetReservationByID failed", err)
+	}
+	if rsvn.RoomID != 1 || rsvn.Room.RoomName != "General's Quarters" || rsvn.Status != models.StatusPending || rsvn.TotalPrice != 32000 ||
+		rsvn.Adults != 2 || !rsvn.StartDate.Equal(day(10)) || !rsvn.EndDate.Equal(day(12)) || rsvn.GroupID != 0 {
+		t.Errorf("unexpected reservation %+v", rsvn)
+	}
+	if _, err = repo.GetReservationByID(ctx, 99); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for a missing reservation, got %v", err)
+	}
+
+	rsvn, err = repo.GetReservationByRef(ctx, "BNB-FIRST")
+	if err != nil || rsvn.ID != firstID {
+		t.Errorf("expected reservation %d by reference, got %+v %v", firstID, rsvn, err)
+	}
+
+	rsvn.FirstName = "Jane"
+	rsvn.Email = "jane@example.com"
+	if err = repo.UpdateReservation(ctx, rsvn); err != nil {
+		t.Fatal("UpdateReservation failed", err)
+	}
+	rsvn, _ = repo.GetReservationByID(ctx, firstID)
+	if rsvn.FirstName != "Jane" || rsvn.Email != "jane@example.com" || rsvn.LastName != "Smith" {
+		t.Errorf("expected guest's details to be updated, got %+v", rsvn)
+	}
+
+	all, err := repo.GetAllReservations(ctx)
+	if err != nil || len(all) != 2 {
+		t.Errorf("expected 2 reservations, got %d %v", len(all), err)
+	}
+	pending, err := repo.GetNewReservations(ctx)
+	if err != nil || len(pending) != 2 {
+		t.Errorf("expected 2 new reservations, got %d %v", len(pending), err)
+	}
+
+	var tests = []struct {
+		name     string
+		filter   models.ReservationFilter
+		expected []int
+	}{
+		{"all", models.ReservationFilter{}, []int{secondID, firstID}},
+		{"room", models.ReservationFilter{RoomID: 1}, []int{firstID}},
+		{"status", models.ReservationFilter{Status: models.StatusConfirmed}, nil},
+		{"departing-on-or-after", models.ReservationFilter{StartDate: day(3)}, []int{secondID, firstID}},
+		{"arriving-on-or-before", models.ReservationFilter{EndDate: day(9)}, []int{secondID}},
+		{"between", models.ReservationFilter{StartDate: day(4), EndDate: day(9)}, nil},
+	}
+	for _, v := range tests {
+		filtered, err := repo.FilterReservations(ctx, v.filter)
+		if err != nil {
+			t.Fatal("FilterReservations failed", err)
+		}
+		var ids []int
+		for _, r := range filtered {
+			ids = append(ids, r.ID)
+		}
+		if len(ids) != len(v.expected) || (len(ids) > 0 && ids[0] != v.expected[0]) {
+			t.Errorf("%s: expected %v, got %v", v.name, v.expected, ids)
+		}
+	}
+
+	// streaming stops at the first error returned
+	stop := errors.New("stop")
+	var streamed int
+	err = repo.StreamReservations(ctx, models.ReservationFilter{}, func(models.Reservation) error {
+		streamed++
+		return stop
+	})
+	if !errors.Is(err, stop) || streamed != 1 {
+		t.Errorf("expected streaming to stop after one reservation, got %d %v", streamed, err)
+	}
+
+	// a reservation & its restriction may also be inserted separately
+	id, err := repo.InsertReservation(ctx, testReservation(2, 20, 22))
+	if err != nil || id == 0 {
+		t.Fatalf("expected reservation to be inserted, got %d %v", id, err)
+	}
+	err = repo.InsertRoomRestriction(ctx, models.RoomRestriction{RoomID: 2, ReservationID: id, RestrictionID: models.RestrictionReservation, StartDate: day(20), EndDate: day(22)})
+	if err != nil {
+		t.Fatal("InsertRoomRestriction failed", err)
+	}
+	expectAvailable(t, repo, 2, 21, 21, false)
+}
+
+func testReservationStatus(t *testing.T, repo repository.DatabaseRepository) {
+	ctx := context.Background()
+	id := mustReserve(t, repo, testReservation(1, 1, 5))
+
+	if err := repo.UpdateReservationStatus(ctx, id, models.StatusPending, models.StatusConfirmed); err != nil {
+		t.Fatal("UpdateReservationStatus failed", err)
+	}
+	confirmed, err := repo.GetReservationsByStatus(ctx, models.StatusConfirmed)
+	if err != nil || len(confirmed) != 1 || confirmed[0].ID != id {
+		t.Errorf("expected reservation to be confirmed, got %+v %v", confirmed, err)
+	}
+
+	// the reservation is no longer pending
+	err = repo.UpdateReservationStatus(ctx, id, models.StatusPending, models.StatusConfirmed)
+	var changed *repository.StatusChangedError
+	if !errors.As(err, &changed) || changed.ReservationID != id {
+		t.Errorf("expected *repository.StatusChangedError, got %v", err)
+	}
+	// nor can it go back to pending
+	err = repo.UpdateReservationStatus(ctx, id, models.StatusConfirmed, models.StatusPending)
+	var transition *lifecycle.TransitionError
+	if !errors.As(err, &transition) {
+		t.Errorf("expected *lifecycle.TransitionError, got %v", err)
+	}
+
+	// cancelling frees the room but keeps the reservation
+	if err = repo.UpdateReservationStatus(ctx, id, models.StatusConfirmed, models.StatusCancelled); err != nil {
+		t.Fatal("UpdateReservationStatus failed", err)
+	}
+	expectAvailable(t, repo, 1, 1, 5, true)
+	rsvn, err := repo.GetReservationByID(ctx, id)
+	if err != nil || rsvn.Status != models.StatusCancelled {
+		t.Errorf("expected reservation to be kept as cancelled, got %+v %v", rsvn, err)
+	}
+
+	changes, err := repo.GetReservationStatusChanges(ctx, id)
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("expected 2 status changes, got %+v %v", changes, err)
+	}
+	if changes[0].FromStatus != models.StatusPending || changes[0].ToStatus != models.StatusConfirmed || changes[1].ToStatus != models.StatusCancelled {
+		t.Errorf("expected status changes oldest first, got %+v", changes)
+	}
+
+	// a cancelled reservation restored from the trash leaves the room free
+	if err = repo.DeleteReservation(ctx, id); err != nil {
+		t.Fatal("DeleteReservation failed", err)
+	}
+	if err = repo.RestoreReservation(ctx, id); err != nil {
+		t.Fatal("RestoreReservation failed", err)
+	}
+	expectAvailable(t, repo, 1, 1, 5, true)
+	if deleted, _ := repo.GetDeletedReservations(ctx); len(deleted) != 0 {
+		t.Errorf("expected the trash to hold no reservations, got %d", len(deleted))
+	}
+}
+
+func testHolds(t *testing.T, repo repository.DatabaseRepository) {
+	ctx := context.Background()
+	expires := time.Now().Add(time.Hour)
+	hold := models.RoomRestriction{RoomID: 1, StartDate: day(1), EndDate: day(3), HoldToken: "guest-a", ExpiresAt: expires}
+
+	if err := repo.PlaceHold(ctx, hold); err != nil {
+		t.Fatal("PlaceHold failed", err)
+	}
+	expectAvailable(t, repo, 1, 2, 2, false)
+
+	// holds are on the calendar, though not in the feed
+	if calendar, _ := repo.GetRoomRestrictionsForCalendar(ctx, 1, day(1), day(3)); len(calendar) != 1 || calendar[0].RestrictionID != models.RestrictionHold {
+		t.Errorf("expected the hold on the calendar, got %+v", calendar)
+	}
+	if feed, _ := repo.GetRoomRestrictionsForFeed(ctx, 1, day(1)); len(feed) != 0 {
+		t.Errorf("expected no holds in the feed, got %+v", feed)
+	}
+
+	// the guest holding the room still finds it available, as does a search for every room, though no other guest does
+	available, err := repo.SearchAvailabilityByDatesAndRoomID(ctx, day(2), day(2), 1, "guest-a")
+	if err != nil || !available {
+		t.Errorf("expected the held room to be available to the guest holding it, got %t %v", available, err)
+	}
+	if available, _ = repo.SearchAvailabilityByDatesAndRoomID(ctx, day(2), day(2), 1, "guest-b"); available {
+		t.Error("expected the held room to be unavailable to another guest")
+	}
+	for token, expected := range map[string]bool{"guest-a": true, "guest-b": false} {
+		rooms, err := repo.SearchAvailabilityForAllRooms(ctx, day(1), day(3), 2, 0, 0, token)
+		found := false
+		for _, rm := range rooms {
+			found = found || rm.ID == 1
+		}
+		if err != nil || found != expected {
+			t.Errorf("%s: expected room 1 to be found %t for every room, got %t %v", token, expected, found, err)
+		}
+	}
+
+	// another guest cannot hold, nor book, the same room
+	other := hold
+	other.HoldToken = "guest-b"
+	expectUnavailable(t, repo.PlaceHold(ctx, other), "hold by another guest")
+	_, err = repo.CreateReservation(ctx, testReservation(1, 2, 4), "guest-b")
+	expectUnavailable(t, err, "booking by another guest")
+
+	// a guest holding other rooms gives up their earlier hold, either every room being held or none
+	block, _ := repo.CreateRoomBlock(ctx, 2, day(2), day(2))
+	room2 := models.RoomRestriction{RoomID: 2, StartDate: day(1), EndDate: day(3), HoldToken: "guest-a", ExpiresAt: expires}
+	room1 := models.RoomRestriction{RoomID: 1, StartDate: day(10), EndDate: day(12), HoldToken: "guest-a", ExpiresAt: expires}
+	expectUnavailable(t, repo.PlaceHold(ctx, room1, room2), "hold of a blocked room")
+	expectAvailable(t, repo, 1, 10, 12, true)
+	expectAvailable(t, repo, 1, 2, 2, false)
+
+	if err = repo.DeleteRoomBlock(ctx, int(block)); err != nil {
+		t.Fatal("DeleteRoomBlock failed", err)
+	}
+	if err = repo.PlaceHold(ctx, room2); err != nil {
+		t.Fatal("PlaceHold failed", err)
+	}
+	expectAvailable(t, repo, 1, 2, 2, true)
+	expectAvailable(t, repo, 2, 2, 2, false)
+
+	// the guest's own hold is turned into their reservation's restriction
+	id, err := repo.CreateReservation(ctx, testReservation(2, 1, 3), "guest-a")
+	if err != nil {
+		t.Fatal("CreateReservation failed", err)
+	}
+	restrictions, _ := repo.GetRoomRestrictionsForCalendar(ctx, 2, day(1), day(3))
+	if len(restrictions) != 1 || restrictions[0].RestrictionID != models.RestrictionReservation || restrictions[0].ReservationID != id {
+		t.Errorf("expected the hold to become the reservation's restriction, got %+v", restrictions)
+	}
+
+	// an expired hold takes no room, until released
+	lapsed := models.RoomRestriction{RoomID: 1, StartDate: day(20), EndDate: day(22), HoldToken: "guest-c", ExpiresAt: time.Now().Add(-time.Minute)}
+	if err = repo.PlaceHold(ctx, lapsed); err != nil {
+		t.Fatal("PlaceHold failed", err)
+	}
+	expectAvailable(t, repo, 1, 20, 22, true)
+	released, err := repo.ReleaseExpiredHolds(ctx, time.Now())
+	if err != nil || released != 1 {
+		t.Errorf("expected 1 expired hold to be released, got %d %v", released, err)
+	}
+
+	if err = repo.PlaceHold(ctx, models.RoomRestriction{RoomID: 1, StartDate: day(25), EndDate: day(26), HoldToken: "guest-d", ExpiresAt: expires}); err != nil {
+		t.Fatal("PlaceHold failed", err)
+	}
+	if err = repo.ReleaseHold(ctx, "guest-d"); err != nil {
+		t.Fatal("ReleaseHold failed", err)
+	}
+	expectAvailable(t, repo, 1, 25, 26, true)
+}
+
+func testTrash(t *testing.T, repo repository.DatabaseRepository) {
+	ctx := context.Background()
+	id := mustReserve(t, repo, testReservation(1, 1, 5))
+
+	if err := repo.DeleteReservation(ctx, id); err != nil {
+		t.Fatal("DeleteReservation failed", err)
+	}
+	expectAvailable(t, repo, 1, 1, 5, true)
+	if all, _ := repo.GetAllReservations(ctx); len(all) != 0 {
+		t.Errorf("expected no live reservations, got %d", len(all))
+	}
+	deleted, err := repo.GetDeletedReservations(ctx)
+	if err != nil || len(deleted) != 1 || deleted[0].ID != id || deleted[0].DeletedAt.IsZero() {
+		t.Errorf("expected the reservation in the trash, got %+v %v", deleted, err)
+	}
+
+	// the room is taken meanwhile, so the reservation cannot be restored
+	block, err := repo.CreateRoomBlock(ctx, 1, day(4), day(6))
+	if err != nil {
+		t.Fatal("CreateRoomBlock failed", err)
+	}
+	expectUnavailable(t, repo.RestoreReservation(ctx, id), "restore of reservation")
+
+	got, err := repo.GetRoomBlockByID(ctx, int(block))
+	if err != nil || got.RoomID != 1 || !got.StartDate.Equal(day(4)) || !got.EndDate.Equal(day(6)) {
+		t.Errorf("expected the block of room 1 for days 4-6, got %+v %v", got, err)
+	}
+
+	if err = repo.DeleteRoomBlock(ctx, int(block)); err != nil {
+		t.Fatal("DeleteRoomBlock failed", err)
+	}
+	if _, err = repo.GetRoomBlockByID(ctx, int(block)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected a block in the trash not to be found, got %v", err)
+	}
+	if err = repo.DeleteRoomBlock(ctx, int(block)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected a block already in the trash not to be deleted again, got %v", err)
+	}
+	blocks, err := repo.GetDeletedRoomBlocks(ctx)
+	if err != nil || len(blocks) != 1 || blocks[0].ID != int(block) || blocks[0].Room.RoomName != "General's Quarters" {
+		t.Errorf("expected the block in the trash, got %+v %v", blocks, err)
+	}
+
+	if err = repo.RestoreReservation(ctx, id); err != nil {
+		t.Fatal("RestoreReservation failed", err)
+	}
+	expectAvailable(t, repo, 1, 1, 5, false)
+	if deleted, _ = repo.GetDeletedReservations(ctx); len(deleted) != 0 {
+		t.Errorf("expected the trash to hold no reservations, got %d", len(deleted))
+	}
+	expectUnavailable(t, repo.RestoreRoomBlock(ctx, int(block)), "restore of block")
+
+	// only what was deleted before the given time is purged
+	purged, err := repo.PurgeDeleted(ctx, time.Now().Add(-time.Hour))
+	if err != nil || purged != 0 {
+		t.Errorf("expected nothing to be purged, got %d %v", purged, err)
+	}
+	if err = repo.DeleteReservation(ctx, id); err != nil {
+		t.Fatal("DeleteReservation failed", err)
+	}
+	purged, err = repo.PurgeDeleted(ctx, time.Now().Add(time.Hour))
+	if err != nil || purged != 2 {
+		t.Errorf("expected the reservation & block to be purged, got %d %v", purged, err)
+	}
+	if _, err = repo.GetReservationByID(ctx, id); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected the reservation to be gone, got %v", err)
+	}
+	if blocks, _ = repo.GetDeletedRoomBlocks(ctx); len(blocks) != 0 {
+		t.Errorf("expected the trash to hold no blocks, got %d", len(blocks))
+	}
+}
+
+func testBookingGroups(t *testing.T, repo repository.DatabaseRepository) {
+	ctx := context.Background()
+	group := models.BookingGroup{
+		ConfirmationRef: "BNB-GROUP",
+		FirstName:       "John",
+		LastName:        "Smith",
+		Email:           "john@example.com",
+		StartDate:       day(1),
+		EndDate:         day(3),
+		Reservations:    []models.Reservation{testReservation(2, 1, 3), testReservation(1, 1, 3)},
+	}
+	groupID, err := repo.CreateBookingGroup(ctx, group, "")
+	if err != nil || groupID == 0 {
+		t.Fatalf("expected group to be created, got %d %v", groupID, err)
+	}
+	expectAvailable(t, repo, 1, 2, 2, false)
+	expectAvailable(t, repo, 2, 2, 2, false)
+
+	g, err := repo.GetBookingGroupByID(ctx, int(groupID))
+	if err != nil || g.ConfirmationRef != "BNB-GROUP" || len(g.Reservations) != 2 {
+		t.Fatalf("expected group with 2 reservations, got %+v %v", g, err)
+	}
+	if g.Reservations[0].RoomID != 1 || g.Reservations[0].GroupID != int(groupID) || g.TotalPrice() != 64000 {
+		t.Errorf("expected the group's reservations in room order, got %+v", g.Reservations)
+	}
+
+	// either every room is booked or none are
+	if _, err = repo.CreateRoomBlock(ctx, 2, day(11), day(11)); err != nil {
+		t.Fatal("CreateRoomBlock failed", err)
+	}
+	clash := group
+	clash.Reservations = []models.Reservation{testReservation(1, 10, 12), testReservation(2, 10, 12)}
+	_, err = repo.CreateBookingGroup(ctx, clash, "")
+	expectUnavailable(t, err, "group with a blocked room")
+	expectAvailable(t, repo, 1, 10, 12, true)
+	if groups, _ := repo.GetAllBookingGroups(ctx); len(groups) != 1 {
+		t.Errorf("expected only the first group, got %d", len(groups))
+	}
+
+	if err = repo.UpdateBookingGroupStatus(ctx, int(groupID), models.StatusConfirmed); err != nil {
+		t.Fatal("UpdateBookingGroupStatus failed", err)
+	}
+	var transition *lifecycle.TransitionError
+	if err = repo.UpdateBookingGroupStatus(ctx, int(groupID), models.StatusCheckedOut); !errors.As(err, &transition) {
+		t.Errorf("expected *lifecycle.TransitionError, got %v", err)
+	}
+	g, _ = repo.GetBookingGroupByID(ctx, int(groupID))
+	for _, s := range g.Statuses() {
+		if s != models.StatusConfirmed {
+			t.Errorf("expected every room to be confirmed, got %v", g.Statuses())
+		}
+	}
+
+	if err = repo.DeleteBookingGroup(ctx, int(groupID)); err != nil {
+		t.Fatal("DeleteBookingGroup failed", err)
+	}
+	expectAvailable(t, repo, 1, 2, 2, true)
+	expectAvailable(t, repo, 2, 2, 2, true)
+	if groups, _ := repo.GetAllBookingGroups(ctx); len(groups) != 0 {
+		t.Errorf("expected no groups with live reservations, got %d", len(groups))
+	}
+}
+
+func testImportReservations(t *testing.T, repo repository.DatabaseRepository) {
+	ctx := context.Background()
+	confirmed := testReservation(1, 1, 3)
+	confirmed.Status = models.StatusConfirmed
+	cancelled := testReservation(1, 2, 4)
+	cancelled.Status = models.StatusCancelled
+	checkedOut := testReservation(2, 1, 2)
+	checkedOut.Status = models.StatusCheckedOut
+
+	ids, err := repo.ImportReservations(ctx, []models.Reservation{confirmed, cancelled, checkedOut})
+	if err != nil || len(ids) != 3 {
+		t.Fatalf("expected 3 reservations to be imported, got %v %v", ids, err)
+	}
+	for i, expected := range []models.ReservationStatus{models.StatusConfirmed, models.StatusCancelled, models.StatusCheckedOut} {
+		rsvn, err := repo.GetReservationByID(ctx, int(ids[i]))
+		if err != nil || rsvn.Status != expected {
+			t.Errorf("reservation %d: expected %s, got %+v %v", i, expected, rsvn, err)
+		}
+	}
+
+	// a cancelled reservation takes no room
+	if restrictions, _ := repo.GetRoomRestrictionsByDate(ctx, 1, day(1), day(31)); len(restrictions) != 1 {
+		t.Errorf("expected only the confirmed reservation to take room 1, got %d restrictions", len(restrictions))
+	}
+
+	// either every reservation is imported or none are, each checked against those before it
+	_, err = repo.ImportReservations(ctx, []models.Reservation{testReservation(2, 10, 12), testReservation(2, 11, 13)})
+	expectUnavailable(t, err, "clashing import")
+	if all, _ := repo.GetAllReservations(ctx); len(all) != 3 {
+		t.Errorf("expected nothing more to be imported, got %d reservations", len(all))
+	}
+}
+
+func testWaitlist(t *testing.T, repo repository.DatabaseRepository) {
+	ctx := context.Background()
+	for _, e := range []models.WaitlistEntry{
+		{FirstName: "Ann", LastName: "Early", Email: "ann@example.com", StartDate: day(1), EndDate: day(3), Adults: 2},
+		{FirstName: "Bob", LastName: "Late", Email: "bob@example.com", StartDate: day(10), EndDate: day(12), Adults: 1, Children: 1},
+	} {
+		if err := repo.InsertWaitlistEntry(ctx, e); err != nil {
+			t.Fatal("InsertWaitlistEntry failed", err)
+		}
+	}
+
+	entries, err := repo.GetWaitlist(ctx)
+	if err != nil || len(entries) != 2 || entries[0].FirstName != "Ann" || entries[1].Children != 1 || !entries[1].NotifiedAt.IsZero() {
+		t.Fatalf("expected both guests in the order they joined, got %+v %v", entries, err)
+	}
+
+	waiting, err := repo.GetWaitlistByDates(ctx, day(3), day(5))
+	if err != nil || len(waiting) != 1 || waiting[0].FirstName != "Ann" {
+		t.Errorf("expected Ann to be waiting for 3 March, got %+v %v", waiting, err)
+	}
+
+	if err = repo.MarkWaitlistNotified(ctx, entries[0].ID); err != nil {
+		t.Fatal("MarkWaitlistNotified failed", err)
+	}
+	if waiting, _ = repo.GetWaitlistByDates(ctx, day(1), day(3)); len(waiting) != 0 {
+		t.Errorf("expected a notified guest not to be told again, got %+v", waiting)
+	}
+	if entries, _ = repo.GetWaitlist(ctx); entries[0].NotifiedAt.IsZero() {
+		t.Errorf("expected Ann to be recorded as notified, got %+v", entries[0])
+	}
+
+	if err = repo.DeleteWaitlistEntry(ctx, entries[1].ID); err != nil {
+		t.Fatal("DeleteWaitlistEntry failed", err)
+	}
+	if entries, _ = repo.GetWaitlist(ctx); len(entries) != 1 {
+		t.Errorf("expected 1 guest left on the waitlist, got %d", len(entries))
+	}
+}
+
+func testSeasonalRatesAndStayRules(t *testing.T, repo repository.DatabaseRepository) {
+	ctx := context.Background()
+	err := repo.InsertSeasonalRate(ctx, models.SeasonalRate{RoomID: 1, SeasonName: "Spring", StartDate: day(1), EndDate: day(10), NightlyRate: 10000, WeekendRate: 12000})
+	if err != nil {
+		t.Fatal("InsertSeasonalRate failed", err)
+	}
+	seasons, err := repo.GetSeasonalRatesByRoomID(ctx, 1, day(10), day(12))
+	if err != nil || len(seasons) != 1 || seasons[0].SeasonName != "Spring" || seasons[0].NightlyRate != 10000 || !seasons[0].EndDate.Equal(day(10)) {
+		t.Errorf("expected Spring to overlap 10 March, got %+v %v", seasons, err)
+	}
+	if seasons, _ = repo.GetSeasonalRatesByRoomID(ctx, 1, day(11), day(12)); len(seasons) != 0 {
+		t.Errorf("expected no season after 10 March, got %+v", seasons)
+	}
+	if seasons, _ = repo.GetSeasonalRatesByRoomID(ctx, 2, day(1), day(12)); len(seasons) != 0 {
+		t.Errorf("expected no season for room 2, got %+v", seasons)
+	}
+	seasons, err = repo.GetAllSeasonalRates(ctx)
+	if err != nil || len(seasons) != 1 || seasons[0].Room.RoomName != "General's Quarters" {
+		t.Fatalf("expected Spring with its room, got %+v %v", seasons, err)
+	}
+	if err = repo.DeleteSeasonalRate(ctx, seasons[0].ID); err != nil {
+		t.Fatal("DeleteSeasonalRate failed", err)
+	}
+	if seasons, _ = repo.GetAllSeasonalRates(ctx); len(seasons) != 0 {
+		t.Errorf("expected no seasons, got %d", len(seasons))
+	}
+
+	err = repo.InsertStayRule(ctx, models.StayRule{RoomID: 2, RuleName: "Easter", StartDate: day(20), EndDate: day(27), MinNights: 3, MaxNights: 7})
+	if err != nil {
+		t.Fatal("InsertStayRule failed", err)
+	}
+	rules, err := repo.GetStayRulesByRoomID(ctx, 2, day(18), day(20))
+	if err != nil || len(rules) != 1 || rules[0].MinNights != 3 || rules[0].MaxNights != 7 || !rules[0].StartDate.Equal(day(20)) {
+		t.Errorf("expected Easter to overlap 20 March, got %+v %v", rules, err)
+	}
+	rules, err = repo.GetAllStayRules(ctx)
+	if err != nil || len(rules) != 1 || rules[0].Room.RoomName != "Major's Suite" {
+		t.Fatalf("expected Easter with its room, got %+v %v", rules, err)
+	}
+	if err = repo.DeleteStayRule(ctx, rules[0].ID); err != nil {
+		t.Fatal("DeleteStayRule failed", err)
+	}
+	if rules, _ = repo.GetAllStayRules(ctx); len(rules) != 0 {
+		t.Errorf("expected no stay rules, got %d", len(rules))
+	}
+}
+
+func testICalFeeds(t *testing.T, repo repository.DatabaseRepository) {
+	ctx := context.Background()
+	if err := repo.InsertICalFeed(ctx, models.ICalFeed{RoomID: 1, Token: "public-token"}); err != nil {
+		t.Fatal("InsertICalFeed failed", err)
+	}
+	if err := repo.InsertICalFeed(ctx, models.ICalFeed{RoomID: 1, Token: "private-token", Private: true}); err != nil {
+		t.Fatal("InsertICalFeed failed", err)
+	}
+
+	feed, err := repo.GetICalFeedByToken(ctx, "private-token")
+	if err != nil || !feed.Private || feed.Room.RoomName != "General's Quarters" {
+		t.Errorf("expected the private feed with its room, got %+v %v", feed, err)
+	}
+
+	feeds, err := repo.GetAllICalFeeds(ctx)
+	if err != nil || len(feeds) != 2 || feeds[0].Private || !feeds[1].Private {
+		t.Fatalf("expected the public feed before the private, got %+v %v", feeds, err)
+	}
+
+	if err = repo.DeleteICalFeed(ctx, feeds[1].ID); err != nil {
+		t.Fatal("DeleteICalFeed failed", err)
+	}
+	if _, err = repo.GetICalFeedByToken(ctx, "private-token"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected a revoked feed to be gone, got %v", err)
+	}
+}
+
+func testICalImports(t *testing.T, repo repository.DatabaseRepository) {
+	ctx := context.Background()
+	rsvnID := mustReserve(t, repo, testReservation(1, 5, 8))
+
+	if err := repo.InsertICalImport(ctx, models.ICalImport{RoomID: 1, Name: "Airbnb", URL: "https://example.com/airbnb.ics"}); err != nil {
+		t.Fatal("InsertICalImport failed", err)
+	}
+	imports, err := repo.GetAllICalImports(ctx)
+	if err != nil || len(imports) != 1 || !imports[0].LastSyncedAt.IsZero() || imports[0].Room.RoomName != "General's Quarters" {
+		t.Fatalf("expected one import never synced, got %+v %v", imports, err)
+	}
+	importID := imports[0].ID
+
+	synced := time.Now().Truncate(time.Second)
+	if err = repo.UpdateICalImportSync(ctx, importID, synced, "calendar not found"); err != nil {
+		t.Fatal("UpdateICalImportSync failed", err)
+	}
+	imports, _ = repo.GetAllICalImports(ctx)
+	if !imports[0].LastSyncedAt.Equal(synced) || imports[0].LastError != "calendar not found" {
+		t.Errorf("expected the sync to be recorded, got %+v", imports[0])
+	}
+
+	add := []models.RoomRestriction{
+		{RoomID: 1, StartDate: day(1), EndDate: day(2), ExternalUID: "a@airbnb"},
+		{RoomID: 1, StartDate: day(10), EndDate: day(12), ExternalUID: "b@airbnb"},
+	}
+	if err = repo.SyncExternalBookings(ctx, importID, add, nil, nil); err != nil {
+		t.Fatal("SyncExternalBookings failed", err)
+	}
+	bookings, err := repo.GetExternalBookings(ctx, importID)
+	if err != nil || len(bookings) != 2 || bookings[0].ExternalUID != "a@airbnb" || bookings[1].ImportID != importID {
+		t.Fatalf("expected 2 external bookings, got %+v %v", bookings, err)
+	}
+	expectAvailable(t, repo, 1, 11, 11, false)
+
+	// a booking moved, another gone & one new which overlaps our own reservation
+	moved := bookings[1]
+	moved.StartDate, moved.EndDate = day(20), day(22)
+	add = []models.RoomRestriction{{RoomID: 1, StartDate: day(7), EndDate: day(9), ExternalUID: "c@airbnb"}}
+	if err = repo.SyncExternalBookings(ctx, importID, add, []models.RoomRestriction{moved}, []int{bookings[0].ID}); err != nil {
+		t.Fatal("SyncExternalBookings failed", err)
+	}
+	bookings, _ = repo.GetExternalBookings(ctx, importID)
+	if len(bookings) != 2 || bookings[0].ExternalUID != "c@airbnb" || !bookings[1].StartDate.Equal(day(20)) {
+		t.Errorf("expected the new & moved bookings, got %+v", bookings)
+	}
+	expectAvailable(t, repo, 1, 11, 11, true)
+	expectAvailable(t, repo, 1, 1, 2, true)
+
+	conflicts, err := repo.GetBookingConflicts(ctx, day(1))
+	if err != nil || len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %+v %v", conflicts, err)
+	}
+	if conflicts[0].Booking.ExternalUID != "c@airbnb" || conflicts[0].Reservation.ID != rsvnID || conflicts[0].Import.Name != "Airbnb" {
+		t.Errorf("unexpected conflict %+v", conflicts[0])
+	}
+	if conflicts, _ = repo.GetBookingConflicts(ctx, day(10)); len(conflicts) != 0 {
+		t.Errorf("expected no conflicts ending on or after 10 March, got %d", len(conflicts))
+	}
+
+	if err = repo.DeleteICalImport(ctx, importID); err != nil {
+		t.Fatal("DeleteICalImport failed", err)
+	}
+	if bookings, _ = repo.GetExternalBookings(ctx, importID); len(bookings) != 0 {
+		t.Errorf("expected the import's bookings to go with it, got %d", len(bookings))
+	}
+	expectAvailable(t, repo, 1, 20, 22, true)
+}
+
+func testWebhooks(t *testing.T, repo repository.DatabaseRepository) {
+	ctx := context.Background()
+	err := repo.InsertWebhook(ctx, models.Webhook{URL: "https://example.com/hook", Secret: "whsec", Events: []string{"reservation.created", "reservation.cancelled"}})
+	if err != nil {
+		t.Fatal("InsertWebhook failed", err)
+	}
+	hooks, err := repo.GetAllWebhooks(ctx)
+	if err != nil || len(hooks) != 1 || len(hooks[0].Events) != 2 || !hooks[0].Receives("reservation.cancelled") || hooks[0].Receives("block.created") {
+		t.Fatalf("expected one webhook for 2 events, got %+v %v", hooks, err)
+	}
+	hookID := hooks[0].ID
+
+	err = repo.InsertWebhookDeliveries(ctx, []models.WebhookDelivery{{WebhookID: hookID, Event: "reservation.created", Payload: `{"id":1}`}})
+	if err != nil {
+		t.Fatal("InsertWebhookDeliveries failed", err)
+	}
+	due, err := repo.GetDueWebhookDeliveries(ctx, time.Now().Add(time.Minute), 10)
+	if err != nil || len(due) != 1 || due[0].Status != models.DeliveryPending || due[0].Webhook.URL != "https://example.com/hook" || due[0].Payload != `{"id":1}` {
+		t.Fatalf("expected one delivery due, got %+v %v", due, err)
+	}
+
+	d := due[0]
+	d.Status = models.DeliveryFailed
+	d.Attempts = 3
+	d.ResponseCode = 503
+	d.LastError = "503 Service Unavailable"
+	if err = repo.UpdateWebhookDelivery(ctx, d); err != nil {
+		t.Fatal("UpdateWebhookDelivery failed", err)
+	}
+	if due, _ = repo.GetDueWebhookDeliveries(ctx, time.Now().Add(time.Minute), 10); len(due) != 0 {
+		t.Errorf("expected a failed delivery not to be due, got %+v", due)
+	}
+	deliveries, err := repo.GetWebhookDeliveries(ctx, 10)
+	if err != nil || len(deliveries) != 1 || deliveries[0].Status != models.DeliveryFailed || deliveries[0].ResponseCode != 503 || !deliveries[0].DeliveredAt.IsZero() {
+		t.Errorf("expected the failure in the deliveries, got %+v %v", deliveries, err)
+	}
+
+	if err = repo.RetryWebhookDelivery(ctx, d.ID); err != nil {
+		t.Fatal("RetryWebhookDelivery failed", err)
+	}
+	due, _ = repo.GetDueWebhookDeliveries(ctx, time.Now().Add(time.Minute), 10)
+	if len(due) != 1 || due[0].Attempts != 0 {
+		t.Errorf("expected the delivery to be due again with no attempts, got %+v", due)
+	}
+	// only a failed delivery can be retried
+	if err = repo.RetryWebhookDelivery(ctx, d.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows retrying a pending delivery, got %v", err)
+	}
+
+	d = due[0]
+	d.Status = models.DeliveryDelivered
+	d.Attempts = 1
+	d.ResponseCode = 200
+	d.LastError = ""
+	d.DeliveredAt = time.Now()
+	if err = repo.UpdateWebhookDelivery(ctx, d); err != nil {
+		t.Fatal("UpdateWebhookDelivery failed", err)
+	}
+	if deliveries, _ = repo.GetWebhookDeliveries(ctx, 10); deliveries[0].Status != models.DeliveryDelivered || deliveries[0].DeliveredAt.IsZero() {
+		t.Errorf("expected the delivery to be recorded, got %+v", deliveries[0])
+	}
+
+	if err = repo.DeleteWebhook(ctx, hookID); err != nil {
+		t.Fatal("DeleteWebhook failed", err)
+	}
+	if deliveries, _ = repo.GetWebhookDeliveries(ctx, 10); len(deliveries) != 0 {
+		t.Errorf("expected the webhook's deliveries to go with it, got %d", len(deliveries))
+	}
+}
+
+func testChannel(t *testing.T, repo repository.DatabaseRepository) {
+	ctx := context.Background()
+	err := repo.SaveChannelRooms(ctx, []models.ChannelRoom{{RoomID: 1, RoomCode: "GQ", RatePlanCode: "BAR"}, {RoomID: 2, RoomCode: "MS"}})
+	if err != nil {
+		t.Fatal("SaveChannelRooms failed", err)
+	}
+	mapped, err := repo.GetChannelRooms(ctx)
+	if err != nil || len(mapped) != 2 || mapped[0].RoomCode != "GQ" || mapped[0].Room.RoomName != "General's Quarters" || mapped[1].RatePlanCode != "" {
+		t.Fatalf("expected both rooms mapped by name, got %+v %v", mapped, err)
+	}
+
+	days := []models.ChannelDay{{Date: day(1), Open: true, Rate: 8000}, {Date: day(2), Open: false, Rate: 8000}}
+	for _, roomID := range []int{1, 2} {
+		if err = repo.SaveChannelDays(ctx, roomID, days); err != nil {
+			t.Fatal("SaveChannelDays failed", err)
+		}
+	}
+	// a night pushed again is replaced
+	if err = repo.SaveChannelDays(ctx, 1, []models.ChannelDay{{Date: day(2), Open: true, Rate: 9500}}); err != nil {
+		t.Fatal("SaveChannelDays failed", err)
+	}
+	pushed, err := repo.GetChannelDays(ctx, 1, day(1), day(31))
+	if err != nil || len(pushed) != 2 || !pushed[0].Date.Equal(day(1)) || !pushed[1].Open || pushed[1].Rate != 9500 {
+		t.Errorf("expected 2 nights pushed, the second replaced, got %+v %v", pushed, err)
+	}
+	if pushed, _ = repo.GetChannelDays(ctx, 1, day(2), day(2)); len(pushed) != 1 {
+		t.Errorf("expected nights from start to end inclusive, got %+v", pushed)
+	}
+
+	// a room keeping its codes keeps what was pushed, whereas one whose codes change has it forgotten
+	err = repo.SaveChannelRooms(ctx, []models.ChannelRoom{{RoomID: 1, RoomCode: "GQ", RatePlanCode: "BAR"}, {RoomID: 2, RoomCode: "MAJ"}})
+	if err != nil {
+		t.Fatal("SaveChannelRooms failed", err)
+	}
+	if pushed, _ = repo.GetChannelDays(ctx, 1, day(1), day(31)); len(pushed) != 2 {
+		t.Errorf("expected room 1's nights to be kept, got %d", len(pushed))
+	}
+	if pushed, _ = repo.GetChannelDays(ctx, 2, day(1), day(31)); len(pushed) != 0 {
+		t.Errorf("expected room 2's nights to be forgotten, got %d", len(pushed))
+	}
+
+	if err = repo.ResetChannelDays(ctx); err != nil {
+		t.Fatal("ResetChannelDays failed", err)
+	}
+	if pushed, _ = repo.GetChannelDays(ctx, 1, day(1), day(31)); len(pushed) != 0 {
+		t.Errorf("expected every night to be forgotten, got %d", len(pushed))
+	}
+
+	s := models.ChannelSync{
+		RoomID:       1,
+		Message:      "OTA_HotelAvailNotifRQ",
+		StartDate:    day(1),
+		EndDate:      day(2),
+		Changes:      2,
+		Status:       models.DeliveryFailed,
+		Attempts:     3,
+		ResponseCode: 503,
+		LastError:    "channel manager returned 503 Service Unavailable",
+		Request:      "<OTA_HotelAvailNotifRQ/>",
+	}
+	if err = repo.InsertChannelSync(ctx, s); err != nil {
+		t.Fatal("InsertChannelSync failed", err)
+	}
+	syncs, err := repo.GetChannelSyncs(ctx, 10)
+	if err != nil || len(syncs) != 1 {
+		t.Fatalf("expected one message in the sync deliveries, got %+v %v", syncs, err)
+	}
+	got := syncs[0]
+	if got.Room.RoomName != "General's Quarters" || got.Message != s.Message || !got.EndDate.Equal(day(2)) || got.Changes != 2 || got.ResponseCode != 503 || got.Request != s.Request {
+		t.Errorf("unexpected message in the sync deliveries %+v", got)
+	}
+}
+
+// expectInterrupted checks an operation failed with a *repository.InterruptedError, for a timeout or not as expected, wrapping ctxErr
+func expectInterrupted(t *testing.T, err error, timeout bool, ctxErr error, what string) {
+	t.Helper()
+	var interrupted *repository.InterruptedError
+	if !errors.As(err, &interrupted) {
+		t.Fatalf("%s: expected *repository.InterruptedError, got %v", what, err)
+	}
+	if interrupted.Timeout() != timeout || !errors.Is(err, ctxErr) {
+		t.Errorf("%s: expected %v, got %v", what, ctxErr, err)
+	}
+}
+
+func testInterrupted(t *testing.T, repo repository.DatabaseRepository) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.CreateReservation(cancelled, testReservation(1, 1, 3), "")
+	expectInterrupted(t, err, false, context.Canceled, "CreateReservation")
+	expectAvailable(t, repo, 1, 1, 3, true)
+
+	_, err = repo.GetAllRooms(cancelled)
+	expectInterrupted(t, err, false, context.Canceled, "GetAllRooms")
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	_, err = repo.GetRoomByID(expired, 1)
+	expectInterrupted(t, err, true, context.DeadlineExceeded, "GetRoomByID")
+
+	err = repo.PlaceHold(expired, models.RoomRestriction{RoomID: 1, StartDate: day(1), EndDate: day(3), HoldToken: "expired", ExpiresAt: time.Now().Add(time.Hour)})
+	expectInterrupted(t, err, true, context.DeadlineExceeded, "PlaceHold")
+	expectAvailable(t, repo, 1, 1, 3, true)
+
+	// a room already taken is reported as such, rather than as interrupted
+	mustReserve(t, repo, testReservation(1, 1, 3))
+	_, err = repo.CreateReservation(context.Background(), testReservation(1, 2, 4), "")
+	expectUnavailable(t, err, "CreateReservation")
+}
@@ -0,0 +1,138 @@
+package dbrepository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/database"
+)
+
+// dialect is how a database's SQL differs from MariaDB's, in which the repository's queries are written
+type dialect struct {
+	driver string
+	// lockRows ends a query whose rows are to be locked until the transaction completes. SQLite has no row locks, instead each
+	// transaction takes the database's write lock as it begins (_txlock=immediate), so no other booking can be made meanwhile
+	lockRows string
+	// lockCounted ends a query counting rows, locking the rows counted. MariaDB would otherwise count rows as they were when the
+	// transaction first read, missing a booking committed while waiting on a room's lock. Postgres cannot lock rows counted by an
+	// aggregate, but reads rows as they are when each query begins, after the room's lock is held
+	lockCounted string
+	// returningID is set for a driver without LastInsertId(), so that the new row's id is returned by the insert itself
+	returningID bool
+}
+
+var dialects = map[string]dialect{
+	database.MariaDB:  {driver: database.MariaDB, lockRows: " FOR UPDATE", lockCounted: " FOR UPDATE"},
+	database.Postgres: {driver: database.Postgres, lockRows: " FOR UPDATE", returningID: true},
+	database.SQLite:   {driver: database.SQLite},
+}
+
+// upsert ends an insert so that a row already having the insert's unique key has the given columns updated instead
+func (d dialect) upsert(key string, columns ...string) string {
+	var set []string
+	if d.driver == database.MariaDB {
+		for _, c := range columns {
+			set = append(set, c+" = VALUES("+c+")")
+		}
+		return "ON DUPLICATE KEY UPDATE " + strings.Join(set, ", ")
+	}
+
+	for _, c := range columns {
+		set = append(set, c+" = excluded."+c)
+	}
+	return "ON CONFLICT (" + key + ") DO UPDATE SET " + strings.Join(set, ", ")
+}
+
+// args gives time arguments in UTC, in which the application keeps its dates, since SQLite would store a time as text in its own time
+// zone, & Postgres would drop the time zone of a time given for a column without one
+func (d dialect) args(args []interface{}) []interface{} {
+	utc := make([]interface{}, len(args))
+	for i, a := range args {
+		switch t := a.(type) {
+		case time.Time:
+			utc[i] = t.UTC()
+		case sql.NullTime:
+			utc[i] = sql.NullTime{Time: t.Time.UTC(), Valid: t.Valid}
+		default:
+			utc[i] = a
+		}
+	}
+	return utc
+}
+
+// execQuerier is a connection pool or transaction, executing its queries in its database's dialect
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// insertID executes an insert by q, returning the new row's id
+func (d dialect) insertID(ctx context.Context, q execQuerier, stmt string, args ...interface{}) (int64, error) {
+	if d.returningID {
+		var id int64
+		err := q.QueryRowContext(ctx, strings.TrimSuffix(strings.TrimSpace(stmt), ";")+" RETURNING id;", args...).Scan(&id)
+		return id, err
+	}
+
+	res, err := q.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// dialectDB is a connection pool given queries written for MariaDB, which it executes in its own database's dialect
+type dialectDB struct {
+	*sql.DB
+	dialect dialect
+}
+
+func (db *dialectDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.ExecContext(ctx, database.Bind(db.dialect.driver, query), db.dialect.args(args)...)
+}
+
+func (db *dialectDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.QueryContext(ctx, database.Bind(db.dialect.driver, query), db.dialect.args(args)...)
+}
+
+func (db *dialectDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRowContext(ctx, database.Bind(db.dialect.driver, query), db.dialect.args(args)...)
+}
+
+func (db *dialectDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*dialectTx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &dialectTx{Tx: tx, dialect: db.dialect}, nil
+}
+
+// insertID executes an insert, returning the new row's id
+func (db *dialectDB) insertID(ctx context.Context, stmt string, args ...interface{}) (int64, error) {
+	return db.dialect.insertID(ctx, db, stmt, args...)
+}
+
+// dialectTx is a transaction given queries written for MariaDB, which it executes in its own database's dialect
+type dialectTx struct {
+	*sql.Tx
+	dialect dialect
+}
+
+func (tx *dialectTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, database.Bind(tx.dialect.driver, query), tx.dialect.args(args)...)
+}
+
+func (tx *dialectTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(ctx, database.Bind(tx.dialect.driver, query), tx.dialect.args(args)...)
+}
+
+func (tx *dialectTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, database.Bind(tx.dialect.driver, query), tx.dialect.args(args)...)
+}
+
+// insertID executes an insert, returning the new row's id
+func (tx *dialectTx) insertID(ctx context.Context, stmt string, args ...interface{}) (int64, error) {
+	return tx.dialect.insertID(ctx, tx, stmt, args...)
+}
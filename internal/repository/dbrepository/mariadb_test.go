@@ -0,0 +1,141 @@
+package dbrepository
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/config"
+	"github.com/StratoNET/bnb-bookings/internal/database"
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/repository"
+)
+
+// TestMariaDBRepository runs the behavioural tests against a MariaDB database, given by TEST_MARIADB_DSN
+// e.g. root:@tcp(localhost:3306)/bnb-bookings-test?parseTime=true, whose tables are dropped & migrated afresh
+func TestMariaDBRepository(t *testing.T) {
+	dsn := os.Getenv("TEST_MARIADB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MARIADB_DSN is not set")
+	}
+
+	db, err := sql.Open(database.MariaDB, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testDatabaseRepository(t, func(t *testing.T) repository.DatabaseRepository {
+		resetSchema(t, db, database.MariaDB)
+		return NewMariaDBRepository(db, &config.AppConfig{})
+	})
+}
+
+// TestMariaDBRepository_LegacySchema migrates a database set up before migrations were versioned, given by TEST_MARIADB_DSN, checking
+// its reservations & restrictions are kept, & again after the upgrade has failed partway, then undoes every migration, checking its
+// tables are left as they were
+func TestMariaDBRepository_LegacySchema(t *testing.T) {
+	dsn := os.Getenv("TEST_MARIADB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MARIADB_DSN is not set")
+	}
+
+	db, err := sql.Open(database.MariaDB, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	dropSchema(t, db)
+	execSQLFile(t, db, "testdata/legacy_mariadb.sql")
+	mdb := &database.DB{SQL: db, Driver: database.MariaDB}
+	if _, err = database.MigrateUp(mdb); err != nil {
+		t.Fatal("cannot migrate", err)
+	}
+	testLegacySchemaUpgraded(t, mdb)
+
+	// the upgrade is undone, then fails partway through once its reservations are converted, leaving their processed flag dropped, MariaDB
+	// having committed each change to a table as it was made
+	for _, name := range []string{"seed_rooms", "upgrade_legacy_tables"} {
+		m, err := database.MigrateDown(mdb)
+		if err != nil || m == nil || m.Name != name {
+			t.Fatalf("cannot undo %s: %v %v", name, m, err)
+		}
+	}
+	all, err := database.Migrations(database.MariaDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range sqlStatements(all[1].Up) {
+		if _, err = db.Exec(stmt); err != nil {
+			t.Fatalf("cannot execute %q: %s", stmt, err)
+		}
+		if strings.Contains(stmt, "DROP COLUMN IF EXISTS processed") {
+			break
+		}
+	}
+	if _, err = database.MigrateUp(mdb); err != nil {
+		t.Fatal("cannot migrate again after a partial upgrade", err)
+	}
+	testLegacySchemaUpgraded(t, mdb)
+
+	for {
+		m, err := database.MigrateDown(mdb)
+		if err != nil {
+			t.Fatal("cannot migrate down", err)
+		}
+		if m == nil {
+			break
+		}
+	}
+
+	// the tables set up before migrations were versioned are kept, with the processed flag back in place of status
+	for id, processed := range map[int]int{1: 1, 2: 0} {
+		var got int
+		err = db.QueryRow("SELECT processed FROM reservations WHERE id = ?;", id).Scan(&got)
+		if err != nil || got != processed {
+			t.Errorf("expected reservation %d to have processed %d, got %d %v", id, processed, got, err)
+		}
+	}
+	var restrictions int
+	err = db.QueryRow("SELECT COUNT(id) FROM room_restrictions;").Scan(&restrictions)
+	if err != nil || restrictions != 3 {
+		t.Errorf("expected the 3 room restrictions to be kept, got %d %v", restrictions, err)
+	}
+	if _, err = db.Exec("SELECT nightly_rate FROM rooms;"); err == nil {
+		t.Error("expected the columns added to rooms to be dropped")
+	}
+}
+
+// testLegacySchemaUpgraded checks the database of TestMariaDBRepository_LegacySchema is up to date, with its reservations & restrictions
+func testLegacySchemaUpgraded(t *testing.T, mdb *database.DB) {
+	t.Helper()
+
+	if err := database.CheckSchema(mdb); err != nil {
+		t.Error("expected schema to be up to date", err)
+	}
+
+	ctx := context.Background()
+	repo := NewMariaDBRepository(mdb.SQL, &config.AppConfig{})
+
+	// a reservation the owner had processed has been confirmed, one they had not is still pending
+	for id, status := range map[int]models.ReservationStatus{1: models.StatusConfirmed, 2: models.StatusPending} {
+		rsvn, err := repo.GetReservationByID(ctx, id)
+		if err != nil || rsvn.Status != status {
+			t.Errorf("expected reservation %d to be %s, got %q %v", id, status, rsvn.Status, err)
+		}
+	}
+
+	rooms, err := repo.GetAllRooms(ctx)
+	if err != nil || len(rooms) != 2 {
+		t.Errorf("expected the 2 rooms to be kept, got %d %v", len(rooms), err)
+	}
+	day := time.Date(2098, 4, 1, 0, 0, 0, 0, time.UTC)
+	available, err := repo.SearchAvailabilityByDatesAndRoomID(ctx, day, day.AddDate(0, 0, 1), 1, "")
+	if err != nil || available {
+		t.Errorf("expected the owner block to be kept, got available %v %v", available, err)
+	}
+}
@@ -0,0 +1,2373 @@
+package dbrepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/config"
+	"github.com/StratoNET/bnb-bookings/internal/lifecycle"
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MemoryDBRepository is a DatabaseRepository holding rooms, reservations, restrictions etc in memory rather than in a database, for
+// integration tests. It behaves as the SQL repositories do, checking availability by the same overlap rules & making each change
+// wholly or not at all, & may be used by any number of goroutines at once. Any method may be made to fail, see FailOn
+type MemoryDBRepository struct {
+	App *config.AppConfig
+
+	mu     sync.Mutex
+	t      *memoryTables
+	faults map[string]error
+}
+
+// memoryTables hold the rows of each table in order of id, joined fields (e.g. a reservation's Room) being left empty until read
+type memoryTables struct {
+	ids            map[string]int
+	administrators []models.Administrator
+	apiTokens      []models.APIToken
+	rooms          []models.Room
+	seasonalRates  []models.SeasonalRate
+	stayRules      []models.StayRule
+	bookingGroups  []models.BookingGroup
+	reservations   []models.Reservation
+	statusChanges  []models.StatusChange
+	restrictions   []models.RoomRestriction
+	waitlist       []models.WaitlistEntry
+	icalFeeds      []models.ICalFeed
+	icalImports    []models.ICalImport
+	webhooks       []models.Webhook
+	deliveries     []models.WebhookDelivery
+	channelRooms   []models.ChannelRoom
+	channelDays    map[channelNight]models.ChannelDay
+	channelSyncs   []models.ChannelSync
+}
+
+// channelNight is the key of a room's night as pushed to the channel manager
+type channelNight struct {
+	roomID int
+	night  time.Time
+}
+
+// NewMemoryDBRepository returns an in-memory repository holding what a newly migrated database holds, being the two rooms, without
+// rates, & nothing else
+func NewMemoryDBRepository(app *config.AppConfig) *MemoryDBRepository {
+	m := &MemoryDBRepository{
+		App: app,
+		t: &memoryTables{
+			ids:         make(map[string]int),
+			channelDays: make(map[channelNight]models.ChannelDay),
+		},
+		faults: make(map[string]error),
+	}
+
+	now := time.Now()
+	for _, room := range []models.Room{
+		{RoomName: "General's Quarters", IncludedGuests: 2, MaxAdults: 2, MaxChildren: 1, MaxInfants: 1},
+		{RoomName: "Major's Suite", IncludedGuests: 2, MaxAdults: 4, MaxChildren: 2, MaxInfants: 1},
+	} {
+		room.ID = m.t.nextID("rooms")
+		room.CreatedAt, room.UpdatedAt = now, now
+		m.t.rooms = append(m.t.rooms, room)
+	}
+
+	return m
+}
+
+// AddAdministrator adds an administrator who logs in with the given password, returning their id, as no migration gives an administrator
+func (m *MemoryDBRepository) AddAdministrator(admin models.Administrator, password string) (int, error) {
+	// the lowest cost will do, the hash never leaving the test
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	admin.ID = m.t.nextID("administrators")
+	admin.Password = string(hash)
+	admin.CreatedAt, admin.UpdatedAt = now, now
+	m.t.administrators = append(m.t.administrators, admin)
+
+	return admin.ID, nil
+}
+
+// FailOn makes every call of a DatabaseRepository method, given by name, return err without doing anything, until FailOn is called for
+// the method again with a nil err. It panics unless the method exists & returns an error, so that a misspelt name cannot pass unnoticed.
+// Cancellation & timeouts need no faking, each method failing with a *repository.InterruptedError once its context has ended
+func (m *MemoryDBRepository) FailOn(method string, err error) {
+	f, ok := reflect.TypeOf((*repository.DatabaseRepository)(nil)).Elem().MethodByName(method)
+	if !ok || f.Type.NumOut() == 0 || f.Type.Out(f.Type.NumOut()-1) != reflect.TypeOf((*error)(nil)).Elem() {
+		panic(fmt.Sprintf("dbrepository: %s is not a DatabaseRepository method returning an error", method))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err == nil {
+		delete(m.faults, method)
+		return
+	}
+	m.faults[method] = err
+}
+
+// fail returns the error a method is to fail with, being a *repository.InterruptedError should its context have ended, else any fault
+// injected by FailOn
+func (m *MemoryDBRepository) fail(ctx context.Context, method string) error {
+	if err := ctx.Err(); err != nil {
+		return &repository.InterruptedError{Err: err, Cause: err}
+	}
+	return m.faults[method]
+}
+
+// transaction applies fn to the tables, putting them back as they were should fn fail, as a rolled back transaction would
+func (m *MemoryDBRepository) transaction(fn func() error) error {
+	saved := m.t.clone()
+	err := fn()
+	if err != nil {
+		m.t = saved
+	}
+
+	return err
+}
+
+// clone copies every table, so that changes to either copy's rows leave the other's alone
+func (t *memoryTables) clone() *memoryTables {
+	c := &memoryTables{
+		ids:            make(map[string]int, len(t.ids)),
+		administrators: append([]models.Administrator(nil), t.administrators...),
+		apiTokens:      append([]models.APIToken(nil), t.apiTokens...),
+		rooms:          append([]models.Room(nil), t.rooms...),
+		seasonalRates:  append([]models.SeasonalRate(nil), t.seasonalRates...),
+		stayRules:      append([]models.StayRule(nil), t.stayRules...),
+		bookingGroups:  append([]models.BookingGroup(nil), t.bookingGroups...),
+		reservations:   append([]models.Reservation(nil), t.reservations...),
+		statusChanges:  append([]models.StatusChange(nil), t.statusChanges...),
+		restrictions:   append([]models.RoomRestriction(nil), t.restrictions...),
+		waitlist:       append([]models.WaitlistEntry(nil), t.waitlist...),
+		icalFeeds:      append([]models.ICalFeed(nil), t.icalFeeds...),
+		icalImports:    append([]models.ICalImport(nil), t.icalImports...),
+		webhooks:       append([]models.Webhook(nil), t.webhooks...),
+		deliveries:     append([]models.WebhookDelivery(nil), t.deliveries...),
+		channelRooms:   append([]models.ChannelRoom(nil), t.channelRooms...),
+		channelDays:    make(map[channelNight]models.ChannelDay, len(t.channelDays)),
+		channelSyncs:   append([]models.ChannelSync(nil), t.channelSyncs...),
+	}
+	for k, v := range t.ids {
+		c.ids[k] = v
+	}
+	for k, v := range t.channelDays {
+		c.channelDays[k] = v
+	}
+
+	return c
+}
+
+// nextID gives the id of a new row of a table, counting from 1 as an auto-increment column does
+func (t *memoryTables) nextID(table string) int {
+	t.ids[table]++
+	return t.ids[table]
+}
+
+// dateOf gives a time as a DATE column holds it, being its day at midnight UTC
+func dateOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// overlaps applies the overlap rule of the SQL repositories, start_date BETWEEN start AND end OR start BETWEEN start_date AND end_date
+func overlaps(startDate, endDate, start, end time.Time) bool {
+	return (!startDate.Before(start) && !startDate.After(end)) || (!start.Before(startDate) && !start.After(endDate))
+}
+
+// limited cuts rows down to at most limit, as LIMIT does
+func limited(n, limit int) int {
+	if limit >= 0 && n > limit {
+		return limit
+	}
+	return n
+}
+
+// room returns a room by id, if there is one
+func (t *memoryTables) room(id int) (models.Room, bool) {
+	for _, rm := range t.rooms {
+		if rm.ID == id {
+			return rm, true
+		}
+	}
+	return models.Room{}, false
+}
+
+// roomRef gives a room's id & name as joined to the rows of other tables, empty should there be no such room
+func (t *memoryTables) roomRef(id int) models.Room {
+	rm, ok := t.room(id)
+	if !ok {
+		return models.Room{}
+	}
+	return models.Room{ID: rm.ID, RoomName: rm.RoomName}
+}
+
+// roomExists stands in for a foreign key on a room, failing as the insert of a row for a missing room would
+func (t *memoryTables) roomExists(id int) error {
+	if _, ok := t.room(id); !ok {
+		return fmt.Errorf("foreign key constraint fails, room %d does not exist", id)
+	}
+	return nil
+}
+
+// reservation returns the row of a reservation by id, deleted or not, or nil should there be none, for use before the table next grows
+func (t *memoryTables) reservation(id int) *models.Reservation {
+	for i := range t.reservations {
+		if t.reservations[i].ID == id {
+			return &t.reservations[i]
+		}
+	}
+	return nil
+}
+
+// taken reports whether a room has a restriction overlapping a stay, live restrictions being those neither deleted nor expired, as
+// the SQL repositories check availability. A guest's own holds do not count should their hold token be given
+func (t *memoryTables) taken(roomID int, start, end time.Time, holdToken string, now time.Time) bool {
+	for _, rr := range t.restrictions {
+		if rr.RoomID != roomID || !rr.DeletedAt.IsZero() || (!rr.ExpiresAt.IsZero() && !rr.ExpiresAt.After(now)) {
+			continue
+		}
+		if holdToken != "" && rr.RestrictionID == models.RestrictionHold && rr.HoldToken == holdToken {
+			continue
+		}
+		if overlaps(rr.StartDate, rr.EndDate, start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+// insertReservation adds a reservation with the given status, returning its id
+func (t *memoryTables) insertReservation(rsvn models.Reservation, status models.ReservationStatus, now time.Time) int {
+	rsvn.ID = t.nextID("reservations")
+	rsvn.StartDate = dateOf(rsvn.StartDate)
+	rsvn.EndDate = dateOf(rsvn.EndDate)
+	rsvn.Status = status
+	rsvn.CreatedAt, rsvn.UpdatedAt = now, now
+	rsvn.DeletedAt = time.Time{}
+	rsvn.Room = models.Room{}
+	t.reservations = append(t.reservations, rsvn)
+
+	return rsvn.ID
+}
+
+// insertRestriction adds a room restriction, returning its id
+func (t *memoryTables) insertRestriction(rr models.RoomRestriction, now time.Time) int {
+	rr.ID = t.nextID("room_restrictions")
+	rr.StartDate = dateOf(rr.StartDate)
+	rr.EndDate = dateOf(rr.EndDate)
+	rr.CreatedAt, rr.UpdatedAt = now, now
+	t.restrictions = append(t.restrictions, rr)
+
+	return rr.ID
+}
+
+// deleteRestrictions removes every room restriction for which drop is true, returning how many were removed
+func (t *memoryTables) deleteRestrictions(drop func(models.RoomRestriction) bool) int64 {
+	var kept []models.RoomRestriction
+	var n int64
+	for _, rr := range t.restrictions {
+		if drop(rr) {
+			n++
+			continue
+		}
+		kept = append(kept, rr)
+	}
+	t.restrictions = kept
+
+	return n
+}
+
+// deleteHolds removes every hold placed under a hold token
+func (t *memoryTables) deleteHolds(holdToken string) {
+	t.deleteRestrictions(func(rr models.RoomRestriction) bool {
+		return rr.RestrictionID == models.RestrictionHold && rr.HoldToken == holdToken
+	})
+}
+
+// createReservation re-checks availability of a reservation's room, then inserts the reservation & its room restriction, turning the
+// guest's hold on the room, if any, into the restriction
+func (t *memoryTables) createReservation(rsvn models.Reservation, holdToken string, now time.Time) (int64, error) {
+	if _, ok := t.room(rsvn.RoomID); !ok {
+		return 0, sql.ErrNoRows
+	}
+
+	if t.taken(rsvn.RoomID, rsvn.StartDate, rsvn.EndDate, holdToken, now) {
+		return 0, &repository.RoomUnavailableError{RoomID: rsvn.RoomID, StartDate: rsvn.StartDate, EndDate: rsvn.EndDate}
+	}
+
+	id := t.insertReservation(rsvn, models.StatusPending, now)
+
+	converted := false
+	for i, rr := range t.restrictions {
+		if rr.RoomID != rsvn.RoomID || rr.RestrictionID != models.RestrictionHold || rr.HoldToken != holdToken {
+			continue
+		}
+		rr.ReservationID = int64(id)
+		rr.RestrictionID = models.RestrictionReservation
+		rr.StartDate = dateOf(rsvn.StartDate)
+		rr.EndDate = dateOf(rsvn.EndDate)
+		rr.HoldToken = ""
+		rr.ExpiresAt = time.Time{}
+		rr.UpdatedAt = now
+		t.restrictions[i] = rr
+		converted = true
+	}
+
+	if !converted {
+		t.insertRestriction(models.RoomRestriction{
+			RoomID:        rsvn.RoomID,
+			ReservationID: int64(id),
+			RestrictionID: models.RestrictionReservation,
+			StartDate:     rsvn.StartDate,
+			EndDate:       rsvn.EndDate,
+		}, now)
+	}
+
+	return int64(id), nil
+}
+
+func (m *MemoryDBRepository) AllAdministrators() bool {
+	return true
+}
+
+// InsertReservation inserts a new reservation
+func (m *MemoryDBRepository) InsertReservation(ctx context.Context, rsvn models.Reservation) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "InsertReservation"); err != nil {
+		return 0, err
+	}
+
+	if err := m.t.roomExists(rsvn.RoomID); err != nil {
+		return 0, err
+	}
+
+	rsvn.GroupID = 0
+	return int64(m.t.insertReservation(rsvn, models.StatusPending, time.Now())), nil
+}
+
+// InsertRoomRestriction inserts a room restriction
+func (m *MemoryDBRepository) InsertRoomRestriction(ctx context.Context, rest models.RoomRestriction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "InsertRoomRestriction"); err != nil {
+		return err
+	}
+
+	if err := m.t.roomExists(rest.RoomID); err != nil {
+		return err
+	}
+
+	m.t.insertRestriction(models.RoomRestriction{
+		RoomID:        rest.RoomID,
+		ReservationID: rest.ReservationID,
+		RestrictionID: rest.RestrictionID,
+		StartDate:     rest.StartDate,
+		EndDate:       rest.EndDate,
+	}, time.Now())
+
+	return nil
+}
+
+// CreateReservation re-checks availability, then inserts a reservation & its room restriction, turning the guest's own hold on the
+// room into the restriction & releasing any other hold the guest has
+func (m *MemoryDBRepository) CreateReservation(ctx context.Context, rsvn models.Reservation, holdToken string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "CreateReservation"); err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err := m.transaction(func() error {
+		var err error
+		id, err = m.t.createReservation(rsvn, holdToken, time.Now())
+		if err != nil {
+			return err
+		}
+		m.t.deleteHolds(holdToken)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// CreateBookingGroup inserts a booking group together with a reservation & room restriction for each of its rooms, either every room
+// being booked or none
+func (m *MemoryDBRepository) CreateBookingGroup(ctx context.Context, group models.BookingGroup, holdToken string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "CreateBookingGroup"); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var groupID int
+	err := m.transaction(func() error {
+		g := group
+		g.ID = m.t.nextID("booking_groups")
+		g.StartDate = dateOf(g.StartDate)
+		g.EndDate = dateOf(g.EndDate)
+		g.CreatedAt, g.UpdatedAt = now, now
+		g.Reservations = nil
+		m.t.bookingGroups = append(m.t.bookingGroups, g)
+		groupID = g.ID
+
+		reservations := make([]models.Reservation, len(group.Reservations))
+		copy(reservations, group.Reservations)
+		sort.Slice(reservations, func(i, j int) bool { return reservations[i].RoomID < reservations[j].RoomID })
+
+		for _, rsvn := range reservations {
+			rsvn.GroupID = groupID
+			_, err := m.t.createReservation(rsvn, holdToken, now)
+			if err != nil {
+				return err
+			}
+		}
+
+		m.t.deleteHolds(holdToken)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(groupID), nil
+}
+
+// ImportReservations inserts reservations, each with its own status, either every one being imported or none. Availability is
+// re-checked for each, including against those imported before it, except for cancelled reservations which take no room
+func (m *MemoryDBRepository) ImportReservations(ctx context.Context, reservations []models.Reservation) ([]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "ImportReservations"); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	ids := make([]int64, 0, len(reservations))
+	err := m.transaction(func() error {
+		for _, rsvn := range reservations {
+			if rsvn.Status == models.StatusCancelled {
+				if err := m.t.roomExists(rsvn.RoomID); err != nil {
+					return err
+				}
+				rsvn.GroupID = 0
+				ids = append(ids, int64(m.t.insertReservation(rsvn, rsvn.Status, now)))
+				continue
+			}
+
+			id, err := m.t.createReservation(rsvn, "", now)
+			if err != nil {
+				return err
+			}
+			if rsvn.Status != "" && rsvn.Status != models.StatusPending {
+				m.t.reservation(int(id)).Status = rsvn.Status
+			}
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// PlaceHold holds one or more rooms for a guest's dates until the holds expire, replacing any holds the guest already has. Either
+// every room is held or, should any of them not be free for its dates, none are
+func (m *MemoryDBRepository) PlaceHold(ctx context.Context, holds ...models.RoomRestriction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "PlaceHold"); err != nil {
+		return err
+	}
+
+	if len(holds) == 0 {
+		return nil
+	}
+
+	sorted := make([]models.RoomRestriction, len(holds))
+	copy(sorted, holds)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].RoomID < sorted[j].RoomID })
+
+	now := time.Now()
+	return m.transaction(func() error {
+		for _, hold := range sorted {
+			if _, ok := m.t.room(hold.RoomID); !ok {
+				return sql.ErrNoRows
+			}
+		}
+
+		m.t.deleteHolds(holds[0].HoldToken)
+
+		for _, hold := range sorted {
+			if m.t.taken(hold.RoomID, hold.StartDate, hold.EndDate, "", now) {
+				return &repository.RoomUnavailableError{RoomID: hold.RoomID, StartDate: hold.StartDate, EndDate: hold.EndDate}
+			}
+			m.t.insertRestriction(models.RoomRestriction{
+				RoomID:        hold.RoomID,
+				RestrictionID: models.RestrictionHold,
+				StartDate:     hold.StartDate,
+				EndDate:       hold.EndDate,
+				HoldToken:     hold.HoldToken,
+				ExpiresAt:     hold.ExpiresAt,
+			}, now)
+		}
+		return nil
+	})
+}
+
+// ReleaseHold removes a guest's hold, if any, freeing the room
+func (m *MemoryDBRepository) ReleaseHold(ctx context.Context, holdToken string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "ReleaseHold"); err != nil {
+		return err
+	}
+
+	m.t.deleteHolds(holdToken)
+
+	return nil
+}
+
+// ReleaseExpiredHolds removes all holds which expired before a given time, returning how many were removed
+func (m *MemoryDBRepository) ReleaseExpiredHolds(ctx context.Context, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "ReleaseExpiredHolds"); err != nil {
+		return 0, err
+	}
+
+	released := m.t.deleteRestrictions(func(rr models.RoomRestriction) bool {
+		return rr.RestrictionID == models.RestrictionHold && !rr.ExpiresAt.IsZero() && !rr.ExpiresAt.After(now)
+	})
+
+	return released, nil
+}
+
+// SearchAvailabilityByDatesAndRoomID returns true if the room is free for the dates, otherwise false
+func (m *MemoryDBRepository) SearchAvailabilityByDatesAndRoomID(ctx context.Context, start, end time.Time, roomID int, holdToken string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "SearchAvailabilityByDatesAndRoomID"); err != nil {
+		return false, err
+	}
+
+	return !m.t.taken(roomID, start, end, holdToken, time.Now()), nil
+}
+
+// SearchAvailabilityForAllRooms returns every room free for the dates which can hold the party
+func (m *MemoryDBRepository) SearchAvailabilityForAllRooms(ctx context.Context, start, end time.Time, adults, children, infants int, holdToken string) ([]models.Room, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "SearchAvailabilityForAllRooms"); err != nil {
+		return nil, err
+	}
+
+	var rooms []models.Room
+	now := time.Now()
+	for _, rm := range m.t.rooms {
+		if !m.t.taken(rm.ID, start, end, holdToken, now) && rm.Accommodates(adults, children, infants) {
+			rooms = append(rooms, rm)
+		}
+	}
+
+	return rooms, nil
+}
+
+// GetRoomByID returns a room by id
+func (m *MemoryDBRepository) GetRoomByID(ctx context.Context, id int) (models.Room, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetRoomByID"); err != nil {
+		return models.Room{}, err
+	}
+
+	rm, ok := m.t.room(id)
+	if !ok {
+		return rm, sql.ErrNoRows
+	}
+
+	return rm, nil
+}
+
+// GetAdministratorByID returns an administrator by id
+func (m *MemoryDBRepository) GetAdministratorByID(ctx context.Context, id int) (models.Administrator, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetAdministratorByID"); err != nil {
+		return models.Administrator{}, err
+	}
+
+	for _, admin := range m.t.administrators {
+		if admin.ID == id {
+			return admin, nil
+		}
+	}
+
+	return models.Administrator{}, sql.ErrNoRows
+}
+
+// UpdateAdministrator updates an administrator's name, email & access level
+func (m *MemoryDBRepository) UpdateAdministrator(ctx context.Context, admin models.Administrator) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "UpdateAdministrator"); err != nil {
+		return err
+	}
+
+	for i, a := range m.t.administrators {
+		if a.ID != admin.ID {
+			continue
+		}
+		a.FirstName = admin.FirstName
+		a.LastName = admin.LastName
+		a.Email = admin.Email
+		a.AccessLevel = admin.AccessLevel
+		a.UpdatedAt = time.Now()
+		m.t.administrators[i] = a
+	}
+
+	return nil
+}
+
+// AuthenticateAdministrator checks an administrator's email & password, returning their id & hashed password
+func (m *MemoryDBRepository) AuthenticateAdministrator(ctx context.Context, email, password string) (int, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "AuthenticateAdministrator"); err != nil {
+		return 0, "", err
+	}
+
+	for _, admin := range m.t.administrators {
+		if admin.Email != email {
+			continue
+		}
+		err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return 0, "", errors.New("incorrect password given, does NOT match stored password")
+		} else if err != nil {
+			return 0, "", err
+		}
+		return admin.ID, admin.Password, nil
+	}
+
+	return 0, "", sql.ErrNoRows
+}
+
+// GetAdministratorByAPIToken returns the administrator an API token belongs to, recording that the token has been used
+func (m *MemoryDBRepository) GetAdministratorByAPIToken(ctx context.Context, tokenHash string) (models.Administrator, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetAdministratorByAPIToken"); err != nil {
+		return models.Administrator{}, err
+	}
+
+	for i, t := range m.t.apiTokens {
+		if t.TokenHash != tokenHash {
+			continue
+		}
+		for _, admin := range m.t.administrators {
+			if admin.ID == t.AdministratorID {
+				m.t.apiTokens[i].LastUsedAt = time.Now()
+				admin.Password = ""
+				return admin, nil
+			}
+		}
+	}
+
+	return models.Administrator{}, sql.ErrNoRows
+}
+
+// GetAPITokensByAdministratorID returns an administrator's API tokens, newest first
+func (m *MemoryDBRepository) GetAPITokensByAdministratorID(ctx context.Context, adminID int) ([]models.APIToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetAPITokensByAdministratorID"); err != nil {
+		return nil, err
+	}
+
+	var tokens []models.APIToken
+	for _, t := range m.t.apiTokens {
+		if t.AdministratorID == adminID {
+			tokens = append(tokens, t)
+		}
+	}
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].CreatedAt.After(tokens[j].CreatedAt) })
+
+	return tokens, nil
+}
+
+// InsertAPIToken inserts an API token, of which only the hash is held
+func (m *MemoryDBRepository) InsertAPIToken(ctx context.Context, token models.APIToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "InsertAPIToken"); err != nil {
+		return err
+	}
+
+	m.t.apiTokens = append(m.t.apiTokens, models.APIToken{
+		ID:              m.t.nextID("api_tokens"),
+		AdministratorID: token.AdministratorID,
+		Name:            token.Name,
+		TokenHash:       token.TokenHash,
+		CreatedAt:       time.Now(),
+	})
+
+	return nil
+}
+
+// DeleteAPIToken revokes an API token, provided it belongs to the given administrator
+func (m *MemoryDBRepository) DeleteAPIToken(ctx context.Context, id, adminID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "DeleteAPIToken"); err != nil {
+		return err
+	}
+
+	var kept []models.APIToken
+	for _, t := range m.t.apiTokens {
+		if t.ID != id || t.AdministratorID != adminID {
+			kept = append(kept, t)
+		}
+	}
+	m.t.apiTokens = kept
+
+	return nil
+}
+
+// GetAllRooms returns every room in order of name
+func (m *MemoryDBRepository) GetAllRooms(ctx context.Context) ([]models.Room, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetAllRooms"); err != nil {
+		return nil, err
+	}
+
+	rooms := append([]models.Room(nil), m.t.rooms...)
+	sort.SliceStable(rooms, func(i, j int) bool { return rooms[i].RoomName < rooms[j].RoomName })
+
+	return rooms, nil
+}
+
+// UpdateRoomRates updates a room's nightly & weekend rates, included guests & extra guest rate
+func (m *MemoryDBRepository) UpdateRoomRates(ctx context.Context, room models.Room) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "UpdateRoomRates"); err != nil {
+		return err
+	}
+
+	for i := range m.t.rooms {
+		if m.t.rooms[i].ID != room.ID {
+			continue
+		}
+		rm := &m.t.rooms[i]
+		rm.NightlyRate = room.NightlyRate
+		rm.WeekendRate = room.WeekendRate
+		rm.IncludedGuests = room.IncludedGuests
+		rm.ExtraGuestRate = room.ExtraGuestRate
+		rm.UpdatedAt = time.Now()
+	}
+
+	return nil
+}
+
+// UpdateRoomOccupancy updates the most adults, children & infants a room can hold
+func (m *MemoryDBRepository) UpdateRoomOccupancy(ctx context.Context, room models.Room) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "UpdateRoomOccupancy"); err != nil {
+		return err
+	}
+
+	for i := range m.t.rooms {
+		if m.t.rooms[i].ID != room.ID {
+			continue
+		}
+		rm := &m.t.rooms[i]
+		rm.MaxAdults = room.MaxAdults
+		rm.MaxChildren = room.MaxChildren
+		rm.MaxInfants = room.MaxInfants
+		rm.UpdatedAt = time.Now()
+	}
+
+	return nil
+}
+
+// GetSeasonalRatesByRoomID returns a room's seasonal rates overlapping a date range, in date order
+func (m *MemoryDBRepository) GetSeasonalRatesByRoomID(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.SeasonalRate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetSeasonalRatesByRoomID"); err != nil {
+		return nil, err
+	}
+
+	var seasons []models.SeasonalRate
+	for _, s := range m.t.seasonalRates {
+		if s.RoomID == roomID && !s.StartDate.After(endDate) && !s.EndDate.Before(startDate) {
+			seasons = append(seasons, s)
+		}
+	}
+	sort.SliceStable(seasons, func(i, j int) bool { return seasons[i].StartDate.Before(seasons[j].StartDate) })
+
+	return seasons, nil
+}
+
+// GetAllSeasonalRates returns every seasonal rate with its room, in date order then by room name
+func (m *MemoryDBRepository) GetAllSeasonalRates(ctx context.Context) ([]models.SeasonalRate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetAllSeasonalRates"); err != nil {
+		return nil, err
+	}
+
+	var seasons []models.SeasonalRate
+	for _, s := range m.t.seasonalRates {
+		s.Room = m.t.roomRef(s.RoomID)
+		seasons = append(seasons, s)
+	}
+	sort.SliceStable(seasons, func(i, j int) bool {
+		if !seasons[i].StartDate.Equal(seasons[j].StartDate) {
+			return seasons[i].StartDate.Before(seasons[j].StartDate)
+		}
+		return seasons[i].Room.RoomName < seasons[j].Room.RoomName
+	})
+
+	return seasons, nil
+}
+
+// InsertSeasonalRate inserts a seasonal rate
+func (m *MemoryDBRepository) InsertSeasonalRate(ctx context.Context, season models.SeasonalRate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "InsertSeasonalRate"); err != nil {
+		return err
+	}
+
+	if err := m.t.roomExists(season.RoomID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	m.t.seasonalRates = append(m.t.seasonalRates, models.SeasonalRate{
+		ID:          m.t.nextID("seasonal_rates"),
+		RoomID:      season.RoomID,
+		SeasonName:  season.SeasonName,
+		StartDate:   dateOf(season.StartDate),
+		EndDate:     dateOf(season.EndDate),
+		NightlyRate: season.NightlyRate,
+		WeekendRate: season.WeekendRate,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+
+	return nil
+}
+
+// DeleteSeasonalRate deletes a seasonal rate
+func (m *MemoryDBRepository) DeleteSeasonalRate(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "DeleteSeasonalRate"); err != nil {
+		return err
+	}
+
+	var kept []models.SeasonalRate
+	for _, s := range m.t.seasonalRates {
+		if s.ID != id {
+			kept = append(kept, s)
+		}
+	}
+	m.t.seasonalRates = kept
+
+	return nil
+}
+
+// GetStayRulesByRoomID returns a room's stay rules overlapping a date range, in date order
+func (m *MemoryDBRepository) GetStayRulesByRoomID(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.StayRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetStayRulesByRoomID"); err != nil {
+		return nil, err
+	}
+
+	var rules []models.StayRule
+	for _, sr := range m.t.stayRules {
+		if sr.RoomID == roomID && !sr.StartDate.After(endDate) && !sr.EndDate.Before(startDate) {
+			rules = append(rules, sr)
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].StartDate.Before(rules[j].StartDate) })
+
+	return rules, nil
+}
+
+// GetAllStayRules returns every stay rule with its room, in date order then by room name
+func (m *MemoryDBRepository) GetAllStayRules(ctx context.Context) ([]models.StayRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetAllStayRules"); err != nil {
+		return nil, err
+	}
+
+	var rules []models.StayRule
+	for _, sr := range m.t.stayRules {
+		sr.Room = m.t.roomRef(sr.RoomID)
+		rules = append(rules, sr)
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		if !rules[i].StartDate.Equal(rules[j].StartDate) {
+			return rules[i].StartDate.Before(rules[j].StartDate)
+		}
+		return rules[i].Room.RoomName < rules[j].Room.RoomName
+	})
+
+	return rules, nil
+}
+
+// InsertStayRule inserts a stay rule
+func (m *MemoryDBRepository) InsertStayRule(ctx context.Context, rule models.StayRule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "InsertStayRule"); err != nil {
+		return err
+	}
+
+	if err := m.t.roomExists(rule.RoomID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	m.t.stayRules = append(m.t.stayRules, models.StayRule{
+		ID:        m.t.nextID("stay_rules"),
+		RoomID:    rule.RoomID,
+		RuleName:  rule.RuleName,
+		StartDate: dateOf(rule.StartDate),
+		EndDate:   dateOf(rule.EndDate),
+		MinNights: rule.MinNights,
+		MaxNights: rule.MaxNights,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+
+	return nil
+}
+
+// DeleteStayRule deletes a stay rule
+func (m *MemoryDBRepository) DeleteStayRule(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "DeleteStayRule"); err != nil {
+		return err
+	}
+
+	var kept []models.StayRule
+	for _, sr := range m.t.stayRules {
+		if sr.ID != id {
+			kept = append(kept, sr)
+		}
+	}
+	m.t.stayRules = kept
+
+	return nil
+}
+
+// liveReservations returns the reservations, not in the trash, for which keep is true, with their rooms in order of arrival
+func (t *memoryTables) liveReservations(keep func(models.Reservation) bool) []models.Reservation {
+	var reservations []models.Reservation
+	for _, r := range t.reservations {
+		if r.DeletedAt.IsZero() && keep(r) {
+			r.Room = t.roomRef(r.RoomID)
+			reservations = append(reservations, r)
+		}
+	}
+	sort.SliceStable(reservations, func(i, j int) bool { return reservations[i].StartDate.Before(reservations[j].StartDate) })
+
+	return reservations
+}
+
+// GetAllReservations returns every reservation not in the trash, in order of arrival
+func (m *MemoryDBRepository) GetAllReservations(ctx context.Context) ([]models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetAllReservations"); err != nil {
+		return nil, err
+	}
+
+	return m.t.liveReservations(func(models.Reservation) bool { return true }), nil
+}
+
+// GetNewReservations returns every pending reservation not in the trash, in order of arrival
+func (m *MemoryDBRepository) GetNewReservations(ctx context.Context) ([]models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetNewReservations"); err != nil {
+		return nil, err
+	}
+
+	return m.t.liveReservations(func(r models.Reservation) bool { return r.Status == models.StatusPending }), nil
+}
+
+// FilterReservations returns the reservations selected by a filter, in order of arrival
+func (m *MemoryDBRepository) FilterReservations(ctx context.Context, filter models.ReservationFilter) ([]models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "FilterReservations"); err != nil {
+		return nil, err
+	}
+
+	return m.t.filteredReservations(filter), nil
+}
+
+// StreamReservations calls fn with each reservation selected by a filter, in order of arrival, stopping at the first error fn returns.
+// The repository is not locked while fn runs, so fn may itself use the repository
+func (m *MemoryDBRepository) StreamReservations(ctx context.Context, filter models.ReservationFilter, fn func(models.Reservation) error) error {
+	m.mu.Lock()
+	if err := m.fail(ctx, "StreamReservations"); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	reservations := m.t.filteredReservations(filter)
+	m.mu.Unlock()
+
+	for _, r := range reservations {
+		if err := ctx.Err(); err != nil {
+			return &repository.InterruptedError{Err: err, Cause: err}
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// filteredReservations returns the reservations, not in the trash, selected by a filter
+func (t *memoryTables) filteredReservations(filter models.ReservationFilter) []models.Reservation {
+	return t.liveReservations(func(r models.Reservation) bool {
+		return (filter.Status == "" || r.Status == filter.Status) &&
+			(filter.RoomID == 0 || r.RoomID == filter.RoomID) &&
+			(filter.StartDate.IsZero() || !r.EndDate.Before(filter.StartDate)) &&
+			(filter.EndDate.IsZero() || !r.StartDate.After(filter.EndDate))
+	})
+}
+
+// GetReservationsByStatus returns every reservation with a status, not in the trash, in order of arrival
+func (m *MemoryDBRepository) GetReservationsByStatus(ctx context.Context, status models.ReservationStatus) ([]models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetReservationsByStatus"); err != nil {
+		return nil, err
+	}
+
+	return m.t.liveReservations(func(r models.Reservation) bool { return r.Status == status }), nil
+}
+
+// GetReservationByID returns a reservation, not in the trash, by id
+func (m *MemoryDBRepository) GetReservationByID(ctx context.Context, id int) (models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetReservationByID"); err != nil {
+		return models.Reservation{}, err
+	}
+
+	found := m.t.liveReservations(func(r models.Reservation) bool { return r.ID == id })
+	if len(found) == 0 {
+		return models.Reservation{}, sql.ErrNoRows
+	}
+
+	return found[0], nil
+}
+
+// GetReservationByRef returns a reservation, not in the trash, by its confirmation reference
+func (m *MemoryDBRepository) GetReservationByRef(ctx context.Context, ref string) (models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetReservationByRef"); err != nil {
+		return models.Reservation{}, err
+	}
+
+	found := m.t.liveReservations(func(r models.Reservation) bool { return r.ConfirmationRef == ref })
+	if len(found) == 0 {
+		return models.Reservation{}, sql.ErrNoRows
+	}
+
+	return found[0], nil
+}
+
+// UpdateReservation updates a guest's name, email & phone
+func (m *MemoryDBRepository) UpdateReservation(ctx context.Context, rsvn models.Reservation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "UpdateReservation"); err != nil {
+		return err
+	}
+
+	if r := m.t.reservation(rsvn.ID); r != nil {
+		r.FirstName = rsvn.FirstName
+		r.LastName = rsvn.LastName
+		r.Email = rsvn.Email
+		r.Phone = rsvn.Phone
+		r.UpdatedAt = time.Now()
+	}
+
+	return nil
+}
+
+// DeleteReservation moves a reservation & its room restrictions to the trash, freeing the room
+func (m *MemoryDBRepository) DeleteReservation(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "DeleteReservation"); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if r := m.t.reservation(id); r != nil && r.DeletedAt.IsZero() {
+		r.DeletedAt = now
+	}
+	for i, rr := range m.t.restrictions {
+		if rr.ReservationID == int64(id) && rr.DeletedAt.IsZero() {
+			m.t.restrictions[i].DeletedAt = now
+		}
+	}
+
+	return nil
+}
+
+// GetDeletedReservations returns every reservation in the trash, most recently deleted first
+func (m *MemoryDBRepository) GetDeletedReservations(ctx context.Context) ([]models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetDeletedReservations"); err != nil {
+		return nil, err
+	}
+
+	var reservations []models.Reservation
+	for _, r := range m.t.reservations {
+		if !r.DeletedAt.IsZero() {
+			r.Room = m.t.roomRef(r.RoomID)
+			reservations = append(reservations, r)
+		}
+	}
+	sort.SliceStable(reservations, func(i, j int) bool { return reservations[i].DeletedAt.After(reservations[j].DeletedAt) })
+
+	return reservations, nil
+}
+
+// RestoreReservation takes a reservation & its room restrictions out of the trash, provided its room is still free for its dates. A
+// cancelled reservation's restrictions were deleted on cancelling it, so are left deleted
+func (m *MemoryDBRepository) RestoreReservation(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "RestoreReservation"); err != nil {
+		return err
+	}
+
+	r := m.t.reservation(id)
+	if r == nil {
+		return nil
+	}
+
+	if r.Status != models.StatusCancelled {
+		now := time.Now()
+		for _, rr := range m.t.restrictions {
+			if rr.ReservationID == int64(id) && !rr.DeletedAt.IsZero() && m.t.taken(rr.RoomID, rr.StartDate, rr.EndDate, "", now) {
+				return &repository.RoomUnavailableError{RoomID: rr.RoomID, StartDate: rr.StartDate, EndDate: rr.EndDate}
+			}
+		}
+
+		for i, rr := range m.t.restrictions {
+			if rr.ReservationID == int64(id) {
+				m.t.restrictions[i].DeletedAt = time.Time{}
+			}
+		}
+	}
+	r.DeletedAt = time.Time{}
+
+	return nil
+}
+
+// UpdateReservationStatus moves a reservation from one status to another, as allowed by its lifecycle, recording the change. Should
+// the reservation no longer have the expected status, a StatusChangedError is returned. Cancelling frees the room
+func (m *MemoryDBRepository) UpdateReservationStatus(ctx context.Context, id int, from, to models.ReservationStatus) error {
+	err := lifecycle.Check(from, to)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "UpdateReservationStatus"); err != nil {
+		return err
+	}
+
+	r := m.t.reservation(id)
+	if r == nil || r.Status != from {
+		return &repository.StatusChangedError{ReservationID: id, Expected: from}
+	}
+
+	m.t.changeStatus(r, to, time.Now())
+
+	return nil
+}
+
+// changeStatus moves a reservation to a status, recording the change & soft deleting its room restrictions should it be cancelled
+func (t *memoryTables) changeStatus(r *models.Reservation, to models.ReservationStatus, now time.Time) {
+	from := r.Status
+	r.Status = to
+	r.UpdatedAt = now
+	id := r.ID
+
+	t.statusChanges = append(t.statusChanges, models.StatusChange{
+		ID:            t.nextID("reservation_status_changes"),
+		ReservationID: id,
+		FromStatus:    from,
+		ToStatus:      to,
+		CreatedAt:     now,
+	})
+
+	if to == models.StatusCancelled {
+		for i, rr := range t.restrictions {
+			if rr.ReservationID == int64(id) && rr.DeletedAt.IsZero() {
+				t.restrictions[i].DeletedAt = now
+			}
+		}
+	}
+}
+
+// GetReservationStatusChanges returns a reservation's status changes, oldest first
+func (m *MemoryDBRepository) GetReservationStatusChanges(ctx context.Context, id int) ([]models.StatusChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetReservationStatusChanges"); err != nil {
+		return nil, err
+	}
+
+	var changes []models.StatusChange
+	for _, c := range m.t.statusChanges {
+		if c.ReservationID == id {
+			changes = append(changes, c)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].CreatedAt.Before(changes[j].CreatedAt) })
+
+	return changes, nil
+}
+
+// groupReservations returns a booking group's reservations, not in the trash, with their rooms in room order
+func (t *memoryTables) groupReservations(groupID int) []models.Reservation {
+	reservations := t.liveReservations(func(r models.Reservation) bool { return r.GroupID == groupID })
+	sort.SliceStable(reservations, func(i, j int) bool { return reservations[i].RoomID < reservations[j].RoomID })
+
+	return reservations
+}
+
+// GetAllBookingGroups returns every booking group with a reservation not in the trash, newest first
+func (m *MemoryDBRepository) GetAllBookingGroups(ctx context.Context) ([]models.BookingGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetAllBookingGroups"); err != nil {
+		return nil, err
+	}
+
+	var groups []models.BookingGroup
+	for _, g := range m.t.bookingGroups {
+		g.Reservations = m.t.groupReservations(g.ID)
+		if len(g.Reservations) > 0 {
+			groups = append(groups, g)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].CreatedAt.After(groups[j].CreatedAt) })
+
+	return groups, nil
+}
+
+// GetBookingGroupByID returns a booking group by id, with its reservations not in the trash
+func (m *MemoryDBRepository) GetBookingGroupByID(ctx context.Context, id int) (models.BookingGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetBookingGroupByID"); err != nil {
+		return models.BookingGroup{}, err
+	}
+
+	for _, g := range m.t.bookingGroups {
+		if g.ID == id {
+			g.Reservations = m.t.groupReservations(id)
+			return g, nil
+		}
+	}
+
+	return models.BookingGroup{}, sql.ErrNoRows
+}
+
+// UpdateBookingGroupStatus moves every reservation of a booking group to a status, provided its lifecycle allows each of them to move
+func (m *MemoryDBRepository) UpdateBookingGroupStatus(ctx context.Context, id int, to models.ReservationStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "UpdateBookingGroupStatus"); err != nil {
+		return err
+	}
+
+	reservations := m.t.groupReservations(id)
+	if len(reservations) == 0 {
+		return sql.ErrNoRows
+	}
+
+	for _, r := range reservations {
+		err := lifecycle.Check(r.Status, to)
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for _, r := range reservations {
+		m.t.changeStatus(m.t.reservation(r.ID), to, now)
+	}
+
+	return nil
+}
+
+// DeleteBookingGroup moves every reservation of a booking group, & their room restrictions, to the trash
+func (m *MemoryDBRepository) DeleteBookingGroup(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "DeleteBookingGroup"); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i, r := range m.t.reservations {
+		if r.GroupID != id || !r.DeletedAt.IsZero() {
+			continue
+		}
+		for j, rr := range m.t.restrictions {
+			if rr.ReservationID == int64(r.ID) && rr.DeletedAt.IsZero() {
+				m.t.restrictions[j].DeletedAt = now
+			}
+		}
+		m.t.reservations[i].DeletedAt = now
+	}
+
+	return nil
+}
+
+// InsertWaitlistEntry adds a guest to the waitlist
+func (m *MemoryDBRepository) InsertWaitlistEntry(ctx context.Context, entry models.WaitlistEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "InsertWaitlistEntry"); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	m.t.waitlist = append(m.t.waitlist, models.WaitlistEntry{
+		ID:        m.t.nextID("waitlist"),
+		FirstName: entry.FirstName,
+		LastName:  entry.LastName,
+		Email:     entry.Email,
+		Phone:     entry.Phone,
+		StartDate: dateOf(entry.StartDate),
+		EndDate:   dateOf(entry.EndDate),
+		Adults:    entry.Adults,
+		Children:  entry.Children,
+		Infants:   entry.Infants,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+
+	return nil
+}
+
+// GetWaitlist returns every guest on the waitlist in the order they joined
+func (m *MemoryDBRepository) GetWaitlist(ctx context.Context) ([]models.WaitlistEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetWaitlist"); err != nil {
+		return nil, err
+	}
+
+	entries := append([]models.WaitlistEntry(nil), m.t.waitlist...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].CreatedAt.Before(entries[j].CreatedAt) })
+
+	return entries, nil
+}
+
+// GetWaitlistByDates returns the guests, not yet notified, waiting for dates overlapping a date range, in the order they joined
+func (m *MemoryDBRepository) GetWaitlistByDates(ctx context.Context, startDate, endDate time.Time) ([]models.WaitlistEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetWaitlistByDates"); err != nil {
+		return nil, err
+	}
+
+	var entries []models.WaitlistEntry
+	for _, e := range m.t.waitlist {
+		if e.NotifiedAt.IsZero() && overlaps(e.StartDate, e.EndDate, startDate, endDate) {
+			entries = append(entries, e)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].CreatedAt.Before(entries[j].CreatedAt) })
+
+	return entries, nil
+}
+
+// MarkWaitlistNotified records that a waiting guest has been told a room is free
+func (m *MemoryDBRepository) MarkWaitlistNotified(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "MarkWaitlistNotified"); err != nil {
+		return err
+	}
+
+	for i := range m.t.waitlist {
+		if m.t.waitlist[i].ID == id {
+			m.t.waitlist[i].NotifiedAt = time.Now()
+			m.t.waitlist[i].UpdatedAt = time.Now()
+		}
+	}
+
+	return nil
+}
+
+// DeleteWaitlistEntry removes a guest from the waitlist
+func (m *MemoryDBRepository) DeleteWaitlistEntry(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "DeleteWaitlistEntry"); err != nil {
+		return err
+	}
+
+	var kept []models.WaitlistEntry
+	for _, e := range m.t.waitlist {
+		if e.ID != id {
+			kept = append(kept, e)
+		}
+	}
+	m.t.waitlist = kept
+
+	return nil
+}
+
+// reservationOf returns the reservation a room restriction is for, deleted or not, as joined to the restriction
+func (t *memoryTables) reservationOf(rr models.RoomRestriction) models.Reservation {
+	if r := t.reservation(int(rr.ReservationID)); r != nil {
+		return *r
+	}
+	return models.Reservation{}
+}
+
+// GetRoomRestrictionsByDate returns a room's reservations & blocks, not in the trash, overlapping a date range, with each reservation's status
+func (m *MemoryDBRepository) GetRoomRestrictionsByDate(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.RoomRestriction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetRoomRestrictionsByDate"); err != nil {
+		return nil, err
+	}
+
+	var restrictions []models.RoomRestriction
+	for _, rr := range m.t.restrictions {
+		if rr.RoomID == roomID && rr.RestrictionID != models.RestrictionHold && rr.DeletedAt.IsZero() && overlaps(rr.StartDate, rr.EndDate, startDate, endDate) {
+			rr.Reservation.Status = m.t.reservationOf(rr).Status
+			restrictions = append(restrictions, rr)
+		}
+	}
+
+	return restrictions, nil
+}
+
+// GetRoomRestrictionsForCalendar returns everything taking a room for dates overlapping a date range, unexpired holds included, in date order
+func (m *MemoryDBRepository) GetRoomRestrictionsForCalendar(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.RoomRestriction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetRoomRestrictionsForCalendar"); err != nil {
+		return nil, err
+	}
+
+	var restrictions []models.RoomRestriction
+	now := time.Now()
+	for _, rr := range m.t.restrictions {
+		if rr.RoomID != roomID || !rr.DeletedAt.IsZero() || (!rr.ExpiresAt.IsZero() && !rr.ExpiresAt.After(now)) {
+			continue
+		}
+		if overlaps(rr.StartDate, rr.EndDate, startDate, endDate) {
+			restrictions = append(restrictions, rr)
+		}
+	}
+	sort.SliceStable(restrictions, func(i, j int) bool { return restrictions[i].StartDate.Before(restrictions[j].StartDate) })
+
+	return restrictions, nil
+}
+
+// GetRoomRestrictionsForFeed returns a room's reservations & blocks, not in the trash, ending on or after a date, with each reservation's
+// guest, in date order
+func (m *MemoryDBRepository) GetRoomRestrictionsForFeed(ctx context.Context, roomID int, from time.Time) ([]models.RoomRestriction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetRoomRestrictionsForFeed"); err != nil {
+		return nil, err
+	}
+
+	var restrictions []models.RoomRestriction
+	for _, rr := range m.t.restrictions {
+		if rr.RoomID != roomID || rr.RestrictionID == models.RestrictionHold || !rr.DeletedAt.IsZero() || rr.EndDate.Before(from) {
+			continue
+		}
+		r := m.t.reservationOf(rr)
+		rr.Reservation = models.Reservation{
+			FirstName:       r.FirstName,
+			LastName:        r.LastName,
+			Status:          r.Status,
+			Adults:          r.Adults,
+			Children:        r.Children,
+			Infants:         r.Infants,
+			ConfirmationRef: r.ConfirmationRef,
+		}
+		restrictions = append(restrictions, rr)
+	}
+	sort.SliceStable(restrictions, func(i, j int) bool { return restrictions[i].StartDate.Before(restrictions[j].StartDate) })
+
+	return restrictions, nil
+}
+
+// InsertRoomBlock inserts an owner block, without checking the room is free
+func (m *MemoryDBRepository) InsertRoomBlock(ctx context.Context, roomID int, startDate, endDate time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "InsertRoomBlock"); err != nil {
+		return err
+	}
+
+	if err := m.t.roomExists(roomID); err != nil {
+		return err
+	}
+
+	m.t.insertRestriction(models.RoomRestriction{RoomID: roomID, RestrictionID: models.RestrictionOwnerBlock, StartDate: startDate, EndDate: endDate}, time.Now())
+
+	return nil
+}
+
+// CreateRoomBlock re-checks availability, then inserts an owner block, returning its id
+func (m *MemoryDBRepository) CreateRoomBlock(ctx context.Context, roomID int, startDate, endDate time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "CreateRoomBlock"); err != nil {
+		return 0, err
+	}
+
+	if _, ok := m.t.room(roomID); !ok {
+		return 0, sql.ErrNoRows
+	}
+
+	now := time.Now()
+	if m.t.taken(roomID, startDate, endDate, "", now) {
+		return 0, &repository.RoomUnavailableError{RoomID: roomID, StartDate: startDate, EndDate: endDate}
+	}
+
+	id := m.t.insertRestriction(models.RoomRestriction{RoomID: roomID, RestrictionID: models.RestrictionOwnerBlock, StartDate: startDate, EndDate: endDate}, now)
+
+	return int64(id), nil
+}
+
+// GetRoomBlockByID returns an owner block not in the trash
+func (m *MemoryDBRepository) GetRoomBlockByID(ctx context.Context, id int) (models.RoomRestriction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetRoomBlockByID"); err != nil {
+		return models.RoomRestriction{}, err
+	}
+
+	for _, rr := range m.t.restrictions {
+		if rr.ID == id && rr.RestrictionID == models.RestrictionOwnerBlock && rr.DeletedAt.IsZero() {
+			return rr, nil
+		}
+	}
+
+	return models.RoomRestriction{}, sql.ErrNoRows
+}
+
+// DeleteRoomBlock moves an owner block to the trash, freeing the room. sql.ErrNoRows is returned if there is no such block, or it is
+// already in the trash
+func (m *MemoryDBRepository) DeleteRoomBlock(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "DeleteRoomBlock"); err != nil {
+		return err
+	}
+
+	for i, rr := range m.t.restrictions {
+		if rr.ID == id && rr.RestrictionID == models.RestrictionOwnerBlock && rr.DeletedAt.IsZero() {
+			m.t.restrictions[i].DeletedAt = time.Now()
+			return nil
+		}
+	}
+
+	return sql.ErrNoRows
+}
+
+// GetDeletedRoomBlocks returns every owner block in the trash with its room, most recently deleted first
+func (m *MemoryDBRepository) GetDeletedRoomBlocks(ctx context.Context) ([]models.RoomRestriction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetDeletedRoomBlocks"); err != nil {
+		return nil, err
+	}
+
+	var blocks []models.RoomRestriction
+	for _, rr := range m.t.restrictions {
+		if rr.RestrictionID == models.RestrictionOwnerBlock && !rr.DeletedAt.IsZero() {
+			rr.Room = m.t.roomRef(rr.RoomID)
+			blocks = append(blocks, rr)
+		}
+	}
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].DeletedAt.After(blocks[j].DeletedAt) })
+
+	return blocks, nil
+}
+
+// RestoreRoomBlock takes an owner block out of the trash, provided its room is still free for its dates
+func (m *MemoryDBRepository) RestoreRoomBlock(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "RestoreRoomBlock"); err != nil {
+		return err
+	}
+
+	for i, rr := range m.t.restrictions {
+		if rr.ID != id || rr.DeletedAt.IsZero() {
+			continue
+		}
+		if m.t.taken(rr.RoomID, rr.StartDate, rr.EndDate, "", time.Now()) {
+			return &repository.RoomUnavailableError{RoomID: rr.RoomID, StartDate: rr.StartDate, EndDate: rr.EndDate}
+		}
+		m.t.restrictions[i].DeletedAt = time.Time{}
+		return nil
+	}
+
+	return sql.ErrNoRows
+}
+
+// PurgeDeleted permanently removes reservations, with their restrictions & status changes, & owner blocks deleted before a given time,
+// returning how many reservations & blocks were removed
+func (m *MemoryDBRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "PurgeDeleted"); err != nil {
+		return 0, err
+	}
+
+	purged := make(map[int64]bool)
+	var kept []models.Reservation
+	for _, r := range m.t.reservations {
+		if !r.DeletedAt.IsZero() && r.DeletedAt.Before(before) {
+			purged[int64(r.ID)] = true
+			continue
+		}
+		kept = append(kept, r)
+	}
+	m.t.reservations = kept
+
+	var changes []models.StatusChange
+	for _, c := range m.t.statusChanges {
+		if !purged[int64(c.ReservationID)] {
+			changes = append(changes, c)
+		}
+	}
+	m.t.statusChanges = changes
+
+	m.t.deleteRestrictions(func(rr models.RoomRestriction) bool { return purged[rr.ReservationID] })
+	blocks := m.t.deleteRestrictions(func(rr models.RoomRestriction) bool {
+		return rr.ReservationID == 0 && !rr.DeletedAt.IsZero() && rr.DeletedAt.Before(before)
+	})
+
+	return int64(len(purged)) + blocks, nil
+}
+
+// GetAllICalFeeds returns every iCal feed with its room, by room name, public feeds before private
+func (m *MemoryDBRepository) GetAllICalFeeds(ctx context.Context) ([]models.ICalFeed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetAllICalFeeds"); err != nil {
+		return nil, err
+	}
+
+	var feeds []models.ICalFeed
+	for _, f := range m.t.icalFeeds {
+		f.Room = m.t.roomRef(f.RoomID)
+		feeds = append(feeds, f)
+	}
+	sort.SliceStable(feeds, func(i, j int) bool {
+		if feeds[i].Room.RoomName != feeds[j].Room.RoomName {
+			return feeds[i].Room.RoomName < feeds[j].Room.RoomName
+		}
+		if feeds[i].Private != feeds[j].Private {
+			return !feeds[i].Private
+		}
+		return feeds[i].CreatedAt.Before(feeds[j].CreatedAt)
+	})
+
+	return feeds, nil
+}
+
+// GetICalFeedByToken returns an iCal feed, with its room, by the token in its URL
+func (m *MemoryDBRepository) GetICalFeedByToken(ctx context.Context, token string) (models.ICalFeed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetICalFeedByToken"); err != nil {
+		return models.ICalFeed{}, err
+	}
+
+	for _, f := range m.t.icalFeeds {
+		if f.Token != token {
+			continue
+		}
+		if _, ok := m.t.room(f.RoomID); ok {
+			f.Room = m.t.roomRef(f.RoomID)
+			return f, nil
+		}
+	}
+
+	return models.ICalFeed{}, sql.ErrNoRows
+}
+
+// InsertICalFeed inserts an iCal feed
+func (m *MemoryDBRepository) InsertICalFeed(ctx context.Context, feed models.ICalFeed) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "InsertICalFeed"); err != nil {
+		return err
+	}
+
+	if err := m.t.roomExists(feed.RoomID); err != nil {
+		return err
+	}
+
+	m.t.icalFeeds = append(m.t.icalFeeds, models.ICalFeed{
+		ID:        m.t.nextID("ical_feeds"),
+		RoomID:    feed.RoomID,
+		Token:     feed.Token,
+		Private:   feed.Private,
+		CreatedAt: time.Now(),
+	})
+
+	return nil
+}
+
+// DeleteICalFeed revokes an iCal feed
+func (m *MemoryDBRepository) DeleteICalFeed(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "DeleteICalFeed"); err != nil {
+		return err
+	}
+
+	var kept []models.ICalFeed
+	for _, f := range m.t.icalFeeds {
+		if f.ID != id {
+			kept = append(kept, f)
+		}
+	}
+	m.t.icalFeeds = kept
+
+	return nil
+}
+
+// GetAllICalImports returns every iCal import with its room, by room name then import name
+func (m *MemoryDBRepository) GetAllICalImports(ctx context.Context) ([]models.ICalImport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetAllICalImports"); err != nil {
+		return nil, err
+	}
+
+	var imports []models.ICalImport
+	for _, i := range m.t.icalImports {
+		i.Room = m.t.roomRef(i.RoomID)
+		imports = append(imports, i)
+	}
+	sort.SliceStable(imports, func(i, j int) bool {
+		if imports[i].Room.RoomName != imports[j].Room.RoomName {
+			return imports[i].Room.RoomName < imports[j].Room.RoomName
+		}
+		return imports[i].Name < imports[j].Name
+	})
+
+	return imports, nil
+}
+
+// InsertICalImport inserts an iCal import, never yet synced
+func (m *MemoryDBRepository) InsertICalImport(ctx context.Context, imp models.ICalImport) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "InsertICalImport"); err != nil {
+		return err
+	}
+
+	if err := m.t.roomExists(imp.RoomID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	m.t.icalImports = append(m.t.icalImports, models.ICalImport{
+		ID:        m.t.nextID("ical_imports"),
+		RoomID:    imp.RoomID,
+		Name:      imp.Name,
+		URL:       imp.URL,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+
+	return nil
+}
+
+// DeleteICalImport deletes an iCal import together with the external bookings copied from it
+func (m *MemoryDBRepository) DeleteICalImport(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "DeleteICalImport"); err != nil {
+		return err
+	}
+
+	m.t.deleteRestrictions(func(rr models.RoomRestriction) bool {
+		return rr.ImportID == id && rr.RestrictionID == models.RestrictionExternalBooking
+	})
+
+	var kept []models.ICalImport
+	for _, i := range m.t.icalImports {
+		if i.ID != id {
+			kept = append(kept, i)
+		}
+	}
+	m.t.icalImports = kept
+
+	return nil
+}
+
+// UpdateICalImportSync records when an iCal import was last synced, & why it failed, if it did
+func (m *MemoryDBRepository) UpdateICalImportSync(ctx context.Context, id int, syncedAt time.Time, syncErr string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "UpdateICalImportSync"); err != nil {
+		return err
+	}
+
+	for i := range m.t.icalImports {
+		if m.t.icalImports[i].ID == id {
+			m.t.icalImports[i].LastSyncedAt = syncedAt
+			m.t.icalImports[i].LastError = syncErr
+			m.t.icalImports[i].UpdatedAt = time.Now()
+		}
+	}
+
+	return nil
+}
+
+// GetExternalBookings returns the external bookings copied from an iCal import, in date order
+func (m *MemoryDBRepository) GetExternalBookings(ctx context.Context, importID int) ([]models.RoomRestriction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetExternalBookings"); err != nil {
+		return nil, err
+	}
+
+	var bookings []models.RoomRestriction
+	for _, rr := range m.t.restrictions {
+		if rr.ImportID == importID && rr.RestrictionID == models.RestrictionExternalBooking {
+			bookings = append(bookings, rr)
+		}
+	}
+	sort.SliceStable(bookings, func(i, j int) bool { return bookings[i].StartDate.Before(bookings[j].StartDate) })
+
+	return bookings, nil
+}
+
+// SyncExternalBookings removes, updates & adds an iCal import's external bookings, either all of them or none
+func (m *MemoryDBRepository) SyncExternalBookings(ctx context.Context, importID int, add, update []models.RoomRestriction, remove []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "SyncExternalBookings"); err != nil {
+		return err
+	}
+
+	removed := make(map[int]bool)
+	for _, id := range remove {
+		removed[id] = true
+	}
+
+	now := time.Now()
+	return m.transaction(func() error {
+		m.t.deleteRestrictions(func(rr models.RoomRestriction) bool {
+			return removed[rr.ID] && rr.ImportID == importID && rr.RestrictionID == models.RestrictionExternalBooking
+		})
+
+		for _, b := range update {
+			for i, rr := range m.t.restrictions {
+				if rr.ID == b.ID && rr.ImportID == importID && rr.RestrictionID == models.RestrictionExternalBooking {
+					m.t.restrictions[i].StartDate = dateOf(b.StartDate)
+					m.t.restrictions[i].EndDate = dateOf(b.EndDate)
+					m.t.restrictions[i].UpdatedAt = now
+				}
+			}
+		}
+
+		for _, b := range add {
+			if err := m.t.roomExists(b.RoomID); err != nil {
+				return err
+			}
+			m.t.insertRestriction(models.RoomRestriction{
+				RoomID:        b.RoomID,
+				RestrictionID: models.RestrictionExternalBooking,
+				StartDate:     b.StartDate,
+				EndDate:       b.EndDate,
+				ImportID:      importID,
+				ExternalUID:   b.ExternalUID,
+			}, now)
+		}
+		return nil
+	})
+}
+
+// GetBookingConflicts returns every external booking, ending on or after a date, overlapping one of our own reservations in the same room,
+// in date order then by room name
+func (m *MemoryDBRepository) GetBookingConflicts(ctx context.Context, from time.Time) ([]models.BookingConflict, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetBookingConflicts"); err != nil {
+		return nil, err
+	}
+
+	var conflicts []models.BookingConflict
+	for _, e := range m.t.restrictions {
+		if e.RestrictionID != models.RestrictionExternalBooking || e.EndDate.Before(from) {
+			continue
+		}
+		var imp models.ICalImport
+		for _, i := range m.t.icalImports {
+			if i.ID == e.ImportID {
+				imp = i
+			}
+		}
+		room, ok := m.t.room(e.RoomID)
+		if imp.ID == 0 || !ok {
+			continue
+		}
+
+		for _, rr := range m.t.restrictions {
+			if rr.RoomID != e.RoomID || rr.RestrictionID != models.RestrictionReservation || !rr.DeletedAt.IsZero() {
+				continue
+			}
+			r := m.t.reservation(int(rr.ReservationID))
+			if r == nil || !r.DeletedAt.IsZero() || rr.StartDate.After(e.EndDate) || !e.StartDate.Before(rr.EndDate) {
+				continue
+			}
+
+			var c models.BookingConflict
+			c.Booking = models.RoomRestriction{
+				ID:          e.ID,
+				RoomID:      e.RoomID,
+				StartDate:   e.StartDate,
+				EndDate:     e.EndDate,
+				ExternalUID: e.ExternalUID,
+				ImportID:    imp.ID,
+				Room:        models.Room{ID: room.ID, RoomName: room.RoomName},
+			}
+			c.Import = models.ICalImport{ID: imp.ID, Name: imp.Name, RoomID: e.RoomID, Room: c.Booking.Room}
+			c.Reservation = models.Reservation{
+				ID:              r.ID,
+				FirstName:       r.FirstName,
+				LastName:        r.LastName,
+				StartDate:       r.StartDate,
+				EndDate:         r.EndDate,
+				Status:          r.Status,
+				ConfirmationRef: r.ConfirmationRef,
+			}
+			conflicts = append(conflicts, c)
+		}
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		if !conflicts[i].Booking.StartDate.Equal(conflicts[j].Booking.StartDate) {
+			return conflicts[i].Booking.StartDate.Before(conflicts[j].Booking.StartDate)
+		}
+		return conflicts[i].Booking.Room.RoomName < conflicts[j].Booking.Room.RoomName
+	})
+
+	return conflicts, nil
+}
+
+// GetAllWebhooks returns every webhook, oldest first
+func (m *MemoryDBRepository) GetAllWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetAllWebhooks"); err != nil {
+		return nil, err
+	}
+
+	var hooks []models.Webhook
+	for _, h := range m.t.webhooks {
+		if len(h.Events) > 0 {
+			h.Events = append([]string(nil), h.Events...)
+		} else {
+			h.Events = nil
+		}
+		hooks = append(hooks, h)
+	}
+	sort.SliceStable(hooks, func(i, j int) bool { return hooks[i].CreatedAt.Before(hooks[j].CreatedAt) })
+
+	return hooks, nil
+}
+
+// InsertWebhook inserts a webhook
+func (m *MemoryDBRepository) InsertWebhook(ctx context.Context, hook models.Webhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "InsertWebhook"); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	m.t.webhooks = append(m.t.webhooks, models.Webhook{
+		ID:        m.t.nextID("webhooks"),
+		URL:       hook.URL,
+		Secret:    hook.Secret,
+		Events:    append([]string(nil), hook.Events...),
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+
+	return nil
+}
+
+// DeleteWebhook deletes a webhook together with its deliveries
+func (m *MemoryDBRepository) DeleteWebhook(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "DeleteWebhook"); err != nil {
+		return err
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, d := range m.t.deliveries {
+		if d.WebhookID != id {
+			deliveries = append(deliveries, d)
+		}
+	}
+	m.t.deliveries = deliveries
+
+	var hooks []models.Webhook
+	for _, h := range m.t.webhooks {
+		if h.ID != id {
+			hooks = append(hooks, h)
+		}
+	}
+	m.t.webhooks = hooks
+
+	return nil
+}
+
+// InsertWebhookDeliveries inserts deliveries, each due at once
+func (m *MemoryDBRepository) InsertWebhookDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "InsertWebhookDeliveries"); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, d := range deliveries {
+		m.t.deliveries = append(m.t.deliveries, models.WebhookDelivery{
+			ID:            m.t.nextID("webhook_deliveries"),
+			WebhookID:     d.WebhookID,
+			Event:         d.Event,
+			Payload:       d.Payload,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+	}
+
+	return nil
+}
+
+// webhookDeliveries returns the deliveries for which keep is true, each with its webhook
+func (t *memoryTables) webhookDeliveries(keep func(models.WebhookDelivery) bool) []models.WebhookDelivery {
+	var deliveries []models.WebhookDelivery
+	for _, d := range t.deliveries {
+		if !keep(d) {
+			continue
+		}
+		for _, h := range t.webhooks {
+			if h.ID == d.WebhookID {
+				d.Webhook = models.Webhook{ID: h.ID, URL: h.URL, Secret: h.Secret}
+				deliveries = append(deliveries, d)
+			}
+		}
+	}
+
+	return deliveries
+}
+
+// GetDueWebhookDeliveries returns up to limit pending deliveries due by a given time, soonest due first
+func (m *MemoryDBRepository) GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetDueWebhookDeliveries"); err != nil {
+		return nil, err
+	}
+
+	due := m.t.webhookDeliveries(func(d models.WebhookDelivery) bool {
+		return d.Status == models.DeliveryPending && !d.NextAttemptAt.After(now)
+	})
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+
+	return due[:limited(len(due), limit)], nil
+}
+
+// UpdateWebhookDelivery records the outcome of an attempt to deliver
+func (m *MemoryDBRepository) UpdateWebhookDelivery(ctx context.Context, d models.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "UpdateWebhookDelivery"); err != nil {
+		return err
+	}
+
+	for i := range m.t.deliveries {
+		if m.t.deliveries[i].ID != d.ID {
+			continue
+		}
+		row := &m.t.deliveries[i]
+		row.Status = d.Status
+		row.Attempts = d.Attempts
+		row.ResponseCode = d.ResponseCode
+		row.LastError = d.LastError
+		row.NextAttemptAt = d.NextAttemptAt
+		row.DeliveredAt = d.DeliveredAt
+		row.UpdatedAt = time.Now()
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries returns up to limit deliveries, newest first
+func (m *MemoryDBRepository) GetWebhookDeliveries(ctx context.Context, limit int) ([]models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetWebhookDeliveries"); err != nil {
+		return nil, err
+	}
+
+	deliveries := m.t.webhookDeliveries(func(models.WebhookDelivery) bool { return true })
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID > deliveries[j].ID
+	})
+
+	return deliveries[:limited(len(deliveries), limit)], nil
+}
+
+// RetryWebhookDelivery makes a failed delivery due again with no attempts, returning sql.ErrNoRows should it not have failed
+func (m *MemoryDBRepository) RetryWebhookDelivery(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "RetryWebhookDelivery"); err != nil {
+		return err
+	}
+
+	for i := range m.t.deliveries {
+		row := &m.t.deliveries[i]
+		if row.ID != id || row.Status != models.DeliveryFailed {
+			continue
+		}
+		row.Status = models.DeliveryPending
+		row.Attempts = 0
+		row.NextAttemptAt = time.Now()
+		row.UpdatedAt = time.Now()
+		return nil
+	}
+
+	return sql.ErrNoRows
+}
+
+// GetChannelRooms returns every room mapped to the channel manager, by room name
+func (m *MemoryDBRepository) GetChannelRooms(ctx context.Context) ([]models.ChannelRoom, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetChannelRooms"); err != nil {
+		return nil, err
+	}
+
+	var rooms []models.ChannelRoom
+	for _, c := range m.t.channelRooms {
+		if _, ok := m.t.room(c.RoomID); ok {
+			c.Room = m.t.roomRef(c.RoomID)
+			rooms = append(rooms, c)
+		}
+	}
+	sort.SliceStable(rooms, func(i, j int) bool { return rooms[i].Room.RoomName < rooms[j].Room.RoomName })
+
+	return rooms, nil
+}
+
+// SaveChannelRooms replaces every room's mapping to the channel manager, forgetting what was pushed for any room whose codes have changed
+// or which is no longer mapped
+func (m *MemoryDBRepository) SaveChannelRooms(ctx context.Context, rooms []models.ChannelRoom) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "SaveChannelRooms"); err != nil {
+		return err
+	}
+
+	existing := make(map[int]models.ChannelRoom)
+	for _, c := range m.t.channelRooms {
+		existing[c.RoomID] = c
+	}
+	kept := make(map[int]bool)
+	for _, c := range rooms {
+		if e, ok := existing[c.RoomID]; ok && e.RoomCode == c.RoomCode && e.RatePlanCode == c.RatePlanCode {
+			kept[c.RoomID] = true
+		}
+	}
+
+	now := time.Now()
+	return m.transaction(func() error {
+		for k := range m.t.channelDays {
+			if !kept[k.roomID] {
+				delete(m.t.channelDays, k)
+			}
+		}
+
+		m.t.channelRooms = nil
+		for _, c := range rooms {
+			if err := m.t.roomExists(c.RoomID); err != nil {
+				return err
+			}
+			createdAt := now
+			if kept[c.RoomID] {
+				createdAt = existing[c.RoomID].CreatedAt
+			}
+			m.t.channelRooms = append(m.t.channelRooms, models.ChannelRoom{
+				RoomID:       c.RoomID,
+				RoomCode:     c.RoomCode,
+				RatePlanCode: c.RatePlanCode,
+				CreatedAt:    createdAt,
+				UpdatedAt:    now,
+			})
+		}
+		return nil
+	})
+}
+
+// GetChannelDays returns a room's nights from start to end inclusive, as last pushed to the channel manager, in date order
+func (m *MemoryDBRepository) GetChannelDays(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.ChannelDay, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetChannelDays"); err != nil {
+		return nil, err
+	}
+
+	var days []models.ChannelDay
+	for k, d := range m.t.channelDays {
+		if k.roomID == roomID && !k.night.Before(startDate) && !k.night.After(endDate) {
+			days = append(days, d)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date.Before(days[j].Date) })
+
+	return days, nil
+}
+
+// SaveChannelDays records a room's nights as pushed to the channel manager, replacing any pushed before
+func (m *MemoryDBRepository) SaveChannelDays(ctx context.Context, roomID int, days []models.ChannelDay) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "SaveChannelDays"); err != nil {
+		return err
+	}
+
+	for _, d := range days {
+		d.Date = dateOf(d.Date)
+		m.t.channelDays[channelNight{roomID: roomID, night: d.Date}] = d
+	}
+
+	return nil
+}
+
+// ResetChannelDays forgets every night pushed to the channel manager, so that everything is pushed again
+func (m *MemoryDBRepository) ResetChannelDays(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "ResetChannelDays"); err != nil {
+		return err
+	}
+
+	m.t.channelDays = make(map[channelNight]models.ChannelDay)
+
+	return nil
+}
+
+// InsertChannelSync records a message pushed to the channel manager in the sync log
+func (m *MemoryDBRepository) InsertChannelSync(ctx context.Context, s models.ChannelSync) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "InsertChannelSync"); err != nil {
+		return err
+	}
+
+	s.ID = m.t.nextID("channel_syncs")
+	s.StartDate = dateOf(s.StartDate)
+	s.EndDate = dateOf(s.EndDate)
+	s.CreatedAt = time.Now()
+	s.Room = models.Room{}
+	m.t.channelSyncs = append(m.t.channelSyncs, s)
+
+	return nil
+}
+
+// GetChannelSyncs returns up to limit messages from the sync log with their rooms, newest first
+func (m *MemoryDBRepository) GetChannelSyncs(ctx context.Context, limit int) ([]models.ChannelSync, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fail(ctx, "GetChannelSyncs"); err != nil {
+		return nil, err
+	}
+
+	var syncs []models.ChannelSync
+	for _, s := range m.t.channelSyncs {
+		if _, ok := m.t.room(s.RoomID); ok {
+			s.Room = m.t.roomRef(s.RoomID)
+			syncs = append(syncs, s)
+		}
+	}
+	sort.SliceStable(syncs, func(i, j int) bool {
+		if !syncs[i].CreatedAt.Equal(syncs[j].CreatedAt) {
+			return syncs[i].CreatedAt.After(syncs[j].CreatedAt)
+		}
+		return syncs[i].ID > syncs[j].ID
+	})
+
+	return syncs[:limited(len(syncs), limit)], nil
+}
@@ -0,0 +1,120 @@
+package dbrepository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/StratoNET/bnb-bookings/internal/config"
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/repository"
+)
+
+var _ repository.DatabaseRepository = (*MemoryDBRepository)(nil)
+
+// newMemoryRepo returns an in-memory repository holding what the behavioural tests expect of a newly migrated database, seeded as by
+// testdata/seed.sql
+func newMemoryRepo(t *testing.T) *MemoryDBRepository {
+	t.Helper()
+	repo := NewMemoryDBRepository(&config.AppConfig{})
+	_, err := repo.AddAdministrator(models.Administrator{FirstName: "Ann", LastName: "Admin", Email: "admin@example.com", AccessLevel: 3}, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, room := range []models.Room{
+		{ID: 1, NightlyRate: 8000, WeekendRate: 9500, IncludedGuests: 2, ExtraGuestRate: 1500},
+		{ID: 2, NightlyRate: 9000, WeekendRate: 11000, IncludedGuests: 2, ExtraGuestRate: 2000},
+	} {
+		if err = repo.UpdateRoomRates(context.Background(), room); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+// TestMemoryDBRepository runs the behavioural tests against the in-memory repository, so that it behaves as the SQL repositories do
+func TestMemoryDBRepository(t *testing.T) {
+	testDatabaseRepository(t, func(t *testing.T) repository.DatabaseRepository {
+		return newMemoryRepo(t)
+	})
+}
+
+func TestMemoryDBRepository_FailOn(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepo(t)
+	injected := errors.New("injected failure")
+
+	repo.FailOn("CreateReservation", injected)
+	_, err := repo.CreateReservation(ctx, testReservation(1, 1, 3), "")
+	if err != injected {
+		t.Fatalf("expected injected error, got %v", err)
+	}
+	expectAvailable(t, repo, 1, 1, 3, true)
+
+	repo.FailOn("CreateReservation", nil)
+	mustReserve(t, repo, testReservation(1, 1, 3))
+	expectAvailable(t, repo, 1, 1, 3, false)
+
+	for _, method := range []string{"CreateReservaton", "AllAdministrators"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected FailOn(%q) to panic", method)
+				}
+			}()
+			repo.FailOn(method, injected)
+		}()
+	}
+}
+
+func TestMemoryDBRepository_FailedTransactionChangesNothing(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepo(t)
+	mustReserve(t, repo, testReservation(2, 5, 7))
+
+	// room 1 is free but room 2 is not, so neither may be booked
+	group := models.BookingGroup{StartDate: day(5), EndDate: day(7), Reservations: []models.Reservation{testReservation(1, 5, 7), testReservation(2, 5, 7)}}
+	_, err := repo.CreateBookingGroup(ctx, group, "")
+	expectUnavailable(t, err, "CreateBookingGroup")
+	expectAvailable(t, repo, 1, 5, 7, true)
+
+	groups, err := repo.GetAllBookingGroups(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 0 {
+		t.Errorf("expected no booking groups, got %d", len(groups))
+	}
+}
+
+func TestMemoryDBRepository_ConcurrentReservations(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepo(t)
+
+	const guests = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, guests)
+	for i := 0; i < guests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.CreateReservation(ctx, testReservation(1, 10, 12), "")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	booked := 0
+	for err := range errs {
+		if err == nil {
+			booked++
+			continue
+		}
+		expectUnavailable(t, err, "CreateReservation")
+	}
+	if booked != 1 {
+		t.Errorf("expected exactly 1 reservation to be made, got %d", booked)
+	}
+}
@@ -0,0 +1,31 @@
+package dbrepository
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/StratoNET/bnb-bookings/internal/config"
+	"github.com/StratoNET/bnb-bookings/internal/database"
+	"github.com/StratoNET/bnb-bookings/internal/repository"
+)
+
+// TestPostgresDBRepository runs the behavioural tests against a Postgres database, given by TEST_POSTGRES_DSN
+// e.g. host=localhost dbname=bnb_bookings_test user=postgres sslmode=disable timezone=UTC, whose tables are dropped & migrated afresh
+func TestPostgresDBRepository(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := sql.Open(database.Postgres, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testDatabaseRepository(t, func(t *testing.T) repository.DatabaseRepository {
+		resetSchema(t, db, database.Postgres)
+		return NewPostgresDBRepository(db, &config.AppConfig{})
+	})
+}
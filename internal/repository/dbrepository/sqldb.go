@@ -0,0 +1,3339 @@
+package dbrepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/lifecycle"
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func (m *sqlDBRepository) AllAdministrators() bool {
+	return true
+}
+
+// InsertReservation inserts a new reservation record into database
+func (m *sqlDBRepository) InsertReservation(ctx context.Context, rsvn models.Reservation) (int64, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "InsertReservation")
+	defer cancel()
+
+	stmt := `INSERT INTO reservations (room_id, first_name, last_name, email, phone, start_date, end_date, status, total_price, adults, children, infants, confirmation_ref, created_at, updated_at) 
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	id, err := m.DB.insertID(ctx, stmt,
+		rsvn.RoomID,
+		rsvn.FirstName,
+		rsvn.LastName,
+		rsvn.Email,
+		rsvn.Phone,
+		rsvn.StartDate,
+		rsvn.EndDate,
+		models.StatusPending,
+		rsvn.TotalPrice,
+		rsvn.Adults,
+		rsvn.Children,
+		rsvn.Infants,
+		rsvn.ConfirmationRef,
+		time.Now(),
+		time.Now(),
+	)
+
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+
+	return id, nil
+}
+
+// InsertRoomRestriction inserts a room restriction in database
+func (m *sqlDBRepository) InsertRoomRestriction(ctx context.Context, rest models.RoomRestriction) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "InsertRoomRestriction")
+	defer cancel()
+
+	stmt := `INSERT INTO room_restrictions (room_id, reservation_id, restriction_id, start_date, end_date, created_at, updated_at) 
+	VALUES (?, ?, ?, ?, ?, ?, ?);`
+
+	_, err := m.DB.ExecContext(ctx, stmt,
+		rest.RoomID,
+		rest.ReservationID,
+		rest.RestrictionID,
+		rest.StartDate,
+		rest.EndDate,
+		time.Now(),
+		time.Now(),
+	)
+
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// CreateReservation re-checks availability, then inserts a reservation & its room restriction within a single transaction. The guest's
+// own hold on the room, identified by holdToken, does not count against availability & is turned into the reservation's restriction
+func (m *sqlDBRepository) CreateReservation(ctx context.Context, rsvn models.Reservation, holdToken string) (int64, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "CreateReservation")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	reservationID, err := createReservation(ctx, tx, rsvn, holdToken)
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+
+	// any other hold left by the guest, e.g. on a room chosen earlier, is no longer needed
+	_, err = tx.ExecContext(ctx, "DELETE FROM room_restrictions WHERE restriction_id = ? AND hold_token = ?;", models.RestrictionHold, holdToken)
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, interrupted(ctx, err)
+	}
+
+	return reservationID, nil
+}
+
+// CreateBookingGroup inserts a booking group together with a reservation & room restriction for each of its rooms, within a single
+// transaction, so either every room is booked or none are. Availability is re-checked for every room, ignoring the guest's own holds
+func (m *sqlDBRepository) CreateBookingGroup(ctx context.Context, group models.BookingGroup, holdToken string) (int64, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "CreateBookingGroup")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO booking_groups (confirmation_ref, first_name, last_name, email, phone, start_date, end_date, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	groupID, err := tx.insertID(ctx, stmt,
+		group.ConfirmationRef,
+		group.FirstName,
+		group.LastName,
+		group.Email,
+		group.Phone,
+		group.StartDate,
+		group.EndDate,
+		time.Now(),
+		time.Now(),
+	)
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+
+	// rooms are always locked in the same order, so two groups sharing rooms cannot each wait on the other
+	reservations := make([]models.Reservation, len(group.Reservations))
+	copy(reservations, group.Reservations)
+	sort.Slice(reservations, func(i, j int) bool { return reservations[i].RoomID < reservations[j].RoomID })
+
+	for _, rsvn := range reservations {
+		rsvn.GroupID = int(groupID)
+		_, err = createReservation(ctx, tx, rsvn, holdToken)
+		if err != nil {
+			return 0, interrupted(ctx, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM room_restrictions WHERE restriction_id = ? AND hold_token = ?;", models.RestrictionHold, holdToken)
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, interrupted(ctx, err)
+	}
+
+	return groupID, nil
+}
+
+// ImportReservations inserts reservations keyed in or imported in bulk, each with its own status, within a single transaction, so either
+// every reservation is imported or none are. Availability is re-checked for each, including against those imported before it, except
+// for cancelled reservations which take no room & so have no room restriction
+func (m *sqlDBRepository) ImportReservations(ctx context.Context, reservations []models.Reservation) ([]int64, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "ImportReservations")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	ids := make([]int64, 0, len(reservations))
+	for _, rsvn := range reservations {
+		var id int64
+		if rsvn.Status == models.StatusCancelled {
+			stmt := `INSERT INTO reservations (room_id, first_name, last_name, email, phone, start_date, end_date, status, total_price, adults, children, infants, confirmation_ref, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+			id, err = tx.insertID(ctx, stmt,
+				rsvn.RoomID,
+				rsvn.FirstName,
+				rsvn.LastName,
+				rsvn.Email,
+				rsvn.Phone,
+				rsvn.StartDate,
+				rsvn.EndDate,
+				rsvn.Status,
+				rsvn.TotalPrice,
+				rsvn.Adults,
+				rsvn.Children,
+				rsvn.Infants,
+				rsvn.ConfirmationRef,
+				time.Now(),
+				time.Now(),
+			)
+			if err != nil {
+				return nil, interrupted(ctx, err)
+			}
+		} else {
+			// no guest holds a room being imported, so no hold is turned into its restriction
+			id, err = createReservation(ctx, tx, rsvn, "")
+			if err != nil {
+				return nil, interrupted(ctx, err)
+			}
+			if rsvn.Status != "" && rsvn.Status != models.StatusPending {
+				_, err = tx.ExecContext(ctx, "UPDATE reservations SET status = ? WHERE id = ?;", rsvn.Status, id)
+				if err != nil {
+					return nil, interrupted(ctx, err)
+				}
+			}
+		}
+		ids = append(ids, id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, interrupted(ctx, err)
+	}
+
+	return ids, nil
+}
+
+// createReservation re-checks availability of a reservation's room, then inserts the reservation & its room restriction within the
+// given transaction, turning the guest's hold on the room, if any, into the restriction
+func createReservation(ctx context.Context, tx *dialectTx, rsvn models.Reservation, holdToken string) (int64, error) {
+	// lock the room row so that any concurrent booking of the same room must wait until this transaction completes
+	var roomID int
+	err := tx.QueryRowContext(ctx, `SELECT id FROM rooms WHERE id = ?`+tx.dialect.lockRows+`;`, rsvn.RoomID).Scan(&roomID)
+	if err != nil {
+		return 0, err
+	}
+
+	// re-check availability (same overlap rules as SearchAvailabilityByDatesAndRoomID) while holding the lock, ignoring the guest's own hold
+	var numRows int
+	query := `SELECT COUNT(id) FROM room_restrictions WHERE room_id = ? AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?) 
+	AND NOT (restriction_id = ? AND hold_token = ?) AND (start_date BETWEEN ? AND ? OR ? BETWEEN start_date AND end_date)` + tx.dialect.lockCounted + `;`
+
+	err = tx.QueryRowContext(ctx, query, rsvn.RoomID, time.Now(), models.RestrictionHold, holdToken, rsvn.StartDate, rsvn.EndDate, rsvn.StartDate).Scan(&numRows)
+	if err != nil {
+		return 0, err
+	}
+	if numRows > 0 {
+		return 0, &repository.RoomUnavailableError{RoomID: rsvn.RoomID, StartDate: rsvn.StartDate, EndDate: rsvn.EndDate}
+	}
+
+	stmt := `INSERT INTO reservations (room_id, first_name, last_name, email, phone, start_date, end_date, status, total_price, adults, children, infants, confirmation_ref, group_id, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	reservationID, err := tx.insertID(ctx, stmt,
+		rsvn.RoomID,
+		rsvn.FirstName,
+		rsvn.LastName,
+		rsvn.Email,
+		rsvn.Phone,
+		rsvn.StartDate,
+		rsvn.EndDate,
+		models.StatusPending,
+		rsvn.TotalPrice,
+		rsvn.Adults,
+		rsvn.Children,
+		rsvn.Infants,
+		rsvn.ConfirmationRef,
+		sql.NullInt64{Int64: int64(rsvn.GroupID), Valid: rsvn.GroupID != 0},
+		time.Now(),
+		time.Now(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	// turn the guest's hold into the reservation's restriction, or insert a new restriction should there be no hold
+	stmt = `UPDATE room_restrictions SET reservation_id = ?, restriction_id = ?, start_date = ?, end_date = ?, hold_token = NULL, expires_at = NULL, updated_at = ? 
+	WHERE room_id = ? AND restriction_id = ? AND hold_token = ?;`
+
+	res, err := tx.ExecContext(ctx, stmt, reservationID, models.RestrictionReservation, rsvn.StartDate, rsvn.EndDate, time.Now(),
+		rsvn.RoomID, models.RestrictionHold, holdToken)
+	if err != nil {
+		return 0, err
+	}
+	converted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if converted == 0 {
+		stmt = `INSERT INTO room_restrictions (room_id, reservation_id, restriction_id, start_date, end_date, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?);`
+
+		_, err = tx.ExecContext(ctx, stmt,
+			rsvn.RoomID,
+			reservationID,
+			models.RestrictionReservation,
+			rsvn.StartDate,
+			rsvn.EndDate,
+			time.Now(),
+			time.Now(),
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return reservationID, nil
+}
+
+// PlaceHold holds one or more rooms for a guest's dates until the holds expire, replacing any holds the guest already has. Either
+// every room is held or, should any of them not be free for its dates, none are. All holds must share the same hold token
+func (m *sqlDBRepository) PlaceHold(ctx context.Context, holds ...models.RoomRestriction) error {
+	if len(holds) == 0 {
+		return nil
+	}
+
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "PlaceHold")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	// lock the room rows, always in the same order, so that any concurrent hold or booking of the same rooms must wait until this transaction completes
+	sorted := make([]models.RoomRestriction, len(holds))
+	copy(sorted, holds)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].RoomID < sorted[j].RoomID })
+
+	for _, hold := range sorted {
+		var roomID int
+		err = tx.QueryRowContext(ctx, `SELECT id FROM rooms WHERE id = ?`+tx.dialect.lockRows+`;`, hold.RoomID).Scan(&roomID)
+		if err != nil {
+			return interrupted(ctx, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM room_restrictions WHERE restriction_id = ? AND hold_token = ?;", models.RestrictionHold, holds[0].HoldToken)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	query := `SELECT COUNT(id) FROM room_restrictions WHERE room_id = ? AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?) 
+	AND (start_date BETWEEN ? AND ? OR ? BETWEEN start_date AND end_date)` + tx.dialect.lockCounted + `;`
+
+	stmt := `INSERT INTO room_restrictions (room_id, restriction_id, start_date, end_date, hold_token, expires_at, created_at, updated_at) 
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
+
+	for _, hold := range sorted {
+		var numRows int
+		err = tx.QueryRowContext(ctx, query, hold.RoomID, time.Now(), hold.StartDate, hold.EndDate, hold.StartDate).Scan(&numRows)
+		if err != nil {
+			return interrupted(ctx, err)
+		}
+		if numRows > 0 {
+			return &repository.RoomUnavailableError{RoomID: hold.RoomID, StartDate: hold.StartDate, EndDate: hold.EndDate}
+		}
+
+		_, err = tx.ExecContext(ctx, stmt,
+			hold.RoomID,
+			models.RestrictionHold,
+			hold.StartDate,
+			hold.EndDate,
+			hold.HoldToken,
+			hold.ExpiresAt,
+			time.Now(),
+			time.Now(),
+		)
+		if err != nil {
+			return interrupted(ctx, err)
+		}
+	}
+
+	return interrupted(ctx, tx.Commit())
+}
+
+// ReleaseHold removes a guest's hold, if any, freeing the room
+func (m *sqlDBRepository) ReleaseHold(ctx context.Context, holdToken string) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "ReleaseHold")
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "DELETE FROM room_restrictions WHERE restriction_id = ? AND hold_token = ?;", models.RestrictionHold, holdToken)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// ReleaseExpiredHolds removes all holds which expired before a given time, returning how many were removed
+func (m *sqlDBRepository) ReleaseExpiredHolds(ctx context.Context, now time.Time) (int64, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "ReleaseExpiredHolds")
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, "DELETE FROM room_restrictions WHERE restriction_id = ? AND expires_at <= ?;", models.RestrictionHold, now)
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+
+	return res.RowsAffected()
+}
+
+//SearchAvailabilityByDatesAndRoomID return true if availability exists, otherwise false. The guest's own hold on the room, identified by
+// holdToken, does not count against availability
+func (m *sqlDBRepository) SearchAvailabilityByDatesAndRoomID(ctx context.Context, start, end time.Time, roomID int, holdToken string) (bool, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "SearchAvailabilityByDatesAndRoomID")
+	defer cancel()
+
+	var numRows int
+
+	// original query (fails to account for SINGLE DAY reservations / owner blocks)
+	// query := `SELECT COUNT(id) FROM room_restrictions WHERE room_id = ? AND ? < end_date AND ? > start_date;`
+
+	// expired holds, not yet released, do not count against availability
+	query := `SELECT COUNT(id) FROM room_restrictions WHERE room_id = ? AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?) 
+	AND NOT (restriction_id = ? AND hold_token = ?) AND (start_date BETWEEN ? AND ? OR ? BETWEEN start_date AND end_date);`
+
+	row := m.DB.QueryRowContext(ctx, query, roomID, time.Now(), models.RestrictionHold, holdToken, start, end, start)
+	err := row.Scan(&numRows)
+	if err != nil {
+		return false, interrupted(ctx, err)
+	}
+
+	if numRows == 0 {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// SearchAvailabilityForAllRooms returns a slice of available rooms, if any, for given date range which can accommodate the party of guests,
+// the guest's own holds, identified by holdToken, not counting against availability
+func (m *sqlDBRepository) SearchAvailabilityForAllRooms(ctx context.Context, start, end time.Time, adults, children, infants int, holdToken string) ([]models.Room, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "SearchAvailabilityForAllRooms")
+	defer cancel()
+
+	var rooms_available []models.Room
+
+	// original query (fails to account for SINGLE DAY reservations / owner blocks)
+	// query := `SELECT r.id, r.room_name FROM rooms r WHERE r.id NOT IN
+	// (SELECT rr.room_id FROM room_restrictions rr WHERE ? < rr.end_date AND ? > rr.start_date);`
+
+	query := `SELECT r.id, r.room_name, r.nightly_rate, r.weekend_rate, r.included_guests, r.extra_guest_rate, r.max_adults, r.max_children, r.max_infants FROM rooms r WHERE r.id NOT IN 
+  (SELECT rr.room_id FROM room_restrictions rr WHERE rr.deleted_at IS NULL AND (rr.expires_at IS NULL OR rr.expires_at > ?) 
+  AND NOT (rr.restriction_id = ? AND rr.hold_token = ?) AND (rr.start_date BETWEEN ? AND ? OR ? BETWEEN rr.start_date AND rr.end_date));`
+
+	rows, err := m.DB.QueryContext(ctx, query, time.Now(), models.RestrictionHold, holdToken, start, end, start)
+	if err != nil {
+		return rooms_available, interrupted(ctx, err)
+	}
+
+	for rows.Next() {
+		var room models.Room
+		err := rows.Scan(
+			&room.ID,
+			&room.RoomName,
+			&room.NightlyRate,
+			&room.WeekendRate,
+			&room.IncludedGuests,
+			&room.ExtraGuestRate,
+			&room.MaxAdults,
+			&room.MaxChildren,
+			&room.MaxInfants,
+		)
+		if err != nil {
+			return rooms_available, interrupted(ctx, err)
+		}
+		// leave out any room too small for the party
+		if !room.Accommodates(adults, children, infants) {
+			continue
+		}
+		rooms_available = append(rooms_available, room)
+	}
+
+	if err = rows.Err(); err != nil {
+		return rooms_available, interrupted(ctx, err)
+	}
+
+	return rooms_available, nil
+
+}
+
+// GetRoomByID gets room details, especially room name, by id
+func (m *sqlDBRepository) GetRoomByID(ctx context.Context, id int) (models.Room, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetRoomByID")
+	defer cancel()
+
+	var room models.Room
+
+	query := `SELECT id, room_name, nightly_rate, weekend_rate, included_guests, extra_guest_rate, max_adults, max_children, max_infants, created_at, updated_at FROM rooms WHERE id = ?;`
+
+	row := m.DB.QueryRowContext(ctx, query, id)
+	err := row.Scan(
+		&room.ID,
+		&room.RoomName,
+		&room.NightlyRate,
+		&room.WeekendRate,
+		&room.IncludedGuests,
+		&room.ExtraGuestRate,
+		&room.MaxAdults,
+		&room.MaxChildren,
+		&room.MaxInfants,
+		&room.CreatedAt,
+		&room.UpdatedAt,
+	)
+
+	if err != nil {
+		return room, interrupted(ctx, err)
+	}
+
+	return room, nil
+}
+
+// GetAdministratorByID does exactly that
+func (m *sqlDBRepository) GetAdministratorByID(ctx context.Context, id int) (models.Administrator, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetAdministratorByID")
+	defer cancel()
+
+	query := `SELECT id, first_name, last_name, email, password, access_level, created_at, updated_at FROM administrators WHERE id = ?;`
+
+	row := m.DB.QueryRowContext(ctx, query, id)
+
+	var admin models.Administrator
+	err := row.Scan(
+		&admin.ID,
+		&admin.FirstName,
+		&admin.LastName,
+		&admin.Email,
+		&admin.Password,
+		&admin.AccessLevel,
+		&admin.CreatedAt,
+		&admin.UpdatedAt,
+	)
+
+	if err != nil {
+		return admin, interrupted(ctx, err)
+	}
+
+	return admin, nil
+}
+
+// UpdateAdministrator updates an administrator record in the database
+func (m *sqlDBRepository) UpdateAdministrator(ctx context.Context, admin models.Administrator) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "UpdateAdministrator")
+	defer cancel()
+
+	query := `UPDATE administrators SET first_name = ?, last_name = ?, email = ?, access_level = ?, updated_at = ? ;`
+
+	_, err := m.DB.ExecContext(ctx, query,
+		admin.FirstName,
+		admin.LastName,
+		admin.Email,
+		admin.AccessLevel,
+		time.Now(),
+	)
+
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// AuthenticateAdministrator does exactly that
+func (m *sqlDBRepository) AuthenticateAdministrator(ctx context.Context, email, password string) (int, string, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "AuthenticateAdministrator")
+	defer cancel()
+
+	// (id) holds ID of administrator after authentication, along with (hPassword)... their hashed password
+	var id int
+	var hPassword string
+
+	// initially get the id and stored hashed password of the administrator to authenticate via the email address
+	row := m.DB.QueryRowContext(ctx, "SELECT id, password FROM administrators WHERE email = ? ;", email)
+	err := row.Scan(&id, &hPassword)
+	if err != nil {
+		return id, "", interrupted(ctx, err)
+	}
+
+	// at this point, initial test to find an administrator record with given email is passed, continue by comparing hashed password = password
+	err = bcrypt.CompareHashAndPassword([]byte(hPassword), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return 0, "", errors.New("incorrect password given, does NOT match stored password")
+	} else if err != nil {
+		return 0, "", interrupted(ctx, err)
+	}
+
+	return id, hPassword, nil
+}
+
+// GetAdministratorByAPIToken returns the administrator owning an API token, given the token's hash, recording that the token has been used
+func (m *sqlDBRepository) GetAdministratorByAPIToken(ctx context.Context, tokenHash string) (models.Administrator, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetAdministratorByAPIToken")
+	defer cancel()
+
+	var admin models.Administrator
+
+	query := `SELECT a.id, a.first_name, a.last_name, a.email, a.access_level, a.created_at, a.updated_at, t.id 
+	FROM api_tokens t JOIN administrators a ON (t.administrator_id = a.id) WHERE t.token_hash = ?;`
+
+	var tokenID int
+	err := m.DB.QueryRowContext(ctx, query, tokenHash).Scan(
+		&admin.ID,
+		&admin.FirstName,
+		&admin.LastName,
+		&admin.Email,
+		&admin.AccessLevel,
+		&admin.CreatedAt,
+		&admin.UpdatedAt,
+		&tokenID,
+	)
+	if err != nil {
+		return admin, interrupted(ctx, err)
+	}
+
+	_, err = m.DB.ExecContext(ctx, `UPDATE api_tokens SET last_used_at = ? WHERE id = ?;`, time.Now(), tokenID)
+	if err != nil {
+		return admin, interrupted(ctx, err)
+	}
+
+	return admin, nil
+}
+
+// GetAPITokensByAdministratorID returns an administrator's API tokens, newest first, as a slice of models.APIToken
+func (m *sqlDBRepository) GetAPITokensByAdministratorID(ctx context.Context, adminID int) ([]models.APIToken, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetAPITokensByAdministratorID")
+	defer cancel()
+
+	var tokens []models.APIToken
+
+	query := `SELECT id, administrator_id, name, token_hash, last_used_at, created_at FROM api_tokens WHERE administrator_id = ? ORDER BY created_at DESC;`
+
+	rows, err := m.DB.QueryContext(ctx, query, adminID)
+	if err != nil {
+		return tokens, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var t models.APIToken
+		var lastUsedAt sql.NullTime
+		err := rows.Scan(
+			&t.ID,
+			&t.AdministratorID,
+			&t.Name,
+			&t.TokenHash,
+			&lastUsedAt,
+			&t.CreatedAt,
+		)
+		if err != nil {
+			return tokens, interrupted(ctx, err)
+		}
+		t.LastUsedAt = lastUsedAt.Time
+		tokens = append(tokens, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return tokens, interrupted(ctx, err)
+	}
+
+	return tokens, nil
+}
+
+// InsertAPIToken inserts a new API token for an administrator, only the token's hash is given
+func (m *sqlDBRepository) InsertAPIToken(ctx context.Context, token models.APIToken) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "InsertAPIToken")
+	defer cancel()
+
+	stmt := `INSERT INTO api_tokens (administrator_id, name, token_hash, created_at) VALUES (?, ?, ?, ?);`
+
+	_, err := m.DB.ExecContext(ctx, stmt, token.AdministratorID, token.Name, token.TokenHash, time.Now())
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// DeleteAPIToken revokes one of an administrator's API tokens by id
+func (m *sqlDBRepository) DeleteAPIToken(ctx context.Context, id, adminID int) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "DeleteAPIToken")
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM api_tokens WHERE id = ? AND administrator_id = ?;`, id, adminID)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// GetAllRooms returns all rooms as a slice of models.Room
+func (m *sqlDBRepository) GetAllRooms(ctx context.Context) ([]models.Room, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetAllRooms")
+	defer cancel()
+
+	var rooms []models.Room
+
+	query := `SELECT id, room_name, nightly_rate, weekend_rate, included_guests, extra_guest_rate, max_adults, max_children, max_infants, created_at, updated_at FROM rooms ORDER BY room_name ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return rooms, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.Room
+		err := rows.Scan(
+			&r.ID,
+			&r.RoomName,
+			&r.NightlyRate,
+			&r.WeekendRate,
+			&r.IncludedGuests,
+			&r.ExtraGuestRate,
+			&r.MaxAdults,
+			&r.MaxChildren,
+			&r.MaxInfants,
+			&r.CreatedAt,
+			&r.UpdatedAt,
+		)
+
+		if err != nil {
+			return rooms, interrupted(ctx, err)
+		}
+		rooms = append(rooms, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return rooms, interrupted(ctx, err)
+	}
+
+	return rooms, nil
+}
+
+// GetAllReservations returns all reservations as a slice of models.Reservation
+func (m *sqlDBRepository) GetAllReservations(ctx context.Context) ([]models.Reservation, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetAllReservations")
+	defer cancel()
+
+	var reservations []models.Reservation
+
+	query := `SELECT r.id, r.room_id, r.first_name, r.last_name, r.email, r.phone, r.start_date, r.end_date, r.status, r.total_price, r.adults, r.children, r.infants, r.confirmation_ref, r.created_at, r.updated_at, rm.id, rm.room_name FROM reservations r LEFT JOIN rooms rm ON (r.room_id = rm.id) WHERE r.deleted_at IS NULL ORDER BY r.start_date ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return reservations, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.Reservation
+		err := rows.Scan(
+			&r.ID,
+			&r.RoomID,
+			&r.FirstName,
+			&r.LastName,
+			&r.Email,
+			&r.Phone,
+			&r.StartDate,
+			&r.EndDate,
+			&r.Status,
+			&r.TotalPrice,
+			&r.Adults,
+			&r.Children,
+			&r.Infants,
+			&r.ConfirmationRef,
+			&r.CreatedAt,
+			&r.UpdatedAt,
+			&r.Room.ID,
+			&r.Room.RoomName,
+		)
+
+		if err != nil {
+			return reservations, interrupted(ctx, err)
+		}
+		reservations = append(reservations, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return reservations, interrupted(ctx, err)
+	}
+
+	return reservations, nil
+}
+
+// FilterReservations returns reservations selected by status, room & dates, ordered by arrival, as a slice of models.Reservation
+func (m *sqlDBRepository) FilterReservations(ctx context.Context, filter models.ReservationFilter) ([]models.Reservation, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "FilterReservations")
+	defer cancel()
+
+	var reservations []models.Reservation
+	err := m.eachFilteredReservation(ctx, filter, func(r models.Reservation) error {
+		reservations = append(reservations, r)
+		return nil
+	})
+	return reservations, interrupted(ctx, err)
+}
+
+// StreamReservations passes reservations selected by status, room & dates, ordered by arrival, to fn one at a time as they are read,
+// so that a long history is never held in memory at once. Should fn return an error, reading stops & that error is returned
+func (m *sqlDBRepository) StreamReservations(ctx context.Context, filter models.ReservationFilter, fn func(models.Reservation) error) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "StreamReservations")
+	defer cancel()
+
+	return m.eachFilteredReservation(ctx, filter, fn)
+}
+
+// eachFilteredReservation queries reservations selected by a filter, passing each to fn as it is scanned
+func (m *sqlDBRepository) eachFilteredReservation(ctx context.Context, filter models.ReservationFilter, fn func(models.Reservation) error) error {
+	query := `SELECT r.id, r.room_id, r.first_name, r.last_name, r.email, r.phone, r.start_date, r.end_date, r.status, r.total_price, r.adults, r.children, r.infants, r.confirmation_ref, COALESCE(r.group_id, 0), r.created_at, r.updated_at, rm.id, rm.room_name 
+	FROM reservations r LEFT JOIN rooms rm ON (r.room_id = rm.id) WHERE r.deleted_at IS NULL`
+
+	var args []interface{}
+	if filter.Status != "" {
+		query += ` AND r.status = ?`
+		args = append(args, filter.Status)
+	}
+	if filter.RoomID != 0 {
+		query += ` AND r.room_id = ?`
+		args = append(args, filter.RoomID)
+	}
+	if !filter.StartDate.IsZero() {
+		query += ` AND r.end_date >= ?`
+		args = append(args, filter.StartDate)
+	}
+	if !filter.EndDate.IsZero() {
+		query += ` AND r.start_date <= ?`
+		args = append(args, filter.EndDate)
+	}
+	query += ` ORDER BY r.start_date ASC, r.id ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.Reservation
+		err := rows.Scan(
+			&r.ID,
+			&r.RoomID,
+			&r.FirstName,
+			&r.LastName,
+			&r.Email,
+			&r.Phone,
+			&r.StartDate,
+			&r.EndDate,
+			&r.Status,
+			&r.TotalPrice,
+			&r.Adults,
+			&r.Children,
+			&r.Infants,
+			&r.ConfirmationRef,
+			&r.GroupID,
+			&r.CreatedAt,
+			&r.UpdatedAt,
+			&r.Room.ID,
+			&r.Room.RoomName,
+		)
+
+		if err != nil {
+			return err
+		}
+		if err = fn(r); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetNewReservations returns only new (pending) reservations as a slice of models.Reservation
+func (m *sqlDBRepository) GetNewReservations(ctx context.Context) ([]models.Reservation, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetNewReservations")
+	defer cancel()
+
+	var reservations []models.Reservation
+
+	query := `SELECT r.id, r.room_id, r.first_name, r.last_name, r.email, r.phone, r.start_date, r.end_date, r.status, r.total_price, r.adults, r.children, r.infants, r.confirmation_ref, r.created_at, r.updated_at, rm.id, rm.room_name FROM reservations r LEFT JOIN rooms rm ON (r.room_id = rm.id) 
+	WHERE r.status = ? AND r.deleted_at IS NULL ORDER BY r.start_date ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query, models.StatusPending)
+	if err != nil {
+		return reservations, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.Reservation
+		err := rows.Scan(
+			&r.ID,
+			&r.RoomID,
+			&r.FirstName,
+			&r.LastName,
+			&r.Email,
+			&r.Phone,
+			&r.StartDate,
+			&r.EndDate,
+			&r.Status,
+			&r.TotalPrice,
+			&r.Adults,
+			&r.Children,
+			&r.Infants,
+			&r.ConfirmationRef,
+			&r.CreatedAt,
+			&r.UpdatedAt,
+			&r.Room.ID,
+			&r.Room.RoomName,
+		)
+
+		if err != nil {
+			return reservations, interrupted(ctx, err)
+		}
+		reservations = append(reservations, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return reservations, interrupted(ctx, err)
+	}
+
+	return reservations, nil
+}
+
+// GetReservationsByStatus returns only reservations of a given status as a slice of models.Reservation
+func (m *sqlDBRepository) GetReservationsByStatus(ctx context.Context, status models.ReservationStatus) ([]models.Reservation, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetReservationsByStatus")
+	defer cancel()
+
+	var reservations []models.Reservation
+
+	query := `SELECT r.id, r.room_id, r.first_name, r.last_name, r.email, r.phone, r.start_date, r.end_date, r.status, r.total_price, r.adults, r.children, r.infants, r.confirmation_ref, r.created_at, r.updated_at, rm.id, rm.room_name FROM reservations r LEFT JOIN rooms rm ON (r.room_id = rm.id) 
+	WHERE r.status = ? AND r.deleted_at IS NULL ORDER BY r.start_date ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query, status)
+	if err != nil {
+		return reservations, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.Reservation
+		err := rows.Scan(
+			&r.ID,
+			&r.RoomID,
+			&r.FirstName,
+			&r.LastName,
+			&r.Email,
+			&r.Phone,
+			&r.StartDate,
+			&r.EndDate,
+			&r.Status,
+			&r.TotalPrice,
+			&r.Adults,
+			&r.Children,
+			&r.Infants,
+			&r.ConfirmationRef,
+			&r.CreatedAt,
+			&r.UpdatedAt,
+			&r.Room.ID,
+			&r.Room.RoomName,
+		)
+
+		if err != nil {
+			return reservations, interrupted(ctx, err)
+		}
+		reservations = append(reservations, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return reservations, interrupted(ctx, err)
+	}
+
+	return reservations, nil
+}
+
+// GetReservationByID returns only one reservation as a models.Reservation
+func (m *sqlDBRepository) GetReservationByID(ctx context.Context, id int) (models.Reservation, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetReservationByID")
+	defer cancel()
+
+	var r models.Reservation
+
+	query := `SELECT r.id, r.room_id, r.first_name, r.last_name, r.email, r.phone, r.start_date, r.end_date, r.status, r.total_price, r.adults, r.children, r.infants, r.confirmation_ref, r.created_at, r.updated_at, COALESCE(r.group_id, 0), rm.id, rm.room_name FROM reservations r LEFT JOIN rooms rm ON (r.room_id = rm.id) WHERE r.id = ? AND r.deleted_at IS NULL;`
+
+	row := m.DB.QueryRowContext(ctx, query, id)
+	err := row.Scan(
+		&r.ID,
+		&r.RoomID,
+		&r.FirstName,
+		&r.LastName,
+		&r.Email,
+		&r.Phone,
+		&r.StartDate,
+		&r.EndDate,
+		&r.Status,
+		&r.TotalPrice,
+		&r.Adults,
+		&r.Children,
+		&r.Infants,
+		&r.ConfirmationRef,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+		&r.GroupID,
+		&r.Room.ID,
+		&r.Room.RoomName,
+	)
+
+	if err != nil {
+		return r, interrupted(ctx, err)
+	}
+
+	return r, nil
+}
+
+// GetReservationByRef returns only one reservation, found by its confirmation reference, as a models.Reservation
+func (m *sqlDBRepository) GetReservationByRef(ctx context.Context, ref string) (models.Reservation, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetReservationByRef")
+	defer cancel()
+
+	var r models.Reservation
+
+	query := `SELECT r.id, r.room_id, r.first_name, r.last_name, r.email, r.phone, r.start_date, r.end_date, r.status, r.total_price, r.adults, r.children, r.infants, r.confirmation_ref, r.created_at, r.updated_at, COALESCE(r.group_id, 0), rm.id, rm.room_name FROM reservations r LEFT JOIN rooms rm ON (r.room_id = rm.id) WHERE r.confirmation_ref = ? AND r.deleted_at IS NULL;`
+
+	row := m.DB.QueryRowContext(ctx, query, ref)
+	err := row.Scan(
+		&r.ID,
+		&r.RoomID,
+		&r.FirstName,
+		&r.LastName,
+		&r.Email,
+		&r.Phone,
+		&r.StartDate,
+		&r.EndDate,
+		&r.Status,
+		&r.TotalPrice,
+		&r.Adults,
+		&r.Children,
+		&r.Infants,
+		&r.ConfirmationRef,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+		&r.GroupID,
+		&r.Room.ID,
+		&r.Room.RoomName,
+	)
+
+	if err != nil {
+		return r, interrupted(ctx, err)
+	}
+
+	return r, nil
+}
+
+// UpdateReservation updates a reservation record in the database
+func (m *sqlDBRepository) UpdateReservation(ctx context.Context, rsvn models.Reservation) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "UpdateReservation")
+	defer cancel()
+
+	query := `UPDATE reservations SET first_name = ?, last_name = ?, email = ?, phone = ?, updated_at = ? WHERE id = ?;`
+
+	_, err := m.DB.ExecContext(ctx, query,
+		rsvn.FirstName,
+		rsvn.LastName,
+		rsvn.Email,
+		rsvn.Phone,
+		time.Now(),
+		rsvn.ID,
+	)
+
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// DeleteReservation moves a reservation, together with its room restriction, to the trash by id, freeing the room
+func (m *sqlDBRepository) DeleteReservation(ctx context.Context, id int) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "DeleteReservation")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	_, err = tx.ExecContext(ctx, "UPDATE reservations SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL;", now, id)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE room_restrictions SET deleted_at = ? WHERE reservation_id = ? AND deleted_at IS NULL;", now, id)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return interrupted(ctx, tx.Commit())
+}
+
+// GetDeletedReservations returns all reservations in the trash, most recently deleted first, as a slice of models.Reservation
+func (m *sqlDBRepository) GetDeletedReservations(ctx context.Context) ([]models.Reservation, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetDeletedReservations")
+	defer cancel()
+
+	var reservations []models.Reservation
+
+	query := `SELECT r.id, r.room_id, r.first_name, r.last_name, r.email, r.phone, r.start_date, r.end_date, r.status, r.total_price, r.adults, r.children, r.infants, r.confirmation_ref, r.created_at, r.updated_at, r.deleted_at, rm.id, rm.room_name FROM reservations r LEFT JOIN rooms rm ON (r.room_id = rm.id) 
+	WHERE r.deleted_at IS NOT NULL ORDER BY r.deleted_at DESC;`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return reservations, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.Reservation
+		err := rows.Scan(
+			&r.ID,
+			&r.RoomID,
+			&r.FirstName,
+			&r.LastName,
+			&r.Email,
+			&r.Phone,
+			&r.StartDate,
+			&r.EndDate,
+			&r.Status,
+			&r.TotalPrice,
+			&r.Adults,
+			&r.Children,
+			&r.Infants,
+			&r.ConfirmationRef,
+			&r.CreatedAt,
+			&r.UpdatedAt,
+			&r.DeletedAt,
+			&r.Room.ID,
+			&r.Room.RoomName,
+		)
+
+		if err != nil {
+			return reservations, interrupted(ctx, err)
+		}
+		reservations = append(reservations, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return reservations, interrupted(ctx, err)
+	}
+
+	return reservations, nil
+}
+
+// RestoreReservation takes a reservation, together with the room restriction deleted with it, back out of the trash by id,
+// provided its room has not since been taken for any of its dates. A cancelled reservation's restriction was deleted on cancelling
+// it, so is left deleted, the room staying free
+func (m *sqlDBRepository) RestoreReservation(ctx context.Context, id int) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "RestoreReservation")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	var restrictions []models.RoomRestriction
+
+	rows, err := tx.QueryContext(ctx, `SELECT room_id, start_date, end_date FROM room_restrictions WHERE reservation_id = ? AND deleted_at IS NOT NULL 
+	AND reservation_id IN (SELECT id FROM reservations WHERE status <> ?);`, id, models.StatusCancelled)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	for rows.Next() {
+		var rs models.RoomRestriction
+		err := rows.Scan(&rs.RoomID, &rs.StartDate, &rs.EndDate)
+		if err != nil {
+			rows.Close()
+			return interrupted(ctx, err)
+		}
+		restrictions = append(restrictions, rs)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return interrupted(ctx, err)
+	}
+
+	// lock the room of each restriction being restored, as a booking does, so the room cannot be taken meanwhile
+	query := `SELECT COUNT(id) FROM room_restrictions WHERE room_id = ? AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?) 
+	AND (start_date BETWEEN ? AND ? OR ? BETWEEN start_date AND end_date)` + tx.dialect.lockCounted + `;`
+	for _, rs := range restrictions {
+		var roomID int
+		err = tx.QueryRowContext(ctx, `SELECT id FROM rooms WHERE id = ?`+tx.dialect.lockRows+`;`, rs.RoomID).Scan(&roomID)
+		if err != nil {
+			return interrupted(ctx, err)
+		}
+
+		var numRows int
+		err = tx.QueryRowContext(ctx, query, rs.RoomID, time.Now(), rs.StartDate, rs.EndDate, rs.StartDate).Scan(&numRows)
+		if err != nil {
+			return interrupted(ctx, err)
+		}
+		if numRows > 0 {
+			return &repository.RoomUnavailableError{RoomID: rs.RoomID, StartDate: rs.StartDate, EndDate: rs.EndDate}
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE room_restrictions SET deleted_at = NULL WHERE reservation_id = ? AND deleted_at IS NOT NULL 
+	AND reservation_id IN (SELECT id FROM reservations WHERE status <> ?);`, id, models.StatusCancelled)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE reservations SET deleted_at = NULL WHERE id = ?;", id)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return interrupted(ctx, tx.Commit())
+}
+
+// UpdateReservationStatus moves a reservation from one status to another, recording when it did so. Only changes allowed by the
+// lifecycle are made & only if the reservation is still in the expected status. A cancelled reservation has its room restriction
+// soft deleted within the same transaction, freeing the room, while the reservation itself is kept
+func (m *sqlDBRepository) UpdateReservationStatus(ctx context.Context, id int, from, to models.ReservationStatus) error {
+	err := lifecycle.Check(from, to)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "UpdateReservationStatus")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE reservations SET status = ?, updated_at = ? WHERE id = ? AND status = ?;", to, time.Now(), id, from)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	changed, err := res.RowsAffected()
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	if changed == 0 {
+		return &repository.StatusChangedError{ReservationID: id, Expected: from}
+	}
+
+	stmt := `INSERT INTO reservation_status_changes (reservation_id, from_status, to_status, created_at) VALUES (?, ?, ?, ?);`
+
+	_, err = tx.ExecContext(ctx, stmt, id, from, to, time.Now())
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	if to == models.StatusCancelled {
+		_, err = tx.ExecContext(ctx, "UPDATE room_restrictions SET deleted_at = ? WHERE reservation_id = ? AND deleted_at IS NULL;", time.Now(), id)
+		if err != nil {
+			return interrupted(ctx, err)
+		}
+	}
+
+	return interrupted(ctx, tx.Commit())
+}
+
+// GetReservationStatusChanges returns the status history of a reservation, oldest first, as a slice of models.StatusChange
+func (m *sqlDBRepository) GetReservationStatusChanges(ctx context.Context, id int) ([]models.StatusChange, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetReservationStatusChanges")
+	defer cancel()
+
+	var changes []models.StatusChange
+
+	query := `SELECT id, reservation_id, from_status, to_status, created_at FROM reservation_status_changes WHERE reservation_id = ? ORDER BY created_at ASC, id ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return changes, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.StatusChange
+		err := rows.Scan(
+			&c.ID,
+			&c.ReservationID,
+			&c.FromStatus,
+			&c.ToStatus,
+			&c.CreatedAt,
+		)
+
+		if err != nil {
+			return changes, interrupted(ctx, err)
+		}
+		changes = append(changes, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return changes, interrupted(ctx, err)
+	}
+
+	return changes, nil
+}
+
+// GetAllBookingGroups returns every booking group with at least one live reservation, most recent first, each with its reservations
+func (m *sqlDBRepository) GetAllBookingGroups(ctx context.Context) ([]models.BookingGroup, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetAllBookingGroups")
+	defer cancel()
+
+	var groups []models.BookingGroup
+
+	query := `SELECT id, confirmation_ref, first_name, last_name, email, phone, start_date, end_date, created_at, updated_at FROM booking_groups 
+	WHERE id IN (SELECT group_id FROM reservations WHERE deleted_at IS NULL) ORDER BY created_at DESC;`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return groups, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var g models.BookingGroup
+		err := rows.Scan(
+			&g.ID,
+			&g.ConfirmationRef,
+			&g.FirstName,
+			&g.LastName,
+			&g.Email,
+			&g.Phone,
+			&g.StartDate,
+			&g.EndDate,
+			&g.CreatedAt,
+			&g.UpdatedAt,
+		)
+
+		if err != nil {
+			return groups, interrupted(ctx, err)
+		}
+		groups = append(groups, g)
+	}
+
+	if err = rows.Err(); err != nil {
+		return groups, interrupted(ctx, err)
+	}
+
+	reservations, err := m.getGroupReservations(ctx, 0)
+	if err != nil {
+		return groups, interrupted(ctx, err)
+	}
+	for i := range groups {
+		groups[i].Reservations = reservations[groups[i].ID]
+	}
+
+	return groups, nil
+}
+
+// GetBookingGroupByID returns only one booking group, with its live reservations, as a models.BookingGroup
+func (m *sqlDBRepository) GetBookingGroupByID(ctx context.Context, id int) (models.BookingGroup, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetBookingGroupByID")
+	defer cancel()
+
+	var g models.BookingGroup
+
+	query := `SELECT id, confirmation_ref, first_name, last_name, email, phone, start_date, end_date, created_at, updated_at FROM booking_groups WHERE id = ?;`
+
+	row := m.DB.QueryRowContext(ctx, query, id)
+	err := row.Scan(
+		&g.ID,
+		&g.ConfirmationRef,
+		&g.FirstName,
+		&g.LastName,
+		&g.Email,
+		&g.Phone,
+		&g.StartDate,
+		&g.EndDate,
+		&g.CreatedAt,
+		&g.UpdatedAt,
+	)
+
+	if err != nil {
+		return g, interrupted(ctx, err)
+	}
+
+	reservations, err := m.getGroupReservations(ctx, id)
+	if err != nil {
+		return g, interrupted(ctx, err)
+	}
+	g.Reservations = reservations[id]
+
+	return g, nil
+}
+
+// getGroupReservations returns the live reservations of one booking group, or of every group when groupID is zero, keyed by group id
+// & in room order
+func (m *sqlDBRepository) getGroupReservations(ctx context.Context, groupID int) (map[int][]models.Reservation, error) {
+	reservations := make(map[int][]models.Reservation)
+
+	query := `SELECT r.id, r.room_id, r.first_name, r.last_name, r.email, r.phone, r.start_date, r.end_date, r.status, r.total_price, r.adults, r.children, r.infants, r.confirmation_ref, r.created_at, r.updated_at, r.group_id, rm.id, rm.room_name FROM reservations r LEFT JOIN rooms rm ON (r.room_id = rm.id) 
+	WHERE r.group_id IS NOT NULL AND (? = 0 OR r.group_id = ?) AND r.deleted_at IS NULL ORDER BY r.group_id, r.room_id;`
+
+	rows, err := m.DB.QueryContext(ctx, query, groupID, groupID)
+	if err != nil {
+		return reservations, err
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.Reservation
+		err := rows.Scan(
+			&r.ID,
+			&r.RoomID,
+			&r.FirstName,
+			&r.LastName,
+			&r.Email,
+			&r.Phone,
+			&r.StartDate,
+			&r.EndDate,
+			&r.Status,
+			&r.TotalPrice,
+			&r.Adults,
+			&r.Children,
+			&r.Infants,
+			&r.ConfirmationRef,
+			&r.CreatedAt,
+			&r.UpdatedAt,
+			&r.GroupID,
+			&r.Room.ID,
+			&r.Room.RoomName,
+		)
+
+		if err != nil {
+			return reservations, err
+		}
+		reservations[r.GroupID] = append(reservations[r.GroupID], r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return reservations, err
+	}
+
+	return reservations, nil
+}
+
+// UpdateBookingGroupStatus moves every live reservation of a booking group on to the same status, recording each change. Either all
+// of them are changed or, should any of them not be allowed to make the change, none are. Cancelled rooms are freed as for a single reservation
+func (m *sqlDBRepository) UpdateBookingGroupStatus(ctx context.Context, id int, to models.ReservationStatus) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "UpdateBookingGroupStatus")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	var reservations []models.Reservation
+
+	// lock the group's reservations so their statuses cannot be changed elsewhere meanwhile
+	rows, err := tx.QueryContext(ctx, "SELECT id, status FROM reservations WHERE group_id = ? AND deleted_at IS NULL"+tx.dialect.lockRows+";", id)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	for rows.Next() {
+		var r models.Reservation
+		err := rows.Scan(&r.ID, &r.Status)
+		if err != nil {
+			rows.Close()
+			return interrupted(ctx, err)
+		}
+		reservations = append(reservations, r)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return interrupted(ctx, err)
+	}
+	if len(reservations) == 0 {
+		return sql.ErrNoRows
+	}
+
+	for _, r := range reservations {
+		err = lifecycle.Check(r.Status, to)
+		if err != nil {
+			return interrupted(ctx, err)
+		}
+	}
+
+	stmt := `INSERT INTO reservation_status_changes (reservation_id, from_status, to_status, created_at) VALUES (?, ?, ?, ?);`
+
+	for _, r := range reservations {
+		_, err = tx.ExecContext(ctx, "UPDATE reservations SET status = ?, updated_at = ? WHERE id = ?;", to, time.Now(), r.ID)
+		if err != nil {
+			return interrupted(ctx, err)
+		}
+
+		_, err = tx.ExecContext(ctx, stmt, r.ID, r.Status, to, time.Now())
+		if err != nil {
+			return interrupted(ctx, err)
+		}
+
+		if to == models.StatusCancelled {
+			_, err = tx.ExecContext(ctx, "UPDATE room_restrictions SET deleted_at = ? WHERE reservation_id = ? AND deleted_at IS NULL;", time.Now(), r.ID)
+			if err != nil {
+				return interrupted(ctx, err)
+			}
+		}
+	}
+
+	return interrupted(ctx, tx.Commit())
+}
+
+// DeleteBookingGroup moves every reservation of a booking group, together with their room restrictions, to the trash, freeing the rooms
+func (m *sqlDBRepository) DeleteBookingGroup(ctx context.Context, id int) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "DeleteBookingGroup")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	stmt := `UPDATE room_restrictions SET deleted_at = ? WHERE deleted_at IS NULL 
+	AND reservation_id IN (SELECT id FROM reservations WHERE group_id = ? AND deleted_at IS NULL);`
+
+	_, err = tx.ExecContext(ctx, stmt, now, id)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE reservations SET deleted_at = ? WHERE group_id = ? AND deleted_at IS NULL;", now, id)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return interrupted(ctx, tx.Commit())
+}
+
+// InsertWaitlistEntry adds a guest to the waitlist for their dates & party
+func (m *sqlDBRepository) InsertWaitlistEntry(ctx context.Context, entry models.WaitlistEntry) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "InsertWaitlistEntry")
+	defer cancel()
+
+	stmt := `INSERT INTO waitlist (first_name, last_name, email, phone, start_date, end_date, adults, children, infants, created_at, updated_at) 
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	_, err := m.DB.ExecContext(ctx, stmt,
+		entry.FirstName,
+		entry.LastName,
+		entry.Email,
+		entry.Phone,
+		entry.StartDate,
+		entry.EndDate,
+		entry.Adults,
+		entry.Children,
+		entry.Infants,
+		time.Now(),
+		time.Now(),
+	)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// GetWaitlist returns the whole waitlist, in the order guests joined it, as a slice of models.WaitlistEntry
+func (m *sqlDBRepository) GetWaitlist(ctx context.Context) ([]models.WaitlistEntry, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetWaitlist")
+	defer cancel()
+
+	var entries []models.WaitlistEntry
+
+	query := `SELECT id, first_name, last_name, email, phone, start_date, end_date, adults, children, infants, notified_at, created_at, updated_at 
+	FROM waitlist ORDER BY created_at ASC, id ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return entries, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.WaitlistEntry
+		var notified sql.NullTime
+		err := rows.Scan(
+			&e.ID,
+			&e.FirstName,
+			&e.LastName,
+			&e.Email,
+			&e.Phone,
+			&e.StartDate,
+			&e.EndDate,
+			&e.Adults,
+			&e.Children,
+			&e.Infants,
+			&notified,
+			&e.CreatedAt,
+			&e.UpdatedAt,
+		)
+
+		if err != nil {
+			return entries, interrupted(ctx, err)
+		}
+		e.NotifiedAt = notified.Time
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return entries, interrupted(ctx, err)
+	}
+
+	return entries, nil
+}
+
+// GetWaitlistByDates returns guests not yet notified who are waiting for dates overlapping a date range, in the order they joined
+// the waitlist, as a slice of models.WaitlistEntry
+func (m *sqlDBRepository) GetWaitlistByDates(ctx context.Context, startDate, endDate time.Time) ([]models.WaitlistEntry, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetWaitlistByDates")
+	defer cancel()
+
+	var entries []models.WaitlistEntry
+
+	query := `SELECT id, first_name, last_name, email, phone, start_date, end_date, adults, children, infants, notified_at, created_at, updated_at 
+	FROM waitlist WHERE notified_at IS NULL AND (start_date BETWEEN ? AND ? OR ? BETWEEN start_date AND end_date) ORDER BY created_at ASC, id ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query, startDate, endDate, startDate)
+	if err != nil {
+		return entries, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.WaitlistEntry
+		var notified sql.NullTime
+		err := rows.Scan(
+			&e.ID,
+			&e.FirstName,
+			&e.LastName,
+			&e.Email,
+			&e.Phone,
+			&e.StartDate,
+			&e.EndDate,
+			&e.Adults,
+			&e.Children,
+			&e.Infants,
+			&notified,
+			&e.CreatedAt,
+			&e.UpdatedAt,
+		)
+
+		if err != nil {
+			return entries, interrupted(ctx, err)
+		}
+		e.NotifiedAt = notified.Time
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return entries, interrupted(ctx, err)
+	}
+
+	return entries, nil
+}
+
+// MarkWaitlistNotified records that a waitlisted guest has been told a room is free, so they are not told again
+func (m *sqlDBRepository) MarkWaitlistNotified(ctx context.Context, id int) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "MarkWaitlistNotified")
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "UPDATE waitlist SET notified_at = ?, updated_at = ? WHERE id = ?;", time.Now(), time.Now(), id)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// DeleteWaitlistEntry removes a guest from the waitlist by id
+func (m *sqlDBRepository) DeleteWaitlistEntry(ctx context.Context, id int) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "DeleteWaitlistEntry")
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "DELETE FROM waitlist WHERE id = ?;", id)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// GetRoomRestrictionsByDate returns all rooms restrictions by room id, for a date range, as a slice of models.RoomRestriction
+func (m *sqlDBRepository) GetRoomRestrictionsByDate(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.RoomRestriction, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetRoomRestrictionsByDate")
+	defer cancel()
+
+	var restrictions []models.RoomRestriction
+
+	// original query (fails to account for SINGLE DAY reservations / owner blocks)
+	// query := `SELECT id, room_id, COALESCE(reservation_id, 0), restriction_id, start_date, end_date, created_at, updated_at FROM room_restrictions
+	// WHERE room_id = ? AND ? < end_date AND ? > start_date;`
+
+	// query uses coalesce to substitute 0 for any null value of reservation_id which GO would not allow, likewise an empty status for owner blocks
+	query := `SELECT rr.id, rr.room_id, COALESCE(rr.reservation_id, 0), rr.restriction_id, rr.start_date, rr.end_date, rr.created_at, rr.updated_at, COALESCE(r.status, '')
+	FROM room_restrictions rr LEFT JOIN reservations r ON (rr.reservation_id = r.id)
+	WHERE rr.room_id = ? AND rr.restriction_id <> ? AND rr.deleted_at IS NULL AND (rr.start_date BETWEEN ? AND ? OR ? BETWEEN rr.start_date AND rr.end_date);`
+
+	// guests' holds during checkout are transient, so are left out
+	rows, err := m.DB.QueryContext(ctx, query, roomID, models.RestrictionHold, startDate, endDate, startDate)
+	if err != nil {
+		return restrictions, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.RoomRestriction
+		err := rows.Scan(
+			&r.ID,
+			&r.RoomID,
+			&r.ReservationID,
+			&r.RestrictionID,
+			&r.StartDate,
+			&r.EndDate,
+			&r.CreatedAt,
+			&r.UpdatedAt,
+			&r.Reservation.Status,
+		)
+
+		if err != nil {
+			return restrictions, interrupted(ctx, err)
+		}
+		restrictions = append(restrictions, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return restrictions, interrupted(ctx, err)
+	}
+
+	return restrictions, nil
+}
+
+// GetRoomRestrictionsForCalendar returns every restriction taking a room during a date range, including guests' unexpired holds, by the same
+// overlap rules as SearchAvailabilityByDatesAndRoomID, as a slice of models.RoomRestriction
+func (m *sqlDBRepository) GetRoomRestrictionsForCalendar(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.RoomRestriction, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetRoomRestrictionsForCalendar")
+	defer cancel()
+
+	var restrictions []models.RoomRestriction
+
+	query := `SELECT id, room_id, COALESCE(reservation_id, 0), restriction_id, start_date, end_date, created_at, updated_at FROM room_restrictions 
+	WHERE room_id = ? AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?) AND (start_date BETWEEN ? AND ? OR ? BETWEEN start_date AND end_date) 
+	ORDER BY start_date ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query, roomID, time.Now(), startDate, endDate, startDate)
+	if err != nil {
+		return restrictions, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.RoomRestriction
+		err := rows.Scan(
+			&r.ID,
+			&r.RoomID,
+			&r.ReservationID,
+			&r.RestrictionID,
+			&r.StartDate,
+			&r.EndDate,
+			&r.CreatedAt,
+			&r.UpdatedAt,
+		)
+
+		if err != nil {
+			return restrictions, interrupted(ctx, err)
+		}
+		restrictions = append(restrictions, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return restrictions, interrupted(ctx, err)
+	}
+
+	return restrictions, nil
+}
+
+// GetRoomRestrictionsForFeed returns a room's reservations, owner blocks & external bookings ending on or after a given date, each reservation with its
+// guest, ordered by start date as a slice of models.RoomRestriction
+func (m *sqlDBRepository) GetRoomRestrictionsForFeed(ctx context.Context, roomID int, from time.Time) ([]models.RoomRestriction, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetRoomRestrictionsForFeed")
+	defer cancel()
+
+	var restrictions []models.RoomRestriction
+
+	// query uses coalesce to substitute 0 or '' for the reservation's values, which GO would not allow to be null, for owner blocks
+	query := `SELECT rr.id, rr.room_id, COALESCE(rr.reservation_id, 0), rr.restriction_id, rr.start_date, rr.end_date, rr.created_at, rr.updated_at, 
+	COALESCE(r.first_name, ''), COALESCE(r.last_name, ''), COALESCE(r.status, ''), COALESCE(r.adults, 0), COALESCE(r.children, 0), COALESCE(r.infants, 0), COALESCE(r.confirmation_ref, '') 
+	FROM room_restrictions rr LEFT JOIN reservations r ON (rr.reservation_id = r.id) 
+	WHERE rr.room_id = ? AND rr.restriction_id <> ? AND rr.deleted_at IS NULL AND rr.end_date >= ? ORDER BY rr.start_date ASC;`
+
+	// guests' holds during checkout are transient, so are left out
+	rows, err := m.DB.QueryContext(ctx, query, roomID, models.RestrictionHold, from)
+	if err != nil {
+		return restrictions, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.RoomRestriction
+		err := rows.Scan(
+			&r.ID,
+			&r.RoomID,
+			&r.ReservationID,
+			&r.RestrictionID,
+			&r.StartDate,
+			&r.EndDate,
+			&r.CreatedAt,
+			&r.UpdatedAt,
+			&r.Reservation.FirstName,
+			&r.Reservation.LastName,
+			&r.Reservation.Status,
+			&r.Reservation.Adults,
+			&r.Reservation.Children,
+			&r.Reservation.Infants,
+			&r.Reservation.ConfirmationRef,
+		)
+
+		if err != nil {
+			return restrictions, interrupted(ctx, err)
+		}
+		restrictions = append(restrictions, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return restrictions, interrupted(ctx, err)
+	}
+
+	return restrictions, nil
+}
+
+// InsertRoomBlock inserts an owner block restriction for a given room
+func (m *sqlDBRepository) InsertRoomBlock(ctx context.Context, roomID int, startDate, endDate time.Time) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "InsertRoomBlock")
+	defer cancel()
+
+	query := `INSERT INTO room_restrictions (room_id, restriction_id, start_date, end_date, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?);`
+
+	_, err := m.DB.ExecContext(ctx, query, roomID, models.RestrictionOwnerBlock, startDate, endDate, time.Now(), time.Now())
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// CreateRoomBlock inserts an owner block restriction for a given room, only if the room is still free for those dates, returning the new
+// block's id. A *repository.RoomUnavailableError is returned if the room has already been taken
+func (m *sqlDBRepository) CreateRoomBlock(ctx context.Context, roomID int, startDate, endDate time.Time) (int64, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "CreateRoomBlock")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	// lock the room row so that any concurrent booking of the same room must wait until this transaction completes
+	var id int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM rooms WHERE id = ?`+tx.dialect.lockRows+`;`, roomID).Scan(&id)
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+
+	// same overlap rules as SearchAvailabilityByDatesAndRoomID
+	var numRows int
+	query := `SELECT COUNT(id) FROM room_restrictions WHERE room_id = ? AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?) 
+	AND (start_date BETWEEN ? AND ? OR ? BETWEEN start_date AND end_date)` + tx.dialect.lockCounted + `;`
+
+	err = tx.QueryRowContext(ctx, query, roomID, time.Now(), startDate, endDate, startDate).Scan(&numRows)
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+	if numRows > 0 {
+		return 0, &repository.RoomUnavailableError{RoomID: roomID, StartDate: startDate, EndDate: endDate}
+	}
+
+	stmt := `INSERT INTO room_restrictions (room_id, restriction_id, start_date, end_date, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?);`
+
+	blockID, err := tx.insertID(ctx, stmt, roomID, models.RestrictionOwnerBlock, startDate, endDate, time.Now(), time.Now())
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+
+	return blockID, interrupted(ctx, tx.Commit())
+}
+
+// GetRoomBlockByID returns an owner block restriction, not in the trash, by id
+func (m *sqlDBRepository) GetRoomBlockByID(ctx context.Context, id int) (models.RoomRestriction, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetRoomBlockByID")
+	defer cancel()
+
+	var block models.RoomRestriction
+
+	query := `SELECT id, room_id, restriction_id, start_date, end_date, created_at, updated_at FROM room_restrictions 
+	WHERE id = ? AND restriction_id = ? AND deleted_at IS NULL;`
+
+	err := m.DB.QueryRowContext(ctx, query, id, models.RestrictionOwnerBlock).Scan(
+		&block.ID,
+		&block.RoomID,
+		&block.RestrictionID,
+		&block.StartDate,
+		&block.EndDate,
+		&block.CreatedAt,
+		&block.UpdatedAt,
+	)
+	if err != nil {
+		return block, interrupted(ctx, err)
+	}
+
+	return block, nil
+}
+
+// DeleteRoomBlock moves an owner block restriction for a room to the trash by id. sql.ErrNoRows is returned if there is no such block,
+// or it is already in the trash
+func (m *sqlDBRepository) DeleteRoomBlock(ctx context.Context, id int) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "DeleteRoomBlock")
+	defer cancel()
+
+	query := `UPDATE room_restrictions SET deleted_at = ? WHERE id = ? AND restriction_id = ? AND deleted_at IS NULL;`
+
+	res, err := m.DB.ExecContext(ctx, query, time.Now(), id, models.RestrictionOwnerBlock)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetDeletedRoomBlocks returns all owner blocks in the trash, most recently deleted first, as a slice of models.RoomRestriction
+func (m *sqlDBRepository) GetDeletedRoomBlocks(ctx context.Context) ([]models.RoomRestriction, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetDeletedRoomBlocks")
+	defer cancel()
+
+	var blocks []models.RoomRestriction
+
+	query := `SELECT rr.id, rr.room_id, rr.restriction_id, rr.start_date, rr.end_date, rr.created_at, rr.updated_at, rr.deleted_at, rm.id, rm.room_name 
+	FROM room_restrictions rr LEFT JOIN rooms rm ON (rr.room_id = rm.id) WHERE rr.restriction_id = ? AND rr.deleted_at IS NOT NULL ORDER BY rr.deleted_at DESC;`
+
+	rows, err := m.DB.QueryContext(ctx, query, models.RestrictionOwnerBlock)
+	if err != nil {
+		return blocks, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var b models.RoomRestriction
+		err := rows.Scan(
+			&b.ID,
+			&b.RoomID,
+			&b.RestrictionID,
+			&b.StartDate,
+			&b.EndDate,
+			&b.CreatedAt,
+			&b.UpdatedAt,
+			&b.DeletedAt,
+			&b.Room.ID,
+			&b.Room.RoomName,
+		)
+
+		if err != nil {
+			return blocks, interrupted(ctx, err)
+		}
+		blocks = append(blocks, b)
+	}
+
+	if err = rows.Err(); err != nil {
+		return blocks, interrupted(ctx, err)
+	}
+
+	return blocks, nil
+}
+
+// RestoreRoomBlock takes an owner block restriction back out of the trash by id, provided its room has not since been taken for any of its dates
+func (m *sqlDBRepository) RestoreRoomBlock(ctx context.Context, id int) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "RestoreRoomBlock")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	var block models.RoomRestriction
+	err = tx.QueryRowContext(ctx, "SELECT room_id, start_date, end_date FROM room_restrictions WHERE id = ? AND deleted_at IS NOT NULL;", id).Scan(&block.RoomID, &block.StartDate, &block.EndDate)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	// lock the block's room, as a booking does, so the room cannot be taken meanwhile
+	var roomID int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM rooms WHERE id = ?`+tx.dialect.lockRows+`;`, block.RoomID).Scan(&roomID)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	var numRows int
+	query := `SELECT COUNT(id) FROM room_restrictions WHERE room_id = ? AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?) 
+	AND (start_date BETWEEN ? AND ? OR ? BETWEEN start_date AND end_date)` + tx.dialect.lockCounted + `;`
+	err = tx.QueryRowContext(ctx, query, block.RoomID, time.Now(), block.StartDate, block.EndDate, block.StartDate).Scan(&numRows)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	if numRows > 0 {
+		return &repository.RoomUnavailableError{RoomID: block.RoomID, StartDate: block.StartDate, EndDate: block.EndDate}
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE room_restrictions SET deleted_at = NULL WHERE id = ?;", id)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return interrupted(ctx, tx.Commit())
+}
+
+// PurgeDeleted permanently removes reservations, together with their status history & restrictions, and owner blocks which
+// were moved to the trash before a given time, returning how many reservations & owner blocks were removed
+func (m *sqlDBRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "PurgeDeleted")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM reservation_status_changes WHERE reservation_id IN 
+	(SELECT id FROM reservations WHERE deleted_at IS NOT NULL AND deleted_at < ?);`, before)
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM room_restrictions WHERE reservation_id IN 
+	(SELECT id FROM reservations WHERE deleted_at IS NOT NULL AND deleted_at < ?);`, before)
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM reservations WHERE deleted_at IS NOT NULL AND deleted_at < ?;", before)
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+	reservations, err := res.RowsAffected()
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+
+	res, err = tx.ExecContext(ctx, "DELETE FROM room_restrictions WHERE reservation_id IS NULL AND deleted_at IS NOT NULL AND deleted_at < ?;", before)
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+	blocks, err := res.RowsAffected()
+	if err != nil {
+		return 0, interrupted(ctx, err)
+	}
+
+	return reservations + blocks, interrupted(ctx, tx.Commit())
+}
+
+// UpdateRoomRates updates the nightly, weekend & extra guest rates of a room
+func (m *sqlDBRepository) UpdateRoomRates(ctx context.Context, room models.Room) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "UpdateRoomRates")
+	defer cancel()
+
+	query := `UPDATE rooms SET nightly_rate = ?, weekend_rate = ?, included_guests = ?, extra_guest_rate = ?, updated_at = ? WHERE id = ?;`
+
+	_, err := m.DB.ExecContext(ctx, query,
+		room.NightlyRate,
+		room.WeekendRate,
+		room.IncludedGuests,
+		room.ExtraGuestRate,
+		time.Now(),
+		room.ID,
+	)
+
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// UpdateRoomOccupancy updates the maximum numbers of adults, children & infants a room can accommodate
+func (m *sqlDBRepository) UpdateRoomOccupancy(ctx context.Context, room models.Room) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "UpdateRoomOccupancy")
+	defer cancel()
+
+	query := `UPDATE rooms SET max_adults = ?, max_children = ?, max_infants = ?, updated_at = ? WHERE id = ?;`
+
+	_, err := m.DB.ExecContext(ctx, query,
+		room.MaxAdults,
+		room.MaxChildren,
+		room.MaxInfants,
+		time.Now(),
+		room.ID,
+	)
+
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// GetSeasonalRatesByRoomID returns all seasonal rates for a room which overlap a date range, as a slice of models.SeasonalRate
+func (m *sqlDBRepository) GetSeasonalRatesByRoomID(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.SeasonalRate, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetSeasonalRatesByRoomID")
+	defer cancel()
+
+	var seasons []models.SeasonalRate
+
+	query := `SELECT id, room_id, season_name, start_date, end_date, nightly_rate, weekend_rate, created_at, updated_at FROM seasonal_rates 
+	WHERE room_id = ? AND start_date <= ? AND end_date >= ? ORDER BY start_date ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query, roomID, endDate, startDate)
+	if err != nil {
+		return seasons, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.SeasonalRate
+		err := rows.Scan(
+			&s.ID,
+			&s.RoomID,
+			&s.SeasonName,
+			&s.StartDate,
+			&s.EndDate,
+			&s.NightlyRate,
+			&s.WeekendRate,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+		)
+
+		if err != nil {
+			return seasons, interrupted(ctx, err)
+		}
+		seasons = append(seasons, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return seasons, interrupted(ctx, err)
+	}
+
+	return seasons, nil
+}
+
+// GetAllSeasonalRates returns all seasonal rates, for all rooms, as a slice of models.SeasonalRate
+func (m *sqlDBRepository) GetAllSeasonalRates(ctx context.Context) ([]models.SeasonalRate, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetAllSeasonalRates")
+	defer cancel()
+
+	var seasons []models.SeasonalRate
+
+	query := `SELECT s.id, s.room_id, s.season_name, s.start_date, s.end_date, s.nightly_rate, s.weekend_rate, s.created_at, s.updated_at, 
+	rm.id, rm.room_name FROM seasonal_rates s LEFT JOIN rooms rm ON (s.room_id = rm.id) ORDER BY s.start_date ASC, rm.room_name ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return seasons, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.SeasonalRate
+		err := rows.Scan(
+			&s.ID,
+			&s.RoomID,
+			&s.SeasonName,
+			&s.StartDate,
+			&s.EndDate,
+			&s.NightlyRate,
+			&s.WeekendRate,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+			&s.Room.ID,
+			&s.Room.RoomName,
+		)
+
+		if err != nil {
+			return seasons, interrupted(ctx, err)
+		}
+		seasons = append(seasons, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return seasons, interrupted(ctx, err)
+	}
+
+	return seasons, nil
+}
+
+// InsertSeasonalRate inserts a seasonal rate for a given room
+func (m *sqlDBRepository) InsertSeasonalRate(ctx context.Context, season models.SeasonalRate) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "InsertSeasonalRate")
+	defer cancel()
+
+	stmt := `INSERT INTO seasonal_rates (room_id, season_name, start_date, end_date, nightly_rate, weekend_rate, created_at, updated_at) 
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
+
+	_, err := m.DB.ExecContext(ctx, stmt,
+		season.RoomID,
+		season.SeasonName,
+		season.StartDate,
+		season.EndDate,
+		season.NightlyRate,
+		season.WeekendRate,
+		time.Now(),
+		time.Now(),
+	)
+
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// DeleteSeasonalRate deletes a seasonal rate by id
+func (m *sqlDBRepository) DeleteSeasonalRate(ctx context.Context, id int) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "DeleteSeasonalRate")
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "DELETE FROM seasonal_rates WHERE id = ?;", id)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// GetStayRulesByRoomID returns all stay rules for a room which overlap a date range, as a slice of models.StayRule
+func (m *sqlDBRepository) GetStayRulesByRoomID(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.StayRule, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetStayRulesByRoomID")
+	defer cancel()
+
+	var rules []models.StayRule
+
+	query := `SELECT id, room_id, rule_name, start_date, end_date, min_nights, max_nights, created_at, updated_at FROM stay_rules 
+	WHERE room_id = ? AND start_date <= ? AND end_date >= ? ORDER BY start_date ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query, roomID, endDate, startDate)
+	if err != nil {
+		return rules, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var sr models.StayRule
+		err := rows.Scan(
+			&sr.ID,
+			&sr.RoomID,
+			&sr.RuleName,
+			&sr.StartDate,
+			&sr.EndDate,
+			&sr.MinNights,
+			&sr.MaxNights,
+			&sr.CreatedAt,
+			&sr.UpdatedAt,
+		)
+
+		if err != nil {
+			return rules, interrupted(ctx, err)
+		}
+		rules = append(rules, sr)
+	}
+
+	if err = rows.Err(); err != nil {
+		return rules, interrupted(ctx, err)
+	}
+
+	return rules, nil
+}
+
+// GetAllStayRules returns all stay rules, for all rooms, as a slice of models.StayRule
+func (m *sqlDBRepository) GetAllStayRules(ctx context.Context) ([]models.StayRule, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetAllStayRules")
+	defer cancel()
+
+	var rules []models.StayRule
+
+	query := `SELECT sr.id, sr.room_id, sr.rule_name, sr.start_date, sr.end_date, sr.min_nights, sr.max_nights, sr.created_at, sr.updated_at, 
+	rm.id, rm.room_name FROM stay_rules sr LEFT JOIN rooms rm ON (sr.room_id = rm.id) ORDER BY sr.start_date ASC, rm.room_name ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return rules, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var sr models.StayRule
+		err := rows.Scan(
+			&sr.ID,
+			&sr.RoomID,
+			&sr.RuleName,
+			&sr.StartDate,
+			&sr.EndDate,
+			&sr.MinNights,
+			&sr.MaxNights,
+			&sr.CreatedAt,
+			&sr.UpdatedAt,
+			&sr.Room.ID,
+			&sr.Room.RoomName,
+		)
+
+		if err != nil {
+			return rules, interrupted(ctx, err)
+		}
+		rules = append(rules, sr)
+	}
+
+	if err = rows.Err(); err != nil {
+		return rules, interrupted(ctx, err)
+	}
+
+	return rules, nil
+}
+
+// InsertStayRule inserts a stay rule for a given room
+func (m *sqlDBRepository) InsertStayRule(ctx context.Context, rule models.StayRule) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "InsertStayRule")
+	defer cancel()
+
+	stmt := `INSERT INTO stay_rules (room_id, rule_name, start_date, end_date, min_nights, max_nights, created_at, updated_at) 
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
+
+	_, err := m.DB.ExecContext(ctx, stmt,
+		rule.RoomID,
+		rule.RuleName,
+		rule.StartDate,
+		rule.EndDate,
+		rule.MinNights,
+		rule.MaxNights,
+		time.Now(),
+		time.Now(),
+	)
+
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// DeleteStayRule deletes a stay rule by id
+func (m *sqlDBRepository) DeleteStayRule(ctx context.Context, id int) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "DeleteStayRule")
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "DELETE FROM stay_rules WHERE id = ?;", id)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// GetAllICalFeeds returns every iCal feed, by room, as a slice of models.ICalFeed
+func (m *sqlDBRepository) GetAllICalFeeds(ctx context.Context) ([]models.ICalFeed, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetAllICalFeeds")
+	defer cancel()
+
+	var feeds []models.ICalFeed
+
+	query := `SELECT f.id, f.room_id, f.token, f.private, f.created_at, rm.id, rm.room_name 
+	FROM ical_feeds f LEFT JOIN rooms rm ON (f.room_id = rm.id) ORDER BY rm.room_name ASC, f.private ASC, f.created_at ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return feeds, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var f models.ICalFeed
+		err := rows.Scan(
+			&f.ID,
+			&f.RoomID,
+			&f.Token,
+			&f.Private,
+			&f.CreatedAt,
+			&f.Room.ID,
+			&f.Room.RoomName,
+		)
+
+		if err != nil {
+			return feeds, interrupted(ctx, err)
+		}
+		feeds = append(feeds, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return feeds, interrupted(ctx, err)
+	}
+
+	return feeds, nil
+}
+
+// GetICalFeedByToken returns the iCal feed with a given secret token, together with its room
+func (m *sqlDBRepository) GetICalFeedByToken(ctx context.Context, token string) (models.ICalFeed, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetICalFeedByToken")
+	defer cancel()
+
+	var f models.ICalFeed
+
+	query := `SELECT f.id, f.room_id, f.token, f.private, f.created_at, rm.id, rm.room_name 
+	FROM ical_feeds f JOIN rooms rm ON (f.room_id = rm.id) WHERE f.token = ?;`
+
+	err := m.DB.QueryRowContext(ctx, query, token).Scan(
+		&f.ID,
+		&f.RoomID,
+		&f.Token,
+		&f.Private,
+		&f.CreatedAt,
+		&f.Room.ID,
+		&f.Room.RoomName,
+	)
+	if err != nil {
+		return f, interrupted(ctx, err)
+	}
+
+	return f, nil
+}
+
+// InsertICalFeed inserts a new iCal feed for a given room
+func (m *sqlDBRepository) InsertICalFeed(ctx context.Context, feed models.ICalFeed) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "InsertICalFeed")
+	defer cancel()
+
+	stmt := `INSERT INTO ical_feeds (room_id, token, private, created_at) VALUES (?, ?, ?, ?);`
+
+	_, err := m.DB.ExecContext(ctx, stmt, feed.RoomID, feed.Token, feed.Private, time.Now())
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// DeleteICalFeed revokes an iCal feed by id, so that its URL no longer works
+func (m *sqlDBRepository) DeleteICalFeed(ctx context.Context, id int) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "DeleteICalFeed")
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "DELETE FROM ical_feeds WHERE id = ?;", id)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// GetAllICalImports returns every iCal import, by room, as a slice of models.ICalImport
+func (m *sqlDBRepository) GetAllICalImports(ctx context.Context) ([]models.ICalImport, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetAllICalImports")
+	defer cancel()
+
+	var imports []models.ICalImport
+
+	query := `SELECT i.id, i.room_id, i.name, i.url, i.last_synced_at, i.last_error, i.created_at, i.updated_at, rm.id, rm.room_name 
+	FROM ical_imports i LEFT JOIN rooms rm ON (i.room_id = rm.id) ORDER BY rm.room_name ASC, i.name ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return imports, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var i models.ICalImport
+		// last_synced_at is null for calendars not yet imported, & coalescing it with a zero time would give a string, not a time
+		var lastSynced sql.NullTime
+		err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Name,
+			&i.URL,
+			&lastSynced,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Room.ID,
+			&i.Room.RoomName,
+		)
+
+		if err != nil {
+			return imports, interrupted(ctx, err)
+		}
+		i.LastSyncedAt = lastSynced.Time
+		imports = append(imports, i)
+	}
+
+	if err = rows.Err(); err != nil {
+		return imports, interrupted(ctx, err)
+	}
+
+	return imports, nil
+}
+
+// InsertICalImport inserts a new iCal import of another site's calendar for a given room
+func (m *sqlDBRepository) InsertICalImport(ctx context.Context, imp models.ICalImport) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "InsertICalImport")
+	defer cancel()
+
+	stmt := `INSERT INTO ical_imports (room_id, name, url, last_error, created_at, updated_at) VALUES (?, ?, ?, '', ?, ?);`
+
+	_, err := m.DB.ExecContext(ctx, stmt, imp.RoomID, imp.Name, imp.URL, time.Now(), time.Now())
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// DeleteICalImport removes an iCal import by id, together with the external bookings copied from its calendar
+func (m *sqlDBRepository) DeleteICalImport(ctx context.Context, id int) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "DeleteICalImport")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM room_restrictions WHERE import_id = ? AND restriction_id = ?;", id, models.RestrictionExternalBooking)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM ical_imports WHERE id = ?;", id)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return interrupted(ctx, tx.Commit())
+}
+
+// UpdateICalImportSync records when an iCal import last ran & why it failed, if it did
+func (m *sqlDBRepository) UpdateICalImportSync(ctx context.Context, id int, syncedAt time.Time, syncErr string) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "UpdateICalImportSync")
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, "UPDATE ical_imports SET last_synced_at = ?, last_error = ?, updated_at = ? WHERE id = ?;", syncedAt, syncErr, time.Now(), id)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// GetExternalBookings returns the external bookings copied from an iCal import's calendar as a slice of models.RoomRestriction
+func (m *sqlDBRepository) GetExternalBookings(ctx context.Context, importID int) ([]models.RoomRestriction, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetExternalBookings")
+	defer cancel()
+
+	var bookings []models.RoomRestriction
+
+	query := `SELECT id, room_id, restriction_id, start_date, end_date, import_id, external_uid, created_at, updated_at 
+	FROM room_restrictions WHERE import_id = ? AND restriction_id = ? ORDER BY start_date ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query, importID, models.RestrictionExternalBooking)
+	if err != nil {
+		return bookings, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var b models.RoomRestriction
+		err := rows.Scan(
+			&b.ID,
+			&b.RoomID,
+			&b.RestrictionID,
+			&b.StartDate,
+			&b.EndDate,
+			&b.ImportID,
+			&b.ExternalUID,
+			&b.CreatedAt,
+			&b.UpdatedAt,
+		)
+
+		if err != nil {
+			return bookings, interrupted(ctx, err)
+		}
+		bookings = append(bookings, b)
+	}
+
+	if err = rows.Err(); err != nil {
+		return bookings, interrupted(ctx, err)
+	}
+
+	return bookings, nil
+}
+
+// SyncExternalBookings brings an iCal import's external bookings up to date with its calendar within one transaction, inserting
+// bookings new to the calendar, moving those whose dates have changed & deleting, by id, those no longer in it
+func (m *sqlDBRepository) SyncExternalBookings(ctx context.Context, importID int, add, update []models.RoomRestriction, remove []int) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "SyncExternalBookings")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	// every change is limited to the import's own external bookings, so that no other restriction can be altered
+	for _, id := range remove {
+		_, err = tx.ExecContext(ctx, "DELETE FROM room_restrictions WHERE id = ? AND import_id = ? AND restriction_id = ?;", id, importID, models.RestrictionExternalBooking)
+		if err != nil {
+			return interrupted(ctx, err)
+		}
+	}
+
+	for _, b := range update {
+		_, err = tx.ExecContext(ctx, "UPDATE room_restrictions SET start_date = ?, end_date = ?, updated_at = ? WHERE id = ? AND import_id = ? AND restriction_id = ?;",
+			b.StartDate, b.EndDate, time.Now(), b.ID, importID, models.RestrictionExternalBooking)
+		if err != nil {
+			return interrupted(ctx, err)
+		}
+	}
+
+	stmt := `INSERT INTO room_restrictions (room_id, restriction_id, start_date, end_date, import_id, external_uid, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
+
+	for _, b := range add {
+		_, err = tx.ExecContext(ctx, stmt, b.RoomID, models.RestrictionExternalBooking, b.StartDate, b.EndDate, importID, b.ExternalUID, time.Now(), time.Now())
+		if err != nil {
+			return interrupted(ctx, err)
+		}
+	}
+
+	return interrupted(ctx, tx.Commit())
+}
+
+// GetBookingConflicts returns every external booking, ending on or after a given date, which overlaps one of our own reservations
+// for the same room, with its import & the reservation, ordered by start date as a slice of models.BookingConflict
+func (m *sqlDBRepository) GetBookingConflicts(ctx context.Context, from time.Time) ([]models.BookingConflict, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetBookingConflicts")
+	defer cancel()
+
+	var conflicts []models.BookingConflict
+
+	// an external booking ends on its last night, whereas a reservation ends on the day of departure, so a guest may arrive on the
+	// day another leaves without conflict
+	query := `SELECT e.id, e.room_id, e.start_date, e.end_date, e.external_uid, i.id, i.name, rm.id, rm.room_name, 
+	r.id, r.first_name, r.last_name, r.start_date, r.end_date, r.status, r.confirmation_ref 
+	FROM room_restrictions e 
+	JOIN ical_imports i ON (e.import_id = i.id) 
+	JOIN rooms rm ON (e.room_id = rm.id) 
+	JOIN room_restrictions rr ON (rr.room_id = e.room_id AND rr.restriction_id = ? AND rr.deleted_at IS NULL) 
+	JOIN reservations r ON (rr.reservation_id = r.id AND r.deleted_at IS NULL) 
+	WHERE e.restriction_id = ? AND e.end_date >= ? AND rr.start_date <= e.end_date AND e.start_date < rr.end_date 
+	ORDER BY e.start_date ASC, rm.room_name ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query, models.RestrictionReservation, models.RestrictionExternalBooking, from)
+	if err != nil {
+		return conflicts, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.BookingConflict
+		err := rows.Scan(
+			&c.Booking.ID,
+			&c.Booking.RoomID,
+			&c.Booking.StartDate,
+			&c.Booking.EndDate,
+			&c.Booking.ExternalUID,
+			&c.Import.ID,
+			&c.Import.Name,
+			&c.Booking.Room.ID,
+			&c.Booking.Room.RoomName,
+			&c.Reservation.ID,
+			&c.Reservation.FirstName,
+			&c.Reservation.LastName,
+			&c.Reservation.StartDate,
+			&c.Reservation.EndDate,
+			&c.Reservation.Status,
+			&c.Reservation.ConfirmationRef,
+		)
+
+		if err != nil {
+			return conflicts, interrupted(ctx, err)
+		}
+		c.Booking.ImportID = c.Import.ID
+		c.Import.RoomID = c.Booking.RoomID
+		c.Import.Room = c.Booking.Room
+		conflicts = append(conflicts, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return conflicts, interrupted(ctx, err)
+	}
+
+	return conflicts, nil
+}
+
+// GetAllWebhooks returns every webhook, oldest first, as a slice of models.Webhook
+func (m *sqlDBRepository) GetAllWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetAllWebhooks")
+	defer cancel()
+
+	var hooks []models.Webhook
+
+	query := `SELECT id, url, secret, events, created_at, updated_at FROM webhooks ORDER BY created_at ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return hooks, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var h models.Webhook
+		var events string
+		err := rows.Scan(
+			&h.ID,
+			&h.URL,
+			&h.Secret,
+			&events,
+			&h.CreatedAt,
+			&h.UpdatedAt,
+		)
+
+		if err != nil {
+			return hooks, interrupted(ctx, err)
+		}
+		// events are held as a comma separated list, empty for all events
+		if events != "" {
+			h.Events = strings.Split(events, ",")
+		}
+		hooks = append(hooks, h)
+	}
+
+	if err = rows.Err(); err != nil {
+		return hooks, interrupted(ctx, err)
+	}
+
+	return hooks, nil
+}
+
+// InsertWebhook inserts a new webhook
+func (m *sqlDBRepository) InsertWebhook(ctx context.Context, hook models.Webhook) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "InsertWebhook")
+	defer cancel()
+
+	stmt := `INSERT INTO webhooks (url, secret, events, created_at, updated_at) VALUES (?, ?, ?, ?, ?);`
+
+	_, err := m.DB.ExecContext(ctx, stmt, hook.URL, hook.Secret, strings.Join(hook.Events, ","), time.Now(), time.Now())
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// DeleteWebhook deletes a webhook by id, together with its deliveries
+func (m *sqlDBRepository) DeleteWebhook(ctx context.Context, id int) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "DeleteWebhook")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE webhook_id = ?;", id)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?;", id)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return interrupted(ctx, tx.Commit())
+}
+
+// InsertWebhookDeliveries queues deliveries of an event to webhooks, to be attempted straight away, within one transaction
+func (m *sqlDBRepository) InsertWebhookDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "InsertWebhookDeliveries")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO webhook_deliveries (webhook_id, event, payload, status, attempts, response_code, last_error, next_attempt_at, created_at, updated_at) 
+	VALUES (?, ?, ?, ?, 0, 0, '', ?, ?, ?);`
+
+	for _, d := range deliveries {
+		_, err = tx.ExecContext(ctx, stmt, d.WebhookID, d.Event, d.Payload, models.DeliveryPending, time.Now(), time.Now(), time.Now())
+		if err != nil {
+			return interrupted(ctx, err)
+		}
+	}
+
+	return interrupted(ctx, tx.Commit())
+}
+
+// scanWebhookDeliveries scans rows of deliveries, each with its webhook's URL & secret
+func scanWebhookDeliveries(rows *sql.Rows) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+
+	for rows.Next() {
+		var d models.WebhookDelivery
+		// delivered_at may be null for deliveries not yet made
+		var deliveredAt sql.NullTime
+		err := rows.Scan(
+			&d.ID,
+			&d.WebhookID,
+			&d.Event,
+			&d.Payload,
+			&d.Status,
+			&d.Attempts,
+			&d.ResponseCode,
+			&d.LastError,
+			&d.NextAttemptAt,
+			&deliveredAt,
+			&d.CreatedAt,
+			&d.UpdatedAt,
+			&d.Webhook.ID,
+			&d.Webhook.URL,
+			&d.Webhook.Secret,
+		)
+
+		if err != nil {
+			return deliveries, err
+		}
+		d.DeliveredAt = deliveredAt.Time
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// GetDueWebhookDeliveries returns up to limit pending deliveries whose next attempt is due, oldest first, as a slice of
+// models.WebhookDelivery
+func (m *sqlDBRepository) GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetDueWebhookDeliveries")
+	defer cancel()
+
+	query := `SELECT d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.response_code, d.last_error, d.next_attempt_at, 
+	d.delivered_at, d.created_at, d.updated_at, h.id, h.url, h.secret 
+	FROM webhook_deliveries d JOIN webhooks h ON (d.webhook_id = h.id) 
+	WHERE d.status = ? AND d.next_attempt_at <= ? ORDER BY d.next_attempt_at ASC, d.id ASC LIMIT ?;`
+
+	rows, err := m.DB.QueryContext(ctx, query, models.DeliveryPending, now, limit)
+	if err != nil {
+		return nil, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	return scanWebhookDeliveries(rows)
+}
+
+// UpdateWebhookDelivery records the outcome of an attempt at a delivery
+func (m *sqlDBRepository) UpdateWebhookDelivery(ctx context.Context, d models.WebhookDelivery) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "UpdateWebhookDelivery")
+	defer cancel()
+
+	// a delivery not yet made has a null delivered_at
+	var deliveredAt interface{}
+	if !d.DeliveredAt.IsZero() {
+		deliveredAt = d.DeliveredAt
+	}
+
+	stmt := `UPDATE webhook_deliveries SET status = ?, attempts = ?, response_code = ?, last_error = ?, next_attempt_at = ?, delivered_at = ?, updated_at = ? 
+	WHERE id = ?;`
+
+	_, err := m.DB.ExecContext(ctx, stmt, d.Status, d.Attempts, d.ResponseCode, d.LastError, d.NextAttemptAt, deliveredAt, time.Now(), d.ID)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries returns up to limit deliveries, most recent first, for the delivery log as a slice of models.WebhookDelivery
+func (m *sqlDBRepository) GetWebhookDeliveries(ctx context.Context, limit int) ([]models.WebhookDelivery, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetWebhookDeliveries")
+	defer cancel()
+
+	query := `SELECT d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.response_code, d.last_error, d.next_attempt_at, 
+	d.delivered_at, d.created_at, d.updated_at, h.id, h.url, h.secret 
+	FROM webhook_deliveries d JOIN webhooks h ON (d.webhook_id = h.id) ORDER BY d.created_at DESC, d.id DESC LIMIT ?;`
+
+	rows, err := m.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	return scanWebhookDeliveries(rows)
+}
+
+// RetryWebhookDelivery queues a delivery which has failed to be attempted again straight away
+func (m *sqlDBRepository) RetryWebhookDelivery(ctx context.Context, id int) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "RetryWebhookDelivery")
+	defer cancel()
+
+	// a failed delivery is given a full set of attempts once more
+	stmt := `UPDATE webhook_deliveries SET status = ?, attempts = 0, next_attempt_at = ?, updated_at = ? WHERE id = ? AND status = ?;`
+
+	res, err := m.DB.ExecContext(ctx, stmt, models.DeliveryPending, time.Now(), time.Now(), id, models.DeliveryFailed)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	retried, err := res.RowsAffected()
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	if retried == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetChannelRooms returns every room mapped to a room type at the channel manager, ordered by room name, as a slice of
+// models.ChannelRoom
+func (m *sqlDBRepository) GetChannelRooms(ctx context.Context) ([]models.ChannelRoom, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetChannelRooms")
+	defer cancel()
+
+	var rooms []models.ChannelRoom
+
+	query := `SELECT c.room_id, c.room_code, c.rate_plan_code, c.created_at, c.updated_at, rm.id, rm.room_name 
+	FROM channel_rooms c JOIN rooms rm ON (c.room_id = rm.id) ORDER BY rm.room_name ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return rooms, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.ChannelRoom
+		err := rows.Scan(
+			&c.RoomID,
+			&c.RoomCode,
+			&c.RatePlanCode,
+			&c.CreatedAt,
+			&c.UpdatedAt,
+			&c.Room.ID,
+			&c.Room.RoomName,
+		)
+
+		if err != nil {
+			return rooms, interrupted(ctx, err)
+		}
+		rooms = append(rooms, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return rooms, interrupted(ctx, err)
+	}
+
+	return rooms, nil
+}
+
+// SaveChannelRooms replaces the mapping of rooms to room types at the channel manager. A room whose codes change, or which is no
+// longer mapped, has what was last pushed for it forgotten, so that it is pushed in full once mapped again
+func (m *sqlDBRepository) SaveChannelRooms(ctx context.Context, rooms []models.ChannelRoom) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "SaveChannelRooms")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	existing := make(map[int]models.ChannelRoom)
+	rows, err := tx.QueryContext(ctx, `SELECT room_id, room_code, rate_plan_code, created_at FROM channel_rooms;`)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	for rows.Next() {
+		var c models.ChannelRoom
+		err = rows.Scan(&c.RoomID, &c.RoomCode, &c.RatePlanCode, &c.CreatedAt)
+		if err != nil {
+			rows.Close()
+			return interrupted(ctx, err)
+		}
+		existing[c.RoomID] = c
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return interrupted(ctx, err)
+	}
+
+	kept := make(map[int]bool)
+	for _, c := range rooms {
+		if e, ok := existing[c.RoomID]; ok && e.RoomCode == c.RoomCode && e.RatePlanCode == c.RatePlanCode {
+			kept[c.RoomID] = true
+		}
+	}
+	for id := range existing {
+		if kept[id] {
+			continue
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM channel_days WHERE room_id = ?;`, id)
+		if err != nil {
+			return interrupted(ctx, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM channel_rooms;`)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+
+	stmt := `INSERT INTO channel_rooms (room_id, room_code, rate_plan_code, created_at, updated_at) VALUES (?, ?, ?, ?, ?);`
+
+	for _, c := range rooms {
+		createdAt := time.Now()
+		if e, ok := existing[c.RoomID]; ok && kept[c.RoomID] {
+			createdAt = e.CreatedAt
+		}
+		_, err = tx.ExecContext(ctx, stmt, c.RoomID, c.RoomCode, c.RatePlanCode, createdAt, time.Now())
+		if err != nil {
+			return interrupted(ctx, err)
+		}
+	}
+
+	return interrupted(ctx, tx.Commit())
+}
+
+// GetChannelDays returns what was last pushed to the channel manager for a room's nights from startDate to endDate inclusive, as a
+// slice of models.ChannelDay. Nights never pushed are left out
+func (m *sqlDBRepository) GetChannelDays(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.ChannelDay, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetChannelDays")
+	defer cancel()
+
+	var days []models.ChannelDay
+
+	query := `SELECT night, open, rate FROM channel_days WHERE room_id = ? AND night BETWEEN ? AND ? ORDER BY night ASC;`
+
+	rows, err := m.DB.QueryContext(ctx, query, roomID, startDate, endDate)
+	if err != nil {
+		return days, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var d models.ChannelDay
+		err := rows.Scan(
+			&d.Date,
+			&d.Open,
+			&d.Rate,
+		)
+
+		if err != nil {
+			return days, interrupted(ctx, err)
+		}
+		days = append(days, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return days, interrupted(ctx, err)
+	}
+
+	return days, nil
+}
+
+// SaveChannelDays records what has been pushed to the channel manager for a room's nights, within one transaction
+func (m *sqlDBRepository) SaveChannelDays(ctx context.Context, roomID int, days []models.ChannelDay) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "SaveChannelDays")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO channel_days (room_id, night, open, rate, updated_at) VALUES (?, ?, ?, ?, ?) ` +
+		tx.dialect.upsert("room_id, night", "open", "rate", "updated_at") + `;`
+
+	for _, d := range days {
+		_, err = tx.ExecContext(ctx, stmt, roomID, d.Date, d.Open, d.Rate, time.Now())
+		if err != nil {
+			return interrupted(ctx, err)
+		}
+	}
+
+	return interrupted(ctx, tx.Commit())
+}
+
+// ResetChannelDays forgets what has been pushed to the channel manager, so that every room is pushed in full by the next sync
+func (m *sqlDBRepository) ResetChannelDays(ctx context.Context) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "ResetChannelDays")
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM channel_days;`)
+	return interrupted(ctx, err)
+}
+
+// InsertChannelSync records a message pushed to the channel manager in the sync log
+func (m *sqlDBRepository) InsertChannelSync(ctx context.Context, s models.ChannelSync) error {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "InsertChannelSync")
+	defer cancel()
+
+	stmt := `INSERT INTO channel_syncs (room_id, message, start_date, end_date, changes, status, attempts, response_code, last_error, request, created_at) 
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	_, err := m.DB.ExecContext(ctx, stmt,
+		s.RoomID,
+		s.Message,
+		s.StartDate,
+		s.EndDate,
+		s.Changes,
+		s.Status,
+		s.Attempts,
+		s.ResponseCode,
+		s.LastError,
+		s.Request,
+		time.Now(),
+	)
+
+	return interrupted(ctx, err)
+}
+
+// GetChannelSyncs returns up to limit messages pushed to the channel manager, most recent first, for the sync log as a slice of
+// models.ChannelSync
+func (m *sqlDBRepository) GetChannelSyncs(ctx context.Context, limit int) ([]models.ChannelSync, error) {
+	// transaction given the operation's configured timeout to complete, unless the caller gives up first, after which connection will be released
+	ctx, cancel := operationContext(ctx, m.App, "GetChannelSyncs")
+	defer cancel()
+
+	var syncs []models.ChannelSync
+
+	query := `SELECT s.id, s.room_id, s.message, s.start_date, s.end_date, s.changes, s.status, s.attempts, s.response_code, s.last_error, s.request, 
+	s.created_at, rm.id, rm.room_name 
+	FROM channel_syncs s JOIN rooms rm ON (s.room_id = rm.id) ORDER BY s.created_at DESC, s.id DESC LIMIT ?;`
+
+	rows, err := m.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return syncs, interrupted(ctx, err)
+	}
+	// must close rows after function has executed
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.ChannelSync
+		err := rows.Scan(
+			&s.ID,
+			&s.RoomID,
+			&s.Message,
+			&s.StartDate,
+			&s.EndDate,
+			&s.Changes,
+			&s.Status,
+			&s.Attempts,
+			&s.ResponseCode,
+			&s.LastError,
+			&s.Request,
+			&s.CreatedAt,
+			&s.Room.ID,
+			&s.Room.RoomName,
+		)
+
+		if err != nil {
+			return syncs, interrupted(ctx, err)
+		}
+		syncs = append(syncs, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return syncs, interrupted(ctx, err)
+	}
+
+	return syncs, nil
+}
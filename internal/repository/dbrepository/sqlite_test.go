@@ -0,0 +1,98 @@
+package dbrepository
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/config"
+	"github.com/StratoNET/bnb-bookings/internal/database"
+	"github.com/StratoNET/bnb-bookings/internal/models"
+	"github.com/StratoNET/bnb-bookings/internal/repository"
+)
+
+// TestSQLiteDBRepository runs the behavioural tests against a SQLite database in a temporary file, so needing no database to be set up
+func TestSQLiteDBRepository(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "bnb-bookings.db") + "?_foreign_keys=1&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+
+	db, err := sql.Open(database.SQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testDatabaseRepository(t, func(t *testing.T) repository.DatabaseRepository {
+		resetSchema(t, db, database.SQLite)
+		return NewSQLiteDBRepository(db, &config.AppConfig{})
+	})
+}
+
+// TestSQLiteDBRepository_Timeouts checks each operation is given its configured timeout, leaving other operations alone
+func TestSQLiteDBRepository_Timeouts(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "bnb-bookings.db") + "?_foreign_keys=1&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+
+	db, err := sql.Open(database.SQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	resetSchema(t, db, database.SQLite)
+
+	repo := NewSQLiteDBRepository(db, &config.AppConfig{
+		DBTimeouts: config.DBTimeouts{Operations: map[string]time.Duration{"GetAllRooms": time.Nanosecond}},
+	})
+	ctx := context.Background()
+
+	_, err = repo.GetAllRooms(ctx)
+	expectInterrupted(t, err, true, context.DeadlineExceeded, "GetAllRooms")
+
+	_, err = repo.GetRoomByID(ctx, 1)
+	if err != nil {
+		t.Errorf("expected GetRoomByID to be given the default timeout, got %v", err)
+	}
+}
+
+// TestSQLiteDBRepository_CancelKeepsRestrictions checks cancelling a reservation, alone or with its booking group, soft deletes its
+// room restriction, so keeping the room's history
+func TestSQLiteDBRepository_CancelKeepsRestrictions(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "bnb-bookings.db") + "?_foreign_keys=1&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+
+	db, err := sql.Open(database.SQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	resetSchema(t, db, database.SQLite)
+
+	repo := NewSQLiteDBRepository(db, &config.AppConfig{})
+	ctx := context.Background()
+
+	id := mustReserve(t, repo, testReservation(1, 1, 5))
+	if err = repo.UpdateReservationStatus(ctx, id, models.StatusPending, models.StatusCancelled); err != nil {
+		t.Fatal("UpdateReservationStatus failed", err)
+	}
+	group := models.BookingGroup{
+		ConfirmationRef: "BNB-GROUP",
+		FirstName:       "John",
+		LastName:        "Smith",
+		Email:           "john@example.com",
+		StartDate:       day(10),
+		EndDate:         day(12),
+		Reservations:    []models.Reservation{testReservation(1, 10, 12), testReservation(2, 10, 12)},
+	}
+	groupID, err := repo.CreateBookingGroup(ctx, group, "")
+	if err != nil {
+		t.Fatal("CreateBookingGroup failed", err)
+	}
+	if err = repo.UpdateBookingGroupStatus(ctx, int(groupID), models.StatusCancelled); err != nil {
+		t.Fatal("UpdateBookingGroupStatus failed", err)
+	}
+
+	var kept int
+	err = db.QueryRow("SELECT COUNT(id) FROM room_restrictions WHERE reservation_id IS NOT NULL AND deleted_at IS NOT NULL;").Scan(&kept)
+	if err != nil || kept != 3 {
+		t.Errorf("expected the 3 cancelled reservations' restrictions to be kept, deleted, got %d %v", kept, err)
+	}
+}
@@ -0,0 +1,68 @@
+-- a MariaDB database as it was set up before migrations were versioned, with a reservation the owner has processed & one they have not
+
+CREATE TABLE administrators (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	first_name VARCHAR(255) NOT NULL DEFAULT '',
+	last_name VARCHAR(255) NOT NULL DEFAULT '',
+	email VARCHAR(255) NOT NULL UNIQUE,
+	password VARCHAR(60) NOT NULL,
+	access_level INT NOT NULL DEFAULT 1,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE rooms (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	room_name VARCHAR(255) NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE restrictions (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	restriction_name VARCHAR(255) NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE reservations (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	room_id INT NOT NULL REFERENCES rooms (id),
+	first_name VARCHAR(255) NOT NULL,
+	last_name VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL,
+	phone VARCHAR(255) NOT NULL DEFAULT '',
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	processed TINYINT NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+CREATE TABLE room_restrictions (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	room_id INT NOT NULL REFERENCES rooms (id),
+	reservation_id INT NULL REFERENCES reservations (id),
+	restriction_id INT NOT NULL REFERENCES restrictions (id),
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+) ENGINE=InnoDB;
+
+INSERT INTO rooms (id, room_name, created_at, updated_at) VALUES
+	(1, 'General''s Quarters', NOW(), NOW()),
+	(2, 'Major''s Suite', NOW(), NOW());
+
+INSERT INTO restrictions (id, restriction_name, created_at, updated_at) VALUES
+	(1, 'Reservation', NOW(), NOW()),
+	(2, 'Owner Block', NOW(), NOW());
+
+INSERT INTO reservations (id, room_id, first_name, last_name, email, phone, start_date, end_date, processed, created_at, updated_at) VALUES
+	(1, 1, 'Joe', 'Soap', 'joe@example.com', '', '2098-03-01', '2098-03-03', 1, NOW(), NOW()),
+	(2, 2, 'Jane', 'Doe', 'jane@example.com', '', '2098-03-10', '2098-03-12', 0, NOW(), NOW());
+
+INSERT INTO room_restrictions (room_id, reservation_id, restriction_id, start_date, end_date, created_at, updated_at) VALUES
+	(1, 1, 1, '2098-03-01', '2098-03-03', NOW(), NOW()),
+	(2, 2, 1, '2098-03-10', '2098-03-12', NOW(), NOW()),
+	(1, NULL, 2, '2098-04-01', '2098-04-01', NOW(), NOW());
@@ -0,0 +1,8 @@
+-- an administrator every behavioural test starts from, & rates for the rooms the migrations give, in SQL common to every database
+
+-- password is "password"
+INSERT INTO administrators (first_name, last_name, email, password, access_level, created_at, updated_at)
+VALUES ('Ann', 'Admin', 'admin@example.com', '$2a$10$x9MNwRvoH6Xr3hrKgq6Dpe.sfttykQn5aTMn/VDVWKnauF1HahZU6', 3, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+
+UPDATE rooms SET nightly_rate = 8000, weekend_rate = 9500, extra_guest_rate = 1500 WHERE id = 1;
+UPDATE rooms SET nightly_rate = 9000, weekend_rate = 11000, extra_guest_rate = 2000 WHERE id = 2;
@@ -1,10 +1,14 @@
 package dbrepository
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"log"
 	"time"
 
+	"github.com/StratoNET/bnb-bookings/internal/apitoken"
+	"github.com/StratoNET/bnb-bookings/internal/lifecycle"
 	"github.com/StratoNET/bnb-bookings/internal/models"
 )
 
@@ -13,7 +17,7 @@ func (m *testDBRepository) AllAdministrators() bool {
 }
 
 // InsertReservation inserts a new reservation record into database
-func (m *testDBRepository) InsertReservation(rsvn models.Reservation) (int64, error) {
+func (m *testDBRepository) InsertReservation(ctx context.Context, rsvn models.Reservation) (int64, error) {
 	if rsvn.RoomID == 99 {
 		return 0, errors.New("insert room reservation failed")
 	}
@@ -21,15 +25,46 @@ func (m *testDBRepository) InsertReservation(rsvn models.Reservation) (int64, er
 }
 
 // InsertRoomRestriction inserts a room restriction in database
-func (m *testDBRepository) InsertRoomRestriction(rest models.RoomRestriction) error {
+func (m *testDBRepository) InsertRoomRestriction(ctx context.Context, rest models.RoomRestriction) error {
 	if rest.RoomID == 9999 {
 		return errors.New("insert room restriction failed")
 	}
 	return nil
 }
 
+// CreateReservation re-checks availability, then inserts a reservation & its room restriction, converting the guest's hold, within a single transaction
+func (m *testDBRepository) CreateReservation(ctx context.Context, rsvn models.Reservation, holdToken string) (int64, error) {
+	return 1, nil
+}
+
+// CreateBookingGroup inserts a booking group together with a reservation & room restriction for each of its rooms, within a single transaction
+func (m *testDBRepository) CreateBookingGroup(ctx context.Context, group models.BookingGroup, holdToken string) (int64, error) {
+	for _, rsvn := range group.Reservations {
+		_, err := m.CreateReservation(ctx, rsvn, holdToken)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return 1, nil
+}
+
+// PlaceHold holds one or more rooms for a guest's dates until the holds expire, provided every room is free for those dates
+func (m *testDBRepository) PlaceHold(ctx context.Context, holds ...models.RoomRestriction) error {
+	return nil
+}
+
+// ReleaseHold removes a guest's hold, if any, freeing the room
+func (m *testDBRepository) ReleaseHold(ctx context.Context, holdToken string) error {
+	return nil
+}
+
+// ReleaseExpiredHolds removes all holds which expired before a given time, returning how many were removed
+func (m *testDBRepository) ReleaseExpiredHolds(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
 //SearchAvailabilityByDatesAndRoomID return true if availability exists, otherwise false
-func (m *testDBRepository) SearchAvailabilityByDatesAndRoomID(start, end time.Time, roomID int) (bool, error) {
+func (m *testDBRepository) SearchAvailabilityByDatesAndRoomID(ctx context.Context, start, end time.Time, roomID int, holdToken string) (bool, error) {
 	// test to fail query
 	layout := "02/01/2006"
 	testDate, err := time.Parse(layout, "01/01/2099")
@@ -44,8 +79,8 @@ func (m *testDBRepository) SearchAvailabilityByDatesAndRoomID(start, end time.Ti
 	return true, nil
 }
 
-// SearchAvailabilityForAllRooms returns a slice of available rooms, if any, for given date range
-func (m *testDBRepository) SearchAvailabilityForAllRooms(start, end time.Time) ([]models.Room, error) {
+// SearchAvailabilityForAllRooms returns a slice of available rooms, if any, for given date range which can accommodate the party of guests
+func (m *testDBRepository) SearchAvailabilityForAllRooms(ctx context.Context, start, end time.Time, adults, children, infants int, holdToken string) ([]models.Room, error) {
 	var rooms_available []models.Room
 	// if the start date is after 31/12/2098 return empty slice, indicating no rooms are available
 	layout := "02/01/2006"
@@ -69,17 +104,27 @@ func (m *testDBRepository) SearchAvailabilityForAllRooms(start, end time.Time) (
 		return rooms_available, nil
 	}
 
-	// otherwise put entry into slice, indicating that some room is available for search dates
+	// otherwise put entry into slice, indicating that some room is available for search dates, if it is large enough for the party
 	room := models.Room{
-		ID: 1,
+		ID:          1,
+		MaxAdults:   2,
+		MaxChildren: 2,
+		MaxInfants:  1,
+	}
+	if room.Accommodates(adults, children, infants) {
+		rooms_available = append(rooms_available, room)
 	}
-	rooms_available = append(rooms_available, room)
 	return rooms_available, nil
 }
 
 // GetRoomByID gets room details, especially room name, by id
-func (m *testDBRepository) GetRoomByID(id int) (models.Room, error) {
-	var room models.Room
+func (m *testDBRepository) GetRoomByID(ctx context.Context, id int) (models.Room, error) {
+	room := models.Room{
+		ID:          id,
+		MaxAdults:   2,
+		MaxChildren: 2,
+		MaxInfants:  1,
+	}
 	if id > 2 {
 		return room, errors.New("attempting to return room number greater than number of rooms available")
 	}
@@ -87,18 +132,18 @@ func (m *testDBRepository) GetRoomByID(id int) (models.Room, error) {
 }
 
 // GetAdministratorByID does exactly that
-func (m *testDBRepository) GetAdministratorByID(id int) (models.Administrator, error) {
+func (m *testDBRepository) GetAdministratorByID(ctx context.Context, id int) (models.Administrator, error) {
 	var admin models.Administrator
 	return admin, nil
 }
 
 // UpdateAdministrator updates an administrator record in the database
-func (m *testDBRepository) UpdateAdministrator(admin models.Administrator) error {
+func (m *testDBRepository) UpdateAdministrator(ctx context.Context, admin models.Administrator) error {
 	return nil
 }
 
 // AuthenticateAdministrator does exactly that
-func (m *testDBRepository) AuthenticateAdministrator(email, password string) (int, string, error) {
+func (m *testDBRepository) AuthenticateAdministrator(ctx context.Context, email, password string) (int, string, error) {
 	// a test authenticated administrator
 	if email == "peter@barrett.com" {
 		return 1, "", nil
@@ -108,36 +153,182 @@ func (m *testDBRepository) AuthenticateAdministrator(email, password string) (in
 	}
 }
 
+// GetAdministratorByAPIToken returns the administrator owning an API token, given the token's hash, recording that the token has been used
+func (m *testDBRepository) GetAdministratorByAPIToken(ctx context.Context, tokenHash string) (models.Administrator, error) {
+	var admin models.Administrator
+	switch tokenHash {
+	case apitoken.Hash("bnb_test"):
+		// a test API token
+		admin = models.Administrator{ID: 1, FirstName: "Peter", LastName: "Barrett", Email: "peter@barrett.com"}
+		return admin, nil
+	}
+	// otherwise, no such token
+	return admin, sql.ErrNoRows
+}
+
+// GetAPITokensByAdministratorID returns an administrator's API tokens, newest first, as a slice of models.APIToken
+func (m *testDBRepository) GetAPITokensByAdministratorID(ctx context.Context, adminID int) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	tokens = append(tokens, models.APIToken{
+		ID:              1,
+		AdministratorID: adminID,
+		Name:            "Channel manager",
+		TokenHash:       apitoken.Hash("bnb_test"),
+		LastUsedAt:      time.Now().AddDate(0, 0, -1),
+		CreatedAt:       time.Now().AddDate(0, -1, 0),
+	})
+	return tokens, nil
+}
+
+// InsertAPIToken inserts a new API token for an administrator, only the token's hash is given
+func (m *testDBRepository) InsertAPIToken(ctx context.Context, token models.APIToken) error {
+	return nil
+}
+
+// DeleteAPIToken revokes one of an administrator's API tokens by id
+func (m *testDBRepository) DeleteAPIToken(ctx context.Context, id, adminID int) error {
+	return nil
+}
+
 // GetAllRooms returns all rooms as a slice of models.Room
-func (m *testDBRepository) GetAllRooms() ([]models.Room, error) {
+func (m *testDBRepository) GetAllRooms(ctx context.Context) ([]models.Room, error) {
 	var rooms []models.Room
+	rooms = append(rooms, models.Room{
+		ID:          1,
+		RoomName:    "General's Quarters",
+		NightlyRate: 8000,
+		MaxAdults:   2,
+		MaxChildren: 2,
+		MaxInfants:  1,
+	}, models.Room{
+		ID:          2,
+		RoomName:    "Major's Suite",
+		MaxAdults:   4,
+		MaxChildren: 0,
+		MaxInfants:  1,
+	})
 	return rooms, nil
 }
 
 // GetAllReservations returns all reservations as a slice of models.Reservation
-func (m *testDBRepository) GetAllReservations() ([]models.Reservation, error) {
+func (m *testDBRepository) GetAllReservations(ctx context.Context) ([]models.Reservation, error) {
 	var reservations []models.Reservation
 	return reservations, nil
 }
 
 // GetNewReservations returns only new reservations as a slice of models.Reservation
-func (m *testDBRepository) GetNewReservations() ([]models.Reservation, error) {
+func (m *testDBRepository) GetNewReservations(ctx context.Context) ([]models.Reservation, error) {
+	var reservations []models.Reservation
+	return reservations, nil
+}
+
+// FilterReservations returns reservations selected by status, room & dates, ordered by arrival, as a slice of models.Reservation
+func (m *testDBRepository) FilterReservations(ctx context.Context, filter models.ReservationFilter) ([]models.Reservation, error) {
+	var reservations []models.Reservation
+
+	all := []models.Reservation{
+		{
+			ID:              1,
+			RoomID:          1,
+			FirstName:       "Joe",
+			LastName:        "Soap",
+			Email:           "joe@soap.bar",
+			StartDate:       time.Date(2098, 1, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:         time.Date(2098, 1, 5, 0, 0, 0, 0, time.UTC),
+			Status:          models.StatusPending,
+			ConfirmationRef: "K7QX2MZP4D",
+			Room:            models.Room{ID: 1, RoomName: "General's Quarters"},
+		},
+		{
+			ID:              2,
+			RoomID:          2,
+			FirstName:       "Jane",
+			LastName:        "Doe",
+			Email:           "jane@doe.bar",
+			StartDate:       time.Date(2098, 2, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:         time.Date(2098, 2, 3, 0, 0, 0, 0, time.UTC),
+			Status:          models.StatusConfirmed,
+			ConfirmationRef: "M2PD7QXK4Z",
+			Room:            models.Room{ID: 2, RoomName: "Major's Suite"},
+		},
+	}
+
+	for _, r := range all {
+		if (filter.Status != "" && r.Status != filter.Status) || (filter.RoomID != 0 && r.RoomID != filter.RoomID) ||
+			(!filter.StartDate.IsZero() && r.EndDate.Before(filter.StartDate)) || (!filter.EndDate.IsZero() && r.StartDate.After(filter.EndDate)) {
+			continue
+		}
+		reservations = append(reservations, r)
+	}
+	return reservations, nil
+}
+
+// StreamReservations passes reservations selected by status, room & dates, ordered by arrival, to fn one at a time
+func (m *testDBRepository) StreamReservations(ctx context.Context, filter models.ReservationFilter, fn func(models.Reservation) error) error {
+	reservations, err := m.FilterReservations(ctx, filter)
+	if err != nil {
+		return err
+	}
+	for _, r := range reservations {
+		if err = fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportReservations inserts reservations keyed in or imported in bulk within a single transaction, so either every reservation is
+// imported or none are
+func (m *testDBRepository) ImportReservations(ctx context.Context, reservations []models.Reservation) ([]int64, error) {
+	var ids []int64
+	for i := range reservations {
+		ids = append(ids, int64(i+1))
+	}
+	return ids, nil
+}
+
+// GetReservationsByStatus returns only reservations of a given status as a slice of models.Reservation
+func (m *testDBRepository) GetReservationsByStatus(ctx context.Context, status models.ReservationStatus) ([]models.Reservation, error) {
 	var reservations []models.Reservation
 	return reservations, nil
 }
 
 // GetReservationByID returns only one reservation as a models.Reservation
-func (m *testDBRepository) GetReservationByID(id int) (models.Reservation, error) {
-	var r models.Reservation
+func (m *testDBRepository) GetReservationByID(ctx context.Context, id int) (models.Reservation, error) {
+	r := models.Reservation{
+		ID:     id,
+		Status: models.StatusPending,
+	}
 	if id == 0 {
 		return r, errors.New("non-existent reservation: test OK")
-	} else {
-		return r, nil
 	}
+	return r, nil
+}
+
+// GetReservationByRef returns only one reservation, found by its confirmation reference, as a models.Reservation
+func (m *testDBRepository) GetReservationByRef(ctx context.Context, ref string) (models.Reservation, error) {
+	r := models.Reservation{
+		ID:              1,
+		RoomID:          1,
+		FirstName:       "Joe",
+		LastName:        "Soap",
+		Email:           "joe@soap.bar",
+		Phone:           "01234 567890",
+		StartDate:       time.Now().AddDate(0, 1, 0),
+		EndDate:         time.Now().AddDate(0, 1, 2),
+		Adults:          2,
+		ConfirmationRef: ref,
+		Room: models.Room{
+			ID:       1,
+			RoomName: "General's Quarters",
+		},
+		Status: models.StatusConfirmed,
+	}
+	return r, nil
 }
 
 // UpdateReservation updates a reservation record in the database
-func (m *testDBRepository) UpdateReservation(admin models.Reservation) error {
+func (m *testDBRepository) UpdateReservation(ctx context.Context, admin models.Reservation) error {
 	if admin.FirstName == "Bonzo" {
 		return errors.New("cannot update non-existent reservation: test OK")
 	} else {
@@ -145,18 +336,151 @@ func (m *testDBRepository) UpdateReservation(admin models.Reservation) error {
 	}
 }
 
-// DeleteReservation deletes a reservation from the database by id
-func (m *testDBRepository) DeleteReservation(id int) error {
+// DeleteReservation moves a reservation, together with its room restriction, to the trash by id, freeing the room
+func (m *testDBRepository) DeleteReservation(ctx context.Context, id int) error {
+	return nil
+}
+
+// GetDeletedReservations returns all reservations in the trash, most recently deleted first, as a slice of models.Reservation
+func (m *testDBRepository) GetDeletedReservations(ctx context.Context) ([]models.Reservation, error) {
+	var reservations []models.Reservation
+	reservations = append(reservations, models.Reservation{
+		ID:              1,
+		RoomID:          1,
+		FirstName:       "Joe",
+		LastName:        "Soap",
+		StartDate:       time.Now().AddDate(0, 1, 0),
+		EndDate:         time.Now().AddDate(0, 1, 2),
+		Status:          models.StatusPending,
+		ConfirmationRef: "K7QX2MZP4D",
+		DeletedAt:       time.Now().AddDate(0, 0, -1),
+		Room: models.Room{
+			ID:       1,
+			RoomName: "General's Quarters",
+		},
+	})
+	return reservations, nil
+}
+
+// RestoreReservation takes a reservation, together with the room restriction deleted with it, back out of the trash by id
+func (m *testDBRepository) RestoreReservation(ctx context.Context, id int) error {
+	return nil
+}
+
+// UpdateReservationStatus moves a reservation from one status to another, freeing the room of a cancelled reservation
+func (m *testDBRepository) UpdateReservationStatus(ctx context.Context, id int, from, to models.ReservationStatus) error {
+	return lifecycle.Check(from, to)
+}
+
+// GetReservationStatusChanges returns the status history of a reservation, oldest first, as a slice of models.StatusChange
+func (m *testDBRepository) GetReservationStatusChanges(ctx context.Context, id int) ([]models.StatusChange, error) {
+	var changes []models.StatusChange
+	return changes, nil
+}
+
+// GetAllBookingGroups returns every booking group with at least one live reservation, most recent first, each with its reservations
+func (m *testDBRepository) GetAllBookingGroups(ctx context.Context) ([]models.BookingGroup, error) {
+	var groups []models.BookingGroup
+	group, _ := m.GetBookingGroupByID(ctx, 1)
+	groups = append(groups, group)
+	return groups, nil
+}
+
+// GetBookingGroupByID returns only one booking group, with its live reservations, as a models.BookingGroup
+func (m *testDBRepository) GetBookingGroupByID(ctx context.Context, id int) (models.BookingGroup, error) {
+	group := models.BookingGroup{
+		ID:              id,
+		ConfirmationRef: "GROUP1",
+		FirstName:       "Joe",
+		LastName:        "Soap",
+		Email:           "joe@soap.bar",
+		Phone:           "01234 567890",
+		StartDate:       time.Now().AddDate(0, 1, 0),
+		EndDate:         time.Now().AddDate(0, 1, 2),
+	}
+	for roomID := 1; roomID <= 2; roomID++ {
+		group.Reservations = append(group.Reservations, models.Reservation{
+			ID:        roomID,
+			RoomID:    roomID,
+			FirstName: group.FirstName,
+			LastName:  group.LastName,
+			StartDate: group.StartDate,
+			EndDate:   group.EndDate,
+			Status:    models.StatusPending,
+			Adults:    2,
+			GroupID:   id,
+		})
+	}
+	return group, nil
+}
+
+// UpdateBookingGroupStatus moves every live reservation of a booking group on to the same status, or none should any not be allowed to
+func (m *testDBRepository) UpdateBookingGroupStatus(ctx context.Context, id int, to models.ReservationStatus) error {
+	return lifecycle.Check(models.StatusPending, to)
+}
+
+// DeleteBookingGroup moves every reservation of a booking group, together with their room restrictions, to the trash, freeing the rooms
+func (m *testDBRepository) DeleteBookingGroup(ctx context.Context, id int) error {
+	return nil
+}
+
+// InsertWaitlistEntry adds a guest to the waitlist for their dates & party
+func (m *testDBRepository) InsertWaitlistEntry(ctx context.Context, entry models.WaitlistEntry) error {
+	return nil
+}
+
+// GetWaitlist returns the whole waitlist, in the order guests joined it, as a slice of models.WaitlistEntry
+func (m *testDBRepository) GetWaitlist(ctx context.Context) ([]models.WaitlistEntry, error) {
+	var entries []models.WaitlistEntry
+	entries = append(entries, models.WaitlistEntry{
+		ID:        1,
+		FirstName: "Joe",
+		LastName:  "Soap",
+		Email:     "joe@soap.bar",
+		Phone:     "01234 567890",
+		StartDate: time.Now().AddDate(0, 1, 0),
+		EndDate:   time.Now().AddDate(0, 1, 2),
+		Adults:    2,
+	})
+	return entries, nil
+}
+
+// GetWaitlistByDates returns guests not yet notified who are waiting for dates overlapping a date range, in the order they joined
+func (m *testDBRepository) GetWaitlistByDates(ctx context.Context, startDate, endDate time.Time) ([]models.WaitlistEntry, error) {
+	var entries []models.WaitlistEntry
+	// one party small enough for any room & one too large for all of them
+	entries = append(entries, models.WaitlistEntry{
+		ID:        1,
+		FirstName: "Joe",
+		LastName:  "Soap",
+		Email:     "joe@soap.bar",
+		StartDate: startDate,
+		EndDate:   endDate,
+		Adults:    2,
+	}, models.WaitlistEntry{
+		ID:        2,
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Email:     "jane@doe.bar",
+		StartDate: startDate,
+		EndDate:   endDate,
+		Adults:    6,
+	})
+	return entries, nil
+}
+
+// MarkWaitlistNotified records that a waitlisted guest has been told a room is free
+func (m *testDBRepository) MarkWaitlistNotified(ctx context.Context, id int) error {
 	return nil
 }
 
-// UpdateReservationProcessed updates processed level of a reservation by id
-func (m *testDBRepository) UpdateReservationProcessed(id int, processed uint8) error {
+// DeleteWaitlistEntry removes a guest from the waitlist by id
+func (m *testDBRepository) DeleteWaitlistEntry(ctx context.Context, id int) error {
 	return nil
 }
 
 // GetRoomRestrictionsByDate returns all rooms restrictions by room id, for a date range, as a slice of models.RoomRestriction
-func (m *testDBRepository) GetRoomRestrictionsByDate(roomID int, startDate, endDate time.Time) ([]models.RoomRestriction, error) {
+func (m *testDBRepository) GetRoomRestrictionsByDate(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.RoomRestriction, error) {
 	var restrictions []models.RoomRestriction
 	// add a block
 	restrictions = append(restrictions, models.RoomRestriction{
@@ -175,16 +499,507 @@ func (m *testDBRepository) GetRoomRestrictionsByDate(roomID int, startDate, endD
 		RoomID:        1,
 		ReservationID: 1,
 		RestrictionID: 1,
+		Reservation: models.Reservation{
+			ID:     1,
+			Status: models.StatusConfirmed,
+		},
+	})
+	return restrictions, nil
+}
+
+// GetRoomRestrictionsForCalendar returns every restriction taking a room during a date range, including guests' unexpired holds. Room 1 has
+// a reservation, a hold & an owner block in March 2098
+func (m *testDBRepository) GetRoomRestrictionsForCalendar(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.RoomRestriction, error) {
+	var restrictions []models.RoomRestriction
+	if roomID != 1 {
+		return restrictions, nil
+	}
+	restrictions = append(restrictions,
+		models.RoomRestriction{ID: 1, RoomID: 1, ReservationID: 1, RestrictionID: models.RestrictionReservation,
+			StartDate: time.Date(2098, 3, 10, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2098, 3, 13, 0, 0, 0, 0, time.UTC)},
+		models.RoomRestriction{ID: 2, RoomID: 1, RestrictionID: models.RestrictionHold,
+			StartDate: time.Date(2098, 3, 15, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2098, 3, 16, 0, 0, 0, 0, time.UTC)},
+		models.RoomRestriction{ID: 3, RoomID: 1, RestrictionID: models.RestrictionOwnerBlock,
+			StartDate: time.Date(2098, 3, 20, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2098, 3, 21, 0, 0, 0, 0, time.UTC)},
+	)
+	return restrictions, nil
+}
+
+// GetRoomRestrictionsForFeed returns a room's reservations, owner blocks & external bookings ending on or after a given date, each reservation with its
+// guest, ordered by start date as a slice of models.RoomRestriction
+func (m *testDBRepository) GetRoomRestrictionsForFeed(ctx context.Context, roomID int, from time.Time) ([]models.RoomRestriction, error) {
+	var restrictions []models.RoomRestriction
+	// add a reservation
+	restrictions = append(restrictions, models.RoomRestriction{
+		ID:            2,
+		StartDate:     time.Now().AddDate(0, 0, 2),
+		EndDate:       time.Now().AddDate(0, 0, 4),
+		RoomID:        roomID,
+		ReservationID: 1,
+		RestrictionID: models.RestrictionReservation,
+		Reservation: models.Reservation{
+			ID:              1,
+			FirstName:       "Joe",
+			LastName:        "Soap",
+			Status:          models.StatusConfirmed,
+			Adults:          2,
+			ConfirmationRef: "K7QX2MZP4D",
+		},
+	})
+	// add a block
+	restrictions = append(restrictions, models.RoomRestriction{
+		ID:            3,
+		StartDate:     time.Now().AddDate(0, 0, 6),
+		EndDate:       time.Now().AddDate(0, 0, 6),
+		RoomID:        roomID,
+		RestrictionID: models.RestrictionOwnerBlock,
 	})
 	return restrictions, nil
 }
 
 // InsertRoomBlock inserts an owner block restriction for a given room
-func (m *testDBRepository) InsertRoomBlock(roomID int, startDate, endDate time.Time) error {
+func (m *testDBRepository) InsertRoomBlock(ctx context.Context, roomID int, startDate, endDate time.Time) error {
 	return nil
 }
 
-// DeleteRoomBlock deletes an owner block restriction for a room by id
-func (m *testDBRepository) DeleteRoomBlock(id int) error {
+// CreateRoomBlock inserts an owner block restriction for a given room, only if the room is still free for those dates, returning the new
+// block's id. A *repository.RoomUnavailableError is returned if the room has already been taken
+func (m *testDBRepository) CreateRoomBlock(ctx context.Context, roomID int, startDate, endDate time.Time) (int64, error) {
+	return 1, nil
+}
+
+// GetRoomBlockByID returns an owner block restriction, not in the trash, by id
+func (m *testDBRepository) GetRoomBlockByID(ctx context.Context, id int) (models.RoomRestriction, error) {
+	return models.RoomRestriction{
+		ID:            id,
+		RoomID:        1,
+		RestrictionID: models.RestrictionOwnerBlock,
+		StartDate:     time.Date(2098, 7, 10, 0, 0, 0, 0, time.UTC),
+		EndDate:       time.Date(2098, 7, 11, 0, 0, 0, 0, time.UTC),
+	}, nil
+}
+
+// DeleteRoomBlock moves an owner block restriction for a room to the trash by id
+func (m *testDBRepository) DeleteRoomBlock(ctx context.Context, id int) error {
 	return nil
 }
+
+// GetDeletedRoomBlocks returns all owner blocks in the trash, most recently deleted first, as a slice of models.RoomRestriction
+func (m *testDBRepository) GetDeletedRoomBlocks(ctx context.Context) ([]models.RoomRestriction, error) {
+	var blocks []models.RoomRestriction
+	blocks = append(blocks, models.RoomRestriction{
+		ID:            3,
+		RoomID:        1,
+		RestrictionID: 2,
+		StartDate:     time.Now().AddDate(0, 0, 5),
+		EndDate:       time.Now().AddDate(0, 0, 6),
+		DeletedAt:     time.Now().AddDate(0, 0, -1),
+		Room: models.Room{
+			ID:       1,
+			RoomName: "General's Quarters",
+		},
+	})
+	return blocks, nil
+}
+
+// RestoreRoomBlock takes an owner block restriction back out of the trash by id
+func (m *testDBRepository) RestoreRoomBlock(ctx context.Context, id int) error {
+	return nil
+}
+
+// PurgeDeleted permanently removes reservations & owner blocks moved to the trash before a given time
+func (m *testDBRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// UpdateRoomRates updates the nightly, weekend & extra guest rates of a room
+func (m *testDBRepository) UpdateRoomRates(ctx context.Context, room models.Room) error {
+	return nil
+}
+
+// UpdateRoomOccupancy updates the maximum numbers of adults, children & infants a room can accommodate
+func (m *testDBRepository) UpdateRoomOccupancy(ctx context.Context, room models.Room) error {
+	return nil
+}
+
+// GetSeasonalRatesByRoomID returns all seasonal rates for a room which overlap a date range, as a slice of models.SeasonalRate
+func (m *testDBRepository) GetSeasonalRatesByRoomID(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.SeasonalRate, error) {
+	var seasons []models.SeasonalRate
+	// add a season covering the whole date range
+	seasons = append(seasons, models.SeasonalRate{
+		ID:          1,
+		RoomID:      roomID,
+		SeasonName:  "High Season",
+		StartDate:   startDate,
+		EndDate:     endDate,
+		NightlyRate: 10000,
+		WeekendRate: 12000,
+	})
+	return seasons, nil
+}
+
+// GetAllSeasonalRates returns all seasonal rates, for all rooms, as a slice of models.SeasonalRate
+func (m *testDBRepository) GetAllSeasonalRates(ctx context.Context) ([]models.SeasonalRate, error) {
+	var seasons []models.SeasonalRate
+	return seasons, nil
+}
+
+// InsertSeasonalRate inserts a seasonal rate for a given room
+func (m *testDBRepository) InsertSeasonalRate(ctx context.Context, season models.SeasonalRate) error {
+	return nil
+}
+
+// DeleteSeasonalRate deletes a seasonal rate by id
+func (m *testDBRepository) DeleteSeasonalRate(ctx context.Context, id int) error {
+	return nil
+}
+
+// GetStayRulesByRoomID returns all stay rules for a room which overlap a date range, as a slice of models.StayRule
+func (m *testDBRepository) GetStayRulesByRoomID(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.StayRule, error) {
+	var rules []models.StayRule
+	return rules, nil
+}
+
+// GetAllStayRules returns all stay rules, for all rooms, as a slice of models.StayRule
+func (m *testDBRepository) GetAllStayRules(ctx context.Context) ([]models.StayRule, error) {
+	var rules []models.StayRule
+	return rules, nil
+}
+
+// InsertStayRule inserts a stay rule for a given room
+func (m *testDBRepository) InsertStayRule(ctx context.Context, rule models.StayRule) error {
+	return nil
+}
+
+// DeleteStayRule deletes a stay rule by id
+func (m *testDBRepository) DeleteStayRule(ctx context.Context, id int) error {
+	return nil
+}
+
+// GetAllICalFeeds returns every iCal feed, by room, as a slice of models.ICalFeed
+func (m *testDBRepository) GetAllICalFeeds(ctx context.Context) ([]models.ICalFeed, error) {
+	var feeds []models.ICalFeed
+	feeds = append(feeds, models.ICalFeed{
+		ID:        1,
+		RoomID:    1,
+		Token:     "public-feed-token",
+		CreatedAt: time.Now().AddDate(0, -1, 0),
+		Room:      models.Room{ID: 1, RoomName: "General's Quarters"},
+	}, models.ICalFeed{
+		ID:        2,
+		RoomID:    1,
+		Token:     "private-feed-token",
+		Private:   true,
+		CreatedAt: time.Now().AddDate(0, -1, 0),
+		Room:      models.Room{ID: 1, RoomName: "General's Quarters"},
+	})
+	return feeds, nil
+}
+
+// GetICalFeedByToken returns the iCal feed with a given secret token, together with its room
+func (m *testDBRepository) GetICalFeedByToken(ctx context.Context, token string) (models.ICalFeed, error) {
+	feed := models.ICalFeed{
+		ID:     1,
+		RoomID: 1,
+		Token:  token,
+		Room:   models.Room{ID: 1, RoomName: "General's Quarters"},
+	}
+	switch token {
+	case "public-feed-token":
+		return feed, nil
+	case "private-feed-token":
+		feed.Private = true
+		return feed, nil
+	}
+	// otherwise, no such feed (or it has been revoked)
+	return models.ICalFeed{}, sql.ErrNoRows
+}
+
+// InsertICalFeed inserts a new iCal feed for a given room
+func (m *testDBRepository) InsertICalFeed(ctx context.Context, feed models.ICalFeed) error {
+	return nil
+}
+
+// DeleteICalFeed revokes an iCal feed by id, so that its URL no longer works
+func (m *testDBRepository) DeleteICalFeed(ctx context.Context, id int) error {
+	return nil
+}
+
+// GetAllICalImports returns every iCal import, by room, as a slice of models.ICalImport
+func (m *testDBRepository) GetAllICalImports(ctx context.Context) ([]models.ICalImport, error) {
+	var imports []models.ICalImport
+	imports = append(imports, models.ICalImport{
+		ID:           1,
+		RoomID:       1,
+		Name:         "Airbnb",
+		URL:          "http://127.0.0.1:0/airbnb.ics",
+		LastSyncedAt: time.Now().Add(-30 * time.Minute),
+		Room:         models.Room{ID: 1, RoomName: "General's Quarters"},
+	}, models.ICalImport{
+		ID:           2,
+		RoomID:       2,
+		Name:         "Booking.com",
+		URL:          "http://127.0.0.1:0/booking.ics",
+		LastSyncedAt: time.Now().Add(-30 * time.Minute),
+		LastError:    "calendar returned 404 Not Found",
+		Room:         models.Room{ID: 2, RoomName: "Major's Suite"},
+	})
+	return imports, nil
+}
+
+// InsertICalImport inserts a new iCal import of another site's calendar for a given room
+func (m *testDBRepository) InsertICalImport(ctx context.Context, imp models.ICalImport) error {
+	return nil
+}
+
+// DeleteICalImport removes an iCal import by id, together with the external bookings copied from its calendar
+func (m *testDBRepository) DeleteICalImport(ctx context.Context, id int) error {
+	return nil
+}
+
+// UpdateICalImportSync records when an iCal import last ran & why it failed, if it did
+func (m *testDBRepository) UpdateICalImportSync(ctx context.Context, id int, syncedAt time.Time, syncErr string) error {
+	return nil
+}
+
+// GetExternalBookings returns the external bookings copied from an iCal import's calendar as a slice of models.RoomRestriction
+func (m *testDBRepository) GetExternalBookings(ctx context.Context, importID int) ([]models.RoomRestriction, error) {
+	var bookings []models.RoomRestriction
+	// one booking still in the calendar, one since removed from it
+	bookings = append(bookings, models.RoomRestriction{
+		ID:            11,
+		RoomID:        1,
+		RestrictionID: models.RestrictionExternalBooking,
+		StartDate:     time.Date(2098, 3, 1, 0, 0, 0, 0, time.Local),
+		EndDate:       time.Date(2098, 3, 2, 0, 0, 0, 0, time.Local),
+		ImportID:      importID,
+		ExternalUID:   "kept@airbnb.com",
+	}, models.RoomRestriction{
+		ID:            12,
+		RoomID:        1,
+		RestrictionID: models.RestrictionExternalBooking,
+		StartDate:     time.Date(2098, 4, 1, 0, 0, 0, 0, time.Local),
+		EndDate:       time.Date(2098, 4, 2, 0, 0, 0, 0, time.Local),
+		ImportID:      importID,
+		ExternalUID:   "removed@airbnb.com",
+	})
+	return bookings, nil
+}
+
+// SyncExternalBookings brings an iCal import's external bookings up to date with its calendar within one transaction, inserting
+// bookings new to the calendar, moving those whose dates have changed & deleting, by id, those no longer in it
+func (m *testDBRepository) SyncExternalBookings(ctx context.Context, importID int, add, update []models.RoomRestriction, remove []int) error {
+	return nil
+}
+
+// GetBookingConflicts returns every external booking, ending on or after a given date, which overlaps one of our own reservations
+// for the same room, with its import & the reservation, ordered by start date as a slice of models.BookingConflict
+func (m *testDBRepository) GetBookingConflicts(ctx context.Context, from time.Time) ([]models.BookingConflict, error) {
+	var conflicts []models.BookingConflict
+	room := models.Room{ID: 1, RoomName: "General's Quarters"}
+	conflicts = append(conflicts, models.BookingConflict{
+		Import: models.ICalImport{ID: 1, RoomID: 1, Name: "Airbnb", Room: room},
+		Booking: models.RoomRestriction{
+			ID:            11,
+			RoomID:        1,
+			RestrictionID: models.RestrictionExternalBooking,
+			StartDate:     time.Date(2098, 3, 1, 0, 0, 0, 0, time.Local),
+			EndDate:       time.Date(2098, 3, 2, 0, 0, 0, 0, time.Local),
+			ImportID:      1,
+			ExternalUID:   "kept@airbnb.com",
+			Room:          room,
+		},
+		Reservation: models.Reservation{
+			ID:              1,
+			FirstName:       "Joe",
+			LastName:        "Soap",
+			StartDate:       time.Date(2098, 3, 2, 0, 0, 0, 0, time.Local),
+			EndDate:         time.Date(2098, 3, 4, 0, 0, 0, 0, time.Local),
+			Status:          models.StatusConfirmed,
+			ConfirmationRef: "K7QX2MZP4D",
+		},
+	})
+	return conflicts, nil
+}
+
+// GetAllWebhooks returns every webhook, oldest first, as a slice of models.Webhook
+func (m *testDBRepository) GetAllWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	var hooks []models.Webhook
+	hooks = append(hooks, models.Webhook{
+		ID:        1,
+		URL:       "https://cleaning.example.com/hooks/bnb",
+		Secret:    "whsec_cleaning",
+		Events:    []string{"reservation.created", "reservation.status_changed", "reservation.deleted"},
+		CreatedAt: time.Now().AddDate(0, -1, 0),
+	}, models.Webhook{
+		ID:        2,
+		URL:       "https://hooks.zapier.example/catch/123",
+		Secret:    "whsec_zapier",
+		CreatedAt: time.Now().AddDate(0, -1, 0),
+	})
+	return hooks, nil
+}
+
+// InsertWebhook inserts a new webhook
+func (m *testDBRepository) InsertWebhook(ctx context.Context, hook models.Webhook) error {
+	return nil
+}
+
+// DeleteWebhook deletes a webhook by id, together with its deliveries
+func (m *testDBRepository) DeleteWebhook(ctx context.Context, id int) error {
+	return nil
+}
+
+// InsertWebhookDeliveries queues deliveries of an event to webhooks, to be attempted straight away, within one transaction
+func (m *testDBRepository) InsertWebhookDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	return nil
+}
+
+// GetDueWebhookDeliveries returns up to limit pending deliveries whose next attempt is due, oldest first, as a slice of
+// models.WebhookDelivery
+func (m *testDBRepository) GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	// a webhook which cannot be reached, so that the delivery fails straight away
+	deliveries = append(deliveries, models.WebhookDelivery{
+		ID:            1,
+		WebhookID:     3,
+		Event:         "block.added",
+		Payload:       `{"id":"evt_1","event":"block.added","created_at":"2026-10-18T09:30:00Z","data":{"room_id":1}}`,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: now,
+		Webhook:       models.Webhook{ID: 3, URL: "http://127.0.0.1:0/hook", Secret: "whsec_unreachable"},
+	})
+	return deliveries, nil
+}
+
+// UpdateWebhookDelivery records the outcome of an attempt at a delivery
+func (m *testDBRepository) UpdateWebhookDelivery(ctx context.Context, d models.WebhookDelivery) error {
+	return nil
+}
+
+// GetWebhookDeliveries returns up to limit deliveries, most recent first, for the delivery log as a slice of models.WebhookDelivery
+func (m *testDBRepository) GetWebhookDeliveries(ctx context.Context, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	hook := models.Webhook{ID: 2, URL: "https://hooks.zapier.example/catch/123"}
+	deliveries = append(deliveries, models.WebhookDelivery{
+		ID:           3,
+		WebhookID:    2,
+		Event:        "reservation.created",
+		Status:       models.DeliveryDelivered,
+		Attempts:     1,
+		ResponseCode: 200,
+		DeliveredAt:  time.Now(),
+		CreatedAt:    time.Now(),
+		Webhook:      hook,
+	}, models.WebhookDelivery{
+		ID:            2,
+		WebhookID:     2,
+		Event:         "reservation.updated",
+		Status:        models.DeliveryPending,
+		Attempts:      2,
+		ResponseCode:  503,
+		LastError:     "webhook returned 503 Service Unavailable",
+		NextAttemptAt: time.Now().Add(4 * time.Minute),
+		CreatedAt:     time.Now().Add(-5 * time.Minute),
+		Webhook:       hook,
+	}, models.WebhookDelivery{
+		ID:        1,
+		WebhookID: 2,
+		Event:     "block.removed",
+		Status:    models.DeliveryFailed,
+		Attempts:  8,
+		LastError: "dial tcp: connection refused",
+		CreatedAt: time.Now().AddDate(0, 0, -1),
+		Webhook:   hook,
+	})
+	return deliveries, nil
+}
+
+// RetryWebhookDelivery queues a delivery which has failed to be attempted again straight away
+func (m *testDBRepository) RetryWebhookDelivery(ctx context.Context, id int) error {
+	return nil
+}
+
+// GetChannelRooms returns every room mapped to a room type at the channel manager, ordered by room name, as a slice of
+// models.ChannelRoom
+func (m *testDBRepository) GetChannelRooms(ctx context.Context) ([]models.ChannelRoom, error) {
+	var rooms []models.ChannelRoom
+	rooms = append(rooms, models.ChannelRoom{
+		RoomID:       1,
+		RoomCode:     "GQ",
+		RatePlanCode: "BAR",
+		Room:         models.Room{ID: 1, RoomName: "General's Quarters"},
+	}, models.ChannelRoom{
+		RoomID:   2,
+		RoomCode: "MS",
+		Room:     models.Room{ID: 2, RoomName: "Major's Suite"},
+	})
+	return rooms, nil
+}
+
+// SaveChannelRooms replaces the mapping of rooms to room types at the channel manager
+func (m *testDBRepository) SaveChannelRooms(ctx context.Context, rooms []models.ChannelRoom) error {
+	return nil
+}
+
+// GetChannelDays returns what was last pushed to the channel manager for a room's nights from startDate to endDate inclusive. Room 1
+// has had its availability, but not its rates, pushed for 1st to 8th March 2098
+func (m *testDBRepository) GetChannelDays(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.ChannelDay, error) {
+	var days []models.ChannelDay
+	if roomID != 1 {
+		return days, nil
+	}
+	for d := 1; d <= 8; d++ {
+		days = append(days, models.ChannelDay{Date: time.Date(2098, 3, d, 0, 0, 0, 0, time.UTC), Open: true})
+	}
+	return days, nil
+}
+
+// SaveChannelDays records what has been pushed to the channel manager for a room's nights
+func (m *testDBRepository) SaveChannelDays(ctx context.Context, roomID int, days []models.ChannelDay) error {
+	return nil
+}
+
+// ResetChannelDays forgets what has been pushed to the channel manager
+func (m *testDBRepository) ResetChannelDays(ctx context.Context) error {
+	return nil
+}
+
+// InsertChannelSync records a message pushed to the channel manager in the sync log
+func (m *testDBRepository) InsertChannelSync(ctx context.Context, s models.ChannelSync) error {
+	return nil
+}
+
+// GetChannelSyncs returns up to limit messages pushed to the channel manager, most recent first, for the sync log
+func (m *testDBRepository) GetChannelSyncs(ctx context.Context, limit int) ([]models.ChannelSync, error) {
+	var syncs []models.ChannelSync
+	room := models.Room{ID: 1, RoomName: "General's Quarters"}
+	syncs = append(syncs, models.ChannelSync{
+		ID:           2,
+		RoomID:       1,
+		Message:      "OTA_HotelRateAmountNotifRQ",
+		StartDate:    time.Now(),
+		EndDate:      time.Now().AddDate(0, 0, 364),
+		Changes:      104,
+		Status:       models.DeliveryFailed,
+		Attempts:     3,
+		ResponseCode: 503,
+		LastError:    "channel manager returned 503 Service Unavailable",
+		CreatedAt:    time.Now(),
+		Room:         room,
+	}, models.ChannelSync{
+		ID:           1,
+		RoomID:       1,
+		Message:      "OTA_HotelAvailNotifRQ",
+		StartDate:    time.Now(),
+		EndDate:      time.Now().AddDate(0, 0, 364),
+		Changes:      3,
+		Status:       models.DeliveryDelivered,
+		Attempts:     1,
+		ResponseCode: 200,
+		Request:      "<OTA_HotelAvailNotifRQ/>",
+		CreatedAt:    time.Now().Add(-time.Hour),
+		Room:         room,
+	})
+	return syncs, nil
+}
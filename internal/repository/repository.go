@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/StratoNET/bnb-bookings/internal/models"
@@ -9,25 +12,145 @@ import (
 type DatabaseRepository interface {
 	AllAdministrators() bool
 
-	InsertReservation(rsvn models.Reservation) (int64, error)
-	InsertRoomRestriction(rest models.RoomRestriction) error
-	SearchAvailabilityByDatesAndRoomID(start, end time.Time, roomID int) (bool, error)
-	SearchAvailabilityForAllRooms(start, end time.Time) ([]models.Room, error)
-	GetRoomByID(id int) (models.Room, error)
-
-	GetAdministratorByID(id int) (models.Administrator, error)
-	UpdateAdministrator(admin models.Administrator) error
-	AuthenticateAdministrator(email, password string) (int, string, error)
-
-	GetAllRooms() ([]models.Room, error)
-
-	GetAllReservations() ([]models.Reservation, error)
-	GetNewReservations() ([]models.Reservation, error)
-	GetReservationByID(id int) (models.Reservation, error)
-	UpdateReservation(admin models.Reservation) error
-	DeleteReservation(id int) error
-	UpdateReservationProcessed(id int, processed uint8) error
-	GetRoomRestrictionsByDate(roomID int, startDate, endDate time.Time) ([]models.RoomRestriction, error)
-	InsertRoomBlock(roomID int, startDate, endDate time.Time) error
-	DeleteRoomBlock(id int) error
+	InsertReservation(ctx context.Context, rsvn models.Reservation) (int64, error)
+	InsertRoomRestriction(ctx context.Context, rest models.RoomRestriction) error
+	CreateReservation(ctx context.Context, rsvn models.Reservation, holdToken string) (int64, error)
+	CreateBookingGroup(ctx context.Context, group models.BookingGroup, holdToken string) (int64, error)
+	ImportReservations(ctx context.Context, reservations []models.Reservation) ([]int64, error)
+	PlaceHold(ctx context.Context, holds ...models.RoomRestriction) error
+	ReleaseHold(ctx context.Context, holdToken string) error
+	ReleaseExpiredHolds(ctx context.Context, now time.Time) (int64, error)
+	SearchAvailabilityByDatesAndRoomID(ctx context.Context, start, end time.Time, roomID int, holdToken string) (bool, error)
+	SearchAvailabilityForAllRooms(ctx context.Context, start, end time.Time, adults, children, infants int, holdToken string) ([]models.Room, error)
+	GetRoomByID(ctx context.Context, id int) (models.Room, error)
+
+	GetAdministratorByID(ctx context.Context, id int) (models.Administrator, error)
+	UpdateAdministrator(ctx context.Context, admin models.Administrator) error
+	AuthenticateAdministrator(ctx context.Context, email, password string) (int, string, error)
+	GetAdministratorByAPIToken(ctx context.Context, tokenHash string) (models.Administrator, error)
+	GetAPITokensByAdministratorID(ctx context.Context, adminID int) ([]models.APIToken, error)
+	InsertAPIToken(ctx context.Context, token models.APIToken) error
+	DeleteAPIToken(ctx context.Context, id, adminID int) error
+
+	GetAllRooms(ctx context.Context) ([]models.Room, error)
+	UpdateRoomRates(ctx context.Context, room models.Room) error
+	UpdateRoomOccupancy(ctx context.Context, room models.Room) error
+
+	GetSeasonalRatesByRoomID(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.SeasonalRate, error)
+	GetAllSeasonalRates(ctx context.Context) ([]models.SeasonalRate, error)
+	InsertSeasonalRate(ctx context.Context, season models.SeasonalRate) error
+	DeleteSeasonalRate(ctx context.Context, id int) error
+
+	GetStayRulesByRoomID(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.StayRule, error)
+	GetAllStayRules(ctx context.Context) ([]models.StayRule, error)
+	InsertStayRule(ctx context.Context, rule models.StayRule) error
+	DeleteStayRule(ctx context.Context, id int) error
+
+	GetAllReservations(ctx context.Context) ([]models.Reservation, error)
+	GetNewReservations(ctx context.Context) ([]models.Reservation, error)
+	FilterReservations(ctx context.Context, filter models.ReservationFilter) ([]models.Reservation, error)
+	StreamReservations(ctx context.Context, filter models.ReservationFilter, fn func(models.Reservation) error) error
+	GetReservationByID(ctx context.Context, id int) (models.Reservation, error)
+	GetReservationByRef(ctx context.Context, ref string) (models.Reservation, error)
+	UpdateReservation(ctx context.Context, admin models.Reservation) error
+	DeleteReservation(ctx context.Context, id int) error
+	GetDeletedReservations(ctx context.Context) ([]models.Reservation, error)
+	RestoreReservation(ctx context.Context, id int) error
+	GetReservationsByStatus(ctx context.Context, status models.ReservationStatus) ([]models.Reservation, error)
+	UpdateReservationStatus(ctx context.Context, id int, from, to models.ReservationStatus) error
+	GetReservationStatusChanges(ctx context.Context, id int) ([]models.StatusChange, error)
+	GetAllBookingGroups(ctx context.Context) ([]models.BookingGroup, error)
+	GetBookingGroupByID(ctx context.Context, id int) (models.BookingGroup, error)
+	UpdateBookingGroupStatus(ctx context.Context, id int, to models.ReservationStatus) error
+	DeleteBookingGroup(ctx context.Context, id int) error
+	InsertWaitlistEntry(ctx context.Context, entry models.WaitlistEntry) error
+	GetWaitlist(ctx context.Context) ([]models.WaitlistEntry, error)
+	GetWaitlistByDates(ctx context.Context, startDate, endDate time.Time) ([]models.WaitlistEntry, error)
+	MarkWaitlistNotified(ctx context.Context, id int) error
+	DeleteWaitlistEntry(ctx context.Context, id int) error
+	GetRoomRestrictionsByDate(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.RoomRestriction, error)
+	GetRoomRestrictionsForFeed(ctx context.Context, roomID int, from time.Time) ([]models.RoomRestriction, error)
+	GetRoomRestrictionsForCalendar(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.RoomRestriction, error)
+	InsertRoomBlock(ctx context.Context, roomID int, startDate, endDate time.Time) error
+	CreateRoomBlock(ctx context.Context, roomID int, startDate, endDate time.Time) (int64, error)
+	GetRoomBlockByID(ctx context.Context, id int) (models.RoomRestriction, error)
+	DeleteRoomBlock(ctx context.Context, id int) error
+	GetDeletedRoomBlocks(ctx context.Context) ([]models.RoomRestriction, error)
+	RestoreRoomBlock(ctx context.Context, id int) error
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+
+	GetAllICalFeeds(ctx context.Context) ([]models.ICalFeed, error)
+	GetICalFeedByToken(ctx context.Context, token string) (models.ICalFeed, error)
+	InsertICalFeed(ctx context.Context, feed models.ICalFeed) error
+	DeleteICalFeed(ctx context.Context, id int) error
+
+	GetAllICalImports(ctx context.Context) ([]models.ICalImport, error)
+	InsertICalImport(ctx context.Context, imp models.ICalImport) error
+	DeleteICalImport(ctx context.Context, id int) error
+	UpdateICalImportSync(ctx context.Context, id int, syncedAt time.Time, syncErr string) error
+	GetExternalBookings(ctx context.Context, importID int) ([]models.RoomRestriction, error)
+	SyncExternalBookings(ctx context.Context, importID int, add, update []models.RoomRestriction, remove []int) error
+	GetBookingConflicts(ctx context.Context, from time.Time) ([]models.BookingConflict, error)
+
+	GetAllWebhooks(ctx context.Context) ([]models.Webhook, error)
+	InsertWebhook(ctx context.Context, hook models.Webhook) error
+	DeleteWebhook(ctx context.Context, id int) error
+	InsertWebhookDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, d models.WebhookDelivery) error
+	GetWebhookDeliveries(ctx context.Context, limit int) ([]models.WebhookDelivery, error)
+	RetryWebhookDelivery(ctx context.Context, id int) error
+
+	GetChannelRooms(ctx context.Context) ([]models.ChannelRoom, error)
+	SaveChannelRooms(ctx context.Context, rooms []models.ChannelRoom) error
+	GetChannelDays(ctx context.Context, roomID int, startDate, endDate time.Time) ([]models.ChannelDay, error)
+	SaveChannelDays(ctx context.Context, roomID int, days []models.ChannelDay) error
+	ResetChannelDays(ctx context.Context) error
+	InsertChannelSync(ctx context.Context, s models.ChannelSync) error
+	GetChannelSyncs(ctx context.Context, limit int) ([]models.ChannelSync, error)
+}
+
+// RoomUnavailableError is returned when a room has been taken, for some or all of the requested dates, by the time a reservation is committed
+type RoomUnavailableError struct {
+	RoomID    int
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+func (e *RoomUnavailableError) Error() string {
+	return fmt.Sprintf("room %d is no longer available from %s to %s", e.RoomID, e.StartDate.Format("02/01/2006"), e.EndDate.Format("02/01/2006"))
+}
+
+// StatusChangedError is returned when a reservation's status has been changed by someone else since it was read, so is no longer as expected
+type StatusChangedError struct {
+	ReservationID int
+	Expected      models.ReservationStatus
+}
+
+func (e *StatusChangedError) Error() string {
+	return fmt.Sprintf("reservation %d is no longer %s", e.ReservationID, e.Expected.Label())
+}
+
+// InterruptedError is returned when a database operation is abandoned before it completes, either because it was cancelled, e.g. by the
+// client disconnecting, or because it ran out of time. Err is context.Canceled or context.DeadlineExceeded, so errors.Is tells which, &
+// Cause is whatever the database driver returned on being interrupted
+type InterruptedError struct {
+	Err   error
+	Cause error
+}
+
+func (e *InterruptedError) Error() string {
+	if e.Timeout() {
+		return fmt.Sprintf("database operation timed out: %v", e.Cause)
+	}
+	return fmt.Sprintf("database operation cancelled: %v", e.Cause)
+}
+
+func (e *InterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the operation ran out of time, rather than being cancelled
+func (e *InterruptedError) Timeout() bool {
+	return errors.Is(e.Err, context.DeadlineExceeded)
 }
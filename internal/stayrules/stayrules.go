@@ -0,0 +1,60 @@
+package stayrules
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/models"
+)
+
+// ErrEndBeforeStart is returned for any stay departing before it arrives
+var ErrEndBeforeStart = errors.New("departure date cannot be before arrival date")
+
+// Violation is returned when a stay breaks a room's stay rule
+type Violation struct {
+	Rule   models.StayRule
+	Nights int
+}
+
+func (v *Violation) Error() string {
+	if v.Nights < v.Rule.MinNights {
+		return fmt.Sprintf("%s: stays must be at least %d nights, %d requested", v.Rule.RuleName, v.Rule.MinNights, v.Nights)
+	}
+	return fmt.Sprintf("%s: stays can be no more than %d nights, %d requested", v.Rule.RuleName, v.Rule.MaxNights, v.Nights)
+}
+
+// Nights counts the nights of a stay, from arrival (start) up to departure (end), a same day arrival & departure counting as a
+// single night in keeping with pricing
+func Nights(start, end time.Time) int {
+	n := int(models.DateOnly(end).Sub(models.DateOnly(start)).Hours()) / 24
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// Check tests a stay against a room's stay rules, a rule applies whenever any night of the stay falls within its dates. The first
+// rule broken is returned as a *Violation, or ErrEndBeforeStart if the dates themselves are invalid.
+func Check(rules []models.StayRule, start, end time.Time) error {
+	start = models.DateOnly(start)
+	end = models.DateOnly(end)
+
+	if end.Before(start) {
+		return ErrEndBeforeStart
+	}
+
+	nights := Nights(start, end)
+	lastNight := start.AddDate(0, 0, nights-1)
+
+	for _, rule := range rules {
+		if lastNight.Before(models.DateOnly(rule.StartDate)) || start.After(models.DateOnly(rule.EndDate)) {
+			continue
+		}
+		if nights < rule.MinNights || (rule.MaxNights > 0 && nights > rule.MaxNights) {
+			return &Violation{Rule: rule, Nights: nights}
+		}
+	}
+
+	return nil
+}
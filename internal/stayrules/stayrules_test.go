@@ -0,0 +1,98 @@
+package stayrules
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/StratoNET/bnb-bookings/internal/models"
+)
+
+var testRules = []models.StayRule{
+	{
+		ID:        1,
+		RoomID:    1,
+		RuleName:  "Bank Holiday Weekend",
+		StartDate: date("28/08/2026"),
+		EndDate:   date("30/08/2026"),
+		MinNights: 3,
+	},
+	{
+		ID:        2,
+		RoomID:    1,
+		RuleName:  "Maximum Stay",
+		StartDate: date("01/01/2026"),
+		EndDate:   date("31/12/2026"),
+		MinNights: 1,
+		MaxNights: 14,
+	},
+}
+
+func date(s string) time.Time {
+	d, _ := time.Parse("02/01/2006", s)
+	return d
+}
+
+var checkTests = []struct {
+	name             string
+	start, end       string
+	expectedRuleName string
+	expectedError    error
+}{
+	{"no-rule-broken", "02/11/2026", "05/11/2026", "", nil},
+	{"same-day", "02/11/2026", "02/11/2026", "", nil},
+	{"below-minimum", "28/08/2026", "30/08/2026", "Bank Holiday Weekend", nil},
+	{"minimum-met", "28/08/2026", "31/08/2026", "", nil},
+	// arriving on the rule's last night still counts
+	{"overlapping-minimum", "30/08/2026", "01/09/2026", "Bank Holiday Weekend", nil},
+	// departing on the rule's first day does not
+	{"departing-into-rule", "26/08/2026", "28/08/2026", "", nil},
+	{"above-maximum", "01/10/2026", "16/10/2026", "Maximum Stay", nil},
+	{"maximum-met", "01/10/2026", "15/10/2026", "", nil},
+	{"end-before-start", "05/11/2026", "02/11/2026", "", ErrEndBeforeStart},
+}
+
+func TestCheck(t *testing.T) {
+	for _, v := range checkTests {
+		err := Check(testRules, date(v.start), date(v.end))
+
+		if v.expectedError != nil {
+			if !errors.Is(err, v.expectedError) {
+				t.Errorf("%s: expected error %v, got %v", v.name, v.expectedError, err)
+			}
+			continue
+		}
+
+		var violation *Violation
+		if errors.As(err, &violation) {
+			if violation.Rule.RuleName != v.expectedRuleName {
+				t.Errorf("%s: expected rule %q to fail, got %q", v.name, v.expectedRuleName, violation.Rule.RuleName)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error %v", v.name, err)
+		} else if v.expectedRuleName != "" {
+			t.Errorf("%s: expected rule %q to fail but stay was allowed", v.name, v.expectedRuleName)
+		}
+	}
+}
+
+func TestNights(t *testing.T) {
+	if n := Nights(date("02/11/2026"), date("05/11/2026")); n != 3 {
+		t.Errorf("expected 3 nights, got %d", n)
+	}
+	if n := Nights(date("02/11/2026"), date("02/11/2026")); n != 1 {
+		t.Errorf("expected same day stay to count as 1 night, got %d", n)
+	}
+}
+
+func TestViolation_Error(t *testing.T) {
+	v := Violation{Rule: testRules[0], Nights: 2}
+	if v.Error() != "Bank Holiday Weekend: stays must be at least 3 nights, 2 requested" {
+		t.Errorf("unexpected minimum violation message: %s", v.Error())
+	}
+
+	v = Violation{Rule: testRules[1], Nights: 15}
+	if v.Error() != "Maximum Stay: stays can be no more than 14 nights, 15 requested" {
+		t.Errorf("unexpected maximum violation message: %s", v.Error())
+	}
+}
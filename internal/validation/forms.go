@@ -3,6 +3,7 @@ package forms
 import (
 	"fmt"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/asaskevich/govalidator"
@@ -66,3 +67,23 @@ func (f *Form) IsEmail(field string) {
 	}
 
 }
+
+// IsNumberInRange checks field is a whole number between min & max (inclusive)
+func (f *Form) IsNumberInRange(field string, min, max int) bool {
+	n, err := strconv.Atoi(strings.TrimSpace(f.Get(field)))
+	if err != nil || n < min || n > max {
+		f.Errors.AddErrMsg(field, fmt.Sprintf("this field must be a whole number from %d to %d", min, max))
+		return false
+	}
+	return true
+}
+
+// IsWebURL checks field is an absolute http or https URL e.g. https://www.example.com/calendar.ics
+func (f *Form) IsWebURL(field string) bool {
+	u, err := url.ParseRequestURI(strings.TrimSpace(f.Get(field)))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		f.Errors.AddErrMsg(field, "please input a valid web address e.g. https://www.example.com/calendar.ics")
+		return false
+	}
+	return true
+}
@@ -145,3 +145,59 @@ func TestForm_IsEmail(t *testing.T) {
 	}
 
 }
+
+func TestForm_IsNumberInRange(t *testing.T) {
+	postedData := url.Values{}
+	postedData.Add("adults", "2")
+	postedData.Add("children", "-1")
+	postedData.Add("infants", "none")
+
+	form := NewForm(postedData)
+
+	if !form.IsNumberInRange("adults", 1, 10) {
+		t.Error("gave out of range when number is actually within range")
+	}
+
+	if form.IsNumberInRange("children", 0, 10) {
+		t.Error("gave within range when number is actually below minimum")
+	}
+
+	if form.IsNumberInRange("infants", 0, 10) {
+		t.Error("gave within range when field is not a number")
+	}
+
+	if form.IsNumberInRange("missing", 0, 10) {
+		t.Error("gave within range for non-existent field")
+	}
+
+	if form.ValidForm() {
+		t.Error("form shows valid when numbers are out of range")
+	}
+}
+
+func TestForm_IsWebURL(t *testing.T) {
+	postedData := url.Values{}
+	postedData.Add("https", "https://www.airbnb.co.uk/calendar/ical/123.ics?s=abc")
+	postedData.Add("http", " http://localhost:8080/ical/token.ics ")
+	postedData.Add("webcal", "webcal://example.com/calendar.ics")
+	postedData.Add("relative", "/calendar.ics")
+	postedData.Add("no-host", "https:///calendar.ics")
+
+	form := NewForm(postedData)
+
+	for _, field := range []string{"https", "http"} {
+		if !form.IsWebURL(field) {
+			t.Errorf("gave invalid for %s when it is actually a valid web address", field)
+		}
+	}
+
+	for _, field := range []string{"webcal", "relative", "no-host", "missing"} {
+		if form.IsWebURL(field) {
+			t.Errorf("gave valid for %s when it is not a web address", field)
+		}
+	}
+
+	if form.ValidForm() {
+		t.Error("form shows valid when web addresses are invalid")
+	}
+}
@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// events posted to webhooks, as named in each payload & its Event header
+const (
+	ReservationCreated       = "reservation.created"
+	ReservationUpdated       = "reservation.updated"
+	ReservationStatusChanged = "reservation.status_changed"
+	ReservationDeleted       = "reservation.deleted"
+	BlockAdded               = "block.added"
+	BlockRemoved             = "block.removed"
+)
+
+// Events returns every event, in the order they are offered to administrators
+func Events() []string {
+	return []string{ReservationCreated, ReservationUpdated, ReservationStatusChanged, ReservationDeleted, BlockAdded, BlockRemoved}
+}
+
+// headers given with every delivery, so that a receiver can check its signature, route it by event & recognise a retried delivery
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// MaxAttempts is how many times a delivery is attempted before it is given up as failed
+const MaxAttempts = 8
+
+// firstRetry & maxRetry bound the delay before retrying a failed delivery, which doubles with each attempt
+const (
+	firstRetry = time.Minute
+	maxRetry   = 6 * time.Hour
+)
+
+// secretPrefix marks a string as a webhook secret, so one pasted into a log is easily recognised
+const secretPrefix = "whsec_"
+
+// Payload is the JSON body posted to a webhook. ID identifies the event, so is the same for every webhook it is posted to
+type Payload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// NewSecret creates a random secret with which a webhook's deliveries are signed
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// NewEventID creates a random id for an event e.g. evt_3f9c...
+func NewEventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "evt_" + hex.EncodeToString(b), nil
+}
+
+// Sign gives the signature header value for a body sent at a given time e.g. "t=1793000000,v1=5257a8...". The signature is the
+// hex encoded HMAC-SHA256, keyed by the webhook's secret, of the unix time, a "." & the body, so a captured delivery cannot be replayed
+// later with a new time
+func Sign(secret string, at time.Time, body []byte) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, signature(secret, ts, body))
+}
+
+func signature(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header value, as a receiver would, rejecting any signed more than tolerance away from now
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			sig = kv[1]
+		}
+	}
+	if ts == "" || sig == "" {
+		return errors.New("webhook: signature header is malformed")
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("webhook: signature time is malformed")
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+		return errors.New("webhook: signature time is outside the tolerance")
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, ts, body))) {
+		return errors.New("webhook: signature does not match")
+	}
+	return nil
+}
+
+// Backoff is how long to wait before the next attempt at a delivery which has failed a given number of times
+func Backoff(attempts int) time.Duration {
+	d := firstRetry
+	for i := 1; i < attempts && d < maxRetry; i++ {
+		d *= 2
+	}
+	if d > maxRetry {
+		d = maxRetry
+	}
+	return d
+}
+
+// Post delivers a signed body to a webhook's URL, returning the response's status code, abandoning the delivery once ctx is done. Any
+// response other than 2xx is an error
+func Post(ctx context.Context, client *http.Client, url, secret, event string, deliveryID int, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(deliveryID))
+	req.Header.Set(SignatureHeader, Sign(secret, time.Now(), body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// read a little of the body, so that the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
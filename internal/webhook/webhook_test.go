@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign_Verify(t *testing.T) {
+	body := []byte(`{"event":"reservation.created"}`)
+	now := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+	header := Sign("whsec_a", now, body)
+
+	if !strings.HasPrefix(header, "t=1792315800,v1=") {
+		t.Errorf("unexpected signature header %q", header)
+	}
+	if err := Verify("whsec_a", header, body, now.Add(time.Minute), 5*time.Minute); err != nil {
+		t.Errorf("expected signature to verify, got %v", err)
+	}
+
+	failures := map[string]error{
+		"wrong-secret": Verify("whsec_b", header, body, now, 5*time.Minute),
+		"changed-body": Verify("whsec_a", header, []byte(`{"event":"reservation.deleted"}`), now, 5*time.Minute),
+		"replayed":     Verify("whsec_a", header, body, now.Add(time.Hour), 5*time.Minute),
+		"changed-time": Verify("whsec_a", strings.Replace(header, "t=1792315800", "t=1792315801", 1), body, now, 5*time.Minute),
+		"malformed":    Verify("whsec_a", "v1=abc", body, now, 5*time.Minute),
+	}
+	for name, err := range failures {
+		if err == nil {
+			t.Errorf("%s: expected signature not to verify", name)
+		}
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := NewSecret()
+	if err != nil {
+		t.Fatal("NewSecret failed", err)
+	}
+	b, _ := NewSecret()
+	if !strings.HasPrefix(a, secretPrefix) || len(a) != len(secretPrefix)+64 || a == b {
+		t.Errorf("unexpected secrets %q & %q", a, b)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	expected := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute}
+	for i, d := range expected {
+		if Backoff(i+1) != d {
+			t.Errorf("attempt %d: expected %s, got %s", i+1, d, Backoff(i+1))
+		}
+	}
+	if Backoff(100) != maxRetry {
+		t.Errorf("expected backoff to be capped at %s, got %s", maxRetry, Backoff(100))
+	}
+}
+
+func TestPost(t *testing.T) {
+	var gotEvent, gotDelivery, gotSignature, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		gotEvent = r.Header.Get(EventHeader)
+		gotDelivery = r.Header.Get(DeliveryHeader)
+		gotSignature = r.Header.Get(SignatureHeader)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	body := []byte(`{"id":"evt_1"}`)
+	code, err := Post(context.Background(), srv.Client(), srv.URL+"/hook", "whsec_a", BlockAdded, 42, body)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("expected delivery to succeed, got %d %v", code, err)
+	}
+	if gotEvent != BlockAdded || gotDelivery != "42" || gotBody != string(body) {
+		t.Errorf("unexpected delivery: event %q, delivery %q, body %q", gotEvent, gotDelivery, gotBody)
+	}
+	if err := Verify("whsec_a", gotSignature, []byte(gotBody), time.Now(), time.Minute); err != nil {
+		t.Errorf("expected delivery's signature to verify, got %v", err)
+	}
+
+	code, err = Post(context.Background(), srv.Client(), srv.URL+"/fail", "whsec_a", BlockAdded, 42, body)
+	if err == nil || code != http.StatusServiceUnavailable {
+		t.Errorf("expected a 503 response to fail, got %d %v", code, err)
+	}
+
+	_, err = Post(context.Background(), srv.Client(), "http://127.0.0.1:0/hook", "whsec_a", BlockAdded, 42, body)
+	if err == nil {
+		t.Error("expected an unreachable webhook to fail")
+	}
+
+	// a delivery whose context is done is abandoned before it is posted
+	gotBody = ""
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Post(ctx, srv.Client(), srv.URL+"/hook", "whsec_a", BlockAdded, 42, body)
+	if !errors.Is(err, context.Canceled) || gotBody != "" {
+		t.Errorf("expected a cancelled delivery to be abandoned, got %v with body %q posted", err, gotBody)
+	}
+}
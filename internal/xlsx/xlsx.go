@@ -0,0 +1,199 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// cell styles, as indexes into cellXfs of styles.xml
+const (
+	styleNone    = 0
+	styleHeading = 1
+	styleDate    = 2
+	styleAmount  = 3
+)
+
+// epoch is day 0 of spreadsheet dates, chosen by Excel so that 1900 may be (wrongly) a leap year & still count days correctly from
+// 1 March 1900
+var epoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// Amount is an amount of money, shown to 2 decimal places e.g. 80.5 as 80.50
+type Amount float64
+
+// Writer writes a workbook of a single sheet, in Office Open XML (.xlsx) format, a row at a time. Nothing is held once a row has
+// been written, so a sheet of any length can be streamed straight to a client
+type Writer struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+// NewWriter begins a workbook written to w, whose one sheet has the given name
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	zw := zip.NewWriter(w)
+
+	workbook := fmt.Sprintf(workbookXML, xmlEscape(sheetName))
+	for _, part := range []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/workbook.xml", workbook},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+		{"xl/styles.xml", stylesXML},
+	} {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	// the sheet is written last, as its rows are not known until they are written
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	sheet.WriteString(xml.Header)
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	return &Writer{zw: zw, sheet: sheet}, nil
+}
+
+// WriteHeading writes a row of column headings, shown in bold
+func (w *Writer) WriteHeading(headings []string) error {
+	values := make([]interface{}, len(headings))
+	for i, h := range headings {
+		values[i] = h
+	}
+	return w.writeRow(values, true)
+}
+
+// WriteRow writes a row of values, each a string, int, float64, Amount or time.Time (written as a date, or left empty if zero)
+func (w *Writer) WriteRow(values []interface{}) error {
+	return w.writeRow(values, false)
+}
+
+func (w *Writer) writeRow(values []interface{}, heading bool) error {
+	// the row is built before it is written, so that a value which cannot be written leaves the sheet as it was
+	n := w.rows + 1
+	var row bytes.Buffer
+	fmt.Fprintf(&row, `<row r="%d">`, n)
+	for i, v := range values {
+		ref := ColumnName(i) + strconv.Itoa(n)
+		style := styleNone
+		if heading {
+			style = styleHeading
+		}
+
+		switch v := v.(type) {
+		case string:
+			fmt.Fprintf(&row, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, xmlEscape(v))
+		case int:
+			fmt.Fprintf(&row, `<c r="%s" s="%d"><v>%d</v></c>`, ref, style, v)
+		case float64:
+			fmt.Fprintf(&row, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(v, 'f', -1, 64))
+		case Amount:
+			fmt.Fprintf(&row, `<c r="%s" s="%d"><v>%s</v></c>`, ref, styleAmount, strconv.FormatFloat(float64(v), 'f', 2, 64))
+		case time.Time:
+			if v.IsZero() {
+				continue
+			}
+			fmt.Fprintf(&row, `<c r="%s" s="%d"><v>%d</v></c>`, ref, styleDate, serialDate(v))
+		default:
+			return fmt.Errorf("xlsx: cannot write a %T to a cell", v)
+		}
+	}
+	row.WriteString("</row>")
+
+	if _, err := row.WriteTo(w.sheet); err != nil {
+		return err
+	}
+	w.rows = n
+	return nil
+}
+
+// Flush writes any buffered rows to the underlying writer
+func (w *Writer) Flush() error {
+	return w.sheet.Flush()
+}
+
+// Close ends the sheet & the workbook. It does not close the underlying writer
+func (w *Writer) Close() error {
+	if w.zw == nil {
+		return errors.New("xlsx: workbook already closed")
+	}
+	w.sheet.WriteString("</sheetData></worksheet>")
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	err := w.zw.Close()
+	w.zw = nil
+	return err
+}
+
+// ColumnName gives the letters naming a column, from its zero based index e.g. 0 as A, 26 as AA
+func ColumnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// serialDate gives a date as the number of days since the spreadsheet epoch, ignoring any time of day
+func serialDate(t time.Time) int {
+	y, m, d := t.Date()
+	return int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Sub(epoch).Hours() / 24)
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const contentTypesXML = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const rootRelsXML = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const workbookXML = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const workbookRelsXML = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// stylesXML gives the cell styles: none, bold headings, dates (built in format 14, shown in the reader's short date form) &
+// amounts (built in format 2, 0.00)
+const stylesXML = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="14" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="2" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
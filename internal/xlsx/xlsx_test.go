@@ -0,0 +1,98 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestColumnName(t *testing.T) {
+	expected := map[int]string{0: "A", 1: "B", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"}
+	for i, name := range expected {
+		if ColumnName(i) != name {
+			t.Errorf("column %d: expected %s, got %s", i, name, ColumnName(i))
+		}
+	}
+}
+
+func TestSerialDate(t *testing.T) {
+	// as given by Excel's DATEVALUE
+	expected := map[string]int{"1900-03-01": 61, "2000-01-01": 36526, "2026-10-18": 46313}
+	for s, serial := range expected {
+		d, _ := time.Parse("2006-01-02", s)
+		if serialDate(d.Add(15*time.Hour)) != serial {
+			t.Errorf("%s: expected %d, got %d", s, serial, serialDate(d))
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "Reservations & Co")
+	if err != nil {
+		t.Fatal("NewWriter failed", err)
+	}
+	w.WriteHeading([]string{"Name", "Nights", "Arrival", "Total"})
+	w.WriteRow([]interface{}{"Soap <Joe>", 4, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), Amount(240.5)})
+	w.WriteRow([]interface{}{"Doe", 2, time.Time{}, Amount(80)})
+	if err = w.WriteRow([]interface{}{true}); err == nil {
+		t.Error("expected a bool not to be written")
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal("Close failed", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal("workbook is not a zip", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(b)
+
+		// every part must be well formed XML
+		d := xml.NewDecoder(bytes.NewReader(b))
+		for {
+			_, err := d.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s is not well formed: %v", f.Name, err)
+			}
+		}
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("workbook has no %s", name)
+		}
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `name="Reservations &amp; Co"`) {
+		t.Errorf("unexpected workbook %s", parts["xl/workbook.xml"])
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, s := range []string{
+		`<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">Name</t></is></c>`,
+		`<t xml:space="preserve">Soap &lt;Joe&gt;</t>`,
+		`<c r="B2" s="0"><v>4</v></c>`,
+		`<c r="C2" s="2"><v>46313</v></c>`,
+		`<c r="D2" s="3"><v>240.50</v></c>`,
+		`<c r="D3" s="3"><v>80.00</v></c>`,
+	} {
+		if !strings.Contains(sheet, s) {
+			t.Errorf("sheet does not contain %s", s)
+		}
+	}
+	if strings.Contains(sheet, `r="C3"`) {
+		t.Error("expected a zero date to leave its cell empty")
+	}
+}
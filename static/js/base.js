@@ -32,10 +32,24 @@ function modalReservation(CSRF, roomID) {
             <input required class="form-control" type="text" name="end_date" id="end_date" placeholder=" Departure date" disabled>
           </div>
         </div>
+        <div class="row mt-3">
+          <div class="col">
+            <label for="adults">Adults</label>
+            <input required class="form-control" type="number" min="1" max="20" name="adults" id="adults" value="1">
+          </div>
+          <div class="col">
+            <label for="children">Children</label>
+            <input class="form-control" type="number" min="0" max="20" name="children" id="children" value="0">
+          </div>
+          <div class="col">
+            <label for="infants">Infants</label>
+            <input class="form-control" type="number" min="0" max="20" name="infants" id="infants" value="0">
+          </div>
+        </div>
       </form>
     </div>`;
     attention.customModal({
-      title: 'Choose your dates',
+      title: 'Choose your dates & guests',
       msg: modal_html,
       inputAttributes: {},
       customClass: {},
@@ -48,6 +62,7 @@ function modalReservation(CSRF, roomID) {
           todayHighlight: true,
           showOnFocus: true,
         })
+        disableUnavailableDates(rp, [roomID]);
       },
       didOpen: () => {
         document.getElementById("start_date").removeAttribute("disabled");
@@ -75,7 +90,8 @@ function modalReservation(CSRF, roomID) {
             attention.customModal({
               title: "This room is available !",
               icon: "success",
-              msg: '<p><a href="/reserve-room?id=' + data.room_id + '&sd=' + data.start_date + '&ed=' + data.end_date + '"' +
+              msg: '<p><a href="/reserve-room?id=' + data.room_id + '&sd=' + data.start_date + '&ed=' + data.end_date +
+                '&adults=' + data.adults + '&children=' + data.children + '&infants=' + data.infants + '"' +
                 ' class="btn btn-primary mt-4">Reserve Now !</a></p>',
               inputAttributes: {},
               customClass: {},
@@ -83,7 +99,7 @@ function modalReservation(CSRF, roomID) {
             })
           } else {
             attention.error({
-              msg: "Sorry, this room is not available",
+              msg: data.message !== "" ? data.message : "Sorry, this room is not available",
             });
           }
         });
@@ -92,6 +108,40 @@ function modalReservation(CSRF, roomID) {
   })
 }
 
+// disableUnavailableDates disables the days of a date range picker on which a stay cannot start or end in any of the given rooms,
+// as given by each room's calendar for the next 12 months
+function disableUnavailableDates(rangePicker, roomIDs) {
+  const pad = n => String(n).padStart(2, '0');
+  const isoMonth = d => d.getFullYear() + '-' + pad(d.getMonth() + 1);
+  const isoDate = d => isoMonth(d) + '-' + pad(d.getDate());
+  const now = new Date();
+  const query = '?start=' + isoMonth(now) + '&end=' + isoMonth(new Date(now.getFullYear(), now.getMonth() + 11, 1));
+
+  Promise.all(roomIDs.map(id => fetch('/api/v1/rooms/' + id + '/calendar' + query).then(response => response.json())))
+    .then(calendars => {
+      const known = new Set(), arrivals = new Set(), departures = new Set();
+      calendars.forEach(calendar => (calendar.days || []).forEach(day => {
+        known.add(day.date);
+        if (day.status === 'available' || day.status === 'arrival-only') {
+          arrivals.add(day.date);
+        }
+        if (day.status === 'available' || day.status === 'departure-only') {
+          departures.add(day.date);
+        }
+      }));
+      // days beyond the calendars are left for the availability search to check
+      rangePicker.datepickers[0].setOptions({
+        beforeShowDay: date => !known.has(isoDate(date)) || arrivals.has(isoDate(date)),
+      });
+      rangePicker.datepickers[1].setOptions({
+        beforeShowDay: date => !known.has(isoDate(date)) || departures.has(isoDate(date)),
+      });
+    })
+    .catch(() => {
+      // without the calendars every day stays selectable, as before
+    });
+}
+
 function notify(msg, msgType, duration) {
   notie.alert({
     type: msgType,